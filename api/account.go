@@ -3,14 +3,27 @@ package api
 import (
 	"database/sql"
 	"errors"
+	"fmt"
 	db "gobank/db/sqlc"
 	"gobank/token"
+	"gobank/util"
 	"net/http"
+	"strconv"
+	"time"
 
 	"github.com/gin-gonic/gin"
 	"github.com/lib/pq"
 )
 
+// accountNumberAttempts bounds how many account numbers createAccount draws when the one it drew is taken,
+// which with ten random digits should all but never happen.
+const accountNumberAttempts = 3
+
+func isAccountNumberTaken(err error) bool {
+	pqErr, ok := err.(*pq.Error)
+	return ok && pqErr.Code.Name() == "unique_violation" && pqErr.Constraint == "accounts_number_key"
+}
+
 type createAccountRequest struct {
 	// Owner    string `json:"owner" binding:"required"`
 	Currency string `json:"currency" binding:"required,currency"`
@@ -30,8 +43,23 @@ func (s *Server) createAccount(ctx *gin.Context) {
 		Currency: req.Currency,
 		Balance:  0,
 	}
-	acc, err := s.store.CreateAccount(ctx, args)
+	var acc db.Account
+	var err error
+	for attempt := 0; attempt < accountNumberAttempts; attempt++ {
+		args.Number, err = util.NewAccountNumber()
+		if err != nil {
+			break
+		}
+		acc, err = s.store.CreateAccountTx(ctx, args)
+		if !isAccountNumberTaken(err) {
+			break
+		}
+	}
 	if err != nil {
+		if isAccountNumberTaken(err) {
+			ctx.JSON(http.StatusInternalServerError, errorResponse(err))
+			return
+		}
 		if pqErr, ok := err.(*pq.Error); ok {
 			switch pqErr.Code.Name() {
 			//2 constraints in accs table: FK owner, UNIQUE owner currency
@@ -50,27 +78,73 @@ type getAccountRequest struct {
 	ID int64 `uri:"id" binding:"required,min=1"`
 }
 
-func (s *Server) getAccount(ctx *gin.Context) {
-	var req getAccountRequest
-	if err := ctx.ShouldBindUri(&req); err != nil {
-		ctx.JSON(http.StatusBadRequest, errorResponse(err))
-		return
+// accountRefRequest is getAccountRequest for the lookup endpoints, which take an account number in place of the ID.
+type accountRefRequest struct {
+	Ref string `uri:"id" binding:"required,accountref"`
+}
+
+// accountRefID returns the ID of the account an accountRefRequest points to, looking it up by number if need be.
+// All the lookup endpoints read the account, so a number the user cannot read is reported as not found.
+func (s *Server) accountRefID(ctx *gin.Context, req accountRefRequest) (int64, bool) {
+	if id, err := strconv.ParseInt(req.Ref, 10, 64); err == nil {
+		return id, true
 	}
-	acc, err := s.store.GetAccount(ctx, req.ID)
+	acc, ok := s.accessibleAccountByNumber(ctx, req.Ref, accessRead)
+	return acc.ID, ok
+}
 
+// accountByNumber loads an account by its account number, writing the error response if it cannot. It is for
+// accounts being paid into, which anyone may name; see accessibleAccountByNumber for the others.
+func (s *Server) accountByNumber(ctx *gin.Context, number string) (db.Account, bool) {
+	acc, err := s.store.GetAccountByNumber(ctx, util.NormalizeAccountNumber(number))
 	if err != nil {
 		if err == sql.ErrNoRows {
 			ctx.JSON(http.StatusNotFound, errorResponse(err))
-			return
+			return acc, false
 		}
 		ctx.JSON(http.StatusInternalServerError, errorResponse(err))
-		return
+		return acc, false
 	}
+	return acc, true
+}
 
-	payload := ctx.MustGet(authorizationPayloadKey).(*token.Payload)
-	if payload.Username != acc.Owner {
-		err := errors.New("account doesn't belong to authenticated user")
-		ctx.JSON(http.StatusUnauthorized, errorResponse(err))
+// accessibleAccountByNumber is accountByNumber for an account the authenticated user needs access to. An account
+// they have no access to gets the same 404 as a number nobody holds, so it cannot tell which numbers exist.
+func (s *Server) accessibleAccountByNumber(ctx *gin.Context, number string, access string) (db.Account, bool) {
+	acc, err := s.store.GetAccountByNumber(ctx, util.NormalizeAccountNumber(number))
+	if err == nil {
+		var ok bool
+		ok, err = s.hasAccountAccess(ctx, acc, access)
+		if err == nil && !ok {
+			err = sql.ErrNoRows
+		}
+	}
+	if err != nil {
+		if err == sql.ErrNoRows {
+			ctx.JSON(http.StatusNotFound, errorResponse(err))
+			return db.Account{}, false
+		}
+		ctx.JSON(http.StatusInternalServerError, errorResponse(err))
+		return db.Account{}, false
+	}
+	return acc, true
+}
+
+func (s *Server) getAccount(ctx *gin.Context) {
+	var req accountRefRequest
+	if err := ctx.ShouldBindUri(&req); err != nil {
+		ctx.JSON(http.StatusBadRequest, errorResponse(err))
+		return
+	}
+	id, ok := s.accountRefID(ctx, req)
+	if !ok {
+		return
+	}
+	if !checkConsent(ctx, id, permissionReadBalances) {
+		return
+	}
+	acc, ok := s.accountAccess(ctx, id, accessRead)
+	if !ok {
 		return
 	}
 
@@ -78,8 +152,8 @@ func (s *Server) getAccount(ctx *gin.Context) {
 }
 
 type listAccountRequest struct {
-	PageID   int32 `form:"page_id" binding:"required, min=1"`
-	PageSize int32 `form:"page_size" binding:"required, min=5,max=10"`
+	PageID   int32 `form:"page_id" binding:"required,min=1"`
+	PageSize int32 `form:"page_size" binding:"required,min=5,max=10"`
 }
 
 func (s *Server) listAccount(ctx *gin.Context) {
@@ -88,15 +162,19 @@ func (s *Server) listAccount(ctx *gin.Context) {
 		ctx.JSON(http.StatusBadRequest, errorResponse(err))
 		return
 	}
+	if consent, bound := ctx.Get(authorizationConsentKey); bound {
+		s.listConsentedAccounts(ctx, consent.(db.AccountConsent), req)
+		return
+	}
 	payload := ctx.MustGet(authorizationPayloadKey).(*token.Payload)
 
-	args := db.ListAccountsParams{
-		Owner:  payload.Username,
-		Limit:  req.PageSize,
-		Offset: (req.PageID - 1) * req.PageSize,
+	args := db.ListHolderAccountsParams{
+		Username: payload.Username,
+		Limit:    req.PageSize,
+		Offset:   (req.PageID - 1) * req.PageSize,
 		// Offset: 0,
 	}
-	accs, err := s.store.ListAccounts(ctx, args)
+	accs, err := s.store.ListHolderAccounts(ctx, args)
 	if err != nil {
 		ctx.JSON(http.StatusInternalServerError, errorResponse(err))
 		return
@@ -104,3 +182,120 @@ func (s *Server) listAccount(ctx *gin.Context) {
 	ctx.JSON(http.StatusOK, accs)
 
 }
+
+// listConsentedAccounts pages through the accounts an account consent covers that the user can still read.
+func (s *Server) listConsentedAccounts(ctx *gin.Context, consent db.AccountConsent, req listAccountRequest) {
+	if !containsString(consent.Permissions, permissionReadBalances) {
+		err := fmt.Errorf("consent lacks the %s permission", permissionReadBalances)
+		ctx.JSON(http.StatusForbidden, errorResponse(err))
+		return
+	}
+
+	accs := []db.Account{}
+	for _, id := range consent.AccountIds {
+		acc, err := s.store.GetAccount(ctx, id)
+		if err == sql.ErrNoRows {
+			continue
+		}
+		if err != nil {
+			ctx.JSON(http.StatusInternalServerError, errorResponse(err))
+			return
+		}
+		ok, err := s.hasAccountAccess(ctx, acc, accessRead)
+		if err != nil {
+			ctx.JSON(http.StatusInternalServerError, errorResponse(err))
+			return
+		}
+		if ok {
+			accs = append(accs, acc)
+		}
+	}
+
+	start := int((req.PageID - 1) * req.PageSize)
+	if start > len(accs) {
+		start = len(accs)
+	}
+	end := start + int(req.PageSize)
+	if end > len(accs) {
+		end = len(accs)
+	}
+	ctx.JSON(http.StatusOK, accs[start:end])
+}
+
+type getAccountBalanceRequest struct {
+	At time.Time `form:"at" time_format:"2006-01-02T15:04:05Z07:00"`
+}
+
+type accountBalanceResponse struct {
+	AccountID int64     `json:"account_id"`
+	Balance   int64     `json:"balance"`
+	Currency  string    `json:"currency"`
+	At        time.Time `json:"at"`
+}
+
+// getAccountBalance returns the balance of an account as it was at a point in time, defaulting to now.
+func (s *Server) getAccountBalance(ctx *gin.Context) {
+	var uri accountRefRequest
+	if err := ctx.ShouldBindUri(&uri); err != nil {
+		ctx.JSON(http.StatusBadRequest, errorResponse(err))
+		return
+	}
+	var req getAccountBalanceRequest
+	if err := ctx.ShouldBindQuery(&req); err != nil {
+		ctx.JSON(http.StatusBadRequest, errorResponse(err))
+		return
+	}
+	if req.At.IsZero() {
+		req.At = time.Now()
+	}
+	id, ok := s.accountRefID(ctx, uri)
+	if !ok {
+		return
+	}
+	if !checkConsent(ctx, id, permissionReadBalances) {
+		return
+	}
+
+	acc, ok := s.accountAccess(ctx, id, accessRead)
+	if !ok {
+		return
+	}
+
+	balance, err := s.store.GetAccountBalanceAt(ctx, db.GetAccountBalanceAtParams{
+		AccountID: acc.ID,
+		At:        req.At,
+	})
+	//no entries yet at that time: nothing had moved in or out of the account
+	if err != nil && err != sql.ErrNoRows {
+		ctx.JSON(http.StatusInternalServerError, errorResponse(err))
+		return
+	}
+
+	ctx.JSON(http.StatusOK, accountBalanceResponse{
+		AccountID: acc.ID,
+		Balance:   balance,
+		Currency:  acc.Currency,
+		At:        req.At,
+	})
+}
+
+// ownedAccount loads an account and checks it belongs to the authenticated user, writing the error response if not.
+func (s *Server) ownedAccount(ctx *gin.Context, accountID int64) (db.Account, bool) {
+	acc, err := s.store.GetAccount(ctx, accountID)
+	if err != nil {
+		if err == sql.ErrNoRows {
+			ctx.JSON(http.StatusNotFound, errorResponse(err))
+			return acc, false
+		}
+		ctx.JSON(http.StatusInternalServerError, errorResponse(err))
+		return acc, false
+	}
+
+	payload := ctx.MustGet(authorizationPayloadKey).(*token.Payload)
+	if payload.Username != acc.Owner {
+		err := errors.New("account doesn't belong to authenticated user")
+		ctx.JSON(http.StatusUnauthorized, errorResponse(err))
+		return acc, false
+	}
+	return acc, true
+}
@@ -0,0 +1,197 @@
+package api
+
+import (
+	"database/sql"
+	"errors"
+	db "gobank/db/sqlc"
+	"gobank/token"
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+)
+
+// An owner can let other users onto one of their accounts. Read access lets them see the account, its
+// balance and statements; transfer access also lets them make transfers from it.
+const (
+	accessRead     = db.AccessRead
+	accessTransfer = db.AccessTransfer
+)
+
+// grantsAccess reports whether a permission with access granted covers access wanted.
+func grantsAccess(granted, wanted string) bool {
+	return granted == wanted || granted == accessTransfer && wanted == accessRead
+}
+
+// accountAccess loads an account and checks the authenticated user holds it or was granted access to it,
+// writing the error response if not.
+func (s *Server) accountAccess(ctx *gin.Context, accountID int64, access string) (db.Account, bool) {
+	acc, err := s.store.GetAccount(ctx, accountID)
+	if err != nil {
+		if err == sql.ErrNoRows {
+			ctx.JSON(http.StatusNotFound, errorResponse(err))
+			return acc, false
+		}
+		ctx.JSON(http.StatusInternalServerError, errorResponse(err))
+		return acc, false
+	}
+	if !s.checkAccountAccess(ctx, acc, access) {
+		return acc, false
+	}
+	return acc, true
+}
+
+// checkAccountAccess lets the holders of acc through, and users the owner granted access to it. It writes
+// the error response and returns false otherwise.
+func (s *Server) checkAccountAccess(ctx *gin.Context, acc db.Account, access string) bool {
+	ok, err := s.hasAccountAccess(ctx, acc, access)
+	if err != nil {
+		ctx.JSON(http.StatusInternalServerError, errorResponse(err))
+		return false
+	}
+	if !ok {
+		err := errors.New("account doesn't belong to authenticated user")
+		ctx.JSON(http.StatusUnauthorized, errorResponse(err))
+		return false
+	}
+	return true
+}
+
+// hasAccountAccess is checkAccountAccess for callers that report a refusal themselves.
+func (s *Server) hasAccountAccess(ctx *gin.Context, acc db.Account, access string) (bool, error) {
+	held, err := s.holdsAccount(ctx, acc)
+	if err != nil || held {
+		return held, err
+	}
+
+	payload := ctx.MustGet(authorizationPayloadKey).(*token.Payload)
+	perm, err := s.store.GetAccountPermission(ctx, db.GetAccountPermissionParams{
+		AccountID: acc.ID,
+		Username:  payload.Username,
+	})
+	if err == sql.ErrNoRows {
+		return false, nil
+	}
+	if err != nil {
+		return false, err
+	}
+	return grantsAccess(perm.Access, access), nil
+}
+
+// holdsAccount reports whether the authenticated user owns acc or was added as a joint holder of it.
+func (s *Server) holdsAccount(ctx *gin.Context, acc db.Account) (bool, error) {
+	payload := ctx.MustGet(authorizationPayloadKey).(*token.Payload)
+	if payload.Username == acc.Owner {
+		return true, nil
+	}
+
+	_, err := s.store.GetAccountHolder(ctx, db.GetAccountHolderParams{
+		AccountID: acc.ID,
+		Username:  payload.Username,
+	})
+	if err == sql.ErrNoRows {
+		return false, nil
+	}
+	return err == nil, err
+}
+
+type grantAccountPermissionRequest struct {
+	Username string `json:"username" binding:"required,alphanum"`
+	Access   string `json:"access" binding:"required,oneof=read transfer"`
+}
+
+// grantAccountPermission gives another user access to an account of the authenticated user, or changes
+// the access they already have.
+func (s *Server) grantAccountPermission(ctx *gin.Context) {
+	var uri getAccountRequest
+	if err := ctx.ShouldBindUri(&uri); err != nil {
+		ctx.JSON(http.StatusBadRequest, errorResponse(err))
+		return
+	}
+	var req grantAccountPermissionRequest
+	if err := ctx.ShouldBindJSON(&req); err != nil {
+		ctx.JSON(http.StatusBadRequest, errorResponse(err))
+		return
+	}
+
+	acc, ok := s.ownedAccount(ctx, uri.ID)
+	if !ok {
+		return
+	}
+	if req.Username == acc.Owner {
+		err := errors.New("the owner already has full access to the account")
+		ctx.JSON(http.StatusBadRequest, errorResponse(err))
+		return
+	}
+	if _, err := s.store.GetUser(ctx, req.Username); err != nil {
+		if err == sql.ErrNoRows {
+			ctx.JSON(http.StatusNotFound, errorResponse(err))
+			return
+		}
+		ctx.JSON(http.StatusInternalServerError, errorResponse(err))
+		return
+	}
+
+	perm, err := s.store.UpsertAccountPermission(ctx, db.UpsertAccountPermissionParams{
+		AccountID: acc.ID,
+		Username:  req.Username,
+		Access:    req.Access,
+		GrantedBy: acc.Owner,
+	})
+	if err != nil {
+		ctx.JSON(http.StatusInternalServerError, errorResponse(err))
+		return
+	}
+	ctx.JSON(http.StatusOK, perm)
+}
+
+// listAccountPermissions shows the owner of an account who else has access to it.
+func (s *Server) listAccountPermissions(ctx *gin.Context) {
+	var uri getAccountRequest
+	if err := ctx.ShouldBindUri(&uri); err != nil {
+		ctx.JSON(http.StatusBadRequest, errorResponse(err))
+		return
+	}
+	acc, ok := s.ownedAccount(ctx, uri.ID)
+	if !ok {
+		return
+	}
+
+	perms, err := s.store.ListAccountPermissions(ctx, acc.ID)
+	if err != nil {
+		ctx.JSON(http.StatusInternalServerError, errorResponse(err))
+		return
+	}
+	ctx.JSON(http.StatusOK, perms)
+}
+
+type revokeAccountPermissionRequest struct {
+	ID       int64  `uri:"id" binding:"required,min=1"`
+	Username string `uri:"username" binding:"required,alphanum"`
+}
+
+// revokeAccountPermission takes a user's access to an account away.
+func (s *Server) revokeAccountPermission(ctx *gin.Context) {
+	var req revokeAccountPermissionRequest
+	if err := ctx.ShouldBindUri(&req); err != nil {
+		ctx.JSON(http.StatusBadRequest, errorResponse(err))
+		return
+	}
+	acc, ok := s.ownedAccount(ctx, req.ID)
+	if !ok {
+		return
+	}
+
+	n, err := s.store.DeleteAccountPermission(ctx, db.DeleteAccountPermissionParams{
+		AccountID: acc.ID,
+		Username:  req.Username,
+	})
+	if err != nil {
+		ctx.JSON(http.StatusInternalServerError, errorResponse(err))
+		return
+	}
+	if n == 0 {
+		ctx.JSON(http.StatusNotFound, errorResponse(sql.ErrNoRows))
+		return
+	}
+	ctx.Status(http.StatusNoContent)
+}
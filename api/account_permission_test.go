@@ -0,0 +1,212 @@
+package api
+
+import (
+	"bytes"
+	"database/sql"
+	"encoding/json"
+	"fmt"
+	mockdb "gobank/db/mock"
+	db "gobank/db/sqlc"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/golang/mock/gomock"
+	"github.com/stretchr/testify/require"
+)
+
+func TestGrantsAccess(t *testing.T) {
+	require.True(t, grantsAccess(accessRead, accessRead))
+	require.True(t, grantsAccess(accessTransfer, accessRead))
+	require.True(t, grantsAccess(accessTransfer, accessTransfer))
+	require.False(t, grantsAccess(accessRead, accessTransfer))
+	require.False(t, grantsAccess("", accessRead))
+}
+
+func TestGrantAccountPermissionAPI(t *testing.T) {
+	owner, _ := randomUser(t)
+	spouse, _ := randomUser(t)
+	acc := randomAccount(owner.Username)
+	acc.ID = 7
+
+	testCases := []struct {
+		name          string
+		username      string
+		body          gin.H
+		buildStubs    func(store *mockdb.MockStore)
+		checkResponse func(t *testing.T, rec *httptest.ResponseRecorder)
+	}{
+		{
+			name:     "OK",
+			username: owner.Username,
+			body:     gin.H{"username": spouse.Username, "access": accessTransfer},
+			buildStubs: func(store *mockdb.MockStore) {
+				arg := db.UpsertAccountPermissionParams{
+					AccountID: acc.ID,
+					Username:  spouse.Username,
+					Access:    accessTransfer,
+					GrantedBy: owner.Username,
+				}
+				store.EXPECT().GetAccount(gomock.Any(), gomock.Eq(acc.ID)).Times(1).Return(acc, nil)
+				store.EXPECT().UpsertAccountPermission(gomock.Any(), gomock.Eq(arg)).Times(1).
+					Return(db.AccountPermission{AccountID: acc.ID, Username: spouse.Username, Access: accessTransfer, GrantedBy: owner.Username}, nil)
+			},
+			checkResponse: func(t *testing.T, rec *httptest.ResponseRecorder) {
+				require.Equal(t, http.StatusOK, rec.Code)
+
+				var perm db.AccountPermission
+				require.NoError(t, json.Unmarshal(rec.Body.Bytes(), &perm))
+				require.Equal(t, spouse.Username, perm.Username)
+				require.Equal(t, accessTransfer, perm.Access)
+			},
+		},
+		{
+			name:     "InvalidAccess",
+			username: owner.Username,
+			body:     gin.H{"username": spouse.Username, "access": "admin"},
+			buildStubs: func(store *mockdb.MockStore) {
+				store.EXPECT().GetAccount(gomock.Any(), gomock.Any()).Times(0)
+			},
+			checkResponse: func(t *testing.T, rec *httptest.ResponseRecorder) {
+				require.Equal(t, http.StatusBadRequest, rec.Code)
+			},
+		},
+		{
+			name:     "NotOwner",
+			username: spouse.Username,
+			body:     gin.H{"username": spouse.Username, "access": accessTransfer},
+			buildStubs: func(store *mockdb.MockStore) {
+				store.EXPECT().GetAccount(gomock.Any(), gomock.Eq(acc.ID)).Times(1).Return(acc, nil)
+				store.EXPECT().UpsertAccountPermission(gomock.Any(), gomock.Any()).Times(0)
+			},
+			checkResponse: func(t *testing.T, rec *httptest.ResponseRecorder) {
+				require.Equal(t, http.StatusUnauthorized, rec.Code)
+			},
+		},
+		{
+			name:     "GrantToOwner",
+			username: owner.Username,
+			body:     gin.H{"username": owner.Username, "access": accessRead},
+			buildStubs: func(store *mockdb.MockStore) {
+				store.EXPECT().GetAccount(gomock.Any(), gomock.Eq(acc.ID)).Times(1).Return(acc, nil)
+				store.EXPECT().UpsertAccountPermission(gomock.Any(), gomock.Any()).Times(0)
+			},
+			checkResponse: func(t *testing.T, rec *httptest.ResponseRecorder) {
+				require.Equal(t, http.StatusBadRequest, rec.Code)
+			},
+		},
+		{
+			name:     "UnknownUser",
+			username: owner.Username,
+			body:     gin.H{"username": "nobody", "access": accessRead},
+			buildStubs: func(store *mockdb.MockStore) {
+				store.EXPECT().GetAccount(gomock.Any(), gomock.Eq(acc.ID)).Times(1).Return(acc, nil)
+				store.EXPECT().GetUser(gomock.Any(), gomock.Eq("nobody")).Times(1).Return(db.User{}, sql.ErrNoRows)
+				store.EXPECT().UpsertAccountPermission(gomock.Any(), gomock.Any()).Times(0)
+			},
+			checkResponse: func(t *testing.T, rec *httptest.ResponseRecorder) {
+				require.Equal(t, http.StatusNotFound, rec.Code)
+			},
+		},
+	}
+
+	for i := range testCases {
+		tc := testCases[i]
+
+		t.Run(tc.name, func(t *testing.T) {
+			ctrl := gomock.NewController(t)
+			defer ctrl.Finish()
+
+			store := mockdb.NewMockStore(ctrl)
+			tc.buildStubs(store)
+			stubAuthUsers(store)
+
+			svr := newTestServer(t, store)
+			rec := httptest.NewRecorder()
+
+			data, err := json.Marshal(tc.body)
+			require.NoError(t, err)
+			req, err := http.NewRequest(http.MethodPost, fmt.Sprintf("/accounts/%d/permissions", acc.ID), bytes.NewReader(data))
+			require.NoError(t, err)
+
+			addAuthorizationHeader(t, req, svr.tokenMaker, authorizationTypeBearer, tc.username, time.Minute)
+			svr.router.ServeHTTP(rec, req)
+			tc.checkResponse(t, rec)
+		})
+	}
+}
+
+func TestRevokeAccountPermissionAPI(t *testing.T) {
+	owner, _ := randomUser(t)
+	accountant, _ := randomUser(t)
+	acc := randomAccount(owner.Username)
+	acc.ID = 7
+	arg := db.DeleteAccountPermissionParams{AccountID: acc.ID, Username: accountant.Username}
+
+	testCases := []struct {
+		name          string
+		username      string
+		buildStubs    func(store *mockdb.MockStore)
+		checkResponse func(t *testing.T, rec *httptest.ResponseRecorder)
+	}{
+		{
+			name:     "OK",
+			username: owner.Username,
+			buildStubs: func(store *mockdb.MockStore) {
+				store.EXPECT().GetAccount(gomock.Any(), gomock.Eq(acc.ID)).Times(1).Return(acc, nil)
+				store.EXPECT().DeleteAccountPermission(gomock.Any(), gomock.Eq(arg)).Times(1).Return(int64(1), nil)
+			},
+			checkResponse: func(t *testing.T, rec *httptest.ResponseRecorder) {
+				require.Equal(t, http.StatusNoContent, rec.Code)
+			},
+		},
+		{
+			name:     "NotGranted",
+			username: owner.Username,
+			buildStubs: func(store *mockdb.MockStore) {
+				store.EXPECT().GetAccount(gomock.Any(), gomock.Eq(acc.ID)).Times(1).Return(acc, nil)
+				store.EXPECT().DeleteAccountPermission(gomock.Any(), gomock.Eq(arg)).Times(1).Return(int64(0), nil)
+			},
+			checkResponse: func(t *testing.T, rec *httptest.ResponseRecorder) {
+				require.Equal(t, http.StatusNotFound, rec.Code)
+			},
+		},
+		{
+			name:     "NotOwner",
+			username: accountant.Username,
+			buildStubs: func(store *mockdb.MockStore) {
+				store.EXPECT().GetAccount(gomock.Any(), gomock.Eq(acc.ID)).Times(1).Return(acc, nil)
+				store.EXPECT().DeleteAccountPermission(gomock.Any(), gomock.Any()).Times(0)
+			},
+			checkResponse: func(t *testing.T, rec *httptest.ResponseRecorder) {
+				require.Equal(t, http.StatusUnauthorized, rec.Code)
+			},
+		},
+	}
+
+	for i := range testCases {
+		tc := testCases[i]
+
+		t.Run(tc.name, func(t *testing.T) {
+			ctrl := gomock.NewController(t)
+			defer ctrl.Finish()
+
+			store := mockdb.NewMockStore(ctrl)
+			stubAuthUsers(store)
+			tc.buildStubs(store)
+
+			svr := newTestServer(t, store)
+			rec := httptest.NewRecorder()
+
+			url := fmt.Sprintf("/accounts/%d/permissions/%s", acc.ID, accountant.Username)
+			req, err := http.NewRequest(http.MethodDelete, url, nil)
+			require.NoError(t, err)
+
+			addAuthorizationHeader(t, req, svr.tokenMaker, authorizationTypeBearer, tc.username, time.Minute)
+			svr.router.ServeHTTP(rec, req)
+			tc.checkResponse(t, rec)
+		})
+	}
+}
@@ -12,10 +12,13 @@ import (
 	"io/ioutil"
 	"net/http"
 	"net/http/httptest"
+	"strings"
 	"testing"
 	"time"
 
+	"github.com/gin-gonic/gin"
 	"github.com/golang/mock/gomock"
+	"github.com/lib/pq"
 	"github.com/stretchr/testify/require"
 )
 
@@ -25,6 +28,7 @@ func randomAccount(owner string) db.Account {
 		Owner:    owner,
 		Balance:  util.RandomMoney(),
 		Currency: util.RandomCurrency(),
+		Number:   util.RandomAccountNumber(),
 	}
 }
 
@@ -87,11 +91,38 @@ func TestGetAccountAPI(t *testing.T) {
 					GetAccount(gomock.Any(), gomock.Eq(acc.ID)).
 					Times(1).
 					Return(acc, nil)
+				store.EXPECT().GetAccountHolder(gomock.Any(), gomock.Any()).Times(1).Return(db.AccountHolder{}, sql.ErrNoRows)
+				store.EXPECT().
+					GetAccountPermission(gomock.Any(), gomock.Eq(db.GetAccountPermissionParams{AccountID: acc.ID, Username: "unauthorized_user"})).
+					Times(1).
+					Return(db.AccountPermission{}, sql.ErrNoRows)
 			},
 			checkResponse: func(t *testing.T, recorder *httptest.ResponseRecorder) {
 				require.Equal(t, http.StatusUnauthorized, recorder.Code)
 			},
 		},
+		{
+			name:      "ReadDelegate",
+			accountID: acc.ID,
+			setupAuth: func(t *testing.T, r *http.Request, tm token.Maker) {
+				addAuthorizationHeader(t, r, tm, authorizationTypeBearer, "accountant", time.Minute)
+			},
+			buildStubs: func(store *mockdb.MockStore) {
+				store.EXPECT().
+					GetAccount(gomock.Any(), gomock.Eq(acc.ID)).
+					Times(1).
+					Return(acc, nil)
+				store.EXPECT().GetAccountHolder(gomock.Any(), gomock.Any()).Times(1).Return(db.AccountHolder{}, sql.ErrNoRows)
+				store.EXPECT().
+					GetAccountPermission(gomock.Any(), gomock.Eq(db.GetAccountPermissionParams{AccountID: acc.ID, Username: "accountant"})).
+					Times(1).
+					Return(db.AccountPermission{AccountID: acc.ID, Username: "accountant", Access: accessRead}, nil)
+			},
+			checkResponse: func(t *testing.T, recorder *httptest.ResponseRecorder) {
+				require.Equal(t, http.StatusOK, recorder.Code)
+				requireBodyMatchAccount(t, recorder.Body, acc)
+			},
+		},
 		{
 			name:      "NoAuthorization",
 			accountID: acc.ID,
@@ -164,6 +195,7 @@ func TestGetAccountAPI(t *testing.T) {
 			defer ctrl.Finish()
 
 			store := mockdb.NewMockStore(ctrl)
+			stubAuthUsers(store)
 			tc.buildStubs(store)
 
 			svr := newTestServer(t, store)
@@ -181,3 +213,330 @@ func TestGetAccountAPI(t *testing.T) {
 	}
 
 }
+
+func TestGetAccountBalanceAPI(t *testing.T) {
+	user, _ := randomUser(t)
+	acc := randomAccount(user.Username)
+	at := time.Date(2021, time.March, 31, 23, 59, 59, 0, time.UTC)
+	balance := util.RandomMoney()
+
+	testCases := []struct {
+		name          string
+		accountID     int64
+		query         string
+		setupAuth     func(t *testing.T, r *http.Request, tm token.Maker)
+		buildStubs    func(store *mockdb.MockStore)
+		checkResponse func(t *testing.T, rec *httptest.ResponseRecorder)
+	}{
+		{
+			name:      "OK",
+			accountID: acc.ID,
+			query:     "?at=" + at.Format(time.RFC3339),
+			setupAuth: func(t *testing.T, r *http.Request, tm token.Maker) {
+				addAuthorizationHeader(t, r, tm, authorizationTypeBearer, user.Username, time.Minute)
+			},
+			buildStubs: func(store *mockdb.MockStore) {
+				store.EXPECT().GetAccount(gomock.Any(), gomock.Eq(acc.ID)).Times(1).Return(acc, nil)
+				arg := db.GetAccountBalanceAtParams{AccountID: acc.ID, At: at}
+				store.EXPECT().GetAccountBalanceAt(gomock.Any(), gomock.Eq(arg)).Times(1).Return(balance, nil)
+			},
+			checkResponse: func(t *testing.T, rec *httptest.ResponseRecorder) {
+				require.Equal(t, http.StatusOK, rec.Code)
+
+				var rsp accountBalanceResponse
+				require.NoError(t, json.Unmarshal(rec.Body.Bytes(), &rsp))
+				require.Equal(t, balance, rsp.Balance)
+				require.Equal(t, acc.Currency, rsp.Currency)
+				require.True(t, at.Equal(rsp.At))
+			},
+		},
+		{
+			name:      "NoEntriesYet",
+			accountID: acc.ID,
+			query:     "?at=" + at.Format(time.RFC3339),
+			setupAuth: func(t *testing.T, r *http.Request, tm token.Maker) {
+				addAuthorizationHeader(t, r, tm, authorizationTypeBearer, user.Username, time.Minute)
+			},
+			buildStubs: func(store *mockdb.MockStore) {
+				store.EXPECT().GetAccount(gomock.Any(), gomock.Eq(acc.ID)).Times(1).Return(acc, nil)
+				store.EXPECT().GetAccountBalanceAt(gomock.Any(), gomock.Any()).Times(1).Return(int64(0), sql.ErrNoRows)
+			},
+			checkResponse: func(t *testing.T, rec *httptest.ResponseRecorder) {
+				require.Equal(t, http.StatusOK, rec.Code)
+				require.Contains(t, rec.Body.String(), `"balance":0`)
+			},
+		},
+		{
+			name:      "DefaultsToNow",
+			accountID: acc.ID,
+			setupAuth: func(t *testing.T, r *http.Request, tm token.Maker) {
+				addAuthorizationHeader(t, r, tm, authorizationTypeBearer, user.Username, time.Minute)
+			},
+			buildStubs: func(store *mockdb.MockStore) {
+				store.EXPECT().GetAccount(gomock.Any(), gomock.Eq(acc.ID)).Times(1).Return(acc, nil)
+				store.EXPECT().GetAccountBalanceAt(gomock.Any(), gomock.Any()).Times(1).Return(balance, nil)
+			},
+			checkResponse: func(t *testing.T, rec *httptest.ResponseRecorder) {
+				require.Equal(t, http.StatusOK, rec.Code)
+			},
+		},
+		{
+			name:      "InvalidTimestamp",
+			accountID: acc.ID,
+			query:     "?at=yesterday",
+			setupAuth: func(t *testing.T, r *http.Request, tm token.Maker) {
+				addAuthorizationHeader(t, r, tm, authorizationTypeBearer, user.Username, time.Minute)
+			},
+			buildStubs: func(store *mockdb.MockStore) {
+				store.EXPECT().GetAccount(gomock.Any(), gomock.Any()).Times(0)
+			},
+			checkResponse: func(t *testing.T, rec *httptest.ResponseRecorder) {
+				require.Equal(t, http.StatusBadRequest, rec.Code)
+			},
+		},
+		{
+			name:      "UnauthorizedUser",
+			accountID: acc.ID,
+			setupAuth: func(t *testing.T, r *http.Request, tm token.Maker) {
+				addAuthorizationHeader(t, r, tm, authorizationTypeBearer, "unauthorized_user", time.Minute)
+			},
+			buildStubs: func(store *mockdb.MockStore) {
+				store.EXPECT().GetAccount(gomock.Any(), gomock.Eq(acc.ID)).Times(1).Return(acc, nil)
+				store.EXPECT().GetAccountHolder(gomock.Any(), gomock.Any()).Times(1).Return(db.AccountHolder{}, sql.ErrNoRows)
+				store.EXPECT().GetAccountPermission(gomock.Any(), gomock.Any()).Times(1).Return(db.AccountPermission{}, sql.ErrNoRows)
+				store.EXPECT().GetAccountBalanceAt(gomock.Any(), gomock.Any()).Times(0)
+			},
+			checkResponse: func(t *testing.T, rec *httptest.ResponseRecorder) {
+				require.Equal(t, http.StatusUnauthorized, rec.Code)
+			},
+		},
+		{
+			name:      "InternalError",
+			accountID: acc.ID,
+			setupAuth: func(t *testing.T, r *http.Request, tm token.Maker) {
+				addAuthorizationHeader(t, r, tm, authorizationTypeBearer, user.Username, time.Minute)
+			},
+			buildStubs: func(store *mockdb.MockStore) {
+				store.EXPECT().GetAccount(gomock.Any(), gomock.Eq(acc.ID)).Times(1).Return(acc, nil)
+				store.EXPECT().GetAccountBalanceAt(gomock.Any(), gomock.Any()).Times(1).Return(int64(0), sql.ErrConnDone)
+			},
+			checkResponse: func(t *testing.T, rec *httptest.ResponseRecorder) {
+				require.Equal(t, http.StatusInternalServerError, rec.Code)
+			},
+		},
+	}
+
+	for i := range testCases {
+		tc := testCases[i]
+
+		t.Run(tc.name, func(t *testing.T) {
+			ctrl := gomock.NewController(t)
+			defer ctrl.Finish()
+
+			store := mockdb.NewMockStore(ctrl)
+			stubAuthUsers(store)
+			tc.buildStubs(store)
+
+			svr := newTestServer(t, store)
+			rec := httptest.NewRecorder()
+
+			url := fmt.Sprintf("/accounts/%d/balance%s", tc.accountID, tc.query)
+			req, err := http.NewRequest(http.MethodGet, url, nil)
+			require.NoError(t, err)
+
+			tc.setupAuth(t, req, svr.tokenMaker)
+			svr.router.ServeHTTP(rec, req)
+			tc.checkResponse(t, rec)
+		})
+	}
+}
+
+func TestCreateAccountAPI(t *testing.T) {
+	user, _ := randomUser(t)
+
+	// validNumber matches CreateAccountParams carrying an account number with valid check digits.
+	validNumber := gomock.AssignableToTypeOf(db.CreateAccountParams{})
+
+	testCases := []struct {
+		name          string
+		buildStubs    func(store *mockdb.MockStore)
+		checkResponse func(t *testing.T, rec *httptest.ResponseRecorder)
+	}{
+		{
+			name: "OK",
+			buildStubs: func(store *mockdb.MockStore) {
+				store.EXPECT().CreateAccountTx(gomock.Any(), validNumber).Times(1).
+					DoAndReturn(func(_ interface{}, arg db.CreateAccountParams) (db.Account, error) {
+						require.Equal(t, user.Username, arg.Owner)
+						require.True(t, util.IsValidAccountNumber(arg.Number), arg.Number)
+						return db.Account{ID: 1, Owner: arg.Owner, Currency: arg.Currency, Number: arg.Number}, nil
+					})
+			},
+			checkResponse: func(t *testing.T, rec *httptest.ResponseRecorder) {
+				require.Equal(t, http.StatusOK, rec.Code)
+
+				var acc db.Account
+				require.NoError(t, json.Unmarshal(rec.Body.Bytes(), &acc))
+				require.True(t, util.IsValidAccountNumber(acc.Number))
+			},
+		},
+		{
+			name: "NumberTaken",
+			buildStubs: func(store *mockdb.MockStore) {
+				var taken string
+				gomock.InOrder(
+					store.EXPECT().CreateAccountTx(gomock.Any(), validNumber).Times(1).
+						DoAndReturn(func(_ interface{}, arg db.CreateAccountParams) (db.Account, error) {
+							taken = arg.Number
+							return db.Account{}, &pq.Error{Code: "23505", Constraint: "accounts_number_key"}
+						}),
+					store.EXPECT().CreateAccountTx(gomock.Any(), validNumber).Times(1).
+						DoAndReturn(func(_ interface{}, arg db.CreateAccountParams) (db.Account, error) {
+							require.NotEqual(t, taken, arg.Number)
+							return db.Account{ID: 1, Owner: arg.Owner, Currency: arg.Currency, Number: arg.Number}, nil
+						}),
+				)
+			},
+			checkResponse: func(t *testing.T, rec *httptest.ResponseRecorder) {
+				require.Equal(t, http.StatusOK, rec.Code)
+			},
+		},
+		{
+			name: "CurrencyAlreadyOpen",
+			buildStubs: func(store *mockdb.MockStore) {
+				store.EXPECT().CreateAccountTx(gomock.Any(), validNumber).Times(1).
+					Return(db.Account{}, &pq.Error{Code: "23505", Constraint: "owner_currency_key"})
+			},
+			checkResponse: func(t *testing.T, rec *httptest.ResponseRecorder) {
+				require.Equal(t, http.StatusForbidden, rec.Code)
+			},
+		},
+	}
+
+	for i := range testCases {
+		tc := testCases[i]
+
+		t.Run(tc.name, func(t *testing.T) {
+			ctrl := gomock.NewController(t)
+			defer ctrl.Finish()
+
+			store := mockdb.NewMockStore(ctrl)
+			stubAuthUsers(store)
+			tc.buildStubs(store)
+
+			svr := newTestServer(t, store)
+			rec := httptest.NewRecorder()
+
+			data, err := json.Marshal(gin.H{"currency": util.USD})
+			require.NoError(t, err)
+			req, err := http.NewRequest(http.MethodPost, "/accounts", bytes.NewReader(data))
+			require.NoError(t, err)
+
+			addAuthorizationHeader(t, req, svr.tokenMaker, authorizationTypeBearer, user.Username, time.Minute)
+			svr.router.ServeHTTP(rec, req)
+			tc.checkResponse(t, rec)
+		})
+	}
+}
+
+func TestGetAccountByNumberAPI(t *testing.T) {
+	user, _ := randomUser(t)
+	acc := randomAccount(user.Username)
+	stranger, _ := randomUser(t)
+	other := randomAccount(stranger.Username)
+
+	// flip the last digit so the check digits no longer match
+	typo := []byte(acc.Number)
+	typo[len(typo)-1] = '0' + (typo[len(typo)-1]-'0'+1)%10
+
+	testCases := []struct {
+		name          string
+		ref           string
+		buildStubs    func(store *mockdb.MockStore)
+		checkResponse func(t *testing.T, rec *httptest.ResponseRecorder)
+	}{
+		{
+			name: "OK",
+			ref:  acc.Number,
+			buildStubs: func(store *mockdb.MockStore) {
+				store.EXPECT().GetAccountByNumber(gomock.Any(), gomock.Eq(acc.Number)).Times(1).Return(acc, nil)
+				store.EXPECT().GetAccount(gomock.Any(), gomock.Eq(acc.ID)).Times(1).Return(acc, nil)
+			},
+			checkResponse: func(t *testing.T, rec *httptest.ResponseRecorder) {
+				require.Equal(t, http.StatusOK, rec.Code)
+				requireBodyMatchAccount(t, rec.Body, acc)
+			},
+		},
+		{
+			name: "Lowercase",
+			ref:  strings.ToLower(acc.Number),
+			buildStubs: func(store *mockdb.MockStore) {
+				store.EXPECT().GetAccountByNumber(gomock.Any(), gomock.Eq(acc.Number)).Times(1).Return(acc, nil)
+				store.EXPECT().GetAccount(gomock.Any(), gomock.Eq(acc.ID)).Times(1).Return(acc, nil)
+			},
+			checkResponse: func(t *testing.T, rec *httptest.ResponseRecorder) {
+				require.Equal(t, http.StatusOK, rec.Code)
+				requireBodyMatchAccount(t, rec.Body, acc)
+			},
+		},
+		{
+			name: "BadCheckDigits",
+			ref:  string(typo),
+			buildStubs: func(store *mockdb.MockStore) {
+				store.EXPECT().GetAccountByNumber(gomock.Any(), gomock.Any()).Times(0)
+				store.EXPECT().GetAccount(gomock.Any(), gomock.Any()).Times(0)
+			},
+			checkResponse: func(t *testing.T, rec *httptest.ResponseRecorder) {
+				require.Equal(t, http.StatusBadRequest, rec.Code)
+			},
+		},
+		{
+			name: "NotFound",
+			ref:  acc.Number,
+			buildStubs: func(store *mockdb.MockStore) {
+				store.EXPECT().GetAccountByNumber(gomock.Any(), gomock.Eq(acc.Number)).Times(1).Return(db.Account{}, sql.ErrNoRows)
+				store.EXPECT().GetAccount(gomock.Any(), gomock.Any()).Times(0)
+			},
+			checkResponse: func(t *testing.T, rec *httptest.ResponseRecorder) {
+				require.Equal(t, http.StatusNotFound, rec.Code)
+			},
+		},
+		{
+			name: "NoAccessLooksNotFound",
+			ref:  other.Number,
+			buildStubs: func(store *mockdb.MockStore) {
+				store.EXPECT().GetAccountByNumber(gomock.Any(), gomock.Eq(other.Number)).Times(1).Return(other, nil)
+				store.EXPECT().GetAccountHolder(gomock.Any(), gomock.Any()).Times(1).Return(db.AccountHolder{}, sql.ErrNoRows)
+				store.EXPECT().GetAccountPermission(gomock.Any(), gomock.Any()).Times(1).Return(db.AccountPermission{}, sql.ErrNoRows)
+				store.EXPECT().GetAccount(gomock.Any(), gomock.Any()).Times(0)
+			},
+			checkResponse: func(t *testing.T, rec *httptest.ResponseRecorder) {
+				require.Equal(t, http.StatusNotFound, rec.Code)
+				require.Contains(t, rec.Body.String(), sql.ErrNoRows.Error())
+			},
+		},
+	}
+
+	for i := range testCases {
+		tc := testCases[i]
+
+		t.Run(tc.name, func(t *testing.T) {
+			ctrl := gomock.NewController(t)
+			defer ctrl.Finish()
+
+			store := mockdb.NewMockStore(ctrl)
+			stubAuthUsers(store)
+			tc.buildStubs(store)
+
+			svr := newTestServer(t, store)
+			rec := httptest.NewRecorder()
+
+			req, err := http.NewRequest(http.MethodGet, "/accounts/"+tc.ref, nil)
+			require.NoError(t, err)
+
+			addAuthorizationHeader(t, req, svr.tokenMaker, authorizationTypeBearer, user.Username, time.Minute)
+			svr.router.ServeHTTP(rec, req)
+			tc.checkResponse(t, rec)
+		})
+	}
+}
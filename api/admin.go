@@ -0,0 +1,131 @@
+package api
+
+import (
+	"database/sql"
+	"errors"
+	db "gobank/db/sqlc"
+	"net/http"
+	"time"
+
+	"github.com/gin-gonic/gin"
+)
+
+func (s *Server) reconcileLedger(ctx *gin.Context) {
+	report, err := s.store.ReconcileLedger(ctx)
+	if err != nil {
+		ctx.JSON(http.StatusInternalServerError, errorResponse(err))
+		return
+	}
+	ctx.JSON(http.StatusOK, report)
+}
+
+type listJobsRequest struct {
+	Status   string `form:"status" binding:"required,oneof=pending running completed dead"`
+	PageID   int32  `form:"page_id" binding:"required,min=1"`
+	PageSize int32  `form:"page_size" binding:"required,min=5,max=50"`
+}
+
+// jobResponse leaves out the payload, which can carry personal data the admin has no need to see.
+type jobResponse struct {
+	ID          int64        `json:"id"`
+	Type        string       `json:"type"`
+	Status      string       `json:"status"`
+	Attempts    int32        `json:"attempts"`
+	MaxAttempts int32        `json:"max_attempts"`
+	LastError   string       `json:"last_error"`
+	RunAt       time.Time    `json:"run_at"`
+	LockedAt    sql.NullTime `json:"locked_at"`
+	CompletedAt sql.NullTime `json:"completed_at"`
+	CreatedAt   time.Time    `json:"created_at"`
+}
+
+func newJobResponse(job db.Job) jobResponse {
+	return jobResponse{
+		ID:          job.ID,
+		Type:        job.Type,
+		Status:      job.Status,
+		Attempts:    job.Attempts,
+		MaxAttempts: job.MaxAttempts,
+		LastError:   job.LastError,
+		RunAt:       job.RunAt,
+		LockedAt:    job.LockedAt,
+		CompletedAt: job.CompletedAt,
+		CreatedAt:   job.CreatedAt,
+	}
+}
+
+func (s *Server) listJobs(ctx *gin.Context) {
+	var req listJobsRequest
+	if err := ctx.ShouldBindQuery(&req); err != nil {
+		ctx.JSON(http.StatusBadRequest, errorResponse(err))
+		return
+	}
+
+	jobs, err := s.store.ListJobsByStatus(ctx, db.ListJobsByStatusParams{
+		Status: req.Status,
+		Limit:  req.PageSize,
+		Offset: (req.PageID - 1) * req.PageSize,
+	})
+	if err != nil {
+		ctx.JSON(http.StatusInternalServerError, errorResponse(err))
+		return
+	}
+	rsp := make([]jobResponse, len(jobs))
+	for i, job := range jobs {
+		rsp[i] = newJobResponse(job)
+	}
+	ctx.JSON(http.StatusOK, rsp)
+}
+
+type retryJobRequest struct {
+	ID int64 `uri:"id" binding:"required,min=1"`
+}
+
+// retryJob gives a dead job a fresh set of attempts once whatever made it fail has been fixed.
+// Jobs that are not dead are reported as not found.
+func (s *Server) retryJob(ctx *gin.Context) {
+	var req retryJobRequest
+	if err := ctx.ShouldBindUri(&req); err != nil {
+		ctx.JSON(http.StatusBadRequest, errorResponse(err))
+		return
+	}
+
+	job, err := s.store.RequeueJob(ctx, req.ID)
+	if err != nil {
+		if err == sql.ErrNoRows {
+			ctx.JSON(http.StatusNotFound, errorResponse(err))
+			return
+		}
+		ctx.JSON(http.StatusInternalServerError, errorResponse(err))
+		return
+	}
+	ctx.JSON(http.StatusOK, newJobResponse(job))
+}
+
+type unlockUserRequest struct {
+	Username string `uri:"username" binding:"required,alphanum"`
+}
+
+// unlockUser lifts a login lockout of a user and clears its failed attempts.
+func (s *Server) unlockUser(ctx *gin.Context) {
+	var req unlockUserRequest
+	if err := ctx.ShouldBindUri(&req); err != nil {
+		ctx.JSON(http.StatusBadRequest, errorResponse(err))
+		return
+	}
+
+	n, err := s.store.DeleteLoginThrottle(ctx, db.DeleteLoginThrottleParams{
+		Scope:   db.LoginScopeUser,
+		Subject: req.Username,
+	})
+	if err != nil {
+		ctx.JSON(http.StatusInternalServerError, errorResponse(err))
+		return
+	}
+	if n == 0 {
+		err := errors.New("user has no failed logins")
+		ctx.JSON(http.StatusNotFound, errorResponse(err))
+		return
+	}
+	ctx.Status(http.StatusNoContent)
+}
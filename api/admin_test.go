@@ -0,0 +1,342 @@
+package api
+
+import (
+	"database/sql"
+	"encoding/json"
+	"fmt"
+	mockdb "gobank/db/mock"
+	db "gobank/db/sqlc"
+	"gobank/token"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/golang/mock/gomock"
+	"github.com/stretchr/testify/require"
+)
+
+func TestReconcileLedgerAPI(t *testing.T) {
+	admin, _ := randomUser(t)
+	user, _ := randomUser(t)
+
+	report := db.ReconciliationReport{
+		CheckedAt: time.Now(),
+		AccountDiscrepancies: []db.AccountDiscrepancy{
+			{AccountID: 1, Balance: 100, EntriesTotal: 90, Difference: 10},
+		},
+		TransferDiscrepancies: []db.TransferDiscrepancy{},
+	}
+
+	testCases := []struct {
+		name          string
+		setupAuth     func(t *testing.T, r *http.Request, tm token.Maker)
+		buildStubs    func(store *mockdb.MockStore)
+		checkResponse func(t *testing.T, rec *httptest.ResponseRecorder)
+	}{
+		{
+			name: "OK",
+			setupAuth: func(t *testing.T, r *http.Request, tm token.Maker) {
+				addAuthorizationHeader(t, r, tm, authorizationTypeBearer, admin.Username, time.Minute)
+			},
+			buildStubs: func(store *mockdb.MockStore) {
+				store.EXPECT().ReconcileLedger(gomock.Any()).Times(1).Return(report, nil)
+			},
+			checkResponse: func(t *testing.T, rec *httptest.ResponseRecorder) {
+				require.Equal(t, http.StatusOK, rec.Code)
+				require.Contains(t, rec.Body.String(), `"account_discrepancies":[{"account_id":1`)
+			},
+		},
+		{
+			name: "NotAdmin",
+			setupAuth: func(t *testing.T, r *http.Request, tm token.Maker) {
+				addAuthorizationHeader(t, r, tm, authorizationTypeBearer, user.Username, time.Minute)
+			},
+			buildStubs: func(store *mockdb.MockStore) {
+				store.EXPECT().ReconcileLedger(gomock.Any()).Times(0)
+			},
+			checkResponse: func(t *testing.T, rec *httptest.ResponseRecorder) {
+				require.Equal(t, http.StatusForbidden, rec.Code)
+			},
+		},
+		{
+			name: "NoAuthorization",
+			setupAuth: func(t *testing.T, r *http.Request, tm token.Maker) {
+			},
+			buildStubs: func(store *mockdb.MockStore) {
+				store.EXPECT().ReconcileLedger(gomock.Any()).Times(0)
+			},
+			checkResponse: func(t *testing.T, rec *httptest.ResponseRecorder) {
+				require.Equal(t, http.StatusUnauthorized, rec.Code)
+			},
+		},
+		{
+			name: "InternalError",
+			setupAuth: func(t *testing.T, r *http.Request, tm token.Maker) {
+				addAuthorizationHeader(t, r, tm, authorizationTypeBearer, admin.Username, time.Minute)
+			},
+			buildStubs: func(store *mockdb.MockStore) {
+				store.EXPECT().ReconcileLedger(gomock.Any()).Times(1).Return(db.ReconciliationReport{}, sql.ErrConnDone)
+			},
+			checkResponse: func(t *testing.T, rec *httptest.ResponseRecorder) {
+				require.Equal(t, http.StatusInternalServerError, rec.Code)
+			},
+		},
+	}
+
+	for i := range testCases {
+		tc := testCases[i]
+
+		t.Run(tc.name, func(t *testing.T) {
+			ctrl := gomock.NewController(t)
+			defer ctrl.Finish()
+
+			store := mockdb.NewMockStore(ctrl)
+			stubAuthUsers(store)
+			tc.buildStubs(store)
+
+			svr := newTestServer(t, store)
+			svr.config.AdminUsernames = []string{admin.Username}
+			rec := httptest.NewRecorder()
+
+			req, err := http.NewRequest(http.MethodGet, "/admin/reconciliation", nil)
+			require.NoError(t, err)
+
+			tc.setupAuth(t, req, svr.tokenMaker)
+			svr.router.ServeHTTP(rec, req)
+			tc.checkResponse(t, rec)
+		})
+	}
+}
+
+func TestListJobsAPI(t *testing.T) {
+	admin, _ := randomUser(t)
+	jobs := []db.Job{
+		{ID: 1, Type: "send_email", Status: "dead", Attempts: 5, MaxAttempts: 5, LastError: "smtp: connection refused", Payload: json.RawMessage(`{"email":"alice@example.com"}`)},
+	}
+
+	testCases := []struct {
+		name          string
+		query         string
+		buildStubs    func(store *mockdb.MockStore)
+		checkResponse func(t *testing.T, rec *httptest.ResponseRecorder)
+	}{
+		{
+			name:  "OK",
+			query: "?status=dead&page_id=2&page_size=5",
+			buildStubs: func(store *mockdb.MockStore) {
+				store.EXPECT().
+					ListJobsByStatus(gomock.Any(), gomock.Eq(db.ListJobsByStatusParams{Status: "dead", Limit: 5, Offset: 5})).
+					Times(1).
+					Return(jobs, nil)
+			},
+			checkResponse: func(t *testing.T, rec *httptest.ResponseRecorder) {
+				require.Equal(t, http.StatusOK, rec.Code)
+				require.Contains(t, rec.Body.String(), `"last_error":"smtp: connection refused"`)
+				require.NotContains(t, rec.Body.String(), "payload")
+				require.NotContains(t, rec.Body.String(), "alice@example.com")
+			},
+		},
+		{
+			name:  "InvalidStatus",
+			query: "?status=lost&page_id=1&page_size=5",
+			buildStubs: func(store *mockdb.MockStore) {
+				store.EXPECT().ListJobsByStatus(gomock.Any(), gomock.Any()).Times(0)
+			},
+			checkResponse: func(t *testing.T, rec *httptest.ResponseRecorder) {
+				require.Equal(t, http.StatusBadRequest, rec.Code)
+			},
+		},
+		{
+			name:  "InternalError",
+			query: "?status=dead&page_id=1&page_size=5",
+			buildStubs: func(store *mockdb.MockStore) {
+				store.EXPECT().ListJobsByStatus(gomock.Any(), gomock.Any()).Times(1).Return(nil, sql.ErrConnDone)
+			},
+			checkResponse: func(t *testing.T, rec *httptest.ResponseRecorder) {
+				require.Equal(t, http.StatusInternalServerError, rec.Code)
+			},
+		},
+	}
+
+	for i := range testCases {
+		tc := testCases[i]
+
+		t.Run(tc.name, func(t *testing.T) {
+			ctrl := gomock.NewController(t)
+			defer ctrl.Finish()
+
+			store := mockdb.NewMockStore(ctrl)
+			stubAuthUsers(store)
+			tc.buildStubs(store)
+
+			svr := newTestServer(t, store)
+			svr.config.AdminUsernames = []string{admin.Username}
+			rec := httptest.NewRecorder()
+
+			req, err := http.NewRequest(http.MethodGet, "/admin/jobs"+tc.query, nil)
+			require.NoError(t, err)
+
+			addAuthorizationHeader(t, req, svr.tokenMaker, authorizationTypeBearer, admin.Username, time.Minute)
+			svr.router.ServeHTTP(rec, req)
+			tc.checkResponse(t, rec)
+		})
+	}
+}
+
+func TestRetryJobAPI(t *testing.T) {
+	admin, _ := randomUser(t)
+	user, _ := randomUser(t)
+	job := db.Job{ID: 7, Type: "send_email", Status: "pending", MaxAttempts: 5}
+
+	testCases := []struct {
+		name          string
+		username      string
+		buildStubs    func(store *mockdb.MockStore)
+		checkResponse func(t *testing.T, rec *httptest.ResponseRecorder)
+	}{
+		{
+			name:     "OK",
+			username: admin.Username,
+			buildStubs: func(store *mockdb.MockStore) {
+				store.EXPECT().RequeueJob(gomock.Any(), gomock.Eq(job.ID)).Times(1).Return(job, nil)
+			},
+			checkResponse: func(t *testing.T, rec *httptest.ResponseRecorder) {
+				require.Equal(t, http.StatusOK, rec.Code)
+				require.Contains(t, rec.Body.String(), `"status":"pending"`)
+			},
+		},
+		{
+			name:     "NotDead",
+			username: admin.Username,
+			buildStubs: func(store *mockdb.MockStore) {
+				store.EXPECT().RequeueJob(gomock.Any(), gomock.Eq(job.ID)).Times(1).Return(db.Job{}, sql.ErrNoRows)
+			},
+			checkResponse: func(t *testing.T, rec *httptest.ResponseRecorder) {
+				require.Equal(t, http.StatusNotFound, rec.Code)
+			},
+		},
+		{
+			name:     "NotAdmin",
+			username: user.Username,
+			buildStubs: func(store *mockdb.MockStore) {
+				store.EXPECT().RequeueJob(gomock.Any(), gomock.Any()).Times(0)
+			},
+			checkResponse: func(t *testing.T, rec *httptest.ResponseRecorder) {
+				require.Equal(t, http.StatusForbidden, rec.Code)
+			},
+		},
+		{
+			name:     "InternalError",
+			username: admin.Username,
+			buildStubs: func(store *mockdb.MockStore) {
+				store.EXPECT().RequeueJob(gomock.Any(), gomock.Any()).Times(1).Return(db.Job{}, sql.ErrConnDone)
+			},
+			checkResponse: func(t *testing.T, rec *httptest.ResponseRecorder) {
+				require.Equal(t, http.StatusInternalServerError, rec.Code)
+			},
+		},
+	}
+
+	for i := range testCases {
+		tc := testCases[i]
+
+		t.Run(tc.name, func(t *testing.T) {
+			ctrl := gomock.NewController(t)
+			defer ctrl.Finish()
+
+			store := mockdb.NewMockStore(ctrl)
+			stubAuthUsers(store)
+			tc.buildStubs(store)
+
+			svr := newTestServer(t, store)
+			svr.config.AdminUsernames = []string{admin.Username}
+			rec := httptest.NewRecorder()
+
+			req, err := http.NewRequest(http.MethodPost, fmt.Sprintf("/admin/jobs/%d/retry", job.ID), nil)
+			require.NoError(t, err)
+
+			addAuthorizationHeader(t, req, svr.tokenMaker, authorizationTypeBearer, tc.username, time.Minute)
+			svr.router.ServeHTTP(rec, req)
+			tc.checkResponse(t, rec)
+		})
+	}
+}
+
+func TestUnlockUserAPI(t *testing.T) {
+	admin, _ := randomUser(t)
+	user, _ := randomUser(t)
+	arg := db.DeleteLoginThrottleParams{Scope: db.LoginScopeUser, Subject: user.Username}
+
+	testCases := []struct {
+		name          string
+		username      string
+		buildStubs    func(store *mockdb.MockStore)
+		checkResponse func(t *testing.T, rec *httptest.ResponseRecorder)
+	}{
+		{
+			name:     "OK",
+			username: admin.Username,
+			buildStubs: func(store *mockdb.MockStore) {
+				store.EXPECT().DeleteLoginThrottle(gomock.Any(), gomock.Eq(arg)).Times(1).Return(int64(1), nil)
+			},
+			checkResponse: func(t *testing.T, rec *httptest.ResponseRecorder) {
+				require.Equal(t, http.StatusNoContent, rec.Code)
+			},
+		},
+		{
+			name:     "NoFailures",
+			username: admin.Username,
+			buildStubs: func(store *mockdb.MockStore) {
+				store.EXPECT().DeleteLoginThrottle(gomock.Any(), gomock.Eq(arg)).Times(1).Return(int64(0), nil)
+			},
+			checkResponse: func(t *testing.T, rec *httptest.ResponseRecorder) {
+				require.Equal(t, http.StatusNotFound, rec.Code)
+			},
+		},
+		{
+			name:     "NotAdmin",
+			username: user.Username,
+			buildStubs: func(store *mockdb.MockStore) {
+				store.EXPECT().DeleteLoginThrottle(gomock.Any(), gomock.Any()).Times(0)
+			},
+			checkResponse: func(t *testing.T, rec *httptest.ResponseRecorder) {
+				require.Equal(t, http.StatusForbidden, rec.Code)
+			},
+		},
+		{
+			name:     "InternalError",
+			username: admin.Username,
+			buildStubs: func(store *mockdb.MockStore) {
+				store.EXPECT().DeleteLoginThrottle(gomock.Any(), gomock.Any()).Times(1).Return(int64(0), sql.ErrConnDone)
+			},
+			checkResponse: func(t *testing.T, rec *httptest.ResponseRecorder) {
+				require.Equal(t, http.StatusInternalServerError, rec.Code)
+			},
+		},
+	}
+
+	for i := range testCases {
+		tc := testCases[i]
+
+		t.Run(tc.name, func(t *testing.T) {
+			ctrl := gomock.NewController(t)
+			defer ctrl.Finish()
+
+			store := mockdb.NewMockStore(ctrl)
+			stubAuthUsers(store)
+			tc.buildStubs(store)
+
+			svr := newTestServer(t, store)
+			svr.config.AdminUsernames = []string{admin.Username}
+			rec := httptest.NewRecorder()
+
+			req, err := http.NewRequest(http.MethodPost, fmt.Sprintf("/admin/users/%s/unlock", user.Username), nil)
+			require.NoError(t, err)
+
+			addAuthorizationHeader(t, req, svr.tokenMaker, authorizationTypeBearer, tc.username, time.Minute)
+			svr.router.ServeHTTP(rec, req)
+			tc.checkResponse(t, rec)
+		})
+	}
+}
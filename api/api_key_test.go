@@ -0,0 +1,528 @@
+package api
+
+import (
+	"bytes"
+	"context"
+	"database/sql"
+	"encoding/json"
+	"fmt"
+	mockdb "gobank/db/mock"
+	db "gobank/db/sqlc"
+	"gobank/token"
+	"gobank/util"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/golang/mock/gomock"
+	"github.com/stretchr/testify/require"
+)
+
+// randomAPIKey returns a stored key of username and the full key to authenticate with.
+func randomAPIKey(t *testing.T, username string, scopes ...string) (db.ApiKey, string) {
+	key, prefix, secret, err := newAPIKey()
+	require.NoError(t, err)
+
+	return db.ApiKey{
+		ID:         util.RandomInt(1, 1000),
+		Username:   username,
+		Name:       "integration",
+		Prefix:     prefix,
+		SecretHash: util.HashToken(secret),
+		Scopes:     scopes,
+		CreatedAt:  time.Now().Add(-time.Hour),
+	}, key
+}
+
+func addAPIKeyHeader(r *http.Request, key string) {
+	r.Header.Set(authorizationHeaderKey, "ApiKey "+key)
+}
+
+func TestParseAPIKey(t *testing.T) {
+	key, prefix, secret, err := newAPIKey()
+	require.NoError(t, err)
+	require.True(t, strings.HasPrefix(key, "gbk_"+prefix+"_"))
+	require.Len(t, prefix, 12)
+
+	gotPrefix, gotSecret, ok := parseAPIKey(key)
+	require.True(t, ok)
+	require.Equal(t, prefix, gotPrefix)
+	require.Equal(t, secret, gotSecret)
+
+	_, _, ok = parseAPIKey("gbk_abc_de_f")
+	require.True(t, ok)
+	for _, bad := range []string{"", "gbk", "gbk_abc", "gbk__secret", "gbk_abc_", "xyz_abc_secret"} {
+		_, _, ok = parseAPIKey(bad)
+		require.False(t, ok, bad)
+	}
+}
+
+func TestAuthMiddlewareAPIKey(t *testing.T) {
+	user, _ := randomUser(t)
+	apiKey, key := randomAPIKey(t, user.Username, token.ScopeAccountsRead)
+
+	testCases := []struct {
+		name       string
+		key        string
+		buildStubs func(store *mockdb.MockStore)
+		status     int
+	}{
+		{
+			name: "OK",
+			key:  key,
+			buildStubs: func(store *mockdb.MockStore) {
+				store.EXPECT().GetAPIKeyByPrefix(gomock.Any(), gomock.Eq(apiKey.Prefix)).Times(1).Return(apiKey, nil)
+				store.EXPECT().GetUser(gomock.Any(), gomock.Eq(user.Username)).Times(1).Return(user, nil)
+				store.EXPECT().TouchAPIKey(gomock.Any(), gomock.Eq(apiKey.ID)).Times(1)
+			},
+			status: http.StatusOK,
+		},
+		{
+			name: "PasswordChangeDoesNotRevoke",
+			key:  key,
+			buildStubs: func(store *mockdb.MockStore) {
+				changed := user
+				changed.PasswordChangedAt = time.Now()
+				store.EXPECT().GetAPIKeyByPrefix(gomock.Any(), gomock.Any()).Times(1).Return(apiKey, nil)
+				store.EXPECT().GetUser(gomock.Any(), gomock.Any()).Times(1).Return(changed, nil)
+				store.EXPECT().TouchAPIKey(gomock.Any(), gomock.Any()).Times(1)
+			},
+			status: http.StatusOK,
+		},
+		{
+			name: "WrongSecret",
+			key:  "gbk_" + apiKey.Prefix + "_wrong",
+			buildStubs: func(store *mockdb.MockStore) {
+				store.EXPECT().GetAPIKeyByPrefix(gomock.Any(), gomock.Any()).Times(1).Return(apiKey, nil)
+				store.EXPECT().GetUser(gomock.Any(), gomock.Any()).Times(0)
+			},
+			status: http.StatusUnauthorized,
+		},
+		{
+			name: "UnknownPrefix",
+			key:  key,
+			buildStubs: func(store *mockdb.MockStore) {
+				store.EXPECT().GetAPIKeyByPrefix(gomock.Any(), gomock.Any()).Times(1).Return(db.ApiKey{}, sql.ErrNoRows)
+			},
+			status: http.StatusUnauthorized,
+		},
+		{
+			name: "Malformed",
+			key:  "not-a-key",
+			buildStubs: func(store *mockdb.MockStore) {
+				store.EXPECT().GetAPIKeyByPrefix(gomock.Any(), gomock.Any()).Times(0)
+			},
+			status: http.StatusUnauthorized,
+		},
+		{
+			name: "Revoked",
+			key:  key,
+			buildStubs: func(store *mockdb.MockStore) {
+				revoked := apiKey
+				revoked.RevokedAt = sql.NullTime{Time: time.Now(), Valid: true}
+				store.EXPECT().GetAPIKeyByPrefix(gomock.Any(), gomock.Any()).Times(1).Return(revoked, nil)
+				store.EXPECT().GetUser(gomock.Any(), gomock.Any()).Times(0)
+			},
+			status: http.StatusUnauthorized,
+		},
+		{
+			name: "Expired",
+			key:  key,
+			buildStubs: func(store *mockdb.MockStore) {
+				expired := apiKey
+				expired.ExpiresAt = sql.NullTime{Time: time.Now().Add(-time.Second), Valid: true}
+				store.EXPECT().GetAPIKeyByPrefix(gomock.Any(), gomock.Any()).Times(1).Return(expired, nil)
+				store.EXPECT().GetUser(gomock.Any(), gomock.Any()).Times(0)
+			},
+			status: http.StatusUnauthorized,
+		},
+		{
+			name: "InternalError",
+			key:  key,
+			buildStubs: func(store *mockdb.MockStore) {
+				store.EXPECT().GetAPIKeyByPrefix(gomock.Any(), gomock.Any()).Times(1).Return(db.ApiKey{}, sql.ErrConnDone)
+			},
+			status: http.StatusInternalServerError,
+		},
+		{
+			name: "TouchError",
+			key:  key,
+			buildStubs: func(store *mockdb.MockStore) {
+				store.EXPECT().GetAPIKeyByPrefix(gomock.Any(), gomock.Any()).Times(1).Return(apiKey, nil)
+				store.EXPECT().GetUser(gomock.Any(), gomock.Any()).Times(1).Return(user, nil)
+				store.EXPECT().TouchAPIKey(gomock.Any(), gomock.Any()).Times(1).Return(sql.ErrConnDone)
+			},
+			status: http.StatusInternalServerError,
+		},
+	}
+
+	for i := range testCases {
+		tc := testCases[i]
+
+		t.Run(tc.name, func(t *testing.T) {
+			ctrl := gomock.NewController(t)
+			defer ctrl.Finish()
+
+			store := mockdb.NewMockStore(ctrl)
+			tc.buildStubs(store)
+
+			server := newTestServer(t, store)
+
+			path := "/auth"
+			server.router.GET(
+				path,
+				authMiddleware(server.tokenMaker, store),
+				func(c *gin.Context) {
+					payload := c.MustGet(authorizationPayloadKey).(*token.Payload)
+					require.Equal(t, user.Username, payload.Username)
+					require.Equal(t, apiKey.Scopes, c.MustGet(authorizationScopesKey))
+					c.JSON(http.StatusOK, gin.H{})
+				},
+			)
+
+			rec := httptest.NewRecorder()
+			req, err := http.NewRequest(http.MethodGet, path, nil)
+			require.NoError(t, err)
+
+			addAPIKeyHeader(req, tc.key)
+			server.router.ServeHTTP(rec, req)
+			require.Equal(t, tc.status, rec.Code)
+		})
+	}
+}
+
+func TestAPIKeyScopes(t *testing.T) {
+	user, _ := randomUser(t)
+	account := randomAccount(user.Username)
+
+	testCases := []struct {
+		name       string
+		method     string
+		url        string
+		scopes     []string
+		useSession bool
+		buildStubs func(store *mockdb.MockStore)
+		status     int
+	}{
+		{
+			name:   "ScopeGranted",
+			method: http.MethodGet,
+			url:    fmt.Sprintf("/accounts/%d", account.ID),
+			scopes: []string{token.ScopeAccountsRead},
+			buildStubs: func(store *mockdb.MockStore) {
+				store.EXPECT().GetAccount(gomock.Any(), gomock.Eq(account.ID)).Times(1).Return(account, nil)
+			},
+			status: http.StatusOK,
+		},
+		{
+			name:   "ScopeMissing",
+			method: http.MethodGet,
+			url:    fmt.Sprintf("/accounts/%d", account.ID),
+			scopes: []string{token.ScopeTransfersWrite},
+			buildStubs: func(store *mockdb.MockStore) {
+				store.EXPECT().GetAccount(gomock.Any(), gomock.Any()).Times(0)
+			},
+			status: http.StatusForbidden,
+		},
+		{
+			name:       "SessionNeedsNoScope",
+			method:     http.MethodGet,
+			url:        fmt.Sprintf("/accounts/%d", account.ID),
+			useSession: true,
+			buildStubs: func(store *mockdb.MockStore) {
+				store.EXPECT().GetAccount(gomock.Any(), gomock.Eq(account.ID)).Times(1).Return(account, nil)
+			},
+			status: http.StatusOK,
+		},
+		{
+			name:   "CannotManageAPIKeys",
+			method: http.MethodGet,
+			url:    "/api_keys",
+			scopes: []string{token.ScopeAccountsRead, token.ScopeAccountsWrite, token.ScopeTransfersWrite, token.ScopeWebhooks},
+			buildStubs: func(store *mockdb.MockStore) {
+				store.EXPECT().ListAPIKeys(gomock.Any(), gomock.Any()).Times(0)
+			},
+			status: http.StatusForbidden,
+		},
+		{
+			name:   "CannotUseAdminRoutes",
+			method: http.MethodGet,
+			url:    "/admin/jobs?status=dead&page_id=1&page_size=5",
+			scopes: []string{token.ScopeAccountsRead},
+			buildStubs: func(store *mockdb.MockStore) {
+				store.EXPECT().ListJobsByStatus(gomock.Any(), gomock.Any()).Times(0)
+			},
+			status: http.StatusForbidden,
+		},
+	}
+
+	for i := range testCases {
+		tc := testCases[i]
+
+		t.Run(tc.name, func(t *testing.T) {
+			ctrl := gomock.NewController(t)
+			defer ctrl.Finish()
+
+			store := mockdb.NewMockStore(ctrl)
+			stubAuthUsers(store)
+			apiKey, key := randomAPIKey(t, user.Username, tc.scopes...)
+			store.EXPECT().GetAPIKeyByPrefix(gomock.Any(), gomock.Eq(apiKey.Prefix)).AnyTimes().Return(apiKey, nil)
+			store.EXPECT().TouchAPIKey(gomock.Any(), gomock.Any()).AnyTimes()
+			tc.buildStubs(store)
+
+			server := newTestServer(t, store)
+			server.config.AdminUsernames = []string{user.Username}
+			rec := httptest.NewRecorder()
+
+			req, err := http.NewRequest(tc.method, tc.url, nil)
+			require.NoError(t, err)
+
+			if tc.useSession {
+				addAuthorizationHeader(t, req, server.tokenMaker, authorizationTypeBearer, user.Username, time.Minute)
+			} else {
+				addAPIKeyHeader(req, key)
+			}
+			server.router.ServeHTTP(rec, req)
+			require.Equal(t, tc.status, rec.Code)
+		})
+	}
+}
+
+func TestCreateAPIKeyAPI(t *testing.T) {
+	user, _ := randomUser(t)
+
+	testCases := []struct {
+		name          string
+		body          gin.H
+		buildStubs    func(store *mockdb.MockStore)
+		checkResponse func(t *testing.T, rec *httptest.ResponseRecorder)
+	}{
+		{
+			name: "OK",
+			body: gin.H{"name": "payroll", "scopes": []string{token.ScopeAccountsRead, token.ScopeTransfersWrite}},
+			buildStubs: func(store *mockdb.MockStore) {
+				store.EXPECT().CreateAPIKey(gomock.Any(), gomock.Any()).Times(1).
+					DoAndReturn(func(_ context.Context, arg db.CreateAPIKeyParams) (db.ApiKey, error) {
+						require.Equal(t, user.Username, arg.Username)
+						require.Equal(t, "payroll", arg.Name)
+						require.Equal(t, []string{token.ScopeAccountsRead, token.ScopeTransfersWrite}, arg.Scopes)
+						require.False(t, arg.ExpiresAt.Valid)
+						return db.ApiKey{ID: 1, Username: arg.Username, Name: arg.Name, Prefix: arg.Prefix,
+							SecretHash: arg.SecretHash, Scopes: arg.Scopes, CreatedAt: time.Now()}, nil
+					})
+			},
+			checkResponse: func(t *testing.T, rec *httptest.ResponseRecorder) {
+				require.Equal(t, http.StatusOK, rec.Code)
+				require.NotContains(t, rec.Body.String(), "secret_hash")
+
+				var rsp createAPIKeyResponse
+				require.NoError(t, json.Unmarshal(rec.Body.Bytes(), &rsp))
+				prefix, _, ok := parseAPIKey(rsp.Key)
+				require.True(t, ok)
+				require.Equal(t, rsp.Prefix, prefix)
+				require.Nil(t, rsp.ExpiresAt)
+			},
+		},
+		{
+			name: "WithExpiry",
+			body: gin.H{"name": "ci", "scopes": []string{token.ScopeAccountsRead}, "expires_at": time.Now().Add(24 * time.Hour)},
+			buildStubs: func(store *mockdb.MockStore) {
+				store.EXPECT().CreateAPIKey(gomock.Any(), gomock.Any()).Times(1).
+					DoAndReturn(func(_ context.Context, arg db.CreateAPIKeyParams) (db.ApiKey, error) {
+						require.True(t, arg.ExpiresAt.Valid)
+						require.WithinDuration(t, time.Now().Add(24*time.Hour), arg.ExpiresAt.Time, time.Minute)
+						return db.ApiKey{ID: 1, Scopes: arg.Scopes, ExpiresAt: arg.ExpiresAt}, nil
+					})
+			},
+			checkResponse: func(t *testing.T, rec *httptest.ResponseRecorder) {
+				require.Equal(t, http.StatusOK, rec.Code)
+				require.Contains(t, rec.Body.String(), `"expires_at":"`)
+			},
+		},
+		{
+			name: "ExpiryInThePast",
+			body: gin.H{"name": "ci", "scopes": []string{token.ScopeAccountsRead}, "expires_at": time.Now().Add(-time.Hour)},
+			buildStubs: func(store *mockdb.MockStore) {
+				store.EXPECT().CreateAPIKey(gomock.Any(), gomock.Any()).Times(0)
+			},
+			checkResponse: func(t *testing.T, rec *httptest.ResponseRecorder) {
+				require.Equal(t, http.StatusBadRequest, rec.Code)
+			},
+		},
+		{
+			name: "UnknownScope",
+			body: gin.H{"name": "ci", "scopes": []string{"everything"}},
+			buildStubs: func(store *mockdb.MockStore) {
+				store.EXPECT().CreateAPIKey(gomock.Any(), gomock.Any()).Times(0)
+			},
+			checkResponse: func(t *testing.T, rec *httptest.ResponseRecorder) {
+				require.Equal(t, http.StatusBadRequest, rec.Code)
+			},
+		},
+		{
+			name: "NoScopes",
+			body: gin.H{"name": "ci", "scopes": []string{}},
+			buildStubs: func(store *mockdb.MockStore) {
+				store.EXPECT().CreateAPIKey(gomock.Any(), gomock.Any()).Times(0)
+			},
+			checkResponse: func(t *testing.T, rec *httptest.ResponseRecorder) {
+				require.Equal(t, http.StatusBadRequest, rec.Code)
+			},
+		},
+		{
+			name: "InternalError",
+			body: gin.H{"name": "ci", "scopes": []string{token.ScopeAccountsRead}},
+			buildStubs: func(store *mockdb.MockStore) {
+				store.EXPECT().CreateAPIKey(gomock.Any(), gomock.Any()).Times(1).Return(db.ApiKey{}, sql.ErrConnDone)
+			},
+			checkResponse: func(t *testing.T, rec *httptest.ResponseRecorder) {
+				require.Equal(t, http.StatusInternalServerError, rec.Code)
+			},
+		},
+	}
+
+	for i := range testCases {
+		tc := testCases[i]
+
+		t.Run(tc.name, func(t *testing.T) {
+			ctrl := gomock.NewController(t)
+			defer ctrl.Finish()
+
+			store := mockdb.NewMockStore(ctrl)
+			stubAuthUsers(store)
+			tc.buildStubs(store)
+
+			svr := newTestServer(t, store)
+			rec := httptest.NewRecorder()
+
+			data, err := json.Marshal(tc.body)
+			require.NoError(t, err)
+
+			req, err := http.NewRequest(http.MethodPost, "/api_keys", bytes.NewReader(data))
+			require.NoError(t, err)
+
+			addAuthorizationHeader(t, req, svr.tokenMaker, authorizationTypeBearer, user.Username, time.Minute)
+			svr.router.ServeHTTP(rec, req)
+			tc.checkResponse(t, rec)
+		})
+	}
+}
+
+func TestListAPIKeysAPI(t *testing.T) {
+	user, _ := randomUser(t)
+	k1, _ := randomAPIKey(t, user.Username, token.ScopeAccountsRead)
+	k2, _ := randomAPIKey(t, user.Username, token.ScopeWebhooks)
+	k2.LastUsedAt = sql.NullTime{Time: time.Now(), Valid: true}
+
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	store := mockdb.NewMockStore(ctrl)
+	stubAuthUsers(store)
+	store.EXPECT().ListAPIKeys(gomock.Any(), gomock.Eq(user.Username)).Times(1).Return([]db.ApiKey{k1, k2}, nil)
+
+	svr := newTestServer(t, store)
+	rec := httptest.NewRecorder()
+
+	req, err := http.NewRequest(http.MethodGet, "/api_keys", nil)
+	require.NoError(t, err)
+
+	addAuthorizationHeader(t, req, svr.tokenMaker, authorizationTypeBearer, user.Username, time.Minute)
+	svr.router.ServeHTTP(rec, req)
+	require.Equal(t, http.StatusOK, rec.Code)
+	require.NotContains(t, rec.Body.String(), "secret_hash")
+	require.NotContains(t, rec.Body.String(), k1.SecretHash)
+
+	var rsp []apiKeyResponse
+	require.NoError(t, json.Unmarshal(rec.Body.Bytes(), &rsp))
+	require.Len(t, rsp, 2)
+	require.Equal(t, k1.Prefix, rsp[0].Prefix)
+	require.Nil(t, rsp[0].LastUsedAt)
+	require.NotNil(t, rsp[1].LastUsedAt)
+}
+
+func TestRevokeAPIKeyAPI(t *testing.T) {
+	user, _ := randomUser(t)
+	other, _ := randomUser(t)
+	apiKey, _ := randomAPIKey(t, user.Username, token.ScopeAccountsRead)
+
+	testCases := []struct {
+		name          string
+		username      string
+		buildStubs    func(store *mockdb.MockStore)
+		checkResponse func(t *testing.T, rec *httptest.ResponseRecorder)
+	}{
+		{
+			name:     "OK",
+			username: user.Username,
+			buildStubs: func(store *mockdb.MockStore) {
+				revoked := apiKey
+				revoked.RevokedAt = sql.NullTime{Time: time.Now(), Valid: true}
+				store.EXPECT().GetAPIKey(gomock.Any(), gomock.Eq(apiKey.ID)).Times(1).Return(apiKey, nil)
+				store.EXPECT().RevokeAPIKey(gomock.Any(), gomock.Eq(apiKey.ID)).Times(1).Return(revoked, nil)
+			},
+			checkResponse: func(t *testing.T, rec *httptest.ResponseRecorder) {
+				require.Equal(t, http.StatusOK, rec.Code)
+				require.Contains(t, rec.Body.String(), `"revoked_at":"`)
+			},
+		},
+		{
+			name:     "OtherUsersKey",
+			username: other.Username,
+			buildStubs: func(store *mockdb.MockStore) {
+				store.EXPECT().GetAPIKey(gomock.Any(), gomock.Eq(apiKey.ID)).Times(1).Return(apiKey, nil)
+				store.EXPECT().RevokeAPIKey(gomock.Any(), gomock.Any()).Times(0)
+			},
+			checkResponse: func(t *testing.T, rec *httptest.ResponseRecorder) {
+				require.Equal(t, http.StatusUnauthorized, rec.Code)
+			},
+		},
+		{
+			name:     "NotFound",
+			username: user.Username,
+			buildStubs: func(store *mockdb.MockStore) {
+				store.EXPECT().GetAPIKey(gomock.Any(), gomock.Any()).Times(1).Return(db.ApiKey{}, sql.ErrNoRows)
+			},
+			checkResponse: func(t *testing.T, rec *httptest.ResponseRecorder) {
+				require.Equal(t, http.StatusNotFound, rec.Code)
+			},
+		},
+		{
+			name:     "AlreadyRevoked",
+			username: user.Username,
+			buildStubs: func(store *mockdb.MockStore) {
+				store.EXPECT().GetAPIKey(gomock.Any(), gomock.Any()).Times(1).Return(apiKey, nil)
+				store.EXPECT().RevokeAPIKey(gomock.Any(), gomock.Any()).Times(1).Return(db.ApiKey{}, sql.ErrNoRows)
+			},
+			checkResponse: func(t *testing.T, rec *httptest.ResponseRecorder) {
+				require.Equal(t, http.StatusNotFound, rec.Code)
+			},
+		},
+	}
+
+	for i := range testCases {
+		tc := testCases[i]
+
+		t.Run(tc.name, func(t *testing.T) {
+			ctrl := gomock.NewController(t)
+			defer ctrl.Finish()
+
+			store := mockdb.NewMockStore(ctrl)
+			stubAuthUsers(store)
+			tc.buildStubs(store)
+
+			svr := newTestServer(t, store)
+			rec := httptest.NewRecorder()
+
+			req, err := http.NewRequest(http.MethodDelete, fmt.Sprintf("/api_keys/%d", apiKey.ID), nil)
+			require.NoError(t, err)
+
+			addAuthorizationHeader(t, req, svr.tokenMaker, authorizationTypeBearer, tc.username, time.Minute)
+			svr.router.ServeHTTP(rec, req)
+			tc.checkResponse(t, rec)
+		})
+	}
+}
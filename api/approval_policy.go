@@ -0,0 +1,101 @@
+package api
+
+import (
+	"database/sql"
+	db "gobank/db/sqlc"
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+)
+
+// Approval policies set up maker-checker on an account: transfers of at least min_amount wait as a draft
+// until required_approvals users other than the one who made them approved. Of the policies of an account,
+// the one with the highest min_amount not above the amount applies.
+
+type setApprovalPolicyRequest struct {
+	MinAmount         int64 `json:"min_amount" binding:"min=0"`
+	RequiredApprovals int32 `json:"required_approvals" binding:"required,min=1,max=10"`
+}
+
+// setApprovalPolicy adds a policy to an account of the authenticated user, or replaces the one with the
+// same min_amount.
+func (s *Server) setApprovalPolicy(ctx *gin.Context) {
+	var uri getAccountRequest
+	if err := ctx.ShouldBindUri(&uri); err != nil {
+		ctx.JSON(http.StatusBadRequest, errorResponse(err))
+		return
+	}
+	var req setApprovalPolicyRequest
+	if err := ctx.ShouldBindJSON(&req); err != nil {
+		ctx.JSON(http.StatusBadRequest, errorResponse(err))
+		return
+	}
+	acc, ok := s.ownedAccount(ctx, uri.ID)
+	if !ok {
+		return
+	}
+
+	policy, err := s.store.UpsertApprovalPolicy(ctx, db.UpsertApprovalPolicyParams{
+		AccountID:         acc.ID,
+		MinAmount:         req.MinAmount,
+		RequiredApprovals: req.RequiredApprovals,
+	})
+	if err != nil {
+		ctx.JSON(http.StatusInternalServerError, errorResponse(err))
+		return
+	}
+	ctx.JSON(http.StatusOK, policy)
+}
+
+// listApprovalPolicies shows the policies of an account to anyone who can see it, lowest min_amount first.
+func (s *Server) listApprovalPolicies(ctx *gin.Context) {
+	var uri getAccountRequest
+	if err := ctx.ShouldBindUri(&uri); err != nil {
+		ctx.JSON(http.StatusBadRequest, errorResponse(err))
+		return
+	}
+	acc, ok := s.accountAccess(ctx, uri.ID, accessRead)
+	if !ok {
+		return
+	}
+
+	policies, err := s.store.ListApprovalPolicies(ctx, acc.ID)
+	if err != nil {
+		ctx.JSON(http.StatusInternalServerError, errorResponse(err))
+		return
+	}
+	ctx.JSON(http.StatusOK, policies)
+}
+
+type deleteApprovalPolicyRequest struct {
+	ID       int64 `uri:"id" binding:"required,min=1"`
+	PolicyID int64 `uri:"policy_id" binding:"required,min=1"`
+}
+
+// deleteApprovalPolicy removes a policy from an account of the authenticated user. Pending drafts keep
+// the approvals they were created with.
+func (s *Server) deleteApprovalPolicy(ctx *gin.Context) {
+	var req deleteApprovalPolicyRequest
+	if err := ctx.ShouldBindUri(&req); err != nil {
+		ctx.JSON(http.StatusBadRequest, errorResponse(err))
+		return
+	}
+	acc, ok := s.ownedAccount(ctx, req.ID)
+	if !ok {
+		return
+	}
+
+	n, err := s.store.DeleteApprovalPolicy(ctx, db.DeleteApprovalPolicyParams{
+		ID:        req.PolicyID,
+		AccountID: acc.ID,
+	})
+	if err != nil {
+		ctx.JSON(http.StatusInternalServerError, errorResponse(err))
+		return
+	}
+	if n == 0 {
+		ctx.JSON(http.StatusNotFound, errorResponse(sql.ErrNoRows))
+		return
+	}
+	ctx.Status(http.StatusNoContent)
+}
@@ -0,0 +1,324 @@
+package api
+
+import (
+	"database/sql"
+	"errors"
+	"fmt"
+	"gobank/batch"
+	db "gobank/db/sqlc"
+	"gobank/token"
+	"gobank/util"
+	"net/http"
+	"unicode/utf8"
+
+	"github.com/gin-gonic/gin"
+	"github.com/lib/pq"
+)
+
+const maxPaymentFileSize = 10 << 20
+
+// maxMemoLength is the longest memo POST /transfers takes, which payment files are held to as well.
+const maxMemoLength = 140
+
+const (
+	paymentStatusCompleted       = "completed"
+	paymentStatusFailed          = "failed"
+	paymentStatusPartiallyFailed = "partially_failed"
+)
+
+type createPaymentBatchRequest struct {
+	Format string `form:"format" binding:"omitempty,oneof=csv pain001"`
+	DryRun bool   `form:"dry_run"`
+}
+
+// paymentBatchReport is what a client sees before (dry run) or instead of (invalid file) executing a batch.
+type paymentBatchReport struct {
+	Format       batch.Format             `json:"format"`
+	MessageID    string                   `json:"message_id,omitempty"`
+	DryRun       bool                     `json:"dry_run"`
+	ItemCount    int                      `json:"item_count"`
+	InvalidCount int                      `json:"invalid_count"`
+	Totals       map[string]int64         `json:"totals"`
+	Items        []paymentBatchReportItem `json:"items"`
+}
+
+type paymentBatchReportItem struct {
+	batch.Instruction
+	Valid bool   `json:"valid"`
+	Error string `json:"error,omitempty"`
+}
+
+type paymentBatchResponse struct {
+	Batch db.PaymentBatch       `json:"batch"`
+	Items []db.PaymentBatchItem `json:"items"`
+}
+
+func (s *Server) createPaymentBatch(ctx *gin.Context) {
+	var req createPaymentBatchRequest
+	if err := ctx.ShouldBind(&req); err != nil {
+		ctx.JSON(http.StatusBadRequest, errorResponse(err))
+		return
+	}
+	fh, err := ctx.FormFile("file")
+	if err != nil {
+		ctx.JSON(http.StatusBadRequest, errorResponse(err))
+		return
+	}
+	if fh.Size > maxPaymentFileSize {
+		err := fmt.Errorf("payment file is larger than %d bytes", maxPaymentFileSize)
+		ctx.JSON(http.StatusRequestEntityTooLarge, errorResponse(err))
+		return
+	}
+	f, err := fh.Open()
+	if err != nil {
+		ctx.JSON(http.StatusInternalServerError, errorResponse(err))
+		return
+	}
+	defer f.Close()
+
+	file, err := batch.Parse(f, batch.Format(req.Format))
+	if err != nil {
+		ctx.JSON(http.StatusBadRequest, errorResponse(err))
+		return
+	}
+	instructions := file.Instructions
+
+	payload := ctx.MustGet(authorizationPayloadKey).(*token.Payload)
+
+	report := paymentBatchReport{
+		Format:    file.Format,
+		MessageID: file.MessageID,
+		DryRun:    req.DryRun,
+		ItemCount: len(instructions),
+		Totals:    map[string]int64{},
+		Items:     make([]paymentBatchReportItem, 0, len(instructions)),
+	}
+	for i := range instructions {
+		problem, err := s.checkInstruction(ctx, &instructions[i])
+		in := instructions[i]
+		item := paymentBatchReportItem{Instruction: in, Valid: true}
+		if err != nil {
+			ctx.JSON(http.StatusInternalServerError, errorResponse(err))
+			return
+		}
+		if problem != "" {
+			item.Valid = false
+			item.Error = problem
+			report.InvalidCount++
+		} else {
+			report.Totals[in.Currency] += in.Amount
+		}
+		report.Items = append(report.Items, item)
+	}
+
+	if req.DryRun {
+		ctx.JSON(http.StatusOK, report)
+		return
+	}
+	//a file is executed all or nothing at validation time, so clients never have to work out which lines went through
+	if report.InvalidCount > 0 {
+		ctx.JSON(http.StatusUnprocessableEntity, report)
+		return
+	}
+	//splitting a large payment over many lines must not get around step-up, so the totals are what count
+	for currency, total := range report.Totals {
+		if !s.checkStepUp(ctx, currency, total) {
+			return
+		}
+	}
+
+	args := db.CreatePaymentBatchTxParams{
+		Owner:        payload.Username,
+		SourceFormat: string(file.Format),
+		MessageID:    file.MessageID,
+		Items:        make([]db.CreatePaymentBatchItemParams, 0, len(instructions)),
+	}
+	for _, in := range instructions {
+		args.Items = append(args.Items, db.CreatePaymentBatchItemParams{
+			Line:          int32(in.Line),
+			FromAccountID: in.FromAccountID,
+			ToAccountID:   in.ToAccountID,
+			Amount:        in.Amount,
+			Currency:      in.Currency,
+			Memo:          in.Memo,
+			EndToEndID:    in.EndToEndID,
+		})
+	}
+	created, err := s.store.CreatePaymentBatchTx(ctx, args)
+	if err != nil {
+		if pqErr, ok := err.(*pq.Error); ok && pqErr.Code.Name() == "unique_violation" && pqErr.Constraint == "payment_batches_owner_message_id_key" {
+			err := fmt.Errorf("payment file %s was already uploaded", file.MessageID)
+			ctx.JSON(http.StatusConflict, errorResponse(err))
+			return
+		}
+		ctx.JSON(http.StatusInternalServerError, errorResponse(err))
+		return
+	}
+
+	res, err := s.executePaymentBatch(ctx, created)
+	if err != nil {
+		ctx.JSON(http.StatusInternalServerError, errorResponse(err))
+		return
+	}
+	ctx.JSON(http.StatusOK, res)
+}
+
+// resolveInstructionNumbers is resolveAccountNumbers for payment batches. A from account the user cannot pay
+// from is reported like a number nobody holds, as for single transfers.
+func (s *Server) resolveInstructionNumbers(ctx *gin.Context, in *batch.Instruction) (string, error) {
+	if in.FromAccountNumber != "" {
+		from, err := s.store.GetAccountByNumber(ctx, in.FromAccountNumber)
+		if err == nil {
+			var ok bool
+			ok, err = s.hasAccountAccess(ctx, from, accessTransfer)
+			if err == nil && !ok {
+				err = sql.ErrNoRows
+			}
+		}
+		if err == sql.ErrNoRows {
+			return fmt.Sprintf("account number %s not found", in.FromAccountNumber), nil
+		}
+		if err != nil {
+			return "", err
+		}
+		in.FromAccountID = from.ID
+	}
+	if in.ToAccountNumber != "" {
+		to, err := s.store.GetAccountByNumber(ctx, in.ToAccountNumber)
+		if err == sql.ErrNoRows {
+			return fmt.Sprintf("account number %s not found", in.ToAccountNumber), nil
+		}
+		if err != nil {
+			return "", err
+		}
+		in.ToAccountID = to.ID
+	}
+	return "", nil
+}
+
+// checkInstruction applies the same account rules as a single transfer, filling in the accounts of an
+// instruction given by account number. It returns why the instruction cannot be executed, or an error when
+// the store itself could not be queried.
+func (s *Server) checkInstruction(ctx *gin.Context, in *batch.Instruction) (string, error) {
+	if !util.IsSupportedCurrency(in.Currency) {
+		return fmt.Sprintf("unsupported currency %s", in.Currency), nil
+	}
+	if utf8.RuneCountInString(in.Memo) > maxMemoLength {
+		return fmt.Sprintf("memo is longer than %d characters", maxMemoLength), nil
+	}
+	if problem, err := s.resolveInstructionNumbers(ctx, in); problem != "" || err != nil {
+		return problem, err
+	}
+	if in.FromAccountID == in.ToAccountID {
+		return "from and to account must differ", nil
+	}
+
+	from, status, err := s.checkAccount(ctx, in.FromAccountID, in.Currency)
+	if status == http.StatusInternalServerError {
+		return "", err
+	}
+	if err != nil {
+		return err.Error(), nil
+	}
+	ok, err := s.hasAccountAccess(ctx, from, accessTransfer)
+	if err != nil {
+		return "", err
+	}
+	if !ok {
+		return fmt.Sprintf("account [%d] doesn't belong to authenticated user", from.ID), nil
+	}
+	required, err := s.countRequiredApprovals(ctx, from, in.Amount)
+	if err != nil {
+		return "", err
+	}
+	if required > 1 {
+		return fmt.Sprintf("transfers of this amount from account [%d] need approval, make it on its own", from.ID), nil
+	}
+
+	to, status, err := s.checkAccount(ctx, in.ToAccountID, in.Currency)
+	if status == http.StatusInternalServerError {
+		return "", err
+	}
+	if err != nil {
+		return err.Error(), nil
+	}
+	return s.payeeCoolingOffProblem(ctx, to, in.Amount)
+}
+
+// executePaymentBatch runs each item as its own transfer and records the outcome per item and for the batch.
+func (s *Server) executePaymentBatch(ctx *gin.Context, created db.PaymentBatchTxResult) (paymentBatchResponse, error) {
+	res := paymentBatchResponse{Items: make([]db.PaymentBatchItem, 0, len(created.Items))}
+
+	failed := 0
+	for _, item := range created.Items {
+		update := db.UpdatePaymentBatchItemParams{ID: item.ID, Status: paymentStatusCompleted}
+		result, err := s.store.TransferTx(ctx, db.TransferTxParams{
+			FromAccountID: item.FromAccountID,
+			ToAccountID:   item.ToAccountID,
+			Amount:        item.Amount,
+			Memo:          item.Memo,
+		})
+		if err != nil {
+			update.Status = paymentStatusFailed
+			update.Error = err.Error()
+			failed++
+		} else {
+			update.TransferID = sql.NullInt64{Int64: result.Transfer.ID, Valid: true}
+		}
+
+		item, err = s.store.UpdatePaymentBatchItem(ctx, update)
+		if err != nil {
+			return res, err
+		}
+		res.Items = append(res.Items, item)
+	}
+
+	status := paymentStatusCompleted
+	switch {
+	case failed == len(created.Items):
+		status = paymentStatusFailed
+	case failed > 0:
+		status = paymentStatusPartiallyFailed
+	}
+	var err error
+	res.Batch, err = s.store.UpdatePaymentBatchStatus(ctx, db.UpdatePaymentBatchStatusParams{
+		ID:     created.Batch.ID,
+		Status: status,
+	})
+	return res, err
+}
+
+type getPaymentBatchRequest struct {
+	ID int64 `uri:"id" binding:"required,min=1"`
+}
+
+func (s *Server) getPaymentBatch(ctx *gin.Context) {
+	var req getPaymentBatchRequest
+	if err := ctx.ShouldBindUri(&req); err != nil {
+		ctx.JSON(http.StatusBadRequest, errorResponse(err))
+		return
+	}
+	b, err := s.store.GetPaymentBatch(ctx, req.ID)
+	if err != nil {
+		if err == sql.ErrNoRows {
+			ctx.JSON(http.StatusNotFound, errorResponse(err))
+			return
+		}
+		ctx.JSON(http.StatusInternalServerError, errorResponse(err))
+		return
+	}
+
+	payload := ctx.MustGet(authorizationPayloadKey).(*token.Payload)
+	if payload.Username != b.Owner {
+		err := errors.New("payment batch doesn't belong to authenticated user")
+		ctx.JSON(http.StatusUnauthorized, errorResponse(err))
+		return
+	}
+
+	items, err := s.store.ListPaymentBatchItems(ctx, b.ID)
+	if err != nil {
+		ctx.JSON(http.StatusInternalServerError, errorResponse(err))
+		return
+	}
+	ctx.JSON(http.StatusOK, paymentBatchResponse{Batch: b, Items: items})
+}
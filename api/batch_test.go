@@ -0,0 +1,552 @@
+package api
+
+import (
+	"bytes"
+	"database/sql"
+	"encoding/json"
+	"fmt"
+	mockdb "gobank/db/mock"
+	db "gobank/db/sqlc"
+	"gobank/token"
+	"gobank/util"
+	"mime/multipart"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/golang/mock/gomock"
+	"github.com/lib/pq"
+	"github.com/stretchr/testify/require"
+)
+
+func paymentFileRequest(t *testing.T, file string, fields map[string]string) *http.Request {
+	var body bytes.Buffer
+	w := multipart.NewWriter(&body)
+	for k, v := range fields {
+		require.NoError(t, w.WriteField(k, v))
+	}
+	part, err := w.CreateFormFile("file", "payments.csv")
+	require.NoError(t, err)
+	_, err = part.Write([]byte(file))
+	require.NoError(t, err)
+	require.NoError(t, w.Close())
+
+	req, err := http.NewRequest(http.MethodPost, "/transfers/batches", &body)
+	require.NoError(t, err)
+	req.Header.Set("Content-Type", w.FormDataContentType())
+	return req
+}
+
+func TestCreatePaymentBatchAPI(t *testing.T) {
+	user, _ := randomUser(t)
+	other, _ := randomUser(t)
+
+	from := randomAccount(user.Username)
+	from.Currency = util.USD
+	to := randomAccount(other.Username)
+	to.ID = from.ID + 1
+	to.Currency = util.USD
+
+	file := fmt.Sprintf("from_account_id,to_account_id,amount,currency,memo\n%d,%d,100,USD,rent\n", from.ID, to.ID)
+	reverse := fmt.Sprintf("from_account_id,to_account_id,amount,currency,memo\n%d,%d,100,USD,rent\n", to.ID, from.ID)
+	byNumber := fmt.Sprintf("from_account_id,to_account_number,amount,currency,memo\n%d,%s,100,USD,rent\n", from.ID, strings.ToLower(to.Number))
+	fromOthers := fmt.Sprintf("from_account_number,to_account_id,amount,currency,memo\n%s,%d,100,USD,rent\n", to.Number, from.ID)
+	longMemo := fmt.Sprintf("from_account_id,to_account_id,amount,currency,memo\n%d,%d,100,USD,%s\n", from.ID, to.ID, strings.Repeat("x", 141))
+	pain := fmt.Sprintf(`<Document><CstmrCdtTrfInitn><GrpHdr><MsgId>PAYROLL-1</MsgId><NbOfTxs>1</NbOfTxs><CtrlSum>100</CtrlSum></GrpHdr>
+		<PmtInf><DbtrAcct><Id><Othr><Id>%d</Id></Othr></Id></DbtrAcct>
+		<CdtTrfTxInf><Amt><InstdAmt Ccy="USD">100</InstdAmt></Amt><CdtrAcct><Id><Othr><Id>%d</Id></Othr></Id></CdtrAcct></CdtTrfTxInf>
+		</PmtInf></CstmrCdtTrfInitn></Document>`, from.ID, to.ID)
+	large := fmt.Sprintf("from_account_id,to_account_id,amount,currency,memo\n%d,%d,600,USD,rent\n", from.ID, to.ID)
+
+	stubAccounts := func(store *mockdb.MockStore) {
+		store.EXPECT().GetAccount(gomock.Any(), gomock.Eq(from.ID)).AnyTimes().Return(from, nil)
+		store.EXPECT().GetAccount(gomock.Any(), gomock.Eq(to.ID)).AnyTimes().Return(to, nil)
+		store.EXPECT().GetApprovalPolicyForAmount(gomock.Any(), gomock.Any()).AnyTimes().Return(db.ApprovalPolicy{}, sql.ErrNoRows)
+	}
+
+	batch := db.PaymentBatch{ID: 7, Owner: user.Username, SourceFormat: "csv", Status: "pending", ItemCount: 1}
+	item := db.PaymentBatchItem{ID: 70, BatchID: batch.ID, Line: 2, FromAccountID: from.ID, ToAccountID: to.ID, Amount: 100, Currency: util.USD, Memo: "rent", Status: "pending"}
+
+	testCases := []struct {
+		name          string
+		file          string
+		fields        map[string]string
+		setupAuth     func(t *testing.T, r *http.Request, tm token.Maker)
+		buildStubs    func(store *mockdb.MockStore)
+		checkResponse func(t *testing.T, rec *httptest.ResponseRecorder)
+	}{
+		{
+			name:   "DryRun",
+			file:   file,
+			fields: map[string]string{"dry_run": "true"},
+			setupAuth: func(t *testing.T, r *http.Request, tm token.Maker) {
+				addAuthorizationHeader(t, r, tm, authorizationTypeBearer, user.Username, time.Minute)
+			},
+			buildStubs: func(store *mockdb.MockStore) {
+				stubAccounts(store)
+				store.EXPECT().CreatePaymentBatchTx(gomock.Any(), gomock.Any()).Times(0)
+				store.EXPECT().TransferTx(gomock.Any(), gomock.Any()).Times(0)
+			},
+			checkResponse: func(t *testing.T, rec *httptest.ResponseRecorder) {
+				require.Equal(t, http.StatusOK, rec.Code)
+
+				var report paymentBatchReport
+				require.NoError(t, json.Unmarshal(rec.Body.Bytes(), &report))
+				require.True(t, report.DryRun)
+				require.Equal(t, 1, report.ItemCount)
+				require.Zero(t, report.InvalidCount)
+				require.Equal(t, int64(100), report.Totals[util.USD])
+				require.True(t, report.Items[0].Valid)
+			},
+		},
+		{
+			name:   "DryRunReportsInvalidLines",
+			file:   reverse,
+			fields: map[string]string{"dry_run": "true"},
+			setupAuth: func(t *testing.T, r *http.Request, tm token.Maker) {
+				addAuthorizationHeader(t, r, tm, authorizationTypeBearer, user.Username, time.Minute)
+			},
+			buildStubs: func(store *mockdb.MockStore) {
+				stubAccounts(store)
+				store.EXPECT().GetAccountHolder(gomock.Any(), gomock.Any()).Times(1).Return(db.AccountHolder{}, sql.ErrNoRows)
+				store.EXPECT().GetAccountPermission(gomock.Any(), gomock.Any()).Times(1).Return(db.AccountPermission{}, sql.ErrNoRows)
+			},
+			checkResponse: func(t *testing.T, rec *httptest.ResponseRecorder) {
+				require.Equal(t, http.StatusOK, rec.Code)
+
+				var report paymentBatchReport
+				require.NoError(t, json.Unmarshal(rec.Body.Bytes(), &report))
+				require.Equal(t, 1, report.InvalidCount)
+				require.False(t, report.Items[0].Valid)
+				require.Contains(t, report.Items[0].Error, "doesn't belong")
+			},
+		},
+		{
+			name:   "DryRunReportsCoolingOff",
+			file:   large,
+			fields: map[string]string{"dry_run": "true"},
+			setupAuth: func(t *testing.T, r *http.Request, tm token.Maker) {
+				addAuthorizationHeader(t, r, tm, authorizationTypeBearer, user.Username, time.Minute)
+			},
+			buildStubs: func(store *mockdb.MockStore) {
+				stubAccounts(store)
+				store.EXPECT().GetAccountHolder(gomock.Any(), gomock.Any()).Times(1).Return(db.AccountHolder{}, sql.ErrNoRows)
+				store.EXPECT().GetPayeeByAccount(gomock.Any(), gomock.Eq(db.GetPayeeByAccountParams{Owner: user.Username, AccountID: to.ID})).
+					Times(1).Return(db.Payee{}, sql.ErrNoRows)
+			},
+			checkResponse: func(t *testing.T, rec *httptest.ResponseRecorder) {
+				require.Equal(t, http.StatusOK, rec.Code)
+
+				var report paymentBatchReport
+				require.NoError(t, json.Unmarshal(rec.Body.Bytes(), &report))
+				require.Equal(t, 1, report.InvalidCount)
+				require.Contains(t, report.Items[0].Error, "only go to payees")
+			},
+		},
+		{
+			name:   "DryRunByAccountNumber",
+			file:   byNumber,
+			fields: map[string]string{"dry_run": "true"},
+			setupAuth: func(t *testing.T, r *http.Request, tm token.Maker) {
+				addAuthorizationHeader(t, r, tm, authorizationTypeBearer, user.Username, time.Minute)
+			},
+			buildStubs: func(store *mockdb.MockStore) {
+				stubAccounts(store)
+				store.EXPECT().GetAccountByNumber(gomock.Any(), gomock.Eq(to.Number)).Times(1).Return(to, nil)
+			},
+			checkResponse: func(t *testing.T, rec *httptest.ResponseRecorder) {
+				require.Equal(t, http.StatusOK, rec.Code)
+
+				var report paymentBatchReport
+				require.NoError(t, json.Unmarshal(rec.Body.Bytes(), &report))
+				require.Zero(t, report.InvalidCount)
+				require.Equal(t, to.ID, report.Items[0].ToAccountID)
+			},
+		},
+		{
+			name:   "DryRunReportsUnknownAccountNumber",
+			file:   byNumber,
+			fields: map[string]string{"dry_run": "true"},
+			setupAuth: func(t *testing.T, r *http.Request, tm token.Maker) {
+				addAuthorizationHeader(t, r, tm, authorizationTypeBearer, user.Username, time.Minute)
+			},
+			buildStubs: func(store *mockdb.MockStore) {
+				stubAccounts(store)
+				store.EXPECT().GetAccountByNumber(gomock.Any(), gomock.Eq(to.Number)).Times(1).Return(db.Account{}, sql.ErrNoRows)
+			},
+			checkResponse: func(t *testing.T, rec *httptest.ResponseRecorder) {
+				require.Equal(t, http.StatusOK, rec.Code)
+
+				var report paymentBatchReport
+				require.NoError(t, json.Unmarshal(rec.Body.Bytes(), &report))
+				require.Equal(t, 1, report.InvalidCount)
+				require.Contains(t, report.Items[0].Error, "not found")
+			},
+		},
+		{
+			name:   "DryRunHidesAccountNumbersOfOthers",
+			file:   fromOthers,
+			fields: map[string]string{"dry_run": "true"},
+			setupAuth: func(t *testing.T, r *http.Request, tm token.Maker) {
+				addAuthorizationHeader(t, r, tm, authorizationTypeBearer, user.Username, time.Minute)
+			},
+			buildStubs: func(store *mockdb.MockStore) {
+				store.EXPECT().GetAccountByNumber(gomock.Any(), gomock.Eq(to.Number)).Times(1).Return(to, nil)
+				store.EXPECT().GetAccountHolder(gomock.Any(), gomock.Any()).Times(1).Return(db.AccountHolder{}, sql.ErrNoRows)
+				store.EXPECT().GetAccountPermission(gomock.Any(), gomock.Any()).Times(1).Return(db.AccountPermission{}, sql.ErrNoRows)
+				store.EXPECT().GetAccount(gomock.Any(), gomock.Any()).Times(0)
+			},
+			checkResponse: func(t *testing.T, rec *httptest.ResponseRecorder) {
+				require.Equal(t, http.StatusOK, rec.Code)
+
+				var report paymentBatchReport
+				require.NoError(t, json.Unmarshal(rec.Body.Bytes(), &report))
+				require.Equal(t, 1, report.InvalidCount)
+				require.Equal(t, fmt.Sprintf("account number %s not found", to.Number), report.Items[0].Error)
+			},
+		},
+		{
+			name:   "DryRunAsDelegate",
+			file:   reverse,
+			fields: map[string]string{"dry_run": "true"},
+			setupAuth: func(t *testing.T, r *http.Request, tm token.Maker) {
+				addAuthorizationHeader(t, r, tm, authorizationTypeBearer, user.Username, time.Minute)
+			},
+			buildStubs: func(store *mockdb.MockStore) {
+				stubAccounts(store)
+				store.EXPECT().GetAccountHolder(gomock.Any(), gomock.Any()).Times(1).Return(db.AccountHolder{}, sql.ErrNoRows)
+				store.EXPECT().GetAccountPermission(gomock.Any(), gomock.Eq(db.GetAccountPermissionParams{
+					AccountID: to.ID,
+					Username:  user.Username,
+				})).Times(1).Return(db.AccountPermission{AccountID: to.ID, Username: user.Username, Access: accessTransfer}, nil)
+			},
+			checkResponse: func(t *testing.T, rec *httptest.ResponseRecorder) {
+				require.Equal(t, http.StatusOK, rec.Code)
+
+				var report paymentBatchReport
+				require.NoError(t, json.Unmarshal(rec.Body.Bytes(), &report))
+				require.Zero(t, report.InvalidCount)
+				require.True(t, report.Items[0].Valid)
+			},
+		},
+		{
+			name:   "DryRunAsReadOnlyDelegate",
+			file:   reverse,
+			fields: map[string]string{"dry_run": "true"},
+			setupAuth: func(t *testing.T, r *http.Request, tm token.Maker) {
+				addAuthorizationHeader(t, r, tm, authorizationTypeBearer, user.Username, time.Minute)
+			},
+			buildStubs: func(store *mockdb.MockStore) {
+				stubAccounts(store)
+				store.EXPECT().GetAccountHolder(gomock.Any(), gomock.Any()).Times(1).Return(db.AccountHolder{}, sql.ErrNoRows)
+				store.EXPECT().GetAccountPermission(gomock.Any(), gomock.Any()).Times(1).
+					Return(db.AccountPermission{AccountID: to.ID, Username: user.Username, Access: accessRead}, nil)
+			},
+			checkResponse: func(t *testing.T, rec *httptest.ResponseRecorder) {
+				require.Equal(t, http.StatusOK, rec.Code)
+
+				var report paymentBatchReport
+				require.NoError(t, json.Unmarshal(rec.Body.Bytes(), &report))
+				require.Equal(t, 1, report.InvalidCount)
+			},
+		},
+		{
+			name: "Execute",
+			file: file,
+			setupAuth: func(t *testing.T, r *http.Request, tm token.Maker) {
+				addAuthorizationHeader(t, r, tm, authorizationTypeBearer, user.Username, time.Minute)
+			},
+			buildStubs: func(store *mockdb.MockStore) {
+				stubAccounts(store)
+				args := db.CreatePaymentBatchTxParams{
+					Owner:        user.Username,
+					SourceFormat: "csv",
+					Items: []db.CreatePaymentBatchItemParams{
+						{Line: 2, FromAccountID: from.ID, ToAccountID: to.ID, Amount: 100, Currency: util.USD, Memo: "rent"},
+					},
+				}
+				store.EXPECT().
+					CreatePaymentBatchTx(gomock.Any(), gomock.Eq(args)).
+					Times(1).
+					Return(db.PaymentBatchTxResult{Batch: batch, Items: []db.PaymentBatchItem{item}}, nil)
+				store.EXPECT().
+					TransferTx(gomock.Any(), gomock.Eq(db.TransferTxParams{FromAccountID: from.ID, ToAccountID: to.ID, Amount: 100, Memo: "rent"})).
+					Times(1).
+					Return(db.TransferTxResult{Transfer: db.Transfer{ID: 99}}, nil)
+
+				done := item
+				done.Status = paymentStatusCompleted
+				done.TransferID = sql.NullInt64{Int64: 99, Valid: true}
+				store.EXPECT().
+					UpdatePaymentBatchItem(gomock.Any(), gomock.Eq(db.UpdatePaymentBatchItemParams{ID: item.ID, Status: paymentStatusCompleted, TransferID: done.TransferID})).
+					Times(1).
+					Return(done, nil)
+
+				executed := batch
+				executed.Status = paymentStatusCompleted
+				store.EXPECT().
+					UpdatePaymentBatchStatus(gomock.Any(), gomock.Eq(db.UpdatePaymentBatchStatusParams{ID: batch.ID, Status: paymentStatusCompleted})).
+					Times(1).
+					Return(executed, nil)
+			},
+			checkResponse: func(t *testing.T, rec *httptest.ResponseRecorder) {
+				require.Equal(t, http.StatusOK, rec.Code)
+
+				var res paymentBatchResponse
+				require.NoError(t, json.Unmarshal(rec.Body.Bytes(), &res))
+				require.Equal(t, paymentStatusCompleted, res.Batch.Status)
+				require.Len(t, res.Items, 1)
+				require.Equal(t, int64(99), res.Items[0].TransferID.Int64)
+			},
+		},
+		{
+			name: "ExecuteSameFileTwice",
+			file: pain,
+			setupAuth: func(t *testing.T, r *http.Request, tm token.Maker) {
+				addAuthorizationHeader(t, r, tm, authorizationTypeBearer, user.Username, time.Minute)
+			},
+			buildStubs: func(store *mockdb.MockStore) {
+				stubAccounts(store)
+				store.EXPECT().
+					CreatePaymentBatchTx(gomock.Any(), gomock.Eq(db.CreatePaymentBatchTxParams{
+						Owner:        user.Username,
+						SourceFormat: "pain001",
+						MessageID:    "PAYROLL-1",
+						Items: []db.CreatePaymentBatchItemParams{
+							{Line: 1, FromAccountID: from.ID, ToAccountID: to.ID, Amount: 100, Currency: util.USD},
+						},
+					})).
+					Times(1).
+					Return(db.PaymentBatchTxResult{}, &pq.Error{Code: "23505", Constraint: "payment_batches_owner_message_id_key"})
+				store.EXPECT().TransferTx(gomock.Any(), gomock.Any()).Times(0)
+			},
+			checkResponse: func(t *testing.T, rec *httptest.ResponseRecorder) {
+				require.Equal(t, http.StatusConflict, rec.Code)
+			},
+		},
+		{
+			name:   "DryRunReportsLongMemo",
+			file:   longMemo,
+			fields: map[string]string{"dry_run": "true"},
+			setupAuth: func(t *testing.T, r *http.Request, tm token.Maker) {
+				addAuthorizationHeader(t, r, tm, authorizationTypeBearer, user.Username, time.Minute)
+			},
+			buildStubs: func(store *mockdb.MockStore) {
+				store.EXPECT().GetAccount(gomock.Any(), gomock.Any()).Times(0)
+			},
+			checkResponse: func(t *testing.T, rec *httptest.ResponseRecorder) {
+				require.Equal(t, http.StatusOK, rec.Code)
+
+				var report paymentBatchReport
+				require.NoError(t, json.Unmarshal(rec.Body.Bytes(), &report))
+				require.Equal(t, 1, report.InvalidCount)
+				require.Contains(t, report.Items[0].Error, "memo is longer than 140")
+			},
+		},
+		{
+			name: "ExecuteTransferFails",
+			file: file,
+			setupAuth: func(t *testing.T, r *http.Request, tm token.Maker) {
+				addAuthorizationHeader(t, r, tm, authorizationTypeBearer, user.Username, time.Minute)
+			},
+			buildStubs: func(store *mockdb.MockStore) {
+				stubAccounts(store)
+				store.EXPECT().
+					CreatePaymentBatchTx(gomock.Any(), gomock.Any()).
+					Times(1).
+					Return(db.PaymentBatchTxResult{Batch: batch, Items: []db.PaymentBatchItem{item}}, nil)
+				store.EXPECT().TransferTx(gomock.Any(), gomock.Any()).Times(1).Return(db.TransferTxResult{}, sql.ErrConnDone)
+				store.EXPECT().
+					UpdatePaymentBatchItem(gomock.Any(), gomock.Eq(db.UpdatePaymentBatchItemParams{ID: item.ID, Status: paymentStatusFailed, Error: sql.ErrConnDone.Error()})).
+					Times(1).
+					Return(item, nil)
+				store.EXPECT().
+					UpdatePaymentBatchStatus(gomock.Any(), gomock.Eq(db.UpdatePaymentBatchStatusParams{ID: batch.ID, Status: paymentStatusFailed})).
+					Times(1).
+					Return(batch, nil)
+			},
+			checkResponse: func(t *testing.T, rec *httptest.ResponseRecorder) {
+				require.Equal(t, http.StatusOK, rec.Code)
+			},
+		},
+		{
+			name: "NeedsApproval",
+			file: file,
+			setupAuth: func(t *testing.T, r *http.Request, tm token.Maker) {
+				addAuthorizationHeader(t, r, tm, authorizationTypeBearer, user.Username, time.Minute)
+			},
+			buildStubs: func(store *mockdb.MockStore) {
+				store.EXPECT().
+					GetApprovalPolicyForAmount(gomock.Any(), gomock.Eq(db.GetApprovalPolicyForAmountParams{AccountID: from.ID, Amount: 100})).
+					Times(1).
+					Return(db.ApprovalPolicy{AccountID: from.ID, MinAmount: 50, RequiredApprovals: 1}, nil)
+				stubAccounts(store)
+				store.EXPECT().CreatePaymentBatchTx(gomock.Any(), gomock.Any()).Times(0)
+			},
+			checkResponse: func(t *testing.T, rec *httptest.ResponseRecorder) {
+				require.Equal(t, http.StatusUnprocessableEntity, rec.Code)
+			},
+		},
+		{
+			name: "ExecuteRejectsInvalidFile",
+			file: reverse,
+			setupAuth: func(t *testing.T, r *http.Request, tm token.Maker) {
+				addAuthorizationHeader(t, r, tm, authorizationTypeBearer, user.Username, time.Minute)
+			},
+			buildStubs: func(store *mockdb.MockStore) {
+				stubAccounts(store)
+				store.EXPECT().GetAccountHolder(gomock.Any(), gomock.Any()).Times(1).Return(db.AccountHolder{}, sql.ErrNoRows)
+				store.EXPECT().GetAccountPermission(gomock.Any(), gomock.Any()).Times(1).Return(db.AccountPermission{}, sql.ErrNoRows)
+				store.EXPECT().CreatePaymentBatchTx(gomock.Any(), gomock.Any()).Times(0)
+			},
+			checkResponse: func(t *testing.T, rec *httptest.ResponseRecorder) {
+				require.Equal(t, http.StatusUnprocessableEntity, rec.Code)
+			},
+		},
+		{
+			name: "MalformedFile",
+			file: "account,amount\n1,2\n",
+			setupAuth: func(t *testing.T, r *http.Request, tm token.Maker) {
+				addAuthorizationHeader(t, r, tm, authorizationTypeBearer, user.Username, time.Minute)
+			},
+			buildStubs: func(store *mockdb.MockStore) {
+				store.EXPECT().GetAccount(gomock.Any(), gomock.Any()).Times(0)
+			},
+			checkResponse: func(t *testing.T, rec *httptest.ResponseRecorder) {
+				require.Equal(t, http.StatusBadRequest, rec.Code)
+			},
+		},
+		{
+			name: "InternalError",
+			file: file,
+			setupAuth: func(t *testing.T, r *http.Request, tm token.Maker) {
+				addAuthorizationHeader(t, r, tm, authorizationTypeBearer, user.Username, time.Minute)
+			},
+			buildStubs: func(store *mockdb.MockStore) {
+				store.EXPECT().GetAccount(gomock.Any(), gomock.Any()).Times(1).Return(db.Account{}, sql.ErrConnDone)
+				store.EXPECT().CreatePaymentBatchTx(gomock.Any(), gomock.Any()).Times(0)
+			},
+			checkResponse: func(t *testing.T, rec *httptest.ResponseRecorder) {
+				require.Equal(t, http.StatusInternalServerError, rec.Code)
+			},
+		},
+		{
+			name: "NoAuthorization",
+			file: file,
+			setupAuth: func(t *testing.T, r *http.Request, tm token.Maker) {
+			},
+			buildStubs: func(store *mockdb.MockStore) {
+				store.EXPECT().GetAccount(gomock.Any(), gomock.Any()).Times(0)
+			},
+			checkResponse: func(t *testing.T, rec *httptest.ResponseRecorder) {
+				require.Equal(t, http.StatusUnauthorized, rec.Code)
+			},
+		},
+	}
+
+	for i := range testCases {
+		tc := testCases[i]
+
+		t.Run(tc.name, func(t *testing.T) {
+			ctrl := gomock.NewController(t)
+			defer ctrl.Finish()
+
+			store := mockdb.NewMockStore(ctrl)
+			stubAuthUsers(store)
+			tc.buildStubs(store)
+
+			svr := newTestServer(t, store)
+			svr.config.PayeeCoolingOff = 24 * time.Hour
+			svr.config.PayeeCoolingOffThreshold = 500
+			rec := httptest.NewRecorder()
+
+			req := paymentFileRequest(t, tc.file, tc.fields)
+			tc.setupAuth(t, req, svr.tokenMaker)
+			svr.router.ServeHTTP(rec, req)
+			tc.checkResponse(t, rec)
+		})
+	}
+}
+
+func TestGetPaymentBatchAPI(t *testing.T) {
+	user, _ := randomUser(t)
+	batch := db.PaymentBatch{ID: util.RandomInt(1, 1000), Owner: user.Username, SourceFormat: "pain001", Status: "completed", ItemCount: 1}
+	items := []db.PaymentBatchItem{{ID: 1, BatchID: batch.ID, Line: 1, Status: "completed"}}
+
+	testCases := []struct {
+		name          string
+		setupAuth     func(t *testing.T, r *http.Request, tm token.Maker)
+		buildStubs    func(store *mockdb.MockStore)
+		checkResponse func(t *testing.T, rec *httptest.ResponseRecorder)
+	}{
+		{
+			name: "OK",
+			setupAuth: func(t *testing.T, r *http.Request, tm token.Maker) {
+				addAuthorizationHeader(t, r, tm, authorizationTypeBearer, user.Username, time.Minute)
+			},
+			buildStubs: func(store *mockdb.MockStore) {
+				store.EXPECT().GetPaymentBatch(gomock.Any(), gomock.Eq(batch.ID)).Times(1).Return(batch, nil)
+				store.EXPECT().ListPaymentBatchItems(gomock.Any(), gomock.Eq(batch.ID)).Times(1).Return(items, nil)
+			},
+			checkResponse: func(t *testing.T, rec *httptest.ResponseRecorder) {
+				require.Equal(t, http.StatusOK, rec.Code)
+
+				var res paymentBatchResponse
+				require.NoError(t, json.Unmarshal(rec.Body.Bytes(), &res))
+				require.Equal(t, batch, res.Batch)
+				require.Equal(t, items, res.Items)
+			},
+		},
+		{
+			name: "NotFound",
+			setupAuth: func(t *testing.T, r *http.Request, tm token.Maker) {
+				addAuthorizationHeader(t, r, tm, authorizationTypeBearer, user.Username, time.Minute)
+			},
+			buildStubs: func(store *mockdb.MockStore) {
+				store.EXPECT().GetPaymentBatch(gomock.Any(), gomock.Eq(batch.ID)).Times(1).Return(db.PaymentBatch{}, sql.ErrNoRows)
+			},
+			checkResponse: func(t *testing.T, rec *httptest.ResponseRecorder) {
+				require.Equal(t, http.StatusNotFound, rec.Code)
+			},
+		},
+		{
+			name: "UnauthorizedUser",
+			setupAuth: func(t *testing.T, r *http.Request, tm token.Maker) {
+				addAuthorizationHeader(t, r, tm, authorizationTypeBearer, "unauthorized_user", time.Minute)
+			},
+			buildStubs: func(store *mockdb.MockStore) {
+				store.EXPECT().GetPaymentBatch(gomock.Any(), gomock.Eq(batch.ID)).Times(1).Return(batch, nil)
+				store.EXPECT().ListPaymentBatchItems(gomock.Any(), gomock.Any()).Times(0)
+			},
+			checkResponse: func(t *testing.T, rec *httptest.ResponseRecorder) {
+				require.Equal(t, http.StatusUnauthorized, rec.Code)
+			},
+		},
+	}
+
+	for i := range testCases {
+		tc := testCases[i]
+
+		t.Run(tc.name, func(t *testing.T) {
+			ctrl := gomock.NewController(t)
+			defer ctrl.Finish()
+
+			store := mockdb.NewMockStore(ctrl)
+			stubAuthUsers(store)
+			tc.buildStubs(store)
+
+			svr := newTestServer(t, store)
+			rec := httptest.NewRecorder()
+
+			url := fmt.Sprintf("/transfers/batches/%d", batch.ID)
+			req, err := http.NewRequest(http.MethodGet, url, nil)
+			require.NoError(t, err)
+
+			tc.setupAuth(t, req, svr.tokenMaker)
+			svr.router.ServeHTTP(rec, req)
+			tc.checkResponse(t, rec)
+		})
+	}
+}
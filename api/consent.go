@@ -0,0 +1,289 @@
+package api
+
+import (
+	"database/sql"
+	"errors"
+	"fmt"
+	db "gobank/db/sqlc"
+	"gobank/token"
+	"net/http"
+	"time"
+
+	"github.com/gin-gonic/gin"
+)
+
+// Account consents work like the account access consents of Open Banking: an app creates one naming the
+// permissions it wants, the user authorises it for some of their accounts while authorizing the app, and the
+// OAuth tokens the app then gets are bound to it. They only reach those accounts with those permissions.
+const (
+	permissionReadBalances     = "ReadBalances"
+	permissionReadTransactions = "ReadTransactions"
+	permissionInitiatePayments = "InitiatePayments"
+)
+
+func isSupportedConsentPermission(permission string) bool {
+	switch permission {
+	case permissionReadBalances, permissionReadTransactions, permissionInitiatePayments:
+		return true
+	}
+	return false
+}
+
+type createAccountConsentRequest struct {
+	Permissions []string `json:"permissions" binding:"required,min=1,dive,consentpermission"`
+	// defaults to, and may not be later than, CONSENT_MAX_DURATION from now
+	ExpiresAt *time.Time `json:"expires_at"`
+}
+
+type accountConsentResponse struct {
+	ID           int64      `json:"id"`
+	ClientID     string     `json:"client_id"`
+	Status       string     `json:"status"`
+	Permissions  []string   `json:"permissions"`
+	AccountIDs   []int64    `json:"account_ids"`
+	ExpiresAt    time.Time  `json:"expires_at"`
+	AuthorisedAt *time.Time `json:"authorised_at"`
+	RevokedAt    *time.Time `json:"revoked_at"`
+	CreatedAt    time.Time  `json:"created_at"`
+}
+
+func newAccountConsentResponse(c db.AccountConsent) accountConsentResponse {
+	return accountConsentResponse{
+		ID:           c.ID,
+		ClientID:     c.ClientID,
+		Status:       c.Status,
+		Permissions:  c.Permissions,
+		AccountIDs:   c.AccountIds,
+		ExpiresAt:    c.ExpiresAt,
+		AuthorisedAt: nullTime(c.AuthorisedAt),
+		RevokedAt:    nullTime(c.RevokedAt),
+		CreatedAt:    c.CreatedAt,
+	}
+}
+
+// createAccountConsent is called by an app, authenticated with HTTP basic auth, before sending the user to
+// authorize it. The consent waits for the user to authorise it.
+func (s *Server) createAccountConsent(ctx *gin.Context) {
+	var req createAccountConsentRequest
+	if err := ctx.ShouldBindJSON(&req); err != nil {
+		ctx.JSON(http.StatusBadRequest, errorResponse(err))
+		return
+	}
+	client, ok := s.authenticateConsentClient(ctx)
+	if !ok {
+		return
+	}
+
+	maxExpiry := time.Now().Add(s.config.ConsentMaxDuration)
+	expiresAt := maxExpiry
+	if req.ExpiresAt != nil {
+		if !req.ExpiresAt.After(time.Now()) || req.ExpiresAt.After(maxExpiry) {
+			err := fmt.Errorf("expires_at must be in the future and within %s", s.config.ConsentMaxDuration)
+			ctx.JSON(http.StatusBadRequest, errorResponse(err))
+			return
+		}
+		expiresAt = *req.ExpiresAt
+	}
+
+	consent, err := s.store.CreateAccountConsent(ctx, db.CreateAccountConsentParams{
+		ClientID:    client.ID,
+		Permissions: req.Permissions,
+		ExpiresAt:   expiresAt,
+	})
+	if err != nil {
+		ctx.JSON(http.StatusInternalServerError, errorResponse(err))
+		return
+	}
+	ctx.JSON(http.StatusOK, newAccountConsentResponse(consent))
+}
+
+type accountConsentRequest struct {
+	ID int64 `uri:"id" binding:"required,min=1"`
+}
+
+// getAccountConsent lets an app see whether the user authorised its consent, and for which accounts.
+func (s *Server) getAccountConsent(ctx *gin.Context) {
+	var req accountConsentRequest
+	if err := ctx.ShouldBindUri(&req); err != nil {
+		ctx.JSON(http.StatusBadRequest, errorResponse(err))
+		return
+	}
+	client, ok := s.authenticateConsentClient(ctx)
+	if !ok {
+		return
+	}
+
+	consent, ok := s.clientAccountConsent(ctx, client.ID, req.ID)
+	if !ok {
+		return
+	}
+	ctx.JSON(http.StatusOK, newAccountConsentResponse(consent))
+}
+
+// deleteAccountConsent lets an app give up a consent it no longer needs.
+func (s *Server) deleteAccountConsent(ctx *gin.Context) {
+	var req accountConsentRequest
+	if err := ctx.ShouldBindUri(&req); err != nil {
+		ctx.JSON(http.StatusBadRequest, errorResponse(err))
+		return
+	}
+	client, ok := s.authenticateConsentClient(ctx)
+	if !ok {
+		return
+	}
+
+	if _, ok := s.clientAccountConsent(ctx, client.ID, req.ID); !ok {
+		return
+	}
+	s.revokeConsent(ctx, req.ID)
+}
+
+// authenticateConsentClient identifies the app managing consents by HTTP basic auth. Only confidential
+// clients may: a public client's id is no secret, so anyone could create or read consents in its name.
+func (s *Server) authenticateConsentClient(ctx *gin.Context) (db.OauthClient, bool) {
+	client, ok := s.authenticateOAuthClient(ctx, "", "")
+	if !ok {
+		return client, false
+	}
+	if client.SecretHash == "" {
+		ctx.JSON(http.StatusUnauthorized, oauthError("invalid_client", "consents can only be managed by confidential clients"))
+		return client, false
+	}
+	return client, true
+}
+
+// clientAccountConsent loads a consent of the app clientID, writing the error response if there is none.
+// Consents of other apps are reported as missing.
+func (s *Server) clientAccountConsent(ctx *gin.Context, clientID string, id int64) (db.AccountConsent, bool) {
+	consent, err := s.store.GetAccountConsent(ctx, id)
+	if err != nil {
+		if err == sql.ErrNoRows {
+			ctx.JSON(http.StatusNotFound, errorResponse(err))
+			return consent, false
+		}
+		ctx.JSON(http.StatusInternalServerError, errorResponse(err))
+		return consent, false
+	}
+	if consent.ClientID != clientID {
+		ctx.JSON(http.StatusNotFound, errorResponse(sql.ErrNoRows))
+		return consent, false
+	}
+	return consent, true
+}
+
+// pendingConsent loads the account consent id and makes sure it was created by the app clientID and is still
+// waiting to be authorised, writing the error response if not.
+func (s *Server) pendingConsent(ctx *gin.Context, clientID string, id int64) (db.AccountConsent, bool) {
+	consent, err := s.store.GetAccountConsent(ctx, id)
+	if err != nil {
+		if err == sql.ErrNoRows {
+			ctx.JSON(http.StatusBadRequest, errorResponse(err))
+			return consent, false
+		}
+		ctx.JSON(http.StatusInternalServerError, errorResponse(err))
+		return consent, false
+	}
+	if consent.ClientID != clientID || consent.Status != db.ConsentAwaitingAuthorisation || !time.Now().Before(consent.ExpiresAt) {
+		err := errors.New("consent is not awaiting authorisation by this client")
+		ctx.JSON(http.StatusBadRequest, errorResponse(err))
+		return consent, false
+	}
+	return consent, true
+}
+
+// consentAccess is the access to an account a user needs to let an app have the consent permissions on it.
+func consentAccess(permissions []string) string {
+	if containsString(permissions, permissionInitiatePayments) {
+		return accessTransfer
+	}
+	return accessRead
+}
+
+// listAccountConsents shows the user every consent they authorised, including revoked and expired ones.
+func (s *Server) listAccountConsents(ctx *gin.Context) {
+	payload := ctx.MustGet(authorizationPayloadKey).(*token.Payload)
+	consents, err := s.store.ListAccountConsents(ctx, sql.NullString{String: payload.Username, Valid: true})
+	if err != nil {
+		ctx.JSON(http.StatusInternalServerError, errorResponse(err))
+		return
+	}
+
+	res := make([]accountConsentResponse, 0, len(consents))
+	for _, c := range consents {
+		res = append(res, newAccountConsentResponse(c))
+	}
+	ctx.JSON(http.StatusOK, res)
+}
+
+// revokeAccountConsent lets the user withdraw a consent, which stops the tokens bound to it at once.
+func (s *Server) revokeAccountConsent(ctx *gin.Context) {
+	var req accountConsentRequest
+	if err := ctx.ShouldBindUri(&req); err != nil {
+		ctx.JSON(http.StatusBadRequest, errorResponse(err))
+		return
+	}
+
+	consent, err := s.store.GetAccountConsent(ctx, req.ID)
+	if err != nil {
+		if err == sql.ErrNoRows {
+			ctx.JSON(http.StatusNotFound, errorResponse(err))
+			return
+		}
+		ctx.JSON(http.StatusInternalServerError, errorResponse(err))
+		return
+	}
+	payload := ctx.MustGet(authorizationPayloadKey).(*token.Payload)
+	if consent.Username.String != payload.Username {
+		err := errors.New("consent doesn't belong to authenticated user")
+		ctx.JSON(http.StatusUnauthorized, errorResponse(err))
+		return
+	}
+	s.revokeConsent(ctx, consent.ID)
+}
+
+func (s *Server) revokeConsent(ctx *gin.Context, id int64) {
+	consent, err := s.store.RevokeAccountConsent(ctx, id)
+	if err != nil {
+		if err == sql.ErrNoRows {
+			err = errors.New("consent is already revoked")
+			ctx.JSON(http.StatusNotFound, errorResponse(err))
+			return
+		}
+		ctx.JSON(http.StatusInternalServerError, errorResponse(err))
+		return
+	}
+	ctx.JSON(http.StatusOK, newAccountConsentResponse(consent))
+}
+
+// activeConsent reports whether consent is authorised, unexpired, and was given by the user and to the
+// app of payload.
+func activeConsent(consent db.AccountConsent, payload *token.Payload) bool {
+	return consent.Status == db.ConsentAuthorised &&
+		time.Now().Before(consent.ExpiresAt) &&
+		consent.ClientID == payload.ClientID &&
+		consent.Username.String == payload.Username
+}
+
+// checkConsent lets requests that are not bound to an account consent through. Bound ones must have
+// permission on accountID. It writes the error response and returns false otherwise.
+func checkConsent(ctx *gin.Context, accountID int64, permission string) bool {
+	value, bound := ctx.Get(authorizationConsentKey)
+	if !bound {
+		return true
+	}
+	consent := value.(db.AccountConsent)
+
+	if !containsString(consent.Permissions, permission) {
+		err := fmt.Errorf("consent lacks the %s permission", permission)
+		ctx.JSON(http.StatusForbidden, errorResponse(err))
+		return false
+	}
+	for _, id := range consent.AccountIds {
+		if id == accountID {
+			return true
+		}
+	}
+	err := fmt.Errorf("consent does not cover account [%d]", accountID)
+	ctx.JSON(http.StatusForbidden, errorResponse(err))
+	return false
+}
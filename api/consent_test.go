@@ -0,0 +1,690 @@
+package api
+
+import (
+	"bytes"
+	"context"
+	"database/sql"
+	"encoding/json"
+	"fmt"
+	mockdb "gobank/db/mock"
+	db "gobank/db/sqlc"
+	"gobank/token"
+	"gobank/util"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/golang/mock/gomock"
+	"github.com/stretchr/testify/require"
+)
+
+func randomAccountConsent(clientID, username string, accountIDs []int64, permissions ...string) db.AccountConsent {
+	return db.AccountConsent{
+		ID:           util.RandomInt(1, 1000),
+		ClientID:     clientID,
+		Username:     sql.NullString{String: username, Valid: true},
+		Permissions:  permissions,
+		AccountIds:   accountIDs,
+		Status:       db.ConsentAuthorised,
+		ExpiresAt:    time.Now().Add(24 * time.Hour),
+		AuthorisedAt: sql.NullTime{Time: time.Now().Add(-time.Minute), Valid: true},
+		CreatedAt:    time.Now().Add(-time.Hour),
+	}
+}
+
+func TestCreateAccountConsentAPI(t *testing.T) {
+	client, secret := randomOAuthClient(t, util.RandomOwner(), true)
+
+	testCases := []struct {
+		name          string
+		body          gin.H
+		basicAuth     bool
+		buildStubs    func(store *mockdb.MockStore)
+		checkResponse func(t *testing.T, rec *httptest.ResponseRecorder)
+	}{
+		{
+			name:      "OK",
+			body:      gin.H{"permissions": []string{permissionReadBalances, permissionReadTransactions}},
+			basicAuth: true,
+			buildStubs: func(store *mockdb.MockStore) {
+				store.EXPECT().GetOAuthClient(gomock.Any(), gomock.Eq(client.ID)).Times(1).Return(client, nil)
+				store.EXPECT().CreateAccountConsent(gomock.Any(), gomock.Any()).Times(1).
+					DoAndReturn(func(_ context.Context, arg db.CreateAccountConsentParams) (db.AccountConsent, error) {
+						require.Equal(t, client.ID, arg.ClientID)
+						require.Equal(t, []string{permissionReadBalances, permissionReadTransactions}, arg.Permissions)
+						require.WithinDuration(t, time.Now().Add(90*24*time.Hour), arg.ExpiresAt, time.Second)
+						return db.AccountConsent{ID: 1, ClientID: arg.ClientID, Permissions: arg.Permissions,
+							Status: db.ConsentAwaitingAuthorisation, ExpiresAt: arg.ExpiresAt}, nil
+					})
+			},
+			checkResponse: func(t *testing.T, rec *httptest.ResponseRecorder) {
+				require.Equal(t, http.StatusOK, rec.Code)
+
+				var rsp accountConsentResponse
+				require.NoError(t, json.Unmarshal(rec.Body.Bytes(), &rsp))
+				require.Equal(t, db.ConsentAwaitingAuthorisation, rsp.Status)
+				require.Nil(t, rsp.AuthorisedAt)
+			},
+		},
+		{
+			name:      "WithExpiry",
+			body:      gin.H{"permissions": []string{permissionInitiatePayments}, "expires_at": time.Now().Add(time.Hour)},
+			basicAuth: true,
+			buildStubs: func(store *mockdb.MockStore) {
+				store.EXPECT().GetOAuthClient(gomock.Any(), gomock.Any()).Times(1).Return(client, nil)
+				store.EXPECT().CreateAccountConsent(gomock.Any(), gomock.Any()).Times(1).
+					DoAndReturn(func(_ context.Context, arg db.CreateAccountConsentParams) (db.AccountConsent, error) {
+						require.WithinDuration(t, time.Now().Add(time.Hour), arg.ExpiresAt, time.Second)
+						return db.AccountConsent{ID: 1}, nil
+					})
+			},
+			checkResponse: func(t *testing.T, rec *httptest.ResponseRecorder) {
+				require.Equal(t, http.StatusOK, rec.Code)
+			},
+		},
+		{
+			name:      "ExpiryTooLate",
+			body:      gin.H{"permissions": []string{permissionReadBalances}, "expires_at": time.Now().Add(100 * 24 * time.Hour)},
+			basicAuth: true,
+			buildStubs: func(store *mockdb.MockStore) {
+				store.EXPECT().GetOAuthClient(gomock.Any(), gomock.Any()).Times(1).Return(client, nil)
+				store.EXPECT().CreateAccountConsent(gomock.Any(), gomock.Any()).Times(0)
+			},
+			checkResponse: func(t *testing.T, rec *httptest.ResponseRecorder) {
+				require.Equal(t, http.StatusBadRequest, rec.Code)
+			},
+		},
+		{
+			name:      "UnknownPermission",
+			body:      gin.H{"permissions": []string{"ReadEverything"}},
+			basicAuth: true,
+			buildStubs: func(store *mockdb.MockStore) {
+				store.EXPECT().CreateAccountConsent(gomock.Any(), gomock.Any()).Times(0)
+			},
+			checkResponse: func(t *testing.T, rec *httptest.ResponseRecorder) {
+				require.Equal(t, http.StatusBadRequest, rec.Code)
+			},
+		},
+		{
+			name: "NoClientAuthentication",
+			body: gin.H{"permissions": []string{permissionReadBalances}},
+			buildStubs: func(store *mockdb.MockStore) {
+				store.EXPECT().GetOAuthClient(gomock.Any(), gomock.Any()).Times(0)
+				store.EXPECT().CreateAccountConsent(gomock.Any(), gomock.Any()).Times(0)
+			},
+			checkResponse: func(t *testing.T, rec *httptest.ResponseRecorder) {
+				require.Equal(t, http.StatusUnauthorized, rec.Code)
+			},
+		},
+	}
+
+	for i := range testCases {
+		tc := testCases[i]
+
+		t.Run(tc.name, func(t *testing.T) {
+			ctrl := gomock.NewController(t)
+			defer ctrl.Finish()
+
+			store := mockdb.NewMockStore(ctrl)
+			tc.buildStubs(store)
+
+			svr := newTestServer(t, store)
+			svr.config.ConsentMaxDuration = 90 * 24 * time.Hour
+			rec := httptest.NewRecorder()
+
+			data, err := json.Marshal(tc.body)
+			require.NoError(t, err)
+
+			req, err := http.NewRequest(http.MethodPost, "/open_banking/consents", bytes.NewReader(data))
+			require.NoError(t, err)
+
+			if tc.basicAuth {
+				req.SetBasicAuth(client.ID, secret)
+			}
+			svr.router.ServeHTTP(rec, req)
+			tc.checkResponse(t, rec)
+		})
+	}
+}
+
+func TestGetAccountConsentAPI(t *testing.T) {
+	client, secret := randomOAuthClient(t, util.RandomOwner(), true)
+
+	testCases := []struct {
+		name     string
+		clientID string
+		status   int
+	}{
+		{name: "OK", clientID: client.ID, status: http.StatusOK},
+		{name: "OtherClientsConsent", clientID: "other", status: http.StatusNotFound},
+	}
+
+	for i := range testCases {
+		tc := testCases[i]
+
+		t.Run(tc.name, func(t *testing.T) {
+			ctrl := gomock.NewController(t)
+			defer ctrl.Finish()
+
+			consent := randomAccountConsent(tc.clientID, util.RandomOwner(), []int64{1}, permissionReadBalances)
+			store := mockdb.NewMockStore(ctrl)
+			store.EXPECT().GetOAuthClient(gomock.Any(), gomock.Eq(client.ID)).Times(1).Return(client, nil)
+			store.EXPECT().GetAccountConsent(gomock.Any(), gomock.Eq(consent.ID)).Times(1).Return(consent, nil)
+
+			svr := newTestServer(t, store)
+			rec := httptest.NewRecorder()
+
+			req, err := http.NewRequest(http.MethodGet, fmt.Sprintf("/open_banking/consents/%d", consent.ID), nil)
+			require.NoError(t, err)
+
+			req.SetBasicAuth(client.ID, secret)
+			svr.router.ServeHTTP(rec, req)
+			require.Equal(t, tc.status, rec.Code)
+		})
+	}
+}
+
+func TestAccountConsentPublicClientAPI(t *testing.T) {
+	client, _ := randomOAuthClient(t, util.RandomOwner(), false)
+	consent := randomAccountConsent(client.ID, util.RandomOwner(), []int64{1}, permissionReadBalances)
+	body, err := json.Marshal(gin.H{"permissions": []string{permissionReadBalances}})
+	require.NoError(t, err)
+
+	testCases := []struct {
+		name   string
+		method string
+		path   string
+		body   []byte
+	}{
+		{name: "Create", method: http.MethodPost, path: "/open_banking/consents", body: body},
+		{name: "Get", method: http.MethodGet, path: fmt.Sprintf("/open_banking/consents/%d", consent.ID)},
+		{name: "Delete", method: http.MethodDelete, path: fmt.Sprintf("/open_banking/consents/%d", consent.ID)},
+	}
+
+	for i := range testCases {
+		tc := testCases[i]
+
+		t.Run(tc.name, func(t *testing.T) {
+			ctrl := gomock.NewController(t)
+			defer ctrl.Finish()
+
+			store := mockdb.NewMockStore(ctrl)
+			store.EXPECT().GetOAuthClient(gomock.Any(), gomock.Eq(client.ID)).Times(1).Return(client, nil)
+			store.EXPECT().CreateAccountConsent(gomock.Any(), gomock.Any()).Times(0)
+			store.EXPECT().GetAccountConsent(gomock.Any(), gomock.Any()).Times(0)
+			store.EXPECT().RevokeAccountConsent(gomock.Any(), gomock.Any()).Times(0)
+
+			svr := newTestServer(t, store)
+			rec := httptest.NewRecorder()
+
+			req, err := http.NewRequest(tc.method, tc.path, bytes.NewReader(tc.body))
+			require.NoError(t, err)
+
+			// a public client's id alone does not authenticate it
+			req.SetBasicAuth(client.ID, "")
+			svr.router.ServeHTTP(rec, req)
+			require.Equal(t, http.StatusUnauthorized, rec.Code)
+			require.Contains(t, rec.Body.String(), "invalid_client")
+		})
+	}
+}
+
+func TestAuthorizeAccountConsentAPI(t *testing.T) {
+	user, _ := randomUser(t)
+	client, _ := randomOAuthClient(t, util.RandomOwner(), false)
+	_, challenge := randomPKCE(t)
+	account := randomAccount(user.Username)
+	account.ID = 7
+	pending := randomAccountConsent(client.ID, "", nil, permissionReadBalances)
+	pending.Username = sql.NullString{}
+	pending.Status = db.ConsentAwaitingAuthorisation
+
+	body := gin.H{
+		"response_type":         "code",
+		"client_id":             client.ID,
+		"redirect_uri":          testRedirectURI,
+		"code_challenge":        challenge,
+		"code_challenge_method": codeChallengeMethodS256,
+		"consent_id":            pending.ID,
+		"account_ids":           []int64{account.ID},
+	}
+
+	testCases := []struct {
+		name       string
+		body       gin.H
+		buildStubs func(store *mockdb.MockStore)
+		status     int
+	}{
+		{
+			name: "OK",
+			body: body,
+			buildStubs: func(store *mockdb.MockStore) {
+				store.EXPECT().GetOAuthClient(gomock.Any(), gomock.Any()).Times(1).Return(client, nil)
+				store.EXPECT().GetAccountConsent(gomock.Any(), gomock.Eq(pending.ID)).Times(1).Return(pending, nil)
+				store.EXPECT().GetAccount(gomock.Any(), gomock.Eq(account.ID)).Times(1).Return(account, nil)
+				store.EXPECT().AuthorizeOAuthClientTx(gomock.Any(), gomock.Any()).Times(1).
+					DoAndReturn(func(_ context.Context, arg db.AuthorizeOAuthClientTxParams) error {
+						require.Equal(t, &db.AuthoriseAccountConsentParams{
+							ID:         pending.ID,
+							Username:   sql.NullString{String: user.Username, Valid: true},
+							AccountIds: []int64{account.ID},
+						}, arg.AccountConsent)
+						return nil
+					})
+			},
+			status: http.StatusOK,
+		},
+		{
+			name: "NoAccounts",
+			body: gin.H{
+				"response_type":         "code",
+				"client_id":             client.ID,
+				"redirect_uri":          testRedirectURI,
+				"code_challenge":        challenge,
+				"code_challenge_method": codeChallengeMethodS256,
+				"consent_id":            pending.ID,
+			},
+			buildStubs: func(store *mockdb.MockStore) {
+				store.EXPECT().GetOAuthClient(gomock.Any(), gomock.Any()).Times(0)
+			},
+			status: http.StatusBadRequest,
+		},
+		{
+			name: "ConsentOfOtherClient",
+			body: body,
+			buildStubs: func(store *mockdb.MockStore) {
+				other := pending
+				other.ClientID = "other"
+				store.EXPECT().GetOAuthClient(gomock.Any(), gomock.Any()).Times(1).Return(client, nil)
+				store.EXPECT().GetAccountConsent(gomock.Any(), gomock.Any()).Times(1).Return(other, nil)
+				store.EXPECT().AuthorizeOAuthClientTx(gomock.Any(), gomock.Any()).Times(0)
+			},
+			status: http.StatusBadRequest,
+		},
+		{
+			name: "ConsentAlreadyAuthorised",
+			body: body,
+			buildStubs: func(store *mockdb.MockStore) {
+				authorised := pending
+				authorised.Status = db.ConsentAuthorised
+				store.EXPECT().GetOAuthClient(gomock.Any(), gomock.Any()).Times(1).Return(client, nil)
+				store.EXPECT().GetAccountConsent(gomock.Any(), gomock.Any()).Times(1).Return(authorised, nil)
+				store.EXPECT().AuthorizeOAuthClientTx(gomock.Any(), gomock.Any()).Times(0)
+			},
+			status: http.StatusBadRequest,
+		},
+		{
+			name: "ConsentExpired",
+			body: body,
+			buildStubs: func(store *mockdb.MockStore) {
+				expired := pending
+				expired.ExpiresAt = time.Now().Add(-time.Second)
+				store.EXPECT().GetOAuthClient(gomock.Any(), gomock.Any()).Times(1).Return(client, nil)
+				store.EXPECT().GetAccountConsent(gomock.Any(), gomock.Any()).Times(1).Return(expired, nil)
+				store.EXPECT().AuthorizeOAuthClientTx(gomock.Any(), gomock.Any()).Times(0)
+			},
+			status: http.StatusBadRequest,
+		},
+		{
+			name: "AccountOfOtherUser",
+			body: body,
+			buildStubs: func(store *mockdb.MockStore) {
+				store.EXPECT().GetOAuthClient(gomock.Any(), gomock.Any()).Times(1).Return(client, nil)
+				store.EXPECT().GetAccountConsent(gomock.Any(), gomock.Any()).Times(1).Return(pending, nil)
+				store.EXPECT().GetAccount(gomock.Any(), gomock.Any()).Times(1).Return(randomAccount(util.RandomOwner()), nil)
+				store.EXPECT().GetAccountHolder(gomock.Any(), gomock.Any()).Times(1).Return(db.AccountHolder{}, sql.ErrNoRows)
+				store.EXPECT().GetAccountPermission(gomock.Any(), gomock.Any()).Times(1).Return(db.AccountPermission{}, sql.ErrNoRows)
+				store.EXPECT().AuthorizeOAuthClientTx(gomock.Any(), gomock.Any()).Times(0)
+			},
+			status: http.StatusUnauthorized,
+		},
+		{
+			name: "JointAccount",
+			body: body,
+			buildStubs: func(store *mockdb.MockStore) {
+				joint := randomAccount(util.RandomOwner())
+				joint.ID = account.ID
+				store.EXPECT().GetOAuthClient(gomock.Any(), gomock.Any()).Times(1).Return(client, nil)
+				store.EXPECT().GetAccountConsent(gomock.Any(), gomock.Any()).Times(1).Return(pending, nil)
+				store.EXPECT().GetAccount(gomock.Any(), gomock.Eq(account.ID)).Times(1).Return(joint, nil)
+				store.EXPECT().GetAccountHolder(gomock.Any(), gomock.Eq(db.GetAccountHolderParams{AccountID: joint.ID, Username: user.Username})).
+					Times(1).Return(db.AccountHolder{AccountID: joint.ID, Username: user.Username}, nil)
+				store.EXPECT().AuthorizeOAuthClientTx(gomock.Any(), gomock.Any()).Times(1).Return(nil)
+			},
+			status: http.StatusOK,
+		},
+		{
+			name: "ReadOnlyDelegateCannotShareForPayments",
+			body: body,
+			buildStubs: func(store *mockdb.MockStore) {
+				payments := pending
+				payments.Permissions = []string{permissionReadBalances, permissionInitiatePayments}
+				delegated := randomAccount(util.RandomOwner())
+				delegated.ID = account.ID
+				store.EXPECT().GetOAuthClient(gomock.Any(), gomock.Any()).Times(1).Return(client, nil)
+				store.EXPECT().GetAccountConsent(gomock.Any(), gomock.Any()).Times(1).Return(payments, nil)
+				store.EXPECT().GetAccount(gomock.Any(), gomock.Eq(account.ID)).Times(1).Return(delegated, nil)
+				store.EXPECT().GetAccountHolder(gomock.Any(), gomock.Any()).Times(1).Return(db.AccountHolder{}, sql.ErrNoRows)
+				store.EXPECT().GetAccountPermission(gomock.Any(), gomock.Any()).Times(1).
+					Return(db.AccountPermission{AccountID: delegated.ID, Username: user.Username, Access: accessRead}, nil)
+				store.EXPECT().AuthorizeOAuthClientTx(gomock.Any(), gomock.Any()).Times(0)
+			},
+			status: http.StatusUnauthorized,
+		},
+		{
+			name: "AuthorisedMeanwhile",
+			body: body,
+			buildStubs: func(store *mockdb.MockStore) {
+				store.EXPECT().GetOAuthClient(gomock.Any(), gomock.Any()).Times(1).Return(client, nil)
+				store.EXPECT().GetAccountConsent(gomock.Any(), gomock.Any()).Times(1).Return(pending, nil)
+				store.EXPECT().GetAccount(gomock.Any(), gomock.Any()).Times(1).Return(account, nil)
+				store.EXPECT().AuthorizeOAuthClientTx(gomock.Any(), gomock.Any()).Times(1).Return(sql.ErrNoRows)
+			},
+			status: http.StatusBadRequest,
+		},
+	}
+
+	for i := range testCases {
+		tc := testCases[i]
+
+		t.Run(tc.name, func(t *testing.T) {
+			ctrl := gomock.NewController(t)
+			defer ctrl.Finish()
+
+			store := mockdb.NewMockStore(ctrl)
+			stubAuthUsers(store)
+			tc.buildStubs(store)
+
+			svr := newTestServer(t, store)
+			rec := httptest.NewRecorder()
+
+			data, err := json.Marshal(tc.body)
+			require.NoError(t, err)
+
+			req, err := http.NewRequest(http.MethodPost, "/oauth/authorize", bytes.NewReader(data))
+			require.NoError(t, err)
+
+			addAuthorizationHeader(t, req, svr.tokenMaker, authorizationTypeBearer, user.Username, time.Minute)
+			svr.router.ServeHTTP(rec, req)
+			require.Equal(t, tc.status, rec.Code)
+		})
+	}
+}
+
+func TestConsentBoundToken(t *testing.T) {
+	user, _ := randomUser(t)
+	client, _ := randomOAuthClient(t, util.RandomOwner(), false)
+	covered := randomAccount(user.Username)
+	covered.ID = 1
+	uncovered := randomAccount(user.Username)
+	uncovered.ID = 2
+	uncovered.Currency = covered.Currency
+	shared := randomAccount(util.RandomOwner())
+	shared.ID = 3
+	consent := randomAccountConsent(client.ID, user.Username, []int64{covered.ID}, permissionReadBalances, permissionInitiatePayments)
+
+	testCases := []struct {
+		name       string
+		method     string
+		url        string
+		body       gin.H
+		consent    func() db.AccountConsent
+		buildStubs func(store *mockdb.MockStore)
+		status     int
+	}{
+		{
+			name:   "CoveredAccount",
+			method: http.MethodGet,
+			url:    fmt.Sprintf("/accounts/%d", covered.ID),
+			buildStubs: func(store *mockdb.MockStore) {
+				store.EXPECT().GetAccount(gomock.Any(), gomock.Eq(covered.ID)).Times(1).Return(covered, nil)
+			},
+			status: http.StatusOK,
+		},
+		{
+			name:   "UncoveredAccount",
+			method: http.MethodGet,
+			url:    fmt.Sprintf("/accounts/%d", uncovered.ID),
+			buildStubs: func(store *mockdb.MockStore) {
+				store.EXPECT().GetAccount(gomock.Any(), gomock.Any()).Times(0)
+			},
+			status: http.StatusForbidden,
+		},
+		{
+			name:   "MissingPermission",
+			method: http.MethodGet,
+			url:    fmt.Sprintf("/accounts/%d/statements?from=2024-01-01T00:00:00Z&to=2024-02-01T00:00:00Z", covered.ID),
+			buildStubs: func(store *mockdb.MockStore) {
+				store.EXPECT().GetAccount(gomock.Any(), gomock.Any()).Times(0)
+			},
+			status: http.StatusForbidden,
+		},
+		{
+			name:   "ListOnlyCoveredAccounts",
+			method: http.MethodGet,
+			url:    "/accounts/?page_id=1&page_size=5",
+			buildStubs: func(store *mockdb.MockStore) {
+				store.EXPECT().ListHolderAccounts(gomock.Any(), gomock.Any()).Times(0)
+				store.EXPECT().GetAccount(gomock.Any(), gomock.Eq(covered.ID)).Times(1).Return(covered, nil)
+			},
+			status: http.StatusOK,
+		},
+		{
+			name:   "ListSharedAccount",
+			method: http.MethodGet,
+			url:    "/accounts/?page_id=1&page_size=5",
+			consent: func() db.AccountConsent {
+				joint := consent
+				joint.AccountIds = []int64{covered.ID, shared.ID}
+				return joint
+			},
+			buildStubs: func(store *mockdb.MockStore) {
+				store.EXPECT().GetAccount(gomock.Any(), gomock.Eq(covered.ID)).Times(1).Return(covered, nil)
+				store.EXPECT().GetAccount(gomock.Any(), gomock.Eq(shared.ID)).Times(1).Return(shared, nil)
+				store.EXPECT().GetAccountHolder(gomock.Any(), gomock.Eq(db.GetAccountHolderParams{AccountID: shared.ID, Username: user.Username})).
+					Times(1).Return(db.AccountHolder{AccountID: shared.ID, Username: user.Username}, nil)
+			},
+			status: http.StatusOK,
+		},
+		{
+			name:   "TransferFromCoveredAccount",
+			method: http.MethodPost,
+			url:    "/transfers",
+			body:   gin.H{"from_account_id": covered.ID, "to_account_id": uncovered.ID, "amount": 10, "currency": covered.Currency},
+			buildStubs: func(store *mockdb.MockStore) {
+				store.EXPECT().GetAccount(gomock.Any(), gomock.Eq(covered.ID)).Times(1).Return(covered, nil)
+				store.EXPECT().GetAccount(gomock.Any(), gomock.Eq(uncovered.ID)).Times(1).Return(uncovered, nil)
+				store.EXPECT().GetApprovalPolicyForAmount(gomock.Any(), gomock.Any()).Times(1).Return(db.ApprovalPolicy{}, sql.ErrNoRows)
+				store.EXPECT().TransferTx(gomock.Any(), gomock.Any()).Times(1)
+			},
+			status: http.StatusOK,
+		},
+		{
+			name:   "TransferFromUncoveredAccount",
+			method: http.MethodPost,
+			url:    "/transfers",
+			body:   gin.H{"from_account_id": uncovered.ID, "to_account_id": covered.ID, "amount": 10, "currency": covered.Currency},
+			buildStubs: func(store *mockdb.MockStore) {
+				store.EXPECT().TransferTx(gomock.Any(), gomock.Any()).Times(0)
+			},
+			status: http.StatusForbidden,
+		},
+		{
+			name:   "StreamNotAvailable",
+			method: http.MethodGet,
+			url:    "/accounts/stream",
+			buildStubs: func(store *mockdb.MockStore) {
+			},
+			status: http.StatusForbidden,
+		},
+		{
+			name:   "ConsentRevoked",
+			method: http.MethodGet,
+			url:    fmt.Sprintf("/accounts/%d", covered.ID),
+			consent: func() db.AccountConsent {
+				revoked := consent
+				revoked.Status = db.ConsentRevoked
+				return revoked
+			},
+			buildStubs: func(store *mockdb.MockStore) {
+				store.EXPECT().GetAccount(gomock.Any(), gomock.Any()).Times(0)
+			},
+			status: http.StatusUnauthorized,
+		},
+		{
+			name:   "ConsentExpired",
+			method: http.MethodGet,
+			url:    fmt.Sprintf("/accounts/%d", covered.ID),
+			consent: func() db.AccountConsent {
+				expired := consent
+				expired.ExpiresAt = time.Now().Add(-time.Second)
+				return expired
+			},
+			buildStubs: func(store *mockdb.MockStore) {
+				store.EXPECT().GetAccount(gomock.Any(), gomock.Any()).Times(0)
+			},
+			status: http.StatusUnauthorized,
+		},
+		{
+			name:   "ConsentOfOtherUser",
+			method: http.MethodGet,
+			url:    fmt.Sprintf("/accounts/%d", covered.ID),
+			consent: func() db.AccountConsent {
+				other := consent
+				other.Username = sql.NullString{String: util.RandomOwner(), Valid: true}
+				return other
+			},
+			buildStubs: func(store *mockdb.MockStore) {
+				store.EXPECT().GetAccount(gomock.Any(), gomock.Any()).Times(0)
+			},
+			status: http.StatusUnauthorized,
+		},
+	}
+
+	for i := range testCases {
+		tc := testCases[i]
+
+		t.Run(tc.name, func(t *testing.T) {
+			ctrl := gomock.NewController(t)
+			defer ctrl.Finish()
+
+			accountConsent := consent
+			if tc.consent != nil {
+				accountConsent = tc.consent()
+			}
+
+			store := mockdb.NewMockStore(ctrl)
+			stubAuthUsers(store)
+			store.EXPECT().GetRevokedOAuthToken(gomock.Any(), gomock.Any()).AnyTimes().Return(db.OauthRevokedToken{}, sql.ErrNoRows)
+			store.EXPECT().GetOAuthConsent(gomock.Any(), gomock.Any()).AnyTimes().
+				Return(db.OauthConsent{Scopes: []string{token.ScopeAccountsRead, token.ScopeTransfersWrite}, CreatedAt: time.Now().Add(-time.Hour)}, nil)
+			store.EXPECT().GetAccountConsent(gomock.Any(), gomock.Eq(consent.ID)).AnyTimes().Return(accountConsent, nil)
+			tc.buildStubs(store)
+
+			svr := newTestServer(t, store)
+			rec := httptest.NewRecorder()
+
+			var body bytes.Buffer
+			if tc.body != nil {
+				require.NoError(t, json.NewEncoder(&body).Encode(tc.body))
+			}
+			req, err := http.NewRequest(tc.method, tc.url, &body)
+			require.NoError(t, err)
+
+			accessToken, err := svr.tokenMaker.CreateToken(user.Username, time.Minute,
+				token.WithClient(client.ID, []string{token.ScopeAccountsRead, token.ScopeTransfersWrite}),
+				token.WithConsent(consent.ID))
+			require.NoError(t, err)
+			req.Header.Set(authorizationHeaderKey, authorizationTypeOauth+" "+accessToken)
+
+			svr.router.ServeHTTP(rec, req)
+			require.Equal(t, tc.status, rec.Code)
+			switch tc.name {
+			case "ListOnlyCoveredAccounts":
+				var accs []db.Account
+				require.NoError(t, json.Unmarshal(rec.Body.Bytes(), &accs))
+				require.Equal(t, []db.Account{covered}, accs)
+			case "ListSharedAccount":
+				var accs []db.Account
+				require.NoError(t, json.Unmarshal(rec.Body.Bytes(), &accs))
+				require.Equal(t, []db.Account{covered, shared}, accs)
+			}
+		})
+	}
+}
+
+func TestRevokeAccountConsentAPI(t *testing.T) {
+	user, _ := randomUser(t)
+	consent := randomAccountConsent("app", user.Username, []int64{1}, permissionReadBalances)
+
+	testCases := []struct {
+		name       string
+		username   string
+		buildStubs func(store *mockdb.MockStore)
+		status     int
+	}{
+		{
+			name:     "OK",
+			username: user.Username,
+			buildStubs: func(store *mockdb.MockStore) {
+				revoked := consent
+				revoked.Status = db.ConsentRevoked
+				revoked.RevokedAt = sql.NullTime{Time: time.Now(), Valid: true}
+				store.EXPECT().GetAccountConsent(gomock.Any(), gomock.Eq(consent.ID)).Times(1).Return(consent, nil)
+				store.EXPECT().RevokeAccountConsent(gomock.Any(), gomock.Eq(consent.ID)).Times(1).Return(revoked, nil)
+			},
+			status: http.StatusOK,
+		},
+		{
+			name:     "OtherUsersConsent",
+			username: util.RandomOwner(),
+			buildStubs: func(store *mockdb.MockStore) {
+				store.EXPECT().GetAccountConsent(gomock.Any(), gomock.Any()).Times(1).Return(consent, nil)
+				store.EXPECT().RevokeAccountConsent(gomock.Any(), gomock.Any()).Times(0)
+			},
+			status: http.StatusUnauthorized,
+		},
+		{
+			name:     "AlreadyRevoked",
+			username: user.Username,
+			buildStubs: func(store *mockdb.MockStore) {
+				store.EXPECT().GetAccountConsent(gomock.Any(), gomock.Any()).Times(1).Return(consent, nil)
+				store.EXPECT().RevokeAccountConsent(gomock.Any(), gomock.Any()).Times(1).Return(db.AccountConsent{}, sql.ErrNoRows)
+			},
+			status: http.StatusNotFound,
+		},
+		{
+			name:     "NotFound",
+			username: user.Username,
+			buildStubs: func(store *mockdb.MockStore) {
+				store.EXPECT().GetAccountConsent(gomock.Any(), gomock.Any()).Times(1).Return(db.AccountConsent{}, sql.ErrNoRows)
+			},
+			status: http.StatusNotFound,
+		},
+	}
+
+	for i := range testCases {
+		tc := testCases[i]
+
+		t.Run(tc.name, func(t *testing.T) {
+			ctrl := gomock.NewController(t)
+			defer ctrl.Finish()
+
+			store := mockdb.NewMockStore(ctrl)
+			stubAuthUsers(store)
+			tc.buildStubs(store)
+
+			svr := newTestServer(t, store)
+			rec := httptest.NewRecorder()
+
+			req, err := http.NewRequest(http.MethodDelete, fmt.Sprintf("/account_consents/%d", consent.ID), nil)
+			require.NoError(t, err)
+
+			addAuthorizationHeader(t, req, svr.tokenMaker, authorizationTypeBearer, tc.username, time.Minute)
+			svr.router.ServeHTTP(rec, req)
+			require.Equal(t, tc.status, rec.Code)
+		})
+	}
+}
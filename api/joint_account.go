@@ -0,0 +1,145 @@
+package api
+
+import (
+	"database/sql"
+	"fmt"
+	db "gobank/db/sqlc"
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+	"github.com/lib/pq"
+)
+
+// A joint account has more than one holder. The owner is its primary holder, who adds and removes the
+// secondary ones and sets the approval rule. Every holder can use the account as if they owned it, except
+// that transfers of at least the joint approval threshold wait as a draft until every holder approved them.
+
+type addAccountHolderRequest struct {
+	Username string `json:"username" binding:"required,alphanum"`
+}
+
+// addAccountHolder makes another user a secondary holder of an account of the authenticated user.
+func (s *Server) addAccountHolder(ctx *gin.Context) {
+	var uri getAccountRequest
+	if err := ctx.ShouldBindUri(&uri); err != nil {
+		ctx.JSON(http.StatusBadRequest, errorResponse(err))
+		return
+	}
+	var req addAccountHolderRequest
+	if err := ctx.ShouldBindJSON(&req); err != nil {
+		ctx.JSON(http.StatusBadRequest, errorResponse(err))
+		return
+	}
+
+	acc, ok := s.ownedAccount(ctx, uri.ID)
+	if !ok {
+		return
+	}
+	holder, err := s.store.CreateAccountHolder(ctx, db.CreateAccountHolderParams{
+		AccountID: acc.ID,
+		Username:  req.Username,
+		Role:      db.HolderSecondary,
+	})
+	if err != nil {
+		if pqErr, ok := err.(*pq.Error); ok {
+			switch pqErr.Code.Name() {
+			case "foreign_key_violation":
+				err := fmt.Errorf("user %s not found", req.Username)
+				ctx.JSON(http.StatusNotFound, errorResponse(err))
+				return
+			case "unique_violation":
+				err := fmt.Errorf("user %s already holds the account", req.Username)
+				ctx.JSON(http.StatusConflict, errorResponse(err))
+				return
+			}
+		}
+		ctx.JSON(http.StatusInternalServerError, errorResponse(err))
+		return
+	}
+	ctx.JSON(http.StatusOK, holder)
+}
+
+// listAccountHolders shows anyone who can see an account who holds it.
+func (s *Server) listAccountHolders(ctx *gin.Context) {
+	var uri getAccountRequest
+	if err := ctx.ShouldBindUri(&uri); err != nil {
+		ctx.JSON(http.StatusBadRequest, errorResponse(err))
+		return
+	}
+	acc, ok := s.accountAccess(ctx, uri.ID, accessRead)
+	if !ok {
+		return
+	}
+
+	holders, err := s.store.ListAccountHolders(ctx, acc.ID)
+	if err != nil {
+		ctx.JSON(http.StatusInternalServerError, errorResponse(err))
+		return
+	}
+	ctx.JSON(http.StatusOK, holders)
+}
+
+type removeAccountHolderRequest struct {
+	ID       int64  `uri:"id" binding:"required,min=1"`
+	Username string `uri:"username" binding:"required,alphanum"`
+}
+
+// removeAccountHolder takes a secondary holder off an account. The primary holder cannot be removed.
+func (s *Server) removeAccountHolder(ctx *gin.Context) {
+	var req removeAccountHolderRequest
+	if err := ctx.ShouldBindUri(&req); err != nil {
+		ctx.JSON(http.StatusBadRequest, errorResponse(err))
+		return
+	}
+	acc, ok := s.ownedAccount(ctx, req.ID)
+	if !ok {
+		return
+	}
+
+	n, err := s.store.DeleteAccountHolder(ctx, db.DeleteAccountHolderParams{
+		AccountID: acc.ID,
+		Username:  req.Username,
+	})
+	if err != nil {
+		ctx.JSON(http.StatusInternalServerError, errorResponse(err))
+		return
+	}
+	if n == 0 {
+		ctx.JSON(http.StatusNotFound, errorResponse(sql.ErrNoRows))
+		return
+	}
+	ctx.Status(http.StatusNoContent)
+}
+
+type setApprovalRuleRequest struct {
+	// transfers of at least this amount need every holder to approve, 0 turns the rule off
+	JointApprovalThreshold int64 `json:"joint_approval_threshold" binding:"min=0"`
+}
+
+// setApprovalRule lets the primary holder decide which transfers need the approval of every holder.
+func (s *Server) setApprovalRule(ctx *gin.Context) {
+	var uri getAccountRequest
+	if err := ctx.ShouldBindUri(&uri); err != nil {
+		ctx.JSON(http.StatusBadRequest, errorResponse(err))
+		return
+	}
+	var req setApprovalRuleRequest
+	if err := ctx.ShouldBindJSON(&req); err != nil {
+		ctx.JSON(http.StatusBadRequest, errorResponse(err))
+		return
+	}
+	acc, ok := s.ownedAccount(ctx, uri.ID)
+	if !ok {
+		return
+	}
+
+	acc, err := s.store.UpdateAccountApprovalThreshold(ctx, db.UpdateAccountApprovalThresholdParams{
+		ID:                     acc.ID,
+		JointApprovalThreshold: req.JointApprovalThreshold,
+	})
+	if err != nil {
+		ctx.JSON(http.StatusInternalServerError, errorResponse(err))
+		return
+	}
+	ctx.JSON(http.StatusOK, acc)
+}
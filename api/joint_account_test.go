@@ -0,0 +1,250 @@
+package api
+
+import (
+	"bytes"
+	"database/sql"
+	"encoding/json"
+	"fmt"
+	mockdb "gobank/db/mock"
+	db "gobank/db/sqlc"
+	"gobank/util"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/golang/mock/gomock"
+	"github.com/lib/pq"
+	"github.com/stretchr/testify/require"
+)
+
+func TestAddAccountHolderAPI(t *testing.T) {
+	owner, _ := randomUser(t)
+	spouse, _ := randomUser(t)
+	acc := randomAccount(owner.Username)
+	acc.ID = 7
+
+	testCases := []struct {
+		name          string
+		username      string
+		buildStubs    func(store *mockdb.MockStore)
+		checkResponse func(t *testing.T, rec *httptest.ResponseRecorder)
+	}{
+		{
+			name:     "OK",
+			username: owner.Username,
+			buildStubs: func(store *mockdb.MockStore) {
+				arg := db.CreateAccountHolderParams{AccountID: acc.ID, Username: spouse.Username, Role: db.HolderSecondary}
+				store.EXPECT().GetAccount(gomock.Any(), gomock.Eq(acc.ID)).Times(1).Return(acc, nil)
+				store.EXPECT().CreateAccountHolder(gomock.Any(), gomock.Eq(arg)).Times(1).
+					Return(db.AccountHolder{AccountID: acc.ID, Username: spouse.Username, Role: db.HolderSecondary}, nil)
+			},
+			checkResponse: func(t *testing.T, rec *httptest.ResponseRecorder) {
+				require.Equal(t, http.StatusOK, rec.Code)
+
+				var holder db.AccountHolder
+				require.NoError(t, json.Unmarshal(rec.Body.Bytes(), &holder))
+				require.Equal(t, spouse.Username, holder.Username)
+				require.Equal(t, db.HolderSecondary, holder.Role)
+			},
+		},
+		{
+			name:     "NotPrimaryHolder",
+			username: spouse.Username,
+			buildStubs: func(store *mockdb.MockStore) {
+				store.EXPECT().GetAccount(gomock.Any(), gomock.Eq(acc.ID)).Times(1).Return(acc, nil)
+				store.EXPECT().CreateAccountHolder(gomock.Any(), gomock.Any()).Times(0)
+			},
+			checkResponse: func(t *testing.T, rec *httptest.ResponseRecorder) {
+				require.Equal(t, http.StatusUnauthorized, rec.Code)
+			},
+		},
+		{
+			name:     "AlreadyHolder",
+			username: owner.Username,
+			buildStubs: func(store *mockdb.MockStore) {
+				store.EXPECT().GetAccount(gomock.Any(), gomock.Eq(acc.ID)).Times(1).Return(acc, nil)
+				store.EXPECT().CreateAccountHolder(gomock.Any(), gomock.Any()).Times(1).
+					Return(db.AccountHolder{}, &pq.Error{Code: "23505"})
+			},
+			checkResponse: func(t *testing.T, rec *httptest.ResponseRecorder) {
+				require.Equal(t, http.StatusConflict, rec.Code)
+			},
+		},
+		{
+			name:     "UnknownUser",
+			username: owner.Username,
+			buildStubs: func(store *mockdb.MockStore) {
+				store.EXPECT().GetAccount(gomock.Any(), gomock.Eq(acc.ID)).Times(1).Return(acc, nil)
+				store.EXPECT().CreateAccountHolder(gomock.Any(), gomock.Any()).Times(1).
+					Return(db.AccountHolder{}, &pq.Error{Code: "23503"})
+			},
+			checkResponse: func(t *testing.T, rec *httptest.ResponseRecorder) {
+				require.Equal(t, http.StatusNotFound, rec.Code)
+			},
+		},
+	}
+
+	for i := range testCases {
+		tc := testCases[i]
+
+		t.Run(tc.name, func(t *testing.T) {
+			ctrl := gomock.NewController(t)
+			defer ctrl.Finish()
+
+			store := mockdb.NewMockStore(ctrl)
+			stubAuthUsers(store)
+			tc.buildStubs(store)
+
+			svr := newTestServer(t, store)
+			rec := httptest.NewRecorder()
+
+			data, err := json.Marshal(gin.H{"username": spouse.Username})
+			require.NoError(t, err)
+			req, err := http.NewRequest(http.MethodPost, fmt.Sprintf("/accounts/%d/holders", acc.ID), bytes.NewReader(data))
+			require.NoError(t, err)
+
+			addAuthorizationHeader(t, req, svr.tokenMaker, authorizationTypeBearer, tc.username, time.Minute)
+			svr.router.ServeHTTP(rec, req)
+			tc.checkResponse(t, rec)
+		})
+	}
+}
+
+func TestJointTransferAPI(t *testing.T) {
+	owner, _ := randomUser(t)
+	spouse, _ := randomUser(t)
+	payee, _ := randomUser(t)
+
+	joint := randomAccount(owner.Username)
+	joint.ID = 1
+	joint.Currency = util.USD
+	joint.JointApprovalThreshold = 100
+	to := randomAccount(payee.Username)
+	to.ID = 2
+	to.Currency = util.USD
+
+	holders := []db.AccountHolder{
+		{AccountID: joint.ID, Username: owner.Username, Role: db.HolderPrimary},
+		{AccountID: joint.ID, Username: spouse.Username, Role: db.HolderSecondary},
+	}
+
+	testCases := []struct {
+		name          string
+		username      string
+		amount        int64
+		buildStubs    func(store *mockdb.MockStore)
+		checkResponse func(t *testing.T, rec *httptest.ResponseRecorder)
+	}{
+		{
+			name:     "BelowThreshold",
+			username: owner.Username,
+			amount:   99,
+			buildStubs: func(store *mockdb.MockStore) {
+				store.EXPECT().GetAccount(gomock.Any(), gomock.Eq(joint.ID)).Times(1).Return(joint, nil)
+				store.EXPECT().GetAccount(gomock.Any(), gomock.Eq(to.ID)).Times(1).Return(to, nil)
+				store.EXPECT().ListAccountHolders(gomock.Any(), gomock.Any()).Times(0)
+				store.EXPECT().GetApprovalPolicyForAmount(gomock.Any(), gomock.Any()).Times(1).Return(db.ApprovalPolicy{}, sql.ErrNoRows)
+				store.EXPECT().TransferTx(gomock.Any(), gomock.Any()).Times(1)
+			},
+			checkResponse: func(t *testing.T, rec *httptest.ResponseRecorder) {
+				require.Equal(t, http.StatusOK, rec.Code)
+			},
+		},
+		{
+			name:     "SecondaryHolder",
+			username: spouse.Username,
+			amount:   99,
+			buildStubs: func(store *mockdb.MockStore) {
+				store.EXPECT().GetAccount(gomock.Any(), gomock.Eq(joint.ID)).Times(1).Return(joint, nil)
+				store.EXPECT().
+					GetAccountHolder(gomock.Any(), gomock.Eq(db.GetAccountHolderParams{AccountID: joint.ID, Username: spouse.Username})).
+					Times(1).
+					Return(holders[1], nil)
+				store.EXPECT().GetAccountPermission(gomock.Any(), gomock.Any()).Times(0)
+				store.EXPECT().GetAccount(gomock.Any(), gomock.Eq(to.ID)).Times(1).Return(to, nil)
+				store.EXPECT().GetApprovalPolicyForAmount(gomock.Any(), gomock.Any()).Times(1).Return(db.ApprovalPolicy{}, sql.ErrNoRows)
+				store.EXPECT().TransferTx(gomock.Any(), gomock.Any()).Times(1)
+			},
+			checkResponse: func(t *testing.T, rec *httptest.ResponseRecorder) {
+				require.Equal(t, http.StatusOK, rec.Code)
+			},
+		},
+		{
+			name:     "NeedsEveryHolder",
+			username: owner.Username,
+			amount:   100,
+			buildStubs: func(store *mockdb.MockStore) {
+				arg := db.CreateTransferDraftParams{
+					FromAccountID:     joint.ID,
+					ToAccountID:       to.ID,
+					Amount:            100,
+					CreatedBy:         owner.Username,
+					RequiredApprovals: 2,
+				}
+				store.EXPECT().GetAccount(gomock.Any(), gomock.Eq(joint.ID)).Times(1).Return(joint, nil)
+				store.EXPECT().GetAccount(gomock.Any(), gomock.Eq(to.ID)).Times(1).Return(to, nil)
+				store.EXPECT().ListAccountHolders(gomock.Any(), gomock.Eq(joint.ID)).Times(1).Return(holders, nil)
+				store.EXPECT().GetApprovalPolicyForAmount(gomock.Any(), gomock.Any()).Times(1).Return(db.ApprovalPolicy{}, sql.ErrNoRows)
+				store.EXPECT().CreateTransferDraftTx(gomock.Any(), gomock.Eq(arg)).Times(1).
+					Return(db.TransferDraft{ID: 3, Status: db.DraftPending, RequiredApprovals: 2}, nil)
+				store.EXPECT().TransferTx(gomock.Any(), gomock.Any()).Times(0)
+			},
+			checkResponse: func(t *testing.T, rec *httptest.ResponseRecorder) {
+				require.Equal(t, http.StatusAccepted, rec.Code)
+
+				var draft db.TransferDraft
+				require.NoError(t, json.Unmarshal(rec.Body.Bytes(), &draft))
+				require.Equal(t, int64(3), draft.ID)
+				require.Equal(t, db.DraftPending, draft.Status)
+			},
+		},
+		{
+			name:     "SingleHolder",
+			username: owner.Username,
+			amount:   100,
+			buildStubs: func(store *mockdb.MockStore) {
+				store.EXPECT().GetAccount(gomock.Any(), gomock.Eq(joint.ID)).Times(1).Return(joint, nil)
+				store.EXPECT().GetAccount(gomock.Any(), gomock.Eq(to.ID)).Times(1).Return(to, nil)
+				store.EXPECT().ListAccountHolders(gomock.Any(), gomock.Eq(joint.ID)).Times(1).Return(holders[:1], nil)
+				store.EXPECT().CreateTransferDraftTx(gomock.Any(), gomock.Any()).Times(0)
+				store.EXPECT().GetApprovalPolicyForAmount(gomock.Any(), gomock.Any()).Times(1).Return(db.ApprovalPolicy{}, sql.ErrNoRows)
+				store.EXPECT().TransferTx(gomock.Any(), gomock.Any()).Times(1)
+			},
+			checkResponse: func(t *testing.T, rec *httptest.ResponseRecorder) {
+				require.Equal(t, http.StatusOK, rec.Code)
+			},
+		},
+	}
+
+	for i := range testCases {
+		tc := testCases[i]
+
+		t.Run(tc.name, func(t *testing.T) {
+			ctrl := gomock.NewController(t)
+			defer ctrl.Finish()
+
+			store := mockdb.NewMockStore(ctrl)
+			stubAuthUsers(store)
+			tc.buildStubs(store)
+
+			svr := newTestServer(t, store)
+			rec := httptest.NewRecorder()
+
+			data, err := json.Marshal(gin.H{
+				"from_account_id": joint.ID,
+				"to_account_id":   to.ID,
+				"amount":          tc.amount,
+				"currency":        util.USD,
+			})
+			require.NoError(t, err)
+			req, err := http.NewRequest(http.MethodPost, "/transfers", bytes.NewReader(data))
+			require.NoError(t, err)
+
+			addAuthorizationHeader(t, req, svr.tokenMaker, authorizationTypeBearer, tc.username, time.Minute)
+			svr.router.ServeHTTP(rec, req)
+			tc.checkResponse(t, rec)
+		})
+	}
+}
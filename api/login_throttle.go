@@ -0,0 +1,118 @@
+package api
+
+import (
+	"errors"
+	"fmt"
+	db "gobank/db/sqlc"
+	"hash/fnv"
+	"math"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/gin-gonic/gin"
+)
+
+// errInvalidCredentials is the one answer to a failed login, so it does not tell which usernames exist.
+var errInvalidCredentials = errors.New("invalid username or password")
+
+// unknownUserSubjects is how many throttles logins naming no user share, so guessing at made-up usernames
+// cannot create throttle rows without end.
+const unknownUserSubjects = 1024
+
+// unknownUserSubject is the throttle subject of a username no user has. Usernames are alphanumeric, so it
+// never names a user.
+func unknownUserSubject(username string) string {
+	h := fnv.New32a()
+	h.Write([]byte(username))
+	return fmt.Sprintf("unknown:%d", h.Sum32()%unknownUserSubjects)
+}
+
+// loginThrottles lists the limits a login for subject, a username or unknownUserSubject, from the client's
+// ip counts against. A limit with no maximum configured is off.
+func (s *Server) loginThrottles(ctx *gin.Context, subject string) []db.LoginAttemptTxParams {
+	var throttles []db.LoginAttemptTxParams
+	add := func(scope, subject string, maxFailures int32) {
+		if maxFailures <= 0 {
+			return
+		}
+		throttles = append(throttles, db.LoginAttemptTxParams{
+			Scope:       scope,
+			Subject:     subject,
+			MaxFailures: maxFailures,
+			Window:      s.config.LoginFailureWindow,
+			Lockout:     s.config.LoginLockout,
+			MaxLockout:  s.config.LoginMaxLockout,
+		})
+	}
+	add(db.LoginScopeUser, subject, s.config.LoginMaxFailures)
+	add(db.LoginScopeIP, ctx.ClientIP(), s.config.LoginMaxFailuresPerIP)
+	return throttles
+}
+
+// beginLoginAttempt counts an attempt as failed against subject and the client's ip before the credentials
+// are checked, so concurrent guesses cannot all get in under the limit. It writes the error response and
+// returns false if either is locked out. An attempt whose credentials turn out right is taken back by
+// endLoginAttempt or clearLoginFailures.
+func (s *Server) beginLoginAttempt(ctx *gin.Context, subject string) bool {
+	throttles := s.loginThrottles(ctx, subject)
+	for i, t := range throttles {
+		lt, err := s.store.BeginLoginAttemptTx(ctx, t)
+		if err != nil {
+			ctx.JSON(http.StatusInternalServerError, errorResponse(err))
+			return false
+		}
+		if wait := time.Until(lt.LockedUntil); wait > 0 {
+			//the attempt never happens, so it must not count against the limits that let it through
+			if !s.refundLoginAttempts(ctx, throttles[:i]) {
+				return false
+			}
+			ctx.Header("Retry-After", strconv.Itoa(int(math.Ceil(wait.Seconds()))))
+			err := errors.New("too many failed login attempts, try again later")
+			ctx.JSON(http.StatusTooManyRequests, errorResponse(err))
+			return false
+		}
+	}
+	return true
+}
+
+// endLoginAttempt takes back the attempt of username once its credentials turned out right.
+func (s *Server) endLoginAttempt(ctx *gin.Context, username string) bool {
+	return s.refundLoginAttempts(ctx, s.loginThrottles(ctx, username))
+}
+
+// clearLoginFailures forgets the failures of username once it has fully logged in. Only the attempt is
+// taken back from the ip, or logging in to one's own account would reset the count while guessing at others.
+func (s *Server) clearLoginFailures(ctx *gin.Context, username string) bool {
+	for _, t := range s.loginThrottles(ctx, username) {
+		if t.Scope == db.LoginScopeIP {
+			if !s.refundLoginAttempts(ctx, []db.LoginAttemptTxParams{t}) {
+				return false
+			}
+			continue
+		}
+		_, err := s.store.DeleteLoginThrottle(ctx, db.DeleteLoginThrottleParams{
+			Scope:   t.Scope,
+			Subject: t.Subject,
+		})
+		if err != nil {
+			ctx.JSON(http.StatusInternalServerError, errorResponse(err))
+			return false
+		}
+	}
+	return true
+}
+
+func (s *Server) refundLoginAttempts(ctx *gin.Context, throttles []db.LoginAttemptTxParams) bool {
+	for _, t := range throttles {
+		err := s.store.RefundLoginAttempt(ctx, db.RefundLoginAttemptParams{
+			Scope:   t.Scope,
+			Subject: t.Subject,
+		})
+		if err != nil {
+			ctx.JSON(http.StatusInternalServerError, errorResponse(err))
+			return false
+		}
+	}
+	return true
+}
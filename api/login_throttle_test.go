@@ -0,0 +1,224 @@
+package api
+
+import (
+	"bytes"
+	"database/sql"
+	"encoding/json"
+	"fmt"
+	mockdb "gobank/db/mock"
+	db "gobank/db/sqlc"
+	"gobank/util"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/golang/mock/gomock"
+	"github.com/stretchr/testify/require"
+)
+
+const testClientIP = "203.0.113.7"
+
+func TestLoginLockoutAPI(t *testing.T) {
+	user, password := randomUser(t)
+
+	userThrottle := db.LoginAttemptTxParams{
+		Scope:       db.LoginScopeUser,
+		Subject:     user.Username,
+		MaxFailures: 5,
+		Window:      15 * time.Minute,
+		Lockout:     time.Minute,
+		MaxLockout:  time.Hour,
+	}
+	ipThrottle := db.LoginAttemptTxParams{
+		Scope:       db.LoginScopeIP,
+		Subject:     testClientIP,
+		MaxFailures: 50,
+		Window:      15 * time.Minute,
+		Lockout:     time.Minute,
+		MaxLockout:  time.Hour,
+	}
+	ipRefund := db.RefundLoginAttemptParams{Scope: db.LoginScopeIP, Subject: testClientIP}
+
+	testCases := []struct {
+		name          string
+		body          gin.H
+		buildStubs    func(store *mockdb.MockStore)
+		checkResponse func(t *testing.T, rec *httptest.ResponseRecorder)
+	}{
+		{
+			name: "OKClearsUserFailures",
+			body: gin.H{"username": user.Username, "password": password},
+			buildStubs: func(store *mockdb.MockStore) {
+				store.EXPECT().GetUser(gomock.Any(), gomock.Eq(user.Username)).Times(1).Return(user, nil)
+				store.EXPECT().BeginLoginAttemptTx(gomock.Any(), gomock.Eq(userThrottle)).Times(1).
+					Return(db.LoginThrottle{Failures: 3}, nil)
+				store.EXPECT().BeginLoginAttemptTx(gomock.Any(), gomock.Eq(ipThrottle)).Times(1).
+					Return(db.LoginThrottle{Failures: 1}, nil)
+				store.EXPECT().GetTOTPEnrollment(gomock.Any(), gomock.Any()).Times(1).Return(db.TotpEnrollment{}, sql.ErrNoRows)
+				store.EXPECT().DeleteLoginThrottle(gomock.Any(), gomock.Eq(db.DeleteLoginThrottleParams{
+					Scope:   db.LoginScopeUser,
+					Subject: user.Username,
+				})).Times(1).Return(int64(1), nil)
+				// the ip only gets its attempt back
+				store.EXPECT().RefundLoginAttempt(gomock.Any(), gomock.Eq(ipRefund)).Times(1).Return(nil)
+			},
+			checkResponse: func(t *testing.T, rec *httptest.ResponseRecorder) {
+				require.Equal(t, http.StatusOK, rec.Code)
+			},
+		},
+		{
+			name: "OKWithMFATakesBackTheAttempt",
+			body: gin.H{"username": user.Username, "password": password},
+			buildStubs: func(store *mockdb.MockStore) {
+				store.EXPECT().GetUser(gomock.Any(), gomock.Eq(user.Username)).Times(1).Return(user, nil)
+				store.EXPECT().BeginLoginAttemptTx(gomock.Any(), gomock.Any()).Times(2).Return(db.LoginThrottle{Failures: 1}, nil)
+				store.EXPECT().GetTOTPEnrollment(gomock.Any(), gomock.Any()).Times(1).
+					Return(db.TotpEnrollment{ConfirmedAt: sql.NullTime{Time: time.Now(), Valid: true}}, nil)
+				store.EXPECT().RefundLoginAttempt(gomock.Any(), gomock.Eq(db.RefundLoginAttemptParams{
+					Scope:   db.LoginScopeUser,
+					Subject: user.Username,
+				})).Times(1).Return(nil)
+				store.EXPECT().RefundLoginAttempt(gomock.Any(), gomock.Eq(ipRefund)).Times(1).Return(nil)
+				store.EXPECT().DeleteLoginThrottle(gomock.Any(), gomock.Any()).Times(0)
+			},
+			checkResponse: func(t *testing.T, rec *httptest.ResponseRecorder) {
+				require.Equal(t, http.StatusOK, rec.Code)
+				require.Contains(t, rec.Body.String(), "mfa_token")
+			},
+		},
+		{
+			name: "WrongPasswordKeepsTheAttempt",
+			body: gin.H{"username": user.Username, "password": "wrong-password"},
+			buildStubs: func(store *mockdb.MockStore) {
+				store.EXPECT().GetUser(gomock.Any(), gomock.Eq(user.Username)).Times(1).Return(user, nil)
+				store.EXPECT().BeginLoginAttemptTx(gomock.Any(), gomock.Eq(userThrottle)).Times(1).Return(db.LoginThrottle{Failures: 1}, nil)
+				store.EXPECT().BeginLoginAttemptTx(gomock.Any(), gomock.Eq(ipThrottle)).Times(1).Return(db.LoginThrottle{Failures: 1}, nil)
+				store.EXPECT().RefundLoginAttempt(gomock.Any(), gomock.Any()).Times(0)
+				store.EXPECT().DeleteLoginThrottle(gomock.Any(), gomock.Any()).Times(0)
+			},
+			checkResponse: func(t *testing.T, rec *httptest.ResponseRecorder) {
+				require.Equal(t, http.StatusUnauthorized, rec.Code)
+			},
+		},
+		{
+			name: "UnknownUserSharesABucket",
+			body: gin.H{"username": "nobody", "password": password},
+			buildStubs: func(store *mockdb.MockStore) {
+				store.EXPECT().GetUser(gomock.Any(), gomock.Eq("nobody")).Times(1).Return(db.User{}, sql.ErrNoRows)
+				bucket := userThrottle
+				bucket.Subject = unknownUserSubject("nobody")
+				store.EXPECT().BeginLoginAttemptTx(gomock.Any(), gomock.Eq(bucket)).Times(1).Return(db.LoginThrottle{Failures: 1}, nil)
+				store.EXPECT().BeginLoginAttemptTx(gomock.Any(), gomock.Eq(ipThrottle)).Times(1).Return(db.LoginThrottle{Failures: 1}, nil)
+			},
+			checkResponse: func(t *testing.T, rec *httptest.ResponseRecorder) {
+				require.Equal(t, http.StatusUnauthorized, rec.Code)
+				require.Contains(t, rec.Body.String(), errInvalidCredentials.Error())
+			},
+		},
+		{
+			name: "UserLockedOut",
+			body: gin.H{"username": user.Username, "password": password},
+			buildStubs: func(store *mockdb.MockStore) {
+				store.EXPECT().GetUser(gomock.Any(), gomock.Eq(user.Username)).Times(1).Return(user, nil)
+				store.EXPECT().BeginLoginAttemptTx(gomock.Any(), gomock.Eq(userThrottle)).Times(1).
+					Return(db.LoginThrottle{LockedUntil: time.Now().Add(90 * time.Second)}, nil)
+				store.EXPECT().BeginLoginAttemptTx(gomock.Any(), gomock.Eq(ipThrottle)).Times(0)
+				store.EXPECT().GetTOTPEnrollment(gomock.Any(), gomock.Any()).Times(0)
+			},
+			checkResponse: func(t *testing.T, rec *httptest.ResponseRecorder) {
+				require.Equal(t, http.StatusTooManyRequests, rec.Code)
+				require.Equal(t, "90", rec.Header().Get("Retry-After"))
+			},
+		},
+		{
+			name: "IPLockedOutTakesBackTheUserAttempt",
+			body: gin.H{"username": user.Username, "password": password},
+			buildStubs: func(store *mockdb.MockStore) {
+				store.EXPECT().GetUser(gomock.Any(), gomock.Eq(user.Username)).Times(1).Return(user, nil)
+				store.EXPECT().BeginLoginAttemptTx(gomock.Any(), gomock.Eq(userThrottle)).Times(1).Return(db.LoginThrottle{Failures: 1}, nil)
+				store.EXPECT().BeginLoginAttemptTx(gomock.Any(), gomock.Eq(ipThrottle)).Times(1).
+					Return(db.LoginThrottle{LockedUntil: time.Now().Add(time.Hour)}, nil)
+				store.EXPECT().RefundLoginAttempt(gomock.Any(), gomock.Eq(db.RefundLoginAttemptParams{
+					Scope:   db.LoginScopeUser,
+					Subject: user.Username,
+				})).Times(1).Return(nil)
+				store.EXPECT().GetTOTPEnrollment(gomock.Any(), gomock.Any()).Times(0)
+			},
+			checkResponse: func(t *testing.T, rec *httptest.ResponseRecorder) {
+				require.Equal(t, http.StatusTooManyRequests, rec.Code)
+			},
+		},
+		{
+			name: "BeginAttemptError",
+			body: gin.H{"username": user.Username, "password": "wrong-password"},
+			buildStubs: func(store *mockdb.MockStore) {
+				store.EXPECT().GetUser(gomock.Any(), gomock.Eq(user.Username)).Times(1).Return(user, nil)
+				store.EXPECT().BeginLoginAttemptTx(gomock.Any(), gomock.Any()).Times(1).Return(db.LoginThrottle{}, sql.ErrConnDone)
+			},
+			checkResponse: func(t *testing.T, rec *httptest.ResponseRecorder) {
+				require.Equal(t, http.StatusInternalServerError, rec.Code)
+			},
+		},
+	}
+
+	for i := range testCases {
+		tc := testCases[i]
+
+		t.Run(tc.name, func(t *testing.T) {
+			ctrl := gomock.NewController(t)
+			defer ctrl.Finish()
+
+			store := mockdb.NewMockStore(ctrl)
+			tc.buildStubs(store)
+
+			svr := newTestServer(t, store)
+			svr.config.LoginMaxFailures = 5
+			svr.config.LoginMaxFailuresPerIP = 50
+			svr.config.LoginFailureWindow = 15 * time.Minute
+			svr.config.LoginLockout = time.Minute
+			svr.config.LoginMaxLockout = time.Hour
+			rec := httptest.NewRecorder()
+
+			data, err := json.Marshal(tc.body)
+			require.NoError(t, err)
+
+			req, err := http.NewRequest(http.MethodPost, "/users/login", bytes.NewReader(data))
+			require.NoError(t, err)
+			req.RemoteAddr = testClientIP + ":40000"
+
+			svr.router.ServeHTTP(rec, req)
+			tc.checkResponse(t, rec)
+		})
+	}
+}
+
+func TestUnknownUserSubject(t *testing.T) {
+	require.Equal(t, unknownUserSubject("nobody"), unknownUserSubject("nobody"))
+
+	subjects := map[string]bool{}
+	for i := 0; i < 10*unknownUserSubjects; i++ {
+		subjects[unknownUserSubject(fmt.Sprintf("nobody%d", i))] = true
+	}
+	require.LessOrEqual(t, len(subjects), unknownUserSubjects)
+}
+
+func TestNewServerTrustedProxies(t *testing.T) {
+	cfg := util.Config{TokenSecretKey: util.RandomString(32)}
+
+	cfg.TrustedProxies = []string{"10.0.0.1", "192.168.0.0/16", "::1"}
+	svr, err := NewServer(cfg, nil)
+	require.NoError(t, err)
+	require.Equal(t, cfg.TrustedProxies, svr.router.TrustedProxies)
+
+	// gin trusts everyone unless told otherwise
+	cfg.TrustedProxies = nil
+	svr, err = NewServer(cfg, nil)
+	require.NoError(t, err)
+	require.Empty(t, svr.router.TrustedProxies)
+
+	cfg.TrustedProxies = []string{"proxy.internal"}
+	_, err = NewServer(cfg, nil)
+	require.Error(t, err)
+}
@@ -0,0 +1,236 @@
+package api
+
+import (
+	"database/sql"
+	"errors"
+	db "gobank/db/sqlc"
+	"gobank/token"
+	"gobank/totp"
+	"gobank/util"
+	"net/http"
+	"time"
+
+	"github.com/gin-gonic/gin"
+)
+
+const (
+	totpIssuer           = "gobank"
+	recoveryCodeCount    = 10
+	mfaChallengeDuration = 5 * time.Minute
+)
+
+type mfaChallengeResponse struct {
+	MFARequired bool      `json:"mfa_required"`
+	MFAToken    string    `json:"mfa_token"`
+	ExpiresAt   time.Time `json:"expires_at"`
+}
+
+// startMFAChallenge answers a login with a correct password for a user with two-factor authentication.
+// The token it returns is only good for loginMFA.
+func (s *Server) startMFAChallenge(ctx *gin.Context, u db.User) {
+	t, err := s.tokenMaker.CreateToken(u.Username, mfaChallengeDuration, token.WithPurpose(token.PurposeMFAChallenge))
+	if err != nil {
+		ctx.JSON(http.StatusInternalServerError, errorResponse(err))
+		return
+	}
+	ctx.JSON(http.StatusOK, mfaChallengeResponse{
+		MFARequired: true,
+		MFAToken:    t,
+		ExpiresAt:   time.Now().Add(mfaChallengeDuration),
+	})
+}
+
+type loginMFARequest struct {
+	MFAToken     string `json:"mfa_token" binding:"required"`
+	Code         string `json:"code" binding:"required_without=RecoveryCode,omitempty,len=6,numeric"`
+	RecoveryCode string `json:"recovery_code" binding:"required_without=Code"`
+}
+
+// loginMFA completes a two-step login with either a current TOTP code or an unused recovery code.
+func (s *Server) loginMFA(ctx *gin.Context) {
+	var req loginMFARequest
+	if err := ctx.ShouldBindJSON(&req); err != nil {
+		ctx.JSON(http.StatusBadRequest, errorResponse(err))
+		return
+	}
+
+	payload, err := s.tokenMaker.VerifyToken(req.MFAToken)
+	if err != nil {
+		ctx.JSON(http.StatusUnauthorized, errorResponse(err))
+		return
+	}
+	if payload.Purpose != token.PurposeMFAChallenge {
+		err := errors.New("not an mfa challenge token")
+		ctx.JSON(http.StatusUnauthorized, errorResponse(err))
+		return
+	}
+
+	if !s.beginLoginAttempt(ctx, payload.Username) {
+		return
+	}
+
+	u, err := s.store.GetUser(ctx, payload.Username)
+	if err != nil {
+		if err == sql.ErrNoRows {
+			ctx.JSON(http.StatusUnauthorized, errorResponse(err))
+			return
+		}
+		ctx.JSON(http.StatusInternalServerError, errorResponse(err))
+		return
+	}
+	if payload.IssuedAt.Before(u.PasswordChangedAt) {
+		err := errors.New("session has been revoked")
+		ctx.JSON(http.StatusUnauthorized, errorResponse(err))
+		return
+	}
+
+	var used int64
+	method := token.AMROTP
+	if req.Code != "" {
+		used, err = s.useTOTPCode(ctx, u.Username, req.Code)
+	} else {
+		method = token.AMRRecoveryCode
+		used, err = s.store.UseRecoveryCode(ctx, db.UseRecoveryCodeParams{
+			Username: u.Username,
+			CodeHash: util.HashToken(totp.NormalizeRecoveryCode(req.RecoveryCode)),
+		})
+	}
+	if err != nil {
+		ctx.JSON(http.StatusInternalServerError, errorResponse(err))
+		return
+	}
+	if used == 0 {
+		ctx.JSON(http.StatusUnauthorized, errorResponse(errors.New("invalid or already used code")))
+		return
+	}
+
+	if !s.clearLoginFailures(ctx, u.Username) {
+		return
+	}
+	s.issueAccessToken(ctx, u, token.AMRPassword, method)
+}
+
+// useTOTPCode checks code against the user's confirmed enrollment and records its time step, returning 0
+// if the code is wrong or was used before.
+func (s *Server) useTOTPCode(ctx *gin.Context, username, code string) (int64, error) {
+	enrollment, err := s.store.GetTOTPEnrollment(ctx, username)
+	if err != nil {
+		if err == sql.ErrNoRows {
+			return 0, nil
+		}
+		return 0, err
+	}
+	if !enrollment.ConfirmedAt.Valid {
+		return 0, nil
+	}
+
+	step, ok := totp.Validate(enrollment.Secret, code, time.Now())
+	if !ok {
+		return 0, nil
+	}
+	return s.store.UseTOTPStep(ctx, db.UseTOTPStepParams{Username: username, Step: step})
+}
+
+type enrollTOTPResponse struct {
+	Secret     string `json:"secret"`
+	OtpauthURI string `json:"otpauth_uri"`
+}
+
+// enrollTOTP starts setting up an authenticator app. Two-factor login only takes effect once confirmTOTP
+// has seen a code from the app; until then calling this again replaces the secret.
+func (s *Server) enrollTOTP(ctx *gin.Context) {
+	user := ctx.MustGet(authorizationUserKey).(db.User)
+
+	secret, err := totp.NewSecret()
+	if err != nil {
+		ctx.JSON(http.StatusInternalServerError, errorResponse(err))
+		return
+	}
+
+	enrollment, err := s.store.StartTOTPEnrollment(ctx, db.StartTOTPEnrollmentParams{
+		Username: user.Username,
+		Secret:   secret,
+	})
+	if err != nil {
+		if err == sql.ErrNoRows {
+			err = errors.New("two-factor authentication is already enabled")
+			ctx.JSON(http.StatusConflict, errorResponse(err))
+			return
+		}
+		ctx.JSON(http.StatusInternalServerError, errorResponse(err))
+		return
+	}
+
+	ctx.JSON(http.StatusOK, enrollTOTPResponse{
+		Secret:     enrollment.Secret,
+		OtpauthURI: totp.URI(totpIssuer, user.Username, enrollment.Secret),
+	})
+}
+
+type confirmTOTPRequest struct {
+	Code string `json:"code" binding:"required,len=6,numeric"`
+}
+
+type confirmTOTPResponse struct {
+	RecoveryCodes []string `json:"recovery_codes"`
+}
+
+// confirmTOTP enables two-factor login with the first code from the authenticator app and returns the
+// recovery codes. They are only shown this once.
+func (s *Server) confirmTOTP(ctx *gin.Context) {
+	var req confirmTOTPRequest
+	if err := ctx.ShouldBindJSON(&req); err != nil {
+		ctx.JSON(http.StatusBadRequest, errorResponse(err))
+		return
+	}
+	user := ctx.MustGet(authorizationUserKey).(db.User)
+
+	enrollment, err := s.store.GetTOTPEnrollment(ctx, user.Username)
+	if err != nil {
+		if err == sql.ErrNoRows {
+			err = errors.New("no two-factor enrollment in progress")
+			ctx.JSON(http.StatusNotFound, errorResponse(err))
+			return
+		}
+		ctx.JSON(http.StatusInternalServerError, errorResponse(err))
+		return
+	}
+	if enrollment.ConfirmedAt.Valid {
+		err := errors.New("two-factor authentication is already enabled")
+		ctx.JSON(http.StatusConflict, errorResponse(err))
+		return
+	}
+
+	step, ok := totp.Validate(enrollment.Secret, req.Code, time.Now())
+	if !ok {
+		err := errors.New("invalid code")
+		ctx.JSON(http.StatusBadRequest, errorResponse(err))
+		return
+	}
+
+	codes, err := totp.NewRecoveryCodes(recoveryCodeCount)
+	if err != nil {
+		ctx.JSON(http.StatusInternalServerError, errorResponse(err))
+		return
+	}
+	hashes := make([]string, len(codes))
+	for i, c := range codes {
+		hashes[i] = util.HashToken(totp.NormalizeRecoveryCode(c))
+	}
+
+	_, err = s.store.ConfirmTOTPTx(ctx, db.ConfirmTOTPTxParams{
+		Username:           user.Username,
+		Step:               step,
+		RecoveryCodeHashes: hashes,
+	})
+	if err != nil {
+		if err == sql.ErrNoRows {
+			err = errors.New("two-factor authentication is already enabled")
+			ctx.JSON(http.StatusConflict, errorResponse(err))
+			return
+		}
+		ctx.JSON(http.StatusInternalServerError, errorResponse(err))
+		return
+	}
+	ctx.JSON(http.StatusOK, confirmTOTPResponse{RecoveryCodes: codes})
+}
@@ -0,0 +1,515 @@
+package api
+
+import (
+	"bytes"
+	"context"
+	"database/sql"
+	"encoding/json"
+	"fmt"
+	mockdb "gobank/db/mock"
+	db "gobank/db/sqlc"
+	"gobank/token"
+	"gobank/totp"
+	"gobank/util"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/golang/mock/gomock"
+	"github.com/stretchr/testify/require"
+	"golang.org/x/crypto/bcrypt"
+)
+
+func confirmedEnrollment(t *testing.T, username string) db.TotpEnrollment {
+	secret, err := totp.NewSecret()
+	require.NoError(t, err)
+	return db.TotpEnrollment{
+		Username:    username,
+		Secret:      secret,
+		ConfirmedAt: sql.NullTime{Time: time.Now(), Valid: true},
+	}
+}
+
+func mustAtoi(t *testing.T, s string) int {
+	n, err := strconv.Atoi(s)
+	require.NoError(t, err)
+	return n
+}
+
+func TestLoginAPI(t *testing.T) {
+	user, password := randomUser(t)
+	enrollment := confirmedEnrollment(t, user.Username)
+
+	testCases := []struct {
+		name          string
+		body          gin.H
+		buildStubs    func(store *mockdb.MockStore)
+		checkResponse func(t *testing.T, rec *httptest.ResponseRecorder, tm token.Maker)
+	}{
+		{
+			name: "OK",
+			body: gin.H{"username": user.Username, "password": password},
+			buildStubs: func(store *mockdb.MockStore) {
+				store.EXPECT().GetUser(gomock.Any(), gomock.Eq(user.Username)).Times(1).Return(user, nil)
+				store.EXPECT().RehashUserPassword(gomock.Any(), gomock.Any()).Times(0)
+				store.EXPECT().GetTOTPEnrollment(gomock.Any(), gomock.Eq(user.Username)).Times(1).Return(db.TotpEnrollment{}, sql.ErrNoRows)
+			},
+			checkResponse: func(t *testing.T, rec *httptest.ResponseRecorder, tm token.Maker) {
+				require.Equal(t, http.StatusOK, rec.Code)
+
+				var rsp loginUserResponse
+				require.NoError(t, json.Unmarshal(rec.Body.Bytes(), &rsp))
+				payload, err := tm.VerifyToken(rsp.AccessToken)
+				require.NoError(t, err)
+				require.Empty(t, payload.Purpose)
+				require.Equal(t, []string{token.AMRPassword}, payload.AMR)
+			},
+		},
+		{
+			name: "RehashesOutdatedHash",
+			body: gin.H{"username": user.Username, "password": password},
+			buildStubs: func(store *mockdb.MockStore) {
+				bcryptHash, err := bcrypt.GenerateFromPassword([]byte(password), bcrypt.MinCost)
+				require.NoError(t, err)
+				legacy := user
+				legacy.HashedPassword = string(bcryptHash)
+
+				store.EXPECT().GetUser(gomock.Any(), gomock.Eq(user.Username)).Times(1).Return(legacy, nil)
+				store.EXPECT().RehashUserPassword(gomock.Any(), gomock.Any()).Times(1).
+					DoAndReturn(func(_ context.Context, arg db.RehashUserPasswordParams) error {
+						require.Equal(t, user.Username, arg.Username)
+						require.Equal(t, legacy.HashedPassword, arg.OldHashedPassword)
+						require.True(t, strings.HasPrefix(arg.NewHashedPassword, "$argon2id$"))
+						require.NoError(t, util.CheckPassword(password, arg.NewHashedPassword))
+						return nil
+					})
+				store.EXPECT().GetTOTPEnrollment(gomock.Any(), gomock.Eq(user.Username)).Times(1).Return(db.TotpEnrollment{}, sql.ErrNoRows)
+			},
+			checkResponse: func(t *testing.T, rec *httptest.ResponseRecorder, tm token.Maker) {
+				require.Equal(t, http.StatusOK, rec.Code)
+			},
+		},
+		{
+			name: "RehashErrorStillLogsIn",
+			body: gin.H{"username": user.Username, "password": password},
+			buildStubs: func(store *mockdb.MockStore) {
+				bcryptHash, err := bcrypt.GenerateFromPassword([]byte(password), bcrypt.MinCost)
+				require.NoError(t, err)
+				legacy := user
+				legacy.HashedPassword = string(bcryptHash)
+
+				store.EXPECT().GetUser(gomock.Any(), gomock.Eq(user.Username)).Times(1).Return(legacy, nil)
+				store.EXPECT().RehashUserPassword(gomock.Any(), gomock.Any()).Times(1).Return(sql.ErrConnDone)
+				store.EXPECT().GetTOTPEnrollment(gomock.Any(), gomock.Eq(user.Username)).Times(1).Return(db.TotpEnrollment{}, sql.ErrNoRows)
+			},
+			checkResponse: func(t *testing.T, rec *httptest.ResponseRecorder, tm token.Maker) {
+				require.Equal(t, http.StatusOK, rec.Code)
+			},
+		},
+		{
+			name: "UnconfirmedEnrollment",
+			body: gin.H{"username": user.Username, "password": password},
+			buildStubs: func(store *mockdb.MockStore) {
+				pending := enrollment
+				pending.ConfirmedAt = sql.NullTime{}
+				store.EXPECT().GetUser(gomock.Any(), gomock.Eq(user.Username)).Times(1).Return(user, nil)
+				store.EXPECT().GetTOTPEnrollment(gomock.Any(), gomock.Eq(user.Username)).Times(1).Return(pending, nil)
+			},
+			checkResponse: func(t *testing.T, rec *httptest.ResponseRecorder, tm token.Maker) {
+				require.Equal(t, http.StatusOK, rec.Code)
+				require.Contains(t, rec.Body.String(), "access_token")
+			},
+		},
+		{
+			name: "MFARequired",
+			body: gin.H{"username": user.Username, "password": password},
+			buildStubs: func(store *mockdb.MockStore) {
+				store.EXPECT().GetUser(gomock.Any(), gomock.Eq(user.Username)).Times(1).Return(user, nil)
+				store.EXPECT().GetTOTPEnrollment(gomock.Any(), gomock.Eq(user.Username)).Times(1).Return(enrollment, nil)
+			},
+			checkResponse: func(t *testing.T, rec *httptest.ResponseRecorder, tm token.Maker) {
+				require.Equal(t, http.StatusOK, rec.Code)
+				require.NotContains(t, rec.Body.String(), "access_token")
+
+				var rsp mfaChallengeResponse
+				require.NoError(t, json.Unmarshal(rec.Body.Bytes(), &rsp))
+				require.True(t, rsp.MFARequired)
+				payload, err := tm.VerifyToken(rsp.MFAToken)
+				require.NoError(t, err)
+				require.Equal(t, token.PurposeMFAChallenge, payload.Purpose)
+			},
+		},
+		{
+			name: "WrongPassword",
+			body: gin.H{"username": user.Username, "password": "wrong-password"},
+			buildStubs: func(store *mockdb.MockStore) {
+				store.EXPECT().GetUser(gomock.Any(), gomock.Eq(user.Username)).Times(1).Return(user, nil)
+				store.EXPECT().GetTOTPEnrollment(gomock.Any(), gomock.Any()).Times(0)
+			},
+			checkResponse: func(t *testing.T, rec *httptest.ResponseRecorder, tm token.Maker) {
+				require.Equal(t, http.StatusUnauthorized, rec.Code)
+				require.Contains(t, rec.Body.String(), errInvalidCredentials.Error())
+			},
+		},
+		{
+			name: "UnknownUser",
+			body: gin.H{"username": "nobody", "password": password},
+			buildStubs: func(store *mockdb.MockStore) {
+				store.EXPECT().GetUser(gomock.Any(), gomock.Eq("nobody")).Times(1).Return(db.User{}, sql.ErrNoRows)
+				store.EXPECT().GetTOTPEnrollment(gomock.Any(), gomock.Any()).Times(0)
+			},
+			checkResponse: func(t *testing.T, rec *httptest.ResponseRecorder, tm token.Maker) {
+				//same answer as a wrong password, so it does not give away which usernames exist
+				require.Equal(t, http.StatusUnauthorized, rec.Code)
+				require.Contains(t, rec.Body.String(), errInvalidCredentials.Error())
+			},
+		},
+		{
+			name: "EnrollmentError",
+			body: gin.H{"username": user.Username, "password": password},
+			buildStubs: func(store *mockdb.MockStore) {
+				store.EXPECT().GetUser(gomock.Any(), gomock.Eq(user.Username)).Times(1).Return(user, nil)
+				store.EXPECT().GetTOTPEnrollment(gomock.Any(), gomock.Any()).Times(1).Return(db.TotpEnrollment{}, sql.ErrConnDone)
+			},
+			checkResponse: func(t *testing.T, rec *httptest.ResponseRecorder, tm token.Maker) {
+				require.Equal(t, http.StatusInternalServerError, rec.Code)
+			},
+		},
+	}
+
+	for i := range testCases {
+		tc := testCases[i]
+
+		t.Run(tc.name, func(t *testing.T) {
+			ctrl := gomock.NewController(t)
+			defer ctrl.Finish()
+
+			store := mockdb.NewMockStore(ctrl)
+			tc.buildStubs(store)
+
+			svr := newTestServer(t, store)
+			rec := httptest.NewRecorder()
+
+			data, err := json.Marshal(tc.body)
+			require.NoError(t, err)
+
+			req, err := http.NewRequest(http.MethodPost, "/users/login", bytes.NewReader(data))
+			require.NoError(t, err)
+
+			svr.router.ServeHTTP(rec, req)
+			tc.checkResponse(t, rec, svr.tokenMaker)
+		})
+	}
+}
+
+func TestLoginMFAAPI(t *testing.T) {
+	user, _ := randomUser(t)
+	enrollment := confirmedEnrollment(t, user.Username)
+	now := time.Now()
+	code, err := totp.Code(enrollment.Secret, totp.Step(now))
+	require.NoError(t, err)
+	wrongCode := fmt.Sprintf("%06d", (mustAtoi(t, code)+1)%1000000)
+
+	mfaToken := func(tm token.Maker) string {
+		tok, err := tm.CreateToken(user.Username, time.Minute, token.WithPurpose(token.PurposeMFAChallenge))
+		require.NoError(t, err)
+		return tok
+	}
+	accessToken := func(tm token.Maker) string {
+		tok, err := tm.CreateToken(user.Username, time.Minute)
+		require.NoError(t, err)
+		return tok
+	}
+
+	testCases := []struct {
+		name          string
+		token         func(tm token.Maker) string
+		body          gin.H
+		buildStubs    func(store *mockdb.MockStore)
+		checkResponse func(t *testing.T, rec *httptest.ResponseRecorder)
+	}{
+		{
+			name:  "TOTPCode",
+			token: mfaToken,
+			body:  gin.H{"code": code},
+			buildStubs: func(store *mockdb.MockStore) {
+				store.EXPECT().GetUser(gomock.Any(), gomock.Eq(user.Username)).Times(1).Return(user, nil)
+				store.EXPECT().GetTOTPEnrollment(gomock.Any(), gomock.Eq(user.Username)).Times(1).Return(enrollment, nil)
+				store.EXPECT().
+					UseTOTPStep(gomock.Any(), gomock.Eq(db.UseTOTPStepParams{Username: user.Username, Step: totp.Step(now)})).
+					Times(1).
+					Return(int64(1), nil)
+			},
+			checkResponse: func(t *testing.T, rec *httptest.ResponseRecorder) {
+				require.Equal(t, http.StatusOK, rec.Code)
+				require.Contains(t, rec.Body.String(), "access_token")
+			},
+		},
+		{
+			name:  "ReplayedCode",
+			token: mfaToken,
+			body:  gin.H{"code": code},
+			buildStubs: func(store *mockdb.MockStore) {
+				store.EXPECT().GetUser(gomock.Any(), gomock.Eq(user.Username)).Times(1).Return(user, nil)
+				store.EXPECT().GetTOTPEnrollment(gomock.Any(), gomock.Eq(user.Username)).Times(1).Return(enrollment, nil)
+				store.EXPECT().UseTOTPStep(gomock.Any(), gomock.Any()).Times(1).Return(int64(0), nil)
+			},
+			checkResponse: func(t *testing.T, rec *httptest.ResponseRecorder) {
+				require.Equal(t, http.StatusUnauthorized, rec.Code)
+			},
+		},
+		{
+			name:  "WrongCode",
+			token: mfaToken,
+			body:  gin.H{"code": wrongCode},
+			buildStubs: func(store *mockdb.MockStore) {
+				store.EXPECT().GetUser(gomock.Any(), gomock.Eq(user.Username)).Times(1).Return(user, nil)
+				store.EXPECT().GetTOTPEnrollment(gomock.Any(), gomock.Eq(user.Username)).Times(1).Return(enrollment, nil)
+				store.EXPECT().UseTOTPStep(gomock.Any(), gomock.Any()).Times(0)
+			},
+			checkResponse: func(t *testing.T, rec *httptest.ResponseRecorder) {
+				require.Equal(t, http.StatusUnauthorized, rec.Code)
+			},
+		},
+		{
+			name:  "RecoveryCode",
+			token: mfaToken,
+			body:  gin.H{"recovery_code": "K7M2P-X9Q4R"},
+			buildStubs: func(store *mockdb.MockStore) {
+				arg := db.UseRecoveryCodeParams{Username: user.Username, CodeHash: util.HashToken("k7m2px9q4r")}
+				store.EXPECT().GetUser(gomock.Any(), gomock.Eq(user.Username)).Times(1).Return(user, nil)
+				store.EXPECT().UseRecoveryCode(gomock.Any(), gomock.Eq(arg)).Times(1).Return(int64(1), nil)
+			},
+			checkResponse: func(t *testing.T, rec *httptest.ResponseRecorder) {
+				require.Equal(t, http.StatusOK, rec.Code)
+				require.Contains(t, rec.Body.String(), "access_token")
+			},
+		},
+		{
+			name:  "UsedRecoveryCode",
+			token: mfaToken,
+			body:  gin.H{"recovery_code": "k7m2p-x9q4r"},
+			buildStubs: func(store *mockdb.MockStore) {
+				store.EXPECT().GetUser(gomock.Any(), gomock.Eq(user.Username)).Times(1).Return(user, nil)
+				store.EXPECT().UseRecoveryCode(gomock.Any(), gomock.Any()).Times(1).Return(int64(0), nil)
+			},
+			checkResponse: func(t *testing.T, rec *httptest.ResponseRecorder) {
+				require.Equal(t, http.StatusUnauthorized, rec.Code)
+			},
+		},
+		{
+			name:  "AccessTokenIsNotAChallenge",
+			token: accessToken,
+			body:  gin.H{"code": code},
+			buildStubs: func(store *mockdb.MockStore) {
+				store.EXPECT().GetUser(gomock.Any(), gomock.Any()).Times(0)
+			},
+			checkResponse: func(t *testing.T, rec *httptest.ResponseRecorder) {
+				require.Equal(t, http.StatusUnauthorized, rec.Code)
+			},
+		},
+		{
+			name:  "NoCode",
+			token: mfaToken,
+			body:  gin.H{},
+			buildStubs: func(store *mockdb.MockStore) {
+				store.EXPECT().GetUser(gomock.Any(), gomock.Any()).Times(0)
+			},
+			checkResponse: func(t *testing.T, rec *httptest.ResponseRecorder) {
+				require.Equal(t, http.StatusBadRequest, rec.Code)
+			},
+		},
+	}
+
+	for i := range testCases {
+		tc := testCases[i]
+
+		t.Run(tc.name, func(t *testing.T) {
+			ctrl := gomock.NewController(t)
+			defer ctrl.Finish()
+
+			store := mockdb.NewMockStore(ctrl)
+			tc.buildStubs(store)
+
+			svr := newTestServer(t, store)
+			rec := httptest.NewRecorder()
+
+			body := tc.body
+			body["mfa_token"] = tc.token(svr.tokenMaker)
+			data, err := json.Marshal(body)
+			require.NoError(t, err)
+
+			req, err := http.NewRequest(http.MethodPost, "/users/login/mfa", bytes.NewReader(data))
+			require.NoError(t, err)
+
+			svr.router.ServeHTTP(rec, req)
+			tc.checkResponse(t, rec)
+		})
+	}
+}
+
+func TestEnrollTOTPAPI(t *testing.T) {
+	user, _ := randomUser(t)
+
+	testCases := []struct {
+		name          string
+		buildStubs    func(store *mockdb.MockStore)
+		checkResponse func(t *testing.T, rec *httptest.ResponseRecorder)
+	}{
+		{
+			name: "OK",
+			buildStubs: func(store *mockdb.MockStore) {
+				store.EXPECT().
+					StartTOTPEnrollment(gomock.Any(), gomock.Any()).
+					Times(1).
+					DoAndReturn(func(_ context.Context, arg db.StartTOTPEnrollmentParams) (db.TotpEnrollment, error) {
+						require.Equal(t, user.Username, arg.Username)
+						return db.TotpEnrollment{Username: arg.Username, Secret: arg.Secret}, nil
+					})
+			},
+			checkResponse: func(t *testing.T, rec *httptest.ResponseRecorder) {
+				require.Equal(t, http.StatusOK, rec.Code)
+
+				var rsp enrollTOTPResponse
+				require.NoError(t, json.Unmarshal(rec.Body.Bytes(), &rsp))
+				require.NotEmpty(t, rsp.Secret)
+				require.Contains(t, rsp.OtpauthURI, "secret="+rsp.Secret)
+			},
+		},
+		{
+			name: "AlreadyEnabled",
+			buildStubs: func(store *mockdb.MockStore) {
+				store.EXPECT().StartTOTPEnrollment(gomock.Any(), gomock.Any()).Times(1).Return(db.TotpEnrollment{}, sql.ErrNoRows)
+			},
+			checkResponse: func(t *testing.T, rec *httptest.ResponseRecorder) {
+				require.Equal(t, http.StatusConflict, rec.Code)
+			},
+		},
+	}
+
+	for i := range testCases {
+		tc := testCases[i]
+
+		t.Run(tc.name, func(t *testing.T) {
+			ctrl := gomock.NewController(t)
+			defer ctrl.Finish()
+
+			store := mockdb.NewMockStore(ctrl)
+			stubAuthUsers(store)
+			tc.buildStubs(store)
+
+			svr := newTestServer(t, store)
+			rec := httptest.NewRecorder()
+
+			req, err := http.NewRequest(http.MethodPost, "/users/mfa/totp", nil)
+			require.NoError(t, err)
+
+			addAuthorizationHeader(t, req, svr.tokenMaker, authorizationTypeBearer, user.Username, time.Minute)
+			svr.router.ServeHTTP(rec, req)
+			tc.checkResponse(t, rec)
+		})
+	}
+}
+
+func TestConfirmTOTPAPI(t *testing.T) {
+	user, _ := randomUser(t)
+	pending := confirmedEnrollment(t, user.Username)
+	pending.ConfirmedAt = sql.NullTime{}
+	now := time.Now()
+	code, err := totp.Code(pending.Secret, totp.Step(now))
+	require.NoError(t, err)
+
+	testCases := []struct {
+		name          string
+		code          string
+		buildStubs    func(store *mockdb.MockStore)
+		checkResponse func(t *testing.T, rec *httptest.ResponseRecorder)
+	}{
+		{
+			name: "OK",
+			code: code,
+			buildStubs: func(store *mockdb.MockStore) {
+				store.EXPECT().GetTOTPEnrollment(gomock.Any(), gomock.Eq(user.Username)).Times(1).Return(pending, nil)
+				store.EXPECT().
+					ConfirmTOTPTx(gomock.Any(), gomock.Any()).
+					Times(1).
+					DoAndReturn(func(_ context.Context, arg db.ConfirmTOTPTxParams) (db.TotpEnrollment, error) {
+						require.Equal(t, user.Username, arg.Username)
+						require.Equal(t, totp.Step(now), arg.Step)
+						require.Len(t, arg.RecoveryCodeHashes, recoveryCodeCount)
+						return pending, nil
+					})
+			},
+			checkResponse: func(t *testing.T, rec *httptest.ResponseRecorder) {
+				require.Equal(t, http.StatusOK, rec.Code)
+
+				var rsp confirmTOTPResponse
+				require.NoError(t, json.Unmarshal(rec.Body.Bytes(), &rsp))
+				require.Len(t, rsp.RecoveryCodes, recoveryCodeCount)
+			},
+		},
+		{
+			name: "WrongCode",
+			code: "abcdef",
+			buildStubs: func(store *mockdb.MockStore) {
+				store.EXPECT().GetTOTPEnrollment(gomock.Any(), gomock.Any()).Times(0)
+			},
+			checkResponse: func(t *testing.T, rec *httptest.ResponseRecorder) {
+				require.Equal(t, http.StatusBadRequest, rec.Code)
+			},
+		},
+		{
+			name: "NotEnrolled",
+			code: code,
+			buildStubs: func(store *mockdb.MockStore) {
+				store.EXPECT().GetTOTPEnrollment(gomock.Any(), gomock.Any()).Times(1).Return(db.TotpEnrollment{}, sql.ErrNoRows)
+			},
+			checkResponse: func(t *testing.T, rec *httptest.ResponseRecorder) {
+				require.Equal(t, http.StatusNotFound, rec.Code)
+			},
+		},
+		{
+			name: "AlreadyEnabled",
+			code: code,
+			buildStubs: func(store *mockdb.MockStore) {
+				confirmed := pending
+				confirmed.ConfirmedAt = sql.NullTime{Time: now, Valid: true}
+				store.EXPECT().GetTOTPEnrollment(gomock.Any(), gomock.Any()).Times(1).Return(confirmed, nil)
+				store.EXPECT().ConfirmTOTPTx(gomock.Any(), gomock.Any()).Times(0)
+			},
+			checkResponse: func(t *testing.T, rec *httptest.ResponseRecorder) {
+				require.Equal(t, http.StatusConflict, rec.Code)
+			},
+		},
+	}
+
+	for i := range testCases {
+		tc := testCases[i]
+
+		t.Run(tc.name, func(t *testing.T) {
+			ctrl := gomock.NewController(t)
+			defer ctrl.Finish()
+
+			store := mockdb.NewMockStore(ctrl)
+			stubAuthUsers(store)
+			tc.buildStubs(store)
+
+			svr := newTestServer(t, store)
+			rec := httptest.NewRecorder()
+
+			data, err := json.Marshal(gin.H{"code": tc.code})
+			require.NoError(t, err)
+
+			req, err := http.NewRequest(http.MethodPost, "/users/mfa/totp/confirm", bytes.NewReader(data))
+			require.NoError(t, err)
+
+			addAuthorizationHeader(t, req, svr.tokenMaker, authorizationTypeBearer, user.Username, time.Minute)
+			svr.router.ServeHTTP(rec, req)
+			tc.checkResponse(t, rec)
+		})
+	}
+}
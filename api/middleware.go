@@ -1,10 +1,16 @@
 package api
 
 import (
+	"crypto/subtle"
+	"database/sql"
 	"errors"
+	"fmt"
+	db "gobank/db/sqlc"
 	"gobank/token"
+	"gobank/util"
 	"net/http"
 	"strings"
+	"time"
 
 	"github.com/gin-gonic/gin"
 )
@@ -13,10 +19,18 @@ const (
 	authorizationHeaderKey  = "authorization"
 	authorizationTypeBearer = "bearer"
 	authorizationTypeOauth  = "oauth"
+	authorizationTypeAPIKey = "apikey"
 	authorizationPayloadKey = "authorizationPayload"
+	authorizationUserKey    = "authorizationUser"
+	authorizationScopesKey  = "authorizationScopes"
+	authorizationConsentKey = "authorizationConsent"
 )
 
-func authMiddleware(tokenMaker token.Maker) gin.HandlerFunc {
+// authMiddleware accepts a valid bearer token whose user still exists and has not changed their password
+// since it was issued, a valid API key, or an active OAuth token. It stores the token payload and the user
+// in the context, and for API keys and OAuth tokens also the scopes the request is limited to. OAuth tokens
+// bound to an account consent store that too.
+func authMiddleware(tokenMaker token.Maker, store db.Store) gin.HandlerFunc {
 	return func(ctx *gin.Context) {
 
 		authHeader := ctx.GetHeader(authorizationHeaderKey)
@@ -33,21 +47,213 @@ func authMiddleware(tokenMaker token.Maker) gin.HandlerFunc {
 			return
 		}
 
-		if strings.ToLower(fields[0]) != authorizationTypeBearer {
+		var ok bool
+		switch strings.ToLower(fields[0]) {
+		case authorizationTypeBearer:
+			ok = authenticateToken(ctx, tokenMaker, store, fields[1])
+		case authorizationTypeAPIKey:
+			ok = authenticateAPIKey(ctx, store, fields[1])
+		case authorizationTypeOauth:
+			ok = authenticateOAuthToken(ctx, tokenMaker, store, fields[1])
+		default:
 			err := errors.New("wrong autoricxation header type provided")
 			ctx.AbortWithStatusJSON(http.StatusUnauthorized, errorResponse(err))
 			return
 		}
+		if ok {
+			ctx.Next()
+		}
+	}
+}
+
+func authenticateToken(ctx *gin.Context, tokenMaker token.Maker, store db.Store, accessToken string) bool {
+	payload, err := tokenMaker.VerifyToken(accessToken)
+	if err != nil {
+		ctx.AbortWithStatusJSON(http.StatusUnauthorized, errorResponse(err))
+		return false
+	}
+	if payload.Purpose != "" {
+		err := errors.New("token cannot be used to access the API")
+		ctx.AbortWithStatusJSON(http.StatusUnauthorized, errorResponse(err))
+		return false
+	}
+
+	user, ok := authenticatedUser(ctx, store, payload.Username)
+	if !ok {
+		return false
+	}
+	//changing the password revokes every session started before it
+	if payload.IssuedAt.Before(user.PasswordChangedAt) {
+		err := errors.New("session has been revoked")
+		ctx.AbortWithStatusJSON(http.StatusUnauthorized, errorResponse(err))
+		return false
+	}
+
+	ctx.Set(authorizationPayloadKey, payload)
+	ctx.Set(authorizationUserKey, user)
+	return true
+}
+
+// authenticateAPIKey accepts a key that is neither revoked nor expired. Keys are independent of the
+// user's password, so changing it does not revoke them.
+func authenticateAPIKey(ctx *gin.Context, store db.Store, apiKey string) bool {
+	prefix, secret, ok := parseAPIKey(apiKey)
+	if !ok {
+		ctx.AbortWithStatusJSON(http.StatusUnauthorized, errorResponse(errInvalidAPIKey))
+		return false
+	}
+
+	key, err := store.GetAPIKeyByPrefix(ctx, prefix)
+	if err != nil {
+		if err == sql.ErrNoRows {
+			ctx.AbortWithStatusJSON(http.StatusUnauthorized, errorResponse(errInvalidAPIKey))
+			return false
+		}
+		ctx.AbortWithStatusJSON(http.StatusInternalServerError, errorResponse(err))
+		return false
+	}
+	if subtle.ConstantTimeCompare([]byte(util.HashToken(secret)), []byte(key.SecretHash)) != 1 {
+		ctx.AbortWithStatusJSON(http.StatusUnauthorized, errorResponse(errInvalidAPIKey))
+		return false
+	}
+	if key.RevokedAt.Valid {
+		err := errors.New("api key has been revoked")
+		ctx.AbortWithStatusJSON(http.StatusUnauthorized, errorResponse(err))
+		return false
+	}
+	if key.ExpiresAt.Valid && time.Now().After(key.ExpiresAt.Time) {
+		err := errors.New("api key has expired")
+		ctx.AbortWithStatusJSON(http.StatusUnauthorized, errorResponse(err))
+		return false
+	}
+
+	user, ok := authenticatedUser(ctx, store, key.Username)
+	if !ok {
+		return false
+	}
+	if err := store.TouchAPIKey(ctx, key.ID); err != nil {
+		ctx.AbortWithStatusJSON(http.StatusInternalServerError, errorResponse(err))
+		return false
+	}
+
+	ctx.Set(authorizationPayloadKey, &token.Payload{
+		Username:  user.Username,
+		IssuedAt:  key.CreatedAt,
+		ExpiredAt: key.ExpiresAt.Time,
+	})
+	ctx.Set(authorizationUserKey, user)
+	ctx.Set(authorizationScopesKey, key.Scopes)
+	return true
+}
+
+// authenticateOAuthToken accepts a token issued to a third-party app as long as it is not revoked and the
+// user's consent still stands. Like API keys, such tokens survive password changes.
+func authenticateOAuthToken(ctx *gin.Context, tokenMaker token.Maker, store db.Store, accessToken string) bool {
+	access, err := checkOAuthToken(ctx, tokenMaker, store, accessToken)
+	if err != nil {
+		if errors.Is(err, errInactiveOAuthToken) {
+			ctx.AbortWithStatusJSON(http.StatusUnauthorized, errorResponse(err))
+			return false
+		}
+		ctx.AbortWithStatusJSON(http.StatusInternalServerError, errorResponse(err))
+		return false
+	}
+
+	user, ok := authenticatedUser(ctx, store, access.payload.Username)
+	if !ok {
+		return false
+	}
+
+	ctx.Set(authorizationPayloadKey, access.payload)
+	ctx.Set(authorizationUserKey, user)
+	ctx.Set(authorizationScopesKey, access.scopes)
+	if access.consent != nil {
+		ctx.Set(authorizationConsentKey, *access.consent)
+	}
+	return true
+}
 
-		accessToken := fields[1]
-		payload, err := tokenMaker.VerifyToken(accessToken)
-		if err != nil {
+// authenticatedUser loads the user a credential belongs to.
+func authenticatedUser(ctx *gin.Context, store db.Store, username string) (db.User, bool) {
+	user, err := store.GetUser(ctx, username)
+	if err != nil {
+		if err == sql.ErrNoRows {
 			ctx.AbortWithStatusJSON(http.StatusUnauthorized, errorResponse(err))
+			return user, false
+		}
+		ctx.AbortWithStatusJSON(http.StatusInternalServerError, errorResponse(err))
+		return user, false
+	}
+	return user, true
+}
+
+// requireScope rejects API keys and OAuth tokens that were not granted scope. It must run after authMiddleware.
+func requireScope(scope string) gin.HandlerFunc {
+	return func(ctx *gin.Context) {
+		scopes, limited := ctx.Get(authorizationScopesKey)
+		if limited && !token.HasScope(scopes.([]string), scope) {
+			err := fmt.Errorf("credential lacks the %s scope", scope)
+			ctx.AbortWithStatusJSON(http.StatusForbidden, errorResponse(err))
+			return
+		}
+		ctx.Next()
+	}
+}
+
+// requireSession only lets in users with a session of their own, which keeps API keys and apps away from
+// managing the user and its credentials. It must run after authMiddleware.
+func requireSession() gin.HandlerFunc {
+	return func(ctx *gin.Context) {
+		if _, limited := ctx.Get(authorizationScopesKey); limited {
+			err := errors.New("this endpoint cannot be used with an api key or oauth token")
+			ctx.AbortWithStatusJSON(http.StatusForbidden, errorResponse(err))
 			return
 		}
+		ctx.Next()
+	}
+}
 
-		ctx.Set(authorizationPayloadKey, payload)
+// rejectAccountConsent keeps tokens bound to an account consent away from endpoints that are not limited to
+// particular accounts, where the consent could not be enforced. It must run after authMiddleware.
+func rejectAccountConsent() gin.HandlerFunc {
+	return func(ctx *gin.Context) {
+		if _, bound := ctx.Get(authorizationConsentKey); bound {
+			err := errors.New("this endpoint cannot be used with a token bound to an account consent")
+			ctx.AbortWithStatusJSON(http.StatusForbidden, errorResponse(err))
+			return
+		}
 		ctx.Next()
+	}
+}
+
+// adminMiddleware only lets through authenticated users listed in the ADMIN_USERNAMES config.
+// It must run after authMiddleware.
+func adminMiddleware(cfg *util.Config) gin.HandlerFunc {
+	return func(ctx *gin.Context) {
+		payload := ctx.MustGet(authorizationPayloadKey).(*token.Payload)
+
+		for _, admin := range cfg.AdminUsernames {
+			if admin == payload.Username {
+				ctx.Next()
+				return
+			}
+		}
 
+		err := errors.New("admin privileges required")
+		ctx.AbortWithStatusJSON(http.StatusForbidden, errorResponse(err))
+	}
+}
+
+// verifiedEmailMiddleware rejects users who have not verified their email yet. It must run after authMiddleware.
+func verifiedEmailMiddleware() gin.HandlerFunc {
+	return func(ctx *gin.Context) {
+		user := ctx.MustGet(authorizationUserKey).(db.User)
+
+		if !user.IsEmailVerified {
+			err := errors.New("email address must be verified first")
+			ctx.AbortWithStatusJSON(http.StatusForbidden, errorResponse(err))
+			return
+		}
+		ctx.Next()
 	}
 }
@@ -1,7 +1,11 @@
 package api
 
 import (
+	"context"
+	"database/sql"
 	"fmt"
+	mockdb "gobank/db/mock"
+	db "gobank/db/sqlc"
 	"gobank/token"
 	"net/http"
 	"net/http/httptest"
@@ -9,6 +13,7 @@ import (
 	"time"
 
 	"github.com/gin-gonic/gin"
+	"github.com/golang/mock/gomock"
 	"github.com/stretchr/testify/require"
 )
 
@@ -68,6 +73,17 @@ func TestAuthMiddleware(t *testing.T) {
 				require.Equal(t, http.StatusUnauthorized, rec.Code)
 			},
 		},
+		{
+			name: "MFA challenge token",
+			setupAuth: func(t *testing.T, r *http.Request, tm token.Maker) {
+				mfaToken, err := tm.CreateToken("user", time.Minute, token.WithPurpose(token.PurposeMFAChallenge))
+				require.NoError(t, err)
+				r.Header.Set(authorizationHeaderKey, authorizationTypeBearer+" "+mfaToken)
+			},
+			checkResponse: func(t *testing.T, rec *httptest.ResponseRecorder) {
+				require.Equal(t, http.StatusUnauthorized, rec.Code)
+			},
+		},
 		{
 			name: "Expired token",
 			setupAuth: func(t *testing.T, r *http.Request, tm token.Maker) {
@@ -81,12 +97,17 @@ func TestAuthMiddleware(t *testing.T) {
 
 	for _, tc := range testCases {
 		t.Run(tc.name, func(t *testing.T) {
-			server := newTestServer(t, nil)
+			ctrl := gomock.NewController(t)
+			defer ctrl.Finish()
+
+			store := mockdb.NewMockStore(ctrl)
+			stubAuthUsers(store)
+			server := newTestServer(t, store)
 
 			path := "/auth"
 			server.router.GET(
 				path,
-				authMiddleware(server.tokenMaker),
+				authMiddleware(server.tokenMaker, store),
 				func(c *gin.Context) {
 					c.JSON(http.StatusOK, gin.H{})
 				},
@@ -102,3 +123,149 @@ func TestAuthMiddleware(t *testing.T) {
 		})
 	}
 }
+
+// stubAuthUsers lets every caller through authMiddleware as a verified user.
+func stubAuthUsers(store *mockdb.MockStore) {
+	store.EXPECT().
+		GetUser(gomock.Any(), gomock.Any()).
+		AnyTimes().
+		DoAndReturn(func(_ context.Context, username string) (db.User, error) {
+			return db.User{Username: username, IsEmailVerified: true}, nil
+		})
+}
+
+func TestAuthMiddlewareUser(t *testing.T) {
+	user, _ := randomUser(t)
+	user.IsEmailVerified = true
+
+	testCases := []struct {
+		name       string
+		buildStubs func(store *mockdb.MockStore)
+		status     int
+	}{
+		{
+			name: "OK",
+			buildStubs: func(store *mockdb.MockStore) {
+				store.EXPECT().GetUser(gomock.Any(), gomock.Eq(user.Username)).Times(1).Return(user, nil)
+			},
+			status: http.StatusOK,
+		},
+		{
+			name: "PasswordChangedSinceLogin",
+			buildStubs: func(store *mockdb.MockStore) {
+				changed := user
+				changed.PasswordChangedAt = time.Now().Add(time.Second)
+				store.EXPECT().GetUser(gomock.Any(), gomock.Eq(user.Username)).Times(1).Return(changed, nil)
+			},
+			status: http.StatusUnauthorized,
+		},
+		{
+			name: "PasswordChangedBeforeLogin",
+			buildStubs: func(store *mockdb.MockStore) {
+				changed := user
+				changed.PasswordChangedAt = time.Now().Add(-time.Minute)
+				store.EXPECT().GetUser(gomock.Any(), gomock.Eq(user.Username)).Times(1).Return(changed, nil)
+			},
+			status: http.StatusOK,
+		},
+		{
+			name: "UserNotFound",
+			buildStubs: func(store *mockdb.MockStore) {
+				store.EXPECT().GetUser(gomock.Any(), gomock.Any()).Times(1).Return(db.User{}, sql.ErrNoRows)
+			},
+			status: http.StatusUnauthorized,
+		},
+		{
+			name: "InternalError",
+			buildStubs: func(store *mockdb.MockStore) {
+				store.EXPECT().GetUser(gomock.Any(), gomock.Any()).Times(1).Return(db.User{}, sql.ErrConnDone)
+			},
+			status: http.StatusInternalServerError,
+		},
+	}
+
+	for i := range testCases {
+		tc := testCases[i]
+
+		t.Run(tc.name, func(t *testing.T) {
+			ctrl := gomock.NewController(t)
+			defer ctrl.Finish()
+
+			store := mockdb.NewMockStore(ctrl)
+			tc.buildStubs(store)
+
+			server := newTestServer(t, store)
+
+			path := "/auth"
+			server.router.GET(
+				path,
+				authMiddleware(server.tokenMaker, store),
+				func(c *gin.Context) {
+					c.JSON(http.StatusOK, gin.H{})
+				},
+			)
+
+			rec := httptest.NewRecorder()
+			req, err := http.NewRequest(http.MethodGet, path, nil)
+			require.NoError(t, err)
+
+			addAuthorizationHeader(t, req, server.tokenMaker, authorizationTypeBearer, user.Username, time.Minute)
+			server.router.ServeHTTP(rec, req)
+			require.Equal(t, tc.status, rec.Code)
+		})
+	}
+}
+
+func TestVerifiedEmailMiddleware(t *testing.T) {
+	user, _ := randomUser(t)
+
+	testCases := []struct {
+		name     string
+		verified bool
+		status   int
+	}{
+		{
+			name:     "OK",
+			verified: true,
+			status:   http.StatusOK,
+		},
+		{
+			name:   "Unverified",
+			status: http.StatusForbidden,
+		},
+	}
+
+	for i := range testCases {
+		tc := testCases[i]
+
+		t.Run(tc.name, func(t *testing.T) {
+			ctrl := gomock.NewController(t)
+			defer ctrl.Finish()
+
+			u := user
+			u.IsEmailVerified = tc.verified
+			store := mockdb.NewMockStore(ctrl)
+			store.EXPECT().GetUser(gomock.Any(), gomock.Eq(user.Username)).Times(1).Return(u, nil)
+
+			server := newTestServer(t, store)
+
+			path := "/verified"
+			server.router.GET(
+				path,
+				authMiddleware(server.tokenMaker, store),
+				verifiedEmailMiddleware(),
+				func(c *gin.Context) {
+					c.JSON(http.StatusOK, gin.H{})
+				},
+			)
+
+			rec := httptest.NewRecorder()
+			req, err := http.NewRequest(http.MethodGet, path, nil)
+			require.NoError(t, err)
+
+			addAuthorizationHeader(t, req, server.tokenMaker, authorizationTypeBearer, user.Username, time.Minute)
+			server.router.ServeHTTP(rec, req)
+			require.Equal(t, tc.status, rec.Code)
+		})
+	}
+}
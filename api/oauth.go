@@ -0,0 +1,609 @@
+package api
+
+import (
+	"context"
+	"crypto/rand"
+	"crypto/sha256"
+	"crypto/subtle"
+	"database/sql"
+	"encoding/base64"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	db "gobank/db/sqlc"
+	"gobank/token"
+	"gobank/util"
+	"net/http"
+	"net/url"
+	"strings"
+	"time"
+
+	"github.com/gin-gonic/gin"
+)
+
+// OAuth2 (RFC 6749) lets third-party apps act for a user within the scopes the user consented to. Apps get
+// tokens with the authorization code grant, which must use PKCE (RFC 7636), or, for the app's own owner, with
+// the client credentials grant. The tokens are sent as "Authorization: OAuth <token>".
+const (
+	grantTypeAuthorizationCode = "authorization_code"
+	grantTypeClientCredentials = "client_credentials"
+	codeChallengeMethodS256    = "S256"
+	oauthTokenType             = "OAuth"
+)
+
+// errInactiveOAuthToken is wrapped by every reason checkOAuthToken turns a token down for.
+var errInactiveOAuthToken = errors.New("oauth token is not active")
+
+// oauthError is the error body of the token endpoints, see RFC 6749 section 5.2.
+func oauthError(code, description string) gin.H {
+	return gin.H{"error": code, "error_description": description}
+}
+
+type createOAuthClientRequest struct {
+	Name         string   `json:"name" binding:"required,max=100"`
+	RedirectURIs []string `json:"redirect_uris" binding:"required,min=1,dive,url"`
+	Scopes       []string `json:"scopes" binding:"required,min=1,dive,scope"`
+	// confidential clients get a secret and may use the client credentials grant
+	Confidential bool `json:"confidential"`
+}
+
+type oauthClientResponse struct {
+	ClientID     string    `json:"client_id"`
+	Name         string    `json:"name"`
+	RedirectURIs []string  `json:"redirect_uris"`
+	Scopes       []string  `json:"scopes"`
+	Confidential bool      `json:"confidential"`
+	CreatedAt    time.Time `json:"created_at"`
+}
+
+// createOAuthClientResponse is the only time the client secret is shown.
+type createOAuthClientResponse struct {
+	oauthClientResponse
+	ClientSecret string `json:"client_secret,omitempty"`
+}
+
+func newOAuthClientResponse(c db.OauthClient) oauthClientResponse {
+	return oauthClientResponse{
+		ClientID:     c.ID,
+		Name:         c.Name,
+		RedirectURIs: c.RedirectUris,
+		Scopes:       c.Scopes,
+		Confidential: c.SecretHash != "",
+		CreatedAt:    c.CreatedAt,
+	}
+}
+
+// createOAuthClient registers an app owned by the authenticated user.
+func (s *Server) createOAuthClient(ctx *gin.Context) {
+	var req createOAuthClientRequest
+	if err := ctx.ShouldBindJSON(&req); err != nil {
+		ctx.JSON(http.StatusBadRequest, errorResponse(err))
+		return
+	}
+
+	b := make([]byte, 16)
+	if _, err := rand.Read(b); err != nil {
+		ctx.JSON(http.StatusInternalServerError, errorResponse(err))
+		return
+	}
+	var secret, secretHash string
+	if req.Confidential {
+		var err error
+		if secret, err = util.NewSecretToken(32); err != nil {
+			ctx.JSON(http.StatusInternalServerError, errorResponse(err))
+			return
+		}
+		secretHash = util.HashToken(secret)
+	}
+
+	payload := ctx.MustGet(authorizationPayloadKey).(*token.Payload)
+	client, err := s.store.CreateOAuthClientTx(ctx, db.CreateOAuthClientParams{
+		ID:           hex.EncodeToString(b),
+		Owner:        payload.Username,
+		Name:         req.Name,
+		SecretHash:   secretHash,
+		RedirectUris: req.RedirectURIs,
+		Scopes:       req.Scopes,
+	})
+	if err != nil {
+		ctx.JSON(http.StatusInternalServerError, errorResponse(err))
+		return
+	}
+	ctx.JSON(http.StatusOK, createOAuthClientResponse{
+		oauthClientResponse: newOAuthClientResponse(client),
+		ClientSecret:        secret,
+	})
+}
+
+func (s *Server) listOAuthClients(ctx *gin.Context) {
+	payload := ctx.MustGet(authorizationPayloadKey).(*token.Payload)
+	clients, err := s.store.ListOAuthClients(ctx, payload.Username)
+	if err != nil {
+		ctx.JSON(http.StatusInternalServerError, errorResponse(err))
+		return
+	}
+
+	res := make([]oauthClientResponse, 0, len(clients))
+	for _, c := range clients {
+		res = append(res, newOAuthClientResponse(c))
+	}
+	ctx.JSON(http.StatusOK, res)
+}
+
+type authorizeOAuthClientRequest struct {
+	ResponseType        string `json:"response_type" binding:"required,eq=code"`
+	ClientID            string `json:"client_id" binding:"required"`
+	RedirectURI         string `json:"redirect_uri" binding:"required"`
+	Scope               string `json:"scope"`
+	State               string `json:"state"`
+	CodeChallenge       string `json:"code_challenge" binding:"required,min=43,max=128"`
+	CodeChallengeMethod string `json:"code_challenge_method" binding:"required,eq=S256"`
+	// an account consent the app created, authorised for the accounts the user picked
+	ConsentID  int64   `json:"consent_id" binding:"omitempty,min=1"`
+	AccountIDs []int64 `json:"account_ids" binding:"required_with=ConsentID,dive,min=1"`
+}
+
+type authorizeOAuthClientResponse struct {
+	// RedirectTo is where the user's browser goes next, carrying the code and state to the app
+	RedirectTo string `json:"redirect_to"`
+	Code       string `json:"code"`
+	State      string `json:"state,omitempty"`
+}
+
+// authorizeOAuthClient is called by our own front end once the user agreed on the consent screen. It records
+// the consent and hands out a single use authorization code for the app. If the app asked for access to
+// accounts through an account consent, the user also picks the accounts it covers here.
+func (s *Server) authorizeOAuthClient(ctx *gin.Context) {
+	var req authorizeOAuthClientRequest
+	if err := ctx.ShouldBindJSON(&req); err != nil {
+		ctx.JSON(http.StatusBadRequest, errorResponse(err))
+		return
+	}
+
+	client, err := s.store.GetOAuthClient(ctx, req.ClientID)
+	if err != nil {
+		if err == sql.ErrNoRows {
+			err = errors.New("unknown client")
+			ctx.JSON(http.StatusBadRequest, errorResponse(err))
+			return
+		}
+		ctx.JSON(http.StatusInternalServerError, errorResponse(err))
+		return
+	}
+	//redirect uris must match exactly, or the code could be sent anywhere
+	if !containsString(client.RedirectUris, req.RedirectURI) {
+		err := errors.New("redirect_uri is not registered for this client")
+		ctx.JSON(http.StatusBadRequest, errorResponse(err))
+		return
+	}
+	redirectTo, err := url.Parse(req.RedirectURI)
+	if err != nil {
+		ctx.JSON(http.StatusBadRequest, errorResponse(err))
+		return
+	}
+	scopes, err := requestedScopes(req.Scope, client.Scopes)
+	if err != nil {
+		ctx.JSON(http.StatusBadRequest, errorResponse(err))
+		return
+	}
+
+	payload := ctx.MustGet(authorizationPayloadKey).(*token.Payload)
+	var accountConsent *db.AuthoriseAccountConsentParams
+	if req.ConsentID != 0 {
+		consent, ok := s.pendingConsent(ctx, client.ID, req.ConsentID)
+		if !ok {
+			return
+		}
+		//joint holders and delegates may share an account with an app as far as they can use it themselves
+		access := consentAccess(consent.Permissions)
+		for _, id := range req.AccountIDs {
+			if _, ok := s.accountAccess(ctx, id, access); !ok {
+				return
+			}
+		}
+		accountConsent = &db.AuthoriseAccountConsentParams{
+			ID:         req.ConsentID,
+			Username:   sql.NullString{String: payload.Username, Valid: true},
+			AccountIds: req.AccountIDs,
+		}
+	}
+
+	code, err := util.NewSecretToken(32)
+	if err != nil {
+		ctx.JSON(http.StatusInternalServerError, errorResponse(err))
+		return
+	}
+	err = s.store.AuthorizeOAuthClientTx(ctx, db.AuthorizeOAuthClientTxParams{
+		Consent: db.UpsertOAuthConsentParams{
+			Username: payload.Username,
+			ClientID: client.ID,
+			Scopes:   scopes,
+		},
+		Code: db.CreateOAuthAuthorizationCodeParams{
+			CodeHash:      util.HashToken(code),
+			ClientID:      client.ID,
+			Username:      payload.Username,
+			RedirectUri:   req.RedirectURI,
+			Scopes:        scopes,
+			CodeChallenge: req.CodeChallenge,
+			ExpiresAt:     time.Now().Add(s.config.OAuthCodeDuration),
+		},
+		AccountConsent: accountConsent,
+	})
+	if err != nil {
+		if err == sql.ErrNoRows {
+			err = errors.New("consent is no longer awaiting authorisation")
+			ctx.JSON(http.StatusBadRequest, errorResponse(err))
+			return
+		}
+		ctx.JSON(http.StatusInternalServerError, errorResponse(err))
+		return
+	}
+
+	q := redirectTo.Query()
+	q.Set("code", code)
+	if req.State != "" {
+		q.Set("state", req.State)
+	}
+	redirectTo.RawQuery = q.Encode()
+	ctx.JSON(http.StatusOK, authorizeOAuthClientResponse{
+		RedirectTo: redirectTo.String(),
+		Code:       code,
+		State:      req.State,
+	})
+}
+
+// requestedScopes parses the space separated scope parameter. An empty one asks for everything allowed.
+func requestedScopes(scope string, allowed []string) ([]string, error) {
+	scopes := strings.Fields(scope)
+	if len(scopes) == 0 {
+		return allowed, nil
+	}
+	for _, sc := range scopes {
+		if !token.HasScope(allowed, sc) {
+			return nil, fmt.Errorf("scope %q is not allowed for this client", sc)
+		}
+	}
+	return scopes, nil
+}
+
+func containsString(list []string, s string) bool {
+	for _, v := range list {
+		if v == s {
+			return true
+		}
+	}
+	return false
+}
+
+type oauthTokenRequest struct {
+	GrantType    string `form:"grant_type" binding:"required"`
+	Code         string `form:"code"`
+	RedirectURI  string `form:"redirect_uri"`
+	CodeVerifier string `form:"code_verifier"`
+	Scope        string `form:"scope"`
+	ClientID     string `form:"client_id"`
+	ClientSecret string `form:"client_secret"`
+}
+
+type oauthTokenResponse struct {
+	AccessToken string `json:"access_token"`
+	TokenType   string `json:"token_type"`
+	ExpiresIn   int    `json:"expires_in"`
+	Scope       string `json:"scope"`
+}
+
+// oauthToken is the token endpoint apps exchange their grant at.
+func (s *Server) oauthToken(ctx *gin.Context) {
+	var req oauthTokenRequest
+	if err := ctx.ShouldBind(&req); err != nil {
+		ctx.JSON(http.StatusBadRequest, oauthError("invalid_request", err.Error()))
+		return
+	}
+	ctx.Header("Cache-Control", "no-store")
+
+	client, ok := s.authenticateOAuthClient(ctx, req.ClientID, req.ClientSecret)
+	if !ok {
+		return
+	}
+
+	var username string
+	var scopes []string
+	var opts []token.Option
+	switch req.GrantType {
+	case grantTypeAuthorizationCode:
+		code, err := s.store.UseOAuthAuthorizationCode(ctx, util.HashToken(req.Code))
+		if err != nil {
+			if err == sql.ErrNoRows {
+				ctx.JSON(http.StatusBadRequest, oauthError("invalid_grant", "code is invalid, expired or already used"))
+				return
+			}
+			ctx.JSON(http.StatusInternalServerError, errorResponse(err))
+			return
+		}
+		if code.ClientID != client.ID || code.RedirectUri != req.RedirectURI {
+			ctx.JSON(http.StatusBadRequest, oauthError("invalid_grant", "code was issued to another client or redirect_uri"))
+			return
+		}
+		if !verifyCodeChallenge(req.CodeVerifier, code.CodeChallenge) {
+			ctx.JSON(http.StatusBadRequest, oauthError("invalid_grant", "code_verifier does not match the code_challenge"))
+			return
+		}
+		username, scopes = code.Username, code.Scopes
+		if code.ConsentID.Valid {
+			opts = append(opts, token.WithConsent(code.ConsentID.Int64))
+		}
+	case grantTypeClientCredentials:
+		if client.SecretHash == "" {
+			ctx.JSON(http.StatusBadRequest, oauthError("unauthorized_client", "public clients cannot use the client credentials grant"))
+			return
+		}
+		var err error
+		if scopes, err = requestedScopes(req.Scope, client.Scopes); err != nil {
+			ctx.JSON(http.StatusBadRequest, oauthError("invalid_scope", err.Error()))
+			return
+		}
+		username = client.Owner
+	default:
+		ctx.JSON(http.StatusBadRequest, oauthError("unsupported_grant_type", req.GrantType))
+		return
+	}
+
+	opts = append(opts, token.WithClient(client.ID, scopes))
+	t, err := s.tokenMaker.CreateToken(username, s.config.OAuthTokenDuration, opts...)
+	if err != nil {
+		ctx.JSON(http.StatusInternalServerError, errorResponse(err))
+		return
+	}
+	ctx.JSON(http.StatusOK, oauthTokenResponse{
+		AccessToken: t,
+		TokenType:   oauthTokenType,
+		ExpiresIn:   int(s.config.OAuthTokenDuration.Seconds()),
+		Scope:       strings.Join(scopes, " "),
+	})
+}
+
+// verifyCodeChallenge checks a PKCE code verifier against its S256 challenge.
+func verifyCodeChallenge(verifier, challenge string) bool {
+	if len(verifier) < 43 || len(verifier) > 128 {
+		return false
+	}
+	sum := sha256.Sum256([]byte(verifier))
+	computed := base64.RawURLEncoding.EncodeToString(sum[:])
+	return subtle.ConstantTimeCompare([]byte(computed), []byte(challenge)) == 1
+}
+
+// authenticateOAuthClient identifies the calling app by HTTP basic auth or by the client_id and client_secret
+// form fields. Public clients only give their id. It writes the error response and returns false on failure.
+func (s *Server) authenticateOAuthClient(ctx *gin.Context, clientID, clientSecret string) (db.OauthClient, bool) {
+	if id, secret, ok := ctx.Request.BasicAuth(); ok {
+		clientID, clientSecret = id, secret
+	}
+	if clientID == "" {
+		ctx.JSON(http.StatusUnauthorized, oauthError("invalid_client", "client authentication is required"))
+		return db.OauthClient{}, false
+	}
+
+	client, err := s.store.GetOAuthClient(ctx, clientID)
+	if err != nil {
+		if err == sql.ErrNoRows {
+			ctx.JSON(http.StatusUnauthorized, oauthError("invalid_client", "unknown client"))
+			return client, false
+		}
+		ctx.JSON(http.StatusInternalServerError, errorResponse(err))
+		return client, false
+	}
+	if client.SecretHash == "" && clientSecret == "" {
+		return client, true
+	}
+	if subtle.ConstantTimeCompare([]byte(util.HashToken(clientSecret)), []byte(client.SecretHash)) != 1 {
+		ctx.JSON(http.StatusUnauthorized, oauthError("invalid_client", "wrong client credentials"))
+		return client, false
+	}
+	return client, true
+}
+
+// oauthAccess is what an active OAuth token lets its app do.
+type oauthAccess struct {
+	payload *token.Payload
+	scopes  []string
+	// consent is set for tokens bound to an account consent
+	consent *db.AccountConsent
+}
+
+// checkOAuthToken returns what an OAuth access token still gives access to. Tokens that are invalid,
+// revoked, or whose consent was withdrawn or expired give an error wrapping errInactiveOAuthToken.
+func checkOAuthToken(ctx context.Context, tokenMaker token.Maker, store db.Store, accessToken string) (oauthAccess, error) {
+	var access oauthAccess
+	payload, err := tokenMaker.VerifyToken(accessToken)
+	if err != nil {
+		return access, fmt.Errorf("%w: %v", errInactiveOAuthToken, err)
+	}
+	if payload.Purpose != token.PurposeOAuth {
+		return access, fmt.Errorf("%w: not an oauth token", errInactiveOAuthToken)
+	}
+
+	_, err = store.GetRevokedOAuthToken(ctx, payload.ID)
+	if err == nil {
+		return access, fmt.Errorf("%w: token has been revoked", errInactiveOAuthToken)
+	}
+	if err != sql.ErrNoRows {
+		return access, err
+	}
+
+	consent, err := store.GetOAuthConsent(ctx, db.GetOAuthConsentParams{
+		Username: payload.Username,
+		ClientID: payload.ClientID,
+	})
+	if err != nil {
+		if err == sql.ErrNoRows {
+			return access, fmt.Errorf("%w: consent has been withdrawn", errInactiveOAuthToken)
+		}
+		return access, err
+	}
+	//a consent given again later does not bring back the tokens of the withdrawn one
+	if payload.IssuedAt.Before(consent.CreatedAt) {
+		return access, fmt.Errorf("%w: consent has been withdrawn", errInactiveOAuthToken)
+	}
+
+	if payload.ConsentID != 0 {
+		accountConsent, err := store.GetAccountConsent(ctx, payload.ConsentID)
+		if err != nil && err != sql.ErrNoRows {
+			return access, err
+		}
+		if err == sql.ErrNoRows || !activeConsent(accountConsent, payload) {
+			return access, fmt.Errorf("%w: account consent is not authorised", errInactiveOAuthToken)
+		}
+		access.consent = &accountConsent
+	}
+
+	//if the user narrowed the consent since, the token is narrowed with it
+	access.scopes = []string{}
+	for _, scope := range payload.Scopes {
+		if token.HasScope(consent.Scopes, scope) {
+			access.scopes = append(access.scopes, scope)
+		}
+	}
+	access.payload = payload
+	return access, nil
+}
+
+type oauthTokenFormRequest struct {
+	Token string `form:"token" binding:"required"`
+}
+
+type introspectOAuthTokenResponse struct {
+	Active    bool   `json:"active"`
+	Scope     string `json:"scope,omitempty"`
+	ClientID  string `json:"client_id,omitempty"`
+	Username  string `json:"username,omitempty"`
+	TokenType string `json:"token_type,omitempty"`
+	ExpiresAt int64  `json:"exp,omitempty"`
+	IssuedAt  int64  `json:"iat,omitempty"`
+	ConsentID int64  `json:"consent_id,omitempty"`
+}
+
+// introspectOAuthToken tells an app whether one of its tokens is still good, see RFC 7662. Tokens of other
+// apps are reported as inactive.
+func (s *Server) introspectOAuthToken(ctx *gin.Context) {
+	var req oauthTokenFormRequest
+	if err := ctx.ShouldBind(&req); err != nil {
+		ctx.JSON(http.StatusBadRequest, oauthError("invalid_request", err.Error()))
+		return
+	}
+	client, ok := s.authenticateOAuthClient(ctx, ctx.PostForm("client_id"), ctx.PostForm("client_secret"))
+	if !ok {
+		return
+	}
+
+	access, err := checkOAuthToken(ctx, s.tokenMaker, s.store, req.Token)
+	if err != nil {
+		if errors.Is(err, errInactiveOAuthToken) {
+			ctx.JSON(http.StatusOK, introspectOAuthTokenResponse{Active: false})
+			return
+		}
+		ctx.JSON(http.StatusInternalServerError, errorResponse(err))
+		return
+	}
+	payload := access.payload
+	if payload.ClientID != client.ID {
+		ctx.JSON(http.StatusOK, introspectOAuthTokenResponse{Active: false})
+		return
+	}
+	ctx.JSON(http.StatusOK, introspectOAuthTokenResponse{
+		Active:    true,
+		Scope:     strings.Join(access.scopes, " "),
+		ClientID:  payload.ClientID,
+		Username:  payload.Username,
+		TokenType: oauthTokenType,
+		ExpiresAt: payload.ExpiredAt.Unix(),
+		IssuedAt:  payload.IssuedAt.Unix(),
+		ConsentID: payload.ConsentID,
+	})
+}
+
+// revokeOAuthToken lets an app give up one of its tokens, see RFC 7009. As the RFC asks, tokens that are
+// already invalid or belong to another app are answered with 200 all the same.
+func (s *Server) revokeOAuthToken(ctx *gin.Context) {
+	var req oauthTokenFormRequest
+	if err := ctx.ShouldBind(&req); err != nil {
+		ctx.JSON(http.StatusBadRequest, oauthError("invalid_request", err.Error()))
+		return
+	}
+	client, ok := s.authenticateOAuthClient(ctx, ctx.PostForm("client_id"), ctx.PostForm("client_secret"))
+	if !ok {
+		return
+	}
+
+	payload, err := s.tokenMaker.VerifyToken(req.Token)
+	if err != nil || payload.Purpose != token.PurposeOAuth || payload.ClientID != client.ID {
+		ctx.Status(http.StatusOK)
+		return
+	}
+	err = s.store.RevokeOAuthToken(ctx, db.RevokeOAuthTokenParams{
+		TokenID:   payload.ID,
+		ExpiresAt: payload.ExpiredAt,
+	})
+	if err != nil {
+		ctx.JSON(http.StatusInternalServerError, errorResponse(err))
+		return
+	}
+	ctx.Status(http.StatusOK)
+}
+
+type oauthConsentResponse struct {
+	ClientID  string    `json:"client_id"`
+	Scopes    []string  `json:"scopes"`
+	CreatedAt time.Time `json:"created_at"`
+	UpdatedAt time.Time `json:"updated_at"`
+}
+
+// listOAuthConsents shows which apps the authenticated user gave access to.
+func (s *Server) listOAuthConsents(ctx *gin.Context) {
+	payload := ctx.MustGet(authorizationPayloadKey).(*token.Payload)
+	consents, err := s.store.ListOAuthConsents(ctx, payload.Username)
+	if err != nil {
+		ctx.JSON(http.StatusInternalServerError, errorResponse(err))
+		return
+	}
+
+	res := make([]oauthConsentResponse, 0, len(consents))
+	for _, c := range consents {
+		res = append(res, oauthConsentResponse{
+			ClientID:  c.ClientID,
+			Scopes:    c.Scopes,
+			CreatedAt: c.CreatedAt,
+			UpdatedAt: c.UpdatedAt,
+		})
+	}
+	ctx.JSON(http.StatusOK, res)
+}
+
+type oauthConsentRequest struct {
+	ClientID string `uri:"client_id" binding:"required"`
+}
+
+// deleteOAuthConsent withdraws an app's access, which stops every token it holds for the user at once.
+func (s *Server) deleteOAuthConsent(ctx *gin.Context) {
+	var req oauthConsentRequest
+	if err := ctx.ShouldBindUri(&req); err != nil {
+		ctx.JSON(http.StatusBadRequest, errorResponse(err))
+		return
+	}
+
+	payload := ctx.MustGet(authorizationPayloadKey).(*token.Payload)
+	n, err := s.store.DeleteOAuthConsent(ctx, db.DeleteOAuthConsentParams{
+		Username: payload.Username,
+		ClientID: req.ClientID,
+	})
+	if err != nil {
+		ctx.JSON(http.StatusInternalServerError, errorResponse(err))
+		return
+	}
+	if n == 0 {
+		err := errors.New("no consent for this client")
+		ctx.JSON(http.StatusNotFound, errorResponse(err))
+		return
+	}
+	ctx.Status(http.StatusNoContent)
+}
@@ -0,0 +1,917 @@
+package api
+
+import (
+	"bytes"
+	"context"
+	"crypto/sha256"
+	"database/sql"
+	"encoding/base64"
+	"encoding/json"
+	mockdb "gobank/db/mock"
+	db "gobank/db/sqlc"
+	"gobank/token"
+	"gobank/util"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/golang/mock/gomock"
+	"github.com/stretchr/testify/require"
+)
+
+const testRedirectURI = "https://app.example.com/callback"
+
+func randomOAuthClient(t *testing.T, owner string, confidential bool) (db.OauthClient, string) {
+	client := db.OauthClient{
+		ID:           util.RandomString(32),
+		Owner:        owner,
+		Name:         "budget app",
+		RedirectUris: []string{testRedirectURI},
+		Scopes:       []string{token.ScopeAccountsRead, token.ScopeTransfersWrite},
+		CreatedAt:    time.Now().Add(-time.Hour),
+	}
+	if !confidential {
+		return client, ""
+	}
+	secret, err := util.NewSecretToken(32)
+	require.NoError(t, err)
+	client.SecretHash = util.HashToken(secret)
+	return client, secret
+}
+
+func randomPKCE(t *testing.T) (verifier, challenge string) {
+	verifier, err := util.NewSecretToken(32)
+	require.NoError(t, err)
+	sum := sha256.Sum256([]byte(verifier))
+	return verifier, base64.RawURLEncoding.EncodeToString(sum[:])
+}
+
+func newFormRequest(t *testing.T, path string, form url.Values) *http.Request {
+	req, err := http.NewRequest(http.MethodPost, path, strings.NewReader(form.Encode()))
+	require.NoError(t, err)
+	req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+	return req
+}
+
+func TestCreateOAuthClientAPI(t *testing.T) {
+	user, _ := randomUser(t)
+
+	testCases := []struct {
+		name          string
+		body          gin.H
+		buildStubs    func(store *mockdb.MockStore)
+		checkResponse func(t *testing.T, rec *httptest.ResponseRecorder)
+	}{
+		{
+			name: "Confidential",
+			body: gin.H{"name": "payroll", "redirect_uris": []string{testRedirectURI}, "scopes": []string{token.ScopeAccountsRead}, "confidential": true},
+			buildStubs: func(store *mockdb.MockStore) {
+				store.EXPECT().CreateOAuthClientTx(gomock.Any(), gomock.Any()).Times(1).
+					DoAndReturn(func(_ context.Context, arg db.CreateOAuthClientParams) (db.OauthClient, error) {
+						require.Equal(t, user.Username, arg.Owner)
+						require.NotEmpty(t, arg.ID)
+						require.NotEmpty(t, arg.SecretHash)
+						return db.OauthClient{ID: arg.ID, Owner: arg.Owner, Name: arg.Name, SecretHash: arg.SecretHash,
+							RedirectUris: arg.RedirectUris, Scopes: arg.Scopes}, nil
+					})
+			},
+			checkResponse: func(t *testing.T, rec *httptest.ResponseRecorder) {
+				require.Equal(t, http.StatusOK, rec.Code)
+				require.NotContains(t, rec.Body.String(), "secret_hash")
+
+				var rsp createOAuthClientResponse
+				require.NoError(t, json.Unmarshal(rec.Body.Bytes(), &rsp))
+				require.True(t, rsp.Confidential)
+				require.NotEmpty(t, rsp.ClientSecret)
+			},
+		},
+		{
+			name: "Public",
+			body: gin.H{"name": "mobile", "redirect_uris": []string{testRedirectURI}, "scopes": []string{token.ScopeAccountsRead}},
+			buildStubs: func(store *mockdb.MockStore) {
+				store.EXPECT().CreateOAuthClientTx(gomock.Any(), gomock.Any()).Times(1).
+					DoAndReturn(func(_ context.Context, arg db.CreateOAuthClientParams) (db.OauthClient, error) {
+						require.Empty(t, arg.SecretHash)
+						return db.OauthClient{ID: arg.ID, Scopes: arg.Scopes}, nil
+					})
+			},
+			checkResponse: func(t *testing.T, rec *httptest.ResponseRecorder) {
+				require.Equal(t, http.StatusOK, rec.Code)
+				require.NotContains(t, rec.Body.String(), "client_secret")
+			},
+		},
+		{
+			name: "InvalidRedirectURI",
+			body: gin.H{"name": "mobile", "redirect_uris": []string{"not a url"}, "scopes": []string{token.ScopeAccountsRead}},
+			buildStubs: func(store *mockdb.MockStore) {
+				store.EXPECT().CreateOAuthClientTx(gomock.Any(), gomock.Any()).Times(0)
+			},
+			checkResponse: func(t *testing.T, rec *httptest.ResponseRecorder) {
+				require.Equal(t, http.StatusBadRequest, rec.Code)
+			},
+		},
+		{
+			name: "UnknownScope",
+			body: gin.H{"name": "mobile", "redirect_uris": []string{testRedirectURI}, "scopes": []string{"admin"}},
+			buildStubs: func(store *mockdb.MockStore) {
+				store.EXPECT().CreateOAuthClientTx(gomock.Any(), gomock.Any()).Times(0)
+			},
+			checkResponse: func(t *testing.T, rec *httptest.ResponseRecorder) {
+				require.Equal(t, http.StatusBadRequest, rec.Code)
+			},
+		},
+	}
+
+	for i := range testCases {
+		tc := testCases[i]
+
+		t.Run(tc.name, func(t *testing.T) {
+			ctrl := gomock.NewController(t)
+			defer ctrl.Finish()
+
+			store := mockdb.NewMockStore(ctrl)
+			stubAuthUsers(store)
+			tc.buildStubs(store)
+
+			svr := newTestServer(t, store)
+			rec := httptest.NewRecorder()
+
+			data, err := json.Marshal(tc.body)
+			require.NoError(t, err)
+
+			req, err := http.NewRequest(http.MethodPost, "/oauth/clients", bytes.NewReader(data))
+			require.NoError(t, err)
+
+			addAuthorizationHeader(t, req, svr.tokenMaker, authorizationTypeBearer, user.Username, time.Minute)
+			svr.router.ServeHTTP(rec, req)
+			tc.checkResponse(t, rec)
+		})
+	}
+}
+
+func TestAuthorizeOAuthClientAPI(t *testing.T) {
+	user, _ := randomUser(t)
+	client, _ := randomOAuthClient(t, util.RandomOwner(), false)
+	_, challenge := randomPKCE(t)
+
+	validBody := func() gin.H {
+		return gin.H{
+			"response_type":         "code",
+			"client_id":             client.ID,
+			"redirect_uri":          testRedirectURI,
+			"scope":                 token.ScopeAccountsRead,
+			"state":                 "xyz",
+			"code_challenge":        challenge,
+			"code_challenge_method": codeChallengeMethodS256,
+		}
+	}
+
+	testCases := []struct {
+		name          string
+		body          func() gin.H
+		buildStubs    func(store *mockdb.MockStore)
+		checkResponse func(t *testing.T, rec *httptest.ResponseRecorder)
+	}{
+		{
+			name: "OK",
+			body: validBody,
+			buildStubs: func(store *mockdb.MockStore) {
+				store.EXPECT().GetOAuthClient(gomock.Any(), gomock.Eq(client.ID)).Times(1).Return(client, nil)
+				store.EXPECT().AuthorizeOAuthClientTx(gomock.Any(), gomock.Any()).Times(1).
+					DoAndReturn(func(_ context.Context, arg db.AuthorizeOAuthClientTxParams) error {
+						require.Equal(t, db.UpsertOAuthConsentParams{
+							Username: user.Username,
+							ClientID: client.ID,
+							Scopes:   []string{token.ScopeAccountsRead},
+						}, arg.Consent)
+						require.Equal(t, user.Username, arg.Code.Username)
+						require.Equal(t, challenge, arg.Code.CodeChallenge)
+						require.Equal(t, testRedirectURI, arg.Code.RedirectUri)
+						require.WithinDuration(t, time.Now().Add(5*time.Minute), arg.Code.ExpiresAt, time.Second)
+						require.Nil(t, arg.AccountConsent)
+						return nil
+					})
+			},
+			checkResponse: func(t *testing.T, rec *httptest.ResponseRecorder) {
+				require.Equal(t, http.StatusOK, rec.Code)
+
+				var rsp authorizeOAuthClientResponse
+				require.NoError(t, json.Unmarshal(rec.Body.Bytes(), &rsp))
+				redirect, err := url.Parse(rsp.RedirectTo)
+				require.NoError(t, err)
+				require.True(t, strings.HasPrefix(rsp.RedirectTo, testRedirectURI+"?"))
+				require.Equal(t, rsp.Code, redirect.Query().Get("code"))
+				require.Equal(t, "xyz", redirect.Query().Get("state"))
+			},
+		},
+		{
+			name: "DefaultsToClientScopes",
+			body: func() gin.H {
+				b := validBody()
+				delete(b, "scope")
+				return b
+			},
+			buildStubs: func(store *mockdb.MockStore) {
+				store.EXPECT().GetOAuthClient(gomock.Any(), gomock.Any()).Times(1).Return(client, nil)
+				store.EXPECT().AuthorizeOAuthClientTx(gomock.Any(), gomock.Any()).Times(1).
+					DoAndReturn(func(_ context.Context, arg db.AuthorizeOAuthClientTxParams) error {
+						require.Equal(t, client.Scopes, arg.Consent.Scopes)
+						require.Equal(t, client.Scopes, arg.Code.Scopes)
+						return nil
+					})
+			},
+			checkResponse: func(t *testing.T, rec *httptest.ResponseRecorder) {
+				require.Equal(t, http.StatusOK, rec.Code)
+			},
+		},
+		{
+			name: "UnknownClient",
+			body: validBody,
+			buildStubs: func(store *mockdb.MockStore) {
+				store.EXPECT().GetOAuthClient(gomock.Any(), gomock.Any()).Times(1).Return(db.OauthClient{}, sql.ErrNoRows)
+				store.EXPECT().AuthorizeOAuthClientTx(gomock.Any(), gomock.Any()).Times(0)
+			},
+			checkResponse: func(t *testing.T, rec *httptest.ResponseRecorder) {
+				require.Equal(t, http.StatusBadRequest, rec.Code)
+			},
+		},
+		{
+			name: "UnregisteredRedirectURI",
+			body: func() gin.H {
+				b := validBody()
+				b["redirect_uri"] = "https://evil.example.com/callback"
+				return b
+			},
+			buildStubs: func(store *mockdb.MockStore) {
+				store.EXPECT().GetOAuthClient(gomock.Any(), gomock.Any()).Times(1).Return(client, nil)
+				store.EXPECT().AuthorizeOAuthClientTx(gomock.Any(), gomock.Any()).Times(0)
+			},
+			checkResponse: func(t *testing.T, rec *httptest.ResponseRecorder) {
+				require.Equal(t, http.StatusBadRequest, rec.Code)
+			},
+		},
+		{
+			name: "ScopeNotAllowed",
+			body: func() gin.H {
+				b := validBody()
+				b["scope"] = token.ScopeAccountsRead + " " + token.ScopeWebhooks
+				return b
+			},
+			buildStubs: func(store *mockdb.MockStore) {
+				store.EXPECT().GetOAuthClient(gomock.Any(), gomock.Any()).Times(1).Return(client, nil)
+				store.EXPECT().AuthorizeOAuthClientTx(gomock.Any(), gomock.Any()).Times(0)
+			},
+			checkResponse: func(t *testing.T, rec *httptest.ResponseRecorder) {
+				require.Equal(t, http.StatusBadRequest, rec.Code)
+			},
+		},
+		{
+			name: "PlainCodeChallenge",
+			body: func() gin.H {
+				b := validBody()
+				b["code_challenge_method"] = "plain"
+				return b
+			},
+			buildStubs: func(store *mockdb.MockStore) {
+				store.EXPECT().GetOAuthClient(gomock.Any(), gomock.Any()).Times(0)
+			},
+			checkResponse: func(t *testing.T, rec *httptest.ResponseRecorder) {
+				require.Equal(t, http.StatusBadRequest, rec.Code)
+			},
+		},
+		{
+			name: "NoCodeChallenge",
+			body: func() gin.H {
+				b := validBody()
+				delete(b, "code_challenge")
+				return b
+			},
+			buildStubs: func(store *mockdb.MockStore) {
+				store.EXPECT().GetOAuthClient(gomock.Any(), gomock.Any()).Times(0)
+			},
+			checkResponse: func(t *testing.T, rec *httptest.ResponseRecorder) {
+				require.Equal(t, http.StatusBadRequest, rec.Code)
+			},
+		},
+	}
+
+	for i := range testCases {
+		tc := testCases[i]
+
+		t.Run(tc.name, func(t *testing.T) {
+			ctrl := gomock.NewController(t)
+			defer ctrl.Finish()
+
+			store := mockdb.NewMockStore(ctrl)
+			stubAuthUsers(store)
+			tc.buildStubs(store)
+
+			svr := newTestServer(t, store)
+			svr.config.OAuthCodeDuration = 5 * time.Minute
+			rec := httptest.NewRecorder()
+
+			data, err := json.Marshal(tc.body())
+			require.NoError(t, err)
+
+			req, err := http.NewRequest(http.MethodPost, "/oauth/authorize", bytes.NewReader(data))
+			require.NoError(t, err)
+
+			addAuthorizationHeader(t, req, svr.tokenMaker, authorizationTypeBearer, user.Username, time.Minute)
+			svr.router.ServeHTTP(rec, req)
+			tc.checkResponse(t, rec)
+		})
+	}
+}
+
+func TestOAuthTokenAPI(t *testing.T) {
+	user, _ := randomUser(t)
+	public, _ := randomOAuthClient(t, util.RandomOwner(), false)
+	confidential, secret := randomOAuthClient(t, util.RandomOwner(), true)
+	verifier, challenge := randomPKCE(t)
+	code := db.OauthAuthorizationCode{
+		ClientID:      public.ID,
+		Username:      user.Username,
+		RedirectUri:   testRedirectURI,
+		Scopes:        []string{token.ScopeAccountsRead},
+		CodeChallenge: challenge,
+	}
+
+	codeForm := func() url.Values {
+		return url.Values{
+			"grant_type":    {grantTypeAuthorizationCode},
+			"code":          {"the-code"},
+			"redirect_uri":  {testRedirectURI},
+			"code_verifier": {verifier},
+			"client_id":     {public.ID},
+		}
+	}
+
+	testCases := []struct {
+		name          string
+		form          func() url.Values
+		setupAuth     func(r *http.Request)
+		buildStubs    func(store *mockdb.MockStore)
+		checkResponse func(t *testing.T, tm token.Maker, rec *httptest.ResponseRecorder)
+	}{
+		{
+			name: "AuthorizationCode",
+			form: codeForm,
+			buildStubs: func(store *mockdb.MockStore) {
+				store.EXPECT().GetOAuthClient(gomock.Any(), gomock.Eq(public.ID)).Times(1).Return(public, nil)
+				store.EXPECT().UseOAuthAuthorizationCode(gomock.Any(), gomock.Eq(util.HashToken("the-code"))).Times(1).Return(code, nil)
+			},
+			checkResponse: func(t *testing.T, tm token.Maker, rec *httptest.ResponseRecorder) {
+				require.Equal(t, http.StatusOK, rec.Code)
+				require.Equal(t, "no-store", rec.Header().Get("Cache-Control"))
+
+				var rsp oauthTokenResponse
+				require.NoError(t, json.Unmarshal(rec.Body.Bytes(), &rsp))
+				require.Equal(t, oauthTokenType, rsp.TokenType)
+				require.Equal(t, 3600, rsp.ExpiresIn)
+				require.Equal(t, token.ScopeAccountsRead, rsp.Scope)
+
+				payload, err := tm.VerifyToken(rsp.AccessToken)
+				require.NoError(t, err)
+				require.Equal(t, user.Username, payload.Username)
+				require.Equal(t, token.PurposeOAuth, payload.Purpose)
+				require.Equal(t, public.ID, payload.ClientID)
+				require.Equal(t, code.Scopes, payload.Scopes)
+			},
+		},
+		{
+			name: "AuthorizationCodeBoundToConsent",
+			form: codeForm,
+			buildStubs: func(store *mockdb.MockStore) {
+				bound := code
+				bound.ConsentID = sql.NullInt64{Int64: 42, Valid: true}
+				store.EXPECT().GetOAuthClient(gomock.Any(), gomock.Any()).Times(1).Return(public, nil)
+				store.EXPECT().UseOAuthAuthorizationCode(gomock.Any(), gomock.Any()).Times(1).Return(bound, nil)
+			},
+			checkResponse: func(t *testing.T, tm token.Maker, rec *httptest.ResponseRecorder) {
+				require.Equal(t, http.StatusOK, rec.Code)
+
+				var rsp oauthTokenResponse
+				require.NoError(t, json.Unmarshal(rec.Body.Bytes(), &rsp))
+				payload, err := tm.VerifyToken(rsp.AccessToken)
+				require.NoError(t, err)
+				require.Equal(t, int64(42), payload.ConsentID)
+			},
+		},
+		{
+			name: "WrongCodeVerifier",
+			form: func() url.Values {
+				f := codeForm()
+				f.Set("code_verifier", strings.Repeat("a", 43))
+				return f
+			},
+			buildStubs: func(store *mockdb.MockStore) {
+				store.EXPECT().GetOAuthClient(gomock.Any(), gomock.Any()).Times(1).Return(public, nil)
+				store.EXPECT().UseOAuthAuthorizationCode(gomock.Any(), gomock.Any()).Times(1).Return(code, nil)
+			},
+			checkResponse: func(t *testing.T, tm token.Maker, rec *httptest.ResponseRecorder) {
+				require.Equal(t, http.StatusBadRequest, rec.Code)
+				require.Contains(t, rec.Body.String(), "invalid_grant")
+			},
+		},
+		{
+			name: "WrongRedirectURI",
+			form: func() url.Values {
+				f := codeForm()
+				f.Set("redirect_uri", "https://app.example.com/other")
+				return f
+			},
+			buildStubs: func(store *mockdb.MockStore) {
+				store.EXPECT().GetOAuthClient(gomock.Any(), gomock.Any()).Times(1).Return(public, nil)
+				store.EXPECT().UseOAuthAuthorizationCode(gomock.Any(), gomock.Any()).Times(1).Return(code, nil)
+			},
+			checkResponse: func(t *testing.T, tm token.Maker, rec *httptest.ResponseRecorder) {
+				require.Equal(t, http.StatusBadRequest, rec.Code)
+				require.Contains(t, rec.Body.String(), "invalid_grant")
+			},
+		},
+		{
+			name: "CodeOfOtherClient",
+			form: func() url.Values {
+				f := codeForm()
+				f.Set("client_id", confidential.ID)
+				f.Set("client_secret", secret)
+				return f
+			},
+			buildStubs: func(store *mockdb.MockStore) {
+				store.EXPECT().GetOAuthClient(gomock.Any(), gomock.Eq(confidential.ID)).Times(1).Return(confidential, nil)
+				store.EXPECT().UseOAuthAuthorizationCode(gomock.Any(), gomock.Any()).Times(1).Return(code, nil)
+			},
+			checkResponse: func(t *testing.T, tm token.Maker, rec *httptest.ResponseRecorder) {
+				require.Equal(t, http.StatusBadRequest, rec.Code)
+				require.Contains(t, rec.Body.String(), "invalid_grant")
+			},
+		},
+		{
+			name: "CodeUsedOrExpired",
+			form: codeForm,
+			buildStubs: func(store *mockdb.MockStore) {
+				store.EXPECT().GetOAuthClient(gomock.Any(), gomock.Any()).Times(1).Return(public, nil)
+				store.EXPECT().UseOAuthAuthorizationCode(gomock.Any(), gomock.Any()).Times(1).Return(db.OauthAuthorizationCode{}, sql.ErrNoRows)
+			},
+			checkResponse: func(t *testing.T, tm token.Maker, rec *httptest.ResponseRecorder) {
+				require.Equal(t, http.StatusBadRequest, rec.Code)
+				require.Contains(t, rec.Body.String(), "invalid_grant")
+			},
+		},
+		{
+			name: "ClientCredentials",
+			form: func() url.Values {
+				return url.Values{"grant_type": {grantTypeClientCredentials}, "scope": {token.ScopeTransfersWrite}}
+			},
+			setupAuth: func(r *http.Request) {
+				r.SetBasicAuth(confidential.ID, secret)
+			},
+			buildStubs: func(store *mockdb.MockStore) {
+				store.EXPECT().GetOAuthClient(gomock.Any(), gomock.Eq(confidential.ID)).Times(1).Return(confidential, nil)
+			},
+			checkResponse: func(t *testing.T, tm token.Maker, rec *httptest.ResponseRecorder) {
+				require.Equal(t, http.StatusOK, rec.Code)
+
+				var rsp oauthTokenResponse
+				require.NoError(t, json.Unmarshal(rec.Body.Bytes(), &rsp))
+				payload, err := tm.VerifyToken(rsp.AccessToken)
+				require.NoError(t, err)
+				require.Equal(t, confidential.Owner, payload.Username)
+				require.Equal(t, []string{token.ScopeTransfersWrite}, payload.Scopes)
+			},
+		},
+		{
+			name: "ClientCredentialsScopeNotAllowed",
+			form: func() url.Values {
+				return url.Values{"grant_type": {grantTypeClientCredentials}, "scope": {token.ScopeWebhooks}}
+			},
+			setupAuth: func(r *http.Request) {
+				r.SetBasicAuth(confidential.ID, secret)
+			},
+			buildStubs: func(store *mockdb.MockStore) {
+				store.EXPECT().GetOAuthClient(gomock.Any(), gomock.Any()).Times(1).Return(confidential, nil)
+			},
+			checkResponse: func(t *testing.T, tm token.Maker, rec *httptest.ResponseRecorder) {
+				require.Equal(t, http.StatusBadRequest, rec.Code)
+				require.Contains(t, rec.Body.String(), "invalid_scope")
+			},
+		},
+		{
+			name: "ClientCredentialsForPublicClient",
+			form: func() url.Values {
+				return url.Values{"grant_type": {grantTypeClientCredentials}, "client_id": {public.ID}}
+			},
+			buildStubs: func(store *mockdb.MockStore) {
+				store.EXPECT().GetOAuthClient(gomock.Any(), gomock.Any()).Times(1).Return(public, nil)
+			},
+			checkResponse: func(t *testing.T, tm token.Maker, rec *httptest.ResponseRecorder) {
+				require.Equal(t, http.StatusBadRequest, rec.Code)
+				require.Contains(t, rec.Body.String(), "unauthorized_client")
+			},
+		},
+		{
+			name: "WrongClientSecret",
+			form: func() url.Values {
+				return url.Values{"grant_type": {grantTypeClientCredentials}}
+			},
+			setupAuth: func(r *http.Request) {
+				r.SetBasicAuth(confidential.ID, "wrong")
+			},
+			buildStubs: func(store *mockdb.MockStore) {
+				store.EXPECT().GetOAuthClient(gomock.Any(), gomock.Any()).Times(1).Return(confidential, nil)
+			},
+			checkResponse: func(t *testing.T, tm token.Maker, rec *httptest.ResponseRecorder) {
+				require.Equal(t, http.StatusUnauthorized, rec.Code)
+				require.Contains(t, rec.Body.String(), "invalid_client")
+			},
+		},
+		{
+			name: "MissingClientSecret",
+			form: func() url.Values {
+				return url.Values{"grant_type": {grantTypeClientCredentials}, "client_id": {confidential.ID}}
+			},
+			buildStubs: func(store *mockdb.MockStore) {
+				store.EXPECT().GetOAuthClient(gomock.Any(), gomock.Any()).Times(1).Return(confidential, nil)
+			},
+			checkResponse: func(t *testing.T, tm token.Maker, rec *httptest.ResponseRecorder) {
+				require.Equal(t, http.StatusUnauthorized, rec.Code)
+			},
+		},
+		{
+			name: "UnknownClient",
+			form: codeForm,
+			buildStubs: func(store *mockdb.MockStore) {
+				store.EXPECT().GetOAuthClient(gomock.Any(), gomock.Any()).Times(1).Return(db.OauthClient{}, sql.ErrNoRows)
+				store.EXPECT().UseOAuthAuthorizationCode(gomock.Any(), gomock.Any()).Times(0)
+			},
+			checkResponse: func(t *testing.T, tm token.Maker, rec *httptest.ResponseRecorder) {
+				require.Equal(t, http.StatusUnauthorized, rec.Code)
+				require.Contains(t, rec.Body.String(), "invalid_client")
+			},
+		},
+		{
+			name: "UnsupportedGrantType",
+			form: func() url.Values {
+				f := codeForm()
+				f.Set("grant_type", "password")
+				return f
+			},
+			buildStubs: func(store *mockdb.MockStore) {
+				store.EXPECT().GetOAuthClient(gomock.Any(), gomock.Any()).Times(1).Return(public, nil)
+			},
+			checkResponse: func(t *testing.T, tm token.Maker, rec *httptest.ResponseRecorder) {
+				require.Equal(t, http.StatusBadRequest, rec.Code)
+				require.Contains(t, rec.Body.String(), "unsupported_grant_type")
+			},
+		},
+	}
+
+	for i := range testCases {
+		tc := testCases[i]
+
+		t.Run(tc.name, func(t *testing.T) {
+			ctrl := gomock.NewController(t)
+			defer ctrl.Finish()
+
+			store := mockdb.NewMockStore(ctrl)
+			tc.buildStubs(store)
+
+			svr := newTestServer(t, store)
+			svr.config.OAuthTokenDuration = time.Hour
+			rec := httptest.NewRecorder()
+
+			req := newFormRequest(t, "/oauth/token", tc.form())
+			if tc.setupAuth != nil {
+				tc.setupAuth(req)
+			}
+			svr.router.ServeHTTP(rec, req)
+			tc.checkResponse(t, svr.tokenMaker, rec)
+		})
+	}
+}
+
+func TestAuthMiddlewareOAuth(t *testing.T) {
+	user, _ := randomUser(t)
+	client, _ := randomOAuthClient(t, util.RandomOwner(), false)
+	consent := db.OauthConsent{
+		Username:  user.Username,
+		ClientID:  client.ID,
+		Scopes:    []string{token.ScopeAccountsRead, token.ScopeTransfersWrite},
+		CreatedAt: time.Now().Add(-time.Hour),
+	}
+
+	testCases := []struct {
+		name       string
+		authType   string
+		buildStubs func(store *mockdb.MockStore)
+		status     int
+	}{
+		{
+			name:     "OK",
+			authType: authorizationTypeOauth,
+			buildStubs: func(store *mockdb.MockStore) {
+				store.EXPECT().GetRevokedOAuthToken(gomock.Any(), gomock.Any()).Times(1).Return(db.OauthRevokedToken{}, sql.ErrNoRows)
+				store.EXPECT().GetOAuthConsent(gomock.Any(), gomock.Eq(db.GetOAuthConsentParams{
+					Username: user.Username,
+					ClientID: client.ID,
+				})).Times(1).Return(consent, nil)
+				store.EXPECT().GetUser(gomock.Any(), gomock.Eq(user.Username)).Times(1).Return(user, nil)
+			},
+			status: http.StatusOK,
+		},
+		{
+			name:     "SentAsBearer",
+			authType: authorizationTypeBearer,
+			buildStubs: func(store *mockdb.MockStore) {
+				store.EXPECT().GetOAuthConsent(gomock.Any(), gomock.Any()).Times(0)
+				store.EXPECT().GetUser(gomock.Any(), gomock.Any()).Times(0)
+			},
+			status: http.StatusUnauthorized,
+		},
+		{
+			name:     "Revoked",
+			authType: authorizationTypeOauth,
+			buildStubs: func(store *mockdb.MockStore) {
+				store.EXPECT().GetRevokedOAuthToken(gomock.Any(), gomock.Any()).Times(1).Return(db.OauthRevokedToken{}, nil)
+				store.EXPECT().GetUser(gomock.Any(), gomock.Any()).Times(0)
+			},
+			status: http.StatusUnauthorized,
+		},
+		{
+			name:     "ConsentWithdrawn",
+			authType: authorizationTypeOauth,
+			buildStubs: func(store *mockdb.MockStore) {
+				store.EXPECT().GetRevokedOAuthToken(gomock.Any(), gomock.Any()).Times(1).Return(db.OauthRevokedToken{}, sql.ErrNoRows)
+				store.EXPECT().GetOAuthConsent(gomock.Any(), gomock.Any()).Times(1).Return(db.OauthConsent{}, sql.ErrNoRows)
+				store.EXPECT().GetUser(gomock.Any(), gomock.Any()).Times(0)
+			},
+			status: http.StatusUnauthorized,
+		},
+		{
+			name:     "ConsentGivenAgain",
+			authType: authorizationTypeOauth,
+			buildStubs: func(store *mockdb.MockStore) {
+				renewed := consent
+				renewed.CreatedAt = time.Now().Add(time.Second)
+				store.EXPECT().GetRevokedOAuthToken(gomock.Any(), gomock.Any()).Times(1).Return(db.OauthRevokedToken{}, sql.ErrNoRows)
+				store.EXPECT().GetOAuthConsent(gomock.Any(), gomock.Any()).Times(1).Return(renewed, nil)
+				store.EXPECT().GetUser(gomock.Any(), gomock.Any()).Times(0)
+			},
+			status: http.StatusUnauthorized,
+		},
+		{
+			name:     "ConsentNarrowed",
+			authType: authorizationTypeOauth,
+			buildStubs: func(store *mockdb.MockStore) {
+				narrowed := consent
+				narrowed.Scopes = []string{token.ScopeTransfersWrite}
+				store.EXPECT().GetRevokedOAuthToken(gomock.Any(), gomock.Any()).Times(1).Return(db.OauthRevokedToken{}, sql.ErrNoRows)
+				store.EXPECT().GetOAuthConsent(gomock.Any(), gomock.Any()).Times(1).Return(narrowed, nil)
+				store.EXPECT().GetUser(gomock.Any(), gomock.Any()).Times(1).Return(user, nil)
+			},
+			status: http.StatusForbidden,
+		},
+		{
+			name:     "InternalError",
+			authType: authorizationTypeOauth,
+			buildStubs: func(store *mockdb.MockStore) {
+				store.EXPECT().GetRevokedOAuthToken(gomock.Any(), gomock.Any()).Times(1).Return(db.OauthRevokedToken{}, sql.ErrConnDone)
+			},
+			status: http.StatusInternalServerError,
+		},
+	}
+
+	for i := range testCases {
+		tc := testCases[i]
+
+		t.Run(tc.name, func(t *testing.T) {
+			ctrl := gomock.NewController(t)
+			defer ctrl.Finish()
+
+			store := mockdb.NewMockStore(ctrl)
+			tc.buildStubs(store)
+
+			server := newTestServer(t, store)
+
+			path := "/auth"
+			server.router.GET(
+				path,
+				authMiddleware(server.tokenMaker, store),
+				requireScope(token.ScopeAccountsRead),
+				func(c *gin.Context) {
+					payload := c.MustGet(authorizationPayloadKey).(*token.Payload)
+					require.Equal(t, client.ID, payload.ClientID)
+					c.JSON(http.StatusOK, gin.H{})
+				},
+			)
+
+			accessToken, err := server.tokenMaker.CreateToken(user.Username, time.Minute,
+				token.WithClient(client.ID, []string{token.ScopeAccountsRead}))
+			require.NoError(t, err)
+
+			rec := httptest.NewRecorder()
+			req, err := http.NewRequest(http.MethodGet, path, nil)
+			require.NoError(t, err)
+
+			req.Header.Set(authorizationHeaderKey, tc.authType+" "+accessToken)
+			server.router.ServeHTTP(rec, req)
+			require.Equal(t, tc.status, rec.Code)
+		})
+	}
+}
+
+func TestIntrospectOAuthTokenAPI(t *testing.T) {
+	user, _ := randomUser(t)
+	client, secret := randomOAuthClient(t, util.RandomOwner(), true)
+	other, _ := randomOAuthClient(t, util.RandomOwner(), false)
+
+	testCases := []struct {
+		name       string
+		tokenOf    string
+		buildStubs func(store *mockdb.MockStore)
+		active     bool
+	}{
+		{
+			name:    "Active",
+			tokenOf: client.ID,
+			buildStubs: func(store *mockdb.MockStore) {
+				store.EXPECT().GetRevokedOAuthToken(gomock.Any(), gomock.Any()).Times(1).Return(db.OauthRevokedToken{}, sql.ErrNoRows)
+				store.EXPECT().GetOAuthConsent(gomock.Any(), gomock.Any()).Times(1).
+					Return(db.OauthConsent{Scopes: []string{token.ScopeAccountsRead}, CreatedAt: time.Now().Add(-time.Hour)}, nil)
+			},
+			active: true,
+		},
+		{
+			name:    "Revoked",
+			tokenOf: client.ID,
+			buildStubs: func(store *mockdb.MockStore) {
+				store.EXPECT().GetRevokedOAuthToken(gomock.Any(), gomock.Any()).Times(1).Return(db.OauthRevokedToken{}, nil)
+			},
+		},
+		{
+			name:    "OtherClientsToken",
+			tokenOf: other.ID,
+			buildStubs: func(store *mockdb.MockStore) {
+				store.EXPECT().GetRevokedOAuthToken(gomock.Any(), gomock.Any()).Times(1).Return(db.OauthRevokedToken{}, sql.ErrNoRows)
+				store.EXPECT().GetOAuthConsent(gomock.Any(), gomock.Any()).Times(1).
+					Return(db.OauthConsent{Scopes: []string{token.ScopeAccountsRead}, CreatedAt: time.Now().Add(-time.Hour)}, nil)
+			},
+		},
+	}
+
+	for i := range testCases {
+		tc := testCases[i]
+
+		t.Run(tc.name, func(t *testing.T) {
+			ctrl := gomock.NewController(t)
+			defer ctrl.Finish()
+
+			store := mockdb.NewMockStore(ctrl)
+			store.EXPECT().GetOAuthClient(gomock.Any(), gomock.Eq(client.ID)).Times(1).Return(client, nil)
+			tc.buildStubs(store)
+
+			svr := newTestServer(t, store)
+			accessToken, err := svr.tokenMaker.CreateToken(user.Username, time.Minute,
+				token.WithClient(tc.tokenOf, []string{token.ScopeAccountsRead}))
+			require.NoError(t, err)
+
+			rec := httptest.NewRecorder()
+			req := newFormRequest(t, "/oauth/introspect", url.Values{"token": {accessToken}})
+			req.SetBasicAuth(client.ID, secret)
+			svr.router.ServeHTTP(rec, req)
+			require.Equal(t, http.StatusOK, rec.Code)
+
+			var rsp introspectOAuthTokenResponse
+			require.NoError(t, json.Unmarshal(rec.Body.Bytes(), &rsp))
+			require.Equal(t, tc.active, rsp.Active)
+			if tc.active {
+				require.Equal(t, user.Username, rsp.Username)
+				require.Equal(t, token.ScopeAccountsRead, rsp.Scope)
+			} else {
+				require.Equal(t, `{"active":false}`, rec.Body.String())
+			}
+		})
+	}
+}
+
+func TestRevokeOAuthTokenAPI(t *testing.T) {
+	user, _ := randomUser(t)
+	client, _ := randomOAuthClient(t, util.RandomOwner(), false)
+	other, _ := randomOAuthClient(t, util.RandomOwner(), false)
+
+	testCases := []struct {
+		name       string
+		token      func(tm token.Maker) string
+		buildStubs func(store *mockdb.MockStore)
+	}{
+		{
+			name: "OK",
+			token: func(tm token.Maker) string {
+				accessToken, err := tm.CreateToken(user.Username, time.Minute, token.WithClient(client.ID, nil))
+				require.NoError(t, err)
+				return accessToken
+			},
+			buildStubs: func(store *mockdb.MockStore) {
+				store.EXPECT().RevokeOAuthToken(gomock.Any(), gomock.Any()).Times(1).
+					DoAndReturn(func(_ context.Context, arg db.RevokeOAuthTokenParams) error {
+						require.WithinDuration(t, time.Now().Add(time.Minute), arg.ExpiresAt, time.Second)
+						return nil
+					})
+			},
+		},
+		{
+			name: "OtherClientsToken",
+			token: func(tm token.Maker) string {
+				accessToken, err := tm.CreateToken(user.Username, time.Minute, token.WithClient(other.ID, nil))
+				require.NoError(t, err)
+				return accessToken
+			},
+			buildStubs: func(store *mockdb.MockStore) {
+				store.EXPECT().RevokeOAuthToken(gomock.Any(), gomock.Any()).Times(0)
+			},
+		},
+		{
+			name: "SessionToken",
+			token: func(tm token.Maker) string {
+				accessToken, err := tm.CreateToken(user.Username, time.Minute)
+				require.NoError(t, err)
+				return accessToken
+			},
+			buildStubs: func(store *mockdb.MockStore) {
+				store.EXPECT().RevokeOAuthToken(gomock.Any(), gomock.Any()).Times(0)
+			},
+		},
+		{
+			name: "InvalidToken",
+			token: func(tm token.Maker) string {
+				return "garbage"
+			},
+			buildStubs: func(store *mockdb.MockStore) {
+				store.EXPECT().RevokeOAuthToken(gomock.Any(), gomock.Any()).Times(0)
+			},
+		},
+	}
+
+	for i := range testCases {
+		tc := testCases[i]
+
+		t.Run(tc.name, func(t *testing.T) {
+			ctrl := gomock.NewController(t)
+			defer ctrl.Finish()
+
+			store := mockdb.NewMockStore(ctrl)
+			store.EXPECT().GetOAuthClient(gomock.Any(), gomock.Eq(client.ID)).Times(1).Return(client, nil)
+			tc.buildStubs(store)
+
+			svr := newTestServer(t, store)
+			rec := httptest.NewRecorder()
+
+			req := newFormRequest(t, "/oauth/revoke", url.Values{"token": {tc.token(svr.tokenMaker)}, "client_id": {client.ID}})
+			svr.router.ServeHTTP(rec, req)
+			require.Equal(t, http.StatusOK, rec.Code)
+		})
+	}
+}
+
+func TestDeleteOAuthConsentAPI(t *testing.T) {
+	user, _ := randomUser(t)
+
+	testCases := []struct {
+		name   string
+		n      int64
+		status int
+	}{
+		{name: "OK", n: 1, status: http.StatusNoContent},
+		{name: "NotFound", n: 0, status: http.StatusNotFound},
+	}
+
+	for i := range testCases {
+		tc := testCases[i]
+
+		t.Run(tc.name, func(t *testing.T) {
+			ctrl := gomock.NewController(t)
+			defer ctrl.Finish()
+
+			store := mockdb.NewMockStore(ctrl)
+			stubAuthUsers(store)
+			store.EXPECT().DeleteOAuthConsent(gomock.Any(), gomock.Eq(db.DeleteOAuthConsentParams{
+				Username: user.Username,
+				ClientID: "app",
+			})).Times(1).Return(tc.n, nil)
+
+			svr := newTestServer(t, store)
+			rec := httptest.NewRecorder()
+
+			req, err := http.NewRequest(http.MethodDelete, "/oauth/consents/app", nil)
+			require.NoError(t, err)
+
+			addAuthorizationHeader(t, req, svr.tokenMaker, authorizationTypeBearer, user.Username, time.Minute)
+			svr.router.ServeHTTP(rec, req)
+			require.Equal(t, tc.status, rec.Code)
+		})
+	}
+}
@@ -0,0 +1,252 @@
+package api
+
+import (
+	"database/sql"
+	"errors"
+	"fmt"
+	db "gobank/db/sqlc"
+	"gobank/token"
+	"net/http"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/lib/pq"
+)
+
+// Payees are the accounts a user saved to transfer to by payee_id instead of typing to_account_id.
+// Transfers over PAYEE_COOLING_OFF_THRESHOLD, however they name their destination, only go to the user's own
+// accounts or to payees added at least PAYEE_COOLING_OFF ago, so whoever takes over a session cannot add
+// their own account and empty the user's at once.
+
+type createPayeeRequest struct {
+	Nickname      string `json:"nickname" binding:"required,max=64"`
+	AccountID     int64  `json:"account_id" binding:"required_without=AccountNumber,excluded_with=AccountNumber,omitempty,min=1"`
+	AccountNumber string `json:"account_number" binding:"omitempty,accountnumber"`
+	Currency      string `json:"currency" binding:"required,currency"`
+}
+
+func (s *Server) createPayee(ctx *gin.Context) {
+	var req createPayeeRequest
+	if err := ctx.ShouldBindJSON(&req); err != nil {
+		ctx.JSON(http.StatusBadRequest, errorResponse(err))
+		return
+	}
+	if req.AccountNumber != "" {
+		acc, ok := s.accountByNumber(ctx, req.AccountNumber)
+		if !ok {
+			return
+		}
+		req.AccountID = acc.ID
+	}
+	if _, ok := s.validAccount(ctx, req.AccountID, req.Currency); !ok {
+		return
+	}
+
+	payload := ctx.MustGet(authorizationPayloadKey).(*token.Payload)
+	payee, err := s.store.CreatePayee(ctx, db.CreatePayeeParams{
+		Owner:     payload.Username,
+		Nickname:  req.Nickname,
+		AccountID: req.AccountID,
+		Currency:  req.Currency,
+	})
+	if err != nil {
+		if pqErr, ok := err.(*pq.Error); ok {
+			switch pqErr.Code.Name() {
+			//nickname or account already saved
+			case "unique_violation":
+				ctx.JSON(http.StatusForbidden, errorResponse(err))
+				return
+			}
+		}
+		ctx.JSON(http.StatusInternalServerError, errorResponse(err))
+		return
+	}
+	ctx.JSON(http.StatusOK, payee)
+}
+
+type listPayeesRequest struct {
+	PageID   int32 `form:"page_id" binding:"required,min=1"`
+	PageSize int32 `form:"page_size" binding:"required,min=5,max=10"`
+}
+
+func (s *Server) listPayees(ctx *gin.Context) {
+	var req listPayeesRequest
+	if err := ctx.ShouldBindQuery(&req); err != nil {
+		ctx.JSON(http.StatusBadRequest, errorResponse(err))
+		return
+	}
+
+	payload := ctx.MustGet(authorizationPayloadKey).(*token.Payload)
+	payees, err := s.store.ListPayees(ctx, db.ListPayeesParams{
+		Owner:  payload.Username,
+		Limit:  req.PageSize,
+		Offset: (req.PageID - 1) * req.PageSize,
+	})
+	if err != nil {
+		ctx.JSON(http.StatusInternalServerError, errorResponse(err))
+		return
+	}
+	ctx.JSON(http.StatusOK, payees)
+}
+
+type payeeRequest struct {
+	ID int64 `uri:"id" binding:"required,min=1"`
+}
+
+func (s *Server) getPayee(ctx *gin.Context) {
+	var req payeeRequest
+	if err := ctx.ShouldBindUri(&req); err != nil {
+		ctx.JSON(http.StatusBadRequest, errorResponse(err))
+		return
+	}
+	payee, ok := s.ownedPayee(ctx, req.ID)
+	if !ok {
+		return
+	}
+	ctx.JSON(http.StatusOK, payee)
+}
+
+type updatePayeeRequest struct {
+	Nickname string `json:"nickname" binding:"required,max=64"`
+}
+
+// updatePayee renames a payee. The account of a payee cannot change: that would be a new payee, with its
+// own cooling-off period.
+func (s *Server) updatePayee(ctx *gin.Context) {
+	var uri payeeRequest
+	if err := ctx.ShouldBindUri(&uri); err != nil {
+		ctx.JSON(http.StatusBadRequest, errorResponse(err))
+		return
+	}
+	var req updatePayeeRequest
+	if err := ctx.ShouldBindJSON(&req); err != nil {
+		ctx.JSON(http.StatusBadRequest, errorResponse(err))
+		return
+	}
+	if _, ok := s.ownedPayee(ctx, uri.ID); !ok {
+		return
+	}
+
+	payee, err := s.store.UpdatePayeeNickname(ctx, db.UpdatePayeeNicknameParams{
+		ID:       uri.ID,
+		Nickname: req.Nickname,
+	})
+	if err != nil {
+		if pqErr, ok := err.(*pq.Error); ok && pqErr.Code.Name() == "unique_violation" {
+			ctx.JSON(http.StatusForbidden, errorResponse(err))
+			return
+		}
+		ctx.JSON(http.StatusInternalServerError, errorResponse(err))
+		return
+	}
+	ctx.JSON(http.StatusOK, payee)
+}
+
+func (s *Server) deletePayee(ctx *gin.Context) {
+	var req payeeRequest
+	if err := ctx.ShouldBindUri(&req); err != nil {
+		ctx.JSON(http.StatusBadRequest, errorResponse(err))
+		return
+	}
+	if _, ok := s.ownedPayee(ctx, req.ID); !ok {
+		return
+	}
+
+	if _, err := s.store.DeletePayee(ctx, req.ID); err != nil {
+		ctx.JSON(http.StatusInternalServerError, errorResponse(err))
+		return
+	}
+	ctx.Status(http.StatusNoContent)
+}
+
+// ownedPayee loads a payee and checks it was saved by the authenticated user, writing the error response if not.
+func (s *Server) ownedPayee(ctx *gin.Context, id int64) (db.Payee, bool) {
+	payee, err := s.store.GetPayee(ctx, id)
+	if err != nil {
+		if err == sql.ErrNoRows {
+			ctx.JSON(http.StatusNotFound, errorResponse(err))
+			return payee, false
+		}
+		ctx.JSON(http.StatusInternalServerError, errorResponse(err))
+		return payee, false
+	}
+
+	payload := ctx.MustGet(authorizationPayloadKey).(*token.Payload)
+	if payload.Username != payee.Owner {
+		err := errors.New("payee doesn't belong to authenticated user")
+		ctx.JSON(http.StatusUnauthorized, errorResponse(err))
+		return payee, false
+	}
+	return payee, true
+}
+
+// resolvePayee fills in the to account of a transfer made to a payee. It writes the error response and
+// returns false if the payee is not the user's or is in another currency.
+func (s *Server) resolvePayee(ctx *gin.Context, req *transferRequest) bool {
+	if req.PayeeID == 0 {
+		return true
+	}
+	payee, ok := s.ownedPayee(ctx, req.PayeeID)
+	if !ok {
+		return false
+	}
+	if payee.Currency != req.Currency {
+		err := fmt.Errorf("payee [%d] currency mismatch: %s vs %s", payee.ID, payee.Currency, req.Currency)
+		ctx.JSON(http.StatusBadRequest, errorResponse(err))
+		return false
+	}
+
+	req.ToAccountID = payee.AccountID
+	return true
+}
+
+// checkPayeeCoolingOff writes the error response and returns false if a transfer of amount to the account
+// to is over the cooling-off threshold and to is neither the user's own account nor a payee past its
+// cooling-off period.
+func (s *Server) checkPayeeCoolingOff(ctx *gin.Context, to db.Account, amount int64) bool {
+	problem, err := s.payeeCoolingOffProblem(ctx, to, amount)
+	if err != nil {
+		ctx.JSON(http.StatusInternalServerError, errorResponse(err))
+		return false
+	}
+	if problem != "" {
+		ctx.JSON(http.StatusForbidden, errorResponse(errors.New(problem)))
+		return false
+	}
+	return true
+}
+
+// payeeCoolingOffProblem is checkPayeeCoolingOff for payment batches, returning why the transfer is not
+// allowed yet instead of writing it.
+func (s *Server) payeeCoolingOffProblem(ctx *gin.Context, to db.Account, amount int64) (string, error) {
+	if s.config.PayeeCoolingOffThreshold <= 0 || amount <= s.config.PayeeCoolingOffThreshold {
+		return "", nil
+	}
+	//moving money between accounts the user holds, joint ones included, is not paying someone new
+	held, err := s.holdsAccount(ctx, to)
+	if err != nil {
+		return "", err
+	}
+	if held {
+		return "", nil
+	}
+
+	payload := ctx.MustGet(authorizationPayloadKey).(*token.Payload)
+	payee, err := s.store.GetPayeeByAccount(ctx, db.GetPayeeByAccountParams{
+		Owner:     payload.Username,
+		AccountID: to.ID,
+	})
+	if err == sql.ErrNoRows {
+		return fmt.Sprintf("transfers over %d only go to payees: add account [%d] as a payee first",
+			s.config.PayeeCoolingOffThreshold, to.ID), nil
+	}
+	if err != nil {
+		return "", err
+	}
+	availableAt := payee.CreatedAt.Add(s.config.PayeeCoolingOff)
+	if time.Now().Before(availableAt) {
+		return fmt.Sprintf("payee [%d] was added recently: transfers over %d to it are allowed from %s",
+			payee.ID, s.config.PayeeCoolingOffThreshold, availableAt.Format(time.RFC3339)), nil
+	}
+	return "", nil
+}
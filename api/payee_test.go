@@ -0,0 +1,452 @@
+package api
+
+import (
+	"bytes"
+	"database/sql"
+	"encoding/json"
+	"fmt"
+	mockdb "gobank/db/mock"
+	db "gobank/db/sqlc"
+	"gobank/util"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/golang/mock/gomock"
+	"github.com/lib/pq"
+	"github.com/stretchr/testify/require"
+)
+
+func TestCreatePayeeAPI(t *testing.T) {
+	user, _ := randomUser(t)
+	landlord, _ := randomUser(t)
+	acc := randomAccount(landlord.Username)
+	acc.ID = 5
+	acc.Currency = util.USD
+	// the way people copy a number off a statement
+	spaced := strings.ToLower(acc.Number[:4] + " " + acc.Number[4:8] + " " + acc.Number[8:])
+
+	testCases := []struct {
+		name          string
+		body          gin.H
+		buildStubs    func(store *mockdb.MockStore)
+		checkResponse func(t *testing.T, rec *httptest.ResponseRecorder)
+	}{
+		{
+			name: "OK",
+			body: gin.H{"nickname": "landlord", "account_id": acc.ID, "currency": util.USD},
+			buildStubs: func(store *mockdb.MockStore) {
+				arg := db.CreatePayeeParams{Owner: user.Username, Nickname: "landlord", AccountID: acc.ID, Currency: util.USD}
+				store.EXPECT().GetAccount(gomock.Any(), gomock.Eq(acc.ID)).Times(1).Return(acc, nil)
+				store.EXPECT().CreatePayee(gomock.Any(), gomock.Eq(arg)).Times(1).
+					Return(db.Payee{ID: 1, Owner: user.Username, Nickname: "landlord", AccountID: acc.ID, Currency: util.USD}, nil)
+			},
+			checkResponse: func(t *testing.T, rec *httptest.ResponseRecorder) {
+				require.Equal(t, http.StatusOK, rec.Code)
+
+				var payee db.Payee
+				require.NoError(t, json.Unmarshal(rec.Body.Bytes(), &payee))
+				require.Equal(t, "landlord", payee.Nickname)
+				require.Equal(t, acc.ID, payee.AccountID)
+			},
+		},
+		{
+			name: "ByAccountNumber",
+			body: gin.H{"nickname": "landlord", "account_number": spaced, "currency": util.USD},
+			buildStubs: func(store *mockdb.MockStore) {
+				arg := db.CreatePayeeParams{Owner: user.Username, Nickname: "landlord", AccountID: acc.ID, Currency: util.USD}
+				store.EXPECT().GetAccountByNumber(gomock.Any(), gomock.Eq(acc.Number)).Times(1).Return(acc, nil)
+				store.EXPECT().GetAccount(gomock.Any(), gomock.Eq(acc.ID)).Times(1).Return(acc, nil)
+				store.EXPECT().CreatePayee(gomock.Any(), gomock.Eq(arg)).Times(1).
+					Return(db.Payee{ID: 1, Owner: user.Username, Nickname: "landlord", AccountID: acc.ID, Currency: util.USD}, nil)
+			},
+			checkResponse: func(t *testing.T, rec *httptest.ResponseRecorder) {
+				require.Equal(t, http.StatusOK, rec.Code)
+			},
+		},
+		{
+			name: "UnknownAccountNumber",
+			body: gin.H{"nickname": "landlord", "account_number": acc.Number, "currency": util.USD},
+			buildStubs: func(store *mockdb.MockStore) {
+				store.EXPECT().GetAccountByNumber(gomock.Any(), gomock.Eq(acc.Number)).Times(1).Return(db.Account{}, sql.ErrNoRows)
+				store.EXPECT().CreatePayee(gomock.Any(), gomock.Any()).Times(0)
+			},
+			checkResponse: func(t *testing.T, rec *httptest.ResponseRecorder) {
+				require.Equal(t, http.StatusNotFound, rec.Code)
+			},
+		},
+		{
+			name: "AccountIDAndNumber",
+			body: gin.H{"nickname": "landlord", "account_id": acc.ID, "account_number": acc.Number, "currency": util.USD},
+			buildStubs: func(store *mockdb.MockStore) {
+				store.EXPECT().GetAccountByNumber(gomock.Any(), gomock.Any()).Times(0)
+				store.EXPECT().CreatePayee(gomock.Any(), gomock.Any()).Times(0)
+			},
+			checkResponse: func(t *testing.T, rec *httptest.ResponseRecorder) {
+				require.Equal(t, http.StatusBadRequest, rec.Code)
+			},
+		},
+		{
+			name: "CurrencyMismatch",
+			body: gin.H{"nickname": "landlord", "account_id": acc.ID, "currency": util.EUR},
+			buildStubs: func(store *mockdb.MockStore) {
+				store.EXPECT().GetAccount(gomock.Any(), gomock.Eq(acc.ID)).Times(1).Return(acc, nil)
+				store.EXPECT().CreatePayee(gomock.Any(), gomock.Any()).Times(0)
+			},
+			checkResponse: func(t *testing.T, rec *httptest.ResponseRecorder) {
+				require.Equal(t, http.StatusBadRequest, rec.Code)
+			},
+		},
+		{
+			name: "UnknownAccount",
+			body: gin.H{"nickname": "landlord", "account_id": acc.ID, "currency": util.USD},
+			buildStubs: func(store *mockdb.MockStore) {
+				store.EXPECT().GetAccount(gomock.Any(), gomock.Eq(acc.ID)).Times(1).Return(db.Account{}, sql.ErrNoRows)
+				store.EXPECT().CreatePayee(gomock.Any(), gomock.Any()).Times(0)
+			},
+			checkResponse: func(t *testing.T, rec *httptest.ResponseRecorder) {
+				require.Equal(t, http.StatusNotFound, rec.Code)
+			},
+		},
+		{
+			name: "AlreadySaved",
+			body: gin.H{"nickname": "landlord", "account_id": acc.ID, "currency": util.USD},
+			buildStubs: func(store *mockdb.MockStore) {
+				store.EXPECT().GetAccount(gomock.Any(), gomock.Eq(acc.ID)).Times(1).Return(acc, nil)
+				store.EXPECT().CreatePayee(gomock.Any(), gomock.Any()).Times(1).Return(db.Payee{}, &pq.Error{Code: "23505"})
+			},
+			checkResponse: func(t *testing.T, rec *httptest.ResponseRecorder) {
+				require.Equal(t, http.StatusForbidden, rec.Code)
+			},
+		},
+	}
+
+	for i := range testCases {
+		tc := testCases[i]
+
+		t.Run(tc.name, func(t *testing.T) {
+			ctrl := gomock.NewController(t)
+			defer ctrl.Finish()
+
+			store := mockdb.NewMockStore(ctrl)
+			stubAuthUsers(store)
+			tc.buildStubs(store)
+
+			svr := newTestServer(t, store)
+			rec := httptest.NewRecorder()
+
+			data, err := json.Marshal(tc.body)
+			require.NoError(t, err)
+			req, err := http.NewRequest(http.MethodPost, "/payees", bytes.NewReader(data))
+			require.NoError(t, err)
+
+			addAuthorizationHeader(t, req, svr.tokenMaker, authorizationTypeBearer, user.Username, time.Minute)
+			svr.router.ServeHTTP(rec, req)
+			tc.checkResponse(t, rec)
+		})
+	}
+}
+
+func TestTransferToPayeeAPI(t *testing.T) {
+	user, _ := randomUser(t)
+	landlord, _ := randomUser(t)
+	from := randomAccount(user.Username)
+	from.ID = 1
+	from.Currency = util.USD
+	to := randomAccount(landlord.Username)
+	to.ID = 2
+	to.Currency = util.USD
+
+	payee := db.Payee{ID: 3, Owner: user.Username, Nickname: "landlord", AccountID: to.ID, Currency: util.USD, CreatedAt: time.Now().Add(-48 * time.Hour)}
+	newPayee := payee
+	newPayee.CreatedAt = time.Now().Add(-time.Hour)
+
+	testCases := []struct {
+		name          string
+		path          string
+		body          gin.H
+		buildStubs    func(store *mockdb.MockStore)
+		checkResponse func(t *testing.T, rec *httptest.ResponseRecorder)
+	}{
+		{
+			name: "OK",
+			body: gin.H{"from_account_id": from.ID, "payee_id": payee.ID, "amount": 600, "currency": util.USD},
+			buildStubs: func(store *mockdb.MockStore) {
+				store.EXPECT().GetPayee(gomock.Any(), gomock.Eq(payee.ID)).Times(1).Return(payee, nil)
+				store.EXPECT().GetAccount(gomock.Any(), gomock.Eq(from.ID)).Times(1).Return(from, nil)
+				store.EXPECT().GetAccount(gomock.Any(), gomock.Eq(to.ID)).Times(1).Return(to, nil)
+				store.EXPECT().GetAccountHolder(gomock.Any(), gomock.Eq(db.GetAccountHolderParams{AccountID: to.ID, Username: user.Username})).
+					Times(1).Return(db.AccountHolder{}, sql.ErrNoRows)
+				store.EXPECT().GetPayeeByAccount(gomock.Any(), gomock.Eq(db.GetPayeeByAccountParams{Owner: user.Username, AccountID: to.ID})).
+					Times(1).Return(payee, nil)
+				store.EXPECT().GetApprovalPolicyForAmount(gomock.Any(), gomock.Any()).Times(1).Return(db.ApprovalPolicy{}, sql.ErrNoRows)
+				store.EXPECT().
+					TransferTx(gomock.Any(), gomock.Eq(db.TransferTxParams{FromAccountID: from.ID, ToAccountID: to.ID, Amount: 600})).
+					Times(1)
+			},
+			checkResponse: func(t *testing.T, rec *httptest.ResponseRecorder) {
+				require.Equal(t, http.StatusOK, rec.Code)
+			},
+		},
+		{
+			name: "CoolingOff",
+			body: gin.H{"from_account_id": from.ID, "payee_id": payee.ID, "amount": 600, "currency": util.USD},
+			buildStubs: func(store *mockdb.MockStore) {
+				store.EXPECT().GetPayee(gomock.Any(), gomock.Eq(payee.ID)).Times(1).Return(newPayee, nil)
+				store.EXPECT().GetAccount(gomock.Any(), gomock.Eq(from.ID)).Times(1).Return(from, nil)
+				store.EXPECT().GetAccount(gomock.Any(), gomock.Eq(to.ID)).Times(1).Return(to, nil)
+				store.EXPECT().GetAccountHolder(gomock.Any(), gomock.Eq(db.GetAccountHolderParams{AccountID: to.ID, Username: user.Username})).
+					Times(1).Return(db.AccountHolder{}, sql.ErrNoRows)
+				store.EXPECT().GetPayeeByAccount(gomock.Any(), gomock.Any()).Times(1).Return(newPayee, nil)
+				store.EXPECT().TransferTx(gomock.Any(), gomock.Any()).Times(0)
+			},
+			checkResponse: func(t *testing.T, rec *httptest.ResponseRecorder) {
+				require.Equal(t, http.StatusForbidden, rec.Code)
+				require.Contains(t, rec.Body.String(), "added recently")
+			},
+		},
+		{
+			name: "CoolingOffByAccountID",
+			body: gin.H{"from_account_id": from.ID, "to_account_id": to.ID, "amount": 600, "currency": util.USD},
+			buildStubs: func(store *mockdb.MockStore) {
+				store.EXPECT().GetAccount(gomock.Any(), gomock.Eq(from.ID)).Times(1).Return(from, nil)
+				store.EXPECT().GetAccount(gomock.Any(), gomock.Eq(to.ID)).Times(1).Return(to, nil)
+				store.EXPECT().GetAccountHolder(gomock.Any(), gomock.Eq(db.GetAccountHolderParams{AccountID: to.ID, Username: user.Username})).
+					Times(1).Return(db.AccountHolder{}, sql.ErrNoRows)
+				store.EXPECT().GetPayeeByAccount(gomock.Any(), gomock.Any()).Times(1).Return(newPayee, nil)
+				store.EXPECT().TransferTx(gomock.Any(), gomock.Any()).Times(0)
+			},
+			checkResponse: func(t *testing.T, rec *httptest.ResponseRecorder) {
+				require.Equal(t, http.StatusForbidden, rec.Code)
+				require.Contains(t, rec.Body.String(), "added recently")
+			},
+		},
+		{
+			name: "LargeTransferToUnsavedAccount",
+			body: gin.H{"from_account_id": from.ID, "to_account_id": to.ID, "amount": 600, "currency": util.USD},
+			buildStubs: func(store *mockdb.MockStore) {
+				store.EXPECT().GetAccount(gomock.Any(), gomock.Eq(from.ID)).Times(1).Return(from, nil)
+				store.EXPECT().GetAccount(gomock.Any(), gomock.Eq(to.ID)).Times(1).Return(to, nil)
+				store.EXPECT().GetAccountHolder(gomock.Any(), gomock.Eq(db.GetAccountHolderParams{AccountID: to.ID, Username: user.Username})).
+					Times(1).Return(db.AccountHolder{}, sql.ErrNoRows)
+				store.EXPECT().GetPayeeByAccount(gomock.Any(), gomock.Any()).Times(1).Return(db.Payee{}, sql.ErrNoRows)
+				store.EXPECT().TransferTx(gomock.Any(), gomock.Any()).Times(0)
+			},
+			checkResponse: func(t *testing.T, rec *httptest.ResponseRecorder) {
+				require.Equal(t, http.StatusForbidden, rec.Code)
+				require.Contains(t, rec.Body.String(), "only go to payees")
+			},
+		},
+		{
+			name: "LargeDraftToUnsavedAccount",
+			path: "/transfers/drafts",
+			body: gin.H{"from_account_id": from.ID, "to_account_id": to.ID, "amount": 600, "currency": util.USD},
+			buildStubs: func(store *mockdb.MockStore) {
+				store.EXPECT().GetAccount(gomock.Any(), gomock.Eq(from.ID)).Times(1).Return(from, nil)
+				store.EXPECT().GetAccount(gomock.Any(), gomock.Eq(to.ID)).Times(1).Return(to, nil)
+				store.EXPECT().GetAccountHolder(gomock.Any(), gomock.Eq(db.GetAccountHolderParams{AccountID: to.ID, Username: user.Username})).
+					Times(1).Return(db.AccountHolder{}, sql.ErrNoRows)
+				store.EXPECT().GetPayeeByAccount(gomock.Any(), gomock.Any()).Times(1).Return(db.Payee{}, sql.ErrNoRows)
+				store.EXPECT().CreateTransferDraftTx(gomock.Any(), gomock.Any()).Times(0)
+			},
+			checkResponse: func(t *testing.T, rec *httptest.ResponseRecorder) {
+				require.Equal(t, http.StatusForbidden, rec.Code)
+			},
+		},
+		{
+			name: "LargeTransferToOwnAccount",
+			body: gin.H{"from_account_id": from.ID, "to_account_id": to.ID, "amount": 600, "currency": util.USD},
+			buildStubs: func(store *mockdb.MockStore) {
+				own := to
+				own.Owner = user.Username
+				store.EXPECT().GetAccount(gomock.Any(), gomock.Eq(from.ID)).Times(1).Return(from, nil)
+				store.EXPECT().GetAccount(gomock.Any(), gomock.Eq(to.ID)).Times(1).Return(own, nil)
+				store.EXPECT().GetPayeeByAccount(gomock.Any(), gomock.Any()).Times(0)
+				store.EXPECT().GetApprovalPolicyForAmount(gomock.Any(), gomock.Any()).Times(1).Return(db.ApprovalPolicy{}, sql.ErrNoRows)
+				store.EXPECT().TransferTx(gomock.Any(), gomock.Any()).Times(1)
+			},
+			checkResponse: func(t *testing.T, rec *httptest.ResponseRecorder) {
+				require.Equal(t, http.StatusOK, rec.Code)
+			},
+		},
+		{
+			name: "LargeTransferToJointAccount",
+			body: gin.H{"from_account_id": from.ID, "to_account_id": to.ID, "amount": 600, "currency": util.USD},
+			buildStubs: func(store *mockdb.MockStore) {
+				store.EXPECT().GetAccount(gomock.Any(), gomock.Eq(from.ID)).Times(1).Return(from, nil)
+				store.EXPECT().GetAccount(gomock.Any(), gomock.Eq(to.ID)).Times(1).Return(to, nil)
+				store.EXPECT().GetAccountHolder(gomock.Any(), gomock.Eq(db.GetAccountHolderParams{AccountID: to.ID, Username: user.Username})).
+					Times(1).Return(db.AccountHolder{AccountID: to.ID, Username: user.Username}, nil)
+				store.EXPECT().GetPayeeByAccount(gomock.Any(), gomock.Any()).Times(0)
+				store.EXPECT().GetApprovalPolicyForAmount(gomock.Any(), gomock.Any()).Times(1).Return(db.ApprovalPolicy{}, sql.ErrNoRows)
+				store.EXPECT().TransferTx(gomock.Any(), gomock.Any()).Times(1)
+			},
+			checkResponse: func(t *testing.T, rec *httptest.ResponseRecorder) {
+				require.Equal(t, http.StatusOK, rec.Code)
+			},
+		},
+		{
+			name: "SmallAmountToNewPayee",
+			body: gin.H{"from_account_id": from.ID, "payee_id": payee.ID, "amount": 500, "currency": util.USD},
+			buildStubs: func(store *mockdb.MockStore) {
+				store.EXPECT().GetPayee(gomock.Any(), gomock.Eq(payee.ID)).Times(1).Return(newPayee, nil)
+				store.EXPECT().GetAccount(gomock.Any(), gomock.Eq(from.ID)).Times(1).Return(from, nil)
+				store.EXPECT().GetAccount(gomock.Any(), gomock.Eq(to.ID)).Times(1).Return(to, nil)
+				store.EXPECT().GetApprovalPolicyForAmount(gomock.Any(), gomock.Any()).Times(1).Return(db.ApprovalPolicy{}, sql.ErrNoRows)
+				store.EXPECT().TransferTx(gomock.Any(), gomock.Any()).Times(1)
+			},
+			checkResponse: func(t *testing.T, rec *httptest.ResponseRecorder) {
+				require.Equal(t, http.StatusOK, rec.Code)
+			},
+		},
+		{
+			name: "OtherUsersPayee",
+			body: gin.H{"from_account_id": from.ID, "payee_id": payee.ID, "amount": 10, "currency": util.USD},
+			buildStubs: func(store *mockdb.MockStore) {
+				other := payee
+				other.Owner = landlord.Username
+				store.EXPECT().GetPayee(gomock.Any(), gomock.Eq(payee.ID)).Times(1).Return(other, nil)
+				store.EXPECT().TransferTx(gomock.Any(), gomock.Any()).Times(0)
+			},
+			checkResponse: func(t *testing.T, rec *httptest.ResponseRecorder) {
+				require.Equal(t, http.StatusUnauthorized, rec.Code)
+			},
+		},
+		{
+			name: "CurrencyMismatch",
+			body: gin.H{"from_account_id": from.ID, "payee_id": payee.ID, "amount": 10, "currency": util.EUR},
+			buildStubs: func(store *mockdb.MockStore) {
+				store.EXPECT().GetPayee(gomock.Any(), gomock.Eq(payee.ID)).Times(1).Return(payee, nil)
+				store.EXPECT().TransferTx(gomock.Any(), gomock.Any()).Times(0)
+			},
+			checkResponse: func(t *testing.T, rec *httptest.ResponseRecorder) {
+				require.Equal(t, http.StatusBadRequest, rec.Code)
+			},
+		},
+		{
+			name: "PayeeAndAccount",
+			body: gin.H{"from_account_id": from.ID, "to_account_id": to.ID, "payee_id": payee.ID, "amount": 10, "currency": util.USD},
+			buildStubs: func(store *mockdb.MockStore) {
+				store.EXPECT().GetPayee(gomock.Any(), gomock.Any()).Times(0)
+				store.EXPECT().TransferTx(gomock.Any(), gomock.Any()).Times(0)
+			},
+			checkResponse: func(t *testing.T, rec *httptest.ResponseRecorder) {
+				require.Equal(t, http.StatusBadRequest, rec.Code)
+			},
+		},
+		{
+			name: "NoRecipient",
+			body: gin.H{"from_account_id": from.ID, "amount": 10, "currency": util.USD},
+			buildStubs: func(store *mockdb.MockStore) {
+				store.EXPECT().TransferTx(gomock.Any(), gomock.Any()).Times(0)
+			},
+			checkResponse: func(t *testing.T, rec *httptest.ResponseRecorder) {
+				require.Equal(t, http.StatusBadRequest, rec.Code)
+			},
+		},
+	}
+
+	for i := range testCases {
+		tc := testCases[i]
+
+		t.Run(tc.name, func(t *testing.T) {
+			ctrl := gomock.NewController(t)
+			defer ctrl.Finish()
+
+			store := mockdb.NewMockStore(ctrl)
+			stubAuthUsers(store)
+			tc.buildStubs(store)
+
+			svr := newTestServer(t, store)
+			svr.config.PayeeCoolingOff = 24 * time.Hour
+			svr.config.PayeeCoolingOffThreshold = 500
+			rec := httptest.NewRecorder()
+
+			data, err := json.Marshal(tc.body)
+			require.NoError(t, err)
+			path := tc.path
+			if path == "" {
+				path = "/transfers"
+			}
+			req, err := http.NewRequest(http.MethodPost, path, bytes.NewReader(data))
+			require.NoError(t, err)
+
+			addAuthorizationHeader(t, req, svr.tokenMaker, authorizationTypeBearer, user.Username, time.Minute)
+			svr.router.ServeHTTP(rec, req)
+			tc.checkResponse(t, rec)
+		})
+	}
+}
+
+func TestDeletePayeeAPI(t *testing.T) {
+	user, _ := randomUser(t)
+	other, _ := randomUser(t)
+	payee := db.Payee{ID: 3, Owner: user.Username, Nickname: "landlord", AccountID: 2, Currency: util.USD}
+
+	testCases := []struct {
+		name          string
+		username      string
+		buildStubs    func(store *mockdb.MockStore)
+		checkResponse func(t *testing.T, rec *httptest.ResponseRecorder)
+	}{
+		{
+			name:     "OK",
+			username: user.Username,
+			buildStubs: func(store *mockdb.MockStore) {
+				store.EXPECT().GetPayee(gomock.Any(), gomock.Eq(payee.ID)).Times(1).Return(payee, nil)
+				store.EXPECT().DeletePayee(gomock.Any(), gomock.Eq(payee.ID)).Times(1).Return(int64(1), nil)
+			},
+			checkResponse: func(t *testing.T, rec *httptest.ResponseRecorder) {
+				require.Equal(t, http.StatusNoContent, rec.Code)
+			},
+		},
+		{
+			name:     "OtherUsersPayee",
+			username: other.Username,
+			buildStubs: func(store *mockdb.MockStore) {
+				store.EXPECT().GetPayee(gomock.Any(), gomock.Eq(payee.ID)).Times(1).Return(payee, nil)
+				store.EXPECT().DeletePayee(gomock.Any(), gomock.Any()).Times(0)
+			},
+			checkResponse: func(t *testing.T, rec *httptest.ResponseRecorder) {
+				require.Equal(t, http.StatusUnauthorized, rec.Code)
+			},
+		},
+		{
+			name:     "NotFound",
+			username: user.Username,
+			buildStubs: func(store *mockdb.MockStore) {
+				store.EXPECT().GetPayee(gomock.Any(), gomock.Eq(payee.ID)).Times(1).Return(db.Payee{}, sql.ErrNoRows)
+				store.EXPECT().DeletePayee(gomock.Any(), gomock.Any()).Times(0)
+			},
+			checkResponse: func(t *testing.T, rec *httptest.ResponseRecorder) {
+				require.Equal(t, http.StatusNotFound, rec.Code)
+			},
+		},
+	}
+
+	for i := range testCases {
+		tc := testCases[i]
+
+		t.Run(tc.name, func(t *testing.T) {
+			ctrl := gomock.NewController(t)
+			defer ctrl.Finish()
+
+			store := mockdb.NewMockStore(ctrl)
+			stubAuthUsers(store)
+			tc.buildStubs(store)
+
+			svr := newTestServer(t, store)
+			rec := httptest.NewRecorder()
+
+			req, err := http.NewRequest(http.MethodDelete, fmt.Sprintf("/payees/%d", payee.ID), nil)
+			require.NoError(t, err)
+
+			addAuthorizationHeader(t, req, svr.tokenMaker, authorizationTypeBearer, tc.username, time.Minute)
+			svr.router.ServeHTTP(rec, req)
+			tc.checkResponse(t, rec)
+		})
+	}
+}
@@ -2,9 +2,13 @@ package api
 
 import (
 	"fmt"
+	"gobank/bloom"
 	db "gobank/db/sqlc"
+	"gobank/realtime"
 	"gobank/token"
 	"gobank/util"
+	"gobank/webhook"
+	"net"
 
 	"github.com/gin-gonic/gin"
 	"github.com/gin-gonic/gin/binding"
@@ -15,7 +19,16 @@ type Server struct {
 	config     util.Config
 	store      db.Store
 	tokenMaker token.Maker
+	hasher     util.PasswordHasher
+	policy     util.PasswordPolicy
 	router     *gin.Engine
+	hub        *realtime.Hub
+	// stepUpThresholds are STEP_UP_THRESHOLDS by currency; STEP_UP_THRESHOLD covers the others
+	stepUpThresholds map[string]int64
+	// resolver looks up webhook hosts when they are registered
+	resolver webhook.Resolver
+	// dummyPasswordHash is checked when a login names no user, so that answer takes as long as a wrong password
+	dummyPasswordHash string
 }
 
 func NewServer(cfg util.Config, s db.Store) (server *Server, err error) {
@@ -24,35 +37,176 @@ func NewServer(cfg util.Config, s db.Store) (server *Server, err error) {
 		return nil, fmt.Errorf("cannot create token maker: %w", err)
 	}
 
+	hasher, err := newPasswordHasher(cfg)
+	if err != nil {
+		return nil, fmt.Errorf("cannot create password hasher: %w", err)
+	}
+	dummyHash, err := hasher.Hash(util.RandomString(32))
+	if err != nil {
+		return nil, err
+	}
+
+	if err := checkTrustedProxies(cfg.TrustedProxies); err != nil {
+		return nil, fmt.Errorf("cannot parse TRUSTED_PROXIES: %w", err)
+	}
+
+	stepUpThresholds, err := util.ParseCurrencyAmounts(cfg.StepUpThresholds)
+	if err != nil {
+		return nil, fmt.Errorf("cannot parse STEP_UP_THRESHOLDS: %w", err)
+	}
+
+	policy := util.PasswordPolicy{
+		MinLength:      cfg.PasswordMinLength,
+		MinCharClasses: cfg.PasswordMinCharClasses,
+	}
+	if cfg.BreachedPasswordsFile != "" {
+		breached, err := bloom.Load(cfg.BreachedPasswordsFile)
+		if err != nil {
+			return nil, fmt.Errorf("cannot load breached passwords: %w", err)
+		}
+		policy.Breached = breached
+	}
+
 	server = &Server{
-		config:     cfg,
-		store:      s,
-		tokenMaker: tm,
+		config:            cfg,
+		store:             s,
+		tokenMaker:        tm,
+		hasher:            hasher,
+		policy:            policy,
+		hub:               realtime.NewHub(),
+		stepUpThresholds:  stepUpThresholds,
+		resolver:          net.DefaultResolver,
+		dummyPasswordHash: dummyHash,
 	}
 
 	if v, ok := binding.Validator.Engine().(*validator.Validate); ok {
 		v.RegisterValidation("currency", validCurrency)
+		v.RegisterValidation("accountnumber", validAccountNumber)
+		v.RegisterValidation("accountref", validAccountRef)
+		v.RegisterValidation("webhookevent", validWebhookEvent)
+		v.RegisterValidation("scope", validScope)
+		v.RegisterValidation("consentpermission", validConsentPermission)
 	}
 
 	server.setupRouter()
 	return
 }
 
+// newPasswordHasher picks the algorithm new password hashes are made with; argon2id unless configured otherwise.
+func newPasswordHasher(cfg util.Config) (util.PasswordHasher, error) {
+	switch cfg.PasswordHasher {
+	case "", util.Argon2id:
+		return util.NewArgon2idHasher(util.Argon2Params{
+			Memory:      cfg.Argon2Memory,
+			Iterations:  cfg.Argon2Iterations,
+			Parallelism: cfg.Argon2Parallelism,
+		})
+	case util.Bcrypt:
+		return util.NewBcryptHasher(cfg.BcryptCost)
+	}
+	return nil, fmt.Errorf("unknown password hasher %q", cfg.PasswordHasher)
+}
+
+// Hub is where account updates must be broadcast for the streaming endpoint to see them.
+func (s *Server) Hub() *realtime.Hub {
+	return s.hub
+}
+
 func (s *Server) Start(addr string) error {
 	return s.router.Run(addr)
 }
+
+// checkTrustedProxies makes sure every entry of TRUSTED_PROXIES is an ip address or a CIDR range.
+func checkTrustedProxies(proxies []string) error {
+	for _, proxy := range proxies {
+		if net.ParseIP(proxy) != nil {
+			continue
+		}
+		if _, _, err := net.ParseCIDR(proxy); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
 func (s *Server) setupRouter() {
 	r := gin.Default()
+	//gin trusts X-Forwarded-For from everyone by default, which would let any client pick the ip its
+	//failed logins count against; only the configured proxies are believed
+	r.TrustedProxies = s.config.TrustedProxies
 
 	r.POST("/users", s.createUser)
 	r.POST("/users/login", s.login)
+	r.POST("/users/login/mfa", s.loginMFA)
+	r.GET("/verify_email", s.verifyEmail)
+	r.POST("/users/password/forgot", s.forgotPassword)
+	r.POST("/users/password/reset", s.resetPassword)
+	r.POST("/oauth/token", s.oauthToken)
+	r.POST("/oauth/introspect", s.introspectOAuthToken)
+	r.POST("/oauth/revoke", s.revokeOAuthToken)
+	r.POST("/open_banking/consents", s.createAccountConsent)
+	r.GET("/open_banking/consents/:id", s.getAccountConsent)
+	r.DELETE("/open_banking/consents/:id", s.deleteAccountConsent)
+
+	authRoutes := r.Group("/").Use(authMiddleware(s.tokenMaker, s.store))
+
+	authRoutes.PATCH("/users/:username", requireSession(), s.updateUser)
+	authRoutes.POST("/users/password", requireSession(), s.changePassword)
+	authRoutes.POST("/users/verify_email", requireSession(), s.resendVerifyEmail)
+	authRoutes.POST("/users/step_up", requireSession(), s.stepUp)
+	authRoutes.POST("/users/mfa/totp", requireSession(), s.enrollTOTP)
+	authRoutes.POST("/users/mfa/totp/confirm", requireSession(), s.confirmTOTP)
+	authRoutes.POST("/api_keys", requireSession(), s.createAPIKey)
+	authRoutes.GET("/api_keys", requireSession(), s.listAPIKeys)
+	authRoutes.DELETE("/api_keys/:id", requireSession(), s.revokeAPIKey)
+	authRoutes.POST("/oauth/clients", requireSession(), s.createOAuthClient)
+	authRoutes.GET("/oauth/clients", requireSession(), s.listOAuthClients)
+	authRoutes.POST("/oauth/authorize", requireSession(), s.authorizeOAuthClient)
+	authRoutes.GET("/oauth/consents", requireSession(), s.listOAuthConsents)
+	authRoutes.DELETE("/oauth/consents/:client_id", requireSession(), s.deleteOAuthConsent)
+	authRoutes.GET("/account_consents", requireSession(), s.listAccountConsents)
+	authRoutes.DELETE("/account_consents/:id", requireSession(), s.revokeAccountConsent)
+	authRoutes.POST("/accounts", requireScope(token.ScopeAccountsWrite), rejectAccountConsent(), s.createAccount)
+	authRoutes.GET("/accounts/:id", requireScope(token.ScopeAccountsRead), s.getAccount)
+	authRoutes.GET("/accounts/:id/balance", requireScope(token.ScopeAccountsRead), s.getAccountBalance)
+	authRoutes.GET("/accounts/:id/statements", requireScope(token.ScopeAccountsRead), s.getStatement)
+	authRoutes.POST("/accounts/:id/permissions", requireSession(), s.grantAccountPermission)
+	authRoutes.GET("/accounts/:id/permissions", requireSession(), s.listAccountPermissions)
+	authRoutes.DELETE("/accounts/:id/permissions/:username", requireSession(), s.revokeAccountPermission)
+	authRoutes.POST("/accounts/:id/holders", requireSession(), s.addAccountHolder)
+	authRoutes.GET("/accounts/:id/holders", requireScope(token.ScopeAccountsRead), rejectAccountConsent(), s.listAccountHolders)
+	authRoutes.DELETE("/accounts/:id/holders/:username", requireSession(), s.removeAccountHolder)
+	authRoutes.PUT("/accounts/:id/approval_rule", requireSession(), s.setApprovalRule)
+	authRoutes.POST("/accounts/:id/approval_policies", requireSession(), s.setApprovalPolicy)
+	authRoutes.GET("/accounts/:id/approval_policies", requireScope(token.ScopeAccountsRead), rejectAccountConsent(), s.listApprovalPolicies)
+	authRoutes.DELETE("/accounts/:id/approval_policies/:policy_id", requireSession(), s.deleteApprovalPolicy)
+	authRoutes.GET("/accounts/:id/transfer_drafts", requireScope(token.ScopeAccountsRead), rejectAccountConsent(), s.listTransferDrafts)
+	authRoutes.GET("/accounts/", requireScope(token.ScopeAccountsRead), s.listAccount)
+	authRoutes.GET("/accounts/stream", requireScope(token.ScopeAccountsRead), rejectAccountConsent(), s.streamAccountUpdates)
+	authRoutes.POST("/payees", requireSession(), s.createPayee)
+	authRoutes.GET("/payees", requireScope(token.ScopeAccountsRead), rejectAccountConsent(), s.listPayees)
+	authRoutes.GET("/payees/:id", requireScope(token.ScopeAccountsRead), rejectAccountConsent(), s.getPayee)
+	authRoutes.PATCH("/payees/:id", requireSession(), s.updatePayee)
+	authRoutes.DELETE("/payees/:id", requireSession(), s.deletePayee)
+	authRoutes.POST("/transfers", requireScope(token.ScopeTransfersWrite), verifiedEmailMiddleware(), s.createTransfer)
+	authRoutes.POST("/transfers/batches", requireScope(token.ScopeTransfersWrite), rejectAccountConsent(), verifiedEmailMiddleware(), s.createPaymentBatch)
+	authRoutes.POST("/transfers/drafts", requireScope(token.ScopeTransfersWrite), rejectAccountConsent(), verifiedEmailMiddleware(), s.createTransferDraft)
+	authRoutes.GET("/transfers/drafts/:id", requireScope(token.ScopeAccountsRead), rejectAccountConsent(), s.getTransferDraft)
+	authRoutes.POST("/transfers/drafts/:id/approve", requireScope(token.ScopeTransfersWrite), rejectAccountConsent(), verifiedEmailMiddleware(), s.approveTransferDraft)
+	authRoutes.POST("/transfers/drafts/:id/reject", requireScope(token.ScopeTransfersWrite), rejectAccountConsent(), s.rejectTransferDraft)
+	authRoutes.GET("/transfers/batches/:id", requireScope(token.ScopeAccountsRead), rejectAccountConsent(), s.getPaymentBatch)
+	authRoutes.POST("/webhooks", requireScope(token.ScopeWebhooks), rejectAccountConsent(), s.createWebhook)
+	authRoutes.GET("/webhooks", requireScope(token.ScopeWebhooks), rejectAccountConsent(), s.listWebhooks)
+	authRoutes.DELETE("/webhooks/:id", requireScope(token.ScopeWebhooks), rejectAccountConsent(), s.deleteWebhook)
+	authRoutes.GET("/webhooks/:id/deliveries", requireScope(token.ScopeWebhooks), rejectAccountConsent(), s.listWebhookDeliveries)
+	authRoutes.POST("/webhooks/:id/deliveries/:delivery_id/redeliver", requireScope(token.ScopeWebhooks), rejectAccountConsent(), s.redeliverWebhook)
 
-	authRoutes := r.Group("/").Use(authMiddleware(s.tokenMaker))
+	adminRoutes := r.Group("/admin").Use(authMiddleware(s.tokenMaker, s.store), requireSession(), adminMiddleware(&s.config))
 
-	authRoutes.POST("/accounts", s.createAccount)
-	authRoutes.GET("/accounts/:id", s.getAccount)
-	authRoutes.GET("/accounts/", s.listAccount)
-	authRoutes.POST("/transfers", s.createTransfer)
+	adminRoutes.GET("/reconciliation", s.reconcileLedger)
+	adminRoutes.GET("/jobs", s.listJobs)
+	adminRoutes.POST("/jobs/:id/retry", s.retryJob)
+	adminRoutes.POST("/users/:username/unlock", s.unlockUser)
 
 	s.router = r
 }
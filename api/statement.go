@@ -0,0 +1,117 @@
+package api
+
+import (
+	"bytes"
+	"database/sql"
+	"errors"
+	"fmt"
+	db "gobank/db/sqlc"
+	"gobank/statement"
+	"net/http"
+	"time"
+
+	"github.com/gin-gonic/gin"
+)
+
+type getStatementRequest struct {
+	From   time.Time `form:"from" binding:"required" time_format:"2006-01-02T15:04:05Z07:00"`
+	To     time.Time `form:"to" binding:"required" time_format:"2006-01-02T15:04:05Z07:00"`
+	Format string    `form:"format" binding:"omitempty,oneof=csv json pdf ofx camt053"`
+}
+
+func (s *Server) getStatement(ctx *gin.Context) {
+	var uri accountRefRequest
+	if err := ctx.ShouldBindUri(&uri); err != nil {
+		ctx.JSON(http.StatusBadRequest, errorResponse(err))
+		return
+	}
+	var req getStatementRequest
+	if err := ctx.ShouldBindQuery(&req); err != nil {
+		ctx.JSON(http.StatusBadRequest, errorResponse(err))
+		return
+	}
+	if !req.To.After(req.From) {
+		err := errors.New("statement period must end after it starts")
+		ctx.JSON(http.StatusBadRequest, errorResponse(err))
+		return
+	}
+	format := statement.Format(req.Format)
+	if format == "" {
+		format = statement.FormatJSON
+	}
+
+	id, ok := s.accountRefID(ctx, uri)
+	if !ok {
+		return
+	}
+	if !checkConsent(ctx, id, permissionReadTransactions) {
+		return
+	}
+	acc, ok := s.accountAccess(ctx, id, accessRead)
+	if !ok {
+		return
+	}
+
+	st, err := s.buildStatement(ctx, acc, req.From, req.To)
+	if err != nil {
+		ctx.JSON(http.StatusInternalServerError, errorResponse(err))
+		return
+	}
+
+	var buf bytes.Buffer
+	if err := statement.Write(&buf, format, st); err != nil {
+		ctx.JSON(http.StatusInternalServerError, errorResponse(err))
+		return
+	}
+
+	ctx.Header("Content-Disposition", fmt.Sprintf("attachment; filename=%q", statement.Filename(format, st)))
+	ctx.Data(http.StatusOK, format.ContentType(), buf.Bytes())
+}
+
+// buildStatement collects the entries of acc in [from, to) along with the balances on either side.
+func (s *Server) buildStatement(ctx *gin.Context, acc db.Account, from, to time.Time) (statement.Statement, error) {
+	st := statement.Statement{
+		AccountID:   acc.ID,
+		Owner:       acc.Owner,
+		Currency:    acc.Currency,
+		From:        from,
+		To:          to,
+		Lines:       []statement.Line{},
+		GeneratedAt: time.Now(),
+	}
+
+	//the balance query is inclusive, so step back to the last instant before the period
+	opening, err := s.store.GetAccountBalanceAt(ctx, db.GetAccountBalanceAtParams{
+		AccountID: acc.ID,
+		At:        from.Add(-time.Microsecond),
+	})
+	if err != nil && err != sql.ErrNoRows {
+		return st, err
+	}
+	st.OpeningBalance = opening
+	st.ClosingBalance = opening
+
+	entries, err := s.store.ListStatementEntries(ctx, db.ListStatementEntriesParams{
+		AccountID: acc.ID,
+		FromTime:  from,
+		ToTime:    to,
+	})
+	if err != nil {
+		return st, err
+	}
+
+	for _, e := range entries {
+		st.Lines = append(st.Lines, statement.Line{
+			EntryID:               e.ID,
+			Date:                  e.CreatedAt,
+			Amount:                e.Amount,
+			BalanceAfter:          e.BalanceAfter,
+			TransferID:            e.TransferID,
+			CounterpartyAccountID: e.CounterpartyAccountID,
+			CounterpartyOwner:     e.CounterpartyOwner,
+			Memo:                  e.Memo,
+		})
+		st.ClosingBalance = e.BalanceAfter
+	}
+	return st, nil
+}
@@ -0,0 +1,220 @@
+package api
+
+import (
+	"database/sql"
+	"encoding/json"
+	"fmt"
+	mockdb "gobank/db/mock"
+	db "gobank/db/sqlc"
+	"gobank/statement"
+	"gobank/token"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/golang/mock/gomock"
+	"github.com/stretchr/testify/require"
+)
+
+func TestGetStatementAPI(t *testing.T) {
+	user, _ := randomUser(t)
+	acc := randomAccount(user.Username)
+	from := time.Date(2021, time.March, 1, 0, 0, 0, 0, time.UTC)
+	to := from.AddDate(0, 1, 0)
+
+	entries := []db.ListStatementEntriesRow{
+		{ID: 1, Amount: 50, BalanceAfter: 150, CreatedAt: from.Add(time.Hour), TransferID: 9, CounterpartyAccountID: 3, CounterpartyOwner: "bob", Memo: "lunch"},
+		{ID: 2, Amount: -20, BalanceAfter: 130, CreatedAt: from.Add(2 * time.Hour), TransferID: 10, CounterpartyAccountID: 3, CounterpartyOwner: "bob"},
+	}
+
+	query := func(format string) url.Values {
+		q := url.Values{}
+		q.Set("from", from.Format(time.RFC3339))
+		q.Set("to", to.Format(time.RFC3339))
+		if format != "" {
+			q.Set("format", format)
+		}
+		return q
+	}
+
+	stubStatement := func(store *mockdb.MockStore) {
+		store.EXPECT().GetAccount(gomock.Any(), gomock.Eq(acc.ID)).Times(1).Return(acc, nil)
+		store.EXPECT().
+			GetAccountBalanceAt(gomock.Any(), gomock.Eq(db.GetAccountBalanceAtParams{AccountID: acc.ID, At: from.Add(-time.Microsecond)})).
+			Times(1).
+			Return(int64(100), nil)
+		store.EXPECT().
+			ListStatementEntries(gomock.Any(), gomock.Eq(db.ListStatementEntriesParams{AccountID: acc.ID, FromTime: from, ToTime: to})).
+			Times(1).
+			Return(entries, nil)
+	}
+
+	testCases := []struct {
+		name          string
+		query         url.Values
+		setupAuth     func(t *testing.T, r *http.Request, tm token.Maker)
+		buildStubs    func(store *mockdb.MockStore)
+		checkResponse func(t *testing.T, rec *httptest.ResponseRecorder)
+	}{
+		{
+			name:  "JSON",
+			query: query(""),
+			setupAuth: func(t *testing.T, r *http.Request, tm token.Maker) {
+				addAuthorizationHeader(t, r, tm, authorizationTypeBearer, user.Username, time.Minute)
+			},
+			buildStubs: stubStatement,
+			checkResponse: func(t *testing.T, rec *httptest.ResponseRecorder) {
+				require.Equal(t, http.StatusOK, rec.Code)
+				require.Equal(t, "application/json", rec.Header().Get("Content-Type"))
+
+				var st statement.Statement
+				require.NoError(t, json.Unmarshal(rec.Body.Bytes(), &st))
+				require.Equal(t, int64(100), st.OpeningBalance)
+				require.Equal(t, int64(130), st.ClosingBalance)
+				require.Len(t, st.Lines, 2)
+				require.Equal(t, "lunch", st.Lines[0].Memo)
+			},
+		},
+		{
+			name:  "CSV",
+			query: query("csv"),
+			setupAuth: func(t *testing.T, r *http.Request, tm token.Maker) {
+				addAuthorizationHeader(t, r, tm, authorizationTypeBearer, user.Username, time.Minute)
+			},
+			buildStubs: stubStatement,
+			checkResponse: func(t *testing.T, rec *httptest.ResponseRecorder) {
+				require.Equal(t, http.StatusOK, rec.Code)
+				require.Equal(t, "text/csv", rec.Header().Get("Content-Type"))
+				require.Contains(t, rec.Header().Get("Content-Disposition"), fmt.Sprintf("statement-%d-20210301-20210401.csv", acc.ID))
+				require.Contains(t, rec.Body.String(), "Transfer 9,bob (account 3),lunch,50,150")
+			},
+		},
+		{
+			name:  "PDF",
+			query: query("pdf"),
+			setupAuth: func(t *testing.T, r *http.Request, tm token.Maker) {
+				addAuthorizationHeader(t, r, tm, authorizationTypeBearer, user.Username, time.Minute)
+			},
+			buildStubs: stubStatement,
+			checkResponse: func(t *testing.T, rec *httptest.ResponseRecorder) {
+				require.Equal(t, http.StatusOK, rec.Code)
+				require.Equal(t, "application/pdf", rec.Header().Get("Content-Type"))
+				require.True(t, strings.HasPrefix(rec.Body.String(), "%PDF-"))
+			},
+		},
+		{
+			name:  "CAMT053",
+			query: query("camt053"),
+			setupAuth: func(t *testing.T, r *http.Request, tm token.Maker) {
+				addAuthorizationHeader(t, r, tm, authorizationTypeBearer, user.Username, time.Minute)
+			},
+			buildStubs: stubStatement,
+			checkResponse: func(t *testing.T, rec *httptest.ResponseRecorder) {
+				require.Equal(t, http.StatusOK, rec.Code)
+				require.Equal(t, "application/xml", rec.Header().Get("Content-Type"))
+				require.Contains(t, rec.Header().Get("Content-Disposition"), ".xml")
+				require.Contains(t, rec.Body.String(), "<Ustrd>lunch</Ustrd>")
+			},
+		},
+		{
+			name:  "OFX",
+			query: query("ofx"),
+			setupAuth: func(t *testing.T, r *http.Request, tm token.Maker) {
+				addAuthorizationHeader(t, r, tm, authorizationTypeBearer, user.Username, time.Minute)
+			},
+			buildStubs: stubStatement,
+			checkResponse: func(t *testing.T, rec *httptest.ResponseRecorder) {
+				require.Equal(t, http.StatusOK, rec.Code)
+				require.Equal(t, "application/x-ofx", rec.Header().Get("Content-Type"))
+				require.Contains(t, rec.Body.String(), "<BALAMT>130</BALAMT>")
+			},
+		},
+		{
+			name:  "UnsupportedFormat",
+			query: query("xls"),
+			setupAuth: func(t *testing.T, r *http.Request, tm token.Maker) {
+				addAuthorizationHeader(t, r, tm, authorizationTypeBearer, user.Username, time.Minute)
+			},
+			buildStubs: func(store *mockdb.MockStore) {
+				store.EXPECT().GetAccount(gomock.Any(), gomock.Any()).Times(0)
+			},
+			checkResponse: func(t *testing.T, rec *httptest.ResponseRecorder) {
+				require.Equal(t, http.StatusBadRequest, rec.Code)
+			},
+		},
+		{
+			name: "PeriodEndsBeforeStart",
+			query: url.Values{
+				"from": []string{to.Format(time.RFC3339)},
+				"to":   []string{from.Format(time.RFC3339)},
+			},
+			setupAuth: func(t *testing.T, r *http.Request, tm token.Maker) {
+				addAuthorizationHeader(t, r, tm, authorizationTypeBearer, user.Username, time.Minute)
+			},
+			buildStubs: func(store *mockdb.MockStore) {
+				store.EXPECT().GetAccount(gomock.Any(), gomock.Any()).Times(0)
+			},
+			checkResponse: func(t *testing.T, rec *httptest.ResponseRecorder) {
+				require.Equal(t, http.StatusBadRequest, rec.Code)
+			},
+		},
+		{
+			name:  "UnauthorizedUser",
+			query: query(""),
+			setupAuth: func(t *testing.T, r *http.Request, tm token.Maker) {
+				addAuthorizationHeader(t, r, tm, authorizationTypeBearer, "unauthorized_user", time.Minute)
+			},
+			buildStubs: func(store *mockdb.MockStore) {
+				store.EXPECT().GetAccount(gomock.Any(), gomock.Eq(acc.ID)).Times(1).Return(acc, nil)
+				store.EXPECT().GetAccountHolder(gomock.Any(), gomock.Any()).Times(1).Return(db.AccountHolder{}, sql.ErrNoRows)
+				store.EXPECT().GetAccountPermission(gomock.Any(), gomock.Any()).Times(1).Return(db.AccountPermission{}, sql.ErrNoRows)
+				store.EXPECT().ListStatementEntries(gomock.Any(), gomock.Any()).Times(0)
+			},
+			checkResponse: func(t *testing.T, rec *httptest.ResponseRecorder) {
+				require.Equal(t, http.StatusUnauthorized, rec.Code)
+			},
+		},
+		{
+			name:  "InternalError",
+			query: query(""),
+			setupAuth: func(t *testing.T, r *http.Request, tm token.Maker) {
+				addAuthorizationHeader(t, r, tm, authorizationTypeBearer, user.Username, time.Minute)
+			},
+			buildStubs: func(store *mockdb.MockStore) {
+				store.EXPECT().GetAccount(gomock.Any(), gomock.Eq(acc.ID)).Times(1).Return(acc, nil)
+				store.EXPECT().GetAccountBalanceAt(gomock.Any(), gomock.Any()).Times(1).Return(int64(0), sql.ErrNoRows)
+				store.EXPECT().ListStatementEntries(gomock.Any(), gomock.Any()).Times(1).Return(nil, sql.ErrConnDone)
+			},
+			checkResponse: func(t *testing.T, rec *httptest.ResponseRecorder) {
+				require.Equal(t, http.StatusInternalServerError, rec.Code)
+			},
+		},
+	}
+
+	for i := range testCases {
+		tc := testCases[i]
+
+		t.Run(tc.name, func(t *testing.T) {
+			ctrl := gomock.NewController(t)
+			defer ctrl.Finish()
+
+			store := mockdb.NewMockStore(ctrl)
+			stubAuthUsers(store)
+			tc.buildStubs(store)
+
+			svr := newTestServer(t, store)
+			rec := httptest.NewRecorder()
+
+			url := fmt.Sprintf("/accounts/%d/statements?%s", acc.ID, tc.query.Encode())
+			req, err := http.NewRequest(http.MethodGet, url, nil)
+			require.NoError(t, err)
+
+			tc.setupAuth(t, req, svr.tokenMaker)
+			svr.router.ServeHTTP(rec, req)
+			tc.checkResponse(t, rec)
+		})
+	}
+}
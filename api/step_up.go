@@ -0,0 +1,95 @@
+package api
+
+import (
+	"errors"
+	"fmt"
+	db "gobank/db/sqlc"
+	"gobank/token"
+	"net/http"
+	"time"
+
+	"github.com/gin-gonic/gin"
+)
+
+type stepUpRequest struct {
+	Password string `json:"password" binding:"required_without=Code"`
+	Code     string `json:"code" binding:"required_without=Password,omitempty,len=6,numeric"`
+}
+
+// stepUp re-authenticates a signed-in user with their password or a TOTP code and returns a fresh access
+// token, which lets them make transfers that need recent authentication.
+func (s *Server) stepUp(ctx *gin.Context) {
+	var req stepUpRequest
+	if err := ctx.ShouldBindJSON(&req); err != nil {
+		ctx.JSON(http.StatusBadRequest, errorResponse(err))
+		return
+	}
+	user := ctx.MustGet(authorizationUserKey).(db.User)
+	if !s.beginLoginAttempt(ctx, user.Username) {
+		return
+	}
+
+	if req.Password != "" {
+		if err := s.hasher.Check(req.Password, user.HashedPassword); err != nil {
+			ctx.JSON(http.StatusUnauthorized, errorResponse(err))
+			return
+		}
+		if !s.endLoginAttempt(ctx, user.Username) {
+			return
+		}
+		s.issueAccessToken(ctx, user, token.AMRPassword)
+		return
+	}
+
+	used, err := s.useTOTPCode(ctx, user.Username, req.Code)
+	if err != nil {
+		ctx.JSON(http.StatusInternalServerError, errorResponse(err))
+		return
+	}
+	if used == 0 {
+		ctx.JSON(http.StatusUnauthorized, errorResponse(errors.New("invalid or already used code")))
+		return
+	}
+	if !s.endLoginAttempt(ctx, user.Username) {
+		return
+	}
+	s.issueAccessToken(ctx, user, token.AMROTP)
+}
+
+// stepUpThreshold is the amount of currency, in its minor unit, above which a transfer needs step-up; zero
+// turns step-up off for it.
+func (s *Server) stepUpThreshold(currency string) int64 {
+	if threshold, ok := s.stepUpThresholds[currency]; ok {
+		return threshold
+	}
+	return s.config.StepUpThreshold
+}
+
+// checkStepUp lets amounts of currency over its step-up threshold through only if the caller authenticated
+// within STEP_UP_MAX_AGE. Otherwise it answers 401 with the RFC 9470 challenge telling the client to call
+// stepUp, or 403 for api keys and oauth tokens, which carry no sign-in and cannot be stepped up.
+func (s *Server) checkStepUp(ctx *gin.Context, currency string, amount int64) bool {
+	threshold := s.stepUpThreshold(currency)
+	if threshold <= 0 || amount <= threshold {
+		return true
+	}
+
+	if _, limited := ctx.Get(authorizationScopesKey); limited {
+		err := fmt.Errorf("amounts over %d %s need a recent sign-in, which api keys and oauth tokens cannot provide; "+
+			"use a session", threshold, currency)
+		ctx.JSON(http.StatusForbidden, errorResponse(err))
+		return false
+	}
+
+	payload := ctx.MustGet(authorizationPayloadKey).(*token.Payload)
+	if time.Since(payload.AuthTime) <= s.config.StepUpMaxAge {
+		return true
+	}
+
+	maxAge := int(s.config.StepUpMaxAge.Seconds())
+	ctx.Header("WWW-Authenticate", fmt.Sprintf(`Bearer error="insufficient_user_authentication", max_age=%d`, maxAge))
+	err := fmt.Errorf("amounts over %d %s need authentication within the last %s; re-authenticate at POST /users/step_up",
+		threshold, currency, s.config.StepUpMaxAge)
+	ctx.JSON(http.StatusUnauthorized, errorResponse(err))
+	return false
+}
@@ -0,0 +1,355 @@
+package api
+
+import (
+	"bytes"
+	"database/sql"
+	"encoding/json"
+	"fmt"
+	mockdb "gobank/db/mock"
+	db "gobank/db/sqlc"
+	"gobank/token"
+	"gobank/totp"
+	"gobank/util"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/golang/mock/gomock"
+	"github.com/stretchr/testify/require"
+)
+
+const testStepUpThreshold = 1000
+
+// addAuthenticatedHeader is addAuthorizationHeader for a token whose user authenticated at authTime.
+func addAuthenticatedHeader(t *testing.T, r *http.Request, tm token.Maker, username string, authTime time.Time) {
+	tok, err := tm.CreateToken(username, time.Minute, token.WithAuthentication(authTime, token.AMRPassword))
+	require.NoError(t, err)
+	r.Header.Set(authorizationHeaderKey, fmt.Sprintf("%s %s", authorizationTypeBearer, tok))
+}
+
+func TestTransferStepUpAPI(t *testing.T) {
+	user, _ := randomUser(t)
+	other, _ := randomUser(t)
+	from := randomAccount(user.Username)
+	from.Currency = util.USD
+	to := randomAccount(other.Username)
+	to.Currency = util.USD
+
+	testCases := []struct {
+		name          string
+		amount        int64
+		authTime      time.Time
+		buildStubs    func(store *mockdb.MockStore)
+		checkResponse func(t *testing.T, rec *httptest.ResponseRecorder)
+	}{
+		{
+			name:     "RecentAuthentication",
+			amount:   testStepUpThreshold + 1,
+			authTime: time.Now().Add(-time.Minute),
+			buildStubs: func(store *mockdb.MockStore) {
+				store.EXPECT().GetAccount(gomock.Any(), gomock.Eq(from.ID)).Times(1).Return(from, nil)
+				store.EXPECT().GetAccount(gomock.Any(), gomock.Eq(to.ID)).Times(1).Return(to, nil)
+				store.EXPECT().GetApprovalPolicyForAmount(gomock.Any(), gomock.Any()).Times(1).Return(db.ApprovalPolicy{}, sql.ErrNoRows)
+				store.EXPECT().TransferTx(gomock.Any(), gomock.Any()).Times(1)
+			},
+			checkResponse: func(t *testing.T, rec *httptest.ResponseRecorder) {
+				require.Equal(t, http.StatusOK, rec.Code)
+			},
+		},
+		{
+			name:     "StaleAuthentication",
+			amount:   testStepUpThreshold + 1,
+			authTime: time.Now().Add(-time.Hour),
+			buildStubs: func(store *mockdb.MockStore) {
+				store.EXPECT().GetAccount(gomock.Any(), gomock.Any()).Times(0)
+				store.EXPECT().TransferTx(gomock.Any(), gomock.Any()).Times(0)
+			},
+			checkResponse: func(t *testing.T, rec *httptest.ResponseRecorder) {
+				require.Equal(t, http.StatusUnauthorized, rec.Code)
+				require.Contains(t, rec.Header().Get("WWW-Authenticate"), `error="insufficient_user_authentication"`)
+			},
+		},
+		{
+			name:     "StaleAuthenticationBelowThreshold",
+			amount:   testStepUpThreshold,
+			authTime: time.Now().Add(-time.Hour),
+			buildStubs: func(store *mockdb.MockStore) {
+				store.EXPECT().GetAccount(gomock.Any(), gomock.Eq(from.ID)).Times(1).Return(from, nil)
+				store.EXPECT().GetAccount(gomock.Any(), gomock.Eq(to.ID)).Times(1).Return(to, nil)
+				store.EXPECT().GetApprovalPolicyForAmount(gomock.Any(), gomock.Any()).Times(1).Return(db.ApprovalPolicy{}, sql.ErrNoRows)
+				store.EXPECT().TransferTx(gomock.Any(), gomock.Any()).Times(1)
+			},
+			checkResponse: func(t *testing.T, rec *httptest.ResponseRecorder) {
+				require.Equal(t, http.StatusOK, rec.Code)
+			},
+		},
+	}
+
+	for i := range testCases {
+		tc := testCases[i]
+
+		t.Run(tc.name, func(t *testing.T) {
+			ctrl := gomock.NewController(t)
+			defer ctrl.Finish()
+
+			store := mockdb.NewMockStore(ctrl)
+			stubAuthUsers(store)
+			tc.buildStubs(store)
+
+			svr := newTestServer(t, store)
+			svr.config.StepUpThreshold = testStepUpThreshold
+			svr.config.StepUpMaxAge = 5 * time.Minute
+			rec := httptest.NewRecorder()
+
+			data, err := json.Marshal(gin.H{
+				"from_account_id": from.ID,
+				"to_account_id":   to.ID,
+				"amount":          tc.amount,
+				"currency":        util.USD,
+			})
+			require.NoError(t, err)
+
+			req, err := http.NewRequest(http.MethodPost, "/transfers", bytes.NewReader(data))
+			require.NoError(t, err)
+
+			addAuthenticatedHeader(t, req, svr.tokenMaker, user.Username, tc.authTime)
+			svr.router.ServeHTTP(rec, req)
+			tc.checkResponse(t, rec)
+		})
+	}
+}
+
+func TestTransferStepUpPerCurrencyAPI(t *testing.T) {
+	user, _ := randomUser(t)
+	other, _ := randomUser(t)
+
+	testCases := []struct {
+		name     string
+		currency string
+		status   int
+	}{
+		// EUR has a threshold of its own
+		{name: "UnderCurrencyThreshold", currency: util.EUR, status: http.StatusOK},
+		// CAD falls back to STEP_UP_THRESHOLD
+		{name: "OverDefaultThreshold", currency: util.CAD, status: http.StatusUnauthorized},
+	}
+
+	for i := range testCases {
+		tc := testCases[i]
+
+		t.Run(tc.name, func(t *testing.T) {
+			from := randomAccount(user.Username)
+			from.Currency = tc.currency
+			to := randomAccount(other.Username)
+			to.Currency = tc.currency
+
+			ctrl := gomock.NewController(t)
+			defer ctrl.Finish()
+
+			store := mockdb.NewMockStore(ctrl)
+			stubAuthUsers(store)
+			store.EXPECT().GetAccount(gomock.Any(), gomock.Eq(from.ID)).AnyTimes().Return(from, nil)
+			store.EXPECT().GetAccount(gomock.Any(), gomock.Eq(to.ID)).AnyTimes().Return(to, nil)
+			store.EXPECT().GetApprovalPolicyForAmount(gomock.Any(), gomock.Any()).AnyTimes().Return(db.ApprovalPolicy{}, sql.ErrNoRows)
+			store.EXPECT().TransferTx(gomock.Any(), gomock.Any()).AnyTimes()
+
+			svr := newTestServer(t, store)
+			svr.config.StepUpThreshold = testStepUpThreshold
+			svr.config.StepUpMaxAge = 5 * time.Minute
+			svr.stepUpThresholds = map[string]int64{util.EUR: 5 * testStepUpThreshold}
+			rec := httptest.NewRecorder()
+
+			data, err := json.Marshal(gin.H{
+				"from_account_id": from.ID,
+				"to_account_id":   to.ID,
+				"amount":          2 * testStepUpThreshold,
+				"currency":        tc.currency,
+			})
+			require.NoError(t, err)
+
+			req, err := http.NewRequest(http.MethodPost, "/transfers", bytes.NewReader(data))
+			require.NoError(t, err)
+
+			addAuthenticatedHeader(t, req, svr.tokenMaker, user.Username, time.Now().Add(-time.Hour))
+			svr.router.ServeHTTP(rec, req)
+			require.Equal(t, tc.status, rec.Code)
+		})
+	}
+}
+
+func TestTransferStepUpWithAPIKeyAPI(t *testing.T) {
+	user, _ := randomUser(t)
+	other, _ := randomUser(t)
+	from := randomAccount(user.Username)
+	from.Currency = util.USD
+	to := randomAccount(other.Username)
+	to.Currency = util.USD
+
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	store := mockdb.NewMockStore(ctrl)
+	stubAuthUsers(store)
+	apiKey, key := randomAPIKey(t, user.Username, token.ScopeTransfersWrite)
+	store.EXPECT().GetAPIKeyByPrefix(gomock.Any(), gomock.Eq(apiKey.Prefix)).AnyTimes().Return(apiKey, nil)
+	store.EXPECT().TouchAPIKey(gomock.Any(), gomock.Any()).AnyTimes()
+	store.EXPECT().TransferTx(gomock.Any(), gomock.Any()).Times(0)
+
+	svr := newTestServer(t, store)
+	svr.config.StepUpThreshold = testStepUpThreshold
+	svr.config.StepUpMaxAge = 5 * time.Minute
+	rec := httptest.NewRecorder()
+
+	data, err := json.Marshal(gin.H{
+		"from_account_id": from.ID,
+		"to_account_id":   to.ID,
+		"amount":          testStepUpThreshold + 1,
+		"currency":        util.USD,
+	})
+	require.NoError(t, err)
+
+	req, err := http.NewRequest(http.MethodPost, "/transfers", bytes.NewReader(data))
+	require.NoError(t, err)
+	addAPIKeyHeader(req, key)
+	svr.router.ServeHTTP(rec, req)
+
+	// an api key has no sign-in to refresh, so it is not sent to POST /users/step_up
+	require.Equal(t, http.StatusForbidden, rec.Code)
+	require.Empty(t, rec.Header().Get("WWW-Authenticate"))
+	require.NotContains(t, rec.Body.String(), "/users/step_up")
+}
+
+func TestPaymentBatchStepUpAPI(t *testing.T) {
+	user, _ := randomUser(t)
+	other, _ := randomUser(t)
+	from := randomAccount(user.Username)
+	from.Currency = util.USD
+	to := randomAccount(other.Username)
+	to.ID = from.ID + 1
+	to.Currency = util.USD
+
+	// no single line is over the threshold but the file is
+	file := fmt.Sprintf("from_account_id,to_account_id,amount,currency,memo\n%d,%d,600,USD,a\n%d,%d,600,USD,b\n", from.ID, to.ID, from.ID, to.ID)
+
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	store := mockdb.NewMockStore(ctrl)
+	stubAuthUsers(store)
+	store.EXPECT().GetAccount(gomock.Any(), gomock.Eq(from.ID)).AnyTimes().Return(from, nil)
+	store.EXPECT().GetAccount(gomock.Any(), gomock.Eq(to.ID)).AnyTimes().Return(to, nil)
+	store.EXPECT().GetApprovalPolicyForAmount(gomock.Any(), gomock.Any()).AnyTimes().Return(db.ApprovalPolicy{}, sql.ErrNoRows)
+	store.EXPECT().CreatePaymentBatchTx(gomock.Any(), gomock.Any()).Times(0)
+
+	svr := newTestServer(t, store)
+	svr.config.StepUpThreshold = testStepUpThreshold
+	svr.config.StepUpMaxAge = 5 * time.Minute
+	rec := httptest.NewRecorder()
+
+	req := paymentFileRequest(t, file, nil)
+	addAuthenticatedHeader(t, req, svr.tokenMaker, user.Username, time.Now().Add(-time.Hour))
+	svr.router.ServeHTTP(rec, req)
+	require.Equal(t, http.StatusUnauthorized, rec.Code)
+}
+
+func TestStepUpAPI(t *testing.T) {
+	user, password := randomUser(t)
+	enrollment := confirmedEnrollment(t, user.Username)
+	code, err := totp.Code(enrollment.Secret, totp.Step(time.Now()))
+	require.NoError(t, err)
+
+	testCases := []struct {
+		name          string
+		body          gin.H
+		buildStubs    func(store *mockdb.MockStore)
+		checkResponse func(t *testing.T, rec *httptest.ResponseRecorder, tm token.Maker)
+	}{
+		{
+			name:       "Password",
+			body:       gin.H{"password": password},
+			buildStubs: func(store *mockdb.MockStore) {},
+			checkResponse: func(t *testing.T, rec *httptest.ResponseRecorder, tm token.Maker) {
+				require.Equal(t, http.StatusOK, rec.Code)
+
+				var rsp loginUserResponse
+				require.NoError(t, json.Unmarshal(rec.Body.Bytes(), &rsp))
+				payload, err := tm.VerifyToken(rsp.AccessToken)
+				require.NoError(t, err)
+				require.WithinDuration(t, time.Now(), payload.AuthTime, time.Second)
+				require.Equal(t, []string{token.AMRPassword}, payload.AMR)
+			},
+		},
+		{
+			name:       "WrongPassword",
+			body:       gin.H{"password": "wrong-password"},
+			buildStubs: func(store *mockdb.MockStore) {},
+			checkResponse: func(t *testing.T, rec *httptest.ResponseRecorder, tm token.Maker) {
+				require.Equal(t, http.StatusUnauthorized, rec.Code)
+			},
+		},
+		{
+			name: "TOTPCode",
+			body: gin.H{"code": code},
+			buildStubs: func(store *mockdb.MockStore) {
+				store.EXPECT().GetTOTPEnrollment(gomock.Any(), gomock.Eq(user.Username)).Times(1).Return(enrollment, nil)
+				store.EXPECT().UseTOTPStep(gomock.Any(), gomock.Any()).Times(1).Return(int64(1), nil)
+			},
+			checkResponse: func(t *testing.T, rec *httptest.ResponseRecorder, tm token.Maker) {
+				require.Equal(t, http.StatusOK, rec.Code)
+
+				var rsp loginUserResponse
+				require.NoError(t, json.Unmarshal(rec.Body.Bytes(), &rsp))
+				payload, err := tm.VerifyToken(rsp.AccessToken)
+				require.NoError(t, err)
+				require.Equal(t, []string{token.AMROTP}, payload.AMR)
+			},
+		},
+		{
+			name: "ReplayedCode",
+			body: gin.H{"code": code},
+			buildStubs: func(store *mockdb.MockStore) {
+				store.EXPECT().GetTOTPEnrollment(gomock.Any(), gomock.Eq(user.Username)).Times(1).Return(enrollment, nil)
+				store.EXPECT().UseTOTPStep(gomock.Any(), gomock.Any()).Times(1).Return(int64(0), nil)
+			},
+			checkResponse: func(t *testing.T, rec *httptest.ResponseRecorder, tm token.Maker) {
+				require.Equal(t, http.StatusUnauthorized, rec.Code)
+			},
+		},
+		{
+			name:       "NothingGiven",
+			body:       gin.H{},
+			buildStubs: func(store *mockdb.MockStore) {},
+			checkResponse: func(t *testing.T, rec *httptest.ResponseRecorder, tm token.Maker) {
+				require.Equal(t, http.StatusBadRequest, rec.Code)
+			},
+		},
+	}
+
+	for i := range testCases {
+		tc := testCases[i]
+
+		t.Run(tc.name, func(t *testing.T) {
+			ctrl := gomock.NewController(t)
+			defer ctrl.Finish()
+
+			store := mockdb.NewMockStore(ctrl)
+			store.EXPECT().GetUser(gomock.Any(), gomock.Eq(user.Username)).AnyTimes().Return(user, nil)
+			tc.buildStubs(store)
+
+			svr := newTestServer(t, store)
+			rec := httptest.NewRecorder()
+
+			data, err := json.Marshal(tc.body)
+			require.NoError(t, err)
+
+			req, err := http.NewRequest(http.MethodPost, "/users/step_up", bytes.NewReader(data))
+			require.NoError(t, err)
+
+			addAuthorizationHeader(t, req, svr.tokenMaker, authorizationTypeBearer, user.Username, time.Minute)
+			svr.router.ServeHTTP(rec, req)
+			tc.checkResponse(t, rec, svr.tokenMaker)
+		})
+	}
+}
@@ -0,0 +1,225 @@
+package api
+
+import (
+	"encoding/json"
+	"fmt"
+	db "gobank/db/sqlc"
+	"gobank/token"
+	"io"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/gin-gonic/gin"
+)
+
+const (
+	streamReplayPageSize = 500
+	streamHeartbeat      = 15 * time.Second
+	streamCheckpoint     = 5 * time.Second
+)
+
+type streamAccountUpdatesRequest struct {
+	// for clients that cannot set the Last-Event-ID header on their first connection
+	LastEventID int64 `form:"last_event_id" binding:"min=0"`
+}
+
+// streamAccountUpdates pushes every new entry on the accounts the caller can read, with the balance it left,
+// as Server-Sent Events.
+//
+// Entry ids are handed out before commit, so a later id can become visible before an earlier one and is no
+// place to resume from. Updates are therefore sent without an event id; every few seconds the stream instead
+// sends an id-only checkpoint, a transaction id below which every entry has been sent. A reconnecting client
+// sends the last checkpoint it saw and first gets everything written from there on. Delivery is at least
+// once: an update may be sent again after a reconnect, so clients dedupe by the id in its data.
+func (s *Server) streamAccountUpdates(ctx *gin.Context) {
+	var req streamAccountUpdatesRequest
+	if err := ctx.ShouldBindQuery(&req); err != nil {
+		ctx.JSON(http.StatusBadRequest, errorResponse(err))
+		return
+	}
+	cursor := req.LastEventID
+	if h := ctx.GetHeader("Last-Event-ID"); h != "" {
+		id, err := strconv.ParseInt(h, 10, 64)
+		if err != nil || id < 0 {
+			err := fmt.Errorf("invalid Last-Event-ID %q", h)
+			ctx.JSON(http.StatusBadRequest, errorResponse(err))
+			return
+		}
+		cursor = id
+	}
+
+	payload := ctx.MustGet(authorizationPayloadKey).(*token.Payload)
+
+	accountIDs, err := s.store.ListAccessibleAccountIDs(ctx, payload.Username)
+	if err != nil {
+		ctx.JSON(http.StatusInternalServerError, errorResponse(err))
+		return
+	}
+
+	//subscribe before reading the backlog so nothing committed in between is missed
+	updates, stop := s.hub.Subscribe(accountIDs)
+	defer stop()
+
+	stream := &accountStream{accountIDs: accountIDs, cursor: cursor, sent: map[int64]int64{}}
+	to, err := s.store.GetOldestRunningTxid(ctx)
+	if err != nil {
+		ctx.JSON(http.StatusInternalServerError, errorResponse(err))
+		return
+	}
+	var backlog []db.AccountUpdate
+	if cursor == 0 {
+		//a new client only wants what happens from now on
+		stream.cursor = to
+	} else {
+		backlog, err = s.accountUpdatesBetween(ctx, accountIDs, cursor, to)
+		if err != nil {
+			ctx.JSON(http.StatusInternalServerError, errorResponse(err))
+			return
+		}
+	}
+
+	ctx.Header("Content-Type", "text/event-stream")
+	ctx.Header("Cache-Control", "no-cache")
+	ctx.Header("Connection", "keep-alive")
+	ctx.Status(http.StatusOK)
+
+	if err := stream.writeCheckpoint(ctx.Writer, backlog, to); err != nil {
+		return
+	}
+	ctx.Writer.Flush()
+
+	heartbeat := time.NewTicker(streamHeartbeat)
+	defer heartbeat.Stop()
+	checkpoint := time.NewTicker(streamCheckpoint)
+	defer checkpoint.Stop()
+
+	for {
+		select {
+		case <-ctx.Request.Context().Done():
+			return
+		case u, ok := <-updates:
+			//the hub dropped us for falling behind; the client reconnects and resumes from its last checkpoint
+			if !ok {
+				return
+			}
+			if err := stream.writeLive(ctx.Writer, u); err != nil {
+				return
+			}
+		case <-checkpoint.C:
+			to, err := s.store.GetOldestRunningTxid(ctx)
+			if err != nil {
+				return
+			}
+			backlog, err := s.accountUpdatesBetween(ctx, accountIDs, stream.cursor, to)
+			if err != nil {
+				return
+			}
+			if err := stream.writeCheckpoint(ctx.Writer, backlog, to); err != nil {
+				return
+			}
+		case <-heartbeat.C:
+			//holders and delegates come and go; the client reconnects to pick up the new set of accounts
+			ids, err := s.store.ListAccessibleAccountIDs(ctx, payload.Username)
+			if err != nil || !sameAccountIDs(ids, accountIDs) {
+				return
+			}
+			if _, err := io.WriteString(ctx.Writer, ": keep-alive\n\n"); err != nil {
+				return
+			}
+		}
+		ctx.Writer.Flush()
+	}
+}
+
+// accountStream is what a stream has sent so far: every update written by a transaction below cursor, and
+// the ids and txids of those at or above it that arrived live.
+type accountStream struct {
+	accountIDs []int64
+	cursor     int64
+	sent       map[int64]int64
+}
+
+// writeLive writes u unless a checkpoint or an earlier notification already covered it.
+func (st *accountStream) writeLive(w io.Writer, u db.AccountUpdate) error {
+	if u.Txid < st.cursor {
+		return nil
+	}
+	if _, ok := st.sent[u.ID]; ok {
+		return nil
+	}
+	if err := writeAccountUpdate(w, u); err != nil {
+		return err
+	}
+	st.sent[u.ID] = u.Txid
+	return nil
+}
+
+// writeCheckpoint writes the updates of transactions between the cursor and to that were not sent live,
+// then moves the cursor, and the client's last event id, to to.
+func (st *accountStream) writeCheckpoint(w io.Writer, backlog []db.AccountUpdate, to int64) error {
+	for _, u := range backlog {
+		if _, ok := st.sent[u.ID]; ok {
+			continue
+		}
+		if err := writeAccountUpdate(w, u); err != nil {
+			return err
+		}
+	}
+	if _, err := fmt.Fprintf(w, "id: %d\n\n", to); err != nil {
+		return err
+	}
+	st.cursor = to
+	for id, txid := range st.sent {
+		if txid < to {
+			delete(st.sent, id)
+		}
+	}
+	return nil
+}
+
+// accountUpdatesBetween pages through the updates of the given accounts written by transactions fromTxid <= txid < toTxid.
+func (s *Server) accountUpdatesBetween(ctx *gin.Context, accountIDs []int64, fromTxid, toTxid int64) ([]db.AccountUpdate, error) {
+	var updates []db.AccountUpdate
+	var afterID int64
+	for {
+		rows, err := s.store.ListAccountUpdatesSince(ctx, db.ListAccountUpdatesSinceParams{
+			AccountIds: accountIDs,
+			FromTxid:   fromTxid,
+			ToTxid:     toTxid,
+			AfterID:    afterID,
+			MaxUpdates: streamReplayPageSize,
+		})
+		if err != nil {
+			return nil, err
+		}
+		for _, row := range rows {
+			updates = append(updates, db.NewAccountUpdate(row))
+			afterID = row.ID
+		}
+		if len(rows) < streamReplayPageSize {
+			return updates, nil
+		}
+	}
+}
+
+func sameAccountIDs(a, b []int64) bool {
+	if len(a) != len(b) {
+		return false
+	}
+	for i := range a {
+		if a[i] != b[i] {
+			return false
+		}
+	}
+	return true
+}
+
+func writeAccountUpdate(w io.Writer, u db.AccountUpdate) error {
+	data, err := json.Marshal(u)
+	if err != nil {
+		return err
+	}
+	_, err = fmt.Fprintf(w, "event: account_update\ndata: %s\n\n", data)
+	return err
+}
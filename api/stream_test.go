@@ -0,0 +1,233 @@
+package api
+
+import (
+	"bufio"
+	"database/sql"
+	"encoding/json"
+	mockdb "gobank/db/mock"
+	db "gobank/db/sqlc"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/golang/mock/gomock"
+	"github.com/stretchr/testify/require"
+)
+
+// readEvent reads one Server-Sent Event, skipping comments, and returns its fields.
+func readEvent(t *testing.T, r *bufio.Reader) map[string]string {
+	fields := map[string]string{}
+	for {
+		line, err := r.ReadString('\n')
+		require.NoError(t, err)
+		line = strings.TrimSuffix(line, "\n")
+		if line == "" {
+			if len(fields) > 0 {
+				return fields
+			}
+			continue
+		}
+		if strings.HasPrefix(line, ":") {
+			continue
+		}
+		kv := strings.SplitN(line, ": ", 2)
+		require.Len(t, kv, 2)
+		fields[kv[0]] = kv[1]
+	}
+}
+
+func TestStreamAccountUpdatesAPI(t *testing.T) {
+	user, _ := randomUser(t)
+	acc := randomAccount(user.Username)
+	// the user is a joint holder or delegate of this one
+	shared := randomAccount("someone_else")
+	accountIDs := []int64{acc.ID, shared.ID}
+
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	store := mockdb.NewMockStore(ctrl)
+	stubAuthUsers(store)
+	store.EXPECT().ListAccessibleAccountIDs(gomock.Any(), gomock.Eq(user.Username)).AnyTimes().Return(accountIDs, nil)
+	store.EXPECT().GetOldestRunningTxid(gomock.Any()).Times(1).Return(int64(20), nil)
+	store.EXPECT().
+		ListAccountUpdatesSince(gomock.Any(), gomock.Eq(db.ListAccountUpdatesSinceParams{
+			AccountIds: accountIDs,
+			FromTxid:   10,
+			ToTxid:     20,
+			MaxUpdates: streamReplayPageSize,
+		})).
+		Times(1).
+		Return([]db.ListAccountUpdatesSinceRow{
+			{ID: 11, AccountID: acc.ID, Owner: user.Username, Amount: -5, BalanceAfter: 95, TransferID: sql.NullInt64{Int64: 3, Valid: true}, Txid: 15},
+		}, nil)
+
+	svr := newTestServer(t, store)
+	ts := httptest.NewServer(svr.router)
+	defer ts.Close()
+
+	req, err := http.NewRequest(http.MethodGet, ts.URL+"/accounts/stream", nil)
+	require.NoError(t, err)
+	req.Header.Set("Last-Event-ID", "10")
+	addAuthorizationHeader(t, req, svr.tokenMaker, authorizationTypeBearer, user.Username, time.Minute)
+
+	res, err := ts.Client().Do(req)
+	require.NoError(t, err)
+	defer res.Body.Close()
+	require.Equal(t, http.StatusOK, res.StatusCode)
+	require.Equal(t, "text/event-stream", res.Header.Get("Content-Type"))
+
+	r := bufio.NewReader(res.Body)
+
+	// the missed entry is replayed first, then the checkpoint to resume from
+	ev := readEvent(t, r)
+	require.Empty(t, ev["id"])
+	require.Equal(t, "account_update", ev["event"])
+
+	var u db.AccountUpdate
+	require.NoError(t, json.Unmarshal([]byte(ev["data"]), &u))
+	require.Equal(t, int64(11), u.ID)
+	require.Equal(t, acc.ID, u.AccountID)
+	require.Equal(t, int64(95), u.Balance)
+	require.Equal(t, int64(3), u.TransferID)
+
+	ev = readEvent(t, r)
+	require.Equal(t, map[string]string{"id": "20"}, ev)
+
+	// live updates follow, skipping anything the checkpoint covered, sent twice or on other accounts
+	svr.hub.Broadcast(db.AccountUpdate{ID: 11, AccountID: acc.ID, Txid: 15})
+	svr.hub.Broadcast(db.AccountUpdate{ID: 12, AccountID: shared.ID + acc.ID, Txid: 21})
+	svr.hub.Broadcast(db.AccountUpdate{ID: 13, AccountID: shared.ID, Owner: "someone_else", Balance: 80, Txid: 21})
+	svr.hub.Broadcast(db.AccountUpdate{ID: 13, AccountID: shared.ID, Owner: "someone_else", Balance: 80, Txid: 21})
+	svr.hub.Broadcast(db.AccountUpdate{ID: 14, AccountID: acc.ID, Txid: 22})
+
+	ev = readEvent(t, r)
+	require.Empty(t, ev["id"])
+	require.NoError(t, json.Unmarshal([]byte(ev["data"]), &u))
+	require.Equal(t, int64(13), u.ID)
+	require.Equal(t, shared.ID, u.AccountID)
+
+	ev = readEvent(t, r)
+	require.NoError(t, json.Unmarshal([]byte(ev["data"]), &u))
+	require.Equal(t, int64(14), u.ID)
+}
+
+func TestStreamAccountUpdatesFromNowAPI(t *testing.T) {
+	user, _ := randomUser(t)
+	acc := randomAccount(user.Username)
+
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	store := mockdb.NewMockStore(ctrl)
+	stubAuthUsers(store)
+	store.EXPECT().ListAccessibleAccountIDs(gomock.Any(), gomock.Eq(user.Username)).AnyTimes().Return([]int64{acc.ID}, nil)
+	store.EXPECT().GetOldestRunningTxid(gomock.Any()).Times(1).Return(int64(20), nil)
+	store.EXPECT().ListAccountUpdatesSince(gomock.Any(), gomock.Any()).Times(0)
+
+	svr := newTestServer(t, store)
+	ts := httptest.NewServer(svr.router)
+	defer ts.Close()
+
+	req, err := http.NewRequest(http.MethodGet, ts.URL+"/accounts/stream", nil)
+	require.NoError(t, err)
+	addAuthorizationHeader(t, req, svr.tokenMaker, authorizationTypeBearer, user.Username, time.Minute)
+
+	res, err := ts.Client().Do(req)
+	require.NoError(t, err)
+	defer res.Body.Close()
+	require.Equal(t, http.StatusOK, res.StatusCode)
+
+	r := bufio.NewReader(res.Body)
+
+	// nothing is replayed, the client just learns where to resume from
+	ev := readEvent(t, r)
+	require.Equal(t, map[string]string{"id": "20"}, ev)
+
+	svr.hub.Broadcast(db.AccountUpdate{ID: 5, AccountID: acc.ID, Txid: 19})
+	svr.hub.Broadcast(db.AccountUpdate{ID: 6, AccountID: acc.ID, Txid: 20})
+
+	ev = readEvent(t, r)
+	var u db.AccountUpdate
+	require.NoError(t, json.Unmarshal([]byte(ev["data"]), &u))
+	require.Equal(t, int64(6), u.ID)
+}
+
+func TestStreamAccountUpdatesErrorsAPI(t *testing.T) {
+	user, _ := randomUser(t)
+
+	testCases := []struct {
+		name        string
+		lastEventID string
+		auth        bool
+		buildStubs  func(store *mockdb.MockStore)
+		status      int
+	}{
+		{
+			name:        "InvalidLastEventID",
+			lastEventID: "abc",
+			auth:        true,
+			buildStubs: func(store *mockdb.MockStore) {
+				store.EXPECT().ListAccessibleAccountIDs(gomock.Any(), gomock.Any()).Times(0)
+				store.EXPECT().ListAccountUpdatesSince(gomock.Any(), gomock.Any()).Times(0)
+			},
+			status: http.StatusBadRequest,
+		},
+		{
+			name:        "InternalError",
+			lastEventID: "10",
+			auth:        true,
+			buildStubs: func(store *mockdb.MockStore) {
+				store.EXPECT().ListAccessibleAccountIDs(gomock.Any(), gomock.Any()).Times(1).Return([]int64{1}, nil)
+				store.EXPECT().GetOldestRunningTxid(gomock.Any()).Times(1).Return(int64(20), nil)
+				store.EXPECT().ListAccountUpdatesSince(gomock.Any(), gomock.Any()).Times(1).Return(nil, sql.ErrConnDone)
+			},
+			status: http.StatusInternalServerError,
+		},
+		{
+			name:        "AccessibleAccountsError",
+			lastEventID: "10",
+			auth:        true,
+			buildStubs: func(store *mockdb.MockStore) {
+				store.EXPECT().ListAccessibleAccountIDs(gomock.Any(), gomock.Any()).Times(1).Return(nil, sql.ErrConnDone)
+				store.EXPECT().ListAccountUpdatesSince(gomock.Any(), gomock.Any()).Times(0)
+			},
+			status: http.StatusInternalServerError,
+		},
+		{
+			name: "NoAuthorization",
+			buildStubs: func(store *mockdb.MockStore) {
+				store.EXPECT().ListAccountUpdatesSince(gomock.Any(), gomock.Any()).Times(0)
+			},
+			status: http.StatusUnauthorized,
+		},
+	}
+
+	for i := range testCases {
+		tc := testCases[i]
+
+		t.Run(tc.name, func(t *testing.T) {
+			ctrl := gomock.NewController(t)
+			defer ctrl.Finish()
+
+			store := mockdb.NewMockStore(ctrl)
+			stubAuthUsers(store)
+			tc.buildStubs(store)
+
+			svr := newTestServer(t, store)
+			rec := httptest.NewRecorder()
+
+			req, err := http.NewRequest(http.MethodGet, "/accounts/stream", nil)
+			require.NoError(t, err)
+			req.Header.Set("Last-Event-ID", tc.lastEventID)
+			if tc.auth {
+				addAuthorizationHeader(t, req, svr.tokenMaker, authorizationTypeBearer, user.Username, time.Minute)
+			}
+
+			svr.router.ServeHTTP(rec, req)
+			require.Equal(t, tc.status, rec.Code)
+		})
+	}
+}
@@ -2,34 +2,65 @@ package api
 
 import (
 	"database/sql"
-	"errors"
 	"fmt"
 	db "gobank/db/sqlc"
-	"gobank/token"
 	"net/http"
 
 	"github.com/gin-gonic/gin"
 )
 
 type transferRequest struct {
-	FromAccountID int64  `json:"from_account_id" binding:"required,min=1"`
-	ToAccountID   int64  `json:"to_account_id" binding:"required,min=1"`
-	Amount        int64  `json:"amount" binding:"required,gt=0"`
-	Currency      string `json:"currency" binding:"required,currency"`
+	FromAccountID     int64  `json:"from_account_id" binding:"required_without=FromAccountNumber,excluded_with=FromAccountNumber,omitempty,min=1"`
+	FromAccountNumber string `json:"from_account_number" binding:"omitempty,accountnumber"`
+	ToAccountID       int64  `json:"to_account_id" binding:"required_without_all=PayeeID ToAccountNumber,excluded_with=PayeeID ToAccountNumber,omitempty,min=1"`
+	ToAccountNumber   string `json:"to_account_number" binding:"excluded_with=PayeeID,omitempty,accountnumber"`
+	PayeeID           int64  `json:"payee_id" binding:"omitempty,min=1"`
+	Amount            int64  `json:"amount" binding:"required,gt=0"`
+	Currency          string `json:"currency" binding:"required,currency"`
+	Memo              string `json:"memo" binding:"max=140"`
 }
 
-func (s *Server) validAccount(ctx *gin.Context, accountID int64, currency string) (db.Account, bool) {
+// resolveAccountNumbers fills in the account IDs of a transfer given by account numbers, whose check digits
+// were validated on binding. It writes the error response and returns false if a number is unknown, or is
+// a from account the user cannot pay from.
+func (s *Server) resolveAccountNumbers(ctx *gin.Context, req *transferRequest) bool {
+	if req.FromAccountNumber != "" {
+		acc, ok := s.accessibleAccountByNumber(ctx, req.FromAccountNumber, accessTransfer)
+		if !ok {
+			return false
+		}
+		req.FromAccountID = acc.ID
+	}
+	if req.ToAccountNumber != "" {
+		acc, ok := s.accountByNumber(ctx, req.ToAccountNumber)
+		if !ok {
+			return false
+		}
+		req.ToAccountID = acc.ID
+	}
+	return true
+}
+
+// checkAccount loads an account and makes sure it holds currency, returning the status code to report if not.
+func (s *Server) checkAccount(ctx *gin.Context, accountID int64, currency string) (db.Account, int, error) {
 	acc, err := s.store.GetAccount(ctx, accountID)
 	if err != nil {
 		if err == sql.ErrNoRows {
-			ctx.JSON(http.StatusNotFound, errorResponse(err))
-			return acc, false
+			return acc, http.StatusNotFound, err
 		}
-		ctx.JSON(http.StatusInternalServerError, errorResponse(err))
+		return acc, http.StatusInternalServerError, err
 	}
 	if acc.Currency != currency {
 		err := fmt.Errorf("account [%d] currency mismatch: %s vs %s", acc.ID, acc.Currency, currency)
-		ctx.JSON(http.StatusBadRequest, errorResponse(err))
+		return acc, http.StatusBadRequest, err
+	}
+	return acc, http.StatusOK, nil
+}
+
+func (s *Server) validAccount(ctx *gin.Context, accountID int64, currency string) (db.Account, bool) {
+	acc, status, err := s.checkAccount(ctx, accountID, currency)
+	if err != nil {
+		ctx.JSON(status, errorResponse(err))
 		return acc, false
 	}
 	return acc, true
@@ -41,28 +72,47 @@ func (s *Server) createTransfer(ctx *gin.Context) {
 		ctx.JSON(http.StatusBadRequest, errorResponse(err))
 		return
 	}
+	if !s.resolveAccountNumbers(ctx, &req) {
+		return
+	}
+	if !checkConsent(ctx, req.FromAccountID, permissionInitiatePayments) {
+		return
+	}
+	if !s.resolvePayee(ctx, &req) {
+		return
+	}
+	if !s.checkStepUp(ctx, req.Currency, req.Amount) {
+		return
+	}
 	args := db.TransferTxParams{
 		FromAccountID: req.FromAccountID,
 		ToAccountID:   req.ToAccountID,
 		Amount:        req.Amount,
+		Memo:          req.Memo,
 	}
 	account, isValid := s.validAccount(ctx, args.FromAccountID, req.Currency)
 	if !isValid {
 		return
 	}
-
-	payload := ctx.MustGet(authorizationPayloadKey).(*token.Payload)
-
-	if payload.Username != account.Owner {
-		err := errors.New("from account doesn't belong to authenticated user. can only make transfer from account you own")
-		ctx.JSON(http.StatusUnauthorized, errorResponse(err))
+	if !s.checkAccountAccess(ctx, account, accessTransfer) {
 		return
 	}
 
-	_, isValid = s.validAccount(ctx, args.ToAccountID, req.Currency)
+	to, isValid := s.validAccount(ctx, args.ToAccountID, req.Currency)
 	if !isValid {
 		return
 	}
+	if !s.checkPayeeCoolingOff(ctx, to, args.Amount) {
+		return
+	}
+	required, ok := s.requiredApprovals(ctx, account, args.Amount)
+	if !ok {
+		return
+	}
+	if required > 1 {
+		s.draftTransfer(ctx, args, required)
+		return
+	}
 	result, err := s.store.TransferTx(ctx, args)
 	if err != nil {
 		ctx.JSON(http.StatusInternalServerError, errorResponse(err))
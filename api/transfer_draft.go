@@ -0,0 +1,310 @@
+package api
+
+import (
+	"database/sql"
+	"errors"
+	db "gobank/db/sqlc"
+	"gobank/token"
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+)
+
+// A transfer draft is a transfer waiting for approval. One user drafts it, the maker, and others who may
+// make transfers from the account, the checkers, approve or reject it. The approval that completes it
+// makes the transfer. Drafts are created by createTransfer when the joint approval rule or an approval
+// policy of the account applies, or on purpose by createTransferDraft.
+
+// requiredApprovals returns how many approvals, counting the maker's, a transfer of amount from acc needs:
+// every holder once the amount reaches the joint approval threshold, one more than the approval policy for
+// the amount asks for, or just the maker's. It writes the error response on failure.
+func (s *Server) requiredApprovals(ctx *gin.Context, acc db.Account, amount int64) (int32, bool) {
+	required, err := s.countRequiredApprovals(ctx, acc, amount)
+	if err != nil {
+		ctx.JSON(http.StatusInternalServerError, errorResponse(err))
+		return 0, false
+	}
+	return required, true
+}
+
+func (s *Server) countRequiredApprovals(ctx *gin.Context, acc db.Account, amount int64) (int32, error) {
+	required := int32(1)
+
+	if acc.JointApprovalThreshold > 0 && amount >= acc.JointApprovalThreshold {
+		holders, err := s.store.ListAccountHolders(ctx, acc.ID)
+		if err != nil {
+			return 0, err
+		}
+		if int32(len(holders)) > required {
+			required = int32(len(holders))
+		}
+	}
+
+	policy, err := s.store.GetApprovalPolicyForAmount(ctx, db.GetApprovalPolicyForAmountParams{
+		AccountID: acc.ID,
+		Amount:    amount,
+	})
+	if err != nil && err != sql.ErrNoRows {
+		return 0, err
+	}
+	if err == nil && policy.RequiredApprovals+1 > required {
+		required = policy.RequiredApprovals + 1
+	}
+	return required, nil
+}
+
+// draftTransfer records a transfer that needs more approvals than its maker's, answering 202 with the draft.
+func (s *Server) draftTransfer(ctx *gin.Context, args db.TransferTxParams, requiredApprovals int32) {
+	payload := ctx.MustGet(authorizationPayloadKey).(*token.Payload)
+	draft, err := s.store.CreateTransferDraftTx(ctx, db.CreateTransferDraftParams{
+		FromAccountID:     args.FromAccountID,
+		ToAccountID:       args.ToAccountID,
+		Amount:            args.Amount,
+		Memo:              args.Memo,
+		CreatedBy:         payload.Username,
+		RequiredApprovals: requiredApprovals,
+	})
+	if err != nil {
+		ctx.JSON(http.StatusInternalServerError, errorResponse(err))
+		return
+	}
+	ctx.JSON(http.StatusAccepted, draft)
+}
+
+// createTransferDraft drafts a transfer for someone else to approve even when no rule asks for it. The
+// transfer needs at least one approval besides the maker's.
+func (s *Server) createTransferDraft(ctx *gin.Context) {
+	var req transferRequest
+	if err := ctx.ShouldBindJSON(&req); err != nil {
+		ctx.JSON(http.StatusBadRequest, errorResponse(err))
+		return
+	}
+	if !s.resolveAccountNumbers(ctx, &req) {
+		return
+	}
+	if !s.resolvePayee(ctx, &req) {
+		return
+	}
+	args := db.TransferTxParams{
+		FromAccountID: req.FromAccountID,
+		ToAccountID:   req.ToAccountID,
+		Amount:        req.Amount,
+		Memo:          req.Memo,
+	}
+	account, isValid := s.validAccount(ctx, args.FromAccountID, req.Currency)
+	if !isValid {
+		return
+	}
+	if !s.checkAccountAccess(ctx, account, accessTransfer) {
+		return
+	}
+	to, isValid := s.validAccount(ctx, args.ToAccountID, req.Currency)
+	if !isValid {
+		return
+	}
+	if !s.checkPayeeCoolingOff(ctx, to, args.Amount) {
+		return
+	}
+
+	required, ok := s.requiredApprovals(ctx, account, args.Amount)
+	if !ok {
+		return
+	}
+	if required < 2 {
+		required = 2
+	}
+	s.draftTransfer(ctx, args, required)
+}
+
+type listTransferDraftsRequest struct {
+	PageID   int32 `form:"page_id" binding:"required,min=1"`
+	PageSize int32 `form:"page_size" binding:"required,min=5,max=10"`
+}
+
+// listTransferDrafts pages through the drafts of transfers from an account, newest first.
+func (s *Server) listTransferDrafts(ctx *gin.Context) {
+	var uri getAccountRequest
+	if err := ctx.ShouldBindUri(&uri); err != nil {
+		ctx.JSON(http.StatusBadRequest, errorResponse(err))
+		return
+	}
+	var req listTransferDraftsRequest
+	if err := ctx.ShouldBindQuery(&req); err != nil {
+		ctx.JSON(http.StatusBadRequest, errorResponse(err))
+		return
+	}
+	acc, ok := s.accountAccess(ctx, uri.ID, accessRead)
+	if !ok {
+		return
+	}
+
+	drafts, err := s.store.ListTransferDrafts(ctx, db.ListTransferDraftsParams{
+		FromAccountID: acc.ID,
+		Limit:         req.PageSize,
+		Offset:        (req.PageID - 1) * req.PageSize,
+	})
+	if err != nil {
+		ctx.JSON(http.StatusInternalServerError, errorResponse(err))
+		return
+	}
+	ctx.JSON(http.StatusOK, drafts)
+}
+
+type transferDraftRequest struct {
+	ID int64 `uri:"id" binding:"required,min=1"`
+}
+
+type transferDraftResponse struct {
+	Draft     db.TransferDraft           `json:"draft"`
+	Approvals []db.TransferDraftApproval `json:"approvals"`
+}
+
+// getTransferDraft shows who approved a draft so far to anyone who can see the account it is from.
+func (s *Server) getTransferDraft(ctx *gin.Context) {
+	var req transferDraftRequest
+	if err := ctx.ShouldBindUri(&req); err != nil {
+		ctx.JSON(http.StatusBadRequest, errorResponse(err))
+		return
+	}
+	draft, ok := s.loadTransferDraft(ctx, req.ID)
+	if !ok {
+		return
+	}
+	if _, ok := s.accountAccess(ctx, draft.FromAccountID, accessRead); !ok {
+		return
+	}
+
+	approvals, err := s.store.ListTransferDraftApprovals(ctx, draft.ID)
+	if err != nil {
+		ctx.JSON(http.StatusInternalServerError, errorResponse(err))
+		return
+	}
+	ctx.JSON(http.StatusOK, transferDraftResponse{Draft: draft, Approvals: approvals})
+}
+
+// approveTransferDraft adds the approval of a checker. The last approval needed makes the transfer.
+func (s *Server) approveTransferDraft(ctx *gin.Context) {
+	var req transferDraftRequest
+	if err := ctx.ShouldBindUri(&req); err != nil {
+		ctx.JSON(http.StatusBadRequest, errorResponse(err))
+		return
+	}
+	draft, from, ok := s.checkerDraft(ctx, req.ID)
+	if !ok {
+		return
+	}
+	payload := ctx.MustGet(authorizationPayloadKey).(*token.Payload)
+	if payload.Username == draft.CreatedBy {
+		err := errors.New("a transfer cannot be approved by the user who drafted it")
+		ctx.JSON(http.StatusForbidden, errorResponse(err))
+		return
+	}
+	if !s.checkJointApprover(ctx, draft, from) {
+		return
+	}
+	if !s.checkStepUp(ctx, from.Currency, draft.Amount) {
+		return
+	}
+
+	result, err := s.store.ApproveTransferDraftTx(ctx, db.ApproveTransferDraftTxParams{
+		DraftID:  draft.ID,
+		Username: payload.Username,
+	})
+	if err != nil {
+		if errors.Is(err, db.ErrDraftDecided) {
+			ctx.JSON(http.StatusConflict, errorResponse(err))
+			return
+		}
+		ctx.JSON(http.StatusInternalServerError, errorResponse(err))
+		return
+	}
+	ctx.JSON(http.StatusOK, result)
+}
+
+// rejectTransferDraft lets a checker turn a draft down, or its maker withdraw it, before it is executed.
+func (s *Server) rejectTransferDraft(ctx *gin.Context) {
+	var req transferDraftRequest
+	if err := ctx.ShouldBindUri(&req); err != nil {
+		ctx.JSON(http.StatusBadRequest, errorResponse(err))
+		return
+	}
+	draft, _, ok := s.checkerDraft(ctx, req.ID)
+	if !ok {
+		return
+	}
+
+	payload := ctx.MustGet(authorizationPayloadKey).(*token.Payload)
+	draft, err := s.store.RejectTransferDraft(ctx, db.RejectTransferDraftParams{
+		ID:        draft.ID,
+		DecidedBy: sql.NullString{String: payload.Username, Valid: true},
+	})
+	if err != nil {
+		if err == sql.ErrNoRows {
+			ctx.JSON(http.StatusConflict, errorResponse(db.ErrDraftDecided))
+			return
+		}
+		ctx.JSON(http.StatusInternalServerError, errorResponse(err))
+		return
+	}
+	ctx.JSON(http.StatusOK, draft)
+}
+
+// checkerDraft loads a draft and the account it is from, and checks the authenticated user may make
+// transfers from it, writing the error response if not.
+func (s *Server) checkerDraft(ctx *gin.Context, id int64) (db.TransferDraft, db.Account, bool) {
+	draft, ok := s.loadTransferDraft(ctx, id)
+	if !ok {
+		return draft, db.Account{}, false
+	}
+	acc, err := s.store.GetAccount(ctx, draft.FromAccountID)
+	if err != nil {
+		ctx.JSON(http.StatusInternalServerError, errorResponse(err))
+		return draft, acc, false
+	}
+	if !s.checkAccountAccess(ctx, acc, accessTransfer) {
+		return draft, acc, false
+	}
+	return draft, acc, true
+}
+
+// checkJointApprover refuses the approval of a user who is not a holder when the joint approval rule applies
+// to the draft and the holders alone make up the approvals it needs, as such an approval never counts. It
+// writes the error response and returns false then.
+func (s *Server) checkJointApprover(ctx *gin.Context, draft db.TransferDraft, acc db.Account) bool {
+	if acc.JointApprovalThreshold == 0 || draft.Amount < acc.JointApprovalThreshold {
+		return true
+	}
+	payload := ctx.MustGet(authorizationPayloadKey).(*token.Payload)
+	if payload.Username == acc.Owner {
+		return true
+	}
+	holders, err := s.store.ListAccountHolders(ctx, acc.ID)
+	if err != nil {
+		ctx.JSON(http.StatusInternalServerError, errorResponse(err))
+		return false
+	}
+	for _, h := range holders {
+		if h.Username == payload.Username {
+			return true
+		}
+	}
+	if int32(len(holders)) < draft.RequiredApprovals {
+		return true
+	}
+	err = errors.New("only the holders of the account can approve this transfer")
+	ctx.JSON(http.StatusForbidden, errorResponse(err))
+	return false
+}
+
+func (s *Server) loadTransferDraft(ctx *gin.Context, id int64) (db.TransferDraft, bool) {
+	draft, err := s.store.GetTransferDraft(ctx, id)
+	if err != nil {
+		if err == sql.ErrNoRows {
+			ctx.JSON(http.StatusNotFound, errorResponse(err))
+			return draft, false
+		}
+		ctx.JSON(http.StatusInternalServerError, errorResponse(err))
+		return draft, false
+	}
+	return draft, true
+}
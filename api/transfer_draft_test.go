@@ -0,0 +1,361 @@
+package api
+
+import (
+	"bytes"
+	"database/sql"
+	"encoding/json"
+	"fmt"
+	mockdb "gobank/db/mock"
+	db "gobank/db/sqlc"
+	"gobank/util"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/golang/mock/gomock"
+	"github.com/stretchr/testify/require"
+)
+
+func TestPolicyTransferAPI(t *testing.T) {
+	owner, _ := randomUser(t)
+	payee, _ := randomUser(t)
+	from := randomAccount(owner.Username)
+	from.ID = 1
+	from.Currency = util.USD
+	to := randomAccount(payee.Username)
+	to.ID = 2
+	to.Currency = util.USD
+
+	testCases := []struct {
+		name          string
+		path          string
+		buildStubs    func(store *mockdb.MockStore)
+		checkResponse func(t *testing.T, rec *httptest.ResponseRecorder)
+	}{
+		{
+			name: "PolicyApplies",
+			path: "/transfers",
+			buildStubs: func(store *mockdb.MockStore) {
+				store.EXPECT().GetAccount(gomock.Any(), gomock.Eq(from.ID)).Times(1).Return(from, nil)
+				store.EXPECT().GetAccount(gomock.Any(), gomock.Eq(to.ID)).Times(1).Return(to, nil)
+				store.EXPECT().
+					GetApprovalPolicyForAmount(gomock.Any(), gomock.Eq(db.GetApprovalPolicyForAmountParams{AccountID: from.ID, Amount: 500})).
+					Times(1).
+					Return(db.ApprovalPolicy{AccountID: from.ID, MinAmount: 100, RequiredApprovals: 2}, nil)
+				store.EXPECT().
+					CreateTransferDraftTx(gomock.Any(), gomock.Eq(db.CreateTransferDraftParams{
+						FromAccountID:     from.ID,
+						ToAccountID:       to.ID,
+						Amount:            500,
+						CreatedBy:         owner.Username,
+						RequiredApprovals: 3,
+					})).
+					Times(1).
+					Return(db.TransferDraft{ID: 4, Status: db.DraftPending, RequiredApprovals: 3}, nil)
+				store.EXPECT().TransferTx(gomock.Any(), gomock.Any()).Times(0)
+			},
+			checkResponse: func(t *testing.T, rec *httptest.ResponseRecorder) {
+				require.Equal(t, http.StatusAccepted, rec.Code)
+			},
+		},
+		{
+			name: "ExplicitDraft",
+			path: "/transfers/drafts",
+			buildStubs: func(store *mockdb.MockStore) {
+				store.EXPECT().GetAccount(gomock.Any(), gomock.Eq(from.ID)).Times(1).Return(from, nil)
+				store.EXPECT().GetAccount(gomock.Any(), gomock.Eq(to.ID)).Times(1).Return(to, nil)
+				store.EXPECT().GetApprovalPolicyForAmount(gomock.Any(), gomock.Any()).Times(1).Return(db.ApprovalPolicy{}, sql.ErrNoRows)
+				store.EXPECT().
+					CreateTransferDraftTx(gomock.Any(), gomock.Eq(db.CreateTransferDraftParams{
+						FromAccountID:     from.ID,
+						ToAccountID:       to.ID,
+						Amount:            500,
+						CreatedBy:         owner.Username,
+						RequiredApprovals: 2,
+					})).
+					Times(1).
+					Return(db.TransferDraft{ID: 4, Status: db.DraftPending, RequiredApprovals: 2}, nil)
+			},
+			checkResponse: func(t *testing.T, rec *httptest.ResponseRecorder) {
+				require.Equal(t, http.StatusAccepted, rec.Code)
+			},
+		},
+	}
+
+	for i := range testCases {
+		tc := testCases[i]
+
+		t.Run(tc.name, func(t *testing.T) {
+			ctrl := gomock.NewController(t)
+			defer ctrl.Finish()
+
+			store := mockdb.NewMockStore(ctrl)
+			stubAuthUsers(store)
+			tc.buildStubs(store)
+
+			svr := newTestServer(t, store)
+			rec := httptest.NewRecorder()
+
+			data, err := json.Marshal(gin.H{
+				"from_account_id": from.ID,
+				"to_account_id":   to.ID,
+				"amount":          500,
+				"currency":        util.USD,
+			})
+			require.NoError(t, err)
+			req, err := http.NewRequest(http.MethodPost, tc.path, bytes.NewReader(data))
+			require.NoError(t, err)
+
+			addAuthorizationHeader(t, req, svr.tokenMaker, authorizationTypeBearer, owner.Username, time.Minute)
+			svr.router.ServeHTTP(rec, req)
+			tc.checkResponse(t, rec)
+		})
+	}
+}
+
+func TestApproveTransferDraftAPI(t *testing.T) {
+	owner, _ := randomUser(t)
+	clerk, _ := randomUser(t)
+	acc := randomAccount(owner.Username)
+	acc.ID = 1
+	draft := db.TransferDraft{
+		ID:                3,
+		FromAccountID:     acc.ID,
+		ToAccountID:       2,
+		Amount:            100,
+		CreatedBy:         clerk.Username,
+		RequiredApprovals: 2,
+		Status:            db.DraftPending,
+	}
+
+	testCases := []struct {
+		name          string
+		username      string
+		buildStubs    func(store *mockdb.MockStore)
+		checkResponse func(t *testing.T, rec *httptest.ResponseRecorder)
+	}{
+		{
+			name:     "OK",
+			username: owner.Username,
+			buildStubs: func(store *mockdb.MockStore) {
+				executed := draft
+				executed.Status = db.DraftExecuted
+				executed.TransferID = sql.NullInt64{Int64: 9, Valid: true}
+
+				store.EXPECT().GetTransferDraft(gomock.Any(), gomock.Eq(draft.ID)).Times(1).Return(draft, nil)
+				store.EXPECT().GetAccount(gomock.Any(), gomock.Eq(acc.ID)).Times(1).Return(acc, nil)
+				store.EXPECT().
+					ApproveTransferDraftTx(gomock.Any(), gomock.Eq(db.ApproveTransferDraftTxParams{DraftID: draft.ID, Username: owner.Username})).
+					Times(1).
+					Return(db.ApproveTransferDraftTxResult{
+						Draft:    executed,
+						Transfer: &db.TransferTxResult{Transfer: db.Transfer{ID: 9}},
+					}, nil)
+			},
+			checkResponse: func(t *testing.T, rec *httptest.ResponseRecorder) {
+				require.Equal(t, http.StatusOK, rec.Code)
+
+				var res db.ApproveTransferDraftTxResult
+				require.NoError(t, json.Unmarshal(rec.Body.Bytes(), &res))
+				require.Equal(t, db.DraftExecuted, res.Draft.Status)
+				require.NotNil(t, res.Transfer)
+				require.Equal(t, int64(9), res.Transfer.Transfer.ID)
+			},
+		},
+		{
+			name:     "MakerApproves",
+			username: clerk.Username,
+			buildStubs: func(store *mockdb.MockStore) {
+				store.EXPECT().GetTransferDraft(gomock.Any(), gomock.Eq(draft.ID)).Times(1).Return(draft, nil)
+				store.EXPECT().GetAccount(gomock.Any(), gomock.Eq(acc.ID)).Times(1).Return(acc, nil)
+				store.EXPECT().GetAccountHolder(gomock.Any(), gomock.Any()).Times(1).Return(db.AccountHolder{}, sql.ErrNoRows)
+				store.EXPECT().GetAccountPermission(gomock.Any(), gomock.Any()).Times(1).
+					Return(db.AccountPermission{AccountID: acc.ID, Username: clerk.Username, Access: accessTransfer}, nil)
+				store.EXPECT().ApproveTransferDraftTx(gomock.Any(), gomock.Any()).Times(0)
+			},
+			checkResponse: func(t *testing.T, rec *httptest.ResponseRecorder) {
+				require.Equal(t, http.StatusForbidden, rec.Code)
+			},
+		},
+		{
+			name:     "ReadOnlyUser",
+			username: "accountant",
+			buildStubs: func(store *mockdb.MockStore) {
+				store.EXPECT().GetTransferDraft(gomock.Any(), gomock.Eq(draft.ID)).Times(1).Return(draft, nil)
+				store.EXPECT().GetAccount(gomock.Any(), gomock.Eq(acc.ID)).Times(1).Return(acc, nil)
+				store.EXPECT().GetAccountHolder(gomock.Any(), gomock.Any()).Times(1).Return(db.AccountHolder{}, sql.ErrNoRows)
+				store.EXPECT().GetAccountPermission(gomock.Any(), gomock.Any()).Times(1).
+					Return(db.AccountPermission{AccountID: acc.ID, Username: "accountant", Access: accessRead}, nil)
+				store.EXPECT().ApproveTransferDraftTx(gomock.Any(), gomock.Any()).Times(0)
+			},
+			checkResponse: func(t *testing.T, rec *httptest.ResponseRecorder) {
+				require.Equal(t, http.StatusUnauthorized, rec.Code)
+			},
+		},
+		{
+			name:     "DelegateApprovesJointDraft",
+			username: "delegate",
+			buildStubs: func(store *mockdb.MockStore) {
+				joint := acc
+				joint.JointApprovalThreshold = 50
+				store.EXPECT().GetTransferDraft(gomock.Any(), gomock.Eq(draft.ID)).Times(1).Return(draft, nil)
+				store.EXPECT().GetAccount(gomock.Any(), gomock.Eq(acc.ID)).Times(1).Return(joint, nil)
+				store.EXPECT().GetAccountHolder(gomock.Any(), gomock.Any()).Times(1).Return(db.AccountHolder{}, sql.ErrNoRows)
+				store.EXPECT().GetAccountPermission(gomock.Any(), gomock.Any()).Times(1).
+					Return(db.AccountPermission{AccountID: acc.ID, Username: "delegate", Access: accessTransfer}, nil)
+				store.EXPECT().ListAccountHolders(gomock.Any(), gomock.Eq(acc.ID)).Times(1).Return([]db.AccountHolder{
+					{AccountID: acc.ID, Username: owner.Username, Role: db.HolderPrimary},
+					{AccountID: acc.ID, Username: clerk.Username, Role: db.HolderSecondary},
+				}, nil)
+				store.EXPECT().ApproveTransferDraftTx(gomock.Any(), gomock.Any()).Times(0)
+			},
+			checkResponse: func(t *testing.T, rec *httptest.ResponseRecorder) {
+				require.Equal(t, http.StatusForbidden, rec.Code)
+			},
+		},
+		{
+			name:     "DelegateApprovesJointDraftOfStricterPolicy",
+			username: "delegate",
+			buildStubs: func(store *mockdb.MockStore) {
+				joint := acc
+				joint.JointApprovalThreshold = 50
+				// the policy asks for more approvals than there are holders, so delegates count too
+				strict := draft
+				strict.RequiredApprovals = 3
+				store.EXPECT().GetTransferDraft(gomock.Any(), gomock.Eq(draft.ID)).Times(1).Return(strict, nil)
+				store.EXPECT().GetAccount(gomock.Any(), gomock.Eq(acc.ID)).Times(1).Return(joint, nil)
+				store.EXPECT().GetAccountHolder(gomock.Any(), gomock.Any()).Times(1).Return(db.AccountHolder{}, sql.ErrNoRows)
+				store.EXPECT().GetAccountPermission(gomock.Any(), gomock.Any()).Times(1).
+					Return(db.AccountPermission{AccountID: acc.ID, Username: "delegate", Access: accessTransfer}, nil)
+				store.EXPECT().ListAccountHolders(gomock.Any(), gomock.Eq(acc.ID)).Times(1).Return([]db.AccountHolder{
+					{AccountID: acc.ID, Username: owner.Username, Role: db.HolderPrimary},
+					{AccountID: acc.ID, Username: clerk.Username, Role: db.HolderSecondary},
+				}, nil)
+				store.EXPECT().ApproveTransferDraftTx(gomock.Any(), gomock.Any()).Times(1).
+					Return(db.ApproveTransferDraftTxResult{Draft: strict}, nil)
+			},
+			checkResponse: func(t *testing.T, rec *httptest.ResponseRecorder) {
+				require.Equal(t, http.StatusOK, rec.Code)
+			},
+		},
+		{
+			name:     "AlreadyDecided",
+			username: owner.Username,
+			buildStubs: func(store *mockdb.MockStore) {
+				store.EXPECT().GetTransferDraft(gomock.Any(), gomock.Eq(draft.ID)).Times(1).Return(draft, nil)
+				store.EXPECT().GetAccount(gomock.Any(), gomock.Eq(acc.ID)).Times(1).Return(acc, nil)
+				store.EXPECT().ApproveTransferDraftTx(gomock.Any(), gomock.Any()).Times(1).
+					Return(db.ApproveTransferDraftTxResult{}, db.ErrDraftDecided)
+			},
+			checkResponse: func(t *testing.T, rec *httptest.ResponseRecorder) {
+				require.Equal(t, http.StatusConflict, rec.Code)
+			},
+		},
+		{
+			name:     "NotFound",
+			username: owner.Username,
+			buildStubs: func(store *mockdb.MockStore) {
+				store.EXPECT().GetTransferDraft(gomock.Any(), gomock.Eq(draft.ID)).Times(1).Return(db.TransferDraft{}, sql.ErrNoRows)
+				store.EXPECT().ApproveTransferDraftTx(gomock.Any(), gomock.Any()).Times(0)
+			},
+			checkResponse: func(t *testing.T, rec *httptest.ResponseRecorder) {
+				require.Equal(t, http.StatusNotFound, rec.Code)
+			},
+		},
+	}
+
+	for i := range testCases {
+		tc := testCases[i]
+
+		t.Run(tc.name, func(t *testing.T) {
+			ctrl := gomock.NewController(t)
+			defer ctrl.Finish()
+
+			store := mockdb.NewMockStore(ctrl)
+			stubAuthUsers(store)
+			tc.buildStubs(store)
+
+			svr := newTestServer(t, store)
+			rec := httptest.NewRecorder()
+
+			req, err := http.NewRequest(http.MethodPost, fmt.Sprintf("/transfers/drafts/%d/approve", draft.ID), nil)
+			require.NoError(t, err)
+
+			addAuthorizationHeader(t, req, svr.tokenMaker, authorizationTypeBearer, tc.username, time.Minute)
+			svr.router.ServeHTTP(rec, req)
+			tc.checkResponse(t, rec)
+		})
+	}
+}
+
+func TestRejectTransferDraftAPI(t *testing.T) {
+	owner, _ := randomUser(t)
+	acc := randomAccount(owner.Username)
+	acc.ID = 1
+	draft := db.TransferDraft{ID: 3, FromAccountID: acc.ID, ToAccountID: 2, Amount: 100, CreatedBy: owner.Username, Status: db.DraftPending}
+
+	testCases := []struct {
+		name          string
+		buildStubs    func(store *mockdb.MockStore)
+		checkResponse func(t *testing.T, rec *httptest.ResponseRecorder)
+	}{
+		{
+			name: "OK",
+			buildStubs: func(store *mockdb.MockStore) {
+				rejected := draft
+				rejected.Status = db.DraftRejected
+				rejected.DecidedBy = sql.NullString{String: owner.Username, Valid: true}
+
+				store.EXPECT().GetTransferDraft(gomock.Any(), gomock.Eq(draft.ID)).Times(1).Return(draft, nil)
+				store.EXPECT().GetAccount(gomock.Any(), gomock.Eq(acc.ID)).Times(1).Return(acc, nil)
+				store.EXPECT().
+					RejectTransferDraft(gomock.Any(), gomock.Eq(db.RejectTransferDraftParams{ID: draft.ID, DecidedBy: rejected.DecidedBy})).
+					Times(1).
+					Return(rejected, nil)
+			},
+			checkResponse: func(t *testing.T, rec *httptest.ResponseRecorder) {
+				require.Equal(t, http.StatusOK, rec.Code)
+
+				var got db.TransferDraft
+				require.NoError(t, json.Unmarshal(rec.Body.Bytes(), &got))
+				require.Equal(t, db.DraftRejected, got.Status)
+			},
+		},
+		{
+			name: "AlreadyDecided",
+			buildStubs: func(store *mockdb.MockStore) {
+				store.EXPECT().GetTransferDraft(gomock.Any(), gomock.Eq(draft.ID)).Times(1).Return(draft, nil)
+				store.EXPECT().GetAccount(gomock.Any(), gomock.Eq(acc.ID)).Times(1).Return(acc, nil)
+				store.EXPECT().RejectTransferDraft(gomock.Any(), gomock.Any()).Times(1).Return(db.TransferDraft{}, sql.ErrNoRows)
+			},
+			checkResponse: func(t *testing.T, rec *httptest.ResponseRecorder) {
+				require.Equal(t, http.StatusConflict, rec.Code)
+			},
+		},
+	}
+
+	for i := range testCases {
+		tc := testCases[i]
+
+		t.Run(tc.name, func(t *testing.T) {
+			ctrl := gomock.NewController(t)
+			defer ctrl.Finish()
+
+			store := mockdb.NewMockStore(ctrl)
+			stubAuthUsers(store)
+			tc.buildStubs(store)
+
+			svr := newTestServer(t, store)
+			rec := httptest.NewRecorder()
+
+			req, err := http.NewRequest(http.MethodPost, fmt.Sprintf("/transfers/drafts/%d/reject", draft.ID), nil)
+			require.NoError(t, err)
+
+			addAuthorizationHeader(t, req, svr.tokenMaker, authorizationTypeBearer, owner.Username, time.Minute)
+			svr.router.ServeHTTP(rec, req)
+			tc.checkResponse(t, rec)
+		})
+	}
+}
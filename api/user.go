@@ -2,9 +2,14 @@ package api
 
 import (
 	"database/sql"
+	"errors"
+	"fmt"
 	db "gobank/db/sqlc"
+	"gobank/token"
 	"gobank/util"
+	"log"
 	"net/http"
+	"strings"
 	"time"
 
 	"github.com/gin-gonic/gin"
@@ -13,7 +18,7 @@ import (
 
 type createUserRequest struct {
 	Username string `json:"username" binding:"required,alphanum"`
-	Password string `json:"password" binding:"required,min=6"`
+	Password string `json:"password" binding:"required"`
 	FullName string `json:"full_name" binding:"required"`
 	Email    string `json:"email" binding:"required,email"`
 }
@@ -22,6 +27,7 @@ type userResponse struct {
 	Username          string    `json:"username"`
 	FullName          string    `json:"full_name"`
 	Email             string    `json:"email"`
+	IsEmailVerified   bool      `json:"is_email_verified"`
 	PasswordChangedAt time.Time `json:"password_changed_at"`
 	CreatedAt         time.Time `json:"created_at"`
 }
@@ -41,6 +47,7 @@ func newUserResponse(u db.User) userResponse {
 		Username:          u.Username,
 		FullName:          u.FullName,
 		Email:             u.Email,
+		IsEmailVerified:   u.IsEmailVerified,
 		PasswordChangedAt: u.PasswordChangedAt,
 		CreatedAt:         u.CreatedAt,
 	}
@@ -52,8 +59,11 @@ func (server *Server) createUser(ctx *gin.Context) {
 		ctx.JSON(http.StatusBadRequest, errorResponse(err))
 		return
 	}
+	if !server.checkPasswordPolicy(ctx, "password", req.Password, req.Username, req.Email) {
+		return
+	}
 
-	hashedPwd, err := util.HashPassword(req.Password)
+	hashedPwd, err := server.hasher.Hash(req.Password)
 	if err != nil {
 		ctx.JSON(http.StatusInternalServerError, errorResponse(err))
 		return
@@ -66,7 +76,7 @@ func (server *Server) createUser(ctx *gin.Context) {
 		Email:          req.Email,
 	}
 
-	user, err := server.store.CreateUser(ctx, arg)
+	user, err := server.store.CreateUserTx(ctx, arg)
 	if err != nil {
 		if pqErr, ok := err.(*pq.Error); ok {
 			switch pqErr.Code.Name() {
@@ -96,20 +106,79 @@ func (s *Server) login(ctx *gin.Context) {
 	u, err := s.store.GetUser(ctx, req.Username)
 	if err != nil {
 		if err == sql.ErrNoRows {
-			ctx.JSON(http.StatusNotFound, errorResponse(err))
+			if !s.beginLoginAttempt(ctx, unknownUserSubject(req.Username)) {
+				return
+			}
+			s.hasher.Check(req.Password, s.dummyPasswordHash)
+			ctx.JSON(http.StatusUnauthorized, errorResponse(errInvalidCredentials))
 			return
 		}
 		ctx.JSON(http.StatusInternalServerError, errorResponse(err))
 		return
 	}
 
-	err = util.CheckPassword(req.Password, u.HashedPassword)
+	if !s.beginLoginAttempt(ctx, u.Username) {
+		return
+	}
+	err = s.hasher.Check(req.Password, u.HashedPassword)
 	if err != nil {
-		ctx.JSON(http.StatusUnauthorized, errorResponse(err))
+		ctx.JSON(http.StatusUnauthorized, errorResponse(errInvalidCredentials))
+		return
+	}
+	if s.hasher.NeedsRehash(u.HashedPassword) {
+		s.rehashPassword(ctx, u, req.Password)
+	}
+
+	enrollment, err := s.store.GetTOTPEnrollment(ctx, u.Username)
+	if err != nil && err != sql.ErrNoRows {
+		ctx.JSON(http.StatusInternalServerError, errorResponse(err))
+		return
+	}
+	if err == nil && enrollment.ConfirmedAt.Valid {
+		if !s.endLoginAttempt(ctx, u.Username) {
+			return
+		}
+		s.startMFAChallenge(ctx, u)
 		return
 	}
 
-	t, err := s.tokenMaker.CreateToken(req.Username, s.config.AccessTokenDuration)
+	if !s.clearLoginFailures(ctx, u.Username) {
+		return
+	}
+	s.issueAccessToken(ctx, u, token.AMRPassword)
+}
+
+// rehashPassword upgrades the stored hash of u to the current algorithm and parameters while the plain
+// password is at hand. A failure only means another try at the next login, so it does not fail this one.
+func (s *Server) rehashPassword(ctx *gin.Context, u db.User, password string) {
+	hashedPwd, err := s.hasher.Hash(password)
+	if err == nil {
+		err = s.store.RehashUserPassword(ctx, db.RehashUserPasswordParams{
+			Username:          u.Username,
+			OldHashedPassword: u.HashedPassword,
+			NewHashedPassword: hashedPwd,
+		})
+	}
+	if err != nil {
+		log.Printf("cannot rehash password of %s: %v", u.Username, err)
+	}
+}
+
+// checkPasswordPolicy writes the error response and returns false if pwd is not acceptable as the password
+// of the user with username and email. The message names field, the request field pwd came from.
+func (s *Server) checkPasswordPolicy(ctx *gin.Context, field, pwd, username, email string) bool {
+	problems := s.policy.Check(pwd, username, email)
+	if len(problems) == 0 {
+		return true
+	}
+	err := fmt.Errorf("%s %s", field, strings.Join(problems, ", "))
+	ctx.JSON(http.StatusBadRequest, errorResponse(err))
+	return false
+}
+
+// issueAccessToken finishes a successful authentication of u with methods.
+func (s *Server) issueAccessToken(ctx *gin.Context, u db.User, methods ...string) {
+	t, err := s.tokenMaker.CreateToken(u.Username, s.config.AccessTokenDuration, token.WithAuthentication(time.Now(), methods...))
 	if err != nil {
 		ctx.JSON(http.StatusInternalServerError, errorResponse(err))
 		return
@@ -120,5 +189,275 @@ func (s *Server) login(ctx *gin.Context) {
 		User:        newUserResponse(u),
 	}
 	ctx.JSON(http.StatusOK, rsp)
+}
+
+type verifyEmailRequest struct {
+	EmailID    int64  `form:"email_id" binding:"required,min=1"`
+	SecretCode string `form:"secret_code" binding:"required"`
+}
+
+type verifyEmailResponse struct {
+	IsVerified bool `json:"is_verified"`
+}
+
+// verifyEmail is the target of the link in the verification email.
+func (s *Server) verifyEmail(ctx *gin.Context) {
+	var req verifyEmailRequest
+	if err := ctx.ShouldBindQuery(&req); err != nil {
+		ctx.JSON(http.StatusBadRequest, errorResponse(err))
+		return
+	}
+
+	result, err := s.store.VerifyEmailTx(ctx, db.VerifyEmailTxParams{
+		EmailID:    req.EmailID,
+		SecretCode: req.SecretCode,
+	})
+	if err != nil {
+		if err == sql.ErrNoRows {
+			err = errors.New("verification link is invalid, already used or expired")
+			ctx.JSON(http.StatusNotFound, errorResponse(err))
+			return
+		}
+		ctx.JSON(http.StatusInternalServerError, errorResponse(err))
+		return
+	}
+	ctx.JSON(http.StatusOK, verifyEmailResponse{IsVerified: result.User.IsEmailVerified})
+}
+
+// resendVerifyEmailInterval is how long a user waits before another verification email can be sent.
+const resendVerifyEmailInterval = time.Minute
+
+type resendVerifyEmailResponse struct {
+	Email string `json:"email"`
+}
+
+// resendVerifyEmail sends a new verification link to the caller's email, for when the last one got lost or
+// ran out.
+func (s *Server) resendVerifyEmail(ctx *gin.Context) {
+	user := ctx.MustGet(authorizationUserKey).(db.User)
+	if user.IsEmailVerified {
+		err := errors.New("email is already verified")
+		ctx.JSON(http.StatusConflict, errorResponse(err))
+		return
+	}
+
+	last, err := s.store.GetLatestVerifyEmail(ctx, user.Username)
+	if err != nil && err != sql.ErrNoRows {
+		ctx.JSON(http.StatusInternalServerError, errorResponse(err))
+		return
+	}
+	if err == nil && last.Email == user.Email && time.Since(last.CreatedAt) < resendVerifyEmailInterval {
+		err := errors.New("a verification email was just sent, try again in a minute")
+		ctx.JSON(http.StatusTooManyRequests, errorResponse(err))
+		return
+	}
+
+	if err := s.store.ResendVerifyEmailTx(ctx, user); err != nil {
+		ctx.JSON(http.StatusInternalServerError, errorResponse(err))
+		return
+	}
+	ctx.JSON(http.StatusOK, resendVerifyEmailResponse{Email: user.Email})
+}
+
+type forgotPasswordRequest struct {
+	Email string `json:"email" binding:"required,email"`
+}
+
+type forgotPasswordResponse struct {
+	Message string `json:"message"`
+}
+
+// forgotPasswordInterval is how long a user waits before another password reset email can be sent, like
+// resendVerifyEmailInterval.
+const forgotPasswordInterval = time.Minute
+
+// forgotPassword emails a password reset code. It answers the same whether or not the address belongs to
+// a user, so it cannot be used to find out who banks here. That includes not saying when an email is held
+// back because one was just sent.
+func (s *Server) forgotPassword(ctx *gin.Context) {
+	var req forgotPasswordRequest
+	if err := ctx.ShouldBindJSON(&req); err != nil {
+		ctx.JSON(http.StatusBadRequest, errorResponse(err))
+		return
+	}
+
+	rsp := forgotPasswordResponse{Message: "if the address belongs to an account, a reset code has been sent to it"}
+
+	user, err := s.store.GetUserByEmail(ctx, req.Email)
+	if err != nil {
+		if err == sql.ErrNoRows {
+			ctx.JSON(http.StatusAccepted, rsp)
+			return
+		}
+		ctx.JSON(http.StatusInternalServerError, errorResponse(err))
+		return
+	}
+
+	last, err := s.store.GetLatestPasswordResetToken(ctx, user.Username)
+	if err != nil && err != sql.ErrNoRows {
+		ctx.JSON(http.StatusInternalServerError, errorResponse(err))
+		return
+	}
+	if err == nil && time.Since(last.CreatedAt) < forgotPasswordInterval {
+		ctx.JSON(http.StatusAccepted, rsp)
+		return
+	}
+
+	if _, err := s.store.CreatePasswordResetTx(ctx, user.Username); err != nil {
+		ctx.JSON(http.StatusInternalServerError, errorResponse(err))
+		return
+	}
+	ctx.JSON(http.StatusAccepted, rsp)
+}
+
+var errInvalidResetCode = errors.New("reset code is invalid, already used or expired")
+
+type resetPasswordRequest struct {
+	Token       string `json:"token" binding:"required"`
+	NewPassword string `json:"new_password" binding:"required"`
+}
+
+// resetPassword sets a new password with a code from forgotPassword, which signs the user out everywhere.
+func (s *Server) resetPassword(ctx *gin.Context) {
+	var req resetPasswordRequest
+	if err := ctx.ShouldBindJSON(&req); err != nil {
+		ctx.JSON(http.StatusBadRequest, errorResponse(err))
+		return
+	}
+
+	prt, err := s.store.GetValidPasswordResetToken(ctx, util.HashToken(req.Token))
+	if err != nil {
+		if err == sql.ErrNoRows {
+			ctx.JSON(http.StatusNotFound, errorResponse(errInvalidResetCode))
+			return
+		}
+		ctx.JSON(http.StatusInternalServerError, errorResponse(err))
+		return
+	}
+	user, err := s.store.GetUser(ctx, prt.Username)
+	if err != nil {
+		ctx.JSON(http.StatusInternalServerError, errorResponse(err))
+		return
+	}
+	if !s.checkPasswordPolicy(ctx, "new_password", req.NewPassword, user.Username, user.Email) {
+		return
+	}
+
+	hashedPwd, err := s.hasher.Hash(req.NewPassword)
+	if err != nil {
+		ctx.JSON(http.StatusInternalServerError, errorResponse(err))
+		return
+	}
+
+	user, err = s.store.ResetPasswordTx(ctx, db.ResetPasswordTxParams{
+		Token:          req.Token,
+		HashedPassword: hashedPwd,
+	})
+	if err != nil {
+		if err == sql.ErrNoRows {
+			ctx.JSON(http.StatusNotFound, errorResponse(errInvalidResetCode))
+			return
+		}
+		ctx.JSON(http.StatusInternalServerError, errorResponse(err))
+		return
+	}
+	ctx.JSON(http.StatusOK, newUserResponse(user))
+}
+
+type updateUserURI struct {
+	Username string `uri:"username" binding:"required,alphanum"`
+}
+
+type updateUserRequest struct {
+	FullName *string `json:"full_name" binding:"omitempty,min=1"`
+	Email    *string `json:"email" binding:"omitempty,email"`
+}
+
+// updateUser edits the caller's own profile. A changed email address has to be verified again before the
+// user can move money.
+func (s *Server) updateUser(ctx *gin.Context) {
+	var uri updateUserURI
+	if err := ctx.ShouldBindUri(&uri); err != nil {
+		ctx.JSON(http.StatusBadRequest, errorResponse(err))
+		return
+	}
+	var req updateUserRequest
+	if err := ctx.ShouldBindJSON(&req); err != nil {
+		ctx.JSON(http.StatusBadRequest, errorResponse(err))
+		return
+	}
+
+	payload := ctx.MustGet(authorizationPayloadKey).(*token.Payload)
+	if payload.Username != uri.Username {
+		err := errors.New("cannot update another user")
+		ctx.JSON(http.StatusUnauthorized, errorResponse(err))
+		return
+	}
+
+	arg := db.UpdateUserTxParams{Username: uri.Username}
+	if req.FullName != nil {
+		arg.FullName = sql.NullString{String: *req.FullName, Valid: true}
+	}
+	if req.Email != nil {
+		arg.Email = sql.NullString{String: *req.Email, Valid: true}
+	}
+
+	user, err := s.store.UpdateUserTx(ctx, arg)
+	if err != nil {
+		if pqErr, ok := err.(*pq.Error); ok && pqErr.Code.Name() == "unique_violation" {
+			ctx.JSON(http.StatusForbidden, errorResponse(err))
+			return
+		}
+		ctx.JSON(http.StatusInternalServerError, errorResponse(err))
+		return
+	}
+	ctx.JSON(http.StatusOK, newUserResponse(user))
+}
+
+type changePasswordRequest struct {
+	CurrentPassword string `json:"current_password" binding:"required"`
+	NewPassword     string `json:"new_password" binding:"required"`
+}
+
+// changePassword replaces the caller's password. That revokes every existing session, so the response
+// carries a fresh access token for the current one.
+func (s *Server) changePassword(ctx *gin.Context) {
+	var req changePasswordRequest
+	if err := ctx.ShouldBindJSON(&req); err != nil {
+		ctx.JSON(http.StatusBadRequest, errorResponse(err))
+		return
+	}
+
+	user := ctx.MustGet(authorizationUserKey).(db.User)
+	if !s.beginLoginAttempt(ctx, user.Username) {
+		return
+	}
+	if err := s.hasher.Check(req.CurrentPassword, user.HashedPassword); err != nil {
+		ctx.JSON(http.StatusUnauthorized, errorResponse(err))
+		return
+	}
+	if !s.endLoginAttempt(ctx, user.Username) {
+		return
+	}
+	if !s.checkPasswordPolicy(ctx, "new_password", req.NewPassword, user.Username, user.Email) {
+		return
+	}
+
+	hashedPwd, err := s.hasher.Hash(req.NewPassword)
+	if err != nil {
+		ctx.JSON(http.StatusInternalServerError, errorResponse(err))
+		return
+	}
+
+	user, err = s.store.UpdateUserPassword(ctx, db.UpdateUserPasswordParams{
+		Username:          user.Username,
+		HashedPassword:    hashedPwd,
+		PasswordChangedAt: time.Now(),
+	})
+	if err != nil {
+		ctx.JSON(http.StatusInternalServerError, errorResponse(err))
+		return
+	}
 
+	s.issueAccessToken(ctx, user, token.AMRPassword)
 }
@@ -1,10 +1,24 @@
 package api
 
 import (
+	"bytes"
+	"context"
+	"database/sql"
+	"encoding/json"
+	"gobank/bloom"
+	mockdb "gobank/db/mock"
 	db "gobank/db/sqlc"
+	"gobank/token"
 	"gobank/util"
+	"net/http"
+	"net/http/httptest"
+	"strings"
 	"testing"
+	"time"
 
+	"github.com/gin-gonic/gin"
+	"github.com/golang/mock/gomock"
+	"github.com/lib/pq"
 	"github.com/stretchr/testify/require"
 )
 
@@ -21,3 +35,797 @@ func randomUser(t *testing.T) (user db.User, password string) {
 	}
 	return
 }
+
+func TestCreateUserAPI(t *testing.T) {
+	user, _ := randomUser(t)
+	password := "c0rrect-Horse"
+
+	breached := bloom.New(10, 0.001)
+	breached.Add("Password123!")
+
+	testCases := []struct {
+		name          string
+		body          gin.H
+		buildStubs    func(store *mockdb.MockStore)
+		checkResponse func(t *testing.T, rec *httptest.ResponseRecorder)
+	}{
+		{
+			name: "OK",
+			body: gin.H{"username": user.Username, "password": password, "full_name": user.FullName, "email": user.Email},
+			buildStubs: func(store *mockdb.MockStore) {
+				store.EXPECT().CreateUserTx(gomock.Any(), gomock.Any()).Times(1).
+					DoAndReturn(func(_ context.Context, arg db.CreateUserParams) (db.User, error) {
+						require.Equal(t, user.Username, arg.Username)
+						require.NoError(t, util.CheckPassword(password, arg.HashedPassword))
+						return user, nil
+					})
+			},
+			checkResponse: func(t *testing.T, rec *httptest.ResponseRecorder) {
+				require.Equal(t, http.StatusOK, rec.Code)
+				require.NotContains(t, rec.Body.String(), "hashed_password")
+			},
+		},
+		{
+			name: "WeakPassword",
+			body: gin.H{"username": user.Username, "password": "abcdefgh", "full_name": user.FullName, "email": user.Email},
+			buildStubs: func(store *mockdb.MockStore) {
+				store.EXPECT().CreateUserTx(gomock.Any(), gomock.Any()).Times(0)
+			},
+			checkResponse: func(t *testing.T, rec *httptest.ResponseRecorder) {
+				require.Equal(t, http.StatusBadRequest, rec.Code)
+				require.JSONEq(t, `{"error":"password must be at least 10 characters long, `+
+					`must mix at least 3 of lowercase letters, uppercase letters, digits and symbols"}`, rec.Body.String())
+			},
+		},
+		{
+			name: "PasswordContainsEmail",
+			body: gin.H{"username": user.Username, "password": "1-" + strings.ToUpper(user.Email), "full_name": user.FullName, "email": user.Email},
+			buildStubs: func(store *mockdb.MockStore) {
+				store.EXPECT().CreateUserTx(gomock.Any(), gomock.Any()).Times(0)
+			},
+			checkResponse: func(t *testing.T, rec *httptest.ResponseRecorder) {
+				require.Equal(t, http.StatusBadRequest, rec.Code)
+				require.Contains(t, rec.Body.String(), "password must not contain the email address")
+			},
+		},
+		{
+			name: "BreachedPassword",
+			body: gin.H{"username": user.Username, "password": "Password123!", "full_name": user.FullName, "email": user.Email},
+			buildStubs: func(store *mockdb.MockStore) {
+				store.EXPECT().CreateUserTx(gomock.Any(), gomock.Any()).Times(0)
+			},
+			checkResponse: func(t *testing.T, rec *httptest.ResponseRecorder) {
+				require.Equal(t, http.StatusBadRequest, rec.Code)
+				require.Contains(t, rec.Body.String(), "password appears in a list of breached passwords")
+			},
+		},
+		{
+			name: "DuplicateUsername",
+			body: gin.H{"username": user.Username, "password": password, "full_name": user.FullName, "email": user.Email},
+			buildStubs: func(store *mockdb.MockStore) {
+				store.EXPECT().CreateUserTx(gomock.Any(), gomock.Any()).Times(1).Return(db.User{}, &pq.Error{Code: "23505"})
+			},
+			checkResponse: func(t *testing.T, rec *httptest.ResponseRecorder) {
+				require.Equal(t, http.StatusForbidden, rec.Code)
+			},
+		},
+		{
+			name: "InternalError",
+			body: gin.H{"username": user.Username, "password": password, "full_name": user.FullName, "email": user.Email},
+			buildStubs: func(store *mockdb.MockStore) {
+				store.EXPECT().CreateUserTx(gomock.Any(), gomock.Any()).Times(1).Return(db.User{}, sql.ErrConnDone)
+			},
+			checkResponse: func(t *testing.T, rec *httptest.ResponseRecorder) {
+				require.Equal(t, http.StatusInternalServerError, rec.Code)
+			},
+		},
+	}
+
+	for i := range testCases {
+		tc := testCases[i]
+
+		t.Run(tc.name, func(t *testing.T) {
+			ctrl := gomock.NewController(t)
+			defer ctrl.Finish()
+
+			store := mockdb.NewMockStore(ctrl)
+			tc.buildStubs(store)
+
+			svr := newTestServer(t, store)
+			svr.policy = util.PasswordPolicy{MinLength: 10, MinCharClasses: 3, Breached: breached}
+			rec := httptest.NewRecorder()
+
+			data, err := json.Marshal(tc.body)
+			require.NoError(t, err)
+
+			req, err := http.NewRequest(http.MethodPost, "/users", bytes.NewReader(data))
+			require.NoError(t, err)
+
+			svr.router.ServeHTTP(rec, req)
+			tc.checkResponse(t, rec)
+		})
+	}
+}
+
+func TestVerifyEmailAPI(t *testing.T) {
+	user, _ := randomUser(t)
+	user.IsEmailVerified = true
+	ve := db.VerifyEmail{ID: 5, Username: user.Username, Email: user.Email, SecretCode: "code", IsUsed: true}
+
+	testCases := []struct {
+		name          string
+		query         string
+		buildStubs    func(store *mockdb.MockStore)
+		checkResponse func(t *testing.T, rec *httptest.ResponseRecorder)
+	}{
+		{
+			name:  "OK",
+			query: "?email_id=5&secret_code=code",
+			buildStubs: func(store *mockdb.MockStore) {
+				store.EXPECT().
+					VerifyEmailTx(gomock.Any(), gomock.Eq(db.VerifyEmailTxParams{EmailID: 5, SecretCode: "code"})).
+					Times(1).
+					Return(db.VerifyEmailTxResult{User: user, VerifyEmail: ve}, nil)
+			},
+			checkResponse: func(t *testing.T, rec *httptest.ResponseRecorder) {
+				require.Equal(t, http.StatusOK, rec.Code)
+				require.JSONEq(t, `{"is_verified":true}`, rec.Body.String())
+			},
+		},
+		{
+			name:  "InvalidCode",
+			query: "?email_id=5&secret_code=wrong",
+			buildStubs: func(store *mockdb.MockStore) {
+				store.EXPECT().VerifyEmailTx(gomock.Any(), gomock.Any()).Times(1).Return(db.VerifyEmailTxResult{}, sql.ErrNoRows)
+			},
+			checkResponse: func(t *testing.T, rec *httptest.ResponseRecorder) {
+				require.Equal(t, http.StatusNotFound, rec.Code)
+			},
+		},
+		{
+			name:  "MissingCode",
+			query: "?email_id=5",
+			buildStubs: func(store *mockdb.MockStore) {
+				store.EXPECT().VerifyEmailTx(gomock.Any(), gomock.Any()).Times(0)
+			},
+			checkResponse: func(t *testing.T, rec *httptest.ResponseRecorder) {
+				require.Equal(t, http.StatusBadRequest, rec.Code)
+			},
+		},
+		{
+			name:  "InternalError",
+			query: "?email_id=5&secret_code=code",
+			buildStubs: func(store *mockdb.MockStore) {
+				store.EXPECT().VerifyEmailTx(gomock.Any(), gomock.Any()).Times(1).Return(db.VerifyEmailTxResult{}, sql.ErrConnDone)
+			},
+			checkResponse: func(t *testing.T, rec *httptest.ResponseRecorder) {
+				require.Equal(t, http.StatusInternalServerError, rec.Code)
+			},
+		},
+	}
+
+	for i := range testCases {
+		tc := testCases[i]
+
+		t.Run(tc.name, func(t *testing.T) {
+			ctrl := gomock.NewController(t)
+			defer ctrl.Finish()
+
+			store := mockdb.NewMockStore(ctrl)
+			tc.buildStubs(store)
+
+			svr := newTestServer(t, store)
+			rec := httptest.NewRecorder()
+
+			req, err := http.NewRequest(http.MethodGet, "/verify_email"+tc.query, nil)
+			require.NoError(t, err)
+
+			svr.router.ServeHTTP(rec, req)
+			tc.checkResponse(t, rec)
+		})
+	}
+}
+
+func TestResendVerifyEmailAPI(t *testing.T) {
+	user, _ := randomUser(t)
+
+	testCases := []struct {
+		name          string
+		verified      bool
+		useAPIKey     bool
+		buildStubs    func(store *mockdb.MockStore)
+		checkResponse func(t *testing.T, rec *httptest.ResponseRecorder)
+	}{
+		{
+			name: "OK",
+			buildStubs: func(store *mockdb.MockStore) {
+				store.EXPECT().
+					GetLatestVerifyEmail(gomock.Any(), gomock.Eq(user.Username)).
+					Times(1).
+					Return(db.VerifyEmail{Email: user.Email, CreatedAt: time.Now().Add(-time.Hour)}, nil)
+				store.EXPECT().
+					ResendVerifyEmailTx(gomock.Any(), gomock.Any()).
+					Times(1).
+					DoAndReturn(func(_ context.Context, u db.User) error {
+						require.Equal(t, user.Username, u.Username)
+						require.Equal(t, user.Email, u.Email)
+						return nil
+					})
+			},
+			checkResponse: func(t *testing.T, rec *httptest.ResponseRecorder) {
+				require.Equal(t, http.StatusOK, rec.Code)
+				require.Contains(t, rec.Body.String(), user.Email)
+			},
+		},
+		{
+			name: "NeverSent",
+			buildStubs: func(store *mockdb.MockStore) {
+				store.EXPECT().GetLatestVerifyEmail(gomock.Any(), gomock.Any()).Times(1).Return(db.VerifyEmail{}, sql.ErrNoRows)
+				store.EXPECT().ResendVerifyEmailTx(gomock.Any(), gomock.Any()).Times(1).Return(nil)
+			},
+			checkResponse: func(t *testing.T, rec *httptest.ResponseRecorder) {
+				require.Equal(t, http.StatusOK, rec.Code)
+			},
+		},
+		{
+			name: "JustSent",
+			buildStubs: func(store *mockdb.MockStore) {
+				store.EXPECT().
+					GetLatestVerifyEmail(gomock.Any(), gomock.Any()).
+					Times(1).
+					Return(db.VerifyEmail{Email: user.Email, CreatedAt: time.Now()}, nil)
+				store.EXPECT().ResendVerifyEmailTx(gomock.Any(), gomock.Any()).Times(0)
+			},
+			checkResponse: func(t *testing.T, rec *httptest.ResponseRecorder) {
+				require.Equal(t, http.StatusTooManyRequests, rec.Code)
+			},
+		},
+		{
+			name: "JustSentToOldAddress",
+			buildStubs: func(store *mockdb.MockStore) {
+				store.EXPECT().
+					GetLatestVerifyEmail(gomock.Any(), gomock.Any()).
+					Times(1).
+					Return(db.VerifyEmail{Email: "old@example.com", CreatedAt: time.Now()}, nil)
+				store.EXPECT().ResendVerifyEmailTx(gomock.Any(), gomock.Any()).Times(1).Return(nil)
+			},
+			checkResponse: func(t *testing.T, rec *httptest.ResponseRecorder) {
+				require.Equal(t, http.StatusOK, rec.Code)
+			},
+		},
+		{
+			name:     "AlreadyVerified",
+			verified: true,
+			buildStubs: func(store *mockdb.MockStore) {
+				store.EXPECT().ResendVerifyEmailTx(gomock.Any(), gomock.Any()).Times(0)
+			},
+			checkResponse: func(t *testing.T, rec *httptest.ResponseRecorder) {
+				require.Equal(t, http.StatusConflict, rec.Code)
+			},
+		},
+		{
+			name:      "APIKey",
+			useAPIKey: true,
+			buildStubs: func(store *mockdb.MockStore) {
+				store.EXPECT().ResendVerifyEmailTx(gomock.Any(), gomock.Any()).Times(0)
+			},
+			checkResponse: func(t *testing.T, rec *httptest.ResponseRecorder) {
+				require.Equal(t, http.StatusForbidden, rec.Code)
+			},
+		},
+		{
+			name: "InternalError",
+			buildStubs: func(store *mockdb.MockStore) {
+				store.EXPECT().GetLatestVerifyEmail(gomock.Any(), gomock.Any()).Times(1).Return(db.VerifyEmail{}, sql.ErrNoRows)
+				store.EXPECT().ResendVerifyEmailTx(gomock.Any(), gomock.Any()).Times(1).Return(sql.ErrConnDone)
+			},
+			checkResponse: func(t *testing.T, rec *httptest.ResponseRecorder) {
+				require.Equal(t, http.StatusInternalServerError, rec.Code)
+			},
+		},
+	}
+
+	for i := range testCases {
+		tc := testCases[i]
+
+		t.Run(tc.name, func(t *testing.T) {
+			ctrl := gomock.NewController(t)
+			defer ctrl.Finish()
+
+			u := user
+			u.IsEmailVerified = tc.verified
+			store := mockdb.NewMockStore(ctrl)
+			store.EXPECT().GetUser(gomock.Any(), gomock.Eq(user.Username)).AnyTimes().Return(u, nil)
+			apiKey, key := randomAPIKey(t, user.Username, token.ScopeAccountsRead)
+			store.EXPECT().GetAPIKeyByPrefix(gomock.Any(), gomock.Eq(apiKey.Prefix)).AnyTimes().Return(apiKey, nil)
+			store.EXPECT().TouchAPIKey(gomock.Any(), gomock.Any()).AnyTimes()
+			tc.buildStubs(store)
+
+			svr := newTestServer(t, store)
+			rec := httptest.NewRecorder()
+
+			req, err := http.NewRequest(http.MethodPost, "/users/verify_email", nil)
+			require.NoError(t, err)
+			if tc.useAPIKey {
+				addAPIKeyHeader(req, key)
+			} else {
+				addAuthorizationHeader(t, req, svr.tokenMaker, authorizationTypeBearer, user.Username, time.Minute)
+			}
+			svr.router.ServeHTTP(rec, req)
+			tc.checkResponse(t, rec)
+		})
+	}
+}
+func TestForgotPasswordAPI(t *testing.T) {
+	user, _ := randomUser(t)
+
+	testCases := []struct {
+		name          string
+		body          gin.H
+		buildStubs    func(store *mockdb.MockStore)
+		checkResponse func(t *testing.T, rec *httptest.ResponseRecorder)
+	}{
+		{
+			name: "OK",
+			body: gin.H{"email": user.Email},
+			buildStubs: func(store *mockdb.MockStore) {
+				store.EXPECT().GetUserByEmail(gomock.Any(), gomock.Eq(user.Email)).Times(1).Return(user, nil)
+				store.EXPECT().GetLatestPasswordResetToken(gomock.Any(), gomock.Eq(user.Username)).Times(1).
+					Return(db.PasswordResetToken{CreatedAt: time.Now().Add(-2 * forgotPasswordInterval)}, nil)
+				store.EXPECT().CreatePasswordResetTx(gomock.Any(), gomock.Eq(user.Username)).Times(1).Return(db.PasswordResetToken{ID: 1}, nil)
+			},
+			checkResponse: func(t *testing.T, rec *httptest.ResponseRecorder) {
+				require.Equal(t, http.StatusAccepted, rec.Code)
+			},
+		},
+		{
+			name: "JustSentLooksTheSame",
+			body: gin.H{"email": user.Email},
+			buildStubs: func(store *mockdb.MockStore) {
+				store.EXPECT().GetUserByEmail(gomock.Any(), gomock.Eq(user.Email)).Times(1).Return(user, nil)
+				store.EXPECT().GetLatestPasswordResetToken(gomock.Any(), gomock.Eq(user.Username)).Times(1).
+					Return(db.PasswordResetToken{CreatedAt: time.Now().Add(-time.Second)}, nil)
+				store.EXPECT().CreatePasswordResetTx(gomock.Any(), gomock.Any()).Times(0)
+			},
+			checkResponse: func(t *testing.T, rec *httptest.ResponseRecorder) {
+				require.Equal(t, http.StatusAccepted, rec.Code)
+				require.Contains(t, rec.Body.String(), "if the address belongs to an account")
+			},
+		},
+		{
+			name: "UnknownEmailLooksTheSame",
+			body: gin.H{"email": "nobody@email.com"},
+			buildStubs: func(store *mockdb.MockStore) {
+				store.EXPECT().GetUserByEmail(gomock.Any(), gomock.Any()).Times(1).Return(db.User{}, sql.ErrNoRows)
+				store.EXPECT().CreatePasswordResetTx(gomock.Any(), gomock.Any()).Times(0)
+			},
+			checkResponse: func(t *testing.T, rec *httptest.ResponseRecorder) {
+				require.Equal(t, http.StatusAccepted, rec.Code)
+			},
+		},
+		{
+			name: "InvalidEmail",
+			body: gin.H{"email": "not-an-email"},
+			buildStubs: func(store *mockdb.MockStore) {
+				store.EXPECT().GetUserByEmail(gomock.Any(), gomock.Any()).Times(0)
+			},
+			checkResponse: func(t *testing.T, rec *httptest.ResponseRecorder) {
+				require.Equal(t, http.StatusBadRequest, rec.Code)
+			},
+		},
+		{
+			name: "InternalError",
+			body: gin.H{"email": user.Email},
+			buildStubs: func(store *mockdb.MockStore) {
+				store.EXPECT().GetUserByEmail(gomock.Any(), gomock.Any()).Times(1).Return(user, nil)
+				store.EXPECT().GetLatestPasswordResetToken(gomock.Any(), gomock.Any()).Times(1).Return(db.PasswordResetToken{}, sql.ErrNoRows)
+				store.EXPECT().CreatePasswordResetTx(gomock.Any(), gomock.Any()).Times(1).Return(db.PasswordResetToken{}, sql.ErrConnDone)
+			},
+			checkResponse: func(t *testing.T, rec *httptest.ResponseRecorder) {
+				require.Equal(t, http.StatusInternalServerError, rec.Code)
+			},
+		},
+	}
+
+	for i := range testCases {
+		tc := testCases[i]
+
+		t.Run(tc.name, func(t *testing.T) {
+			ctrl := gomock.NewController(t)
+			defer ctrl.Finish()
+
+			store := mockdb.NewMockStore(ctrl)
+			tc.buildStubs(store)
+
+			svr := newTestServer(t, store)
+			rec := httptest.NewRecorder()
+
+			data, err := json.Marshal(tc.body)
+			require.NoError(t, err)
+
+			req, err := http.NewRequest(http.MethodPost, "/users/password/forgot", bytes.NewReader(data))
+			require.NoError(t, err)
+
+			svr.router.ServeHTTP(rec, req)
+			tc.checkResponse(t, rec)
+		})
+	}
+}
+
+func TestResetPasswordAPI(t *testing.T) {
+	user, _ := randomUser(t)
+	prt := db.PasswordResetToken{ID: 3, Username: user.Username, TokenHash: sql.NullString{String: util.HashToken("reset-token"), Valid: true}}
+
+	testCases := []struct {
+		name          string
+		body          gin.H
+		buildStubs    func(store *mockdb.MockStore)
+		checkResponse func(t *testing.T, rec *httptest.ResponseRecorder)
+	}{
+		{
+			name: "OK",
+			body: gin.H{"token": "reset-token", "new_password": "n3w-Secret"},
+			buildStubs: func(store *mockdb.MockStore) {
+				store.EXPECT().GetValidPasswordResetToken(gomock.Any(), gomock.Eq(prt.TokenHash.String)).Times(1).Return(prt, nil)
+				store.EXPECT().GetUser(gomock.Any(), gomock.Eq(user.Username)).Times(1).Return(user, nil)
+				store.EXPECT().
+					ResetPasswordTx(gomock.Any(), gomock.Any()).
+					Times(1).
+					DoAndReturn(func(_ context.Context, arg db.ResetPasswordTxParams) (db.User, error) {
+						require.Equal(t, "reset-token", arg.Token)
+						require.NoError(t, util.CheckPassword("n3w-Secret", arg.HashedPassword))
+						return user, nil
+					})
+			},
+			checkResponse: func(t *testing.T, rec *httptest.ResponseRecorder) {
+				require.Equal(t, http.StatusOK, rec.Code)
+				require.NotContains(t, rec.Body.String(), "hashed_password")
+			},
+		},
+		{
+			name: "InvalidToken",
+			body: gin.H{"token": "wrong", "new_password": "n3w-Secret"},
+			buildStubs: func(store *mockdb.MockStore) {
+				store.EXPECT().GetValidPasswordResetToken(gomock.Any(), gomock.Any()).Times(1).Return(db.PasswordResetToken{}, sql.ErrNoRows)
+				store.EXPECT().ResetPasswordTx(gomock.Any(), gomock.Any()).Times(0)
+			},
+			checkResponse: func(t *testing.T, rec *httptest.ResponseRecorder) {
+				require.Equal(t, http.StatusNotFound, rec.Code)
+			},
+		},
+		{
+			name: "TokenUsedMeanwhile",
+			body: gin.H{"token": "reset-token", "new_password": "n3w-Secret"},
+			buildStubs: func(store *mockdb.MockStore) {
+				store.EXPECT().GetValidPasswordResetToken(gomock.Any(), gomock.Any()).Times(1).Return(prt, nil)
+				store.EXPECT().GetUser(gomock.Any(), gomock.Any()).Times(1).Return(user, nil)
+				store.EXPECT().ResetPasswordTx(gomock.Any(), gomock.Any()).Times(1).Return(db.User{}, sql.ErrNoRows)
+			},
+			checkResponse: func(t *testing.T, rec *httptest.ResponseRecorder) {
+				require.Equal(t, http.StatusNotFound, rec.Code)
+			},
+		},
+		{
+			name: "PasswordTooShort",
+			body: gin.H{"token": "reset-token", "new_password": "Ab1-"},
+			buildStubs: func(store *mockdb.MockStore) {
+				store.EXPECT().GetValidPasswordResetToken(gomock.Any(), gomock.Any()).Times(1).Return(prt, nil)
+				store.EXPECT().GetUser(gomock.Any(), gomock.Any()).Times(1).Return(user, nil)
+				store.EXPECT().ResetPasswordTx(gomock.Any(), gomock.Any()).Times(0)
+			},
+			checkResponse: func(t *testing.T, rec *httptest.ResponseRecorder) {
+				require.Equal(t, http.StatusBadRequest, rec.Code)
+				require.Contains(t, rec.Body.String(), "new_password must be at least 10 characters long")
+			},
+		},
+		{
+			name: "PasswordContainsUsername",
+			body: gin.H{"token": "reset-token", "new_password": "X-" + user.Username + "-99"},
+			buildStubs: func(store *mockdb.MockStore) {
+				store.EXPECT().GetValidPasswordResetToken(gomock.Any(), gomock.Any()).Times(1).Return(prt, nil)
+				store.EXPECT().GetUser(gomock.Any(), gomock.Any()).Times(1).Return(user, nil)
+				store.EXPECT().ResetPasswordTx(gomock.Any(), gomock.Any()).Times(0)
+			},
+			checkResponse: func(t *testing.T, rec *httptest.ResponseRecorder) {
+				require.Equal(t, http.StatusBadRequest, rec.Code)
+				require.Contains(t, rec.Body.String(), "new_password must not contain the username")
+			},
+		},
+		{
+			name: "InternalError",
+			body: gin.H{"token": "reset-token", "new_password": "n3w-Secret"},
+			buildStubs: func(store *mockdb.MockStore) {
+				store.EXPECT().GetValidPasswordResetToken(gomock.Any(), gomock.Any()).Times(1).Return(prt, nil)
+				store.EXPECT().GetUser(gomock.Any(), gomock.Any()).Times(1).Return(user, nil)
+				store.EXPECT().ResetPasswordTx(gomock.Any(), gomock.Any()).Times(1).Return(db.User{}, sql.ErrConnDone)
+			},
+			checkResponse: func(t *testing.T, rec *httptest.ResponseRecorder) {
+				require.Equal(t, http.StatusInternalServerError, rec.Code)
+			},
+		},
+	}
+
+	for i := range testCases {
+		tc := testCases[i]
+
+		t.Run(tc.name, func(t *testing.T) {
+			ctrl := gomock.NewController(t)
+			defer ctrl.Finish()
+
+			store := mockdb.NewMockStore(ctrl)
+			tc.buildStubs(store)
+
+			svr := newTestServer(t, store)
+			svr.policy = util.PasswordPolicy{MinLength: 10, MinCharClasses: 3}
+			rec := httptest.NewRecorder()
+
+			data, err := json.Marshal(tc.body)
+			require.NoError(t, err)
+
+			req, err := http.NewRequest(http.MethodPost, "/users/password/reset", bytes.NewReader(data))
+			require.NoError(t, err)
+
+			svr.router.ServeHTTP(rec, req)
+			tc.checkResponse(t, rec)
+		})
+	}
+}
+
+func TestUpdateUserAPI(t *testing.T) {
+	user, _ := randomUser(t)
+	other, _ := randomUser(t)
+	newEmail := util.RandomEmail()
+
+	testCases := []struct {
+		name          string
+		username      string
+		body          gin.H
+		buildStubs    func(store *mockdb.MockStore)
+		checkResponse func(t *testing.T, rec *httptest.ResponseRecorder)
+	}{
+		{
+			name:     "FullNameOnly",
+			username: user.Username,
+			body:     gin.H{"full_name": "New Name"},
+			buildStubs: func(store *mockdb.MockStore) {
+				arg := db.UpdateUserTxParams{
+					Username: user.Username,
+					FullName: sql.NullString{String: "New Name", Valid: true},
+				}
+				updated := user
+				updated.FullName = "New Name"
+				store.EXPECT().UpdateUserTx(gomock.Any(), gomock.Eq(arg)).Times(1).Return(updated, nil)
+			},
+			checkResponse: func(t *testing.T, rec *httptest.ResponseRecorder) {
+				require.Equal(t, http.StatusOK, rec.Code)
+				require.Contains(t, rec.Body.String(), `"full_name":"New Name"`)
+			},
+		},
+		{
+			name:     "EmailNeedsVerification",
+			username: user.Username,
+			body:     gin.H{"email": newEmail},
+			buildStubs: func(store *mockdb.MockStore) {
+				arg := db.UpdateUserTxParams{
+					Username: user.Username,
+					Email:    sql.NullString{String: newEmail, Valid: true},
+				}
+				updated := user
+				updated.Email = newEmail
+				store.EXPECT().UpdateUserTx(gomock.Any(), gomock.Eq(arg)).Times(1).Return(updated, nil)
+			},
+			checkResponse: func(t *testing.T, rec *httptest.ResponseRecorder) {
+				require.Equal(t, http.StatusOK, rec.Code)
+				require.Contains(t, rec.Body.String(), `"is_email_verified":false`)
+			},
+		},
+		{
+			name:     "OtherUser",
+			username: other.Username,
+			body:     gin.H{"full_name": "New Name"},
+			buildStubs: func(store *mockdb.MockStore) {
+				store.EXPECT().UpdateUserTx(gomock.Any(), gomock.Any()).Times(0)
+			},
+			checkResponse: func(t *testing.T, rec *httptest.ResponseRecorder) {
+				require.Equal(t, http.StatusUnauthorized, rec.Code)
+			},
+		},
+		{
+			name:     "InvalidEmail",
+			username: user.Username,
+			body:     gin.H{"email": "not-an-email"},
+			buildStubs: func(store *mockdb.MockStore) {
+				store.EXPECT().UpdateUserTx(gomock.Any(), gomock.Any()).Times(0)
+			},
+			checkResponse: func(t *testing.T, rec *httptest.ResponseRecorder) {
+				require.Equal(t, http.StatusBadRequest, rec.Code)
+			},
+		},
+		{
+			name:     "EmailTaken",
+			username: user.Username,
+			body:     gin.H{"email": other.Email},
+			buildStubs: func(store *mockdb.MockStore) {
+				store.EXPECT().UpdateUserTx(gomock.Any(), gomock.Any()).Times(1).Return(db.User{}, &pq.Error{Code: "23505"})
+			},
+			checkResponse: func(t *testing.T, rec *httptest.ResponseRecorder) {
+				require.Equal(t, http.StatusForbidden, rec.Code)
+			},
+		},
+		{
+			name:     "InternalError",
+			username: user.Username,
+			body:     gin.H{"full_name": "New Name"},
+			buildStubs: func(store *mockdb.MockStore) {
+				store.EXPECT().UpdateUserTx(gomock.Any(), gomock.Any()).Times(1).Return(db.User{}, sql.ErrConnDone)
+			},
+			checkResponse: func(t *testing.T, rec *httptest.ResponseRecorder) {
+				require.Equal(t, http.StatusInternalServerError, rec.Code)
+			},
+		},
+	}
+
+	for i := range testCases {
+		tc := testCases[i]
+
+		t.Run(tc.name, func(t *testing.T) {
+			ctrl := gomock.NewController(t)
+			defer ctrl.Finish()
+
+			store := mockdb.NewMockStore(ctrl)
+			stubAuthUsers(store)
+			tc.buildStubs(store)
+
+			svr := newTestServer(t, store)
+			rec := httptest.NewRecorder()
+
+			data, err := json.Marshal(tc.body)
+			require.NoError(t, err)
+
+			req, err := http.NewRequest(http.MethodPatch, "/users/"+tc.username, bytes.NewReader(data))
+			require.NoError(t, err)
+
+			addAuthorizationHeader(t, req, svr.tokenMaker, authorizationTypeBearer, user.Username, time.Minute)
+			svr.router.ServeHTTP(rec, req)
+			tc.checkResponse(t, rec)
+		})
+	}
+}
+
+func TestChangePasswordAPI(t *testing.T) {
+	user, password := randomUser(t)
+
+	testCases := []struct {
+		name          string
+		body          gin.H
+		buildStubs    func(store *mockdb.MockStore)
+		checkResponse func(t *testing.T, rec *httptest.ResponseRecorder)
+	}{
+		{
+			name: "OK",
+			body: gin.H{"current_password": password, "new_password": "n3w-secret"},
+			buildStubs: func(store *mockdb.MockStore) {
+				store.EXPECT().
+					UpdateUserPassword(gomock.Any(), gomock.Any()).
+					Times(1).
+					DoAndReturn(func(_ context.Context, arg db.UpdateUserPasswordParams) (db.User, error) {
+						require.Equal(t, user.Username, arg.Username)
+						require.NoError(t, util.CheckPassword("n3w-secret", arg.HashedPassword))
+						return user, nil
+					})
+			},
+			checkResponse: func(t *testing.T, rec *httptest.ResponseRecorder) {
+				require.Equal(t, http.StatusOK, rec.Code)
+				require.Contains(t, rec.Body.String(), `"access_token"`)
+			},
+		},
+		{
+			name: "WrongCurrentPassword",
+			body: gin.H{"current_password": "wrong-password", "new_password": "n3w-secret"},
+			buildStubs: func(store *mockdb.MockStore) {
+				store.EXPECT().UpdateUserPassword(gomock.Any(), gomock.Any()).Times(0)
+			},
+			checkResponse: func(t *testing.T, rec *httptest.ResponseRecorder) {
+				require.Equal(t, http.StatusUnauthorized, rec.Code)
+			},
+		},
+		{
+			name: "NewPasswordTooShort",
+			body: gin.H{"current_password": password, "new_password": "abc"},
+			buildStubs: func(store *mockdb.MockStore) {
+				store.EXPECT().UpdateUserPassword(gomock.Any(), gomock.Any()).Times(0)
+			},
+			checkResponse: func(t *testing.T, rec *httptest.ResponseRecorder) {
+				require.Equal(t, http.StatusBadRequest, rec.Code)
+				require.Contains(t, rec.Body.String(), "new_password must be at least 6 characters long")
+			},
+		},
+		{
+			name: "InternalError",
+			body: gin.H{"current_password": password, "new_password": "n3w-secret"},
+			buildStubs: func(store *mockdb.MockStore) {
+				store.EXPECT().UpdateUserPassword(gomock.Any(), gomock.Any()).Times(1).Return(db.User{}, sql.ErrConnDone)
+			},
+			checkResponse: func(t *testing.T, rec *httptest.ResponseRecorder) {
+				require.Equal(t, http.StatusInternalServerError, rec.Code)
+			},
+		},
+	}
+
+	for i := range testCases {
+		tc := testCases[i]
+
+		t.Run(tc.name, func(t *testing.T) {
+			ctrl := gomock.NewController(t)
+			defer ctrl.Finish()
+
+			store := mockdb.NewMockStore(ctrl)
+			store.EXPECT().GetUser(gomock.Any(), gomock.Eq(user.Username)).AnyTimes().Return(user, nil)
+			tc.buildStubs(store)
+
+			svr := newTestServer(t, store)
+			rec := httptest.NewRecorder()
+
+			data, err := json.Marshal(tc.body)
+			require.NoError(t, err)
+
+			req, err := http.NewRequest(http.MethodPost, "/users/password", bytes.NewReader(data))
+			require.NoError(t, err)
+
+			addAuthorizationHeader(t, req, svr.tokenMaker, authorizationTypeBearer, user.Username, time.Minute)
+			svr.router.ServeHTTP(rec, req)
+			tc.checkResponse(t, rec)
+		})
+	}
+}
+
+func TestChangePasswordRevokesOnlyOlderSessions(t *testing.T) {
+	user, password := randomUser(t)
+
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	// the user as the store has it, updated by the password change
+	current := user
+	store := mockdb.NewMockStore(ctrl)
+	store.EXPECT().
+		GetUser(gomock.Any(), gomock.Eq(user.Username)).
+		AnyTimes().
+		DoAndReturn(func(_ context.Context, _ string) (db.User, error) { return current, nil })
+	store.EXPECT().
+		UpdateUserPassword(gomock.Any(), gomock.Any()).
+		Times(1).
+		DoAndReturn(func(_ context.Context, arg db.UpdateUserPasswordParams) (db.User, error) {
+			require.WithinDuration(t, time.Now(), arg.PasswordChangedAt, time.Second)
+			current.HashedPassword = arg.HashedPassword
+			current.PasswordChangedAt = arg.PasswordChangedAt
+			return current, nil
+		})
+	store.EXPECT().ListAPIKeys(gomock.Any(), gomock.Any()).AnyTimes().Return([]db.ApiKey{}, nil)
+
+	svr := newTestServer(t, store)
+	oldToken, err := svr.tokenMaker.CreateToken(user.Username, time.Minute)
+	require.NoError(t, err)
+
+	data, err := json.Marshal(gin.H{"current_password": password, "new_password": "n3w-secret"})
+	require.NoError(t, err)
+	req, err := http.NewRequest(http.MethodPost, "/users/password", bytes.NewReader(data))
+	require.NoError(t, err)
+	req.Header.Set(authorizationHeaderKey, authorizationTypeBearer+" "+oldToken)
+	rec := httptest.NewRecorder()
+	svr.router.ServeHTTP(rec, req)
+	require.Equal(t, http.StatusOK, rec.Code)
+
+	var rsp loginUserResponse
+	require.NoError(t, json.Unmarshal(rec.Body.Bytes(), &rsp))
+
+	// the token handed out with the change works, the one used to make it does not
+	for tok, status := range map[string]int{rsp.AccessToken: http.StatusOK, oldToken: http.StatusUnauthorized} {
+		req, err := http.NewRequest(http.MethodGet, "/api_keys", nil)
+		require.NoError(t, err)
+		req.Header.Set(authorizationHeaderKey, authorizationTypeBearer+" "+tok)
+		rec := httptest.NewRecorder()
+		svr.router.ServeHTTP(rec, req)
+		require.Equal(t, status, rec.Code)
+	}
+}
@@ -1,7 +1,10 @@
 package api
 
 import (
+	"gobank/token"
 	"gobank/util"
+	"gobank/webhook"
+	"strconv"
 
 	"github.com/go-playground/validator/v10"
 )
@@ -12,3 +15,42 @@ var validCurrency validator.Func = func(fl validator.FieldLevel) bool {
 	}
 	return false
 }
+
+var validAccountNumber validator.Func = func(fl validator.FieldLevel) bool {
+	if number, ok := fl.Field().Interface().(string); ok {
+		return util.IsValidAccountNumber(number)
+	}
+	return false
+}
+
+// validAccountRef accepts either an account ID or an account number.
+var validAccountRef validator.Func = func(fl validator.FieldLevel) bool {
+	if ref, ok := fl.Field().Interface().(string); ok {
+		if id, err := strconv.ParseInt(ref, 10, 64); err == nil {
+			return id > 0
+		}
+		return util.IsValidAccountNumber(ref)
+	}
+	return false
+}
+
+var validWebhookEvent validator.Func = func(fl validator.FieldLevel) bool {
+	if eventType, ok := fl.Field().Interface().(string); ok {
+		return webhook.IsSupportedEventType(eventType)
+	}
+	return false
+}
+
+var validScope validator.Func = func(fl validator.FieldLevel) bool {
+	if scope, ok := fl.Field().Interface().(string); ok {
+		return token.IsSupportedScope(scope)
+	}
+	return false
+}
+
+var validConsentPermission validator.Func = func(fl validator.FieldLevel) bool {
+	if permission, ok := fl.Field().Interface().(string); ok {
+		return isSupportedConsentPermission(permission)
+	}
+	return false
+}
@@ -0,0 +1,204 @@
+package api
+
+import (
+	"database/sql"
+	"errors"
+	db "gobank/db/sqlc"
+	"gobank/token"
+	"gobank/webhook"
+	"net/http"
+	"time"
+
+	"github.com/gin-gonic/gin"
+)
+
+type createWebhookRequest struct {
+	URL        string   `json:"url" binding:"required,url"`
+	EventTypes []string `json:"event_types" binding:"required,min=1,dive,webhookevent"`
+}
+
+type webhookEndpointResponse struct {
+	ID         int64     `json:"id"`
+	URL        string    `json:"url"`
+	EventTypes []string  `json:"event_types"`
+	CreatedAt  time.Time `json:"created_at"`
+}
+
+// createWebhookResponse is the only time the signing secret is shown.
+type createWebhookResponse struct {
+	webhookEndpointResponse
+	Secret string `json:"secret"`
+}
+
+func newWebhookEndpointResponse(ep db.WebhookEndpoint) webhookEndpointResponse {
+	return webhookEndpointResponse{
+		ID:         ep.ID,
+		URL:        ep.Url,
+		EventTypes: ep.EventTypes,
+		CreatedAt:  ep.CreatedAt,
+	}
+}
+
+func (s *Server) createWebhook(ctx *gin.Context) {
+	var req createWebhookRequest
+	if err := ctx.ShouldBindJSON(&req); err != nil {
+		ctx.JSON(http.StatusBadRequest, errorResponse(err))
+		return
+	}
+	if err := webhook.CheckURL(ctx, s.resolver, req.URL); err != nil {
+		ctx.JSON(http.StatusBadRequest, errorResponse(err))
+		return
+	}
+
+	secret, err := webhook.NewSecret()
+	if err != nil {
+		ctx.JSON(http.StatusInternalServerError, errorResponse(err))
+		return
+	}
+
+	payload := ctx.MustGet(authorizationPayloadKey).(*token.Payload)
+	ep, err := s.store.CreateWebhookEndpoint(ctx, db.CreateWebhookEndpointParams{
+		Owner:      payload.Username,
+		Url:        req.URL,
+		EventTypes: req.EventTypes,
+		Secret:     secret,
+	})
+	if err != nil {
+		ctx.JSON(http.StatusInternalServerError, errorResponse(err))
+		return
+	}
+	ctx.JSON(http.StatusOK, createWebhookResponse{
+		webhookEndpointResponse: newWebhookEndpointResponse(ep),
+		Secret:                  ep.Secret,
+	})
+}
+
+func (s *Server) listWebhooks(ctx *gin.Context) {
+	payload := ctx.MustGet(authorizationPayloadKey).(*token.Payload)
+	endpoints, err := s.store.ListWebhookEndpoints(ctx, payload.Username)
+	if err != nil {
+		ctx.JSON(http.StatusInternalServerError, errorResponse(err))
+		return
+	}
+
+	res := make([]webhookEndpointResponse, 0, len(endpoints))
+	for _, ep := range endpoints {
+		res = append(res, newWebhookEndpointResponse(ep))
+	}
+	ctx.JSON(http.StatusOK, res)
+}
+
+type webhookRequest struct {
+	ID int64 `uri:"id" binding:"required,min=1"`
+}
+
+func (s *Server) deleteWebhook(ctx *gin.Context) {
+	var req webhookRequest
+	if err := ctx.ShouldBindUri(&req); err != nil {
+		ctx.JSON(http.StatusBadRequest, errorResponse(err))
+		return
+	}
+	ep, ok := s.ownedWebhookEndpoint(ctx, req.ID)
+	if !ok {
+		return
+	}
+
+	if err := s.store.DeleteWebhookEndpoint(ctx, ep.ID); err != nil {
+		ctx.JSON(http.StatusInternalServerError, errorResponse(err))
+		return
+	}
+	ctx.JSON(http.StatusOK, newWebhookEndpointResponse(ep))
+}
+
+type listWebhookDeliveriesRequest struct {
+	PageID   int32 `form:"page_id" binding:"required,min=1"`
+	PageSize int32 `form:"page_size" binding:"required,min=5,max=50"`
+}
+
+func (s *Server) listWebhookDeliveries(ctx *gin.Context) {
+	var uri webhookRequest
+	if err := ctx.ShouldBindUri(&uri); err != nil {
+		ctx.JSON(http.StatusBadRequest, errorResponse(err))
+		return
+	}
+	var req listWebhookDeliveriesRequest
+	if err := ctx.ShouldBindQuery(&req); err != nil {
+		ctx.JSON(http.StatusBadRequest, errorResponse(err))
+		return
+	}
+	ep, ok := s.ownedWebhookEndpoint(ctx, uri.ID)
+	if !ok {
+		return
+	}
+
+	deliveries, err := s.store.ListWebhookDeliveries(ctx, db.ListWebhookDeliveriesParams{
+		EndpointID: ep.ID,
+		Limit:      req.PageSize,
+		Offset:     (req.PageID - 1) * req.PageSize,
+	})
+	if err != nil {
+		ctx.JSON(http.StatusInternalServerError, errorResponse(err))
+		return
+	}
+	ctx.JSON(http.StatusOK, deliveries)
+}
+
+type redeliverWebhookRequest struct {
+	ID         int64 `uri:"id" binding:"required,min=1"`
+	DeliveryID int64 `uri:"delivery_id" binding:"required,min=1"`
+}
+
+// redeliverWebhook queues a delivery to be sent again straight away with a fresh set of retries.
+func (s *Server) redeliverWebhook(ctx *gin.Context) {
+	var req redeliverWebhookRequest
+	if err := ctx.ShouldBindUri(&req); err != nil {
+		ctx.JSON(http.StatusBadRequest, errorResponse(err))
+		return
+	}
+	ep, ok := s.ownedWebhookEndpoint(ctx, req.ID)
+	if !ok {
+		return
+	}
+
+	d, err := s.store.GetWebhookDelivery(ctx, req.DeliveryID)
+	if err != nil {
+		if err == sql.ErrNoRows {
+			ctx.JSON(http.StatusNotFound, errorResponse(err))
+			return
+		}
+		ctx.JSON(http.StatusInternalServerError, errorResponse(err))
+		return
+	}
+	if d.EndpointID != ep.ID {
+		ctx.JSON(http.StatusNotFound, errorResponse(sql.ErrNoRows))
+		return
+	}
+
+	d, err = s.store.RequeueWebhookDelivery(ctx, d.ID)
+	if err != nil {
+		ctx.JSON(http.StatusInternalServerError, errorResponse(err))
+		return
+	}
+	ctx.JSON(http.StatusOK, d)
+}
+
+// ownedWebhookEndpoint loads an endpoint and checks it belongs to the authenticated user, writing the error response if not.
+func (s *Server) ownedWebhookEndpoint(ctx *gin.Context, id int64) (db.WebhookEndpoint, bool) {
+	ep, err := s.store.GetWebhookEndpoint(ctx, id)
+	if err != nil {
+		if err == sql.ErrNoRows {
+			ctx.JSON(http.StatusNotFound, errorResponse(err))
+			return ep, false
+		}
+		ctx.JSON(http.StatusInternalServerError, errorResponse(err))
+		return ep, false
+	}
+
+	payload := ctx.MustGet(authorizationPayloadKey).(*token.Payload)
+	if payload.Username != ep.Owner {
+		err := errors.New("webhook doesn't belong to authenticated user")
+		ctx.JSON(http.StatusUnauthorized, errorResponse(err))
+		return ep, false
+	}
+	return ep, true
+}
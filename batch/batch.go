@@ -0,0 +1,111 @@
+package batch
+
+import (
+	"bytes"
+	"fmt"
+	"gobank/util"
+	"io"
+	"io/ioutil"
+	"strconv"
+	"strings"
+)
+
+type Format string
+
+const (
+	FormatCSV     Format = "csv"
+	FormatPain001 Format = "pain001"
+)
+
+// Instruction is a single transfer requested by a payment file. Each of its accounts is given by ID or,
+// for files that name it by account number, by FromAccountNumber or ToAccountNumber.
+type Instruction struct {
+	Line              int    `json:"line"`
+	FromAccountID     int64  `json:"from_account_id"`
+	FromAccountNumber string `json:"from_account_number,omitempty"`
+	ToAccountID       int64  `json:"to_account_id"`
+	ToAccountNumber   string `json:"to_account_number,omitempty"`
+	Amount            int64  `json:"amount"`
+	Currency          string `json:"currency"`
+	Memo              string `json:"memo"`
+	EndToEndID        string `json:"end_to_end_id"`
+}
+
+// File is a parsed payment file. MessageID is the id the client gave the file, if its format has one
+// (MsgId in pain.001), so the same file is not executed twice.
+type File struct {
+	Format       Format
+	MessageID    string
+	Instructions []Instruction
+}
+
+// Parse reads every instruction in a payment file. An empty format is detected from the content.
+func Parse(r io.Reader, f Format) (File, error) {
+	data, err := ioutil.ReadAll(r)
+	if err != nil {
+		return File{Format: f}, err
+	}
+	if f == "" {
+		f = DetectFormat(data)
+	}
+
+	var file File
+	switch f {
+	case FormatCSV:
+		file, err = ParseCSV(bytes.NewReader(data))
+	case FormatPain001:
+		file, err = ParsePain001(bytes.NewReader(data))
+	default:
+		return File{Format: f}, fmt.Errorf("unsupported payment file format: %s", f)
+	}
+	if err != nil {
+		return File{Format: f}, err
+	}
+	if len(file.Instructions) == 0 {
+		return File{Format: f}, fmt.Errorf("payment file has no instructions")
+	}
+	return file, nil
+}
+
+// DetectFormat guesses the format of a payment file: anything that looks like XML is pain.001.
+func DetectFormat(data []byte) Format {
+	if bytes.HasPrefix(bytes.TrimSpace(data), []byte("<")) {
+		return FormatPain001
+	}
+	return FormatCSV
+}
+
+// parseAmount accepts whole amounts, optionally written with a zero fractional part ("10" or "10.00").
+func parseAmount(s string) (int64, error) {
+	s = strings.TrimSpace(s)
+	if i := strings.IndexByte(s, '.'); i >= 0 {
+		if strings.Trim(s[i+1:], "0") != "" {
+			return 0, fmt.Errorf("amount %q must be a whole number", s)
+		}
+		s = s[:i]
+	}
+	amount, err := strconv.ParseInt(s, 10, 64)
+	if err != nil {
+		return 0, fmt.Errorf("invalid amount %q", s)
+	}
+	if amount <= 0 {
+		return 0, fmt.Errorf("amount %d must be positive", amount)
+	}
+	return amount, nil
+}
+
+// parseAccountNumber accepts account numbers with valid check digits, written with spaces or in lowercase.
+func parseAccountNumber(s string) (string, error) {
+	if !util.IsValidAccountNumber(s) {
+		return "", fmt.Errorf("invalid account number %q", s)
+	}
+	return util.NormalizeAccountNumber(s), nil
+}
+
+func parseAccountID(s string) (int64, error) {
+	id, err := strconv.ParseInt(strings.TrimSpace(s), 10, 64)
+	if err != nil || id < 1 {
+		return 0, fmt.Errorf("invalid account id %q", s)
+	}
+	return id, nil
+}
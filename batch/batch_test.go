@@ -0,0 +1,167 @@
+package batch
+
+import (
+	"gobank/util"
+	"os"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestParsePain001(t *testing.T) {
+	f, err := os.Open("testdata/payments.xml")
+	require.NoError(t, err)
+	defer f.Close()
+
+	file, err := Parse(f, "")
+	require.NoError(t, err)
+	require.Equal(t, FormatPain001, file.Format)
+	require.Equal(t, "PAYROLL-2021-03", file.MessageID)
+	require.Equal(t, []Instruction{
+		{Line: 1, FromAccountID: 10, ToAccountID: 11, Amount: 1000, Currency: "USD", Memo: "March salary", EndToEndID: "SALARY-0001"},
+		{Line: 2, FromAccountID: 10, ToAccountID: 12, Amount: 500, Currency: "USD", EndToEndID: "SALARY-0002"},
+		{Line: 3, FromAccountID: 20, ToAccountID: 21, Amount: 250, Currency: "EUR", Memo: "Invoice 778", EndToEndID: "INV-778"},
+	}, file.Instructions)
+}
+
+func TestParseCSV(t *testing.T) {
+	f, err := os.Open("testdata/payments.csv")
+	require.NoError(t, err)
+	defer f.Close()
+
+	file, err := Parse(f, "")
+	require.NoError(t, err)
+	require.Equal(t, FormatCSV, file.Format)
+	require.Empty(t, file.MessageID)
+	require.Equal(t, []Instruction{
+		{Line: 2, FromAccountID: 10, ToAccountID: 11, Amount: 1000, Currency: "USD", Memo: "March salary"},
+		{Line: 3, FromAccountID: 10, ToAccountID: 12, Amount: 500, Currency: "USD"},
+		{Line: 4, FromAccountID: 20, ToAccountID: 21, Amount: 250, Currency: "EUR", Memo: "Invoice 778, final"},
+	}, file.Instructions)
+}
+
+func TestParseByAccountNumber(t *testing.T) {
+	number := util.RandomAccountNumber()
+	written := strings.ToLower(number[:4] + " " + number[4:])
+
+	csv := "from_account_id,to_account_number,amount,currency\n10," + written + ",100,USD\n"
+	file, err := Parse(strings.NewReader(csv), FormatCSV)
+	require.NoError(t, err)
+	require.Equal(t, []Instruction{
+		{Line: 2, FromAccountID: 10, ToAccountNumber: number, Amount: 100, Currency: "USD"},
+	}, file.Instructions)
+
+	pain := `<Document><CstmrCdtTrfInitn>
+		<PmtInf><DbtrAcct><Id><Othr><Id>10</Id></Othr></Id></DbtrAcct>
+		<CdtTrfTxInf><Amt><InstdAmt Ccy="USD">100</InstdAmt></Amt><CdtrAcct><Id><IBAN>` + written + `</IBAN></Id></CdtrAcct></CdtTrfTxInf>
+		</PmtInf></CstmrCdtTrfInitn></Document>`
+	file, err = Parse(strings.NewReader(pain), FormatPain001)
+	require.NoError(t, err)
+	require.Equal(t, []Instruction{
+		{Line: 1, FromAccountID: 10, ToAccountNumber: number, Amount: 100, Currency: "USD"},
+	}, file.Instructions)
+
+	csv = "from_account_number,to_account_id,amount,currency\n" + written + ",20,100,USD\n"
+	file, err = Parse(strings.NewReader(csv), FormatCSV)
+	require.NoError(t, err)
+	require.Equal(t, []Instruction{
+		{Line: 2, FromAccountNumber: number, ToAccountID: 20, Amount: 100, Currency: "USD"},
+	}, file.Instructions)
+
+	pain = `<Document><CstmrCdtTrfInitn>
+		<PmtInf><DbtrAcct><Id><IBAN>` + written + `</IBAN></Id></DbtrAcct>
+		<CdtTrfTxInf><Amt><InstdAmt Ccy="USD">100</InstdAmt></Amt><CdtrAcct><Id><Othr><Id>20</Id></Othr></Id></CdtrAcct></CdtTrfTxInf>
+		</PmtInf></CstmrCdtTrfInitn></Document>`
+	file, err = Parse(strings.NewReader(pain), FormatPain001)
+	require.NoError(t, err)
+	require.Equal(t, []Instruction{
+		{Line: 1, FromAccountNumber: number, ToAccountID: 20, Amount: 100, Currency: "USD"},
+	}, file.Instructions)
+}
+
+func TestParseErrors(t *testing.T) {
+	testCases := []struct {
+		name   string
+		format Format
+		input  string
+		errMsg string
+	}{
+		{
+			name:   "CSVWrongHeader",
+			format: FormatCSV,
+			input:  "from,to,amount\n1,2,3\n",
+			errMsg: "csv header must be",
+		},
+		{
+			name:   "CSVFractionalAmount",
+			format: FormatCSV,
+			input:  "from_account_id,to_account_id,amount,currency\n1,2,10.50,USD\n",
+			errMsg: "line 2: amount \"10.50\" must be a whole number",
+		},
+		{
+			name:   "CSVNegativeAmount",
+			format: FormatCSV,
+			input:  "from_account_id,to_account_id,amount,currency\n1,2,-5,USD\n",
+			errMsg: "line 2: amount -5 must be positive",
+		},
+		{
+			name:   "CSVBadAccount",
+			format: FormatCSV,
+			input:  "from_account_id,to_account_id,amount,currency\n1,abc,5,USD\n",
+			errMsg: "line 2: invalid account id \"abc\"",
+		},
+		{
+			name:   "CSVBadAccountNumber",
+			format: FormatCSV,
+			input:  "from_account_id,to_account_number,amount,currency\n1,GO00GBNK0000000000,5,USD\n",
+			errMsg: "line 2: invalid account number \"GO00GBNK0000000000\"",
+		},
+		{
+			name:   "CSVEmpty",
+			format: FormatCSV,
+			input:  "from_account_id,to_account_id,amount,currency\n",
+			errMsg: "payment file has no instructions",
+		},
+		{
+			name:   "Pain001Malformed",
+			format: FormatPain001,
+			input:  "<Document><CstmrCdtTrfInitn>",
+			errMsg: "cannot parse pain.001 document",
+		},
+		{
+			name:   "Pain001CountMismatch",
+			format: FormatPain001,
+			input: `<Document><CstmrCdtTrfInitn><GrpHdr><NbOfTxs>2</NbOfTxs></GrpHdr>
+				<PmtInf><DbtrAcct><Id><Othr><Id>1</Id></Othr></Id></DbtrAcct>
+				<CdtTrfTxInf><Amt><InstdAmt Ccy="USD">5</InstdAmt></Amt><CdtrAcct><Id><Othr><Id>2</Id></Othr></Id></CdtrAcct></CdtTrfTxInf>
+				</PmtInf></CstmrCdtTrfInitn></Document>`,
+			errMsg: "group header declares 2 transactions, document has 1",
+		},
+		{
+			name:   "Pain001ControlSumMismatch",
+			format: FormatPain001,
+			input: `<Document><CstmrCdtTrfInitn><GrpHdr><NbOfTxs>1</NbOfTxs><CtrlSum>50.00</CtrlSum></GrpHdr>
+				<PmtInf><DbtrAcct><Id><Othr><Id>1</Id></Othr></Id></DbtrAcct>
+				<CdtTrfTxInf><Amt><InstdAmt Ccy="USD">5</InstdAmt></Amt><CdtrAcct><Id><Othr><Id>2</Id></Othr></Id></CdtrAcct></CdtTrfTxInf>
+				</PmtInf></CstmrCdtTrfInitn></Document>`,
+			errMsg: "group header control sum is 50, transactions add up to 5",
+		},
+		{
+			name:   "UnsupportedFormat",
+			format: Format("mt940"),
+			input:  "anything",
+			errMsg: "unsupported payment file format: mt940",
+		},
+	}
+
+	for i := range testCases {
+		tc := testCases[i]
+
+		t.Run(tc.name, func(t *testing.T) {
+			_, err := Parse(strings.NewReader(tc.input), tc.format)
+			require.Error(t, err)
+			require.Contains(t, err.Error(), tc.errMsg)
+		})
+	}
+}
@@ -0,0 +1,82 @@
+package batch
+
+import (
+	"encoding/csv"
+	"fmt"
+	"io"
+	"strings"
+)
+
+// csvHeader is the template corporate clients fill in; memo is optional. Either account can be given by
+// number instead, with the column of csvNumberColumns in place of the account id one.
+var csvHeader = []string{"from_account_id", "to_account_id", "amount", "currency", "memo"}
+
+var csvNumberColumns = []string{"from_account_number", "to_account_number"}
+
+// ParseCSV reads instructions from a CSV file that starts with the template header.
+func ParseCSV(r io.Reader) (File, error) {
+	file := File{Format: FormatCSV}
+	cr := csv.NewReader(r)
+	cr.FieldsPerRecord = -1
+	cr.TrimLeadingSpace = true
+
+	header, err := cr.Read()
+	if err != nil {
+		return file, fmt.Errorf("cannot read csv header: %w", err)
+	}
+	if len(header) < len(csvHeader)-1 {
+		return file, fmt.Errorf("csv header must be %s", strings.Join(csvHeader, ","))
+	}
+	byNumber := make([]bool, len(csvNumberColumns))
+	for i, h := range header {
+		h = strings.ToLower(strings.TrimSpace(h))
+		if i < len(csvNumberColumns) && h == csvNumberColumns[i] {
+			byNumber[i] = true
+			continue
+		}
+		if i >= len(csvHeader) || h != csvHeader[i] {
+			return file, fmt.Errorf("csv header must be %s", strings.Join(csvHeader, ","))
+		}
+	}
+
+	for {
+		record, err := cr.Read()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			return file, err
+		}
+		line, _ := cr.FieldPos(0)
+
+		if len(record) < 4 || len(record) > len(header) {
+			return file, fmt.Errorf("line %d: expected %d fields, got %d", line, len(header), len(record))
+		}
+
+		in := Instruction{Line: line, Currency: strings.ToUpper(strings.TrimSpace(record[3]))}
+		if byNumber[0] {
+			in.FromAccountNumber, err = parseAccountNumber(record[0])
+		} else {
+			in.FromAccountID, err = parseAccountID(record[0])
+		}
+		if err != nil {
+			return file, fmt.Errorf("line %d: %w", line, err)
+		}
+		if byNumber[1] {
+			in.ToAccountNumber, err = parseAccountNumber(record[1])
+		} else {
+			in.ToAccountID, err = parseAccountID(record[1])
+		}
+		if err != nil {
+			return file, fmt.Errorf("line %d: %w", line, err)
+		}
+		if in.Amount, err = parseAmount(record[2]); err != nil {
+			return file, fmt.Errorf("line %d: %w", line, err)
+		}
+		if len(record) > 4 {
+			in.Memo = strings.TrimSpace(record[4])
+		}
+		file.Instructions = append(file.Instructions, in)
+	}
+	return file, nil
+}
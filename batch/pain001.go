@@ -0,0 +1,108 @@
+package batch
+
+import (
+	"encoding/xml"
+	"fmt"
+	"io"
+	"strconv"
+	"strings"
+)
+
+// ISO 20022 CustomerCreditTransferInitiation (pain.001.001.03), only the elements needed to move money between our accounts.
+type painDocument struct {
+	XMLName    xml.Name `xml:"Document"`
+	Initiation struct {
+		GroupHeader struct {
+			MsgID   string `xml:"MsgId"`
+			NbOfTxs string `xml:"NbOfTxs"`
+			CtrlSum string `xml:"CtrlSum"`
+		} `xml:"GrpHdr"`
+		PaymentInfos []painPaymentInfo `xml:"PmtInf"`
+	} `xml:"CstmrCdtTrfInitn"`
+}
+
+type painPaymentInfo struct {
+	PmtInfID      string            `xml:"PmtInfId"`
+	DebtorAccount string            `xml:"DbtrAcct>Id>Othr>Id"`
+	DebtorIBAN    string            `xml:"DbtrAcct>Id>IBAN"`
+	Transactions  []painTransaction `xml:"CdtTrfTxInf"`
+}
+
+type painTransaction struct {
+	EndToEndID string `xml:"PmtId>EndToEndId"`
+	Amount     struct {
+		Currency string `xml:"Ccy,attr"`
+		Value    string `xml:",chardata"`
+	} `xml:"Amt>InstdAmt"`
+	CreditorAccount string   `xml:"CdtrAcct>Id>Othr>Id"`
+	CreditorIBAN    string   `xml:"CdtrAcct>Id>IBAN"`
+	Remittance      []string `xml:"RmtInf>Ustrd"`
+}
+
+// ParsePain001 reads the credit transfers of a pain.001 document. Lines are numbered by transaction, in document order.
+// The transaction count and control sum of the group header must match the transactions.
+func ParsePain001(r io.Reader) (File, error) {
+	file := File{Format: FormatPain001}
+	var doc painDocument
+	if err := xml.NewDecoder(r).Decode(&doc); err != nil {
+		return file, fmt.Errorf("cannot parse pain.001 document: %w", err)
+	}
+	header := doc.Initiation.GroupHeader
+	file.MessageID = strings.TrimSpace(header.MsgID)
+
+	var instructions []Instruction
+	var sum int64
+	for _, pmt := range doc.Initiation.PaymentInfos {
+		//our account numbers follow the IBAN layout, so that is where an account named by number is
+		var from Instruction
+		var err error
+		if pmt.DebtorIBAN != "" {
+			from.FromAccountNumber, err = parseAccountNumber(pmt.DebtorIBAN)
+		} else {
+			from.FromAccountID, err = parseAccountID(pmt.DebtorAccount)
+		}
+		if err != nil {
+			return file, fmt.Errorf("payment %s: debtor account: %w", pmt.PmtInfID, err)
+		}
+
+		for _, tx := range pmt.Transactions {
+			line := len(instructions) + 1
+			in := Instruction{
+				Line:              line,
+				FromAccountID:     from.FromAccountID,
+				FromAccountNumber: from.FromAccountNumber,
+				Currency:          strings.ToUpper(strings.TrimSpace(tx.Amount.Currency)),
+				Memo:              strings.TrimSpace(strings.Join(tx.Remittance, " ")),
+				EndToEndID:        strings.TrimSpace(tx.EndToEndID),
+			}
+			if tx.CreditorIBAN != "" {
+				in.ToAccountNumber, err = parseAccountNumber(tx.CreditorIBAN)
+			} else {
+				in.ToAccountID, err = parseAccountID(tx.CreditorAccount)
+			}
+			if err != nil {
+				return file, fmt.Errorf("transaction %d: creditor account: %w", line, err)
+			}
+			if in.Amount, err = parseAmount(tx.Amount.Value); err != nil {
+				return file, fmt.Errorf("transaction %d: %w", line, err)
+			}
+			sum += in.Amount
+			instructions = append(instructions, in)
+		}
+	}
+
+	if n := header.NbOfTxs; n != "" && n != strconv.Itoa(len(instructions)) {
+		return file, fmt.Errorf("group header declares %s transactions, document has %d", n, len(instructions))
+	}
+	if header.CtrlSum != "" {
+		ctrlSum, err := parseAmount(header.CtrlSum)
+		if err != nil {
+			return file, fmt.Errorf("group header control sum: %w", err)
+		}
+		if ctrlSum != sum {
+			return file, fmt.Errorf("group header control sum is %d, transactions add up to %d", ctrlSum, sum)
+		}
+	}
+	file.Instructions = instructions
+	return file, nil
+}
@@ -0,0 +1,120 @@
+// Package bloom implements a Bloom filter that can be saved to and loaded from a file, for answering
+// "have we seen this string" over lists too large to keep in memory as they are.
+package bloom
+
+import (
+	"bufio"
+	"crypto/sha256"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"io"
+	"math"
+	"os"
+)
+
+// magic starts every filter file, followed by the number of bits and hash functions and then the bits.
+var magic = [8]byte{'G', 'B', 'B', 'L', 'O', 'O', 'M', '1'}
+
+var ErrInvalidFile = errors.New("not a bloom filter file")
+
+// maxBits keeps a corrupt header from allocating unbounded memory on load: 1<<33 bits is 1 GiB.
+const maxBits = 1 << 33
+
+type Filter struct {
+	bits []uint64
+	m    uint64 // number of bits
+	k    uint32 // number of hash functions
+}
+
+// New returns an empty filter sized for n entries with a false positive rate of about fpRate.
+func New(n uint64, fpRate float64) *Filter {
+	if n == 0 {
+		n = 1
+	}
+	m := uint64(math.Ceil(-float64(n) * math.Log(fpRate) / (math.Ln2 * math.Ln2)))
+	if m < 64 {
+		m = 64
+	}
+	k := uint32(math.Round(float64(m) / float64(n) * math.Ln2))
+	if k < 1 {
+		k = 1
+	}
+	return &Filter{bits: make([]uint64, (m+63)/64), m: m, k: k}
+}
+
+// locations derives the k bit positions of s by double hashing one sha256 sum.
+func (f *Filter) locations(s string) []uint64 {
+	sum := sha256.Sum256([]byte(s))
+	h1 := binary.BigEndian.Uint64(sum[0:8])
+	h2 := binary.BigEndian.Uint64(sum[8:16]) | 1
+
+	locs := make([]uint64, f.k)
+	for i := range locs {
+		locs[i] = (h1 + uint64(i)*h2) % f.m
+	}
+	return locs
+}
+
+func (f *Filter) Add(s string) {
+	for _, l := range f.locations(s) {
+		f.bits[l/64] |= 1 << (l % 64)
+	}
+}
+
+// Contains reports whether s may have been added. It is never wrong about strings that were added.
+func (f *Filter) Contains(s string) bool {
+	for _, l := range f.locations(s) {
+		if f.bits[l/64]&(1<<(l%64)) == 0 {
+			return false
+		}
+	}
+	return true
+}
+
+type header struct {
+	Magic [8]byte
+	M     uint64
+	K     uint32
+}
+
+const headerSize = 8 + 8 + 4
+
+func (f *Filter) WriteTo(w io.Writer) (int64, error) {
+	bw := bufio.NewWriter(w)
+	if err := binary.Write(bw, binary.BigEndian, header{Magic: magic, M: f.m, K: f.k}); err != nil {
+		return 0, err
+	}
+	if err := binary.Write(bw, binary.BigEndian, f.bits); err != nil {
+		return headerSize, err
+	}
+	return int64(headerSize + 8*len(f.bits)), bw.Flush()
+}
+
+// Read loads a filter written by WriteTo.
+func Read(r io.Reader) (*Filter, error) {
+	br := bufio.NewReader(r)
+	var h header
+	if err := binary.Read(br, binary.BigEndian, &h); err != nil {
+		return nil, fmt.Errorf("%w: %v", ErrInvalidFile, err)
+	}
+	if h.Magic != magic || h.M == 0 || h.M > maxBits || h.K == 0 {
+		return nil, ErrInvalidFile
+	}
+
+	f := &Filter{bits: make([]uint64, (h.M+63)/64), m: h.M, k: h.K}
+	if err := binary.Read(br, binary.BigEndian, f.bits); err != nil {
+		return nil, fmt.Errorf("%w: %v", ErrInvalidFile, err)
+	}
+	return f, nil
+}
+
+// Load reads the filter file at path.
+func Load(path string) (*Filter, error) {
+	file, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer file.Close()
+	return Read(file)
+}
@@ -0,0 +1,80 @@
+package bloom
+
+import (
+	"bytes"
+	"fmt"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestFilter(t *testing.T) {
+	f := New(1000, 0.001)
+	for i := 0; i < 1000; i++ {
+		f.Add(fmt.Sprintf("password%d", i))
+	}
+
+	for i := 0; i < 1000; i++ {
+		require.True(t, f.Contains(fmt.Sprintf("password%d", i)))
+	}
+
+	falsePositives := 0
+	for i := 0; i < 10000; i++ {
+		if f.Contains(fmt.Sprintf("other%d", i)) {
+			falsePositives++
+		}
+	}
+	// about 10 expected at 0.1%
+	require.Less(t, falsePositives, 50)
+}
+
+func TestWriteRead(t *testing.T) {
+	f := New(100, 0.01)
+	f.Add("hunter2")
+	f.Add("correcthorse")
+
+	var buf bytes.Buffer
+	n, err := f.WriteTo(&buf)
+	require.NoError(t, err)
+	require.Equal(t, int64(buf.Len()), n)
+
+	loaded, err := Read(&buf)
+	require.NoError(t, err)
+	require.Equal(t, f, loaded)
+	require.True(t, loaded.Contains("hunter2"))
+	require.False(t, loaded.Contains("n0t-in-the-list"))
+}
+
+func TestLoad(t *testing.T) {
+	f := New(10, 0.01)
+	f.Add("hunter2")
+
+	path := filepath.Join(t.TempDir(), "breached.bloom")
+	file, err := os.Create(path)
+	require.NoError(t, err)
+	_, err = f.WriteTo(file)
+	require.NoError(t, err)
+	require.NoError(t, file.Close())
+
+	loaded, err := Load(path)
+	require.NoError(t, err)
+	require.True(t, loaded.Contains("hunter2"))
+
+	_, err = Load(filepath.Join(t.TempDir(), "missing.bloom"))
+	require.ErrorIs(t, err, os.ErrNotExist)
+}
+
+func TestReadInvalid(t *testing.T) {
+	_, err := Read(bytes.NewReader([]byte("hunter2\npassword\n")))
+	require.ErrorIs(t, err, ErrInvalidFile)
+
+	// truncated bits
+	f := New(100, 0.01)
+	var buf bytes.Buffer
+	_, err = f.WriteTo(&buf)
+	require.NoError(t, err)
+	_, err = Read(bytes.NewReader(buf.Bytes()[:buf.Len()-1]))
+	require.ErrorIs(t, err, ErrInvalidFile)
+}
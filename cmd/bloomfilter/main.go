@@ -0,0 +1,63 @@
+// Command bloomfilter builds the breached password filter loaded through BREACHED_PASSWORDS_FILE
+// from a plain text list with one password per line.
+package main
+
+import (
+	"bufio"
+	"flag"
+	"gobank/bloom"
+	"log"
+	"os"
+)
+
+func main() {
+	in := flag.String("in", "", "password list, one per line")
+	out := flag.String("out", "breached.bloom", "filter file to write")
+	fpRate := flag.Float64("fp", 0.001, "false positive rate")
+	flag.Parse()
+
+	if *in == "" {
+		flag.Usage()
+		os.Exit(2)
+	}
+
+	//count first so the filter can be sized for the list
+	var n uint64
+	err := eachLine(*in, func(string) { n++ })
+	if err != nil {
+		log.Fatal("cannot read password list:", err)
+	}
+
+	f := bloom.New(n, *fpRate)
+	if err := eachLine(*in, f.Add); err != nil {
+		log.Fatal("cannot read password list:", err)
+	}
+
+	file, err := os.Create(*out)
+	if err != nil {
+		log.Fatal("cannot create filter file:", err)
+	}
+	if _, err := f.WriteTo(file); err != nil {
+		log.Fatal("cannot write filter file:", err)
+	}
+	if err := file.Close(); err != nil {
+		log.Fatal("cannot write filter file:", err)
+	}
+	log.Printf("wrote %d passwords to %s", n, *out)
+}
+
+func eachLine(path string, fn func(string)) error {
+	file, err := os.Open(path)
+	if err != nil {
+		return err
+	}
+	defer file.Close()
+
+	scanner := bufio.NewScanner(file)
+	for scanner.Scan() {
+		if line := scanner.Text(); line != "" {
+			fn(line)
+		}
+	}
+	return scanner.Err()
+}
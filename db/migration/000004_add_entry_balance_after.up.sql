@@ -0,0 +1,23 @@
+ALTER TABLE "entries" ADD COLUMN "balance_after" bigint NOT NULL DEFAULT 0;
+
+-- walk back from the current balance so existing entries agree with accounts.balance
+UPDATE "entries" e
+SET "balance_after" = r."balance_after"
+FROM (
+  SELECT
+    en."id",
+    a."balance" - COALESCE(SUM(en."amount") OVER (
+      PARTITION BY en."account_id"
+      ORDER BY en."created_at" DESC, en."id" DESC
+      ROWS BETWEEN UNBOUNDED PRECEDING AND 1 PRECEDING
+    ), 0) AS "balance_after"
+  FROM "entries" en
+  JOIN "accounts" a ON a."id" = en."account_id"
+) r
+WHERE e."id" = r."id";
+
+ALTER TABLE "entries" ALTER COLUMN "balance_after" DROP DEFAULT;
+
+CREATE INDEX "entries_account_id_created_at_idx" ON "entries" ("account_id", "created_at", "id");
+
+COMMENT ON COLUMN "entries"."balance_after" IS 'account balance right after this entry was applied';
@@ -0,0 +1,3 @@
+ALTER TABLE "transfers" ADD COLUMN "memo" varchar NOT NULL DEFAULT '';
+
+COMMENT ON COLUMN "transfers"."memo" IS 'free text shown on both parties statements';
@@ -0,0 +1,2 @@
+DROP TABLE IF EXISTS "payment_batch_items";
+DROP TABLE IF EXISTS "payment_batches";
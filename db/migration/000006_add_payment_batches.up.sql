@@ -0,0 +1,39 @@
+CREATE TABLE "payment_batches" (
+  "id" bigserial PRIMARY KEY,
+  "owner" varchar NOT NULL,
+  "source_format" varchar NOT NULL,
+  "status" varchar NOT NULL DEFAULT 'pending',
+  "item_count" int NOT NULL,
+  "created_at" timestamptz NOT NULL DEFAULT (now()),
+  "executed_at" timestamptz
+);
+
+CREATE TABLE "payment_batch_items" (
+  "id" bigserial PRIMARY KEY,
+  "batch_id" bigint NOT NULL,
+  "line" int NOT NULL,
+  "from_account_id" bigint NOT NULL,
+  "to_account_id" bigint NOT NULL,
+  "amount" bigint NOT NULL,
+  "currency" varchar NOT NULL,
+  "memo" varchar NOT NULL DEFAULT '',
+  "end_to_end_id" varchar NOT NULL DEFAULT '',
+  "status" varchar NOT NULL DEFAULT 'pending',
+  "error" varchar NOT NULL DEFAULT '',
+  "transfer_id" bigint,
+  "created_at" timestamptz NOT NULL DEFAULT (now())
+);
+
+ALTER TABLE "payment_batches" ADD FOREIGN KEY ("owner") REFERENCES "users" ("username");
+
+ALTER TABLE "payment_batch_items" ADD FOREIGN KEY ("batch_id") REFERENCES "payment_batches" ("id");
+
+ALTER TABLE "payment_batch_items" ADD FOREIGN KEY ("transfer_id") REFERENCES "transfers" ("id");
+
+CREATE INDEX ON "payment_batches" ("owner");
+
+CREATE INDEX ON "payment_batch_items" ("batch_id");
+
+COMMENT ON COLUMN "payment_batches"."status" IS 'pending, completed, partially_failed or failed';
+
+COMMENT ON COLUMN "payment_batch_items"."status" IS 'pending, completed or failed';
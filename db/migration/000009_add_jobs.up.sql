@@ -0,0 +1,19 @@
+CREATE TABLE "jobs" (
+  "id" bigserial PRIMARY KEY,
+  "type" varchar NOT NULL,
+  "payload" jsonb NOT NULL,
+  "status" varchar NOT NULL DEFAULT 'pending',
+  "attempts" int NOT NULL DEFAULT 0,
+  "max_attempts" int NOT NULL,
+  "last_error" varchar NOT NULL DEFAULT '',
+  "run_at" timestamptz NOT NULL DEFAULT (now()),
+  "locked_at" timestamptz,
+  "completed_at" timestamptz,
+  "created_at" timestamptz NOT NULL DEFAULT (now())
+);
+
+CREATE INDEX ON "jobs" ("run_at", "id") WHERE "status" = 'pending';
+
+CREATE INDEX ON "jobs" ("status");
+
+COMMENT ON COLUMN "jobs"."status" IS 'pending, running, completed or dead';
@@ -0,0 +1,25 @@
+CREATE TABLE "totp_enrollments" (
+  "username" varchar PRIMARY KEY,
+  "secret" varchar NOT NULL,
+  "last_used_step" bigint NOT NULL DEFAULT 0,
+  "confirmed_at" timestamptz,
+  "created_at" timestamptz NOT NULL DEFAULT (now())
+);
+
+CREATE TABLE "recovery_codes" (
+  "id" bigserial PRIMARY KEY,
+  "username" varchar NOT NULL,
+  "code_hash" varchar NOT NULL,
+  "used_at" timestamptz,
+  "created_at" timestamptz NOT NULL DEFAULT (now())
+);
+
+ALTER TABLE "totp_enrollments" ADD FOREIGN KEY ("username") REFERENCES "users" ("username");
+
+ALTER TABLE "recovery_codes" ADD FOREIGN KEY ("username") REFERENCES "users" ("username");
+
+CREATE UNIQUE INDEX ON "recovery_codes" ("username", "code_hash");
+
+COMMENT ON COLUMN "totp_enrollments"."last_used_step" IS 'time step of the last accepted code, so a code cannot be replayed';
+
+COMMENT ON COLUMN "totp_enrollments"."confirmed_at" IS 'null while enrollment waits for the first valid code';
@@ -0,0 +1,15 @@
+CREATE TABLE "login_throttles" (
+  "scope" varchar NOT NULL,
+  "subject" varchar NOT NULL,
+  "failures" int NOT NULL DEFAULT 0,
+  "lockouts" int NOT NULL DEFAULT 0,
+  "locked_until" timestamptz NOT NULL DEFAULT('0001-01-01 00:00:00Z'),
+  "last_failed_at" timestamptz NOT NULL DEFAULT('0001-01-01 00:00:00Z'),
+  PRIMARY KEY ("scope", "subject")
+);
+
+COMMENT ON COLUMN "login_throttles"."scope" IS 'user or ip';
+
+COMMENT ON COLUMN "login_throttles"."subject" IS 'the username or client ip that failed to log in';
+
+COMMENT ON COLUMN "login_throttles"."lockouts" IS 'lockouts in a row, each one twice as long as the one before';
@@ -0,0 +1,57 @@
+CREATE TABLE "oauth_clients" (
+  "id" varchar PRIMARY KEY,
+  "owner" varchar NOT NULL,
+  "name" varchar NOT NULL,
+  "secret_hash" varchar NOT NULL DEFAULT '',
+  "redirect_uris" varchar[] NOT NULL,
+  "scopes" varchar[] NOT NULL,
+  "created_at" timestamptz NOT NULL DEFAULT (now())
+);
+
+CREATE TABLE "oauth_consents" (
+  "username" varchar NOT NULL,
+  "client_id" varchar NOT NULL,
+  "scopes" varchar[] NOT NULL,
+  "created_at" timestamptz NOT NULL DEFAULT (now()),
+  "updated_at" timestamptz NOT NULL DEFAULT (now()),
+  PRIMARY KEY ("username", "client_id")
+);
+
+CREATE TABLE "oauth_authorization_codes" (
+  "code_hash" varchar PRIMARY KEY,
+  "client_id" varchar NOT NULL,
+  "username" varchar NOT NULL,
+  "redirect_uri" varchar NOT NULL,
+  "scopes" varchar[] NOT NULL,
+  "code_challenge" varchar NOT NULL,
+  "expires_at" timestamptz NOT NULL,
+  "used_at" timestamptz,
+  "created_at" timestamptz NOT NULL DEFAULT (now())
+);
+
+CREATE TABLE "oauth_revoked_tokens" (
+  "token_id" uuid PRIMARY KEY,
+  "expires_at" timestamptz NOT NULL
+);
+
+ALTER TABLE "oauth_clients" ADD FOREIGN KEY ("owner") REFERENCES "users" ("username");
+
+ALTER TABLE "oauth_consents" ADD FOREIGN KEY ("username") REFERENCES "users" ("username");
+
+ALTER TABLE "oauth_consents" ADD FOREIGN KEY ("client_id") REFERENCES "oauth_clients" ("id");
+
+ALTER TABLE "oauth_authorization_codes" ADD FOREIGN KEY ("client_id") REFERENCES "oauth_clients" ("id");
+
+ALTER TABLE "oauth_authorization_codes" ADD FOREIGN KEY ("username") REFERENCES "users" ("username");
+
+CREATE INDEX ON "oauth_clients" ("owner");
+
+CREATE INDEX ON "oauth_consents" ("client_id");
+
+COMMENT ON COLUMN "oauth_clients"."secret_hash" IS 'sha256 of the client secret; empty for public clients, which must use PKCE';
+
+COMMENT ON COLUMN "oauth_consents"."scopes" IS 'what the user allowed the client to do on their behalf';
+
+COMMENT ON COLUMN "oauth_authorization_codes"."code_challenge" IS 'PKCE S256 challenge the code verifier must match';
+
+COMMENT ON COLUMN "oauth_revoked_tokens"."expires_at" IS 'when the token would have expired anyway, after which the row can go';
@@ -0,0 +1,3 @@
+ALTER TABLE IF EXISTS "oauth_authorization_codes" DROP COLUMN IF EXISTS "consent_id";
+
+DROP TABLE IF EXISTS "account_consents";
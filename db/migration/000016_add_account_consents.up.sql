@@ -0,0 +1,30 @@
+CREATE TABLE "account_consents" (
+  "id" bigserial PRIMARY KEY,
+  "client_id" varchar NOT NULL,
+  "username" varchar,
+  "permissions" varchar[] NOT NULL,
+  "account_ids" bigint[] NOT NULL DEFAULT '{}',
+  "status" varchar NOT NULL DEFAULT 'awaiting_authorisation',
+  "expires_at" timestamptz NOT NULL,
+  "authorised_at" timestamptz,
+  "revoked_at" timestamptz,
+  "created_at" timestamptz NOT NULL DEFAULT (now())
+);
+
+ALTER TABLE "account_consents" ADD FOREIGN KEY ("client_id") REFERENCES "oauth_clients" ("id");
+
+ALTER TABLE "account_consents" ADD FOREIGN KEY ("username") REFERENCES "users" ("username");
+
+CREATE INDEX ON "account_consents" ("username");
+
+ALTER TABLE "oauth_authorization_codes" ADD COLUMN "consent_id" bigint;
+
+ALTER TABLE "oauth_authorization_codes" ADD FOREIGN KEY ("consent_id") REFERENCES "account_consents" ("id");
+
+COMMENT ON COLUMN "account_consents"."username" IS 'the user who authorised the consent, unknown until then';
+
+COMMENT ON COLUMN "account_consents"."account_ids" IS 'accounts the user chose to share with the app';
+
+COMMENT ON COLUMN "account_consents"."status" IS 'awaiting_authorisation, authorised or revoked';
+
+COMMENT ON COLUMN "oauth_authorization_codes"."consent_id" IS 'account consent the tokens got for this code are bound to';
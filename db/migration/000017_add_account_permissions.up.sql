@@ -0,0 +1,18 @@
+CREATE TABLE "account_permissions" (
+  "account_id" bigint NOT NULL,
+  "username" varchar NOT NULL,
+  "access" varchar NOT NULL,
+  "granted_by" varchar NOT NULL,
+  "created_at" timestamptz NOT NULL DEFAULT (now()),
+  PRIMARY KEY ("account_id", "username")
+);
+
+ALTER TABLE "account_permissions" ADD FOREIGN KEY ("account_id") REFERENCES "accounts" ("id");
+
+ALTER TABLE "account_permissions" ADD FOREIGN KEY ("username") REFERENCES "users" ("username");
+
+ALTER TABLE "account_permissions" ADD FOREIGN KEY ("granted_by") REFERENCES "users" ("username");
+
+CREATE INDEX ON "account_permissions" ("username");
+
+COMMENT ON COLUMN "account_permissions"."access" IS 'read, or transfer which includes read';
@@ -0,0 +1,7 @@
+DROP TABLE IF EXISTS "transfer_draft_approvals";
+
+DROP TABLE IF EXISTS "transfer_drafts";
+
+ALTER TABLE IF EXISTS "accounts" DROP COLUMN IF EXISTS "joint_approval_threshold";
+
+DROP TABLE IF EXISTS "account_holders";
@@ -0,0 +1,61 @@
+CREATE TABLE "account_holders" (
+  "account_id" bigint NOT NULL,
+  "username" varchar NOT NULL,
+  "role" varchar NOT NULL,
+  "created_at" timestamptz NOT NULL DEFAULT (now()),
+  PRIMARY KEY ("account_id", "username")
+);
+
+ALTER TABLE "account_holders" ADD FOREIGN KEY ("account_id") REFERENCES "accounts" ("id");
+
+ALTER TABLE "account_holders" ADD FOREIGN KEY ("username") REFERENCES "users" ("username");
+
+CREATE INDEX ON "account_holders" ("username");
+
+INSERT INTO "account_holders" ("account_id", "username", "role")
+SELECT "id", "owner", 'primary' FROM "accounts";
+
+ALTER TABLE "accounts" ADD COLUMN "joint_approval_threshold" bigint NOT NULL DEFAULT 0;
+
+CREATE TABLE "transfer_drafts" (
+  "id" bigserial PRIMARY KEY,
+  "from_account_id" bigint NOT NULL,
+  "to_account_id" bigint NOT NULL,
+  "amount" bigint NOT NULL,
+  "memo" varchar NOT NULL DEFAULT '',
+  "created_by" varchar NOT NULL,
+  "required_approvals" int NOT NULL,
+  "status" varchar NOT NULL DEFAULT 'pending',
+  "transfer_id" bigint,
+  "decided_at" timestamptz,
+  "created_at" timestamptz NOT NULL DEFAULT (now())
+);
+
+ALTER TABLE "transfer_drafts" ADD FOREIGN KEY ("from_account_id") REFERENCES "accounts" ("id");
+
+ALTER TABLE "transfer_drafts" ADD FOREIGN KEY ("to_account_id") REFERENCES "accounts" ("id");
+
+ALTER TABLE "transfer_drafts" ADD FOREIGN KEY ("created_by") REFERENCES "users" ("username");
+
+ALTER TABLE "transfer_drafts" ADD FOREIGN KEY ("transfer_id") REFERENCES "transfers" ("id");
+
+CREATE INDEX ON "transfer_drafts" ("from_account_id");
+
+CREATE TABLE "transfer_draft_approvals" (
+  "draft_id" bigint NOT NULL,
+  "username" varchar NOT NULL,
+  "created_at" timestamptz NOT NULL DEFAULT (now()),
+  PRIMARY KEY ("draft_id", "username")
+);
+
+ALTER TABLE "transfer_draft_approvals" ADD FOREIGN KEY ("draft_id") REFERENCES "transfer_drafts" ("id");
+
+ALTER TABLE "transfer_draft_approvals" ADD FOREIGN KEY ("username") REFERENCES "users" ("username");
+
+COMMENT ON COLUMN "account_holders"."role" IS 'primary for the owner, who manages the account, or secondary';
+
+COMMENT ON COLUMN "accounts"."joint_approval_threshold" IS 'transfers of at least this amount from a joint account need every holder to approve, 0 for no rule';
+
+COMMENT ON COLUMN "transfer_drafts"."status" IS 'pending, executed or rejected';
+
+COMMENT ON COLUMN "transfer_drafts"."transfer_id" IS 'the transfer made once the draft was approved';
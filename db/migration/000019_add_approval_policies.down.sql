@@ -0,0 +1,3 @@
+ALTER TABLE IF EXISTS "transfer_drafts" DROP COLUMN IF EXISTS "decided_by";
+
+DROP TABLE IF EXISTS "approval_policies";
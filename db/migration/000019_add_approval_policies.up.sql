@@ -0,0 +1,23 @@
+CREATE TABLE "approval_policies" (
+  "id" bigserial PRIMARY KEY,
+  "account_id" bigint NOT NULL,
+  "min_amount" bigint NOT NULL DEFAULT 0,
+  "required_approvals" int NOT NULL,
+  "created_at" timestamptz NOT NULL DEFAULT (now())
+);
+
+ALTER TABLE "approval_policies" ADD FOREIGN KEY ("account_id") REFERENCES "accounts" ("id");
+
+CREATE UNIQUE INDEX ON "approval_policies" ("account_id", "min_amount");
+
+ALTER TABLE "transfer_drafts" ADD COLUMN "decided_by" varchar;
+
+ALTER TABLE "transfer_drafts" ADD FOREIGN KEY ("decided_by") REFERENCES "users" ("username");
+
+COMMENT ON COLUMN "approval_policies"."min_amount" IS 'the policy with the highest min_amount not above a transfer amount applies to it';
+
+COMMENT ON COLUMN "approval_policies"."required_approvals" IS 'approvals needed besides the one of the user who drafted the transfer';
+
+COMMENT ON COLUMN "transfer_drafts"."required_approvals" IS 'approvals needed, counting the one of created_by';
+
+COMMENT ON COLUMN "transfer_drafts"."decided_by" IS 'the user whose approval executed the draft, or who rejected it';
@@ -0,0 +1,13 @@
+ALTER TABLE "accounts" ADD COLUMN "number" varchar;
+
+-- existing accounts get random account digits, then the check digits of GO..GBNK + digits:
+-- G=16 B=11 N=23 K=20 O=24, so the rearranged number is 16112320 || digits || 1624 || 00
+UPDATE "accounts" SET "number" = lpad(floor(random() * 10000000000)::bigint::text, 10, '0');
+
+UPDATE "accounts" SET "number" = 'GO' || lpad((98 - ('16112320' || "number" || '162400')::numeric % 97)::text, 2, '0') || 'GBNK' || "number";
+
+ALTER TABLE "accounts" ALTER COLUMN "number" SET NOT NULL;
+
+ALTER TABLE "accounts" ADD CONSTRAINT "accounts_number_key" UNIQUE ("number");
+
+COMMENT ON COLUMN "accounts"."number" IS 'IBAN-style external account number with mod-97 check digits';
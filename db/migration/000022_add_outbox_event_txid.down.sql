@@ -0,0 +1 @@
+ALTER TABLE IF EXISTS "outbox_events" DROP COLUMN IF EXISTS "txid";
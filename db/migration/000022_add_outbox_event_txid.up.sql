@@ -0,0 +1,3 @@
+ALTER TABLE "outbox_events" ADD COLUMN "txid" bigint NOT NULL DEFAULT (txid_current());
+
+COMMENT ON COLUMN "outbox_events"."txid" IS 'transaction that wrote the event; the relay waits until every older transaction has ended';
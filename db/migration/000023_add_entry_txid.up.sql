@@ -0,0 +1,5 @@
+ALTER TABLE "entries" ADD COLUMN "txid" bigint NOT NULL DEFAULT (txid_current());
+
+CREATE INDEX ON "entries" ("account_id", "txid");
+
+COMMENT ON COLUMN "entries"."txid" IS 'transaction that wrote the entry; account update streams resume from a transaction id';
@@ -0,0 +1,2 @@
+-- the codes cannot be recovered from their hashes, so links sent before stay unusable
+COMMENT ON COLUMN "verify_emails"."secret_code" IS NULL;
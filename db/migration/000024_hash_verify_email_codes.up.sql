@@ -0,0 +1,6 @@
+-- codes are now minted by the mail job and only their sha256 is kept, like password reset tokens
+UPDATE "verify_emails"
+SET "secret_code" = encode(sha256(convert_to("secret_code", 'UTF8')), 'hex')
+WHERE "secret_code" <> '';
+
+COMMENT ON COLUMN "verify_emails"."secret_code" IS 'sha256 of the code in the emailed link, empty until the email is sent';
@@ -0,0 +1,5 @@
+DELETE FROM "password_reset_tokens" WHERE "token_hash" IS NULL;
+
+ALTER TABLE "password_reset_tokens" ALTER COLUMN "token_hash" SET NOT NULL;
+
+COMMENT ON COLUMN "password_reset_tokens"."token_hash" IS 'sha256 of the token, which is only ever sent to the user';
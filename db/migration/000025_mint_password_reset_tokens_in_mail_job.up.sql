@@ -0,0 +1,9 @@
+-- the mail job mints the token as it sends it, so there is no hash until then and the plain token is never
+-- written to the jobs table
+ALTER TABLE "password_reset_tokens" ALTER COLUMN "token_hash" DROP NOT NULL;
+
+UPDATE "jobs"
+SET "payload" = "payload" - 'token'
+WHERE "type" = 'send_password_reset';
+
+COMMENT ON COLUMN "password_reset_tokens"."token_hash" IS 'sha256 of the token, which is only ever sent to the user; null until it is';
@@ -0,0 +1,2 @@
+-- balances and statements follow entry ids rather than created_at, so they need the ids of an account in order
+CREATE INDEX "entries_account_id_id_idx" ON "entries" ("account_id", "id") INCLUDE ("created_at", "balance_after");
@@ -0,0 +1,2 @@
+DROP INDEX IF EXISTS "payment_batches_owner_message_id_key";
+ALTER TABLE IF EXISTS "payment_batches" DROP COLUMN IF EXISTS "message_id";
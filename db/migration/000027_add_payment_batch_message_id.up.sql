@@ -0,0 +1,6 @@
+ALTER TABLE "payment_batches" ADD COLUMN "message_id" varchar NOT NULL DEFAULT '';
+
+-- a client that uploads the same file twice must not pay everyone twice
+CREATE UNIQUE INDEX "payment_batches_owner_message_id_key" ON "payment_batches" ("owner", "message_id") WHERE "message_id" <> '';
+
+COMMENT ON COLUMN "payment_batches"."message_id" IS 'id the client gave the payment file (pain.001 MsgId), empty if the format has none';
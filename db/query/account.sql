@@ -2,15 +2,20 @@
 INSERT INTO accounts (
   owner,
   balance,
-  currency
+  currency,
+  number
 ) VALUES (
-  $1, $2, $3
+  $1, $2, $3, $4
 ) RETURNING *;
 
 -- name: GetAccount :one
 SELECT * FROM accounts
 WHERE id = $1 LIMIT 1;
 
+-- name: GetAccountByNumber :one
+SELECT * FROM accounts
+WHERE number = $1 LIMIT 1;
+
 -- name: GetAccountForUpdate :one
 SELECT * FROM accounts
 WHERE id = $1 LIMIT 1
@@ -41,3 +46,19 @@ RETURNING *;
 -- name: DeleteAccount :exec
 DELETE FROM accounts
 WHERE id = $1;
+
+-- name: ListHolderAccounts :many
+SELECT * FROM accounts
+WHERE id IN (
+  SELECT account_id FROM account_holders
+  WHERE username = sqlc.arg(username)
+)
+ORDER BY id
+LIMIT $2
+OFFSET $3;
+
+-- name: UpdateAccountApprovalThreshold :one
+UPDATE accounts
+SET joint_approval_threshold = $2
+WHERE id = $1
+RETURNING *;
@@ -0,0 +1,29 @@
+-- name: CreateAccountConsent :one
+INSERT INTO account_consents (
+  client_id,
+  permissions,
+  expires_at
+) VALUES (
+  $1, $2, $3
+) RETURNING *;
+
+-- name: GetAccountConsent :one
+SELECT * FROM account_consents
+WHERE id = $1 LIMIT 1;
+
+-- name: ListAccountConsents :many
+SELECT * FROM account_consents
+WHERE username = $1
+ORDER BY id;
+
+-- name: AuthoriseAccountConsent :one
+UPDATE account_consents
+SET username = $2, account_ids = $3, status = 'authorised', authorised_at = now()
+WHERE id = $1 AND status = 'awaiting_authorisation' AND expires_at > now()
+RETURNING *;
+
+-- name: RevokeAccountConsent :one
+UPDATE account_consents
+SET status = 'revoked', revoked_at = now()
+WHERE id = $1 AND status <> 'revoked'
+RETURNING *;
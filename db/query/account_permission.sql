@@ -0,0 +1,37 @@
+-- name: UpsertAccountPermission :one
+INSERT INTO account_permissions (
+  account_id,
+  username,
+  access,
+  granted_by
+) VALUES (
+  $1, $2, $3, $4
+)
+ON CONFLICT (account_id, username) DO UPDATE
+SET access = EXCLUDED.access, granted_by = EXCLUDED.granted_by
+RETURNING *;
+
+-- name: GetAccountPermission :one
+SELECT * FROM account_permissions
+WHERE account_id = $1 AND username = $2 LIMIT 1;
+
+-- name: ListAccountPermissions :many
+SELECT * FROM account_permissions
+WHERE account_id = $1
+ORDER BY created_at;
+
+-- name: DeleteAccountPermission :execrows
+DELETE FROM account_permissions
+WHERE account_id = $1 AND username = $2;
+
+-- name: ListAccessibleAccountIDs :many
+-- The accounts checkAccountAccess lets username read: those it owns or holds, and those it was granted.
+SELECT id AS account_id FROM accounts
+WHERE accounts.owner = sqlc.arg(username)
+UNION
+SELECT account_id FROM account_holders
+WHERE account_holders.username = sqlc.arg(username)
+UNION
+SELECT account_id FROM account_permissions
+WHERE account_permissions.username = sqlc.arg(username)
+ORDER BY account_id;
@@ -0,0 +1,18 @@
+-- name: NotifyAccountUpdate :exec
+SELECT pg_notify(sqlc.arg(channel)::text, sqlc.arg(payload)::text);
+
+-- name: ListAccountUpdatesSince :many
+-- Entry ids are handed out before commit, so they are not a safe place to resume from: streams page through
+-- the entries written by transactions from_txid <= txid < to_txid instead.
+SELECT e.id, e.account_id, a.owner, e.amount, e.balance_after, e.transfer_id, e.created_at, e.txid
+FROM entries e
+JOIN accounts a ON a.id = e.account_id
+WHERE e.account_id = ANY(sqlc.arg(account_ids)::bigint[])
+  AND e.txid >= sqlc.arg(from_txid) AND e.txid < sqlc.arg(to_txid)
+  AND e.id > sqlc.arg(after_id)
+ORDER BY e.id
+LIMIT sqlc.arg(max_updates);
+
+-- name: GetOldestRunningTxid :one
+-- Every transaction with a lower id has committed or rolled back.
+SELECT txid_snapshot_xmin(txid_current_snapshot())::bigint AS txid;
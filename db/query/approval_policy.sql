@@ -0,0 +1,26 @@
+-- name: UpsertApprovalPolicy :one
+INSERT INTO approval_policies (
+  account_id,
+  min_amount,
+  required_approvals
+) VALUES (
+  $1, $2, $3
+)
+ON CONFLICT (account_id, min_amount) DO UPDATE
+SET required_approvals = EXCLUDED.required_approvals
+RETURNING *;
+
+-- name: ListApprovalPolicies :many
+SELECT * FROM approval_policies
+WHERE account_id = $1
+ORDER BY min_amount;
+
+-- name: GetApprovalPolicyForAmount :one
+SELECT * FROM approval_policies
+WHERE account_id = sqlc.arg(account_id) AND min_amount <= sqlc.arg(amount)
+ORDER BY min_amount DESC
+LIMIT 1;
+
+-- name: DeleteApprovalPolicy :execrows
+DELETE FROM approval_policies
+WHERE id = $1 AND account_id = $2;
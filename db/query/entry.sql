@@ -1,9 +1,11 @@
 -- name: CreateEntry :one
 INSERT INTO entries (
     account_id,
-    amount
+    amount,
+    transfer_id,
+    balance_after
 ) VALUES (
-    $1, $2
+    $1, $2, $3, $4
 ) RETURNING *;
 
 -- name: GetEntry :one
@@ -17,3 +19,33 @@ WHERE account_id = $1
 ORDER BY id
 LIMIT $2
 OFFSET $3;
+
+-- name: GetAccountBalanceAt :one
+-- created_at is the start of the writing transaction, so a transfer that waited on the account lock can carry an
+-- earlier created_at than one it followed. Entries are written under that lock, so ids follow the running balance.
+-- entries_account_id_id_idx walks the ids of the account backwards without visiting the table.
+SELECT balance_after FROM entries
+WHERE account_id = sqlc.arg(account_id) AND created_at <= sqlc.arg(at)
+ORDER BY id DESC
+LIMIT 1;
+
+-- name: ListStatementEntries :many
+-- Ordered by id for the same reason as GetAccountBalanceAt, which entries_account_id_id_idx serves.
+SELECT
+    e.id,
+    e.amount,
+    e.balance_after,
+    e.created_at,
+    COALESCE(e.transfer_id, 0)::bigint AS transfer_id,
+    COALESCE(ca.id, 0)::bigint AS counterparty_account_id,
+    COALESCE(ca.owner, '')::varchar AS counterparty_owner,
+    COALESCE(t.memo, '')::varchar AS memo
+FROM entries e
+LEFT JOIN transfers t ON t.id = e.transfer_id
+LEFT JOIN accounts ca ON ca.id = (
+    CASE WHEN t.from_account_id = e.account_id THEN t.to_account_id ELSE t.from_account_id END
+)
+WHERE e.account_id = sqlc.arg(account_id)
+  AND e.created_at >= sqlc.arg(from_time)
+  AND e.created_at < sqlc.arg(to_time)
+ORDER BY e.id;
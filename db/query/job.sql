@@ -0,0 +1,75 @@
+-- name: CreateJob :one
+INSERT INTO jobs (
+  type,
+  payload,
+  max_attempts
+) VALUES (
+  $1, $2, $3
+) RETURNING *;
+
+-- name: GetJob :one
+SELECT * FROM jobs
+WHERE id = $1 LIMIT 1;
+
+-- name: ListJobsByStatus :many
+SELECT * FROM jobs
+WHERE status = $1
+ORDER BY id
+LIMIT $2
+OFFSET $3;
+
+-- name: ClaimJobs :many
+UPDATE jobs
+SET status = 'running', attempts = attempts + 1, locked_at = now()
+WHERE id IN (
+  SELECT id FROM jobs
+  WHERE status = 'pending' AND run_at <= now()
+  ORDER BY run_at, id
+  LIMIT sqlc.arg(max_jobs)
+  FOR UPDATE SKIP LOCKED
+)
+RETURNING *;
+
+-- name: CompleteJob :exec
+UPDATE jobs
+SET status = 'completed', locked_at = NULL, completed_at = now()
+WHERE id = $1;
+
+-- name: RetryJob :exec
+UPDATE jobs
+SET status = 'pending', locked_at = NULL, last_error = $2, run_at = $3
+WHERE id = $1;
+
+-- name: BuryJob :exec
+UPDATE jobs
+SET status = 'dead', locked_at = NULL, last_error = $2
+WHERE id = $1;
+
+-- name: RequeueJob :one
+UPDATE jobs
+SET status = 'pending', attempts = 0, run_at = now()
+WHERE id = $1 AND status = 'dead'
+RETURNING *;
+
+-- name: RequeueStuckJobs :execrows
+-- Claiming a job counted the attempt its worker never finished, so a job that keeps taking its worker down
+-- is dead-lettered once it has used up its attempts rather than claimed again forever.
+UPDATE jobs
+SET status = CASE WHEN attempts >= max_attempts THEN 'dead' ELSE 'pending' END,
+  locked_at = NULL,
+  last_error = 'worker stopped before finishing the job'
+WHERE status = 'running' AND locked_at < sqlc.arg(locked_before)::timestamptz;
+
+-- name: ScheduleJob :execrows
+-- Queues a job of a repeating type unless one is already pending or running, so it runs one at a time
+-- however many workers schedule it.
+INSERT INTO jobs (
+  type,
+  payload,
+  max_attempts
+)
+SELECT sqlc.arg(type)::varchar, '{}'::jsonb, sqlc.arg(max_attempts)::int
+WHERE NOT EXISTS (
+  SELECT 1 FROM jobs
+  WHERE type = sqlc.arg(type)::varchar AND status IN ('pending', 'running')
+);
@@ -0,0 +1,33 @@
+-- name: GetLoginThrottle :one
+SELECT * FROM login_throttles
+WHERE scope = $1 AND subject = $2 LIMIT 1;
+
+-- name: CreateLoginThrottleIfMissing :exec
+INSERT INTO login_throttles (
+  scope,
+  subject
+) VALUES (
+  $1, $2
+)
+ON CONFLICT (scope, subject) DO NOTHING;
+
+-- name: GetLoginThrottleForUpdate :one
+SELECT * FROM login_throttles
+WHERE scope = $1 AND subject = $2 LIMIT 1
+FOR UPDATE;
+
+-- name: UpdateLoginThrottle :one
+UPDATE login_throttles
+SET failures = $3, lockouts = $4, locked_until = $5, last_failed_at = $6
+WHERE scope = $1 AND subject = $2
+RETURNING *;
+
+-- name: DeleteLoginThrottle :execrows
+DELETE FROM login_throttles
+WHERE scope = $1 AND subject = $2;
+
+-- name: RefundLoginAttempt :exec
+-- RefundLoginAttempt takes back an attempt that turned out to have the right credentials.
+UPDATE login_throttles
+SET failures = GREATEST(failures - 1, 0)
+WHERE scope = $1 AND subject = $2;
@@ -0,0 +1,83 @@
+-- name: CreateOAuthClient :one
+INSERT INTO oauth_clients (
+  id,
+  owner,
+  name,
+  secret_hash,
+  redirect_uris,
+  scopes
+) VALUES (
+  $1, $2, $3, $4, $5, $6
+) RETURNING *;
+
+-- name: GetOAuthClient :one
+SELECT * FROM oauth_clients
+WHERE id = $1 LIMIT 1;
+
+-- name: ListOAuthClients :many
+SELECT * FROM oauth_clients
+WHERE owner = $1
+ORDER BY created_at;
+
+-- name: UpsertOAuthConsent :one
+INSERT INTO oauth_consents (
+  username,
+  client_id,
+  scopes
+) VALUES (
+  $1, $2, $3
+)
+ON CONFLICT (username, client_id) DO UPDATE
+SET scopes = EXCLUDED.scopes, updated_at = now()
+RETURNING *;
+
+-- name: GetOAuthConsent :one
+SELECT * FROM oauth_consents
+WHERE username = $1 AND client_id = $2 LIMIT 1;
+
+-- name: ListOAuthConsents :many
+SELECT * FROM oauth_consents
+WHERE username = $1
+ORDER BY created_at;
+
+-- name: DeleteOAuthConsent :execrows
+DELETE FROM oauth_consents
+WHERE username = $1 AND client_id = $2;
+
+-- name: CreateOAuthAuthorizationCode :exec
+INSERT INTO oauth_authorization_codes (
+  code_hash,
+  client_id,
+  username,
+  redirect_uri,
+  scopes,
+  code_challenge,
+  expires_at,
+  consent_id
+) VALUES (
+  $1, $2, $3, $4, $5, $6, $7, $8
+);
+
+-- name: UseOAuthAuthorizationCode :one
+UPDATE oauth_authorization_codes
+SET used_at = now()
+WHERE code_hash = $1 AND used_at IS NULL AND expires_at > now()
+RETURNING *;
+
+-- name: RevokeOAuthToken :exec
+INSERT INTO oauth_revoked_tokens (
+  token_id,
+  expires_at
+) VALUES (
+  $1, $2
+)
+ON CONFLICT (token_id) DO NOTHING;
+
+-- name: GetRevokedOAuthToken :one
+SELECT * FROM oauth_revoked_tokens
+WHERE token_id = $1 LIMIT 1;
+
+-- name: DeleteExpiredRevokedOAuthTokens :execrows
+-- A revoked token that has expired is refused anyway, so its row is no longer needed.
+DELETE FROM oauth_revoked_tokens
+WHERE expires_at < now();
@@ -0,0 +1,24 @@
+-- name: CreateOutboxEvent :one
+INSERT INTO outbox_events (
+  aggregate_type,
+  aggregate_id,
+  event_type,
+  payload
+) VALUES (
+  $1, $2, $3, $4
+) RETURNING *;
+
+-- name: ListUnpublishedOutboxEvents :many
+-- ids are handed out before commit, so a transaction still running may yet commit an event with a lower id
+-- than one already visible. Only events written before the oldest running transaction began are listed.
+SELECT * FROM outbox_events
+WHERE published_at IS NULL
+  AND txid < txid_snapshot_xmin(txid_current_snapshot())
+ORDER BY id
+LIMIT $1
+FOR UPDATE;
+
+-- name: MarkOutboxEventPublished :exec
+UPDATE outbox_events
+SET published_at = now()
+WHERE id = $1;
@@ -0,0 +1,47 @@
+-- name: CreatePasswordResetToken :one
+INSERT INTO password_reset_tokens (
+  username,
+  token_hash,
+  expired_at
+) VALUES (
+  $1, $2, $3
+) RETURNING *;
+
+-- name: GetPasswordResetToken :one
+SELECT * FROM password_reset_tokens
+WHERE id = $1 LIMIT 1;
+
+-- name: GetLatestPasswordResetToken :one
+SELECT * FROM password_reset_tokens
+WHERE username = $1
+ORDER BY id DESC
+LIMIT 1;
+
+-- name: GetValidPasswordResetToken :one
+SELECT * FROM password_reset_tokens
+WHERE token_hash = sqlc.arg(token_hash)::varchar
+  AND used_at IS NULL
+  AND expired_at > now()
+LIMIT 1;
+
+-- name: SetPasswordResetTokenHash :one
+-- The mail job mints the token as it sends it; a new token replaces the last.
+UPDATE password_reset_tokens
+SET token_hash = sqlc.arg(token_hash)::varchar
+WHERE id = sqlc.arg(id)
+  AND used_at IS NULL
+  AND expired_at > now()
+RETURNING *;
+
+-- name: UsePasswordResetToken :one
+UPDATE password_reset_tokens
+SET used_at = now()
+WHERE token_hash = sqlc.arg(token_hash)::varchar
+  AND used_at IS NULL
+  AND expired_at > now()
+RETURNING *;
+
+-- name: InvalidatePasswordResetTokens :exec
+UPDATE password_reset_tokens
+SET used_at = now()
+WHERE username = $1 AND used_at IS NULL;
@@ -0,0 +1,45 @@
+-- name: CreatePaymentBatch :one
+INSERT INTO payment_batches (
+    owner,
+    source_format,
+    item_count,
+    message_id
+) VALUES (
+    $1, $2, $3, $4
+) RETURNING *;
+
+-- name: GetPaymentBatch :one
+SELECT * FROM payment_batches
+WHERE id = $1
+LIMIT 1;
+
+-- name: UpdatePaymentBatchStatus :one
+UPDATE payment_batches
+SET status = $2, executed_at = now()
+WHERE id = $1
+RETURNING *;
+
+-- name: CreatePaymentBatchItem :one
+INSERT INTO payment_batch_items (
+    batch_id,
+    line,
+    from_account_id,
+    to_account_id,
+    amount,
+    currency,
+    memo,
+    end_to_end_id
+) VALUES (
+    $1, $2, $3, $4, $5, $6, $7, $8
+) RETURNING *;
+
+-- name: ListPaymentBatchItems :many
+SELECT * FROM payment_batch_items
+WHERE batch_id = $1
+ORDER BY line, id;
+
+-- name: UpdatePaymentBatchItem :one
+UPDATE payment_batch_items
+SET status = $2, error = $3, transfer_id = $4
+WHERE id = $1
+RETURNING *;
@@ -0,0 +1,28 @@
+-- name: ListAccountBalanceMismatches :many
+SELECT
+    a.id AS account_id,
+    a.balance,
+    COALESCE(SUM(e.amount), 0)::bigint AS entries_total
+FROM accounts a
+LEFT JOIN entries e ON e.account_id = a.id
+GROUP BY a.id
+HAVING a.balance <> COALESCE(SUM(e.amount), 0)
+ORDER BY a.id;
+
+-- name: ListTransferEntryMismatches :many
+SELECT
+    t.id AS transfer_id,
+    t.from_account_id,
+    t.to_account_id,
+    t.amount,
+    COUNT(e.id) AS entry_count,
+    COUNT(e.id) FILTER (WHERE e.account_id = t.from_account_id AND e.amount = -t.amount) AS debit_count,
+    COUNT(e.id) FILTER (WHERE e.account_id = t.to_account_id AND e.amount = t.amount) AS credit_count
+FROM transfers t
+LEFT JOIN entries e ON e.transfer_id = t.id
+GROUP BY t.id
+HAVING
+    COUNT(e.id) <> 2 OR
+    COUNT(e.id) FILTER (WHERE e.account_id = t.from_account_id AND e.amount = -t.amount) <> 1 OR
+    COUNT(e.id) FILTER (WHERE e.account_id = t.to_account_id AND e.amount = t.amount) <> 1
+ORDER BY t.id;
@@ -0,0 +1,43 @@
+-- name: StartTOTPEnrollment :one
+INSERT INTO totp_enrollments (
+  username,
+  secret
+) VALUES (
+  $1, $2
+)
+ON CONFLICT (username) DO UPDATE
+SET secret = EXCLUDED.secret, last_used_step = 0, created_at = now()
+WHERE totp_enrollments.confirmed_at IS NULL
+RETURNING *;
+
+-- name: GetTOTPEnrollment :one
+SELECT * FROM totp_enrollments
+WHERE username = $1 LIMIT 1;
+
+-- name: ConfirmTOTPEnrollment :one
+UPDATE totp_enrollments
+SET confirmed_at = now(), last_used_step = $2
+WHERE username = $1 AND confirmed_at IS NULL
+RETURNING *;
+
+-- name: UseTOTPStep :execrows
+UPDATE totp_enrollments
+SET last_used_step = sqlc.arg(step)
+WHERE username = sqlc.arg(username) AND last_used_step < sqlc.arg(step);
+
+-- name: CreateRecoveryCode :exec
+INSERT INTO recovery_codes (
+  username,
+  code_hash
+) VALUES (
+  $1, $2
+);
+
+-- name: DeleteRecoveryCodes :exec
+DELETE FROM recovery_codes
+WHERE username = $1;
+
+-- name: UseRecoveryCode :execrows
+UPDATE recovery_codes
+SET used_at = now()
+WHERE username = $1 AND code_hash = $2 AND used_at IS NULL;
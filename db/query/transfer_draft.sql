@@ -0,0 +1,53 @@
+-- name: CreateTransferDraft :one
+INSERT INTO transfer_drafts (
+  from_account_id,
+  to_account_id,
+  amount,
+  memo,
+  created_by,
+  required_approvals
+) VALUES (
+  $1, $2, $3, $4, $5, $6
+) RETURNING *;
+
+-- name: GetTransferDraft :one
+SELECT * FROM transfer_drafts
+WHERE id = $1 LIMIT 1;
+
+-- name: GetTransferDraftForUpdate :one
+SELECT * FROM transfer_drafts
+WHERE id = $1 LIMIT 1
+FOR UPDATE;
+
+-- name: ListTransferDrafts :many
+SELECT * FROM transfer_drafts
+WHERE from_account_id = $1
+ORDER BY id DESC
+LIMIT $2
+OFFSET $3;
+
+-- name: ExecuteTransferDraft :one
+UPDATE transfer_drafts
+SET status = 'executed', transfer_id = $2, decided_by = $3, decided_at = now()
+WHERE id = $1
+RETURNING *;
+
+-- name: RejectTransferDraft :one
+UPDATE transfer_drafts
+SET status = 'rejected', decided_by = $2, decided_at = now()
+WHERE id = $1 AND status = 'pending'
+RETURNING *;
+
+-- name: CreateTransferDraftApproval :exec
+INSERT INTO transfer_draft_approvals (
+  draft_id,
+  username
+) VALUES (
+  $1, $2
+)
+ON CONFLICT (draft_id, username) DO NOTHING;
+
+-- name: ListTransferDraftApprovals :many
+SELECT * FROM transfer_draft_approvals
+WHERE draft_id = $1
+ORDER BY created_at;
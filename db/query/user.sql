@@ -11,3 +11,37 @@ INSERT INTO users (
 -- name: GetUser :one
 SELECT * FROM users
 WHERE username = $1 LIMIT 1;
+
+-- name: MarkUserEmailVerified :one
+UPDATE users
+SET is_email_verified = true
+WHERE username = $1 AND email = $2
+RETURNING *;
+
+-- name: GetUserByEmail :one
+SELECT * FROM users
+WHERE email = $1 LIMIT 1;
+
+-- name: UpdateUserPassword :one
+-- password_changed_at is compared with the issue time of tokens, so it comes from the same clock as they do
+-- rather than the database's.
+UPDATE users
+SET hashed_password = sqlc.arg(hashed_password), password_changed_at = sqlc.arg(password_changed_at)
+WHERE username = sqlc.arg(username)
+RETURNING *;
+
+-- name: RehashUserPassword :exec
+UPDATE users
+SET hashed_password = sqlc.arg(new_hashed_password)
+WHERE username = sqlc.arg(username) AND hashed_password = sqlc.arg(old_hashed_password);
+
+-- name: GetUserForUpdate :one
+SELECT * FROM users
+WHERE username = $1 LIMIT 1
+FOR NO KEY UPDATE;
+
+-- name: UpdateUser :one
+UPDATE users
+SET full_name = $2, email = $3, is_email_verified = $4
+WHERE username = $1
+RETURNING *;
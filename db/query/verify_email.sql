@@ -0,0 +1,37 @@
+-- name: CreateVerifyEmail :one
+INSERT INTO verify_emails (
+  username,
+  email,
+  secret_code,
+  expired_at
+) VALUES (
+  $1, $2, $3, $4
+) RETURNING *;
+
+-- name: GetVerifyEmail :one
+SELECT * FROM verify_emails
+WHERE id = $1 LIMIT 1;
+
+-- name: GetLatestVerifyEmail :one
+SELECT * FROM verify_emails
+WHERE username = $1
+ORDER BY id DESC
+LIMIT 1;
+
+-- name: SetVerifyEmailSecretCode :one
+-- The mail job mints the code as it sends it, so only its hash is ever stored; a new code replaces the last.
+UPDATE verify_emails
+SET secret_code = $2
+WHERE id = $1
+  AND is_used = false
+  AND expired_at > now()
+RETURNING *;
+
+-- name: UseVerifyEmail :one
+UPDATE verify_emails
+SET is_used = true
+WHERE id = $1
+  AND secret_code = $2
+  AND is_used = false
+  AND expired_at > now()
+RETURNING *;
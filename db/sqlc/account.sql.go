@@ -11,7 +11,7 @@ const addAccountBalance = `-- name: AddAccountBalance :one
 UPDATE accounts
 SET balance = balance + $1
 WHERE id = $2
-RETURNING id, owner, balance, currency, created_at
+RETURNING id, owner, balance, currency, created_at, joint_approval_threshold, number
 `
 
 type AddAccountBalanceParams struct {
@@ -28,6 +28,8 @@ func (q *Queries) AddAccountBalance(ctx context.Context, arg AddAccountBalancePa
 		&i.Balance,
 		&i.Currency,
 		&i.CreatedAt,
+		&i.JointApprovalThreshold,
+		&i.Number,
 	)
 	return i, err
 }
@@ -36,20 +38,27 @@ const createAccount = `-- name: CreateAccount :one
 INSERT INTO accounts (
   owner,
   balance,
-  currency
+  currency,
+  number
 ) VALUES (
-  $1, $2, $3
-) RETURNING id, owner, balance, currency, created_at
+  $1, $2, $3, $4
+) RETURNING id, owner, balance, currency, created_at, joint_approval_threshold, number
 `
 
 type CreateAccountParams struct {
 	Owner    string `json:"owner"`
 	Balance  int64  `json:"balance"`
 	Currency string `json:"currency"`
+	Number   string `json:"number"`
 }
 
 func (q *Queries) CreateAccount(ctx context.Context, arg CreateAccountParams) (Account, error) {
-	row := q.db.QueryRowContext(ctx, createAccount, arg.Owner, arg.Balance, arg.Currency)
+	row := q.db.QueryRowContext(ctx, createAccount,
+		arg.Owner,
+		arg.Balance,
+		arg.Currency,
+		arg.Number,
+	)
 	var i Account
 	err := row.Scan(
 		&i.ID,
@@ -57,6 +66,8 @@ func (q *Queries) CreateAccount(ctx context.Context, arg CreateAccountParams) (A
 		&i.Balance,
 		&i.Currency,
 		&i.CreatedAt,
+		&i.JointApprovalThreshold,
+		&i.Number,
 	)
 	return i, err
 }
@@ -72,7 +83,7 @@ func (q *Queries) DeleteAccount(ctx context.Context, id int64) error {
 }
 
 const getAccount = `-- name: GetAccount :one
-SELECT id, owner, balance, currency, created_at FROM accounts
+SELECT id, owner, balance, currency, created_at, joint_approval_threshold, number FROM accounts
 WHERE id = $1 LIMIT 1
 `
 
@@ -85,12 +96,34 @@ func (q *Queries) GetAccount(ctx context.Context, id int64) (Account, error) {
 		&i.Balance,
 		&i.Currency,
 		&i.CreatedAt,
+		&i.JointApprovalThreshold,
+		&i.Number,
+	)
+	return i, err
+}
+
+const getAccountByNumber = `-- name: GetAccountByNumber :one
+SELECT id, owner, balance, currency, created_at, joint_approval_threshold, number FROM accounts
+WHERE number = $1 LIMIT 1
+`
+
+func (q *Queries) GetAccountByNumber(ctx context.Context, number string) (Account, error) {
+	row := q.db.QueryRowContext(ctx, getAccountByNumber, number)
+	var i Account
+	err := row.Scan(
+		&i.ID,
+		&i.Owner,
+		&i.Balance,
+		&i.Currency,
+		&i.CreatedAt,
+		&i.JointApprovalThreshold,
+		&i.Number,
 	)
 	return i, err
 }
 
 const getAccountForUpdate = `-- name: GetAccountForUpdate :one
-SELECT id, owner, balance, currency, created_at FROM accounts
+SELECT id, owner, balance, currency, created_at, joint_approval_threshold, number FROM accounts
 WHERE id = $1 LIMIT 1
 FOR NO KEY UPDATE
 `
@@ -104,12 +137,14 @@ func (q *Queries) GetAccountForUpdate(ctx context.Context, id int64) (Account, e
 		&i.Balance,
 		&i.Currency,
 		&i.CreatedAt,
+		&i.JointApprovalThreshold,
+		&i.Number,
 	)
 	return i, err
 }
 
 const listAccounts = `-- name: ListAccounts :many
-SELECT id, owner, balance, currency, created_at FROM accounts
+SELECT id, owner, balance, currency, created_at, joint_approval_threshold, number FROM accounts
 WHERE owner = $1
 ORDER BY id
 LIMIT $2
@@ -137,6 +172,56 @@ func (q *Queries) ListAccounts(ctx context.Context, arg ListAccountsParams) ([]A
 			&i.Balance,
 			&i.Currency,
 			&i.CreatedAt,
+			&i.JointApprovalThreshold,
+			&i.Number,
+		); err != nil {
+			return nil, err
+		}
+		items = append(items, i)
+	}
+	if err := rows.Close(); err != nil {
+		return nil, err
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return items, nil
+}
+
+const listHolderAccounts = `-- name: ListHolderAccounts :many
+SELECT id, owner, balance, currency, created_at, joint_approval_threshold, number FROM accounts
+WHERE id IN (
+  SELECT account_id FROM account_holders
+  WHERE username = $1
+)
+ORDER BY id
+LIMIT $2
+OFFSET $3
+`
+
+type ListHolderAccountsParams struct {
+	Username string `json:"username"`
+	Limit    int32  `json:"limit"`
+	Offset   int32  `json:"offset"`
+}
+
+func (q *Queries) ListHolderAccounts(ctx context.Context, arg ListHolderAccountsParams) ([]Account, error) {
+	rows, err := q.db.QueryContext(ctx, listHolderAccounts, arg.Username, arg.Limit, arg.Offset)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	items := []Account{}
+	for rows.Next() {
+		var i Account
+		if err := rows.Scan(
+			&i.ID,
+			&i.Owner,
+			&i.Balance,
+			&i.Currency,
+			&i.CreatedAt,
+			&i.JointApprovalThreshold,
+			&i.Number,
 		); err != nil {
 			return nil, err
 		}
@@ -155,7 +240,7 @@ const updateAccount = `-- name: UpdateAccount :one
 UPDATE accounts
 SET balance = $2
 WHERE id = $1
-RETURNING id, owner, balance, currency, created_at
+RETURNING id, owner, balance, currency, created_at, joint_approval_threshold, number
 `
 
 type UpdateAccountParams struct {
@@ -172,6 +257,35 @@ func (q *Queries) UpdateAccount(ctx context.Context, arg UpdateAccountParams) (A
 		&i.Balance,
 		&i.Currency,
 		&i.CreatedAt,
+		&i.JointApprovalThreshold,
+		&i.Number,
+	)
+	return i, err
+}
+
+const updateAccountApprovalThreshold = `-- name: UpdateAccountApprovalThreshold :one
+UPDATE accounts
+SET joint_approval_threshold = $2
+WHERE id = $1
+RETURNING id, owner, balance, currency, created_at, joint_approval_threshold, number
+`
+
+type UpdateAccountApprovalThresholdParams struct {
+	ID                     int64 `json:"id"`
+	JointApprovalThreshold int64 `json:"joint_approval_threshold"`
+}
+
+func (q *Queries) UpdateAccountApprovalThreshold(ctx context.Context, arg UpdateAccountApprovalThresholdParams) (Account, error) {
+	row := q.db.QueryRowContext(ctx, updateAccountApprovalThreshold, arg.ID, arg.JointApprovalThreshold)
+	var i Account
+	err := row.Scan(
+		&i.ID,
+		&i.Owner,
+		&i.Balance,
+		&i.Currency,
+		&i.CreatedAt,
+		&i.JointApprovalThreshold,
+		&i.Number,
 	)
 	return i, err
 }
@@ -0,0 +1,104 @@
+package db
+
+import (
+	"context"
+	"database/sql"
+	"gobank/util"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+)
+
+func createRandomAccountConsent(t *testing.T, clientID string) AccountConsent {
+	arg := CreateAccountConsentParams{
+		ClientID:    clientID,
+		Permissions: []string{"ReadBalances", "ReadTransactions"},
+		ExpiresAt:   time.Now().Add(time.Hour),
+	}
+
+	consent, err := testQueries.CreateAccountConsent(context.Background(), arg)
+	require.NoError(t, err)
+	require.NotZero(t, consent.ID)
+	require.Equal(t, arg.ClientID, consent.ClientID)
+	require.Equal(t, arg.Permissions, consent.Permissions)
+	require.Equal(t, ConsentAwaitingAuthorisation, consent.Status)
+	require.False(t, consent.Username.Valid)
+	require.Empty(t, consent.AccountIds)
+	require.False(t, consent.AuthorisedAt.Valid)
+
+	return consent
+}
+
+func TestAuthorizeOAuthClientTxWithAccountConsent(t *testing.T) {
+	store := NewStore(testDB)
+	client := createRandomOAuthClient(t)
+	user := createRandomUser(t)
+	consent := createRandomAccountConsent(t, client.ID)
+
+	arg := AuthorizeOAuthClientTxParams{
+		Consent: UpsertOAuthConsentParams{
+			Username: user.Username,
+			ClientID: client.ID,
+			Scopes:   client.Scopes,
+		},
+		Code: CreateOAuthAuthorizationCodeParams{
+			CodeHash:      util.RandomString(64),
+			ClientID:      client.ID,
+			Username:      user.Username,
+			RedirectUri:   client.RedirectUris[0],
+			Scopes:        client.Scopes,
+			CodeChallenge: util.RandomString(64),
+			ExpiresAt:     time.Now().Add(time.Minute),
+		},
+		AccountConsent: &AuthoriseAccountConsentParams{
+			ID:         consent.ID,
+			Username:   sql.NullString{String: user.Username, Valid: true},
+			AccountIds: []int64{1, 2},
+		},
+	}
+	require.NoError(t, store.AuthorizeOAuthClientTx(context.Background(), arg))
+
+	authorised, err := testQueries.GetAccountConsent(context.Background(), consent.ID)
+	require.NoError(t, err)
+	require.Equal(t, ConsentAuthorised, authorised.Status)
+	require.Equal(t, user.Username, authorised.Username.String)
+	require.Equal(t, []int64{1, 2}, authorised.AccountIds)
+	require.True(t, authorised.AuthorisedAt.Valid)
+
+	code, err := testQueries.UseOAuthAuthorizationCode(context.Background(), arg.Code.CodeHash)
+	require.NoError(t, err)
+	require.Equal(t, sql.NullInt64{Int64: consent.ID, Valid: true}, code.ConsentID)
+
+	consents, err := testQueries.ListAccountConsents(context.Background(), authorised.Username)
+	require.NoError(t, err)
+	require.Len(t, consents, 1)
+
+	// a consent is only authorised once, and nothing else of the authorization is kept then
+	arg.Code.CodeHash = util.RandomString(64)
+	err = store.AuthorizeOAuthClientTx(context.Background(), arg)
+	require.EqualError(t, err, sql.ErrNoRows.Error())
+	_, err = testQueries.UseOAuthAuthorizationCode(context.Background(), arg.Code.CodeHash)
+	require.EqualError(t, err, sql.ErrNoRows.Error())
+}
+
+func TestRevokeAccountConsent(t *testing.T) {
+	client := createRandomOAuthClient(t)
+	consent := createRandomAccountConsent(t, client.ID)
+
+	revoked, err := testQueries.RevokeAccountConsent(context.Background(), consent.ID)
+	require.NoError(t, err)
+	require.Equal(t, ConsentRevoked, revoked.Status)
+	require.True(t, revoked.RevokedAt.Valid)
+
+	_, err = testQueries.RevokeAccountConsent(context.Background(), consent.ID)
+	require.EqualError(t, err, sql.ErrNoRows.Error())
+
+	// a revoked consent can no longer be authorised
+	_, err = testQueries.AuthoriseAccountConsent(context.Background(), AuthoriseAccountConsentParams{
+		ID:         consent.ID,
+		Username:   sql.NullString{String: client.Owner, Valid: true},
+		AccountIds: []int64{1},
+	})
+	require.EqualError(t, err, sql.ErrNoRows.Error())
+}
@@ -0,0 +1,7 @@
+package db
+
+// Roles of an account holder. The primary holder is the account owner and manages the other holders.
+const (
+	HolderPrimary   = "primary"
+	HolderSecondary = "secondary"
+)
@@ -0,0 +1,70 @@
+package db
+
+import (
+	"context"
+	"database/sql"
+	"gobank/util"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestCreateAccountTxAddsPrimaryHolder(t *testing.T) {
+	store := NewStore(testDB)
+	user := createRandomUser(t)
+
+	acc, err := store.CreateAccountTx(context.Background(), CreateAccountParams{
+		Owner:    user.Username,
+		Currency: "USD",
+		Number:   util.RandomAccountNumber(),
+	})
+	require.NoError(t, err)
+	require.Zero(t, acc.JointApprovalThreshold)
+
+	holder, err := testQueries.GetAccountHolder(context.Background(), GetAccountHolderParams{
+		AccountID: acc.ID,
+		Username:  user.Username,
+	})
+	require.NoError(t, err)
+	require.Equal(t, HolderPrimary, holder.Role)
+}
+
+func TestAccountHolders(t *testing.T) {
+	acc := createRandomAccount(t)
+	spouse := createRandomUser(t)
+
+	holder, err := testQueries.CreateAccountHolder(context.Background(), CreateAccountHolderParams{
+		AccountID: acc.ID,
+		Username:  spouse.Username,
+		Role:      HolderSecondary,
+	})
+	require.NoError(t, err)
+	require.Equal(t, acc.ID, holder.AccountID)
+	require.NotZero(t, holder.CreatedAt)
+
+	accs, err := testQueries.ListHolderAccounts(context.Background(), ListHolderAccountsParams{
+		Username: spouse.Username,
+		Limit:    5,
+		Offset:   0,
+	})
+	require.NoError(t, err)
+	require.Len(t, accs, 1)
+	require.Equal(t, acc.ID, accs[0].ID)
+
+	holders, err := testQueries.ListAccountHolders(context.Background(), acc.ID)
+	require.NoError(t, err)
+	require.Len(t, holders, 1)
+
+	n, err := testQueries.DeleteAccountHolder(context.Background(), DeleteAccountHolderParams{
+		AccountID: acc.ID,
+		Username:  spouse.Username,
+	})
+	require.NoError(t, err)
+	require.Equal(t, int64(1), n)
+
+	_, err = testQueries.GetAccountHolder(context.Background(), GetAccountHolderParams{
+		AccountID: acc.ID,
+		Username:  spouse.Username,
+	})
+	require.EqualError(t, err, sql.ErrNoRows.Error())
+}
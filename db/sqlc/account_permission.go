@@ -0,0 +1,7 @@
+package db
+
+// Access an account permission grants. Transfer access includes read.
+const (
+	AccessRead     = "read"
+	AccessTransfer = "transfer"
+)
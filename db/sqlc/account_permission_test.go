@@ -0,0 +1,82 @@
+package db
+
+import (
+	"context"
+	"database/sql"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func createRandomAccountPermission(t *testing.T, acc Account, access string) AccountPermission {
+	user := createRandomUser(t)
+	arg := UpsertAccountPermissionParams{
+		AccountID: acc.ID,
+		Username:  user.Username,
+		Access:    access,
+		GrantedBy: acc.Owner,
+	}
+
+	perm, err := testQueries.UpsertAccountPermission(context.Background(), arg)
+	require.NoError(t, err)
+	require.Equal(t, arg.AccountID, perm.AccountID)
+	require.Equal(t, arg.Username, perm.Username)
+	require.Equal(t, arg.Access, perm.Access)
+	require.Equal(t, arg.GrantedBy, perm.GrantedBy)
+	require.NotZero(t, perm.CreatedAt)
+
+	return perm
+}
+
+func TestUpsertAccountPermission(t *testing.T) {
+	acc := createRandomAccount(t)
+	perm := createRandomAccountPermission(t, acc, "read")
+
+	upgraded, err := testQueries.UpsertAccountPermission(context.Background(), UpsertAccountPermissionParams{
+		AccountID: acc.ID,
+		Username:  perm.Username,
+		Access:    "transfer",
+		GrantedBy: acc.Owner,
+	})
+	require.NoError(t, err)
+	require.Equal(t, "transfer", upgraded.Access)
+	require.Equal(t, perm.CreatedAt, upgraded.CreatedAt)
+
+	got, err := testQueries.GetAccountPermission(context.Background(), GetAccountPermissionParams{
+		AccountID: acc.ID,
+		Username:  perm.Username,
+	})
+	require.NoError(t, err)
+	require.Equal(t, upgraded, got)
+}
+
+func TestListAccountPermissions(t *testing.T) {
+	acc := createRandomAccount(t)
+	for i := 0; i < 3; i++ {
+		createRandomAccountPermission(t, acc, "read")
+	}
+
+	perms, err := testQueries.ListAccountPermissions(context.Background(), acc.ID)
+	require.NoError(t, err)
+	require.Len(t, perms, 3)
+	for _, perm := range perms {
+		require.Equal(t, acc.ID, perm.AccountID)
+	}
+}
+
+func TestDeleteAccountPermission(t *testing.T) {
+	acc := createRandomAccount(t)
+	perm := createRandomAccountPermission(t, acc, "transfer")
+	arg := DeleteAccountPermissionParams{AccountID: acc.ID, Username: perm.Username}
+
+	n, err := testQueries.DeleteAccountPermission(context.Background(), arg)
+	require.NoError(t, err)
+	require.Equal(t, int64(1), n)
+
+	_, err = testQueries.GetAccountPermission(context.Background(), GetAccountPermissionParams(arg))
+	require.EqualError(t, err, sql.ErrNoRows.Error())
+
+	n, err = testQueries.DeleteAccountPermission(context.Background(), arg)
+	require.NoError(t, err)
+	require.Zero(t, n)
+}
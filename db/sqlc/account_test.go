@@ -17,6 +17,7 @@ func createRandomAccount(t *testing.T) Account {
 		Owner:    user.Username,
 		Balance:  util.RandomMoney(),
 		Currency: util.RandomCurrency(),
+		Number:   util.RandomAccountNumber(),
 	}
 
 	account, err := testQueries.CreateAccount(context.Background(), arg)
@@ -26,6 +27,7 @@ func createRandomAccount(t *testing.T) Account {
 	require.Equal(t, arg.Owner, account.Owner)
 	require.Equal(t, arg.Balance, account.Balance)
 	require.Equal(t, arg.Currency, account.Currency)
+	require.Equal(t, arg.Number, account.Number)
 
 	require.NotZero(t, account.ID)
 	require.NotZero(t, account.CreatedAt)
@@ -52,6 +54,16 @@ func TestGetAccount(t *testing.T) {
 	require.WithinDuration(t, acc1.CreatedAt, acc2.CreatedAt, time.Second)
 }
 
+func TestGetAccountByNumber(t *testing.T) {
+	acc1 := createRandomAccount(t)
+	acc2, err := testQueries.GetAccountByNumber(context.Background(), acc1.Number)
+	require.NoError(t, err)
+	require.Equal(t, acc1.ID, acc2.ID)
+
+	_, err = testQueries.GetAccountByNumber(context.Background(), util.RandomAccountNumber())
+	require.EqualError(t, err, sql.ErrNoRows.Error())
+}
+
 func TestUpdateAccount(t *testing.T) {
 	acc1 := createRandomAccount(t)
 	args := UpdateAccountParams{
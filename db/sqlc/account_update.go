@@ -0,0 +1,76 @@
+package db
+
+import (
+	"context"
+	"encoding/json"
+	"time"
+)
+
+// AccountUpdatesChannel is the Postgres NOTIFY channel carrying AccountUpdate payloads.
+const AccountUpdatesChannel = "account_updates"
+
+// AccountUpdate announces a new entry and the balance it left its account with. ID is the entry id. Entry ids
+// are handed out before commit, so updates can arrive out of id order; Txid, the writing transaction, is what
+// listeners resume from.
+type AccountUpdate struct {
+	ID         int64     `json:"id"`
+	AccountID  int64     `json:"account_id"`
+	Owner      string    `json:"owner"`
+	Amount     int64     `json:"amount"`
+	Balance    int64     `json:"balance"`
+	TransferID int64     `json:"transfer_id,omitempty"`
+	CreatedAt  time.Time `json:"created_at"`
+	Txid       int64     `json:"-"`
+}
+
+// accountUpdateNotification is the NOTIFY payload: the update and, unlike what clients are sent, its Txid.
+type accountUpdateNotification struct {
+	AccountUpdate
+	Txid int64 `json:"txid"`
+}
+
+// ParseAccountUpdate decodes a notification sent on AccountUpdatesChannel.
+func ParseAccountUpdate(payload string) (AccountUpdate, error) {
+	var n accountUpdateNotification
+	if err := json.Unmarshal([]byte(payload), &n); err != nil {
+		return AccountUpdate{}, err
+	}
+	n.AccountUpdate.Txid = n.Txid
+	return n.AccountUpdate, nil
+}
+
+func NewAccountUpdate(row ListAccountUpdatesSinceRow) AccountUpdate {
+	return AccountUpdate{
+		ID:         row.ID,
+		AccountID:  row.AccountID,
+		Owner:      row.Owner,
+		Amount:     row.Amount,
+		Balance:    row.BalanceAfter,
+		TransferID: row.TransferID.Int64,
+		CreatedAt:  row.CreatedAt,
+		Txid:       row.Txid,
+	}
+}
+
+// publishAccountUpdate queues a notification for entry on acc. Postgres only delivers it once q's transaction commits.
+func publishAccountUpdate(ctx context.Context, q *Queries, acc Account, entry Entry) error {
+	payload, err := json.Marshal(accountUpdateNotification{
+		AccountUpdate: AccountUpdate{
+			ID:         entry.ID,
+			AccountID:  acc.ID,
+			Owner:      acc.Owner,
+			Amount:     entry.Amount,
+			Balance:    entry.BalanceAfter,
+			TransferID: entry.TransferID.Int64,
+			CreatedAt:  entry.CreatedAt,
+		},
+		Txid: entry.Txid,
+	})
+	if err != nil {
+		return err
+	}
+	return q.NotifyAccountUpdate(ctx, NotifyAccountUpdateParams{
+		Channel: AccountUpdatesChannel,
+		Payload: string(payload),
+	})
+}
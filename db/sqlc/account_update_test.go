@@ -0,0 +1,80 @@
+package db
+
+import (
+	"context"
+	"gobank/util"
+	"testing"
+	"time"
+
+	"github.com/lib/pq"
+	"github.com/stretchr/testify/require"
+)
+
+func TestTransferTxNotifiesAccountUpdates(t *testing.T) {
+	cfg, err := util.LoadConfig("../..")
+	require.NoError(t, err)
+
+	// Listen blocks until it connects, so fail fast when the database is down
+	require.NoError(t, testDB.Ping())
+	listener := pq.NewListener(cfg.DBSource, time.Second, time.Second, nil)
+	defer listener.Close()
+	require.NoError(t, listener.Listen(AccountUpdatesChannel))
+
+	store := NewStore(testDB)
+	acc1 := createRandomAccount(t)
+	acc2 := createRandomAccount(t)
+
+	result, err := store.TransferTx(context.Background(), TransferTxParams{
+		FromAccountID: acc1.ID,
+		ToAccountID:   acc2.ID,
+		Amount:        10,
+	})
+	require.NoError(t, err)
+
+	// other tests may be moving money too, so only look for this transfer's entries
+	got := map[int64]AccountUpdate{}
+	timeout := time.After(5 * time.Second)
+	for len(got) < 2 {
+		select {
+		case n := <-listener.Notify:
+			require.NotNil(t, n)
+			u, err := ParseAccountUpdate(n.Extra)
+			require.NoError(t, err)
+			if u.TransferID == result.Transfer.ID {
+				got[u.ID] = u
+			}
+		case <-timeout:
+			t.Fatal("timed out waiting for account updates")
+		}
+	}
+
+	from := got[result.FromEntry.ID]
+	require.Equal(t, acc1.Owner, from.Owner)
+	require.Equal(t, result.FromAccount.Balance, from.Balance)
+	require.Equal(t, int64(-10), from.Amount)
+	require.Equal(t, result.FromEntry.Txid, from.Txid)
+	require.NotZero(t, from.Txid)
+
+	to := got[result.ToEntry.ID]
+	require.Equal(t, acc2.Owner, to.Owner)
+	require.Equal(t, result.ToAccount.Balance, to.Balance)
+
+	// the transfer has committed, so it sits below the oldest running transaction
+	toTxid, err := store.GetOldestRunningTxid(context.Background())
+	require.NoError(t, err)
+	require.Greater(t, toTxid, to.Txid)
+
+	rows, err := store.ListAccountUpdatesSince(context.Background(), ListAccountUpdatesSinceParams{
+		AccountIds: []int64{acc2.ID},
+		FromTxid:   to.Txid,
+		ToTxid:     toTxid,
+		AfterID:    0,
+		MaxUpdates: 10,
+	})
+	require.NoError(t, err)
+	require.Len(t, rows, 1)
+	replayed := NewAccountUpdate(rows[0])
+	require.Equal(t, to.ID, replayed.ID)
+	require.Equal(t, to.Balance, replayed.Balance)
+	require.Equal(t, to.TransferID, replayed.TransferID)
+}
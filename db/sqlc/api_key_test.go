@@ -0,0 +1,100 @@
+package db
+
+import (
+	"context"
+	"database/sql"
+	"gobank/util"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+)
+
+func createRandomAPIKey(t *testing.T, username string) ApiKey {
+	arg := CreateAPIKeyParams{
+		Username:   username,
+		Name:       util.RandomOwner(),
+		Prefix:     util.RandomString(12),
+		SecretHash: util.RandomString(64),
+		Scopes:     []string{"accounts:read", "transfers:write"},
+		ExpiresAt:  sql.NullTime{Time: time.Now().Add(time.Hour), Valid: true},
+	}
+
+	key, err := testQueries.CreateAPIKey(context.Background(), arg)
+	require.NoError(t, err)
+	require.NotZero(t, key.ID)
+	require.Equal(t, arg.Username, key.Username)
+	require.Equal(t, arg.Name, key.Name)
+	require.Equal(t, arg.Prefix, key.Prefix)
+	require.Equal(t, arg.SecretHash, key.SecretHash)
+	require.Equal(t, arg.Scopes, key.Scopes)
+	require.WithinDuration(t, arg.ExpiresAt.Time, key.ExpiresAt.Time, time.Second)
+	require.False(t, key.LastUsedAt.Valid)
+	require.False(t, key.RevokedAt.Valid)
+	require.NotZero(t, key.CreatedAt)
+
+	return key
+}
+
+func TestCreateAPIKey(t *testing.T) {
+	user := createRandomUser(t)
+	createRandomAPIKey(t, user.Username)
+}
+
+func TestGetAPIKeyByPrefix(t *testing.T) {
+	user := createRandomUser(t)
+	key := createRandomAPIKey(t, user.Username)
+
+	got, err := testQueries.GetAPIKeyByPrefix(context.Background(), key.Prefix)
+	require.NoError(t, err)
+	require.Equal(t, key.ID, got.ID)
+	require.Equal(t, key.Scopes, got.Scopes)
+
+	_, err = testQueries.GetAPIKeyByPrefix(context.Background(), util.RandomString(12))
+	require.EqualError(t, err, sql.ErrNoRows.Error())
+}
+
+func TestListAPIKeys(t *testing.T) {
+	user := createRandomUser(t)
+	for i := 0; i < 3; i++ {
+		createRandomAPIKey(t, user.Username)
+	}
+	createRandomAPIKey(t, createRandomUser(t).Username)
+
+	keys, err := testQueries.ListAPIKeys(context.Background(), user.Username)
+	require.NoError(t, err)
+	require.Len(t, keys, 3)
+	for _, key := range keys {
+		require.Equal(t, user.Username, key.Username)
+	}
+}
+
+func TestRevokeAPIKey(t *testing.T) {
+	user := createRandomUser(t)
+	key := createRandomAPIKey(t, user.Username)
+
+	revoked, err := testQueries.RevokeAPIKey(context.Background(), key.ID)
+	require.NoError(t, err)
+	require.True(t, revoked.RevokedAt.Valid)
+	require.WithinDuration(t, time.Now(), revoked.RevokedAt.Time, time.Second)
+
+	// revoking twice finds nothing to revoke
+	_, err = testQueries.RevokeAPIKey(context.Background(), key.ID)
+	require.EqualError(t, err, sql.ErrNoRows.Error())
+}
+
+func TestTouchAPIKey(t *testing.T) {
+	user := createRandomUser(t)
+	key := createRandomAPIKey(t, user.Username)
+
+	require.NoError(t, testQueries.TouchAPIKey(context.Background(), key.ID))
+	touched, err := testQueries.GetAPIKey(context.Background(), key.ID)
+	require.NoError(t, err)
+	require.True(t, touched.LastUsedAt.Valid)
+
+	// a second use within the minute leaves the timestamp alone
+	require.NoError(t, testQueries.TouchAPIKey(context.Background(), key.ID))
+	again, err := testQueries.GetAPIKey(context.Background(), key.ID)
+	require.NoError(t, err)
+	require.Equal(t, touched.LastUsedAt.Time, again.LastUsedAt.Time)
+}
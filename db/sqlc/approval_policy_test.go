@@ -0,0 +1,49 @@
+package db
+
+import (
+	"context"
+	"database/sql"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestApprovalPolicies(t *testing.T) {
+	acc := createRandomAccount(t)
+
+	for _, arg := range []UpsertApprovalPolicyParams{
+		{AccountID: acc.ID, MinAmount: 100, RequiredApprovals: 1},
+		{AccountID: acc.ID, MinAmount: 1000, RequiredApprovals: 1},
+		{AccountID: acc.ID, MinAmount: 1000, RequiredApprovals: 2},
+	} {
+		policy, err := testQueries.UpsertApprovalPolicy(context.Background(), arg)
+		require.NoError(t, err)
+		require.Equal(t, arg.MinAmount, policy.MinAmount)
+		require.Equal(t, arg.RequiredApprovals, policy.RequiredApprovals)
+	}
+
+	policies, err := testQueries.ListApprovalPolicies(context.Background(), acc.ID)
+	require.NoError(t, err)
+	require.Len(t, policies, 2)
+	require.Equal(t, int64(100), policies[0].MinAmount)
+	require.Equal(t, int32(2), policies[1].RequiredApprovals)
+
+	_, err = testQueries.GetApprovalPolicyForAmount(context.Background(), GetApprovalPolicyForAmountParams{AccountID: acc.ID, Amount: 99})
+	require.EqualError(t, err, sql.ErrNoRows.Error())
+
+	policy, err := testQueries.GetApprovalPolicyForAmount(context.Background(), GetApprovalPolicyForAmountParams{AccountID: acc.ID, Amount: 999})
+	require.NoError(t, err)
+	require.Equal(t, policies[0].ID, policy.ID)
+
+	policy, err = testQueries.GetApprovalPolicyForAmount(context.Background(), GetApprovalPolicyForAmountParams{AccountID: acc.ID, Amount: 1000})
+	require.NoError(t, err)
+	require.Equal(t, policies[1].ID, policy.ID)
+
+	n, err := testQueries.DeleteApprovalPolicy(context.Background(), DeleteApprovalPolicyParams{ID: policies[1].ID, AccountID: acc.ID})
+	require.NoError(t, err)
+	require.Equal(t, int64(1), n)
+
+	n, err = testQueries.DeleteApprovalPolicy(context.Background(), DeleteApprovalPolicyParams{ID: policies[0].ID, AccountID: acc.ID + 1})
+	require.NoError(t, err)
+	require.Zero(t, n)
+}
@@ -5,36 +5,72 @@ package db
 
 import (
 	"context"
+	"database/sql"
+	"time"
 )
 
 const createEntry = `-- name: CreateEntry :one
 INSERT INTO entries (
     account_id,
-    amount
+    amount,
+    transfer_id,
+    balance_after
 ) VALUES (
-    $1, $2
-) RETURNING id, account_id, amount, created_at
+    $1, $2, $3, $4
+) RETURNING id, account_id, amount, created_at, transfer_id, balance_after, txid
 `
 
 type CreateEntryParams struct {
-	AccountID int64 `json:"account_id"`
-	Amount    int64 `json:"amount"`
+	AccountID    int64         `json:"account_id"`
+	Amount       int64         `json:"amount"`
+	TransferID   sql.NullInt64 `json:"transfer_id"`
+	BalanceAfter int64         `json:"balance_after"`
 }
 
 func (q *Queries) CreateEntry(ctx context.Context, arg CreateEntryParams) (Entry, error) {
-	row := q.db.QueryRowContext(ctx, createEntry, arg.AccountID, arg.Amount)
+	row := q.db.QueryRowContext(ctx, createEntry,
+		arg.AccountID,
+		arg.Amount,
+		arg.TransferID,
+		arg.BalanceAfter,
+	)
 	var i Entry
 	err := row.Scan(
 		&i.ID,
 		&i.AccountID,
 		&i.Amount,
 		&i.CreatedAt,
+		&i.TransferID,
+		&i.BalanceAfter,
+		&i.Txid,
 	)
 	return i, err
 }
 
+const getAccountBalanceAt = `-- name: GetAccountBalanceAt :one
+SELECT balance_after FROM entries
+WHERE account_id = $1 AND created_at <= $2
+ORDER BY id DESC
+LIMIT 1
+`
+
+type GetAccountBalanceAtParams struct {
+	AccountID int64     `json:"account_id"`
+	At        time.Time `json:"at"`
+}
+
+// created_at is the start of the writing transaction, so a transfer that waited on the account lock can carry an
+// earlier created_at than one it followed. Entries are written under that lock, so ids follow the running balance.
+// entries_account_id_id_idx walks the ids of the account backwards without visiting the table.
+func (q *Queries) GetAccountBalanceAt(ctx context.Context, arg GetAccountBalanceAtParams) (int64, error) {
+	row := q.db.QueryRowContext(ctx, getAccountBalanceAt, arg.AccountID, arg.At)
+	var balance_after int64
+	err := row.Scan(&balance_after)
+	return balance_after, err
+}
+
 const getEntry = `-- name: GetEntry :one
-SELECT id, account_id, amount, created_at FROM entries
+SELECT id, account_id, amount, created_at, transfer_id, balance_after, txid FROM entries
 WHERE id = $1
 LIMIT 1
 `
@@ -47,12 +83,15 @@ func (q *Queries) GetEntry(ctx context.Context, id int64) (Entry, error) {
 		&i.AccountID,
 		&i.Amount,
 		&i.CreatedAt,
+		&i.TransferID,
+		&i.BalanceAfter,
+		&i.Txid,
 	)
 	return i, err
 }
 
 const listEntries = `-- name: ListEntries :many
-SELECT id, account_id, amount, created_at FROM entries
+SELECT id, account_id, amount, created_at, transfer_id, balance_after, txid FROM entries
 WHERE account_id = $1
 ORDER BY id
 LIMIT $2
@@ -79,6 +118,80 @@ func (q *Queries) ListEntries(ctx context.Context, arg ListEntriesParams) ([]Ent
 			&i.AccountID,
 			&i.Amount,
 			&i.CreatedAt,
+			&i.TransferID,
+			&i.BalanceAfter,
+			&i.Txid,
+		); err != nil {
+			return nil, err
+		}
+		items = append(items, i)
+	}
+	if err := rows.Close(); err != nil {
+		return nil, err
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return items, nil
+}
+
+const listStatementEntries = `-- name: ListStatementEntries :many
+SELECT
+    e.id,
+    e.amount,
+    e.balance_after,
+    e.created_at,
+    COALESCE(e.transfer_id, 0)::bigint AS transfer_id,
+    COALESCE(ca.id, 0)::bigint AS counterparty_account_id,
+    COALESCE(ca.owner, '')::varchar AS counterparty_owner,
+    COALESCE(t.memo, '')::varchar AS memo
+FROM entries e
+LEFT JOIN transfers t ON t.id = e.transfer_id
+LEFT JOIN accounts ca ON ca.id = (
+    CASE WHEN t.from_account_id = e.account_id THEN t.to_account_id ELSE t.from_account_id END
+)
+WHERE e.account_id = $1
+  AND e.created_at >= $2
+  AND e.created_at < $3
+ORDER BY e.id
+`
+
+type ListStatementEntriesParams struct {
+	AccountID int64     `json:"account_id"`
+	FromTime  time.Time `json:"from_time"`
+	ToTime    time.Time `json:"to_time"`
+}
+
+type ListStatementEntriesRow struct {
+	ID                    int64     `json:"id"`
+	Amount                int64     `json:"amount"`
+	BalanceAfter          int64     `json:"balance_after"`
+	CreatedAt             time.Time `json:"created_at"`
+	TransferID            int64     `json:"transfer_id"`
+	CounterpartyAccountID int64     `json:"counterparty_account_id"`
+	CounterpartyOwner     string    `json:"counterparty_owner"`
+	Memo                  string    `json:"memo"`
+}
+
+// Ordered by id for the same reason as GetAccountBalanceAt, which entries_account_id_id_idx serves.
+func (q *Queries) ListStatementEntries(ctx context.Context, arg ListStatementEntriesParams) ([]ListStatementEntriesRow, error) {
+	rows, err := q.db.QueryContext(ctx, listStatementEntries, arg.AccountID, arg.FromTime, arg.ToTime)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	items := []ListStatementEntriesRow{}
+	for rows.Next() {
+		var i ListStatementEntriesRow
+		if err := rows.Scan(
+			&i.ID,
+			&i.Amount,
+			&i.BalanceAfter,
+			&i.CreatedAt,
+			&i.TransferID,
+			&i.CounterpartyAccountID,
+			&i.CounterpartyOwner,
+			&i.Memo,
 		); err != nil {
 			return nil, err
 		}
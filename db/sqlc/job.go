@@ -0,0 +1,23 @@
+package db
+
+import (
+	"context"
+	"encoding/json"
+)
+
+// DefaultJobMaxAttempts is how often a job is tried before it is moved to the dead-letter state.
+const DefaultJobMaxAttempts = 5
+
+// EnqueueJob queues a job of jobType with payload encoded as JSON. Called on the Queries of a transaction,
+// the job only becomes visible to workers if that transaction commits.
+func (q *Queries) EnqueueJob(ctx context.Context, jobType string, payload interface{}) (Job, error) {
+	data, err := json.Marshal(payload)
+	if err != nil {
+		return Job{}, err
+	}
+	return q.CreateJob(ctx, CreateJobParams{
+		Type:        jobType,
+		Payload:     data,
+		MaxAttempts: DefaultJobMaxAttempts,
+	})
+}
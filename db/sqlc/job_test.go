@@ -0,0 +1,157 @@
+package db
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"gobank/util"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+)
+
+// claimJob claims pending jobs until it finds the one with id, so jobs left by other tests don't get in the way.
+func claimJob(t *testing.T, id int64) Job {
+	jobs, err := testQueries.ClaimJobs(context.Background(), 1000)
+	require.NoError(t, err)
+	for _, job := range jobs {
+		if job.ID == id {
+			return job
+		}
+	}
+	t.Fatalf("job %d was not claimed", id)
+	return Job{}
+}
+
+func TestJobLifecycle(t *testing.T) {
+	job, err := testQueries.EnqueueJob(context.Background(), "test_job", map[string]string{"to": "alice"})
+	require.NoError(t, err)
+	require.Equal(t, "pending", job.Status)
+	require.JSONEq(t, `{"to":"alice"}`, string(job.Payload))
+	require.Equal(t, int32(DefaultJobMaxAttempts), job.MaxAttempts)
+
+	claimed := claimJob(t, job.ID)
+	require.Equal(t, "running", claimed.Status)
+	require.Equal(t, int32(1), claimed.Attempts)
+	require.True(t, claimed.LockedAt.Valid)
+
+	// a retry scheduled in the future is not claimed again yet
+	runAt := time.Now().Add(time.Hour)
+	err = testQueries.RetryJob(context.Background(), RetryJobParams{ID: job.ID, LastError: "timeout", RunAt: runAt})
+	require.NoError(t, err)
+	jobs, err := testQueries.ClaimJobs(context.Background(), 1000)
+	require.NoError(t, err)
+	for _, j := range jobs {
+		require.NotEqual(t, job.ID, j.ID)
+	}
+
+	retried, err := testQueries.GetJob(context.Background(), job.ID)
+	require.NoError(t, err)
+	require.Equal(t, "pending", retried.Status)
+	require.Equal(t, "timeout", retried.LastError)
+	require.WithinDuration(t, runAt, retried.RunAt, time.Second)
+
+	// only dead jobs can be requeued
+	_, err = testQueries.RequeueJob(context.Background(), job.ID)
+	require.ErrorIs(t, err, sql.ErrNoRows)
+
+	require.NoError(t, testQueries.BuryJob(context.Background(), BuryJobParams{ID: job.ID, LastError: "gave up"}))
+	requeued, err := testQueries.RequeueJob(context.Background(), job.ID)
+	require.NoError(t, err)
+	require.Equal(t, "pending", requeued.Status)
+	require.Zero(t, requeued.Attempts)
+
+	claimJob(t, job.ID)
+	require.NoError(t, testQueries.CompleteJob(context.Background(), job.ID))
+	completed, err := testQueries.GetJob(context.Background(), job.ID)
+	require.NoError(t, err)
+	require.Equal(t, "completed", completed.Status)
+	require.True(t, completed.CompletedAt.Valid)
+	require.False(t, completed.LockedAt.Valid)
+}
+
+func TestRequeueStuckJobs(t *testing.T) {
+	job, err := testQueries.EnqueueJob(context.Background(), "test_job", struct{}{})
+	require.NoError(t, err)
+	claimJob(t, job.ID)
+
+	_, err = testQueries.RequeueStuckJobs(context.Background(), time.Now().Add(time.Second))
+	require.NoError(t, err)
+
+	stuck, err := testQueries.GetJob(context.Background(), job.ID)
+	require.NoError(t, err)
+	require.Equal(t, "pending", stuck.Status)
+	require.Equal(t, int32(1), stuck.Attempts)
+	require.False(t, stuck.LockedAt.Valid)
+	require.NotEmpty(t, stuck.LastError)
+}
+
+func TestRequeueStuckJobsOutOfAttempts(t *testing.T) {
+	job, err := testQueries.CreateJob(context.Background(), CreateJobParams{
+		Type:        "test_job",
+		Payload:     []byte(`{}`),
+		MaxAttempts: 1,
+	})
+	require.NoError(t, err)
+	claimJob(t, job.ID)
+
+	_, err = testQueries.RequeueStuckJobs(context.Background(), time.Now().Add(time.Second))
+	require.NoError(t, err)
+
+	dead, err := testQueries.GetJob(context.Background(), job.ID)
+	require.NoError(t, err)
+	require.Equal(t, "dead", dead.Status)
+	require.False(t, dead.LockedAt.Valid)
+}
+
+func TestEnqueueJobRollsBackWithTx(t *testing.T) {
+	store := NewStore(testDB).(*SQLStore)
+
+	var job Job
+	errAbort := errors.New("abort")
+	err := store.execTx(context.Background(), func(q *Queries) error {
+		var err error
+		job, err = q.EnqueueJob(context.Background(), "test_job", struct{}{})
+		require.NoError(t, err)
+		return errAbort
+	})
+	require.ErrorIs(t, err, errAbort)
+
+	_, err = store.GetJob(context.Background(), job.ID)
+	require.ErrorIs(t, err, sql.ErrNoRows)
+}
+
+func TestScheduleJob(t *testing.T) {
+	arg := ScheduleJobParams{Type: "test_schedule_" + util.RandomString(8), MaxAttempts: DefaultJobMaxAttempts}
+
+	n, err := testQueries.ScheduleJob(context.Background(), arg)
+	require.NoError(t, err)
+	require.Equal(t, int64(1), n)
+
+	// one is waiting already
+	n, err = testQueries.ScheduleJob(context.Background(), arg)
+	require.NoError(t, err)
+	require.Zero(t, n)
+
+	jobs, err := testQueries.ClaimJobs(context.Background(), 1000)
+	require.NoError(t, err)
+	var job Job
+	for _, j := range jobs {
+		if j.Type == arg.Type {
+			job = j
+		}
+	}
+	require.NotZero(t, job.ID)
+	require.JSONEq(t, `{}`, string(job.Payload))
+
+	// nor while it runs
+	n, err = testQueries.ScheduleJob(context.Background(), arg)
+	require.NoError(t, err)
+	require.Zero(t, n)
+
+	require.NoError(t, testQueries.CompleteJob(context.Background(), job.ID))
+	n, err = testQueries.ScheduleJob(context.Background(), arg)
+	require.NoError(t, err)
+	require.Equal(t, int64(1), n)
+}
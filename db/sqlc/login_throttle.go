@@ -0,0 +1,96 @@
+package db
+
+import (
+	"context"
+	"time"
+)
+
+// Login throttle scopes.
+const (
+	LoginScopeUser = "user"
+	LoginScopeIP   = "ip"
+)
+
+type LoginAttemptTxParams struct {
+	Scope   string
+	Subject string
+	// MaxFailures failures without a break of Window lock the subject out for Lockout, doubled for every
+	// lockout in a row and capped at MaxLockout.
+	MaxFailures int32
+	Window      time.Duration
+	Lockout     time.Duration
+	MaxLockout  time.Duration
+}
+
+// BeginLoginAttemptTx counts an attempt to log in as a username or from a client ip as failed before its
+// credentials are checked, so concurrent attempts cannot all get in under the limit; RefundLoginAttempt
+// takes it back if they turn out right. An attempt that finds MaxFailures already counted is refused and
+// locks the subject out. The returned throttle is locked if the attempt was refused. A subject that stays
+// quiet for Window after its last failure or lockout starts over.
+func (s *SQLStore) BeginLoginAttemptTx(ctx context.Context, arg LoginAttemptTxParams) (LoginThrottle, error) {
+	var lt LoginThrottle
+	err := s.execTx(ctx, func(q *Queries) error {
+		//make sure there is a row to lock, so concurrent first attempts are not lost
+		err := q.CreateLoginThrottleIfMissing(ctx, CreateLoginThrottleIfMissingParams{
+			Scope:   arg.Scope,
+			Subject: arg.Subject,
+		})
+		if err != nil {
+			return err
+		}
+		lt, err = q.GetLoginThrottleForUpdate(ctx, GetLoginThrottleForUpdateParams{
+			Scope:   arg.Scope,
+			Subject: arg.Subject,
+		})
+		if err != nil {
+			return err
+		}
+
+		now := time.Now()
+		if lt.LockedUntil.After(now) {
+			return nil
+		}
+		failures, lockouts, lockedUntil := lt.Failures, lt.Lockouts, lt.LockedUntil
+		quietSince := lt.LastFailedAt
+		if lockedUntil.After(quietSince) {
+			quietSince = lockedUntil
+		}
+		if now.Sub(quietSince) > arg.Window {
+			failures, lockouts = 0, 0
+		}
+
+		if failures >= arg.MaxFailures {
+			lockouts++
+			lockedUntil = now.Add(LockoutDuration(arg.Lockout, arg.MaxLockout, lockouts))
+			failures = 0
+		} else {
+			failures++
+		}
+
+		lt, err = q.UpdateLoginThrottle(ctx, UpdateLoginThrottleParams{
+			Scope:        arg.Scope,
+			Subject:      arg.Subject,
+			Failures:     failures,
+			Lockouts:     lockouts,
+			LockedUntil:  lockedUntil,
+			LastFailedAt: now,
+		})
+		return err
+	})
+	return lt, err
+}
+
+// LockoutDuration is how long lockout number lockouts in a row lasts: base, 2*base, 4*base, ... capped at max.
+func LockoutDuration(base, max time.Duration, lockouts int32) time.Duration {
+	d := base
+	for i := int32(1); i < lockouts; i++ {
+		d *= 2
+		if d >= max {
+			return max
+		}
+	}
+	if d > max {
+		return max
+	}
+	return d
+}
@@ -0,0 +1,187 @@
+package db
+
+import (
+	"context"
+	"database/sql"
+	"gobank/util"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+)
+
+func randomThrottle() LoginAttemptTxParams {
+	return LoginAttemptTxParams{
+		Scope:       LoginScopeUser,
+		Subject:     util.RandomOwner(),
+		MaxFailures: 3,
+		Window:      time.Minute,
+		Lockout:     time.Minute,
+		MaxLockout:  time.Hour,
+	}
+}
+
+func TestBeginLoginAttemptTx(t *testing.T) {
+	store := NewStore(testDB)
+	arg := randomThrottle()
+
+	for i := int32(1); i <= arg.MaxFailures; i++ {
+		lt, err := store.BeginLoginAttemptTx(context.Background(), arg)
+		require.NoError(t, err)
+		require.Equal(t, i, lt.Failures)
+		require.Zero(t, lt.Lockouts)
+		require.False(t, lt.LockedUntil.After(time.Now()))
+	}
+
+	// the attempt that finds the limit used up is refused and locks the subject out
+	lt, err := store.BeginLoginAttemptTx(context.Background(), arg)
+	require.NoError(t, err)
+	require.Zero(t, lt.Failures)
+	require.Equal(t, int32(1), lt.Lockouts)
+	require.WithinDuration(t, time.Now().Add(arg.Lockout), lt.LockedUntil, time.Second)
+
+	// attempts during the lockout change nothing
+	locked, err := store.BeginLoginAttemptTx(context.Background(), arg)
+	require.NoError(t, err)
+	require.Equal(t, lt, locked)
+}
+
+func TestBeginLoginAttemptTxBacksOff(t *testing.T) {
+	store := NewStore(testDB)
+	arg := randomThrottle()
+	arg.MaxFailures = 1
+
+	_, err := store.BeginLoginAttemptTx(context.Background(), arg)
+	require.NoError(t, err)
+	lt, err := store.BeginLoginAttemptTx(context.Background(), arg)
+	require.NoError(t, err)
+	require.Equal(t, int32(1), lt.Lockouts)
+
+	// failing again right after the lockout doubles the next one
+	_, err = testQueries.UpdateLoginThrottle(context.Background(), UpdateLoginThrottleParams{
+		Scope:        arg.Scope,
+		Subject:      arg.Subject,
+		Failures:     arg.MaxFailures,
+		Lockouts:     lt.Lockouts,
+		LockedUntil:  time.Now().Add(-time.Second),
+		LastFailedAt: lt.LastFailedAt,
+	})
+	require.NoError(t, err)
+	lt, err = store.BeginLoginAttemptTx(context.Background(), arg)
+	require.NoError(t, err)
+	require.Equal(t, int32(2), lt.Lockouts)
+	require.WithinDuration(t, time.Now().Add(2*arg.Lockout), lt.LockedUntil, time.Second)
+}
+
+func TestRefundLoginAttempt(t *testing.T) {
+	store := NewStore(testDB)
+	arg := randomThrottle()
+	key := GetLoginThrottleParams{Scope: arg.Scope, Subject: arg.Subject}
+
+	_, err := store.BeginLoginAttemptTx(context.Background(), arg)
+	require.NoError(t, err)
+
+	for i := 0; i < 2; i++ {
+		err = testQueries.RefundLoginAttempt(context.Background(), RefundLoginAttemptParams(key))
+		require.NoError(t, err)
+
+		lt, err := testQueries.GetLoginThrottle(context.Background(), key)
+		require.NoError(t, err)
+		require.Zero(t, lt.Failures)
+	}
+}
+
+func TestBeginLoginAttemptTxWindow(t *testing.T) {
+	store := NewStore(testDB)
+	arg := randomThrottle()
+
+	_, err := store.BeginLoginAttemptTx(context.Background(), arg)
+	require.NoError(t, err)
+
+	// pretend the failure and an earlier lockout are older than the window
+	_, err = testQueries.UpdateLoginThrottle(context.Background(), UpdateLoginThrottleParams{
+		Scope:        arg.Scope,
+		Subject:      arg.Subject,
+		Failures:     arg.MaxFailures - 1,
+		Lockouts:     4,
+		LockedUntil:  time.Now().Add(-2 * arg.Window),
+		LastFailedAt: time.Now().Add(-3 * arg.Window),
+	})
+	require.NoError(t, err)
+
+	lt, err := store.BeginLoginAttemptTx(context.Background(), arg)
+	require.NoError(t, err)
+	require.Equal(t, int32(1), lt.Failures)
+	require.Zero(t, lt.Lockouts)
+}
+
+func TestBeginLoginAttemptTxConcurrent(t *testing.T) {
+	store := NewStore(testDB)
+	arg := randomThrottle()
+	arg.MaxFailures = 100
+
+	n := 10
+	errs := make(chan error)
+	for i := 0; i < n; i++ {
+		go func() {
+			_, err := store.BeginLoginAttemptTx(context.Background(), arg)
+			errs <- err
+		}()
+	}
+	for i := 0; i < n; i++ {
+		require.NoError(t, <-errs)
+	}
+
+	lt, err := testQueries.GetLoginThrottle(context.Background(), GetLoginThrottleParams{Scope: arg.Scope, Subject: arg.Subject})
+	require.NoError(t, err)
+	require.Equal(t, int32(n), lt.Failures)
+}
+
+func TestBeginLoginAttemptTxLetsOnlyMaxFailuresThrough(t *testing.T) {
+	store := NewStore(testDB)
+	arg := randomThrottle()
+
+	n := 10
+	allowed := make(chan bool)
+	for i := 0; i < n; i++ {
+		go func() {
+			lt, err := store.BeginLoginAttemptTx(context.Background(), arg)
+			allowed <- err == nil && !lt.LockedUntil.After(time.Now())
+		}()
+	}
+	got := 0
+	for i := 0; i < n; i++ {
+		if <-allowed {
+			got++
+		}
+	}
+	require.Equal(t, int(arg.MaxFailures), got)
+}
+
+func TestDeleteLoginThrottle(t *testing.T) {
+	store := NewStore(testDB)
+	arg := randomThrottle()
+
+	_, err := store.BeginLoginAttemptTx(context.Background(), arg)
+	require.NoError(t, err)
+
+	key := DeleteLoginThrottleParams{Scope: arg.Scope, Subject: arg.Subject}
+	n, err := testQueries.DeleteLoginThrottle(context.Background(), key)
+	require.NoError(t, err)
+	require.Equal(t, int64(1), n)
+
+	_, err = testQueries.GetLoginThrottle(context.Background(), GetLoginThrottleParams(key))
+	require.ErrorIs(t, err, sql.ErrNoRows)
+
+	n, err = testQueries.DeleteLoginThrottle(context.Background(), key)
+	require.NoError(t, err)
+	require.Zero(t, n)
+}
+
+func TestLockoutDuration(t *testing.T) {
+	require.Equal(t, time.Minute, LockoutDuration(time.Minute, time.Hour, 1))
+	require.Equal(t, 2*time.Minute, LockoutDuration(time.Minute, time.Hour, 2))
+	require.Equal(t, 32*time.Minute, LockoutDuration(time.Minute, time.Hour, 6))
+	require.Equal(t, time.Hour, LockoutDuration(time.Minute, time.Hour, 7))
+	require.Equal(t, time.Hour, LockoutDuration(time.Minute, time.Hour, 1000))
+}
@@ -3,7 +3,11 @@
 package db
 
 import (
+	"database/sql"
+	"encoding/json"
 	"time"
+
+	"github.com/google/uuid"
 )
 
 type Account struct {
@@ -12,14 +16,230 @@ type Account struct {
 	Balance   int64     `json:"balance"`
 	Currency  string    `json:"currency"`
 	CreatedAt time.Time `json:"created_at"`
+	// transfers of at least this amount from a joint account need every holder to approve, 0 for no rule
+	JointApprovalThreshold int64 `json:"joint_approval_threshold"`
+	// IBAN-style external account number with mod-97 check digits
+	Number string `json:"number"`
+}
+
+type AccountConsent struct {
+	ID       int64  `json:"id"`
+	ClientID string `json:"client_id"`
+	// the user who authorised the consent, unknown until then
+	Username    sql.NullString `json:"username"`
+	Permissions []string       `json:"permissions"`
+	// accounts the user chose to share with the app
+	AccountIds []int64 `json:"account_ids"`
+	// awaiting_authorisation, authorised or revoked
+	Status       string       `json:"status"`
+	ExpiresAt    time.Time    `json:"expires_at"`
+	AuthorisedAt sql.NullTime `json:"authorised_at"`
+	RevokedAt    sql.NullTime `json:"revoked_at"`
+	CreatedAt    time.Time    `json:"created_at"`
+}
+
+type AccountHolder struct {
+	AccountID int64  `json:"account_id"`
+	Username  string `json:"username"`
+	// primary for the owner, who manages the account, or secondary
+	Role      string    `json:"role"`
+	CreatedAt time.Time `json:"created_at"`
+}
+
+type AccountPermission struct {
+	AccountID int64  `json:"account_id"`
+	Username  string `json:"username"`
+	// read, or transfer which includes read
+	Access    string    `json:"access"`
+	GrantedBy string    `json:"granted_by"`
+	CreatedAt time.Time `json:"created_at"`
+}
+
+type ApiKey struct {
+	ID       int64  `json:"id"`
+	Username string `json:"username"`
+	Name     string `json:"name"`
+	// public part of the key, used to look it up
+	Prefix string `json:"prefix"`
+	// sha256 of the secret part of the key, which is only shown once
+	SecretHash string       `json:"secret_hash"`
+	Scopes     []string     `json:"scopes"`
+	ExpiresAt  sql.NullTime `json:"expires_at"`
+	LastUsedAt sql.NullTime `json:"last_used_at"`
+	RevokedAt  sql.NullTime `json:"revoked_at"`
+	CreatedAt  time.Time    `json:"created_at"`
+}
+
+type ApprovalPolicy struct {
+	ID        int64 `json:"id"`
+	AccountID int64 `json:"account_id"`
+	// the policy with the highest min_amount not above a transfer amount applies to it
+	MinAmount int64 `json:"min_amount"`
+	// approvals needed besides the one of the user who drafted the transfer
+	RequiredApprovals int32     `json:"required_approvals"`
+	CreatedAt         time.Time `json:"created_at"`
 }
 
 type Entry struct {
 	ID        int64 `json:"id"`
 	AccountID int64 `json:"account_id"`
 	// can be negative or positive
-	Amount    int64     `json:"amount"`
+	Amount     int64         `json:"amount"`
+	CreatedAt  time.Time     `json:"created_at"`
+	TransferID sql.NullInt64 `json:"transfer_id"`
+	// account balance right after this entry was applied
+	BalanceAfter int64 `json:"balance_after"`
+	// transaction that wrote the entry; account update streams resume from a transaction id
+	Txid int64 `json:"-"`
+}
+
+type Job struct {
+	ID      int64           `json:"id"`
+	Type    string          `json:"type"`
+	Payload json.RawMessage `json:"payload"`
+	// pending, running, completed or dead
+	Status      string       `json:"status"`
+	Attempts    int32        `json:"attempts"`
+	MaxAttempts int32        `json:"max_attempts"`
+	LastError   string       `json:"last_error"`
+	RunAt       time.Time    `json:"run_at"`
+	LockedAt    sql.NullTime `json:"locked_at"`
+	CompletedAt sql.NullTime `json:"completed_at"`
+	CreatedAt   time.Time    `json:"created_at"`
+}
+
+type LoginThrottle struct {
+	// user or ip
+	Scope string `json:"scope"`
+	// the username or client ip that failed to log in
+	Subject  string `json:"subject"`
+	Failures int32  `json:"failures"`
+	// lockouts in a row, each one twice as long as the one before
+	Lockouts     int32     `json:"lockouts"`
+	LockedUntil  time.Time `json:"locked_until"`
+	LastFailedAt time.Time `json:"last_failed_at"`
+}
+
+type OauthAuthorizationCode struct {
+	CodeHash    string   `json:"code_hash"`
+	ClientID    string   `json:"client_id"`
+	Username    string   `json:"username"`
+	RedirectUri string   `json:"redirect_uri"`
+	Scopes      []string `json:"scopes"`
+	// PKCE S256 challenge the code verifier must match
+	CodeChallenge string       `json:"code_challenge"`
+	ExpiresAt     time.Time    `json:"expires_at"`
+	UsedAt        sql.NullTime `json:"used_at"`
+	CreatedAt     time.Time    `json:"created_at"`
+	// account consent the tokens got for this code are bound to
+	ConsentID sql.NullInt64 `json:"consent_id"`
+}
+
+type OauthClient struct {
+	ID    string `json:"id"`
+	Owner string `json:"owner"`
+	Name  string `json:"name"`
+	// sha256 of the client secret; empty for public clients, which must use PKCE
+	SecretHash   string    `json:"secret_hash"`
+	RedirectUris []string  `json:"redirect_uris"`
+	Scopes       []string  `json:"scopes"`
+	CreatedAt    time.Time `json:"created_at"`
+}
+
+type OauthConsent struct {
+	Username string `json:"username"`
+	ClientID string `json:"client_id"`
+	// what the user allowed the client to do on their behalf
+	Scopes    []string  `json:"scopes"`
 	CreatedAt time.Time `json:"created_at"`
+	UpdatedAt time.Time `json:"updated_at"`
+}
+
+type OauthRevokedToken struct {
+	TokenID uuid.UUID `json:"token_id"`
+	// when the token would have expired anyway, after which the row can go
+	ExpiresAt time.Time `json:"expires_at"`
+}
+
+type OutboxEvent struct {
+	ID            int64  `json:"id"`
+	AggregateType string `json:"aggregate_type"`
+	AggregateID   string `json:"aggregate_id"`
+	// TransferCompleted, AccountCreated or UserCreated
+	EventType   string          `json:"event_type"`
+	Payload     json.RawMessage `json:"payload"`
+	CreatedAt   time.Time       `json:"created_at"`
+	PublishedAt sql.NullTime    `json:"published_at"`
+	// transaction that wrote the event; the relay waits until every older transaction has ended
+	Txid int64 `json:"txid"`
+}
+
+type PasswordResetToken struct {
+	ID       int64  `json:"id"`
+	Username string `json:"username"`
+	// sha256 of the token, which is only ever sent to the user; null until it is
+	TokenHash sql.NullString `json:"token_hash"`
+	ExpiredAt time.Time      `json:"expired_at"`
+	UsedAt    sql.NullTime   `json:"used_at"`
+	CreatedAt time.Time      `json:"created_at"`
+}
+
+type Payee struct {
+	ID        int64  `json:"id"`
+	Owner     string `json:"owner"`
+	Nickname  string `json:"nickname"`
+	AccountID int64  `json:"account_id"`
+	Currency  string `json:"currency"`
+	// large transfers to a payee wait for the cooling-off period to pass from here
+	CreatedAt time.Time `json:"created_at"`
+}
+
+type PaymentBatch struct {
+	ID           int64  `json:"id"`
+	Owner        string `json:"owner"`
+	SourceFormat string `json:"source_format"`
+	// pending, completed, partially_failed or failed
+	Status     string       `json:"status"`
+	ItemCount  int32        `json:"item_count"`
+	CreatedAt  time.Time    `json:"created_at"`
+	ExecutedAt sql.NullTime `json:"executed_at"`
+	// id the client gave the payment file (pain.001 MsgId), empty if the format has none
+	MessageID string `json:"message_id"`
+}
+
+type PaymentBatchItem struct {
+	ID            int64  `json:"id"`
+	BatchID       int64  `json:"batch_id"`
+	Line          int32  `json:"line"`
+	FromAccountID int64  `json:"from_account_id"`
+	ToAccountID   int64  `json:"to_account_id"`
+	Amount        int64  `json:"amount"`
+	Currency      string `json:"currency"`
+	Memo          string `json:"memo"`
+	EndToEndID    string `json:"end_to_end_id"`
+	// pending, completed or failed
+	Status     string        `json:"status"`
+	Error      string        `json:"error"`
+	TransferID sql.NullInt64 `json:"transfer_id"`
+	CreatedAt  time.Time     `json:"created_at"`
+}
+
+type RecoveryCode struct {
+	ID        int64        `json:"id"`
+	Username  string       `json:"username"`
+	CodeHash  string       `json:"code_hash"`
+	UsedAt    sql.NullTime `json:"used_at"`
+	CreatedAt time.Time    `json:"created_at"`
+}
+
+type TotpEnrollment struct {
+	Username string `json:"username"`
+	Secret   string `json:"secret"`
+	// time step of the last accepted code, so a code cannot be replayed
+	LastUsedStep int64 `json:"last_used_step"`
+	// null while enrollment waits for the first valid code
+	ConfirmedAt sql.NullTime `json:"confirmed_at"`
+	CreatedAt   time.Time    `json:"created_at"`
 }
 
 type Transfer struct {
@@ -29,6 +249,33 @@ type Transfer struct {
 	// must be positive
 	Amount    int64     `json:"amount"`
 	CreatedAt time.Time `json:"created_at"`
+	// free text shown on both parties statements
+	Memo string `json:"memo"`
+}
+
+type TransferDraft struct {
+	ID            int64  `json:"id"`
+	FromAccountID int64  `json:"from_account_id"`
+	ToAccountID   int64  `json:"to_account_id"`
+	Amount        int64  `json:"amount"`
+	Memo          string `json:"memo"`
+	CreatedBy     string `json:"created_by"`
+	// approvals needed, counting the one of created_by
+	RequiredApprovals int32 `json:"required_approvals"`
+	// pending, executed or rejected
+	Status string `json:"status"`
+	// the transfer made once the draft was approved
+	TransferID sql.NullInt64 `json:"transfer_id"`
+	DecidedAt  sql.NullTime  `json:"decided_at"`
+	CreatedAt  time.Time     `json:"created_at"`
+	// the user whose approval executed the draft, or who rejected it
+	DecidedBy sql.NullString `json:"decided_by"`
+}
+
+type TransferDraftApproval struct {
+	DraftID   int64     `json:"draft_id"`
+	Username  string    `json:"username"`
+	CreatedAt time.Time `json:"created_at"`
 }
 
 type User struct {
@@ -38,4 +285,42 @@ type User struct {
 	Email             string    `json:"email"`
 	PasswordChangedAt time.Time `json:"password_changed_at"`
 	CreatedAt         time.Time `json:"created_at"`
+	IsEmailVerified   bool      `json:"is_email_verified"`
+}
+
+type VerifyEmail struct {
+	ID       int64  `json:"id"`
+	Username string `json:"username"`
+	Email    string `json:"email"`
+	// sha256 of the code in the emailed link, empty until the email is sent
+	SecretCode string    `json:"secret_code"`
+	IsUsed     bool      `json:"is_used"`
+	CreatedAt  time.Time `json:"created_at"`
+	ExpiredAt  time.Time `json:"expired_at"`
+}
+
+type WebhookDelivery struct {
+	ID         int64  `json:"id"`
+	EndpointID int64  `json:"endpoint_id"`
+	EventID    int64  `json:"event_id"`
+	EventType  string `json:"event_type"`
+	// exact request body, so redeliveries are byte for byte identical
+	Payload json.RawMessage `json:"payload"`
+	// pending, succeeded or failed
+	Status         string       `json:"status"`
+	Attempts       int32        `json:"attempts"`
+	ResponseStatus int32        `json:"response_status"`
+	LastError      string       `json:"last_error"`
+	NextAttemptAt  time.Time    `json:"next_attempt_at"`
+	DeliveredAt    sql.NullTime `json:"delivered_at"`
+	CreatedAt      time.Time    `json:"created_at"`
+}
+
+type WebhookEndpoint struct {
+	ID         int64     `json:"id"`
+	Owner      string    `json:"owner"`
+	Url        string    `json:"url"`
+	EventTypes []string  `json:"event_types"`
+	Secret     string    `json:"secret"`
+	CreatedAt  time.Time `json:"created_at"`
 }
@@ -0,0 +1,63 @@
+package db
+
+import (
+	"context"
+	"database/sql"
+)
+
+// Statuses of an account consent. Only authorised consents give access.
+const (
+	ConsentAwaitingAuthorisation = "awaiting_authorisation"
+	ConsentAuthorised            = "authorised"
+	ConsentRevoked               = "revoked"
+)
+
+// JobDeleteExpiredRevokedOAuthTokens clears the revocations of tokens that have expired since. It runs on a
+// schedule and has no payload.
+const JobDeleteExpiredRevokedOAuthTokens = "delete_expired_revoked_oauth_tokens"
+
+// CreateOAuthClientTx registers a client and records its owner's consent to the client's scopes, so the
+// client credentials grant, which acts as the owner, can be cut off like any other consent.
+func (s *SQLStore) CreateOAuthClientTx(ctx context.Context, arg CreateOAuthClientParams) (OauthClient, error) {
+	var client OauthClient
+	err := s.execTx(ctx, func(q *Queries) error {
+		var err error
+		client, err = q.CreateOAuthClient(ctx, arg)
+		if err != nil {
+			return err
+		}
+		_, err = q.UpsertOAuthConsent(ctx, UpsertOAuthConsentParams{
+			Username: client.Owner,
+			ClientID: client.ID,
+			Scopes:   client.Scopes,
+		})
+		return err
+	})
+	return client, err
+}
+
+type AuthorizeOAuthClientTxParams struct {
+	Consent UpsertOAuthConsentParams
+	Code    CreateOAuthAuthorizationCodeParams
+	// AccountConsent, if set, is authorised as well and the code bound to it
+	AccountConsent *AuthoriseAccountConsentParams
+}
+
+// AuthorizeOAuthClientTx records a user's consent to a client and stores the authorization code handed out
+// for it. It returns sql.ErrNoRows if the account consent is no longer awaiting authorisation.
+func (s *SQLStore) AuthorizeOAuthClientTx(ctx context.Context, arg AuthorizeOAuthClientTxParams) error {
+	return s.execTx(ctx, func(q *Queries) error {
+		if _, err := q.UpsertOAuthConsent(ctx, arg.Consent); err != nil {
+			return err
+		}
+		code := arg.Code
+		if arg.AccountConsent != nil {
+			consent, err := q.AuthoriseAccountConsent(ctx, *arg.AccountConsent)
+			if err != nil {
+				return err
+			}
+			code.ConsentID = sql.NullInt64{Int64: consent.ID, Valid: true}
+		}
+		return q.CreateOAuthAuthorizationCode(ctx, code)
+	})
+}
@@ -0,0 +1,160 @@
+package db
+
+import (
+	"context"
+	"database/sql"
+	"gobank/util"
+	"testing"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/require"
+)
+
+func createRandomOAuthClient(t *testing.T) OauthClient {
+	owner := createRandomUser(t)
+	arg := CreateOAuthClientParams{
+		ID:           util.RandomString(32),
+		Owner:        owner.Username,
+		Name:         util.RandomOwner(),
+		SecretHash:   util.RandomString(64),
+		RedirectUris: []string{"https://app.example.com/callback"},
+		Scopes:       []string{"accounts:read"},
+	}
+
+	store := NewStore(testDB)
+	client, err := store.CreateOAuthClientTx(context.Background(), arg)
+	require.NoError(t, err)
+	require.Equal(t, arg.ID, client.ID)
+	require.Equal(t, arg.Owner, client.Owner)
+	require.Equal(t, arg.RedirectUris, client.RedirectUris)
+	require.Equal(t, arg.Scopes, client.Scopes)
+	require.NotZero(t, client.CreatedAt)
+
+	return client
+}
+
+func TestCreateOAuthClientTx(t *testing.T) {
+	client := createRandomOAuthClient(t)
+
+	// the owner consents to their own client
+	consent, err := testQueries.GetOAuthConsent(context.Background(), GetOAuthConsentParams{
+		Username: client.Owner,
+		ClientID: client.ID,
+	})
+	require.NoError(t, err)
+	require.Equal(t, client.Scopes, consent.Scopes)
+}
+
+func TestUpsertOAuthConsent(t *testing.T) {
+	client := createRandomOAuthClient(t)
+	user := createRandomUser(t)
+	arg := UpsertOAuthConsentParams{
+		Username: user.Username,
+		ClientID: client.ID,
+		Scopes:   []string{"accounts:read", "transfers:write"},
+	}
+
+	consent1, err := testQueries.UpsertOAuthConsent(context.Background(), arg)
+	require.NoError(t, err)
+	require.Equal(t, arg.Scopes, consent1.Scopes)
+
+	arg.Scopes = []string{"accounts:read"}
+	consent2, err := testQueries.UpsertOAuthConsent(context.Background(), arg)
+	require.NoError(t, err)
+	require.Equal(t, arg.Scopes, consent2.Scopes)
+	require.Equal(t, consent1.CreatedAt, consent2.CreatedAt)
+	require.True(t, consent2.UpdatedAt.After(consent1.UpdatedAt))
+
+	consents, err := testQueries.ListOAuthConsents(context.Background(), user.Username)
+	require.NoError(t, err)
+	require.Len(t, consents, 1)
+
+	n, err := testQueries.DeleteOAuthConsent(context.Background(), DeleteOAuthConsentParams{
+		Username: user.Username,
+		ClientID: client.ID,
+	})
+	require.NoError(t, err)
+	require.Equal(t, int64(1), n)
+
+	_, err = testQueries.GetOAuthConsent(context.Background(), GetOAuthConsentParams{
+		Username: user.Username,
+		ClientID: client.ID,
+	})
+	require.EqualError(t, err, sql.ErrNoRows.Error())
+}
+
+func TestUseOAuthAuthorizationCode(t *testing.T) {
+	client := createRandomOAuthClient(t)
+	arg := CreateOAuthAuthorizationCodeParams{
+		CodeHash:      util.RandomString(64),
+		ClientID:      client.ID,
+		Username:      client.Owner,
+		RedirectUri:   client.RedirectUris[0],
+		Scopes:        client.Scopes,
+		CodeChallenge: util.RandomString(43),
+		ExpiresAt:     time.Now().Add(time.Minute),
+	}
+	require.NoError(t, testQueries.CreateOAuthAuthorizationCode(context.Background(), arg))
+
+	code, err := testQueries.UseOAuthAuthorizationCode(context.Background(), arg.CodeHash)
+	require.NoError(t, err)
+	require.Equal(t, arg.Username, code.Username)
+	require.Equal(t, arg.CodeChallenge, code.CodeChallenge)
+	require.True(t, code.UsedAt.Valid)
+
+	// codes work only once
+	_, err = testQueries.UseOAuthAuthorizationCode(context.Background(), arg.CodeHash)
+	require.EqualError(t, err, sql.ErrNoRows.Error())
+}
+
+func TestUseExpiredOAuthAuthorizationCode(t *testing.T) {
+	client := createRandomOAuthClient(t)
+	arg := CreateOAuthAuthorizationCodeParams{
+		CodeHash:      util.RandomString(64),
+		ClientID:      client.ID,
+		Username:      client.Owner,
+		RedirectUri:   client.RedirectUris[0],
+		Scopes:        client.Scopes,
+		CodeChallenge: util.RandomString(43),
+		ExpiresAt:     time.Now().Add(-time.Second),
+	}
+	require.NoError(t, testQueries.CreateOAuthAuthorizationCode(context.Background(), arg))
+
+	_, err := testQueries.UseOAuthAuthorizationCode(context.Background(), arg.CodeHash)
+	require.EqualError(t, err, sql.ErrNoRows.Error())
+}
+
+func TestRevokeOAuthToken(t *testing.T) {
+	arg := RevokeOAuthTokenParams{
+		TokenID:   uuid.New(),
+		ExpiresAt: time.Now().Add(time.Hour),
+	}
+
+	_, err := testQueries.GetRevokedOAuthToken(context.Background(), arg.TokenID)
+	require.EqualError(t, err, sql.ErrNoRows.Error())
+
+	require.NoError(t, testQueries.RevokeOAuthToken(context.Background(), arg))
+	// revoking again is harmless
+	require.NoError(t, testQueries.RevokeOAuthToken(context.Background(), arg))
+
+	revoked, err := testQueries.GetRevokedOAuthToken(context.Background(), arg.TokenID)
+	require.NoError(t, err)
+	require.Equal(t, arg.TokenID, revoked.TokenID)
+}
+
+func TestDeleteExpiredRevokedOAuthTokens(t *testing.T) {
+	expired := RevokeOAuthTokenParams{TokenID: uuid.New(), ExpiresAt: time.Now().Add(-time.Minute)}
+	live := RevokeOAuthTokenParams{TokenID: uuid.New(), ExpiresAt: time.Now().Add(time.Hour)}
+	require.NoError(t, testQueries.RevokeOAuthToken(context.Background(), expired))
+	require.NoError(t, testQueries.RevokeOAuthToken(context.Background(), live))
+
+	n, err := testQueries.DeleteExpiredRevokedOAuthTokens(context.Background())
+	require.NoError(t, err)
+	require.GreaterOrEqual(t, n, int64(1))
+
+	_, err = testQueries.GetRevokedOAuthToken(context.Background(), expired.TokenID)
+	require.EqualError(t, err, sql.ErrNoRows.Error())
+	_, err = testQueries.GetRevokedOAuthToken(context.Background(), live.TokenID)
+	require.NoError(t, err)
+}
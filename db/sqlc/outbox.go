@@ -0,0 +1,113 @@
+package db
+
+import (
+	"context"
+	"encoding/json"
+	"strconv"
+	"time"
+)
+
+// Domain event types written to the outbox.
+const (
+	EventTransferCompleted = "TransferCompleted"
+	EventAccountCreated    = "AccountCreated"
+	EventUserCreated       = "UserCreated"
+)
+
+// UserCreatedEvent is the payload of a UserCreated event; it never carries the password hash.
+type UserCreatedEvent struct {
+	Username  string    `json:"username"`
+	FullName  string    `json:"full_name"`
+	Email     string    `json:"email"`
+	CreatedAt time.Time `json:"created_at"`
+}
+
+// addOutboxEvent stores an event in the outbox using q, so it commits or rolls back with the change it describes.
+func addOutboxEvent(ctx context.Context, q *Queries, aggregateType string, aggregateID string, eventType string, payload interface{}) error {
+	data, err := json.Marshal(payload)
+	if err != nil {
+		return err
+	}
+	_, err = q.CreateOutboxEvent(ctx, CreateOutboxEventParams{
+		AggregateType: aggregateType,
+		AggregateID:   aggregateID,
+		EventType:     eventType,
+		Payload:       data,
+	})
+	return err
+}
+
+// CreateAccountTx creates an account, makes its owner the primary holder and records an AccountCreated
+// event in the same transaction.
+func (s *SQLStore) CreateAccountTx(ctx context.Context, arg CreateAccountParams) (Account, error) {
+	var account Account
+	err := s.execTx(ctx, func(q *Queries) error {
+		var err error
+		account, err = q.CreateAccount(ctx, arg)
+		if err != nil {
+			return err
+		}
+		_, err = q.CreateAccountHolder(ctx, CreateAccountHolderParams{
+			AccountID: account.ID,
+			Username:  account.Owner,
+			Role:      HolderPrimary,
+		})
+		if err != nil {
+			return err
+		}
+		return addOutboxEvent(ctx, q, "account", strconv.FormatInt(account.ID, 10), EventAccountCreated, account)
+	})
+	return account, err
+}
+
+// CreateUserTx creates a user, queues the email verification and records a UserCreated event in the same transaction.
+func (s *SQLStore) CreateUserTx(ctx context.Context, arg CreateUserParams) (User, error) {
+	var user User
+	err := s.execTx(ctx, func(q *Queries) error {
+		var err error
+		user, err = q.CreateUser(ctx, arg)
+		if err != nil {
+			return err
+		}
+		if err := addVerifyEmail(ctx, q, user); err != nil {
+			return err
+		}
+		return addOutboxEvent(ctx, q, "user", user.Username, EventUserCreated, UserCreatedEvent{
+			Username:  user.Username,
+			FullName:  user.FullName,
+			Email:     user.Email,
+			CreatedAt: user.CreatedAt,
+		})
+	})
+	return user, err
+}
+
+// RelayOutboxEvents hands up to limit unpublished events to publish in id order and marks each one published.
+// An event waits until every transaction older than its own has ended, so no event with a lower id can commit
+// after it is published; a long-running transaction therefore holds the relay back.
+// Events stay locked until the transaction ends, so concurrent relays cannot publish out of order. The first
+// publish failure stops the run; events published before it are still marked and the error is returned.
+func (s *SQLStore) RelayOutboxEvents(ctx context.Context, limit int32, publish func(OutboxEvent) error) (int, error) {
+	published := 0
+	var publishErr error
+	err := s.execTx(ctx, func(q *Queries) error {
+		events, err := q.ListUnpublishedOutboxEvents(ctx, limit)
+		if err != nil {
+			return err
+		}
+		for _, e := range events {
+			if publishErr = publish(e); publishErr != nil {
+				return nil
+			}
+			if err := q.MarkOutboxEventPublished(ctx, e.ID); err != nil {
+				return err
+			}
+			published++
+		}
+		return nil
+	})
+	if err != nil {
+		return 0, err
+	}
+	return published, publishErr
+}
@@ -0,0 +1,124 @@
+package db
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"gobank/util"
+	"strconv"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+// drainOutbox publishes everything pending so each test only sees the events it caused.
+func drainOutbox(t *testing.T, store Store) {
+	for {
+		n, err := store.RelayOutboxEvents(context.Background(), 1000, func(OutboxEvent) error { return nil })
+		require.NoError(t, err)
+		if n == 0 {
+			return
+		}
+	}
+}
+
+func TestOutboxEvents(t *testing.T) {
+	store := NewStore(testDB)
+	drainOutbox(t, store)
+
+	user, err := store.CreateUserTx(context.Background(), CreateUserParams{
+		Username:       util.RandomOwner(),
+		HashedPassword: "secret",
+		FullName:       util.RandomOwner(),
+		Email:          util.RandomEmail(),
+	})
+	require.NoError(t, err)
+
+	acc1, err := store.CreateAccountTx(context.Background(), CreateAccountParams{
+		Owner:    user.Username,
+		Balance:  100,
+		Currency: util.USD,
+		Number:   util.RandomAccountNumber(),
+	})
+	require.NoError(t, err)
+	acc2 := createRandomAccount(t)
+
+	result, err := store.TransferTx(context.Background(), TransferTxParams{
+		FromAccountID: acc1.ID,
+		ToAccountID:   acc2.ID,
+		Amount:        10,
+	})
+	require.NoError(t, err)
+
+	// a failing publisher leaves everything in the outbox
+	n, err := store.RelayOutboxEvents(context.Background(), 10, func(OutboxEvent) error {
+		return errors.New("broker unavailable")
+	})
+	require.Error(t, err)
+	require.Zero(t, n)
+
+	var events []OutboxEvent
+	n, err = store.RelayOutboxEvents(context.Background(), 10, func(e OutboxEvent) error {
+		events = append(events, e)
+		return nil
+	})
+	require.NoError(t, err)
+	require.Equal(t, 3, n)
+
+	require.Equal(t, EventUserCreated, events[0].EventType)
+	require.Equal(t, user.Username, events[0].AggregateID)
+	require.NotContains(t, string(events[0].Payload), "secret")
+
+	require.Equal(t, EventAccountCreated, events[1].EventType)
+	require.Equal(t, strconv.FormatInt(acc1.ID, 10), events[1].AggregateID)
+
+	require.Equal(t, EventTransferCompleted, events[2].EventType)
+	var payload TransferTxResult
+	require.NoError(t, json.Unmarshal(events[2].Payload, &payload))
+	require.Equal(t, result.Transfer.ID, payload.Transfer.ID)
+	require.Equal(t, result.FromEntry.ID, payload.FromEntry.ID)
+
+	n, err = store.RelayOutboxEvents(context.Background(), 10, func(OutboxEvent) error { return nil })
+	require.NoError(t, err)
+	require.Zero(t, n)
+}
+
+func TestRelayOutboxEventsWaitsForOlderTransactions(t *testing.T) {
+	store := NewStore(testDB)
+	drainOutbox(t, store)
+
+	event := CreateOutboxEventParams{
+		AggregateType: "test",
+		AggregateID:   util.RandomOwner(),
+		EventType:     "Test",
+		Payload:       json.RawMessage(`{}`),
+	}
+
+	// slow takes the lower id but commits after fast
+	slow, err := testDB.Begin()
+	require.NoError(t, err)
+	defer slow.Rollback()
+	first, err := New(slow).CreateOutboxEvent(context.Background(), event)
+	require.NoError(t, err)
+
+	second, err := testQueries.CreateOutboxEvent(context.Background(), event)
+	require.NoError(t, err)
+	require.Greater(t, second.ID, first.ID)
+
+	var published []int64
+	relay := func(e OutboxEvent) error {
+		published = append(published, e.ID)
+		return nil
+	}
+
+	n, err := store.RelayOutboxEvents(context.Background(), 10, relay)
+	require.NoError(t, err)
+	require.Zero(t, n, "second must wait for the older transaction")
+
+	require.NoError(t, slow.Commit())
+
+	n, err = store.RelayOutboxEvents(context.Background(), 10, relay)
+	require.NoError(t, err)
+	require.Equal(t, 2, n)
+	require.Equal(t, []int64{first.ID, second.ID}, published)
+}
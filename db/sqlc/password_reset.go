@@ -0,0 +1,64 @@
+package db
+
+import (
+	"context"
+	"gobank/util"
+	"time"
+)
+
+const (
+	// JobSendPasswordReset mints the token of a SendPasswordResetPayload and emails it.
+	JobSendPasswordReset = "send_password_reset"
+
+	PasswordResetDuration = time.Hour
+)
+
+type SendPasswordResetPayload struct {
+	PasswordResetID int64 `json:"password_reset_id"`
+}
+
+// CreatePasswordResetTx starts a password reset for username and queues the email carrying its token. The
+// mail job mints the token, so the plain token only ever exists in the email.
+func (s *SQLStore) CreatePasswordResetTx(ctx context.Context, username string) (PasswordResetToken, error) {
+	var prt PasswordResetToken
+	err := s.execTx(ctx, func(q *Queries) error {
+		var err error
+		prt, err = q.CreatePasswordResetToken(ctx, CreatePasswordResetTokenParams{
+			Username:  username,
+			ExpiredAt: time.Now().Add(PasswordResetDuration),
+		})
+		if err != nil {
+			return err
+		}
+		_, err = q.EnqueueJob(ctx, JobSendPasswordReset, SendPasswordResetPayload{PasswordResetID: prt.ID})
+		return err
+	})
+	return prt, err
+}
+
+type ResetPasswordTxParams struct {
+	Token          string
+	HashedPassword string
+}
+
+// ResetPasswordTx uses up a reset token and sets the user's new password. Every other outstanding token of
+// the user is invalidated too. An unknown, used or expired token returns sql.ErrNoRows.
+func (s *SQLStore) ResetPasswordTx(ctx context.Context, arg ResetPasswordTxParams) (User, error) {
+	var user User
+	err := s.execTx(ctx, func(q *Queries) error {
+		prt, err := q.UsePasswordResetToken(ctx, util.HashToken(arg.Token))
+		if err != nil {
+			return err
+		}
+		user, err = q.UpdateUserPassword(ctx, UpdateUserPasswordParams{
+			Username:          prt.Username,
+			HashedPassword:    arg.HashedPassword,
+			PasswordChangedAt: time.Now(),
+		})
+		if err != nil {
+			return err
+		}
+		return q.InvalidatePasswordResetTokens(ctx, prt.Username)
+	})
+	return user, err
+}
@@ -0,0 +1,142 @@
+package db
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"gobank/util"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+)
+
+// requestPasswordReset issues a reset for user, mints its token as the mail job would and returns it.
+func requestPasswordReset(t *testing.T, store Store, user User) (PasswordResetToken, string) {
+	prt, err := store.CreatePasswordResetTx(context.Background(), user.Username)
+	require.NoError(t, err)
+	require.Equal(t, user.Username, prt.Username)
+	require.WithinDuration(t, time.Now().Add(PasswordResetDuration), prt.ExpiredAt, time.Minute)
+
+	jobs, err := store.ClaimJobs(context.Background(), 1000)
+	require.NoError(t, err)
+	for _, job := range jobs {
+		if job.Type != JobSendPasswordReset {
+			continue
+		}
+		var p SendPasswordResetPayload
+		require.NoError(t, json.Unmarshal(job.Payload, &p))
+		if p.PasswordResetID == prt.ID {
+			// the token does not exist until the job mints it
+			require.False(t, prt.TokenHash.Valid)
+			require.NotContains(t, string(job.Payload), "token\"")
+
+			token := util.RandomString(32)
+			prt, err = store.SetPasswordResetTokenHash(context.Background(), SetPasswordResetTokenHashParams{
+				ID:        prt.ID,
+				TokenHash: util.HashToken(token),
+			})
+			require.NoError(t, err)
+			return prt, token
+		}
+	}
+	t.Fatalf("no email queued for password reset %d", prt.ID)
+	return prt, ""
+}
+
+func TestResetPasswordTx(t *testing.T) {
+	store := NewStore(testDB)
+	user := createRandomUser(t)
+
+	_, older := requestPasswordReset(t, store, user)
+	_, token := requestPasswordReset(t, store, user)
+
+	_, err := store.ResetPasswordTx(context.Background(), ResetPasswordTxParams{Token: "wrong", HashedPassword: "new"})
+	require.ErrorIs(t, err, sql.ErrNoRows)
+
+	updated, err := store.ResetPasswordTx(context.Background(), ResetPasswordTxParams{Token: token, HashedPassword: "new"})
+	require.NoError(t, err)
+	require.Equal(t, "new", updated.HashedPassword)
+	require.WithinDuration(t, time.Now(), updated.PasswordChangedAt, time.Minute)
+
+	// the token is single use and resetting invalidates the user's other tokens
+	_, err = store.ResetPasswordTx(context.Background(), ResetPasswordTxParams{Token: token, HashedPassword: "again"})
+	require.ErrorIs(t, err, sql.ErrNoRows)
+	_, err = store.ResetPasswordTx(context.Background(), ResetPasswordTxParams{Token: older, HashedPassword: "again"})
+	require.ErrorIs(t, err, sql.ErrNoRows)
+}
+
+func TestResetPasswordTxExpired(t *testing.T) {
+	store := NewStore(testDB)
+	user := createRandomUser(t)
+
+	token := util.RandomString(32)
+	_, err := testQueries.CreatePasswordResetToken(context.Background(), CreatePasswordResetTokenParams{
+		Username:  user.Username,
+		TokenHash: sql.NullString{String: util.HashToken(token), Valid: true},
+		ExpiredAt: time.Now().Add(-time.Minute),
+	})
+	require.NoError(t, err)
+
+	_, err = store.ResetPasswordTx(context.Background(), ResetPasswordTxParams{Token: token, HashedPassword: "new"})
+	require.ErrorIs(t, err, sql.ErrNoRows)
+}
+
+func TestGetValidPasswordResetToken(t *testing.T) {
+	store := NewStore(testDB)
+	user := createRandomUser(t)
+
+	prt, token := requestPasswordReset(t, store, user)
+	got, err := testQueries.GetValidPasswordResetToken(context.Background(), util.HashToken(token))
+	require.NoError(t, err)
+	require.Equal(t, prt.ID, got.ID)
+	require.Equal(t, user.Username, got.Username)
+
+	_, err = store.ResetPasswordTx(context.Background(), ResetPasswordTxParams{Token: token, HashedPassword: "new"})
+	require.NoError(t, err)
+
+	// used tokens are not found any more
+	_, err = testQueries.GetValidPasswordResetToken(context.Background(), util.HashToken(token))
+	require.ErrorIs(t, err, sql.ErrNoRows)
+}
+
+func TestSetPasswordResetTokenHash(t *testing.T) {
+	store := NewStore(testDB)
+	user := createRandomUser(t)
+
+	prt, token := requestPasswordReset(t, store, user)
+
+	// a retried email replaces the token
+	retried := util.RandomString(32)
+	_, err := store.SetPasswordResetTokenHash(context.Background(), SetPasswordResetTokenHashParams{
+		ID:        prt.ID,
+		TokenHash: util.HashToken(retried),
+	})
+	require.NoError(t, err)
+	_, err = store.ResetPasswordTx(context.Background(), ResetPasswordTxParams{Token: token, HashedPassword: "new"})
+	require.ErrorIs(t, err, sql.ErrNoRows)
+	_, err = store.ResetPasswordTx(context.Background(), ResetPasswordTxParams{Token: retried, HashedPassword: "new"})
+	require.NoError(t, err)
+
+	// a used reset cannot be given a new token
+	_, err = store.SetPasswordResetTokenHash(context.Background(), SetPasswordResetTokenHashParams{
+		ID:        prt.ID,
+		TokenHash: util.HashToken(util.RandomString(32)),
+	})
+	require.ErrorIs(t, err, sql.ErrNoRows)
+}
+
+func TestGetLatestPasswordResetToken(t *testing.T) {
+	store := NewStore(testDB)
+	user := createRandomUser(t)
+
+	_, err := store.GetLatestPasswordResetToken(context.Background(), user.Username)
+	require.ErrorIs(t, err, sql.ErrNoRows)
+
+	requestPasswordReset(t, store, user)
+	second, _ := requestPasswordReset(t, store, user)
+
+	latest, err := store.GetLatestPasswordResetToken(context.Background(), user.Username)
+	require.NoError(t, err)
+	require.Equal(t, second.ID, latest.ID)
+}
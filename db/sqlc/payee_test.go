@@ -0,0 +1,102 @@
+package db
+
+import (
+	"context"
+	"database/sql"
+	"gobank/util"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func createRandomPayee(t *testing.T, owner string) Payee {
+	acc := createRandomAccount(t)
+	arg := CreatePayeeParams{
+		Owner:     owner,
+		Nickname:  util.RandomOwner(),
+		AccountID: acc.ID,
+		Currency:  acc.Currency,
+	}
+
+	payee, err := testQueries.CreatePayee(context.Background(), arg)
+	require.NoError(t, err)
+	require.NotZero(t, payee.ID)
+	require.Equal(t, arg.Owner, payee.Owner)
+	require.Equal(t, arg.Nickname, payee.Nickname)
+	require.Equal(t, arg.AccountID, payee.AccountID)
+	require.Equal(t, arg.Currency, payee.Currency)
+	require.NotZero(t, payee.CreatedAt)
+
+	return payee
+}
+
+func TestCreatePayee(t *testing.T) {
+	user := createRandomUser(t)
+	payee := createRandomPayee(t, user.Username)
+
+	_, err := testQueries.CreatePayee(context.Background(), CreatePayeeParams{
+		Owner:     user.Username,
+		Nickname:  util.RandomOwner(),
+		AccountID: payee.AccountID,
+		Currency:  payee.Currency,
+	})
+	require.Error(t, err, "an account is saved once per user")
+}
+
+func TestGetPayeeByAccount(t *testing.T) {
+	user := createRandomUser(t)
+	payee := createRandomPayee(t, user.Username)
+
+	got, err := testQueries.GetPayeeByAccount(context.Background(), GetPayeeByAccountParams{
+		Owner:     user.Username,
+		AccountID: payee.AccountID,
+	})
+	require.NoError(t, err)
+	require.Equal(t, payee, got)
+
+	// another user's payee is not theirs
+	_, err = testQueries.GetPayeeByAccount(context.Background(), GetPayeeByAccountParams{
+		Owner:     createRandomUser(t).Username,
+		AccountID: payee.AccountID,
+	})
+	require.ErrorIs(t, err, sql.ErrNoRows)
+}
+
+func TestListPayees(t *testing.T) {
+	user := createRandomUser(t)
+	for i := 0; i < 3; i++ {
+		createRandomPayee(t, user.Username)
+	}
+
+	payees, err := testQueries.ListPayees(context.Background(), ListPayeesParams{
+		Owner:  user.Username,
+		Limit:  5,
+		Offset: 0,
+	})
+	require.NoError(t, err)
+	require.Len(t, payees, 3)
+	for _, payee := range payees {
+		require.Equal(t, user.Username, payee.Owner)
+	}
+}
+
+func TestUpdateAndDeletePayee(t *testing.T) {
+	user := createRandomUser(t)
+	payee := createRandomPayee(t, user.Username)
+
+	renamed, err := testQueries.UpdatePayeeNickname(context.Background(), UpdatePayeeNicknameParams{
+		ID:       payee.ID,
+		Nickname: "renamed",
+	})
+	require.NoError(t, err)
+	require.Equal(t, "renamed", renamed.Nickname)
+	require.Equal(t, payee.AccountID, renamed.AccountID)
+	require.Equal(t, payee.CreatedAt, renamed.CreatedAt)
+
+	n, err := testQueries.DeletePayee(context.Background(), payee.ID)
+	require.NoError(t, err)
+	require.Equal(t, int64(1), n)
+
+	_, err = testQueries.GetPayee(context.Background(), payee.ID)
+	require.EqualError(t, err, sql.ErrNoRows.Error())
+}
@@ -0,0 +1,47 @@
+package db
+
+import (
+	"context"
+)
+
+// CreatePaymentBatchTxParams holds an uploaded payment file that has already passed validation.
+type CreatePaymentBatchTxParams struct {
+	Owner        string                         `json:"owner"`
+	SourceFormat string                         `json:"source_format"`
+	MessageID    string                         `json:"message_id"`
+	Items        []CreatePaymentBatchItemParams `json:"items"`
+}
+
+type PaymentBatchTxResult struct {
+	Batch PaymentBatch       `json:"batch"`
+	Items []PaymentBatchItem `json:"items"`
+}
+
+// CreatePaymentBatchTx records a batch and all of its items as pending, so a file is either tracked in full or not at all.
+func (s *SQLStore) CreatePaymentBatchTx(ctx context.Context, arg CreatePaymentBatchTxParams) (PaymentBatchTxResult, error) {
+	var result PaymentBatchTxResult
+	err := s.execTx(ctx, func(q *Queries) error {
+		var err error
+		result.Batch, err = q.CreatePaymentBatch(ctx, CreatePaymentBatchParams{
+			Owner:        arg.Owner,
+			SourceFormat: arg.SourceFormat,
+			ItemCount:    int32(len(arg.Items)),
+			MessageID:    arg.MessageID,
+		})
+		if err != nil {
+			return err
+		}
+
+		result.Items = make([]PaymentBatchItem, 0, len(arg.Items))
+		for _, item := range arg.Items {
+			item.BatchID = result.Batch.ID
+			created, err := q.CreatePaymentBatchItem(ctx, item)
+			if err != nil {
+				return err
+			}
+			result.Items = append(result.Items, created)
+		}
+		return nil
+	})
+	return result, err
+}
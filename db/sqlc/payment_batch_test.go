@@ -0,0 +1,106 @@
+package db
+
+import (
+	"context"
+	"database/sql"
+	"gobank/util"
+	"testing"
+
+	"github.com/lib/pq"
+	"github.com/stretchr/testify/require"
+)
+
+func TestCreatePaymentBatchTx(t *testing.T) {
+	store := NewStore(testDB)
+
+	acc1 := createRandomAccount(t)
+	acc2 := createRandomAccount(t)
+
+	arg := CreatePaymentBatchTxParams{
+		Owner:        acc1.Owner,
+		SourceFormat: "csv",
+		Items: []CreatePaymentBatchItemParams{
+			{Line: 2, FromAccountID: acc1.ID, ToAccountID: acc2.ID, Amount: 10, Currency: acc1.Currency, Memo: "first"},
+			{Line: 3, FromAccountID: acc1.ID, ToAccountID: acc2.ID, Amount: 20, Currency: acc1.Currency, EndToEndID: "E2E-2"},
+		},
+	}
+	result, err := store.CreatePaymentBatchTx(context.Background(), arg)
+	require.NoError(t, err)
+
+	require.NotZero(t, result.Batch.ID)
+	require.Equal(t, acc1.Owner, result.Batch.Owner)
+	require.Equal(t, "pending", result.Batch.Status)
+	require.Equal(t, int32(2), result.Batch.ItemCount)
+	require.False(t, result.Batch.ExecutedAt.Valid)
+	require.Len(t, result.Items, 2)
+
+	items, err := store.ListPaymentBatchItems(context.Background(), result.Batch.ID)
+	require.NoError(t, err)
+	require.Equal(t, result.Items, items)
+	require.Equal(t, "first", items[0].Memo)
+	require.Equal(t, "E2E-2", items[1].EndToEndID)
+
+	transfer, err := store.TransferTx(context.Background(), TransferTxParams{
+		FromAccountID: acc1.ID,
+		ToAccountID:   acc2.ID,
+		Amount:        items[0].Amount,
+	})
+	require.NoError(t, err)
+
+	item, err := store.UpdatePaymentBatchItem(context.Background(), UpdatePaymentBatchItemParams{
+		ID:         items[0].ID,
+		Status:     "completed",
+		TransferID: sql.NullInt64{Int64: transfer.Transfer.ID, Valid: true},
+	})
+	require.NoError(t, err)
+	require.Equal(t, "completed", item.Status)
+	require.Equal(t, transfer.Transfer.ID, item.TransferID.Int64)
+
+	batch, err := store.UpdatePaymentBatchStatus(context.Background(), UpdatePaymentBatchStatusParams{
+		ID:     result.Batch.ID,
+		Status: "partially_failed",
+	})
+	require.NoError(t, err)
+	require.Equal(t, "partially_failed", batch.Status)
+	require.True(t, batch.ExecutedAt.Valid)
+}
+
+func TestCreatePaymentBatchTxMessageID(t *testing.T) {
+	store := NewStore(testDB)
+
+	acc1 := createRandomAccount(t)
+	acc2 := createRandomAccount(t)
+
+	arg := CreatePaymentBatchTxParams{
+		Owner:        acc1.Owner,
+		SourceFormat: "pain001",
+		MessageID:    util.RandomString(12),
+		Items: []CreatePaymentBatchItemParams{
+			{Line: 1, FromAccountID: acc1.ID, ToAccountID: acc2.ID, Amount: 10, Currency: acc1.Currency},
+		},
+	}
+	result, err := store.CreatePaymentBatchTx(context.Background(), arg)
+	require.NoError(t, err)
+	require.Equal(t, arg.MessageID, result.Batch.MessageID)
+
+	_, err = store.CreatePaymentBatchTx(context.Background(), arg)
+	require.Error(t, err)
+	pqErr, ok := err.(*pq.Error)
+	require.True(t, ok)
+	require.Equal(t, "payment_batches_owner_message_id_key", pqErr.Constraint)
+
+	// files without an id, and the same id from another client, are not duplicates
+	other := arg
+	other.Owner = acc2.Owner
+	other.Items = []CreatePaymentBatchItemParams{
+		{Line: 1, FromAccountID: acc2.ID, ToAccountID: acc1.ID, Amount: 10, Currency: acc2.Currency},
+	}
+	_, err = store.CreatePaymentBatchTx(context.Background(), other)
+	require.NoError(t, err)
+
+	arg.MessageID = ""
+	for i := 0; i < 2; i++ {
+		_, err = store.CreatePaymentBatchTx(context.Background(), arg)
+		require.NoError(t, err)
+	}
+}
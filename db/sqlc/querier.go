@@ -4,24 +4,149 @@ package db
 
 import (
 	"context"
+	"database/sql"
+	"time"
+
+	"github.com/google/uuid"
 )
 
 type Querier interface {
 	AddAccountBalance(ctx context.Context, arg AddAccountBalanceParams) (Account, error)
+	AuthoriseAccountConsent(ctx context.Context, arg AuthoriseAccountConsentParams) (AccountConsent, error)
+	BuryJob(ctx context.Context, arg BuryJobParams) error
+	ClaimDueWebhookDeliveries(ctx context.Context, arg ClaimDueWebhookDeliveriesParams) ([]WebhookDelivery, error)
+	ClaimJobs(ctx context.Context, maxJobs int32) ([]Job, error)
+	CompleteJob(ctx context.Context, id int64) error
+	ConfirmTOTPEnrollment(ctx context.Context, arg ConfirmTOTPEnrollmentParams) (TotpEnrollment, error)
+	CreateAPIKey(ctx context.Context, arg CreateAPIKeyParams) (ApiKey, error)
 	CreateAccount(ctx context.Context, arg CreateAccountParams) (Account, error)
+	CreateAccountConsent(ctx context.Context, arg CreateAccountConsentParams) (AccountConsent, error)
+	CreateAccountHolder(ctx context.Context, arg CreateAccountHolderParams) (AccountHolder, error)
 	CreateEntry(ctx context.Context, arg CreateEntryParams) (Entry, error)
+	CreateJob(ctx context.Context, arg CreateJobParams) (Job, error)
+	CreateLoginThrottleIfMissing(ctx context.Context, arg CreateLoginThrottleIfMissingParams) error
+	CreateOAuthAuthorizationCode(ctx context.Context, arg CreateOAuthAuthorizationCodeParams) error
+	CreateOAuthClient(ctx context.Context, arg CreateOAuthClientParams) (OauthClient, error)
+	CreateOutboxEvent(ctx context.Context, arg CreateOutboxEventParams) (OutboxEvent, error)
+	CreatePasswordResetToken(ctx context.Context, arg CreatePasswordResetTokenParams) (PasswordResetToken, error)
+	CreatePayee(ctx context.Context, arg CreatePayeeParams) (Payee, error)
+	CreatePaymentBatch(ctx context.Context, arg CreatePaymentBatchParams) (PaymentBatch, error)
+	CreatePaymentBatchItem(ctx context.Context, arg CreatePaymentBatchItemParams) (PaymentBatchItem, error)
+	CreateRecoveryCode(ctx context.Context, arg CreateRecoveryCodeParams) error
 	CreateTransfer(ctx context.Context, arg CreateTransferParams) (Transfer, error)
+	CreateTransferDraft(ctx context.Context, arg CreateTransferDraftParams) (TransferDraft, error)
+	CreateTransferDraftApproval(ctx context.Context, arg CreateTransferDraftApprovalParams) error
 	CreateUser(ctx context.Context, arg CreateUserParams) (User, error)
+	CreateVerifyEmail(ctx context.Context, arg CreateVerifyEmailParams) (VerifyEmail, error)
+	CreateWebhookDelivery(ctx context.Context, arg CreateWebhookDeliveryParams) error
+	CreateWebhookEndpoint(ctx context.Context, arg CreateWebhookEndpointParams) (WebhookEndpoint, error)
 	DeleteAccount(ctx context.Context, id int64) error
+	DeleteAccountHolder(ctx context.Context, arg DeleteAccountHolderParams) (int64, error)
+	DeleteAccountPermission(ctx context.Context, arg DeleteAccountPermissionParams) (int64, error)
+	DeleteApprovalPolicy(ctx context.Context, arg DeleteApprovalPolicyParams) (int64, error)
+	DeleteExpiredRevokedOAuthTokens(ctx context.Context) (int64, error)
+	DeleteLoginThrottle(ctx context.Context, arg DeleteLoginThrottleParams) (int64, error)
+	DeleteOAuthConsent(ctx context.Context, arg DeleteOAuthConsentParams) (int64, error)
+	DeletePayee(ctx context.Context, id int64) (int64, error)
+	DeleteRecoveryCodes(ctx context.Context, username string) error
+	DeleteWebhookEndpoint(ctx context.Context, id int64) error
+	ExecuteTransferDraft(ctx context.Context, arg ExecuteTransferDraftParams) (TransferDraft, error)
+	GetAPIKey(ctx context.Context, id int64) (ApiKey, error)
+	GetAPIKeyByPrefix(ctx context.Context, prefix string) (ApiKey, error)
 	GetAccount(ctx context.Context, id int64) (Account, error)
+	GetAccountBalanceAt(ctx context.Context, arg GetAccountBalanceAtParams) (int64, error)
+	GetAccountByNumber(ctx context.Context, number string) (Account, error)
+	GetAccountConsent(ctx context.Context, id int64) (AccountConsent, error)
 	GetAccountForUpdate(ctx context.Context, id int64) (Account, error)
+	GetAccountHolder(ctx context.Context, arg GetAccountHolderParams) (AccountHolder, error)
+	GetAccountPermission(ctx context.Context, arg GetAccountPermissionParams) (AccountPermission, error)
+	GetApprovalPolicyForAmount(ctx context.Context, arg GetApprovalPolicyForAmountParams) (ApprovalPolicy, error)
 	GetEntry(ctx context.Context, id int64) (Entry, error)
+	GetJob(ctx context.Context, id int64) (Job, error)
+	GetLatestPasswordResetToken(ctx context.Context, username string) (PasswordResetToken, error)
+	GetLatestVerifyEmail(ctx context.Context, username string) (VerifyEmail, error)
+	GetLoginThrottle(ctx context.Context, arg GetLoginThrottleParams) (LoginThrottle, error)
+	GetLoginThrottleForUpdate(ctx context.Context, arg GetLoginThrottleForUpdateParams) (LoginThrottle, error)
+	GetOAuthClient(ctx context.Context, id string) (OauthClient, error)
+	GetOAuthConsent(ctx context.Context, arg GetOAuthConsentParams) (OauthConsent, error)
+	GetOldestRunningTxid(ctx context.Context) (int64, error)
+	GetPasswordResetToken(ctx context.Context, id int64) (PasswordResetToken, error)
+	GetPayee(ctx context.Context, id int64) (Payee, error)
+	GetPayeeByAccount(ctx context.Context, arg GetPayeeByAccountParams) (Payee, error)
+	GetPaymentBatch(ctx context.Context, id int64) (PaymentBatch, error)
+	GetRevokedOAuthToken(ctx context.Context, tokenID uuid.UUID) (OauthRevokedToken, error)
+	GetTOTPEnrollment(ctx context.Context, username string) (TotpEnrollment, error)
 	GetTransfer(ctx context.Context, id int64) (Transfer, error)
+	GetTransferDraft(ctx context.Context, id int64) (TransferDraft, error)
+	GetTransferDraftForUpdate(ctx context.Context, id int64) (TransferDraft, error)
 	GetUser(ctx context.Context, username string) (User, error)
+	GetUserByEmail(ctx context.Context, email string) (User, error)
+	GetUserForUpdate(ctx context.Context, username string) (User, error)
+	GetValidPasswordResetToken(ctx context.Context, tokenHash string) (PasswordResetToken, error)
+	GetVerifyEmail(ctx context.Context, id int64) (VerifyEmail, error)
+	GetWebhookDelivery(ctx context.Context, id int64) (WebhookDelivery, error)
+	GetWebhookEndpoint(ctx context.Context, id int64) (WebhookEndpoint, error)
+	InvalidatePasswordResetTokens(ctx context.Context, username string) error
+	ListAPIKeys(ctx context.Context, username string) ([]ApiKey, error)
+	ListAccessibleAccountIDs(ctx context.Context, username string) ([]int64, error)
+	ListAccountBalanceMismatches(ctx context.Context) ([]ListAccountBalanceMismatchesRow, error)
+	ListAccountConsents(ctx context.Context, username sql.NullString) ([]AccountConsent, error)
+	ListAccountHolders(ctx context.Context, accountID int64) ([]AccountHolder, error)
+	ListAccountPermissions(ctx context.Context, accountID int64) ([]AccountPermission, error)
+	ListAccountUpdatesSince(ctx context.Context, arg ListAccountUpdatesSinceParams) ([]ListAccountUpdatesSinceRow, error)
 	ListAccounts(ctx context.Context, arg ListAccountsParams) ([]Account, error)
+	ListApprovalPolicies(ctx context.Context, accountID int64) ([]ApprovalPolicy, error)
 	ListEntries(ctx context.Context, arg ListEntriesParams) ([]Entry, error)
+	ListHolderAccounts(ctx context.Context, arg ListHolderAccountsParams) ([]Account, error)
+	ListJobsByStatus(ctx context.Context, arg ListJobsByStatusParams) ([]Job, error)
+	ListOAuthClients(ctx context.Context, owner string) ([]OauthClient, error)
+	ListOAuthConsents(ctx context.Context, username string) ([]OauthConsent, error)
+	ListPayees(ctx context.Context, arg ListPayeesParams) ([]Payee, error)
+	ListPaymentBatchItems(ctx context.Context, batchID int64) ([]PaymentBatchItem, error)
+	ListStatementEntries(ctx context.Context, arg ListStatementEntriesParams) ([]ListStatementEntriesRow, error)
+	ListTransferDraftApprovals(ctx context.Context, draftID int64) ([]TransferDraftApproval, error)
+	ListTransferDrafts(ctx context.Context, arg ListTransferDraftsParams) ([]TransferDraft, error)
+	ListTransferEntryMismatches(ctx context.Context) ([]ListTransferEntryMismatchesRow, error)
 	ListTransfers(ctx context.Context, arg ListTransfersParams) ([]Transfer, error)
+	ListUnpublishedOutboxEvents(ctx context.Context, limit int32) ([]OutboxEvent, error)
+	ListWebhookDeliveries(ctx context.Context, arg ListWebhookDeliveriesParams) ([]WebhookDelivery, error)
+	ListWebhookEndpoints(ctx context.Context, owner string) ([]WebhookEndpoint, error)
+	ListWebhookEndpointsForEvent(ctx context.Context, arg ListWebhookEndpointsForEventParams) ([]WebhookEndpoint, error)
+	MarkOutboxEventPublished(ctx context.Context, id int64) error
+	MarkUserEmailVerified(ctx context.Context, arg MarkUserEmailVerifiedParams) (User, error)
+	NotifyAccountUpdate(ctx context.Context, arg NotifyAccountUpdateParams) error
+	RecordWebhookDeliveryAttempt(ctx context.Context, arg RecordWebhookDeliveryAttemptParams) (WebhookDelivery, error)
+	RefundLoginAttempt(ctx context.Context, arg RefundLoginAttemptParams) error
+	RehashUserPassword(ctx context.Context, arg RehashUserPasswordParams) error
+	RejectTransferDraft(ctx context.Context, arg RejectTransferDraftParams) (TransferDraft, error)
+	RequeueJob(ctx context.Context, id int64) (Job, error)
+	RequeueStuckJobs(ctx context.Context, lockedBefore time.Time) (int64, error)
+	RequeueWebhookDelivery(ctx context.Context, id int64) (WebhookDelivery, error)
+	RetryJob(ctx context.Context, arg RetryJobParams) error
+	RevokeAPIKey(ctx context.Context, id int64) (ApiKey, error)
+	RevokeAccountConsent(ctx context.Context, id int64) (AccountConsent, error)
+	RevokeOAuthToken(ctx context.Context, arg RevokeOAuthTokenParams) error
+	ScheduleJob(ctx context.Context, arg ScheduleJobParams) (int64, error)
+	SetPasswordResetTokenHash(ctx context.Context, arg SetPasswordResetTokenHashParams) (PasswordResetToken, error)
+	SetVerifyEmailSecretCode(ctx context.Context, arg SetVerifyEmailSecretCodeParams) (VerifyEmail, error)
+	StartTOTPEnrollment(ctx context.Context, arg StartTOTPEnrollmentParams) (TotpEnrollment, error)
+	TouchAPIKey(ctx context.Context, id int64) error
 	UpdateAccount(ctx context.Context, arg UpdateAccountParams) (Account, error)
+	UpdateAccountApprovalThreshold(ctx context.Context, arg UpdateAccountApprovalThresholdParams) (Account, error)
+	UpdateLoginThrottle(ctx context.Context, arg UpdateLoginThrottleParams) (LoginThrottle, error)
+	UpdatePayeeNickname(ctx context.Context, arg UpdatePayeeNicknameParams) (Payee, error)
+	UpdatePaymentBatchItem(ctx context.Context, arg UpdatePaymentBatchItemParams) (PaymentBatchItem, error)
+	UpdatePaymentBatchStatus(ctx context.Context, arg UpdatePaymentBatchStatusParams) (PaymentBatch, error)
+	UpdateUser(ctx context.Context, arg UpdateUserParams) (User, error)
+	UpdateUserPassword(ctx context.Context, arg UpdateUserPasswordParams) (User, error)
+	UpsertAccountPermission(ctx context.Context, arg UpsertAccountPermissionParams) (AccountPermission, error)
+	UpsertApprovalPolicy(ctx context.Context, arg UpsertApprovalPolicyParams) (ApprovalPolicy, error)
+	UpsertOAuthConsent(ctx context.Context, arg UpsertOAuthConsentParams) (OauthConsent, error)
+	UseOAuthAuthorizationCode(ctx context.Context, codeHash string) (OauthAuthorizationCode, error)
+	UsePasswordResetToken(ctx context.Context, tokenHash string) (PasswordResetToken, error)
+	UseRecoveryCode(ctx context.Context, arg UseRecoveryCodeParams) (int64, error)
+	UseTOTPStep(ctx context.Context, arg UseTOTPStepParams) (int64, error)
+	UseVerifyEmail(ctx context.Context, arg UseVerifyEmailParams) (VerifyEmail, error)
 }
 
 var _ Querier = (*Queries)(nil)
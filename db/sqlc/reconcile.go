@@ -0,0 +1,75 @@
+package db
+
+import (
+	"context"
+	"database/sql"
+	"time"
+)
+
+// ReconciliationReport lists every place where the ledger disagrees with itself.
+type ReconciliationReport struct {
+	CheckedAt             time.Time             `json:"checked_at"`
+	Balanced              bool                  `json:"balanced"`
+	AccountDiscrepancies  []AccountDiscrepancy  `json:"account_discrepancies"`
+	TransferDiscrepancies []TransferDiscrepancy `json:"transfer_discrepancies"`
+}
+
+// AccountDiscrepancy is an account whose stored balance differs from the sum of its entries.
+type AccountDiscrepancy struct {
+	AccountID    int64 `json:"account_id"`
+	Balance      int64 `json:"balance"`
+	EntriesTotal int64 `json:"entries_total"`
+	Difference   int64 `json:"difference"`
+}
+
+// TransferDiscrepancy is a transfer without exactly one matching debit and one matching credit entry.
+type TransferDiscrepancy struct {
+	TransferID    int64 `json:"transfer_id"`
+	FromAccountID int64 `json:"from_account_id"`
+	ToAccountID   int64 `json:"to_account_id"`
+	Amount        int64 `json:"amount"`
+	EntryCount    int64 `json:"entry_count"`
+	DebitCount    int64 `json:"debit_count"`
+	CreditCount   int64 `json:"credit_count"`
+}
+
+// ReconcileLedger checks sum(entries) == balance for every account and that every transfer
+// has matching debit/credit entries. Both checks read from the same snapshot.
+func (s *SQLStore) ReconcileLedger(ctx context.Context) (ReconciliationReport, error) {
+	report := ReconciliationReport{
+		AccountDiscrepancies:  []AccountDiscrepancy{},
+		TransferDiscrepancies: []TransferDiscrepancy{},
+	}
+	opts := &sql.TxOptions{Isolation: sql.LevelRepeatableRead, ReadOnly: true}
+
+	err := s.execTxWithOptions(ctx, opts, func(q *Queries) error {
+		accounts, err := q.ListAccountBalanceMismatches(ctx)
+		if err != nil {
+			return err
+		}
+		for _, a := range accounts {
+			report.AccountDiscrepancies = append(report.AccountDiscrepancies, AccountDiscrepancy{
+				AccountID:    a.AccountID,
+				Balance:      a.Balance,
+				EntriesTotal: a.EntriesTotal,
+				Difference:   a.Balance - a.EntriesTotal,
+			})
+		}
+
+		transfers, err := q.ListTransferEntryMismatches(ctx)
+		if err != nil {
+			return err
+		}
+		for _, t := range transfers {
+			report.TransferDiscrepancies = append(report.TransferDiscrepancies, TransferDiscrepancy(t))
+		}
+		return nil
+	})
+	if err != nil {
+		return report, err
+	}
+
+	report.CheckedAt = time.Now()
+	report.Balanced = len(report.AccountDiscrepancies) == 0 && len(report.TransferDiscrepancies) == 0
+	return report, nil
+}
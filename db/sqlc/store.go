@@ -4,12 +4,30 @@ import (
 	"context"
 	"database/sql"
 	"fmt"
+	"strconv"
 )
 
 type Store interface {
 	Querier //Querier interface instance
 	// execTx(ctx context.Context, fn func(*Queries) error) error
 	TransferTx(ctx context.Context, arg TransferTxParams) (TransferTxResult, error)
+	ReconcileLedger(ctx context.Context) (ReconciliationReport, error)
+	CreateAccountTx(ctx context.Context, arg CreateAccountParams) (Account, error)
+	CreateUserTx(ctx context.Context, arg CreateUserParams) (User, error)
+	RelayOutboxEvents(ctx context.Context, limit int32, publish func(OutboxEvent) error) (int, error)
+	EnqueueJob(ctx context.Context, jobType string, payload interface{}) (Job, error)
+	VerifyEmailTx(ctx context.Context, arg VerifyEmailTxParams) (VerifyEmailTxResult, error)
+	ResendVerifyEmailTx(ctx context.Context, user User) error
+	CreatePasswordResetTx(ctx context.Context, username string) (PasswordResetToken, error)
+	ResetPasswordTx(ctx context.Context, arg ResetPasswordTxParams) (User, error)
+	UpdateUserTx(ctx context.Context, arg UpdateUserTxParams) (User, error)
+	ConfirmTOTPTx(ctx context.Context, arg ConfirmTOTPTxParams) (TotpEnrollment, error)
+	BeginLoginAttemptTx(ctx context.Context, arg LoginAttemptTxParams) (LoginThrottle, error)
+	CreatePaymentBatchTx(ctx context.Context, arg CreatePaymentBatchTxParams) (PaymentBatchTxResult, error)
+	CreateOAuthClientTx(ctx context.Context, arg CreateOAuthClientParams) (OauthClient, error)
+	AuthorizeOAuthClientTx(ctx context.Context, arg AuthorizeOAuthClientTxParams) error
+	CreateTransferDraftTx(ctx context.Context, arg CreateTransferDraftParams) (TransferDraft, error)
+	ApproveTransferDraftTx(ctx context.Context, arg ApproveTransferDraftTxParams) (ApproveTransferDraftTxResult, error)
 }
 
 type SQLStore struct {
@@ -25,7 +43,11 @@ func NewStore(db *sql.DB) Store {
 }
 
 func (s *SQLStore) execTx(ctx context.Context, fn func(*Queries) error) error {
-	tx, err := s.db.BeginTx(ctx, nil)
+	return s.execTxWithOptions(ctx, nil, fn)
+}
+
+func (s *SQLStore) execTxWithOptions(ctx context.Context, opts *sql.TxOptions, fn func(*Queries) error) error {
+	tx, err := s.db.BeginTx(ctx, opts)
 	if err != nil {
 		return err
 	}
@@ -43,9 +65,10 @@ func (s *SQLStore) execTx(ctx context.Context, fn func(*Queries) error) error {
 
 //Transfer transaction: create a new transfer record, add 2 new account entries, and update the 2 accounts’ balance within a single database transaction.
 type TransferTxParams struct {
-	FromAccountID int64 `json:"from_account_id"`
-	ToAccountID   int64 `json:"to_account_id"`
-	Amount        int64 `json:"amount"`
+	FromAccountID int64  `json:"from_account_id"`
+	ToAccountID   int64  `json:"to_account_id"`
+	Amount        int64  `json:"amount"`
+	Memo          string `json:"memo"`
 }
 
 type TransferTxResult struct {
@@ -86,77 +109,98 @@ func (s *SQLStore) TransferTx(ctx context.Context, args TransferTxParams) (Trans
 	var result TransferTxResult
 	err := s.execTx(ctx, func(q *Queries) error {
 		var err error
+		result, err = transfer(ctx, q, args)
+		return err
+	})
+	return result, err
+}
 
-		txName := ctx.Value(txKey)
+// transfer moves the money within the transaction of q, so other transactions can make transfers too.
+func transfer(ctx context.Context, q *Queries, args TransferTxParams) (TransferTxResult, error) {
+	var result TransferTxResult
+	var err error
 
-		fmt.Println(txName, "create transfer")
-		result.Transfer, err = q.CreateTransfer(ctx, CreateTransferParams{
-			FromAccountID: args.FromAccountID,
-			ToAccountID:   args.ToAccountID,
-			Amount:        args.Amount,
-		})
+	result.Transfer, err = q.CreateTransfer(ctx, CreateTransferParams{
+		FromAccountID: args.FromAccountID,
+		ToAccountID:   args.ToAccountID,
+		Amount:        args.Amount,
+		Memo:          args.Memo,
+	})
 
-		if err != nil {
-			return err
-		}
+	if err != nil {
+		return result, err
+	}
 
-		fmt.Println(txName, "create entry 1")
-		result.FromEntry, err = q.CreateEntry(ctx, CreateEntryParams{
-			AccountID: args.FromAccountID,
-			Amount:    -args.Amount,
-		})
-		if err != nil {
-			return err
-		}
+	///##
+	//avoiding deadlock by making both transactions update the accounts balance in the same order
+	//here, I update the account with smaller ID first.
+	if args.FromAccountID < args.ToAccountID {
+		result.FromAccount, result.ToAccount, err = addMoney(ctx, q, args.FromAccountID, -args.Amount, args.ToAccountID, args.Amount)
+	} else {
+		result.ToAccount, result.FromAccount, err = addMoney(ctx, q, args.ToAccountID, args.Amount, args.FromAccountID, -args.Amount)
+	}
+	//##
+	if err != nil {
+		return result, err
+	}
 
-		fmt.Println(txName, "create entry 2")
-		result.ToEntry, err = q.CreateEntry(ctx, CreateEntryParams{
-			AccountID: args.ToAccountID,
-			Amount:    args.Amount,
-		})
-		if err != nil {
-			return err
-		}
+	//entries are written after the balances so they can record the running balance
+	result.FromEntry, err = q.CreateEntry(ctx, CreateEntryParams{
+		AccountID:    args.FromAccountID,
+		Amount:       -args.Amount,
+		TransferID:   sql.NullInt64{Int64: result.Transfer.ID, Valid: true},
+		BalanceAfter: result.FromAccount.Balance,
+	})
+	if err != nil {
+		return result, err
+	}
 
-		//Add update accounts' balance later
-		// fmt.Println(txName, "get account 1")
-		// acc1, err := q.GetAccountForUpdate(ctx, args.FromAccountID)
-		// if err != nil {
-		// 	return err
-		// }
-
-		//move money out of acc1: sender
-		// fmt.Println(txName, "update account 1")
-		// result.FromAccount, err = q.UpdateAccount(ctx, UpdateAccountParams{
-		// 	ID:      args.FromAccountID,
-		// 	Balance: acc1.Balance - args.Amount,
-		// })
-
-		///##
-		//avoiding deadlock by making both transactions update the accounts balance in the same order
-		//here, I update the account with smaller ID first.
-		if args.FromAccountID < args.ToAccountID {
-			result.FromAccount, result.ToAccount, err = addMoney(ctx, q, args.FromAccountID, -args.Amount, args.ToAccountID, args.Amount)
-		} else {
-			result.ToAccount, result.FromAccount, err = addMoney(ctx, q, args.ToAccountID, args.Amount, args.FromAccountID, -args.Amount)
-		}
-		//##
+	result.ToEntry, err = q.CreateEntry(ctx, CreateEntryParams{
+		AccountID:    args.ToAccountID,
+		Amount:       args.Amount,
+		TransferID:   sql.NullInt64{Int64: result.Transfer.ID, Valid: true},
+		BalanceAfter: result.ToAccount.Balance,
+	})
+	if err != nil {
+		return result, err
+	}
 
-		// fmt.Println(txName, "get account 2")
-		// acc2, err := q.GetAccountForUpdate(ctx, args.ToAccountID)
-		// if err != nil {
-		// 	return err
-		// }
+	if err := publishAccountUpdate(ctx, q, result.FromAccount, result.FromEntry); err != nil {
+		return result, err
+	}
+	if err := publishAccountUpdate(ctx, q, result.ToAccount, result.ToEntry); err != nil {
+		return result, err
+	}
 
-		//move money into acc2: receiver
+	err = addOutboxEvent(ctx, q, "transfer", strconv.FormatInt(result.Transfer.ID, 10), EventTransferCompleted, result)
 
-		// fmt.Println(txName, "update account 2")
-		// result.ToAccount, err = q.UpdateAccount(ctx, UpdateAccountParams{
-		// 	ID:      args.ToAccountID,
-		// 	Balance: acc2.Balance + args.Amount,
-		// })
+	//Add update accounts' balance later
+	// fmt.Println(txName, "get account 1")
+	// acc1, err := q.GetAccountForUpdate(ctx, args.FromAccountID)
+	// if err != nil {
+	// 	return err
+	// }
+
+	//move money out of acc1: sender
+	// fmt.Println(txName, "update account 1")
+	// result.FromAccount, err = q.UpdateAccount(ctx, UpdateAccountParams{
+	// 	ID:      args.FromAccountID,
+	// 	Balance: acc1.Balance - args.Amount,
+	// })
+
+	// fmt.Println(txName, "get account 2")
+	// acc2, err := q.GetAccountForUpdate(ctx, args.ToAccountID)
+	// if err != nil {
+	// 	return err
+	// }
+
+	//move money into acc2: receiver
+
+	// fmt.Println(txName, "update account 2")
+	// result.ToAccount, err = q.UpdateAccount(ctx, UpdateAccountParams{
+	// 	ID:      args.ToAccountID,
+	// 	Balance: acc2.Balance + args.Amount,
+	// })
 
-		return err
-	})
 	return result, err
 }
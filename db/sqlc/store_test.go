@@ -2,8 +2,11 @@ package db
 
 import (
 	"context"
+	"database/sql"
 	"fmt"
+	"gobank/util"
 	"testing"
+	"time"
 
 	"github.com/stretchr/testify/require"
 )
@@ -23,6 +26,7 @@ func TestTransferTx(t *testing.T) {
 
 	// run n concurrent transfer transactions
 	for i := 0; i < n; i++ {
+		i := i
 		go func() {
 			txName := fmt.Sprintf("tx %d", i+1)
 			ctx := context.WithValue(context.Background(), txKey, txName)
@@ -62,6 +66,7 @@ func TestTransferTx(t *testing.T) {
 		require.NotEmpty(t, fromEntry)
 		require.Equal(t, acc1.ID, fromEntry.AccountID)
 		require.Equal(t, -amount, fromEntry.Amount)
+		require.Equal(t, transfer.ID, fromEntry.TransferID.Int64)
 		require.NotZero(t, fromEntry.ID)
 		require.NotZero(t, fromEntry.CreatedAt)
 
@@ -72,6 +77,7 @@ func TestTransferTx(t *testing.T) {
 		require.NotEmpty(t, toEntry)
 		require.Equal(t, acc2.ID, toEntry.AccountID)
 		require.Equal(t, amount, toEntry.Amount)
+		require.Equal(t, transfer.ID, toEntry.TransferID.Int64)
 		require.NotZero(t, toEntry.ID)
 		require.NotZero(t, toEntry.CreatedAt)
 
@@ -90,6 +96,9 @@ func TestTransferTx(t *testing.T) {
 		// check balances
 		fmt.Println(">> tx:", fromAccount.Balance, toAccount.Balance)
 
+		require.Equal(t, fromAccount.Balance, fromEntry.BalanceAfter)
+		require.Equal(t, toAccount.Balance, toEntry.BalanceAfter)
+
 		diff1 := acc1.Balance - fromAccount.Balance
 		diff2 := toAccount.Balance - acc2.Balance
 
@@ -135,6 +144,7 @@ func TestTransferTxDeadlock(t *testing.T) {
 			toAccID = acc1.ID
 		}
 
+		i := i
 		go func() {
 			txName := fmt.Sprintf("tx %d", i+1)
 			ctx := context.WithValue(context.Background(), txKey, txName)
@@ -163,3 +173,124 @@ func TestTransferTxDeadlock(t *testing.T) {
 	require.Equal(t, acc1.Balance, updatedAcc1.Balance)
 	require.Equal(t, acc2.Balance, updatedAcc2.Balance)
 }
+
+func TestReconcileLedger(t *testing.T) {
+	store := NewStore(testDB)
+
+	// random accounts start with a balance but no entries, so open these two empty to have them balanced
+	createEmptyAccount := func() Account {
+		acc, err := testQueries.CreateAccount(context.Background(), CreateAccountParams{
+			Owner:    createRandomUser(t).Username,
+			Currency: util.USD,
+			Number:   util.RandomAccountNumber(),
+		})
+		require.NoError(t, err)
+		return acc
+	}
+	acc1 := createEmptyAccount()
+	acc2 := createEmptyAccount()
+
+	result, err := store.TransferTx(context.Background(), TransferTxParams{
+		FromAccountID: acc1.ID,
+		ToAccountID:   acc2.ID,
+		Amount:        10,
+	})
+	require.NoError(t, err)
+
+	// drift acc2's balance away from its entries
+	_, err = testQueries.AddAccountBalance(context.Background(), AddAccountBalanceParams{
+		ID:     acc2.ID,
+		Amount: 7,
+	})
+	require.NoError(t, err)
+
+	report, err := store.ReconcileLedger(context.Background())
+	require.NoError(t, err)
+	require.NotZero(t, report.CheckedAt)
+	require.False(t, report.Balanced)
+
+	var flagged []AccountDiscrepancy
+	for _, d := range report.AccountDiscrepancies {
+		if d.AccountID == acc1.ID || d.AccountID == acc2.ID {
+			flagged = append(flagged, d)
+		}
+	}
+	require.Equal(t, []AccountDiscrepancy{{
+		AccountID:    acc2.ID,
+		Balance:      17,
+		EntriesTotal: 10,
+		Difference:   7,
+	}}, flagged)
+
+	// the transfer itself wrote exactly one debit and one credit entry
+	for _, d := range report.TransferDiscrepancies {
+		require.NotEqual(t, result.Transfer.ID, d.TransferID)
+	}
+}
+
+func TestGetAccountBalanceAt(t *testing.T) {
+	store := NewStore(testDB)
+
+	acc1 := createRandomAccount(t)
+	acc2 := createRandomAccount(t)
+
+	_, err := store.GetAccountBalanceAt(context.Background(), GetAccountBalanceAtParams{
+		AccountID: acc1.ID,
+		At:        time.Now(),
+	})
+	require.EqualError(t, err, sql.ErrNoRows.Error())
+
+	result, err := store.TransferTx(context.Background(), TransferTxParams{
+		FromAccountID: acc1.ID,
+		ToAccountID:   acc2.ID,
+		Amount:        10,
+	})
+	require.NoError(t, err)
+
+	balance, err := store.GetAccountBalanceAt(context.Background(), GetAccountBalanceAtParams{
+		AccountID: acc1.ID,
+		At:        time.Now(),
+	})
+	require.NoError(t, err)
+	require.Equal(t, result.FromAccount.Balance, balance)
+
+	_, err = store.GetAccountBalanceAt(context.Background(), GetAccountBalanceAtParams{
+		AccountID: acc1.ID,
+		At:        result.FromEntry.CreatedAt.Add(-time.Second),
+	})
+	require.EqualError(t, err, sql.ErrNoRows.Error())
+}
+
+func TestGetAccountBalanceAtOrdersByEntry(t *testing.T) {
+	acc := createRandomAccount(t)
+	at := time.Now().Add(-time.Minute).Truncate(time.Microsecond)
+
+	addEntry := func(amount, balanceAfter int64, createdAt time.Time) {
+		_, err := testDB.Exec(
+			`INSERT INTO entries (account_id, amount, balance_after, created_at) VALUES ($1, $2, $3, $4)`,
+			acc.ID, amount, balanceAfter, createdAt,
+		)
+		require.NoError(t, err)
+	}
+
+	// two entries written by transactions that started at the same instant
+	addEntry(10, 10, at)
+	addEntry(5, 15, at)
+
+	balance, err := testQueries.GetAccountBalanceAt(context.Background(), GetAccountBalanceAtParams{
+		AccountID: acc.ID,
+		At:        at,
+	})
+	require.NoError(t, err)
+	require.Equal(t, int64(15), balance)
+
+	// a transaction that started earlier but got the account lock last
+	addEntry(-3, 12, at.Add(-time.Second))
+
+	balance, err = testQueries.GetAccountBalanceAt(context.Background(), GetAccountBalanceAtParams{
+		AccountID: acc.ID,
+		At:        at,
+	})
+	require.NoError(t, err)
+	require.Equal(t, int64(12), balance)
+}
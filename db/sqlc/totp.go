@@ -0,0 +1,39 @@
+package db
+
+import "context"
+
+type ConfirmTOTPTxParams struct {
+	Username string
+	// Step is the time step of the code that confirmed the enrollment, which cannot be used again
+	Step               int64
+	RecoveryCodeHashes []string
+}
+
+// ConfirmTOTPTx turns on two-factor login for a user with a pending enrollment and replaces their
+// recovery codes. It returns sql.ErrNoRows if there is no enrollment waiting for confirmation.
+func (s *SQLStore) ConfirmTOTPTx(ctx context.Context, arg ConfirmTOTPTxParams) (TotpEnrollment, error) {
+	var enrollment TotpEnrollment
+	err := s.execTx(ctx, func(q *Queries) error {
+		var err error
+		enrollment, err = q.ConfirmTOTPEnrollment(ctx, ConfirmTOTPEnrollmentParams{
+			Username:     arg.Username,
+			LastUsedStep: arg.Step,
+		})
+		if err != nil {
+			return err
+		}
+		if err := q.DeleteRecoveryCodes(ctx, arg.Username); err != nil {
+			return err
+		}
+		for _, hash := range arg.RecoveryCodeHashes {
+			if err := q.CreateRecoveryCode(ctx, CreateRecoveryCodeParams{
+				Username: arg.Username,
+				CodeHash: hash,
+			}); err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+	return enrollment, err
+}
@@ -0,0 +1,60 @@
+package db
+
+import (
+	"context"
+	"database/sql"
+	"gobank/util"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestTOTPEnrollment(t *testing.T) {
+	store := NewStore(testDB)
+	user := createRandomUser(t)
+
+	first, err := store.StartTOTPEnrollment(context.Background(), StartTOTPEnrollmentParams{Username: user.Username, Secret: "FIRST"})
+	require.NoError(t, err)
+	require.False(t, first.ConfirmedAt.Valid)
+
+	// restarting an unconfirmed enrollment replaces the secret
+	second, err := store.StartTOTPEnrollment(context.Background(), StartTOTPEnrollmentParams{Username: user.Username, Secret: "SECOND"})
+	require.NoError(t, err)
+	require.Equal(t, "SECOND", second.Secret)
+
+	hashes := []string{util.HashToken("code1"), util.HashToken("code2")}
+	confirmed, err := store.ConfirmTOTPTx(context.Background(), ConfirmTOTPTxParams{
+		Username:           user.Username,
+		Step:               100,
+		RecoveryCodeHashes: hashes,
+	})
+	require.NoError(t, err)
+	require.True(t, confirmed.ConfirmedAt.Valid)
+	require.Equal(t, int64(100), confirmed.LastUsedStep)
+
+	_, err = store.ConfirmTOTPTx(context.Background(), ConfirmTOTPTxParams{Username: user.Username, Step: 101})
+	require.ErrorIs(t, err, sql.ErrNoRows)
+
+	// a confirmed enrollment cannot be restarted
+	_, err = store.StartTOTPEnrollment(context.Background(), StartTOTPEnrollmentParams{Username: user.Username, Secret: "THIRD"})
+	require.ErrorIs(t, err, sql.ErrNoRows)
+
+	// each step is accepted once and never an older one
+	n, err := store.UseTOTPStep(context.Background(), UseTOTPStepParams{Username: user.Username, Step: 100})
+	require.NoError(t, err)
+	require.Zero(t, n)
+	n, err = store.UseTOTPStep(context.Background(), UseTOTPStepParams{Username: user.Username, Step: 101})
+	require.NoError(t, err)
+	require.Equal(t, int64(1), n)
+	n, err = store.UseTOTPStep(context.Background(), UseTOTPStepParams{Username: user.Username, Step: 101})
+	require.NoError(t, err)
+	require.Zero(t, n)
+
+	// recovery codes are single use
+	n, err = store.UseRecoveryCode(context.Background(), UseRecoveryCodeParams{Username: user.Username, CodeHash: hashes[0]})
+	require.NoError(t, err)
+	require.Equal(t, int64(1), n)
+	n, err = store.UseRecoveryCode(context.Background(), UseRecoveryCodeParams{Username: user.Username, CodeHash: hashes[0]})
+	require.NoError(t, err)
+	require.Zero(t, n)
+}
@@ -0,0 +1,145 @@
+package db
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+)
+
+// Statuses of a transfer draft. A draft waits for the approvals the joint approval rule or the approval
+// policies of its account ask for, and is executed by the last one.
+const (
+	DraftPending  = "pending"
+	DraftExecuted = "executed"
+	DraftRejected = "rejected"
+)
+
+// ErrDraftDecided is returned when approving a transfer draft that was already executed or rejected.
+var ErrDraftDecided = errors.New("transfer draft is no longer pending")
+
+// CreateTransferDraftTx records a transfer waiting for approval, counting its creator as the first approver.
+func (s *SQLStore) CreateTransferDraftTx(ctx context.Context, arg CreateTransferDraftParams) (TransferDraft, error) {
+	var draft TransferDraft
+	err := s.execTx(ctx, func(q *Queries) error {
+		var err error
+		draft, err = q.CreateTransferDraft(ctx, arg)
+		if err != nil {
+			return err
+		}
+		return q.CreateTransferDraftApproval(ctx, CreateTransferDraftApprovalParams{
+			DraftID:  draft.ID,
+			Username: draft.CreatedBy,
+		})
+	})
+	return draft, err
+}
+
+type ApproveTransferDraftTxParams struct {
+	DraftID  int64  `json:"draft_id"`
+	Username string `json:"username"`
+}
+
+type ApproveTransferDraftTxResult struct {
+	Draft     TransferDraft           `json:"draft"`
+	Approvals []TransferDraftApproval `json:"approvals"`
+	// set once the last approval needed made the transfer
+	Transfer *TransferTxResult `json:"transfer,omitempty"`
+}
+
+// ApproveTransferDraftTx adds the approval of a user to a pending draft and, once it has all the approvals
+// it needs, makes the transfer in the same transaction. The draft row is locked so two last approvals
+// cannot both make it. Only approvals of users who may still make transfers from the account count, and
+// while the joint approval rule applies to the amount every current holder must be among them.
+func (s *SQLStore) ApproveTransferDraftTx(ctx context.Context, arg ApproveTransferDraftTxParams) (ApproveTransferDraftTxResult, error) {
+	var result ApproveTransferDraftTxResult
+	err := s.execTx(ctx, func(q *Queries) error {
+		var err error
+		result.Draft, err = q.GetTransferDraftForUpdate(ctx, arg.DraftID)
+		if err != nil {
+			return err
+		}
+		if result.Draft.Status != DraftPending {
+			return ErrDraftDecided
+		}
+
+		err = q.CreateTransferDraftApproval(ctx, CreateTransferDraftApprovalParams{
+			DraftID:  arg.DraftID,
+			Username: arg.Username,
+		})
+		if err != nil {
+			return err
+		}
+		result.Approvals, err = q.ListTransferDraftApprovals(ctx, arg.DraftID)
+		if err != nil {
+			return err
+		}
+		approved, err := draftApproved(ctx, q, result.Draft, result.Approvals)
+		if err != nil || !approved {
+			return err
+		}
+
+		transferred, err := transfer(ctx, q, TransferTxParams{
+			FromAccountID: result.Draft.FromAccountID,
+			ToAccountID:   result.Draft.ToAccountID,
+			Amount:        result.Draft.Amount,
+			Memo:          result.Draft.Memo,
+		})
+		if err != nil {
+			return err
+		}
+		result.Transfer = &transferred
+		result.Draft, err = q.ExecuteTransferDraft(ctx, ExecuteTransferDraftParams{
+			ID:         arg.DraftID,
+			TransferID: sql.NullInt64{Int64: transferred.Transfer.ID, Valid: true},
+			DecidedBy:  sql.NullString{String: arg.Username, Valid: true},
+		})
+		return err
+	})
+	return result, err
+}
+
+// draftApproved reports whether the approvals of draft are enough to make the transfer, checking each
+// approver's access to the account as it is now: holders and delegates come and go while a draft waits.
+func draftApproved(ctx context.Context, q *Queries, draft TransferDraft, approvals []TransferDraftApproval) (bool, error) {
+	acc, err := q.GetAccount(ctx, draft.FromAccountID)
+	if err != nil {
+		return false, err
+	}
+	holders, err := q.ListAccountHolders(ctx, acc.ID)
+	if err != nil {
+		return false, err
+	}
+	isHolder := map[string]bool{acc.Owner: true}
+	for _, h := range holders {
+		isHolder[h.Username] = true
+	}
+
+	approvedBy := map[string]bool{}
+	for _, a := range approvals {
+		if !isHolder[a.Username] {
+			perm, err := q.GetAccountPermission(ctx, GetAccountPermissionParams{
+				AccountID: acc.ID,
+				Username:  a.Username,
+			})
+			if err == sql.ErrNoRows {
+				continue
+			}
+			if err != nil {
+				return false, err
+			}
+			if perm.Access != AccessTransfer {
+				continue
+			}
+		}
+		approvedBy[a.Username] = true
+	}
+
+	if acc.JointApprovalThreshold > 0 && draft.Amount >= acc.JointApprovalThreshold {
+		for holder := range isHolder {
+			if !approvedBy[holder] {
+				return false, nil
+			}
+		}
+	}
+	return len(approvedBy) >= int(draft.RequiredApprovals), nil
+}
@@ -0,0 +1,199 @@
+package db
+
+import (
+	"context"
+	"database/sql"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestApproveTransferDraftTx(t *testing.T) {
+	store := NewStore(testDB)
+	from := createRandomAccount(t)
+	to := createRandomAccount(t)
+	spouse := createRandomUser(t)
+	_, err := testQueries.CreateAccountHolder(context.Background(), CreateAccountHolderParams{
+		AccountID: from.ID,
+		Username:  spouse.Username,
+		Role:      HolderSecondary,
+	})
+	require.NoError(t, err)
+
+	draft, err := store.CreateTransferDraftTx(context.Background(), CreateTransferDraftParams{
+		FromAccountID:     from.ID,
+		ToAccountID:       to.ID,
+		Amount:            10,
+		CreatedBy:         from.Owner,
+		RequiredApprovals: 2,
+	})
+	require.NoError(t, err)
+	require.Equal(t, DraftPending, draft.Status)
+	require.False(t, draft.TransferID.Valid)
+
+	// approving twice does not count twice
+	result, err := store.ApproveTransferDraftTx(context.Background(), ApproveTransferDraftTxParams{
+		DraftID:  draft.ID,
+		Username: from.Owner,
+	})
+	require.NoError(t, err)
+	require.Len(t, result.Approvals, 1)
+	require.Nil(t, result.Transfer)
+	require.Equal(t, DraftPending, result.Draft.Status)
+
+	result, err = store.ApproveTransferDraftTx(context.Background(), ApproveTransferDraftTxParams{
+		DraftID:  draft.ID,
+		Username: spouse.Username,
+	})
+	require.NoError(t, err)
+	require.Len(t, result.Approvals, 2)
+	require.NotNil(t, result.Transfer)
+	require.Equal(t, DraftExecuted, result.Draft.Status)
+	require.Equal(t, result.Transfer.Transfer.ID, result.Draft.TransferID.Int64)
+	require.True(t, result.Draft.DecidedAt.Valid)
+	require.Equal(t, spouse.Username, result.Draft.DecidedBy.String)
+	require.Equal(t, from.Balance-10, result.Transfer.FromAccount.Balance)
+	require.Equal(t, to.Balance+10, result.Transfer.ToAccount.Balance)
+
+	_, err = store.ApproveTransferDraftTx(context.Background(), ApproveTransferDraftTxParams{
+		DraftID:  draft.ID,
+		Username: spouse.Username,
+	})
+	require.ErrorIs(t, err, ErrDraftDecided)
+}
+
+func TestApproveTransferDraftTxJointRuleNeedsHolders(t *testing.T) {
+	store := NewStore(testDB)
+	from := createRandomAccount(t)
+	to := createRandomAccount(t)
+	spouse := createRandomUser(t)
+	delegate := createRandomUser(t)
+
+	from, err := testQueries.UpdateAccountApprovalThreshold(context.Background(), UpdateAccountApprovalThresholdParams{
+		ID:                     from.ID,
+		JointApprovalThreshold: 5,
+	})
+	require.NoError(t, err)
+	_, err = testQueries.CreateAccountHolder(context.Background(), CreateAccountHolderParams{
+		AccountID: from.ID,
+		Username:  spouse.Username,
+		Role:      HolderSecondary,
+	})
+	require.NoError(t, err)
+	_, err = testQueries.UpsertAccountPermission(context.Background(), UpsertAccountPermissionParams{
+		AccountID: from.ID,
+		Username:  delegate.Username,
+		Access:    AccessTransfer,
+		GrantedBy: from.Owner,
+	})
+	require.NoError(t, err)
+
+	draft, err := store.CreateTransferDraftTx(context.Background(), CreateTransferDraftParams{
+		FromAccountID:     from.ID,
+		ToAccountID:       to.ID,
+		Amount:            10,
+		CreatedBy:         from.Owner,
+		RequiredApprovals: 2,
+	})
+	require.NoError(t, err)
+
+	// a delegate's approval does not stand in for a holder's
+	result, err := store.ApproveTransferDraftTx(context.Background(), ApproveTransferDraftTxParams{
+		DraftID:  draft.ID,
+		Username: delegate.Username,
+	})
+	require.NoError(t, err)
+	require.Len(t, result.Approvals, 2)
+	require.Nil(t, result.Transfer)
+	require.Equal(t, DraftPending, result.Draft.Status)
+
+	result, err = store.ApproveTransferDraftTx(context.Background(), ApproveTransferDraftTxParams{
+		DraftID:  draft.ID,
+		Username: spouse.Username,
+	})
+	require.NoError(t, err)
+	require.NotNil(t, result.Transfer)
+	require.Equal(t, DraftExecuted, result.Draft.Status)
+}
+
+func TestApproveTransferDraftTxRechecksApprovers(t *testing.T) {
+	store := NewStore(testDB)
+	from := createRandomAccount(t)
+	to := createRandomAccount(t)
+	delegate := createRandomUser(t)
+
+	_, err := testQueries.UpsertAccountPermission(context.Background(), UpsertAccountPermissionParams{
+		AccountID: from.ID,
+		Username:  delegate.Username,
+		Access:    AccessTransfer,
+		GrantedBy: from.Owner,
+	})
+	require.NoError(t, err)
+	draft, err := store.CreateTransferDraftTx(context.Background(), CreateTransferDraftParams{
+		FromAccountID:     from.ID,
+		ToAccountID:       to.ID,
+		Amount:            10,
+		CreatedBy:         delegate.Username,
+		RequiredApprovals: 2,
+	})
+	require.NoError(t, err)
+
+	// the maker lost access while the draft waited, so their approval no longer counts
+	_, err = testQueries.DeleteAccountPermission(context.Background(), DeleteAccountPermissionParams{
+		AccountID: from.ID,
+		Username:  delegate.Username,
+	})
+	require.NoError(t, err)
+
+	result, err := store.ApproveTransferDraftTx(context.Background(), ApproveTransferDraftTxParams{
+		DraftID:  draft.ID,
+		Username: from.Owner,
+	})
+	require.NoError(t, err)
+	require.Len(t, result.Approvals, 2)
+	require.Nil(t, result.Transfer)
+	require.Equal(t, DraftPending, result.Draft.Status)
+}
+
+func TestRejectTransferDraft(t *testing.T) {
+	store := NewStore(testDB)
+	from := createRandomAccount(t)
+	to := createRandomAccount(t)
+
+	draft, err := store.CreateTransferDraftTx(context.Background(), CreateTransferDraftParams{
+		FromAccountID:     from.ID,
+		ToAccountID:       to.ID,
+		Amount:            10,
+		CreatedBy:         from.Owner,
+		RequiredApprovals: 2,
+	})
+	require.NoError(t, err)
+
+	arg := RejectTransferDraftParams{
+		ID:        draft.ID,
+		DecidedBy: sql.NullString{String: from.Owner, Valid: true},
+	}
+	rejected, err := testQueries.RejectTransferDraft(context.Background(), arg)
+	require.NoError(t, err)
+	require.Equal(t, DraftRejected, rejected.Status)
+	require.Equal(t, from.Owner, rejected.DecidedBy.String)
+	require.True(t, rejected.DecidedAt.Valid)
+
+	_, err = testQueries.RejectTransferDraft(context.Background(), arg)
+	require.EqualError(t, err, sql.ErrNoRows.Error())
+
+	_, err = store.ApproveTransferDraftTx(context.Background(), ApproveTransferDraftTxParams{
+		DraftID:  draft.ID,
+		Username: to.Owner,
+	})
+	require.ErrorIs(t, err, ErrDraftDecided)
+
+	drafts, err := testQueries.ListTransferDrafts(context.Background(), ListTransferDraftsParams{
+		FromAccountID: from.ID,
+		Limit:         5,
+		Offset:        0,
+	})
+	require.NoError(t, err)
+	require.Len(t, drafts, 1)
+	require.Equal(t, rejected.ID, drafts[0].ID)
+}
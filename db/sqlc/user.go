@@ -0,0 +1,50 @@
+package db
+
+import (
+	"context"
+	"database/sql"
+)
+
+type UpdateUserTxParams struct {
+	Username string
+	FullName sql.NullString
+	Email    sql.NullString
+}
+
+// UpdateUserTx changes the fields set in arg. A new email address has to be verified again, so changing it
+// clears is_email_verified and queues a verification email for the new address.
+func (s *SQLStore) UpdateUserTx(ctx context.Context, arg UpdateUserTxParams) (User, error) {
+	var user User
+	err := s.execTx(ctx, func(q *Queries) error {
+		var err error
+		user, err = q.GetUserForUpdate(ctx, arg.Username)
+		if err != nil {
+			return err
+		}
+
+		update := UpdateUserParams{
+			Username:        user.Username,
+			FullName:        user.FullName,
+			Email:           user.Email,
+			IsEmailVerified: user.IsEmailVerified,
+		}
+		if arg.FullName.Valid {
+			update.FullName = arg.FullName.String
+		}
+		emailChanged := arg.Email.Valid && arg.Email.String != user.Email
+		if emailChanged {
+			update.Email = arg.Email.String
+			update.IsEmailVerified = false
+		}
+
+		user, err = q.UpdateUser(ctx, update)
+		if err != nil {
+			return err
+		}
+		if emailChanged {
+			return addVerifyEmail(ctx, q, user)
+		}
+		return nil
+	})
+	return user, err
+}
@@ -2,6 +2,7 @@ package db
 
 import (
 	"context"
+	"database/sql"
 	"gobank/util"
 	"testing"
 	"time"
@@ -54,3 +55,79 @@ func TestGetUser(t *testing.T) {
 	require.WithinDuration(t, user1.CreatedAt, user2.CreatedAt, time.Second)
 
 }
+
+func TestUpdateUserTx(t *testing.T) {
+	store := NewStore(testDB)
+	user := createRandomUser(t)
+	_, err := testQueries.UpdateUser(context.Background(), UpdateUserParams{
+		Username:        user.Username,
+		FullName:        user.FullName,
+		Email:           user.Email,
+		IsEmailVerified: true,
+	})
+	require.NoError(t, err)
+
+	// changing only the name keeps the email verified
+	updated, err := store.UpdateUserTx(context.Background(), UpdateUserTxParams{
+		Username: user.Username,
+		FullName: sql.NullString{String: "New Name", Valid: true},
+	})
+	require.NoError(t, err)
+	require.Equal(t, "New Name", updated.FullName)
+	require.Equal(t, user.Email, updated.Email)
+	require.True(t, updated.IsEmailVerified)
+
+	// a new address has to be verified again
+	newEmail := util.RandomEmail()
+	updated, err = store.UpdateUserTx(context.Background(), UpdateUserTxParams{
+		Username: user.Username,
+		Email:    sql.NullString{String: newEmail, Valid: true},
+	})
+	require.NoError(t, err)
+	require.Equal(t, newEmail, updated.Email)
+	require.Equal(t, "New Name", updated.FullName)
+	require.False(t, updated.IsEmailVerified)
+}
+
+func TestUpdateUserPassword(t *testing.T) {
+	user := createRandomUser(t)
+
+	changedAt := time.Now().Add(-time.Hour)
+	updated, err := testQueries.UpdateUserPassword(context.Background(), UpdateUserPasswordParams{
+		Username:          user.Username,
+		HashedPassword:    "new",
+		PasswordChangedAt: changedAt,
+	})
+	require.NoError(t, err)
+	require.Equal(t, "new", updated.HashedPassword)
+	// the time given is stored, whatever the database's clock says
+	require.WithinDuration(t, changedAt, updated.PasswordChangedAt, time.Millisecond)
+}
+
+func TestRehashUserPassword(t *testing.T) {
+	user := createRandomUser(t)
+
+	err := testQueries.RehashUserPassword(context.Background(), RehashUserPasswordParams{
+		Username:          user.Username,
+		OldHashedPassword: user.HashedPassword,
+		NewHashedPassword: "rehashed",
+	})
+	require.NoError(t, err)
+
+	// a rehash is not a password change, so sessions stay valid
+	got, err := testQueries.GetUser(context.Background(), user.Username)
+	require.NoError(t, err)
+	require.Equal(t, "rehashed", got.HashedPassword)
+	require.Equal(t, user.PasswordChangedAt, got.PasswordChangedAt)
+
+	// a hash that changed in the meantime is left alone
+	err = testQueries.RehashUserPassword(context.Background(), RehashUserPasswordParams{
+		Username:          user.Username,
+		OldHashedPassword: user.HashedPassword,
+		NewHashedPassword: "stale",
+	})
+	require.NoError(t, err)
+	got, err = testQueries.GetUser(context.Background(), user.Username)
+	require.NoError(t, err)
+	require.Equal(t, "rehashed", got.HashedPassword)
+}
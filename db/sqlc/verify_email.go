@@ -0,0 +1,72 @@
+package db
+
+import (
+	"context"
+	"gobank/util"
+	"time"
+)
+
+const (
+	// JobSendVerifyEmail sends the verification link for a SendVerifyEmailPayload.
+	JobSendVerifyEmail = "send_verify_email"
+
+	VerifyEmailDuration = 24 * time.Hour
+)
+
+type SendVerifyEmailPayload struct {
+	VerifyEmailID int64 `json:"verify_email_id"`
+}
+
+// addVerifyEmail starts the verification of user's email and queues the email carrying the code. The mail
+// job mints the code, so it never sits in the job's payload.
+func addVerifyEmail(ctx context.Context, q *Queries, user User) error {
+	ve, err := q.CreateVerifyEmail(ctx, CreateVerifyEmailParams{
+		Username:  user.Username,
+		Email:     user.Email,
+		ExpiredAt: time.Now().Add(VerifyEmailDuration),
+	})
+	if err != nil {
+		return err
+	}
+	_, err = q.EnqueueJob(ctx, JobSendVerifyEmail, SendVerifyEmailPayload{VerifyEmailID: ve.ID})
+	return err
+}
+
+// ResendVerifyEmailTx starts a new verification of user's current email and queues the email carrying it.
+func (s *SQLStore) ResendVerifyEmailTx(ctx context.Context, user User) error {
+	return s.execTx(ctx, func(q *Queries) error {
+		return addVerifyEmail(ctx, q, user)
+	})
+}
+
+type VerifyEmailTxParams struct {
+	EmailID    int64
+	SecretCode string
+}
+
+type VerifyEmailTxResult struct {
+	User        User
+	VerifyEmail VerifyEmail
+}
+
+// VerifyEmailTx uses up a verification code and marks the user's email verified. An unknown, used or
+// expired code, or one for an address the user has since changed, returns sql.ErrNoRows.
+func (s *SQLStore) VerifyEmailTx(ctx context.Context, arg VerifyEmailTxParams) (VerifyEmailTxResult, error) {
+	var result VerifyEmailTxResult
+	err := s.execTx(ctx, func(q *Queries) error {
+		var err error
+		result.VerifyEmail, err = q.UseVerifyEmail(ctx, UseVerifyEmailParams{
+			ID:         arg.EmailID,
+			SecretCode: util.HashToken(arg.SecretCode),
+		})
+		if err != nil {
+			return err
+		}
+		result.User, err = q.MarkUserEmailVerified(ctx, MarkUserEmailVerifiedParams{
+			Username: result.VerifyEmail.Username,
+			Email:    result.VerifyEmail.Email,
+		})
+		return err
+	})
+	return result, err
+}
@@ -0,0 +1,130 @@
+package db
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"gobank/util"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestCreateUserTxQueuesVerifyEmail(t *testing.T) {
+	store := NewStore(testDB)
+
+	user, err := store.CreateUserTx(context.Background(), CreateUserParams{
+		Username:       util.RandomOwner(),
+		HashedPassword: "secret",
+		FullName:       util.RandomOwner(),
+		Email:          util.RandomEmail(),
+	})
+	require.NoError(t, err)
+	require.False(t, user.IsEmailVerified)
+
+	// the verification email goes out through the job queue
+	var sent SendVerifyEmailPayload
+	jobs, err := store.ClaimJobs(context.Background(), 1000)
+	require.NoError(t, err)
+	for _, job := range jobs {
+		if job.Type != JobSendVerifyEmail {
+			continue
+		}
+		var p SendVerifyEmailPayload
+		require.NoError(t, json.Unmarshal(job.Payload, &p))
+		ve, err := store.GetVerifyEmail(context.Background(), p.VerifyEmailID)
+		require.NoError(t, err)
+		if ve.Username == user.Username {
+			sent = p
+		}
+	}
+	require.NotZero(t, sent.VerifyEmailID)
+
+	ve, err := store.GetVerifyEmail(context.Background(), sent.VerifyEmailID)
+	require.NoError(t, err)
+	require.Equal(t, user.Email, ve.Email)
+	require.False(t, ve.IsUsed)
+	// the code is only minted when the email is sent
+	require.Empty(t, ve.SecretCode)
+	require.WithinDuration(t, time.Now().Add(VerifyEmailDuration), ve.ExpiredAt, time.Minute)
+}
+
+// createRandomVerifyEmail returns a verification as the mail job leaves it, and the code it emailed.
+func createRandomVerifyEmail(t *testing.T, user User, expiredAt time.Time) (VerifyEmail, string) {
+	code := util.RandomString(32)
+	ve, err := testQueries.CreateVerifyEmail(context.Background(), CreateVerifyEmailParams{
+		Username:   user.Username,
+		Email:      user.Email,
+		SecretCode: util.HashToken(code),
+		ExpiredAt:  expiredAt,
+	})
+	require.NoError(t, err)
+	return ve, code
+}
+
+func TestVerifyEmailTx(t *testing.T) {
+	store := NewStore(testDB)
+	user := createRandomUser(t)
+	ve, code := createRandomVerifyEmail(t, user, time.Now().Add(time.Hour))
+
+	_, err := store.VerifyEmailTx(context.Background(), VerifyEmailTxParams{EmailID: ve.ID, SecretCode: "wrong"})
+	require.ErrorIs(t, err, sql.ErrNoRows)
+
+	// the stored hash is no use to someone who can read the table
+	_, err = store.VerifyEmailTx(context.Background(), VerifyEmailTxParams{EmailID: ve.ID, SecretCode: ve.SecretCode})
+	require.ErrorIs(t, err, sql.ErrNoRows)
+
+	result, err := store.VerifyEmailTx(context.Background(), VerifyEmailTxParams{EmailID: ve.ID, SecretCode: code})
+	require.NoError(t, err)
+	require.True(t, result.VerifyEmail.IsUsed)
+	require.True(t, result.User.IsEmailVerified)
+	require.Equal(t, user.Username, result.User.Username)
+
+	// a code only works once
+	_, err = store.VerifyEmailTx(context.Background(), VerifyEmailTxParams{EmailID: ve.ID, SecretCode: code})
+	require.ErrorIs(t, err, sql.ErrNoRows)
+}
+
+func TestVerifyEmailTxExpired(t *testing.T) {
+	store := NewStore(testDB)
+	user := createRandomUser(t)
+	ve, code := createRandomVerifyEmail(t, user, time.Now().Add(-time.Minute))
+
+	_, err := store.VerifyEmailTx(context.Background(), VerifyEmailTxParams{EmailID: ve.ID, SecretCode: code})
+	require.ErrorIs(t, err, sql.ErrNoRows)
+
+	got, err := store.GetUser(context.Background(), user.Username)
+	require.NoError(t, err)
+	require.False(t, got.IsEmailVerified)
+}
+
+func TestSetVerifyEmailSecretCode(t *testing.T) {
+	user := createRandomUser(t)
+	ve, _ := createRandomVerifyEmail(t, user, time.Now().Add(time.Hour))
+
+	hash := util.HashToken("new code")
+	updated, err := testQueries.SetVerifyEmailSecretCode(context.Background(), SetVerifyEmailSecretCodeParams{ID: ve.ID, SecretCode: hash})
+	require.NoError(t, err)
+	require.Equal(t, hash, updated.SecretCode)
+
+	// once used, the code can no longer be replaced
+	_, err = NewStore(testDB).VerifyEmailTx(context.Background(), VerifyEmailTxParams{EmailID: ve.ID, SecretCode: "new code"})
+	require.NoError(t, err)
+	_, err = testQueries.SetVerifyEmailSecretCode(context.Background(), SetVerifyEmailSecretCodeParams{ID: ve.ID, SecretCode: hash})
+	require.ErrorIs(t, err, sql.ErrNoRows)
+}
+
+func TestResendVerifyEmailTx(t *testing.T) {
+	store := NewStore(testDB)
+	user := createRandomUser(t)
+	first, _ := createRandomVerifyEmail(t, user, time.Now().Add(time.Hour))
+
+	require.NoError(t, store.ResendVerifyEmailTx(context.Background(), user))
+
+	latest, err := store.GetLatestVerifyEmail(context.Background(), user.Username)
+	require.NoError(t, err)
+	require.Greater(t, latest.ID, first.ID)
+	require.Equal(t, user.Email, latest.Email)
+	require.Empty(t, latest.SecretCode)
+}
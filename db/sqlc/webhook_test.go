@@ -0,0 +1,96 @@
+package db
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestWebhookDeliveries(t *testing.T) {
+	store := NewStore(testDB)
+	ctx := context.Background()
+
+	user := createRandomUser(t)
+	endpoint, err := store.CreateWebhookEndpoint(ctx, CreateWebhookEndpointParams{
+		Owner:      user.Username,
+		Url:        "https://example.com/hooks",
+		EventTypes: []string{"transfer.incoming", "account.created"},
+		Secret:     "whsec_test",
+	})
+	require.NoError(t, err)
+	require.Equal(t, []string{"transfer.incoming", "account.created"}, endpoint.EventTypes)
+
+	endpoints, err := store.ListWebhookEndpointsForEvent(ctx, ListWebhookEndpointsForEventParams{
+		Owner:     user.Username,
+		EventType: "account.created",
+	})
+	require.NoError(t, err)
+	require.Len(t, endpoints, 1)
+
+	endpoints, err = store.ListWebhookEndpointsForEvent(ctx, ListWebhookEndpointsForEventParams{
+		Owner:     user.Username,
+		EventType: "transfer.outgoing",
+	})
+	require.NoError(t, err)
+	require.Empty(t, endpoints)
+
+	event, err := store.CreateOutboxEvent(ctx, CreateOutboxEventParams{
+		AggregateType: "account",
+		AggregateID:   "1",
+		EventType:     EventAccountCreated,
+		Payload:       json.RawMessage(`{}`),
+	})
+	require.NoError(t, err)
+
+	// queuing the same event twice for an endpoint keeps a single delivery
+	for i := 0; i < 2; i++ {
+		err = store.CreateWebhookDelivery(ctx, CreateWebhookDeliveryParams{
+			EndpointID: endpoint.ID,
+			EventID:    event.ID,
+			EventType:  "account.created",
+			Payload:    json.RawMessage(`{"type":"account.created"}`),
+		})
+		require.NoError(t, err)
+	}
+	deliveries, err := store.ListWebhookDeliveries(ctx, ListWebhookDeliveriesParams{EndpointID: endpoint.ID, Limit: 10})
+	require.NoError(t, err)
+	require.Len(t, deliveries, 1)
+	require.Equal(t, "pending", deliveries[0].Status)
+
+	claimed, err := store.ClaimDueWebhookDeliveries(ctx, ClaimDueWebhookDeliveriesParams{
+		LeaseUntil:    time.Now().Add(time.Minute),
+		MaxDeliveries: 1000,
+	})
+	require.NoError(t, err)
+	var found bool
+	for _, d := range claimed {
+		found = found || d.ID == deliveries[0].ID
+	}
+	require.True(t, found)
+
+	failed, err := store.RecordWebhookDeliveryAttempt(ctx, RecordWebhookDeliveryAttemptParams{
+		ID:             deliveries[0].ID,
+		Status:         "failed",
+		ResponseStatus: 500,
+		LastError:      "endpoint responded with 500",
+		NextAttemptAt:  time.Now(),
+	})
+	require.NoError(t, err)
+	require.Equal(t, int32(1), failed.Attempts)
+	require.False(t, failed.DeliveredAt.Valid)
+
+	requeued, err := store.RequeueWebhookDelivery(ctx, failed.ID)
+	require.NoError(t, err)
+	require.Equal(t, "pending", requeued.Status)
+	require.Zero(t, requeued.Attempts)
+	require.Empty(t, requeued.LastError)
+
+	err = store.DeleteWebhookEndpoint(ctx, endpoint.ID)
+	require.NoError(t, err)
+	_, err = store.GetWebhookDelivery(ctx, failed.ID)
+	require.EqualError(t, err, sql.ErrNoRows.Error())
+}
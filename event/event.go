@@ -0,0 +1,34 @@
+package event
+
+import (
+	"context"
+	"encoding/json"
+	db "gobank/db/sqlc"
+	"time"
+)
+
+// Event is a domain event as it leaves the outbox.
+type Event struct {
+	ID            int64           `json:"id"`
+	Type          string          `json:"type"`
+	AggregateType string          `json:"aggregate_type"`
+	AggregateID   string          `json:"aggregate_id"`
+	Payload       json.RawMessage `json:"payload"`
+	OccurredAt    time.Time       `json:"occurred_at"`
+}
+
+// Publisher delivers events to whatever other services listen on.
+type Publisher interface {
+	Publish(ctx context.Context, e Event) error
+}
+
+func fromOutbox(e db.OutboxEvent) Event {
+	return Event{
+		ID:            e.ID,
+		Type:          e.EventType,
+		AggregateType: e.AggregateType,
+		AggregateID:   e.AggregateID,
+		Payload:       e.Payload,
+		OccurredAt:    e.CreatedAt,
+	}
+}
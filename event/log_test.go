@@ -0,0 +1,27 @@
+package event
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestLogPublisherLeavesOutPayload(t *testing.T) {
+	var buf bytes.Buffer
+	p := NewLogPublisher(&buf)
+
+	err := p.Publish(context.Background(), Event{
+		ID:            7,
+		Type:          "UserCreated",
+		AggregateType: "user",
+		AggregateID:   "alice",
+		Payload:       json.RawMessage(`{"email":"alice@email.com"}`),
+		OccurredAt:    time.Now(),
+	})
+	require.NoError(t, err)
+	require.JSONEq(t, `{"id":7,"type":"UserCreated","aggregate_id":"alice"}`, buf.String())
+}
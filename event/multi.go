@@ -0,0 +1,19 @@
+package event
+
+import "context"
+
+type multiPublisher []Publisher
+
+// Multi publishes every event to each of publishers in turn, stopping at the first error.
+func Multi(publishers ...Publisher) Publisher {
+	return multiPublisher(publishers)
+}
+
+func (m multiPublisher) Publish(ctx context.Context, e Event) error {
+	for _, p := range m {
+		if err := p.Publish(ctx, e); err != nil {
+			return err
+		}
+	}
+	return nil
+}
@@ -0,0 +1,59 @@
+package event
+
+import (
+	"context"
+	db "gobank/db/sqlc"
+	"log"
+	"time"
+)
+
+const defaultRelayBatchSize = 100
+
+// Relay moves events from the outbox to a Publisher, oldest first.
+type Relay struct {
+	store     db.Store
+	publisher Publisher
+	interval  time.Duration
+	batchSize int32
+}
+
+func NewRelay(store db.Store, publisher Publisher, interval time.Duration) *Relay {
+	return &Relay{
+		store:     store,
+		publisher: publisher,
+		interval:  interval,
+		batchSize: defaultRelayBatchSize,
+	}
+}
+
+// RelayOnce publishes pending events until the outbox is drained or publishing fails.
+func (r *Relay) RelayOnce(ctx context.Context) (int, error) {
+	total := 0
+	for {
+		n, err := r.store.RelayOutboxEvents(ctx, r.batchSize, func(e db.OutboxEvent) error {
+			return r.publisher.Publish(ctx, fromOutbox(e))
+		})
+		total += n
+		if err != nil || n < int(r.batchSize) {
+			return total, err
+		}
+	}
+}
+
+// Run relays events every interval until ctx is cancelled. Failed events are retried on the next tick.
+func (r *Relay) Run(ctx context.Context) {
+	ticker := time.NewTicker(r.interval)
+	defer ticker.Stop()
+
+	for {
+		if _, err := r.RelayOnce(ctx); err != nil && ctx.Err() == nil {
+			log.Println("cannot relay outbox events:", err)
+		}
+
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+	}
+}
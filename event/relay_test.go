@@ -0,0 +1,125 @@
+package event
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	mockdb "gobank/db/mock"
+	db "gobank/db/sqlc"
+	"testing"
+	"time"
+
+	"github.com/golang/mock/gomock"
+	"github.com/stretchr/testify/require"
+)
+
+type failingPublisher struct {
+	failOn int64
+	inner  *MemoryPublisher
+}
+
+func (p *failingPublisher) Publish(ctx context.Context, e Event) error {
+	if e.ID == p.failOn {
+		return errors.New("broker unavailable")
+	}
+	return p.inner.Publish(ctx, e)
+}
+
+// relayFrom stubs RelayOutboxEvents so the callback sees events as the store would hand them out.
+func relayFrom(outbox []db.OutboxEvent) func(ctx context.Context, limit int32, publish func(db.OutboxEvent) error) (int, error) {
+	return func(ctx context.Context, limit int32, publish func(db.OutboxEvent) error) (int, error) {
+		n := 0
+		for _, e := range outbox {
+			if n == int(limit) {
+				break
+			}
+			if err := publish(e); err != nil {
+				return n, err
+			}
+			n++
+		}
+		return n, nil
+	}
+}
+
+func TestRelayOnce(t *testing.T) {
+	outbox := []db.OutboxEvent{
+		{ID: 1, EventType: db.EventUserCreated, AggregateType: "user", AggregateID: "alice", Payload: json.RawMessage(`{"username":"alice"}`), CreatedAt: time.Now()},
+		{ID: 2, EventType: db.EventAccountCreated, AggregateType: "account", AggregateID: "1", Payload: json.RawMessage(`{"id":1}`), CreatedAt: time.Now()},
+		{ID: 3, EventType: db.EventTransferCompleted, AggregateType: "transfer", AggregateID: "5", Payload: json.RawMessage(`{}`), CreatedAt: time.Now()},
+	}
+
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	store := mockdb.NewMockStore(ctrl)
+	store.EXPECT().
+		RelayOutboxEvents(gomock.Any(), gomock.Eq(int32(defaultRelayBatchSize)), gomock.Any()).
+		Times(1).
+		DoAndReturn(relayFrom(outbox))
+
+	publisher := NewMemoryPublisher()
+	relay := NewRelay(store, publisher, time.Second)
+
+	n, err := relay.RelayOnce(context.Background())
+	require.NoError(t, err)
+	require.Equal(t, 3, n)
+
+	events := publisher.Events()
+	require.Len(t, events, 3)
+	for i, e := range events {
+		require.Equal(t, outbox[i].ID, e.ID)
+		require.Equal(t, outbox[i].EventType, e.Type)
+		require.Equal(t, outbox[i].AggregateID, e.AggregateID)
+		require.JSONEq(t, string(outbox[i].Payload), string(e.Payload))
+	}
+}
+
+func TestRelayOnceDrainsInBatches(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	store := mockdb.NewMockStore(ctrl)
+	gomock.InOrder(
+		store.EXPECT().
+			RelayOutboxEvents(gomock.Any(), gomock.Any(), gomock.Any()).
+			DoAndReturn(relayFrom([]db.OutboxEvent{{ID: 1}, {ID: 2}})),
+		store.EXPECT().
+			RelayOutboxEvents(gomock.Any(), gomock.Any(), gomock.Any()).
+			DoAndReturn(relayFrom([]db.OutboxEvent{{ID: 3}})),
+	)
+
+	publisher := NewMemoryPublisher()
+	relay := NewRelay(store, publisher, time.Second)
+	relay.batchSize = 2
+
+	n, err := relay.RelayOnce(context.Background())
+	require.NoError(t, err)
+	require.Equal(t, 3, n)
+	require.Len(t, publisher.Events(), 3)
+}
+
+func TestRelayOnceStopsAtFailure(t *testing.T) {
+	outbox := []db.OutboxEvent{{ID: 1}, {ID: 2}, {ID: 3}}
+
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	store := mockdb.NewMockStore(ctrl)
+	store.EXPECT().
+		RelayOutboxEvents(gomock.Any(), gomock.Any(), gomock.Any()).
+		Times(1).
+		DoAndReturn(relayFrom(outbox))
+
+	publisher := &failingPublisher{failOn: 2, inner: NewMemoryPublisher()}
+	relay := NewRelay(store, publisher, time.Second)
+
+	n, err := relay.RelayOnce(context.Background())
+	require.Error(t, err)
+	require.Equal(t, 1, n)
+
+	// nothing after the failed event may be published, or consumers would see events out of order
+	events := publisher.inner.Events()
+	require.Len(t, events, 1)
+	require.Equal(t, int64(1), events[0].ID)
+}
@@ -0,0 +1,30 @@
+package mail
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sync/atomic"
+	"time"
+)
+
+// FileSender writes each email to an .eml file in a directory instead of sending it; for development.
+type FileSender struct {
+	dir  string
+	from string
+	seq  uint64
+}
+
+func NewFileSender(dir, from string) (*FileSender, error) {
+	if err := os.MkdirAll(dir, 0o755); err != nil {
+		return nil, err
+	}
+	return &FileSender{dir: dir, from: from}, nil
+}
+
+func (s *FileSender) Send(ctx context.Context, msg Message) error {
+	now := time.Now()
+	name := fmt.Sprintf("%s-%d.eml", now.Format("20060102T150405.000000000"), atomic.AddUint64(&s.seq, 1))
+	return os.WriteFile(filepath.Join(s.dir, name), format(s.from, msg, now), 0o644)
+}
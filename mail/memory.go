@@ -0,0 +1,30 @@
+package mail
+
+import (
+	"context"
+	"sync"
+)
+
+// MemorySender keeps sent emails in memory; for tests.
+type MemorySender struct {
+	mu   sync.Mutex
+	sent []Message
+}
+
+func NewMemorySender() *MemorySender {
+	return &MemorySender{}
+}
+
+func (s *MemorySender) Send(ctx context.Context, msg Message) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.sent = append(s.sent, msg)
+	return nil
+}
+
+// Messages returns a copy of everything sent so far.
+func (s *MemorySender) Messages() []Message {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return append([]Message(nil), s.sent...)
+}
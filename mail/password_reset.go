@@ -0,0 +1,64 @@
+package mail
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"fmt"
+	db "gobank/db/sqlc"
+	"gobank/queue"
+	"gobank/util"
+	"time"
+)
+
+// NewPasswordResetHandler returns the queue handler for db.JobSendPasswordReset. It mints the token and stores
+// only its hash, so a retried job sends a new token and the one before stops working.
+func NewPasswordResetHandler(store db.Store, sender Sender) queue.Handler {
+	return func(ctx context.Context, job db.Job) error {
+		var payload db.SendPasswordResetPayload
+		if err := json.Unmarshal(job.Payload, &payload); err != nil {
+			return fmt.Errorf("%v: %w", err, queue.ErrPermanent)
+		}
+
+		prt, err := store.GetPasswordResetToken(ctx, payload.PasswordResetID)
+		if err != nil {
+			if err == sql.ErrNoRows {
+				return fmt.Errorf("password reset %d not found: %w", payload.PasswordResetID, queue.ErrPermanent)
+			}
+			return err
+		}
+		if prt.UsedAt.Valid || time.Now().After(prt.ExpiredAt) {
+			return nil
+		}
+
+		user, err := store.GetUser(ctx, prt.Username)
+		if err != nil {
+			return err
+		}
+
+		token, err := util.NewSecretToken(32)
+		if err != nil {
+			return err
+		}
+		_, err = store.SetPasswordResetTokenHash(ctx, db.SetPasswordResetTokenHashParams{
+			ID:        prt.ID,
+			TokenHash: util.HashToken(token),
+		})
+		if err != nil {
+			//used, or invalidated by another reset, since we looked
+			if err == sql.ErrNoRows {
+				return nil
+			}
+			return err
+		}
+
+		return sender.Send(ctx, Message{
+			To:      []string{user.Email},
+			Subject: "Reset your password",
+			Body: fmt.Sprintf("Hello %s,\n\nSomeone asked to reset the password of your gobank account. If it was you, "+
+				"use this code with POST /users/password/reset within %d minutes:\n\n%s\n\n"+
+				"If you did not ask for it, you can ignore this email; your password stays as it is.\n",
+				user.FullName, int(db.PasswordResetDuration.Minutes()), token),
+		})
+	}
+}
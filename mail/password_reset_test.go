@@ -0,0 +1,95 @@
+package mail
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	mockdb "gobank/db/mock"
+	db "gobank/db/sqlc"
+	"gobank/util"
+	"regexp"
+	"testing"
+	"time"
+
+	"github.com/golang/mock/gomock"
+	"github.com/stretchr/testify/require"
+)
+
+func TestPasswordResetHandler(t *testing.T) {
+	user := db.User{Username: "alice", FullName: "Alice Liddell", Email: "alice@example.com"}
+	prt := db.PasswordResetToken{ID: 3, Username: user.Username, ExpiredAt: time.Now().Add(time.Hour)}
+
+	payload, err := json.Marshal(db.SendPasswordResetPayload{PasswordResetID: prt.ID})
+	require.NoError(t, err)
+	job := db.Job{ID: 1, Type: db.JobSendPasswordReset, Payload: payload}
+
+	// the hash the handler stored for the token it minted
+	var storedHash string
+
+	testCases := []struct {
+		name       string
+		buildStubs func(store *mockdb.MockStore)
+		sent       int
+	}{
+		{
+			name: "Sent",
+			buildStubs: func(store *mockdb.MockStore) {
+				store.EXPECT().GetPasswordResetToken(gomock.Any(), gomock.Eq(prt.ID)).Times(1).Return(prt, nil)
+				store.EXPECT().GetUser(gomock.Any(), gomock.Eq(user.Username)).Times(1).Return(user, nil)
+				store.EXPECT().
+					SetPasswordResetTokenHash(gomock.Any(), gomock.Any()).
+					Times(1).
+					DoAndReturn(func(_ context.Context, arg db.SetPasswordResetTokenHashParams) (db.PasswordResetToken, error) {
+						require.Equal(t, prt.ID, arg.ID)
+						storedHash = arg.TokenHash
+						return prt, nil
+					})
+			},
+			sent: 1,
+		},
+		{
+			name: "UsedWhileSending",
+			buildStubs: func(store *mockdb.MockStore) {
+				store.EXPECT().GetPasswordResetToken(gomock.Any(), gomock.Eq(prt.ID)).Times(1).Return(prt, nil)
+				store.EXPECT().GetUser(gomock.Any(), gomock.Eq(user.Username)).Times(1).Return(user, nil)
+				store.EXPECT().SetPasswordResetTokenHash(gomock.Any(), gomock.Any()).Times(1).Return(db.PasswordResetToken{}, sql.ErrNoRows)
+			},
+		},
+		{
+			name: "AlreadyUsed",
+			buildStubs: func(store *mockdb.MockStore) {
+				used := prt
+				used.UsedAt = sql.NullTime{Time: time.Now(), Valid: true}
+				store.EXPECT().GetPasswordResetToken(gomock.Any(), gomock.Eq(prt.ID)).Times(1).Return(used, nil)
+				store.EXPECT().GetUser(gomock.Any(), gomock.Any()).Times(0)
+			},
+		},
+	}
+
+	for i := range testCases {
+		tc := testCases[i]
+
+		t.Run(tc.name, func(t *testing.T) {
+			ctrl := gomock.NewController(t)
+			defer ctrl.Finish()
+
+			store := mockdb.NewMockStore(ctrl)
+			tc.buildStubs(store)
+
+			sender := NewMemorySender()
+			err := NewPasswordResetHandler(store, sender)(context.Background(), job)
+			require.NoError(t, err)
+
+			sent := sender.Messages()
+			require.Len(t, sent, tc.sent)
+			if tc.sent > 0 {
+				require.Equal(t, []string{user.Email}, sent[0].To)
+
+				// the token is the line after the instructions
+				token := regexp.MustCompile(`minutes:\n\n(\S+)\n`).FindStringSubmatch(sent[0].Body)
+				require.Len(t, token, 2)
+				require.Equal(t, util.HashToken(token[1]), storedHash)
+			}
+		})
+	}
+}
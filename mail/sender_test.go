@@ -0,0 +1,44 @@
+package mail
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestFormat(t *testing.T) {
+	date := time.Date(2024, 3, 1, 10, 0, 0, 0, time.UTC)
+	raw := string(format("no-reply@gobank.local", Message{
+		To:      []string{"alice@example.com", "bob@example.com"},
+		Subject: "Grüße",
+		Body:    "line one\nline two",
+	}, date))
+
+	require.Contains(t, raw, "From: no-reply@gobank.local\r\n")
+	require.Contains(t, raw, "To: alice@example.com, bob@example.com\r\n")
+	require.Contains(t, raw, "Subject: =?utf-8?q?Gr=C3=BC=C3=9Fe?=\r\n")
+	require.Contains(t, raw, "Date: Fri, 01 Mar 2024 10:00:00 +0000\r\n")
+	require.True(t, strings.HasSuffix(raw, "\r\n\r\nline one\r\nline two"))
+}
+
+func TestFileSender(t *testing.T) {
+	dir := filepath.Join(t.TempDir(), "mail")
+	sender, err := NewFileSender(dir, "no-reply@gobank.local")
+	require.NoError(t, err)
+
+	require.NoError(t, sender.Send(context.Background(), Message{To: []string{"a@example.com"}, Subject: "one", Body: "1"}))
+	require.NoError(t, sender.Send(context.Background(), Message{To: []string{"b@example.com"}, Subject: "two", Body: "2"}))
+
+	files, err := os.ReadDir(dir)
+	require.NoError(t, err)
+	require.Len(t, files, 2)
+
+	data, err := os.ReadFile(filepath.Join(dir, files[0].Name()))
+	require.NoError(t, err)
+	require.Contains(t, string(data), "To: a@example.com\r\n")
+}
@@ -0,0 +1,72 @@
+package mail
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"fmt"
+	db "gobank/db/sqlc"
+	"gobank/queue"
+	"gobank/util"
+	"net/url"
+	"strconv"
+	"time"
+)
+
+// NewVerifyEmailHandler returns the queue handler for db.JobSendVerifyEmail. The link in the email points
+// at GET /verify_email on the server reachable at baseURL. The handler mints the code and stores only its
+// hash, so a retried job sends a new code and the one before stops working.
+func NewVerifyEmailHandler(store db.Store, sender Sender, baseURL string) queue.Handler {
+	return func(ctx context.Context, job db.Job) error {
+		var payload db.SendVerifyEmailPayload
+		if err := json.Unmarshal(job.Payload, &payload); err != nil {
+			return fmt.Errorf("%v: %w", err, queue.ErrPermanent)
+		}
+
+		ve, err := store.GetVerifyEmail(ctx, payload.VerifyEmailID)
+		if err != nil {
+			if err == sql.ErrNoRows {
+				return fmt.Errorf("verify email %d not found: %w", payload.VerifyEmailID, queue.ErrPermanent)
+			}
+			return err
+		}
+		//nothing left to do if the user got in first or the code ran out while the job waited
+		if ve.IsUsed || time.Now().After(ve.ExpiredAt) {
+			return nil
+		}
+
+		user, err := store.GetUser(ctx, ve.Username)
+		if err != nil {
+			return err
+		}
+
+		code, err := util.NewSecretToken(32)
+		if err != nil {
+			return err
+		}
+		_, err = store.SetVerifyEmailSecretCode(ctx, db.SetVerifyEmailSecretCodeParams{
+			ID:         ve.ID,
+			SecretCode: util.HashToken(code),
+		})
+		if err != nil {
+			//used or expired since we looked
+			if err == sql.ErrNoRows {
+				return nil
+			}
+			return err
+		}
+
+		link := baseURL + "/verify_email?" + url.Values{
+			"email_id":    {strconv.FormatInt(ve.ID, 10)},
+			"secret_code": {code},
+		}.Encode()
+
+		return sender.Send(ctx, Message{
+			To:      []string{ve.Email},
+			Subject: "Verify your email address",
+			Body: fmt.Sprintf("Hello %s,\n\nPlease confirm your email address for gobank by opening the link below "+
+				"within %d hours:\n\n%s\n\nIf you did not sign up, you can ignore this email.\n",
+				user.FullName, int(db.VerifyEmailDuration.Hours()), link),
+		})
+	}
+}
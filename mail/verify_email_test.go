@@ -0,0 +1,133 @@
+package mail
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	mockdb "gobank/db/mock"
+	db "gobank/db/sqlc"
+	"gobank/queue"
+	"gobank/util"
+	"regexp"
+	"testing"
+	"time"
+
+	"github.com/golang/mock/gomock"
+	"github.com/stretchr/testify/require"
+)
+
+func TestVerifyEmailHandler(t *testing.T) {
+	user := db.User{Username: "alice", FullName: "Alice Liddell", Email: "alice@example.com"}
+	ve := db.VerifyEmail{ID: 5, Username: user.Username, Email: user.Email, ExpiredAt: time.Now().Add(time.Hour)}
+
+	payload, err := json.Marshal(db.SendVerifyEmailPayload{VerifyEmailID: ve.ID})
+	require.NoError(t, err)
+	job := db.Job{ID: 1, Type: db.JobSendVerifyEmail, Payload: payload}
+
+	// the hash the handler stored for the code it minted
+	var storedHash string
+
+	testCases := []struct {
+		name       string
+		buildStubs func(store *mockdb.MockStore)
+		check      func(t *testing.T, err error, sent []Message)
+	}{
+		{
+			name: "Sent",
+			buildStubs: func(store *mockdb.MockStore) {
+				store.EXPECT().GetVerifyEmail(gomock.Any(), gomock.Eq(ve.ID)).Times(1).Return(ve, nil)
+				store.EXPECT().GetUser(gomock.Any(), gomock.Eq(user.Username)).Times(1).Return(user, nil)
+				store.EXPECT().
+					SetVerifyEmailSecretCode(gomock.Any(), gomock.Any()).
+					Times(1).
+					DoAndReturn(func(_ context.Context, arg db.SetVerifyEmailSecretCodeParams) (db.VerifyEmail, error) {
+						require.Equal(t, ve.ID, arg.ID)
+						storedHash = arg.SecretCode
+						return ve, nil
+					})
+			},
+			check: func(t *testing.T, err error, sent []Message) {
+				require.NoError(t, err)
+				require.Len(t, sent, 1)
+				require.Equal(t, []string{user.Email}, sent[0].To)
+				require.Contains(t, sent[0].Body, "Alice Liddell")
+
+				link := regexp.MustCompile(`https://bank.example/verify_email\?email_id=5&secret_code=(\S+)`).FindStringSubmatch(sent[0].Body)
+				require.Len(t, link, 2)
+				require.Equal(t, util.HashToken(link[1]), storedHash)
+			},
+		},
+		{
+			name: "UsedWhileSending",
+			buildStubs: func(store *mockdb.MockStore) {
+				store.EXPECT().GetVerifyEmail(gomock.Any(), gomock.Eq(ve.ID)).Times(1).Return(ve, nil)
+				store.EXPECT().GetUser(gomock.Any(), gomock.Eq(user.Username)).Times(1).Return(user, nil)
+				store.EXPECT().SetVerifyEmailSecretCode(gomock.Any(), gomock.Any()).Times(1).Return(db.VerifyEmail{}, sql.ErrNoRows)
+			},
+			check: func(t *testing.T, err error, sent []Message) {
+				require.NoError(t, err)
+				require.Empty(t, sent)
+			},
+		},
+		{
+			name: "AlreadyUsed",
+			buildStubs: func(store *mockdb.MockStore) {
+				used := ve
+				used.IsUsed = true
+				store.EXPECT().GetVerifyEmail(gomock.Any(), gomock.Eq(ve.ID)).Times(1).Return(used, nil)
+				store.EXPECT().GetUser(gomock.Any(), gomock.Any()).Times(0)
+			},
+			check: func(t *testing.T, err error, sent []Message) {
+				require.NoError(t, err)
+				require.Empty(t, sent)
+			},
+		},
+		{
+			name: "Expired",
+			buildStubs: func(store *mockdb.MockStore) {
+				expired := ve
+				expired.ExpiredAt = time.Now().Add(-time.Minute)
+				store.EXPECT().GetVerifyEmail(gomock.Any(), gomock.Eq(ve.ID)).Times(1).Return(expired, nil)
+			},
+			check: func(t *testing.T, err error, sent []Message) {
+				require.NoError(t, err)
+				require.Empty(t, sent)
+			},
+		},
+		{
+			name: "NotFound",
+			buildStubs: func(store *mockdb.MockStore) {
+				store.EXPECT().GetVerifyEmail(gomock.Any(), gomock.Any()).Times(1).Return(db.VerifyEmail{}, sql.ErrNoRows)
+			},
+			check: func(t *testing.T, err error, sent []Message) {
+				require.ErrorIs(t, err, queue.ErrPermanent)
+			},
+		},
+		{
+			name: "InternalError",
+			buildStubs: func(store *mockdb.MockStore) {
+				store.EXPECT().GetVerifyEmail(gomock.Any(), gomock.Any()).Times(1).Return(db.VerifyEmail{}, sql.ErrConnDone)
+			},
+			check: func(t *testing.T, err error, sent []Message) {
+				require.ErrorIs(t, err, sql.ErrConnDone)
+				require.NotErrorIs(t, err, queue.ErrPermanent)
+			},
+		},
+	}
+
+	for i := range testCases {
+		tc := testCases[i]
+
+		t.Run(tc.name, func(t *testing.T) {
+			ctrl := gomock.NewController(t)
+			defer ctrl.Finish()
+
+			store := mockdb.NewMockStore(ctrl)
+			tc.buildStubs(store)
+
+			sender := NewMemorySender()
+			err := NewVerifyEmailHandler(store, sender, "https://bank.example")(context.Background(), job)
+			tc.check(t, err, sender.Messages())
+		})
+	}
+}
@@ -1,11 +1,19 @@
 package main
 
 import (
+	"context"
 	"database/sql"
+	"encoding/json"
 	"gobank/api"
 	db "gobank/db/sqlc"
+	"gobank/event"
+	"gobank/mail"
+	"gobank/queue"
+	"gobank/realtime"
 	"gobank/util"
+	"gobank/webhook"
 	"log"
+	"os"
 
 	_ "github.com/lib/pq"
 )
@@ -24,14 +32,79 @@ func main() {
 	}
 
 	store := db.NewStore(conn)
+
+	if len(os.Args) > 1 && os.Args[1] == "reconcile" {
+		runReconcile(store)
+		return
+	}
+
+	publisher := event.Multi(event.NewLogPublisher(os.Stdout), webhook.NewDispatcher(store))
+	relay := event.NewRelay(store, publisher, cfg.OutboxRelayInterval)
+	go relay.Run(context.Background())
+
+	deliveries := webhook.NewWorker(store, webhook.NewClient(cfg.WebhookTimeout), cfg.WebhookPollInterval)
+	go deliveries.Run(context.Background())
+
+	sender, err := newMailSender(cfg)
+	if err != nil {
+		log.Fatal("cannot create mail sender:", err)
+	}
+
+	jobs := queue.NewWorker(store, cfg.JobWorkers, cfg.JobPollInterval)
+	jobs.Handle(db.JobSendVerifyEmail, mail.NewVerifyEmailHandler(store, sender, cfg.PublicURL))
+	jobs.Handle(db.JobSendPasswordReset, mail.NewPasswordResetHandler(store, sender))
+	jobs.Handle(db.JobDeleteExpiredRevokedOAuthTokens, deleteExpiredRevokedOAuthTokens(store))
+	jobs.Every(db.JobDeleteExpiredRevokedOAuthTokens, cfg.OAuthRevocationCleanup)
+	go jobs.Run(context.Background())
+
 	server, err := api.NewServer(cfg, store)
 
 	if err != nil {
 		log.Fatal("cannot create server:", err)
 	}
 
+	go func() {
+		if err := realtime.Listen(context.Background(), cfg.DBSource, server.Hub()); err != nil {
+			log.Println("cannot listen for account updates:", err)
+		}
+	}()
+
 	err = server.Start(cfg.ServerAddr)
 	if err != nil {
 		log.Fatal("cannot start server:", err)
 	}
 }
+
+// newMailSender sends through SMTP_ADDR when it is set and otherwise writes emails to MAIL_DIR.
+func newMailSender(cfg util.Config) (mail.Sender, error) {
+	if cfg.SMTPAddr != "" {
+		return mail.NewSMTPSender(cfg.SMTPAddr, cfg.SMTPUsername, cfg.SMTPPassword, cfg.MailFrom)
+	}
+	return mail.NewFileSender(cfg.MailDir, cfg.MailFrom)
+}
+
+// deleteExpiredRevokedOAuthTokens keeps oauth_revoked_tokens down to the tokens that could still be used.
+func deleteExpiredRevokedOAuthTokens(store db.Store) queue.Handler {
+	return func(ctx context.Context, job db.Job) error {
+		_, err := store.DeleteExpiredRevokedOAuthTokens(ctx)
+		return err
+	}
+}
+
+// runReconcile prints the ledger reconciliation report as JSON and exits non-zero on discrepancies.
+func runReconcile(store db.Store) {
+	report, err := store.ReconcileLedger(context.Background())
+	if err != nil {
+		log.Fatal("cannot reconcile ledger:", err)
+	}
+
+	enc := json.NewEncoder(os.Stdout)
+	enc.SetIndent("", "  ")
+	if err := enc.Encode(report); err != nil {
+		log.Fatal("cannot encode report:", err)
+	}
+
+	if !report.Balanced {
+		os.Exit(1)
+	}
+}
@@ -0,0 +1,207 @@
+package queue
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	db "gobank/db/sqlc"
+	"log"
+	"sync"
+	"time"
+)
+
+// Job statuses.
+const (
+	StatusPending   = "pending"
+	StatusRunning   = "running"
+	StatusCompleted = "completed"
+	StatusDead      = "dead"
+)
+
+const (
+	baseBackoff = 10 * time.Second
+	maxBackoff  = time.Hour
+	// a job still running after this long is assumed to belong to a crashed worker and is picked up again
+	stuckAfter = 15 * time.Minute
+)
+
+// Handler performs a job. A returned error schedules a retry until the job runs out of attempts.
+type Handler func(ctx context.Context, job db.Job) error
+
+// ErrPermanent can be wrapped by a Handler to skip the remaining attempts and dead-letter the job at once.
+var ErrPermanent = errors.New("permanent job failure")
+
+// Worker runs queued jobs with a fixed number of concurrent pollers.
+type Worker struct {
+	store       db.Store
+	handlers    map[string]Handler
+	schedules   []schedule
+	concurrency int
+	interval    time.Duration
+	now         func() time.Time
+}
+
+// NewWorker returns a worker with concurrency pollers, or a single one when concurrency is not set.
+func NewWorker(store db.Store, concurrency int, interval time.Duration) *Worker {
+	if concurrency < 1 {
+		concurrency = 1
+	}
+	return &Worker{
+		store:       store,
+		handlers:    make(map[string]Handler),
+		concurrency: concurrency,
+		interval:    interval,
+		now:         time.Now,
+	}
+}
+
+// Handle registers h for jobs of jobType; it must be called before Run.
+func (w *Worker) Handle(jobType string, h Handler) {
+	w.handlers[jobType] = h
+}
+
+type schedule struct {
+	jobType  string
+	interval time.Duration
+}
+
+// Every queues a job of jobType, without a payload, every interval while Run runs, unless the last one is still
+// waiting. Like Handle, it must be called before Run.
+func (w *Worker) Every(jobType string, interval time.Duration) {
+	w.schedules = append(w.schedules, schedule{jobType: jobType, interval: interval})
+}
+
+// Run polls for jobs until ctx is cancelled, then waits for running jobs to finish.
+func (w *Worker) Run(ctx context.Context) {
+	var wg sync.WaitGroup
+	for i := 0; i < w.concurrency; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			w.poll(ctx)
+		}()
+	}
+	for _, sch := range w.schedules {
+		wg.Add(1)
+		go func(sch schedule) {
+			defer wg.Done()
+			w.repeat(ctx, sch)
+		}(sch)
+	}
+
+	ticker := time.NewTicker(stuckAfter)
+	defer ticker.Stop()
+	for {
+		if _, err := w.store.RequeueStuckJobs(ctx, w.now().Add(-stuckAfter)); err != nil && ctx.Err() == nil {
+			log.Println("cannot requeue stuck jobs:", err)
+		}
+		select {
+		case <-ctx.Done():
+			wg.Wait()
+			return
+		case <-ticker.C:
+		}
+	}
+}
+
+func (w *Worker) poll(ctx context.Context) {
+	ticker := time.NewTicker(w.interval)
+	defer ticker.Stop()
+
+	for {
+		//keep going while there is work, only sleep once the queue is empty
+		n, err := w.RunOnce(ctx)
+		if err != nil && ctx.Err() == nil {
+			log.Println("cannot run jobs:", err)
+		}
+		if n > 0 && err == nil {
+			continue
+		}
+
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+	}
+}
+
+// repeat queues the job of sch at once and then every interval, until ctx is cancelled.
+func (w *Worker) repeat(ctx context.Context, sch schedule) {
+	ticker := time.NewTicker(sch.interval)
+	defer ticker.Stop()
+
+	for {
+		_, err := w.store.ScheduleJob(ctx, db.ScheduleJobParams{Type: sch.jobType, MaxAttempts: db.DefaultJobMaxAttempts})
+		if err != nil && ctx.Err() == nil {
+			log.Printf("cannot schedule %s job: %v", sch.jobType, err)
+		}
+
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+	}
+}
+
+// RunOnce claims and runs at most one job, returning how many it ran.
+func (w *Worker) RunOnce(ctx context.Context) (int, error) {
+	jobs, err := w.store.ClaimJobs(ctx, 1)
+	if err != nil {
+		return 0, err
+	}
+	for _, job := range jobs {
+		if err := w.run(ctx, job); err != nil {
+			return 0, err
+		}
+	}
+	return len(jobs), nil
+}
+
+// run executes job and records the outcome; the error is only about recording it.
+func (w *Worker) run(ctx context.Context, job db.Job) error {
+	h, ok := w.handlers[job.Type]
+	if !ok {
+		return w.store.BuryJob(ctx, db.BuryJobParams{
+			ID:        job.ID,
+			LastError: fmt.Sprintf("no handler for job type %q", job.Type),
+		})
+	}
+
+	err := safeRun(ctx, h, job)
+	if err == nil {
+		return w.store.CompleteJob(ctx, job.ID)
+	}
+
+	if errors.Is(err, ErrPermanent) || job.Attempts >= job.MaxAttempts {
+		return w.store.BuryJob(ctx, db.BuryJobParams{ID: job.ID, LastError: err.Error()})
+	}
+	return w.store.RetryJob(ctx, db.RetryJobParams{
+		ID:        job.ID,
+		LastError: err.Error(),
+		RunAt:     w.now().Add(Backoff(job.Attempts)),
+	})
+}
+
+// safeRun turns a panicking handler into a failed attempt instead of a dead worker.
+func safeRun(ctx context.Context, h Handler, job db.Job) (err error) {
+	defer func() {
+		if r := recover(); r != nil {
+			err = fmt.Errorf("job panicked: %v", r)
+		}
+	}()
+	return h(ctx, job)
+}
+
+// Backoff is the wait after failed attempt number attempts: 10s, 20s, 40s, ... capped at an hour.
+func Backoff(attempts int32) time.Duration {
+	d := baseBackoff
+	for i := int32(1); i < attempts; i++ {
+		d *= 2
+		if d >= maxBackoff {
+			return maxBackoff
+		}
+	}
+	return d
+}
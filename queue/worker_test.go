@@ -0,0 +1,174 @@
+package queue
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	mockdb "gobank/db/mock"
+	db "gobank/db/sqlc"
+	"testing"
+	"time"
+
+	"github.com/golang/mock/gomock"
+	"github.com/stretchr/testify/require"
+)
+
+func TestWorkerRunOnce(t *testing.T) {
+	now := time.Now().Truncate(time.Second)
+	errBoom := errors.New("boom")
+
+	testCases := []struct {
+		name       string
+		job        db.Job
+		handler    Handler
+		buildStubs func(store *mockdb.MockStore, job db.Job)
+	}{
+		{
+			name:    "Completed",
+			job:     db.Job{ID: 1, Type: "send_email", Attempts: 1, MaxAttempts: 5},
+			handler: func(ctx context.Context, job db.Job) error { return nil },
+			buildStubs: func(store *mockdb.MockStore, job db.Job) {
+				store.EXPECT().CompleteJob(gomock.Any(), gomock.Eq(job.ID)).Times(1).Return(nil)
+			},
+		},
+		{
+			name:    "RetriedWithBackoff",
+			job:     db.Job{ID: 2, Type: "send_email", Attempts: 2, MaxAttempts: 5},
+			handler: func(ctx context.Context, job db.Job) error { return errBoom },
+			buildStubs: func(store *mockdb.MockStore, job db.Job) {
+				store.EXPECT().
+					RetryJob(gomock.Any(), gomock.Eq(db.RetryJobParams{ID: job.ID, LastError: "boom", RunAt: now.Add(2 * baseBackoff)})).
+					Times(1).
+					Return(nil)
+			},
+		},
+		{
+			name:    "BuriedAfterMaxAttempts",
+			job:     db.Job{ID: 3, Type: "send_email", Attempts: 5, MaxAttempts: 5},
+			handler: func(ctx context.Context, job db.Job) error { return errBoom },
+			buildStubs: func(store *mockdb.MockStore, job db.Job) {
+				store.EXPECT().BuryJob(gomock.Any(), gomock.Eq(db.BuryJobParams{ID: job.ID, LastError: "boom"})).Times(1).Return(nil)
+				store.EXPECT().RetryJob(gomock.Any(), gomock.Any()).Times(0)
+			},
+		},
+		{
+			name: "BuriedOnPermanentError",
+			job:  db.Job{ID: 5, Type: "send_email", Attempts: 1, MaxAttempts: 5},
+			handler: func(ctx context.Context, job db.Job) error {
+				return fmt.Errorf("bad address: %w", ErrPermanent)
+			},
+			buildStubs: func(store *mockdb.MockStore, job db.Job) {
+				store.EXPECT().BuryJob(gomock.Any(), gomock.Any()).Times(1).Return(nil)
+			},
+		},
+		{
+			name:    "PanicIsAFailedAttempt",
+			job:     db.Job{ID: 6, Type: "send_email", Attempts: 1, MaxAttempts: 5},
+			handler: func(ctx context.Context, job db.Job) error { panic("nil map") },
+			buildStubs: func(store *mockdb.MockStore, job db.Job) {
+				store.EXPECT().
+					RetryJob(gomock.Any(), gomock.Eq(db.RetryJobParams{ID: job.ID, LastError: "job panicked: nil map", RunAt: now.Add(baseBackoff)})).
+					Times(1).
+					Return(nil)
+			},
+		},
+		{
+			name: "UnknownTypeBuried",
+			job:  db.Job{ID: 7, Type: "unknown", Attempts: 1, MaxAttempts: 5},
+			buildStubs: func(store *mockdb.MockStore, job db.Job) {
+				store.EXPECT().
+					BuryJob(gomock.Any(), gomock.Eq(db.BuryJobParams{ID: job.ID, LastError: `no handler for job type "unknown"`})).
+					Times(1).
+					Return(nil)
+			},
+		},
+	}
+
+	for i := range testCases {
+		tc := testCases[i]
+
+		t.Run(tc.name, func(t *testing.T) {
+			ctrl := gomock.NewController(t)
+			defer ctrl.Finish()
+
+			store := mockdb.NewMockStore(ctrl)
+			store.EXPECT().ClaimJobs(gomock.Any(), gomock.Eq(int32(1))).Times(1).Return([]db.Job{tc.job}, nil)
+			tc.buildStubs(store, tc.job)
+
+			w := NewWorker(store, 1, time.Second)
+			w.now = func() time.Time { return now }
+			if tc.handler != nil {
+				w.Handle("send_email", tc.handler)
+			}
+
+			n, err := w.RunOnce(context.Background())
+			require.NoError(t, err)
+			require.Equal(t, 1, n)
+		})
+	}
+}
+
+func TestWorkerRunOnceEmpty(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	store := mockdb.NewMockStore(ctrl)
+	store.EXPECT().ClaimJobs(gomock.Any(), gomock.Any()).Times(1).Return([]db.Job{}, nil)
+
+	n, err := NewWorker(store, 1, time.Second).RunOnce(context.Background())
+	require.NoError(t, err)
+	require.Zero(t, n)
+}
+
+func TestWorkerEvery(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	scheduled := make(chan struct{}, 10)
+	store := mockdb.NewMockStore(ctrl)
+	store.EXPECT().RequeueStuckJobs(gomock.Any(), gomock.Any()).AnyTimes().Return(int64(0), nil)
+	store.EXPECT().ClaimJobs(gomock.Any(), gomock.Any()).AnyTimes().Return([]db.Job{}, nil)
+	store.EXPECT().
+		ScheduleJob(gomock.Any(), gomock.Eq(db.ScheduleJobParams{Type: "cleanup", MaxAttempts: db.DefaultJobMaxAttempts})).
+		MinTimes(2).
+		DoAndReturn(func(ctx context.Context, arg db.ScheduleJobParams) (int64, error) {
+			select {
+			case scheduled <- struct{}{}:
+			default:
+			}
+			return 1, nil
+		})
+
+	w := NewWorker(store, 1, time.Hour)
+	w.Every("cleanup", 10*time.Millisecond)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	done := make(chan struct{})
+	go func() {
+		w.Run(ctx)
+		close(done)
+	}()
+
+	// once when the worker starts, then again after the interval
+	for i := 0; i < 2; i++ {
+		select {
+		case <-scheduled:
+		case <-time.After(time.Second):
+			t.Fatal("job was not scheduled")
+		}
+	}
+	cancel()
+	<-done
+}
+
+func TestNewWorkerDefaultsToOnePoller(t *testing.T) {
+	require.Equal(t, 1, NewWorker(nil, 0, time.Second).concurrency)
+	require.Equal(t, 4, NewWorker(nil, 4, time.Second).concurrency)
+}
+
+func TestBackoff(t *testing.T) {
+	require.Equal(t, 10*time.Second, Backoff(1))
+	require.Equal(t, 20*time.Second, Backoff(2))
+	require.Equal(t, 80*time.Second, Backoff(4))
+	require.Equal(t, time.Hour, Backoff(20))
+}
@@ -0,0 +1,76 @@
+package realtime
+
+import (
+	db "gobank/db/sqlc"
+	"sync"
+)
+
+// subscriberBuffer is how far a subscriber may fall behind before it is dropped.
+const subscriberBuffer = 64
+
+// Hub fans account updates out to the subscribers of each account.
+type Hub struct {
+	mu   sync.Mutex
+	subs map[int64]map[chan db.AccountUpdate]struct{}
+	// accounts remembers what each subscriber listens to, so it can be removed from all of them at once
+	accounts map[chan db.AccountUpdate][]int64
+}
+
+func NewHub() *Hub {
+	return &Hub{
+		subs:     make(map[int64]map[chan db.AccountUpdate]struct{}),
+		accounts: make(map[chan db.AccountUpdate][]int64),
+	}
+}
+
+// Subscribe returns a channel receiving the updates of the given accounts and a function to stop receiving them.
+// The channel is closed if the subscriber falls too far behind; it should then resume from its last checkpoint.
+func (h *Hub) Subscribe(accountIDs []int64) (<-chan db.AccountUpdate, func()) {
+	ch := make(chan db.AccountUpdate, subscriberBuffer)
+
+	h.mu.Lock()
+	for _, id := range accountIDs {
+		if h.subs[id] == nil {
+			h.subs[id] = make(map[chan db.AccountUpdate]struct{})
+		}
+		h.subs[id][ch] = struct{}{}
+	}
+	h.accounts[ch] = accountIDs
+	h.mu.Unlock()
+
+	return ch, func() {
+		h.mu.Lock()
+		defer h.mu.Unlock()
+		h.remove(ch)
+	}
+}
+
+// Broadcast hands u to every subscriber of its account without blocking.
+func (h *Hub) Broadcast(u db.AccountUpdate) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+
+	for ch := range h.subs[u.AccountID] {
+		select {
+		case ch <- u:
+		default:
+			h.remove(ch)
+		}
+	}
+}
+
+// remove closes and forgets ch; h.mu must be held.
+func (h *Hub) remove(ch chan db.AccountUpdate) {
+	ids, ok := h.accounts[ch]
+	if !ok {
+		return
+	}
+	delete(h.accounts, ch)
+	for _, id := range ids {
+		delete(h.subs[id], ch)
+		if len(h.subs[id]) == 0 {
+			delete(h.subs, id)
+		}
+	}
+	close(ch)
+}
@@ -0,0 +1,62 @@
+package realtime
+
+import (
+	db "gobank/db/sqlc"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestHubRoutesByAccount(t *testing.T) {
+	hub := NewHub()
+
+	alice, stopAlice := hub.Subscribe([]int64{1, 2})
+	defer stopAlice()
+	// bob is a joint holder of account 2
+	bob, stopBob := hub.Subscribe([]int64{2, 3})
+	defer stopBob()
+
+	hub.Broadcast(db.AccountUpdate{ID: 1, AccountID: 1, Owner: "alice", Balance: 90})
+	hub.Broadcast(db.AccountUpdate{ID: 2, AccountID: 2, Owner: "alice", Balance: 10})
+
+	require.Equal(t, int64(1), (<-alice).ID)
+	require.Equal(t, int64(2), (<-alice).ID)
+	require.Equal(t, int64(2), (<-bob).ID)
+	require.Empty(t, bob)
+}
+
+func TestHubUnsubscribe(t *testing.T) {
+	hub := NewHub()
+
+	ch, stop := hub.Subscribe([]int64{1, 2})
+	stop()
+	stop()
+
+	_, ok := <-ch
+	require.False(t, ok)
+
+	// broadcasting to nobody must not panic
+	hub.Broadcast(db.AccountUpdate{ID: 1, AccountID: 1})
+	hub.Broadcast(db.AccountUpdate{ID: 2, AccountID: 2})
+	require.Empty(t, hub.subs)
+}
+
+func TestHubDropsSlowSubscriber(t *testing.T) {
+	hub := NewHub()
+
+	ch, stop := hub.Subscribe([]int64{1, 2})
+	defer stop()
+
+	for i := 0; i <= subscriberBuffer; i++ {
+		hub.Broadcast(db.AccountUpdate{ID: int64(i + 1), AccountID: 1})
+	}
+	// the dropped subscriber is gone from its other accounts too
+	hub.Broadcast(db.AccountUpdate{ID: 100, AccountID: 2})
+
+	received := 0
+	for range ch {
+		received++
+	}
+	require.Equal(t, subscriberBuffer, received)
+	require.Empty(t, hub.subs)
+}
@@ -0,0 +1,51 @@
+package realtime
+
+import (
+	"context"
+	db "gobank/db/sqlc"
+	"log"
+	"time"
+
+	"github.com/lib/pq"
+)
+
+const (
+	minReconnectInterval = time.Second
+	maxReconnectInterval = time.Minute
+	listenerPingInterval = 90 * time.Second
+)
+
+// Listen feeds hub with the account updates Postgres notifies on db.AccountUpdatesChannel until ctx is cancelled.
+// Notifications sent while the connection is down are lost; streams pick those entries up at their next checkpoint.
+func Listen(ctx context.Context, dataSource string, hub *Hub) error {
+	listener := pq.NewListener(dataSource, minReconnectInterval, maxReconnectInterval, func(ev pq.ListenerEventType, err error) {
+		if err != nil {
+			log.Println("account updates listener:", err)
+		}
+	})
+	defer listener.Close()
+
+	if err := listener.Listen(db.AccountUpdatesChannel); err != nil {
+		return err
+	}
+
+	for {
+		select {
+		case <-ctx.Done():
+			return nil
+		case n := <-listener.Notify:
+			//a nil notification means the connection was re-established
+			if n == nil {
+				continue
+			}
+			u, err := db.ParseAccountUpdate(n.Extra)
+			if err != nil {
+				log.Println("cannot decode account update:", err)
+				continue
+			}
+			hub.Broadcast(u)
+		case <-time.After(listenerPingInterval):
+			go listener.Ping()
+		}
+	}
+}
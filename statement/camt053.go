@@ -0,0 +1,190 @@
+package statement
+
+import (
+	"encoding/xml"
+	"io"
+	"strconv"
+	"time"
+)
+
+const camt053Namespace = "urn:iso:std:iso:20022:tech:xsd:camt.053.001.02"
+
+// ISO 20022 BankToCustomerStatement (camt.053.001.02) with the mandatory elements and the remittance details we hold.
+type camtDocument struct {
+	XMLName   xml.Name `xml:"Document"`
+	Namespace string   `xml:"xmlns,attr"`
+	Statement struct {
+		GroupHeader struct {
+			MsgID   string `xml:"MsgId"`
+			CreDtTm string `xml:"CreDtTm"`
+		} `xml:"GrpHdr"`
+		Stmt camtStatement `xml:"Stmt"`
+	} `xml:"BkToCstmrStmt"`
+}
+
+type camtStatement struct {
+	ID      string `xml:"Id"`
+	CreDtTm string `xml:"CreDtTm"`
+	FromTo  struct {
+		FromDtTm string `xml:"FrDtTm"`
+		ToDtTm   string `xml:"ToDtTm"`
+	} `xml:"FrToDt"`
+	Account struct {
+		ID       camtAccountID `xml:"Id"`
+		Currency string        `xml:"Ccy"`
+		Owner    camtParty     `xml:"Ownr"`
+	} `xml:"Acct"`
+	Balances []camtBalance `xml:"Bal"`
+	Summary  struct {
+		Total struct {
+			Count int `xml:"NbOfNtries"`
+		} `xml:"TtlNtries"`
+	} `xml:"TxsSummry"`
+	Entries []camtEntry `xml:"Ntry"`
+}
+
+type camtAccountID struct {
+	Other struct {
+		ID string `xml:"Id"`
+	} `xml:"Othr"`
+}
+
+type camtParty struct {
+	Name string `xml:"Nm"`
+}
+
+type camtAmount struct {
+	Currency string `xml:"Ccy,attr"`
+	Value    string `xml:",chardata"`
+}
+
+type camtDate struct {
+	DtTm string `xml:"DtTm"`
+}
+
+type camtBalance struct {
+	Code      string     `xml:"Tp>CdOrPrtry>Cd"`
+	Amount    camtAmount `xml:"Amt"`
+	CdtDbtInd string     `xml:"CdtDbtInd"`
+	Date      camtDate   `xml:"Dt"`
+}
+
+type camtEntry struct {
+	Ref         string     `xml:"NtryRef"`
+	Amount      camtAmount `xml:"Amt"`
+	CdtDbtInd   string     `xml:"CdtDbtInd"`
+	Status      string     `xml:"Sts"`
+	BookingDate camtDate   `xml:"BookgDt"`
+	ValueDate   camtDate   `xml:"ValDt"`
+	BankTxCode  string     `xml:"BkTxCd>Prtry>Cd"`
+	Details     struct {
+		Tx camtTxDetails `xml:"TxDtls"`
+	} `xml:"NtryDtls"`
+}
+
+type camtTxDetails struct {
+	EndToEndID     string          `xml:"Refs>EndToEndId"`
+	RelatedParties *camtRelated    `xml:"RltdPties,omitempty"`
+	RemittanceInfo *camtRemittance `xml:"RmtInf,omitempty"`
+}
+
+type camtRelated struct {
+	Debtor          *camtParty     `xml:"Dbtr,omitempty"`
+	DebtorAccount   *camtAccountID `xml:"DbtrAcct>Id,omitempty"`
+	Creditor        *camtParty     `xml:"Cdtr,omitempty"`
+	CreditorAccount *camtAccountID `xml:"CdtrAcct>Id,omitempty"`
+}
+
+type camtRemittance struct {
+	Unstructured string `xml:"Ustrd"`
+}
+
+// WriteCAMT053 renders st as an ISO 20022 camt.053.001.02 statement.
+func WriteCAMT053(w io.Writer, st Statement) error {
+	var doc camtDocument
+	doc.Namespace = camt053Namespace
+
+	id := strconv.FormatInt(st.AccountID, 10) + "-" + st.From.UTC().Format("20060102")
+	doc.Statement.GroupHeader.MsgID = "STMT-" + id
+	doc.Statement.GroupHeader.CreDtTm = camtTime(st.GeneratedAt)
+
+	s := &doc.Statement.Stmt
+	s.ID = id
+	s.CreDtTm = camtTime(st.GeneratedAt)
+	s.FromTo.FromDtTm = camtTime(st.From)
+	s.FromTo.ToDtTm = camtTime(st.To)
+	s.Account.ID.Other.ID = strconv.FormatInt(st.AccountID, 10)
+	s.Account.Currency = st.Currency
+	s.Account.Owner.Name = st.Owner
+	s.Balances = []camtBalance{
+		camtBalanceOf("OPBD", st.OpeningBalance, st.Currency, st.From),
+		camtBalanceOf("CLBD", st.ClosingBalance, st.Currency, st.To),
+	}
+	s.Summary.Total.Count = len(st.Lines)
+
+	for _, l := range st.Lines {
+		amount, ind := camtSigned(l.Amount)
+		e := camtEntry{
+			Ref:         strconv.FormatInt(l.EntryID, 10),
+			Amount:      camtAmount{Currency: st.Currency, Value: amount},
+			CdtDbtInd:   ind,
+			Status:      "BOOK",
+			BookingDate: camtDate{DtTm: camtTime(l.Date)},
+			ValueDate:   camtDate{DtTm: camtTime(l.Date)},
+			BankTxCode:  "TRANSFER",
+		}
+		e.Details.Tx.EndToEndID = "NOTPROVIDED"
+		if l.TransferID != 0 {
+			e.Details.Tx.EndToEndID = strconv.FormatInt(l.TransferID, 10)
+		}
+		if l.CounterpartyAccountID != 0 {
+			party := &camtParty{Name: l.CounterpartyOwner}
+			acct := &camtAccountID{}
+			acct.Other.ID = strconv.FormatInt(l.CounterpartyAccountID, 10)
+
+			// money coming in was sent by the counterparty, money going out was received by it
+			if ind == "CRDT" {
+				e.Details.Tx.RelatedParties = &camtRelated{Debtor: party, DebtorAccount: acct}
+			} else {
+				e.Details.Tx.RelatedParties = &camtRelated{Creditor: party, CreditorAccount: acct}
+			}
+		}
+		if l.Memo != "" {
+			e.Details.Tx.RemittanceInfo = &camtRemittance{Unstructured: truncate(l.Memo, 140)}
+		}
+		s.Entries = append(s.Entries, e)
+	}
+
+	if _, err := io.WriteString(w, xml.Header); err != nil {
+		return err
+	}
+	enc := xml.NewEncoder(w)
+	enc.Indent("", "  ")
+	if err := enc.Encode(doc); err != nil {
+		return err
+	}
+	_, err := io.WriteString(w, "\n")
+	return err
+}
+
+func camtBalanceOf(code string, balance int64, currency string, at time.Time) camtBalance {
+	amount, ind := camtSigned(balance)
+	return camtBalance{
+		Code:      code,
+		Amount:    camtAmount{Currency: currency, Value: amount},
+		CdtDbtInd: ind,
+		Date:      camtDate{DtTm: camtTime(at)},
+	}
+}
+
+// camtSigned splits a signed amount into the unsigned amount and credit/debit indicator camt expects.
+func camtSigned(amount int64) (string, string) {
+	if amount < 0 {
+		return strconv.FormatInt(-amount, 10), "DBIT"
+	}
+	return strconv.FormatInt(amount, 10), "CRDT"
+}
+
+func camtTime(t time.Time) string {
+	return t.UTC().Format("2006-01-02T15:04:05Z")
+}
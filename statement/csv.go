@@ -0,0 +1,34 @@
+package statement
+
+import (
+	"encoding/csv"
+	"io"
+	"strconv"
+	"time"
+)
+
+// WriteCSV renders st as CSV: one row per entry, preceded by the opening balance and followed by the closing balance.
+func WriteCSV(w io.Writer, st Statement) error {
+	cw := csv.NewWriter(w)
+
+	records := [][]string{
+		{"date", "description", "counterparty", "memo", "amount", "balance"},
+		{st.From.UTC().Format(time.RFC3339), "Opening balance", "", "", "", strconv.FormatInt(st.OpeningBalance, 10)},
+	}
+	for _, l := range st.Lines {
+		records = append(records, []string{
+			l.Date.UTC().Format(time.RFC3339),
+			l.description(),
+			l.counterparty(),
+			l.Memo,
+			strconv.FormatInt(l.Amount, 10),
+			strconv.FormatInt(l.BalanceAfter, 10),
+		})
+	}
+	records = append(records, []string{st.To.UTC().Format(time.RFC3339), "Closing balance", "", "", "", strconv.FormatInt(st.ClosingBalance, 10)})
+
+	if err := cw.WriteAll(records); err != nil {
+		return err
+	}
+	return cw.Error()
+}
@@ -0,0 +1,118 @@
+package statement
+
+import (
+	"bytes"
+	"encoding/xml"
+	"flag"
+	"io/ioutil"
+	"path/filepath"
+	"strconv"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+var update = flag.Bool("update", false, "rewrite the sample files in testdata")
+
+// requireMatchesSample compares got against testdata/name. The samples were checked by hand against sampleStatement,
+// and the tests below also assert the totals on their own so an -update cannot bless a wrong figure.
+func requireMatchesSample(t *testing.T, name string, got []byte) {
+	path := filepath.Join("testdata", name)
+	if *update {
+		require.NoError(t, ioutil.WriteFile(path, got, 0644))
+	}
+
+	want, err := ioutil.ReadFile(path)
+	require.NoError(t, err)
+	require.Equal(t, string(want), string(got))
+}
+
+func requireWellFormedXML(t *testing.T, data []byte) {
+	dec := xml.NewDecoder(bytes.NewReader(data))
+	for {
+		_, err := dec.Token()
+		if err != nil {
+			require.EqualError(t, err, "EOF")
+			return
+		}
+	}
+}
+
+func TestWriteOFX(t *testing.T) {
+	st := sampleStatement(3)
+
+	var buf bytes.Buffer
+	require.NoError(t, Write(&buf, FormatOFX, st))
+
+	requireWellFormedXML(t, buf.Bytes())
+	requireMatchesSample(t, "statement.ofx", buf.Bytes())
+
+	var doc ofxDocument
+	require.NoError(t, xml.Unmarshal(buf.Bytes(), &doc))
+	stmt := doc.Bank.Transaction.Statement
+	require.Equal(t, "115", stmt.LedgerBalance.Amount)
+
+	var types, amounts []string
+	var total int64
+	for _, trn := range stmt.TransactionList.Transactions {
+		types = append(types, trn.TrnType)
+		amounts = append(amounts, trn.TrnAmt)
+		amount, err := strconv.ParseInt(trn.TrnAmt, 10, 64)
+		require.NoError(t, err)
+		total += amount
+	}
+	require.Equal(t, []string{"CREDIT", "DEBIT", "CREDIT"}, types)
+	require.Equal(t, []string{"10", "-5", "10"}, amounts)
+	// the transactions account for the whole move from the opening to the closing balance
+	require.Equal(t, st.ClosingBalance-st.OpeningBalance, total)
+}
+
+func TestWriteCAMT053(t *testing.T) {
+	st := sampleStatement(3)
+	st.Lines[2].CounterpartyAccountID = 0
+	st.Lines[2].CounterpartyOwner = ""
+	st.Lines[2].Memo = ""
+
+	var buf bytes.Buffer
+	require.NoError(t, Write(&buf, FormatCAMT053, st))
+
+	requireWellFormedXML(t, buf.Bytes())
+	requireMatchesSample(t, "statement.camt053.xml", buf.Bytes())
+
+	var doc camtDocument
+	require.NoError(t, xml.Unmarshal(buf.Bytes(), &doc))
+	stmt := doc.Statement.Stmt
+	require.Len(t, stmt.Balances, 2)
+	require.Equal(t, "OPBD", stmt.Balances[0].Code)
+	require.Equal(t, "100", stmt.Balances[0].Amount.Value)
+	require.Equal(t, "CLBD", stmt.Balances[1].Code)
+	require.Equal(t, "115", stmt.Balances[1].Amount.Value)
+	require.Equal(t, 3, stmt.Summary.Total.Count)
+
+	// camt amounts are unsigned, the indicator carries the direction
+	var total int64
+	for _, e := range stmt.Entries {
+		amount, err := strconv.ParseInt(e.Amount.Value, 10, 64)
+		require.NoError(t, err)
+		require.Positive(t, amount)
+		if e.CdtDbtInd == "DBIT" {
+			amount = -amount
+		}
+		total += amount
+	}
+	require.Equal(t, st.ClosingBalance-st.OpeningBalance, total)
+
+	// we pay bob on a debit and bob pays us on a credit; the last entry has no counterparty
+	require.Equal(t, "bob", stmt.Entries[0].Details.Tx.RelatedParties.Debtor.Name)
+	require.Nil(t, stmt.Entries[0].Details.Tx.RelatedParties.Creditor)
+	require.Equal(t, "bob", stmt.Entries[1].Details.Tx.RelatedParties.Creditor.Name)
+	require.Nil(t, stmt.Entries[1].Details.Tx.RelatedParties.Debtor)
+	require.Nil(t, stmt.Entries[2].Details.Tx.RelatedParties)
+	require.Nil(t, stmt.Entries[2].Details.Tx.RemittanceInfo)
+}
+
+func TestFilename(t *testing.T) {
+	st := sampleStatement(0)
+	require.Equal(t, "statement-42-20210301-20210401.xml", Filename(FormatCAMT053, st))
+	require.Equal(t, "statement-42-20210301-20210401.ofx", Filename(FormatOFX, st))
+}
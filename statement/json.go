@@ -0,0 +1,16 @@
+package statement
+
+import (
+	"encoding/json"
+	"io"
+)
+
+// WriteJSON renders st as an indented JSON document.
+func WriteJSON(w io.Writer, st Statement) error {
+	if st.Lines == nil {
+		st.Lines = []Line{}
+	}
+	enc := json.NewEncoder(w)
+	enc.SetIndent("", "  ")
+	return enc.Encode(st)
+}
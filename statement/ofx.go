@@ -0,0 +1,114 @@
+package statement
+
+import (
+	"encoding/xml"
+	"io"
+	"strconv"
+	"time"
+)
+
+const ofxHeader = `<?OFX OFXHEADER="200" VERSION="220" SECURITY="NONE" OLDFILEUID="NONE" NEWFILEUID="NONE"?>` + "\n"
+
+// OFX 2.2 bank statement response, trimmed to the aggregates accounting software reads.
+type ofxDocument struct {
+	XMLName xml.Name `xml:"OFX"`
+	SignOn  struct {
+		Response struct {
+			Status   ofxStatus `xml:"STATUS"`
+			DTServer string    `xml:"DTSERVER"`
+			Language string    `xml:"LANGUAGE"`
+		} `xml:"SONRS"`
+	} `xml:"SIGNONMSGSRSV1"`
+	Bank struct {
+		Transaction struct {
+			TrnUID    string           `xml:"TRNUID"`
+			Status    ofxStatus        `xml:"STATUS"`
+			Statement ofxStatementResp `xml:"STMTRS"`
+		} `xml:"STMTTRNRS"`
+	} `xml:"BANKMSGSRSV1"`
+}
+
+type ofxStatus struct {
+	Code     int    `xml:"CODE"`
+	Severity string `xml:"SEVERITY"`
+}
+
+type ofxStatementResp struct {
+	CurDef      string `xml:"CURDEF"`
+	BankAccount struct {
+		BankID   string `xml:"BANKID"`
+		AcctID   string `xml:"ACCTID"`
+		AcctType string `xml:"ACCTTYPE"`
+	} `xml:"BANKACCTFROM"`
+	TransactionList struct {
+		DTStart      string              `xml:"DTSTART"`
+		DTEnd        string              `xml:"DTEND"`
+		Transactions []ofxStatementTrans `xml:"STMTTRN"`
+	} `xml:"BANKTRANLIST"`
+	LedgerBalance struct {
+		Amount string `xml:"BALAMT"`
+		DTAsOf string `xml:"DTASOF"`
+	} `xml:"LEDGERBAL"`
+}
+
+type ofxStatementTrans struct {
+	TrnType  string `xml:"TRNTYPE"`
+	DTPosted string `xml:"DTPOSTED"`
+	TrnAmt   string `xml:"TRNAMT"`
+	FITID    string `xml:"FITID"`
+	Name     string `xml:"NAME,omitempty"`
+	Memo     string `xml:"MEMO,omitempty"`
+}
+
+// WriteOFX renders st as an OFX 2.2 bank statement download.
+func WriteOFX(w io.Writer, st Statement) error {
+	var doc ofxDocument
+	doc.SignOn.Response.Status = ofxStatus{Code: 0, Severity: "INFO"}
+	doc.SignOn.Response.DTServer = ofxTime(st.GeneratedAt)
+	doc.SignOn.Response.Language = "ENG"
+
+	trn := &doc.Bank.Transaction
+	trn.TrnUID = strconv.FormatInt(st.AccountID, 10) + "-" + st.From.UTC().Format("20060102")
+	trn.Status = ofxStatus{Code: 0, Severity: "INFO"}
+
+	rs := &trn.Statement
+	rs.CurDef = st.Currency
+	rs.BankAccount.BankID = bankID
+	rs.BankAccount.AcctID = strconv.FormatInt(st.AccountID, 10)
+	rs.BankAccount.AcctType = "CHECKING"
+	rs.TransactionList.DTStart = ofxTime(st.From)
+	rs.TransactionList.DTEnd = ofxTime(st.To)
+	rs.TransactionList.Transactions = []ofxStatementTrans{}
+	for _, l := range st.Lines {
+		trnType := "CREDIT"
+		if l.Amount < 0 {
+			trnType = "DEBIT"
+		}
+		rs.TransactionList.Transactions = append(rs.TransactionList.Transactions, ofxStatementTrans{
+			TrnType:  trnType,
+			DTPosted: ofxTime(l.Date),
+			TrnAmt:   strconv.FormatInt(l.Amount, 10),
+			FITID:    strconv.FormatInt(l.EntryID, 10),
+			Name:     truncate(l.counterparty(), 32),
+			Memo:     truncate(l.Memo, 255),
+		})
+	}
+	rs.LedgerBalance.Amount = strconv.FormatInt(st.ClosingBalance, 10)
+	rs.LedgerBalance.DTAsOf = ofxTime(st.To)
+
+	if _, err := io.WriteString(w, xml.Header+ofxHeader); err != nil {
+		return err
+	}
+	enc := xml.NewEncoder(w)
+	enc.Indent("", "  ")
+	if err := enc.Encode(doc); err != nil {
+		return err
+	}
+	_, err := io.WriteString(w, "\n")
+	return err
+}
+
+// ofxTime formats t as an OFX datetime with an explicit UTC offset.
+func ofxTime(t time.Time) string {
+	return t.UTC().Format("20060102150405.000") + "[0:UTC]"
+}
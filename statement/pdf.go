@@ -0,0 +1,150 @@
+package statement
+
+import (
+	"bytes"
+	"fmt"
+	"io"
+	"strings"
+)
+
+// A4 in points, with a monospaced font so columns line up without measuring glyphs.
+const (
+	pdfPageWidth  = 595
+	pdfPageHeight = 842
+	pdfMargin     = 50
+	pdfFontSize   = 8
+	pdfLeading    = 12
+	pdfLinesPage  = (pdfPageHeight - 2*pdfMargin) / pdfLeading
+)
+
+// WritePDF renders st as a plain text PDF 1.4 document using the built-in Courier font.
+func WritePDF(w io.Writer, st Statement) error {
+	lines := pdfLines(st)
+
+	var pages [][]string
+	for len(lines) > pdfLinesPage {
+		pages = append(pages, lines[:pdfLinesPage])
+		lines = lines[pdfLinesPage:]
+	}
+	pages = append(pages, lines)
+
+	doc := &pdfDocument{}
+	// object numbers: 1 catalog, 2 page tree, 3 font, then a page and a content stream per page
+	pageObj := func(i int) int { return 4 + 2*i }
+
+	doc.object("<< /Type /Catalog /Pages 2 0 R >>")
+
+	kids := make([]string, len(pages))
+	for i := range pages {
+		kids[i] = fmt.Sprintf("%d 0 R", pageObj(i))
+	}
+	doc.object(fmt.Sprintf("<< /Type /Pages /Kids [%s] /Count %d >>", strings.Join(kids, " "), len(pages)))
+	doc.object("<< /Type /Font /Subtype /Type1 /BaseFont /Courier /Encoding /WinAnsiEncoding >>")
+
+	for i, page := range pages {
+		doc.object(fmt.Sprintf(
+			"<< /Type /Page /Parent 2 0 R /MediaBox [0 0 %d %d] /Resources << /Font << /F1 3 0 R >> >> /Contents %d 0 R >>",
+			pdfPageWidth, pdfPageHeight, pageObj(i)+1,
+		))
+
+		var content bytes.Buffer
+		fmt.Fprintf(&content, "BT\n/F1 %d Tf\n%d TL\n%d %d Td\n", pdfFontSize, pdfLeading, pdfMargin, pdfPageHeight-pdfMargin)
+		for _, line := range page {
+			fmt.Fprintf(&content, "(%s) Tj T*\n", pdfEscape(line))
+		}
+		fmt.Fprintf(&content, "(Page %d of %d) Tj\nET", i+1, len(pages))
+		doc.stream(content.Bytes())
+	}
+
+	_, err := w.Write(doc.bytes())
+	return err
+}
+
+func pdfLines(st Statement) []string {
+	const row = "%-19s %-12s %-24s %-18s %12s %12s"
+
+	lines := []string{
+		"ACCOUNT STATEMENT",
+		"",
+		fmt.Sprintf("Account:   %d (%s)", st.AccountID, st.Currency),
+		fmt.Sprintf("Holder:    %s", st.Owner),
+		fmt.Sprintf("Period:    %s to %s", st.From.UTC().Format("2006-01-02 15:04"), st.To.UTC().Format("2006-01-02 15:04")),
+		fmt.Sprintf("Generated: %s", st.GeneratedAt.UTC().Format("2006-01-02 15:04:05 MST")),
+		"",
+		fmt.Sprintf(row, "Date", "Description", "Counterparty", "Memo", "Amount", "Balance"),
+		strings.Repeat("-", 102),
+		fmt.Sprintf(row, st.From.UTC().Format("2006-01-02 15:04:05"), "Opening", "", "", "", fmt.Sprint(st.OpeningBalance)),
+	}
+	for _, l := range st.Lines {
+		lines = append(lines, fmt.Sprintf(row,
+			l.Date.UTC().Format("2006-01-02 15:04:05"),
+			truncate(l.description(), 12),
+			truncate(l.counterparty(), 24),
+			truncate(l.Memo, 18),
+			fmt.Sprint(l.Amount),
+			fmt.Sprint(l.BalanceAfter),
+		))
+	}
+	lines = append(lines, fmt.Sprintf(row, st.To.UTC().Format("2006-01-02 15:04:05"), "Closing", "", "", "", fmt.Sprint(st.ClosingBalance)))
+	return lines
+}
+
+func truncate(s string, n int) string {
+	if len(s) <= n {
+		return s
+	}
+	return s[:n-1] + "~"
+}
+
+// pdfEscape makes s safe inside a PDF literal string. Anything outside printable ASCII becomes '?'.
+func pdfEscape(s string) string {
+	var b strings.Builder
+	for _, r := range s {
+		switch {
+		case r == '(' || r == ')' || r == '\\':
+			b.WriteByte('\\')
+			b.WriteRune(r)
+		case r < 32 || r > 126:
+			b.WriteByte('?')
+		default:
+			b.WriteRune(r)
+		}
+	}
+	return b.String()
+}
+
+// pdfDocument assembles numbered objects and the cross-reference table that points at them.
+type pdfDocument struct {
+	buf     bytes.Buffer
+	offsets []int
+}
+
+func (d *pdfDocument) begin() {
+	if d.buf.Len() == 0 {
+		d.buf.WriteString("%PDF-1.4\n")
+	}
+	d.offsets = append(d.offsets, d.buf.Len())
+	fmt.Fprintf(&d.buf, "%d 0 obj\n", len(d.offsets))
+}
+
+func (d *pdfDocument) object(body string) {
+	d.begin()
+	fmt.Fprintf(&d.buf, "%s\nendobj\n", body)
+}
+
+func (d *pdfDocument) stream(data []byte) {
+	d.begin()
+	fmt.Fprintf(&d.buf, "<< /Length %d >>\nstream\n", len(data))
+	d.buf.Write(data)
+	d.buf.WriteString("\nendstream\nendobj\n")
+}
+
+func (d *pdfDocument) bytes() []byte {
+	xref := d.buf.Len()
+	fmt.Fprintf(&d.buf, "xref\n0 %d\n0000000000 65535 f \n", len(d.offsets)+1)
+	for _, off := range d.offsets {
+		fmt.Fprintf(&d.buf, "%010d 00000 n \n", off)
+	}
+	fmt.Fprintf(&d.buf, "trailer\n<< /Size %d /Root 1 0 R >>\nstartxref\n%d\n%%%%EOF\n", len(d.offsets)+1, xref)
+	return d.buf.Bytes()
+}
@@ -0,0 +1,110 @@
+package statement
+
+import (
+	"fmt"
+	"io"
+	"time"
+)
+
+type Format string
+
+const (
+	FormatCSV     Format = "csv"
+	FormatJSON    Format = "json"
+	FormatPDF     Format = "pdf"
+	FormatOFX     Format = "ofx"
+	FormatCAMT053 Format = "camt053"
+)
+
+// bankID identifies us as the servicing institution in exported statements.
+const bankID = "GOBANK"
+
+// Statement is an account's activity over [From, To) bracketed by its opening and closing balance.
+type Statement struct {
+	AccountID      int64     `json:"account_id"`
+	Owner          string    `json:"owner"`
+	Currency       string    `json:"currency"`
+	From           time.Time `json:"from"`
+	To             time.Time `json:"to"`
+	OpeningBalance int64     `json:"opening_balance"`
+	ClosingBalance int64     `json:"closing_balance"`
+	Lines          []Line    `json:"lines"`
+	GeneratedAt    time.Time `json:"generated_at"`
+}
+
+// Line is a single entry on the statement.
+type Line struct {
+	EntryID               int64     `json:"entry_id"`
+	Date                  time.Time `json:"date"`
+	Amount                int64     `json:"amount"`
+	BalanceAfter          int64     `json:"balance_after"`
+	TransferID            int64     `json:"transfer_id,omitempty"`
+	CounterpartyAccountID int64     `json:"counterparty_account_id,omitempty"`
+	CounterpartyOwner     string    `json:"counterparty_owner,omitempty"`
+	Memo                  string    `json:"memo,omitempty"`
+}
+
+// ContentType is the MIME type a statement rendered in f is served with.
+func (f Format) ContentType() string {
+	switch f {
+	case FormatCSV:
+		return "text/csv"
+	case FormatJSON:
+		return "application/json"
+	case FormatPDF:
+		return "application/pdf"
+	case FormatOFX:
+		return "application/x-ofx"
+	case FormatCAMT053:
+		return "application/xml"
+	}
+	return "application/octet-stream"
+}
+
+// Extension is the file extension for statements rendered in f.
+func (f Format) Extension() string {
+	if f == FormatCAMT053 {
+		return "xml"
+	}
+	return string(f)
+}
+
+// Write renders st in the requested format.
+func Write(w io.Writer, f Format, st Statement) error {
+	switch f {
+	case FormatCSV:
+		return WriteCSV(w, st)
+	case FormatJSON:
+		return WriteJSON(w, st)
+	case FormatPDF:
+		return WritePDF(w, st)
+	case FormatOFX:
+		return WriteOFX(w, st)
+	case FormatCAMT053:
+		return WriteCAMT053(w, st)
+	}
+	return fmt.Errorf("unsupported statement format: %s", f)
+}
+
+// Filename is the suggested download name for st rendered in f.
+func Filename(f Format, st Statement) string {
+	return fmt.Sprintf("statement-%d-%s-%s.%s", st.AccountID, st.From.Format("20060102"), st.To.Format("20060102"), f.Extension())
+}
+
+// counterparty describes who was on the other side of a line.
+func (l Line) counterparty() string {
+	if l.CounterpartyAccountID == 0 {
+		return ""
+	}
+	if l.CounterpartyOwner == "" {
+		return fmt.Sprintf("account %d", l.CounterpartyAccountID)
+	}
+	return fmt.Sprintf("%s (account %d)", l.CounterpartyOwner, l.CounterpartyAccountID)
+}
+
+func (l Line) description() string {
+	if l.TransferID == 0 {
+		return fmt.Sprintf("Entry %d", l.EntryID)
+	}
+	return fmt.Sprintf("Transfer %d", l.TransferID)
+}
@@ -0,0 +1,139 @@
+package statement
+
+import (
+	"bytes"
+	"encoding/csv"
+	"encoding/json"
+	"fmt"
+	"regexp"
+	"strconv"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+)
+
+func sampleStatement(n int) Statement {
+	from := time.Date(2021, time.March, 1, 0, 0, 0, 0, time.UTC)
+	st := Statement{
+		AccountID:      42,
+		Owner:          "alice",
+		Currency:       "USD",
+		From:           from,
+		To:             from.AddDate(0, 1, 0),
+		OpeningBalance: 100,
+		GeneratedAt:    time.Date(2021, time.April, 1, 8, 0, 0, 0, time.UTC),
+	}
+	balance := st.OpeningBalance
+	for i := 0; i < n; i++ {
+		amount := int64(10)
+		if i%2 == 1 {
+			amount = -5
+		}
+		balance += amount
+		st.Lines = append(st.Lines, Line{
+			EntryID:               int64(i + 1),
+			Date:                  from.Add(time.Duration(i) * time.Hour),
+			Amount:                amount,
+			BalanceAfter:          balance,
+			TransferID:            int64(100 + i),
+			CounterpartyAccountID: 7,
+			CounterpartyOwner:     "bob",
+			Memo:                  fmt.Sprintf("rent (part %d)", i),
+		})
+	}
+	st.ClosingBalance = balance
+	return st
+}
+
+func TestWriteCSV(t *testing.T) {
+	st := sampleStatement(2)
+
+	var buf bytes.Buffer
+	require.NoError(t, Write(&buf, FormatCSV, st))
+
+	records, err := csv.NewReader(&buf).ReadAll()
+	require.NoError(t, err)
+	require.Len(t, records, 5)
+
+	require.Equal(t, []string{"date", "description", "counterparty", "memo", "amount", "balance"}, records[0])
+	require.Equal(t, "Opening balance", records[1][1])
+	require.Equal(t, "100", records[1][5])
+	require.Equal(t, []string{"2021-03-01T00:00:00Z", "Transfer 100", "bob (account 7)", "rent (part 0)", "10", "110"}, records[2])
+	require.Equal(t, "Closing balance", records[4][1])
+	require.Equal(t, "105", records[4][5])
+
+	// each row's balance is the one above it plus its amount
+	for i := 2; i < len(records)-1; i++ {
+		prev, err := strconv.ParseInt(records[i-1][5], 10, 64)
+		require.NoError(t, err)
+		amount, err := strconv.ParseInt(records[i][4], 10, 64)
+		require.NoError(t, err)
+		require.Equal(t, records[i][5], strconv.FormatInt(prev+amount, 10))
+	}
+}
+
+func TestWriteCSVEscaping(t *testing.T) {
+	st := sampleStatement(1)
+	st.Lines[0].CounterpartyOwner = "bob, jr"
+	st.Lines[0].Memo = "say \"hi\"\nthen pay"
+
+	var buf bytes.Buffer
+	require.NoError(t, Write(&buf, FormatCSV, st))
+	require.Contains(t, buf.String(), "\n2021-03-01T00:00:00Z,Transfer 100,\"bob, jr (account 7)\",\"say \"\"hi\"\"\nthen pay\",10,110\n")
+
+	records, err := csv.NewReader(&buf).ReadAll()
+	require.NoError(t, err)
+	require.Len(t, records, 4)
+	require.Equal(t, "bob, jr (account 7)", records[2][2])
+	require.Equal(t, st.Lines[0].Memo, records[2][3])
+}
+
+func TestWriteJSON(t *testing.T) {
+	st := sampleStatement(0)
+	st.Lines = nil
+
+	var buf bytes.Buffer
+	require.NoError(t, Write(&buf, FormatJSON, st))
+
+	var got map[string]interface{}
+	require.NoError(t, json.Unmarshal(buf.Bytes(), &got))
+	require.Equal(t, []interface{}{}, got["lines"])
+	require.EqualValues(t, 100, got["opening_balance"])
+	require.EqualValues(t, 100, got["closing_balance"])
+}
+
+func TestWritePDF(t *testing.T) {
+	st := sampleStatement(150)
+
+	var buf bytes.Buffer
+	require.NoError(t, Write(&buf, FormatPDF, st))
+	doc := buf.String()
+
+	require.True(t, strings.HasPrefix(doc, "%PDF-1.4\n"))
+	require.True(t, strings.HasSuffix(doc, "%%EOF\n"))
+	require.Contains(t, doc, "/Count 3")
+	require.Contains(t, doc, `rent \(part 0\)`)
+	require.Contains(t, doc, "(Page 3 of 3) Tj")
+
+	// every xref entry must point at the object it claims to
+	startxref := regexp.MustCompile(`startxref\n(\d+)\n`).FindStringSubmatch(doc)
+	require.Len(t, startxref, 2)
+	xrefAt, err := strconv.Atoi(startxref[1])
+	require.NoError(t, err)
+	require.True(t, strings.HasPrefix(doc[xrefAt:], "xref\n"))
+
+	offsets := regexp.MustCompile(`(\d{10}) 00000 n `).FindAllStringSubmatch(doc[xrefAt:], -1)
+	require.Len(t, offsets, 3+2*3)
+	for i, m := range offsets {
+		off, err := strconv.Atoi(m[1])
+		require.NoError(t, err)
+		require.True(t, strings.HasPrefix(doc[off:], fmt.Sprintf("%d 0 obj\n", i+1)))
+	}
+}
+
+func TestWriteUnsupportedFormat(t *testing.T) {
+	var buf bytes.Buffer
+	require.Error(t, Write(&buf, Format("xls"), sampleStatement(1)))
+}
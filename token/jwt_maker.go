@@ -21,8 +21,8 @@ func NewJWTMaker(secretkey string) (Maker, error) {
 	return &JWTMaker{secretkey: secretkey}, nil
 }
 
-func (m *JWTMaker) CreateToken(username string, duration time.Duration) (string, error) {
-	claims, err := NewPayload(username, duration)
+func (m *JWTMaker) CreateToken(username string, duration time.Duration, opts ...Option) (string, error) {
+	claims, err := NewPayload(username, duration, opts...)
 	if err != nil {
 		return "", err
 	}
@@ -63,3 +63,44 @@ func TestInvalidJWTTokenAlgNone(t *testing.T) {
 	require.EqualError(t, err, ErrInvalidToken.Error())
 	require.Nil(t, payload)
 }
+
+func TestJWTTokenPurpose(t *testing.T) {
+	maker, err := NewJWTMaker(util.RandomString(32))
+	require.NoError(t, err)
+
+	token, err := maker.CreateToken(util.RandomOwner(), time.Minute, WithPurpose(PurposeMFAChallenge))
+	require.NoError(t, err)
+
+	payload, err := maker.VerifyToken(token)
+	require.NoError(t, err)
+	require.Equal(t, PurposeMFAChallenge, payload.Purpose)
+}
+
+func TestJWTTokenAuthentication(t *testing.T) {
+	maker, err := NewJWTMaker(util.RandomString(32))
+	require.NoError(t, err)
+
+	authTime := time.Now().Add(-time.Minute)
+	token, err := maker.CreateToken(util.RandomOwner(), time.Minute, WithAuthentication(authTime, AMRPassword, AMROTP))
+	require.NoError(t, err)
+
+	payload, err := maker.VerifyToken(token)
+	require.NoError(t, err)
+	require.WithinDuration(t, authTime, payload.AuthTime, time.Millisecond)
+	require.Equal(t, []string{AMRPassword, AMROTP}, payload.AMR)
+	require.Empty(t, payload.Purpose)
+}
+
+func TestJWTTokenClient(t *testing.T) {
+	maker, err := NewJWTMaker(util.RandomString(32))
+	require.NoError(t, err)
+
+	token, err := maker.CreateToken(util.RandomOwner(), time.Minute, WithClient("app", []string{ScopeAccountsRead}))
+	require.NoError(t, err)
+
+	payload, err := maker.VerifyToken(token)
+	require.NoError(t, err)
+	require.Equal(t, PurposeOAuth, payload.Purpose)
+	require.Equal(t, "app", payload.ClientID)
+	require.Equal(t, []string{ScopeAccountsRead}, payload.Scopes)
+}
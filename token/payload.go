@@ -12,6 +12,65 @@ type Payload struct {
 	Username  string    `json:"username"`
 	IssuedAt  time.Time `json:"issued_at"`
 	ExpiredAt time.Time `json:"expired_at"`
+	// empty for access tokens; anything else limits what the token may be used for
+	Purpose string `json:"purpose,omitempty"`
+	// when and how the user last proved who they are, which can be older than the token itself
+	AuthTime time.Time `json:"auth_time"`
+	AMR      []string  `json:"amr,omitempty"`
+	// the app an OAuth token was issued to and what it may do
+	ClientID string   `json:"client_id,omitempty"`
+	Scopes   []string `json:"scope,omitempty"`
+	// an account consent further limiting an OAuth token to some accounts and permissions
+	ConsentID int64 `json:"consent_id,omitempty"`
+}
+
+// Authentication method references, from RFC 8176 where one fits.
+const (
+	AMRPassword     = "pwd"
+	AMROTP          = "otp"
+	AMRRecoveryCode = "recovery_code"
+)
+
+// PurposeMFAChallenge marks the token handed out after the password step of a login that still needs a
+// second factor. It can only be exchanged for an access token.
+const PurposeMFAChallenge = "mfa_challenge"
+
+// PurposeOAuth marks the access tokens of third-party apps, which are only accepted with the OAuth
+// authorization type and only for their scopes.
+const PurposeOAuth = "oauth"
+
+// Option customises the payload of a new token.
+type Option func(*Payload)
+
+// WithAuthentication records that the user authenticated at authTime using methods.
+func WithAuthentication(authTime time.Time, methods ...string) Option {
+	return func(p *Payload) {
+		p.AuthTime = authTime
+		p.AMR = methods
+	}
+}
+
+// WithClient makes an OAuth token for the app clientID, limited to scopes.
+func WithClient(clientID string, scopes []string) Option {
+	return func(p *Payload) {
+		p.Purpose = PurposeOAuth
+		p.ClientID = clientID
+		p.Scopes = scopes
+	}
+}
+
+// WithConsent binds an OAuth token to the account consent consentID.
+func WithConsent(consentID int64) Option {
+	return func(p *Payload) {
+		p.ConsentID = consentID
+	}
+}
+
+// WithPurpose restricts the token to purpose.
+func WithPurpose(purpose string) Option {
+	return func(p *Payload) {
+		p.Purpose = purpose
+	}
 }
 
 var (
@@ -19,7 +78,7 @@ var (
 	ErrExpiredToken = errors.New("token has expired!")
 )
 
-func NewPayload(username string, duration time.Duration) (*Payload, error) {
+func NewPayload(username string, duration time.Duration, opts ...Option) (*Payload, error) {
 	tokenID, err := uuid.NewRandom()
 	if err != nil {
 		return nil, err
@@ -31,6 +90,9 @@ func NewPayload(username string, duration time.Duration) (*Payload, error) {
 		IssuedAt:  time.Now(),
 		ExpiredAt: time.Now().Add(duration),
 	}
+	for _, opt := range opts {
+		opt(payload)
+	}
 	return payload, nil
 }
 
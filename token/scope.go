@@ -0,0 +1,28 @@
+package token
+
+// Scopes limit what a delegated credential, such as an API key or OAuth token, may do on behalf of its user.
+// A user's own session is not limited by scopes.
+const (
+	ScopeAccountsRead   = "accounts:read"
+	ScopeAccountsWrite  = "accounts:write"
+	ScopeTransfersWrite = "transfers:write"
+	ScopeWebhooks       = "webhooks"
+)
+
+func IsSupportedScope(scope string) bool {
+	switch scope {
+	case ScopeAccountsRead, ScopeAccountsWrite, ScopeTransfersWrite, ScopeWebhooks:
+		return true
+	}
+	return false
+}
+
+// HasScope reports whether scopes grant scope.
+func HasScope(scopes []string, scope string) bool {
+	for _, s := range scopes {
+		if s == scope {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,82 @@
+package totp
+
+import (
+	"encoding/base32"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+)
+
+// SHA1 vectors from RFC 6238 appendix B, cut to 6 digits.
+func TestCodeRFC6238(t *testing.T) {
+	secret := base32.StdEncoding.EncodeToString([]byte("12345678901234567890"))
+
+	testCases := []struct {
+		unix int64
+		code string
+	}{
+		{59, "287082"},
+		{1111111109, "081804"},
+		{1111111111, "050471"},
+		{1234567890, "005924"},
+		{2000000000, "279037"},
+		{20000000000, "353130"},
+	}
+
+	for _, tc := range testCases {
+		code, err := Code(secret, Step(time.Unix(tc.unix, 0)))
+		require.NoError(t, err)
+		require.Equal(t, tc.code, code, "at %d", tc.unix)
+	}
+}
+
+func TestValidate(t *testing.T) {
+	secret, err := NewSecret()
+	require.NoError(t, err)
+
+	now := time.Now()
+	code, err := Code(secret, Step(now))
+	require.NoError(t, err)
+
+	step, ok := Validate(secret, code, now)
+	require.True(t, ok)
+	require.Equal(t, Step(now), step)
+
+	// one step of drift either way is tolerated, more is not
+	_, ok = Validate(secret, code, now.Add(Period))
+	require.True(t, ok)
+	_, ok = Validate(secret, code, now.Add(-3*Period))
+	require.False(t, ok)
+
+	_, ok = Validate(secret, "12345", now)
+	require.False(t, ok)
+	_, ok = Validate("not base32!", "123456", now)
+	require.False(t, ok)
+}
+
+func TestURI(t *testing.T) {
+	uri := URI("gobank", "alice", "JBSWY3DPEHPK3PXP")
+	require.True(t, strings.HasPrefix(uri, "otpauth://totp/gobank:alice?"))
+	require.Contains(t, uri, "secret=JBSWY3DPEHPK3PXP")
+	require.Contains(t, uri, "issuer=gobank")
+	require.Contains(t, uri, "digits=6")
+	require.Contains(t, uri, "period=30")
+}
+
+func TestRecoveryCodes(t *testing.T) {
+	codes, err := NewRecoveryCodes(10)
+	require.NoError(t, err)
+	require.Len(t, codes, 10)
+
+	seen := map[string]bool{}
+	for _, c := range codes {
+		require.Len(t, c, 11)
+		require.Equal(t, byte('-'), c[5])
+		require.False(t, seen[c])
+		seen[c] = true
+	}
+
+	require.Equal(t, "k7m2px9q4r", NormalizeRecoveryCode(" K7M2P-X9Q4R"))
+}
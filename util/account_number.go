@@ -0,0 +1,83 @@
+package util
+
+import (
+	"crypto/rand"
+	"fmt"
+	"math/big"
+	"strings"
+)
+
+// Account numbers follow the IBAN layout: a country code, two mod-97 check digits, a bank code and the
+// account digits, e.g. GO45GBNK0123456789. The account digits are random, not derived from the account ID.
+const (
+	accountNumberCountry = "GO"
+	accountNumberBank    = "GBNK"
+	accountNumberDigits  = 10
+	AccountNumberLength  = len(accountNumberCountry) + 2 + len(accountNumberBank) + accountNumberDigits
+)
+
+// NewAccountNumber returns an account number with valid check digits whose account digits come from
+// crypto/rand, so the numbers of other accounts cannot be guessed from one's own.
+func NewAccountNumber() (string, error) {
+	n, err := rand.Int(rand.Reader, big.NewInt(10_000_000_000))
+	if err != nil {
+		return "", err
+	}
+	return accountNumber(n.Int64()), nil
+}
+
+// accountNumber lays out the account digits n with the country, check digits and bank code.
+func accountNumber(n int64) string {
+	digits := fmt.Sprintf("%0*d", accountNumberDigits, n)
+	bban := accountNumberBank + digits
+	check := 98 - mod97(bban+accountNumberCountry+"00")
+	return fmt.Sprintf("%s%02d%s", accountNumberCountry, check, bban)
+}
+
+// NormalizeAccountNumber drops the spaces and lowercase of an account number as people write it,
+// e.g. "go45 gbnk 0123 4567 89", leaving the form it is stored in.
+func NormalizeAccountNumber(number string) string {
+	return strings.ToUpper(strings.Join(strings.Fields(number), ""))
+}
+
+// IsValidAccountNumber reports whether number, once normalized, is laid out like the numbers NewAccountNumber
+// makes and its check digits match, so mistyped numbers are caught without a database lookup.
+func IsValidAccountNumber(number string) bool {
+	number = NormalizeAccountNumber(number)
+	if len(number) != AccountNumberLength {
+		return false
+	}
+	bankStart := len(accountNumberCountry) + 2
+	digitsStart := bankStart + len(accountNumberBank)
+	if number[:len(accountNumberCountry)] != accountNumberCountry || number[bankStart:digitsStart] != accountNumberBank {
+		return false
+	}
+	if !isDigits(number[len(accountNumberCountry):bankStart]) || !isDigits(number[digitsStart:]) {
+		return false
+	}
+	return mod97(number[bankStart:]+number[:bankStart]) == 1
+}
+
+// mod97 computes the ISO 7064 remainder of s, letters counting as two digits (A=10 ... Z=35), a digit at a time
+// since the whole would overflow an int64.
+func mod97(s string) int {
+	mod := 0
+	for _, r := range s {
+		switch {
+		case r >= '0' && r <= '9':
+			mod = (mod*10 + int(r-'0')) % 97
+		case r >= 'A' && r <= 'Z':
+			mod = (mod*100 + int(r-'A') + 10) % 97
+		}
+	}
+	return mod
+}
+
+func isDigits(s string) bool {
+	for _, r := range s {
+		if r < '0' || r > '9' {
+			return false
+		}
+	}
+	return true
+}
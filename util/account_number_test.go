@@ -0,0 +1,60 @@
+package util
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestMod97(t *testing.T) {
+	// a published example IBAN, rearranged with its country code and check digits moved to the end
+	require.Equal(t, 1, mod97("WEST12345698765432"+"GB82"))
+}
+
+func TestNewAccountNumber(t *testing.T) {
+	for i := 0; i < 100; i++ {
+		number, err := NewAccountNumber()
+		require.NoError(t, err)
+		require.Len(t, number, AccountNumberLength)
+		require.True(t, IsValidAccountNumber(number), number)
+	}
+}
+
+func TestIsValidAccountNumber(t *testing.T) {
+	number := RandomAccountNumber()
+
+	// a single mistyped digit always changes the remainder
+	for i := 2; i < len(number); i++ {
+		if number[i] < '0' || number[i] > '9' {
+			continue
+		}
+		typo := []byte(number)
+		typo[i] = '0' + (typo[i]-'0'+1)%10
+		require.False(t, IsValidAccountNumber(string(typo)), string(typo))
+	}
+
+	// and so does swapping two neighbouring digits that differ
+	last := len(number) - 1
+	if number[last] != number[last-1] {
+		swapped := []byte(number)
+		swapped[last], swapped[last-1] = swapped[last-1], swapped[last]
+		require.False(t, IsValidAccountNumber(string(swapped)))
+	}
+
+	require.False(t, IsValidAccountNumber(""))
+	require.False(t, IsValidAccountNumber("   "))
+	require.False(t, IsValidAccountNumber("GB82WEST12345698765432"))
+	require.False(t, IsValidAccountNumber(number[:len(number)-1]))
+	require.False(t, IsValidAccountNumber("XX"+number[2:]))
+	require.False(t, IsValidAccountNumber(number[:8]+"12345x7890"))
+}
+
+func TestNormalizeAccountNumber(t *testing.T) {
+	number := RandomAccountNumber()
+	written := strings.ToLower(number[:4] + " " + number[4:8] + "  " + number[8:12] + "\t" + number[12:])
+
+	require.Equal(t, number, NormalizeAccountNumber(written))
+	require.True(t, IsValidAccountNumber(written))
+	require.Equal(t, number, NormalizeAccountNumber(number))
+}
@@ -0,0 +1,130 @@
+package util
+
+import (
+	"crypto/rand"
+	"crypto/subtle"
+	"encoding/base64"
+	"fmt"
+	"strings"
+
+	"golang.org/x/crypto/argon2"
+)
+
+// Argon2Params are the argon2id cost parameters. Memory is in KiB.
+type Argon2Params struct {
+	Memory      uint32
+	Iterations  uint32
+	Parallelism uint8
+}
+
+// DefaultArgon2Params follow the OWASP password storage recommendation.
+var DefaultArgon2Params = Argon2Params{
+	Memory:      19 * 1024,
+	Iterations:  2,
+	Parallelism: 1,
+}
+
+const (
+	argon2SaltLength = 16
+	argon2KeyLength  = 32
+)
+
+type Argon2idHasher struct {
+	params Argon2Params
+}
+
+// NewArgon2idHasher returns a hasher using params, with zero fields taken from DefaultArgon2Params.
+func NewArgon2idHasher(params Argon2Params) (PasswordHasher, error) {
+	if params.Memory == 0 {
+		params.Memory = DefaultArgon2Params.Memory
+	}
+	if params.Iterations == 0 {
+		params.Iterations = DefaultArgon2Params.Iterations
+	}
+	if params.Parallelism == 0 {
+		params.Parallelism = DefaultArgon2Params.Parallelism
+	}
+	if params.Memory < 8*uint32(params.Parallelism) {
+		return nil, fmt.Errorf("invalid argon2 memory: must be at least %d KiB", 8*uint32(params.Parallelism))
+	}
+	return &Argon2idHasher{params: params}, nil
+}
+
+// Hash returns pwd hashed in the PHC string format: $argon2id$v=19$m=...,t=...,p=...$salt$hash
+func (h *Argon2idHasher) Hash(pwd string) (string, error) {
+	salt := make([]byte, argon2SaltLength)
+	if _, err := rand.Read(salt); err != nil {
+		return "", fmt.Errorf("failed to hash password: %w", err)
+	}
+	key := argon2.IDKey([]byte(pwd), salt, h.params.Iterations, h.params.Memory, h.params.Parallelism, argon2KeyLength)
+
+	return fmt.Sprintf("$argon2id$v=%d$m=%d,t=%d,p=%d$%s$%s", argon2.Version,
+		h.params.Memory, h.params.Iterations, h.params.Parallelism,
+		base64.RawStdEncoding.EncodeToString(salt), base64.RawStdEncoding.EncodeToString(key)), nil
+}
+
+func (h *Argon2idHasher) Check(pwd string, hashedPwd string) error {
+	return CheckPassword(pwd, hashedPwd)
+}
+
+// NeedsRehash reports whether hashedPwd is not argon2id or is weaker than h in any parameter. A hash that
+// is stronger is kept, so lowering the configured cost never downgrades stored hashes.
+func (h *Argon2idHasher) NeedsRehash(hashedPwd string) bool {
+	params, salt, key, err := decodeArgon2id(hashedPwd)
+	if err != nil {
+		return true
+	}
+	return params.Memory < h.params.Memory ||
+		params.Iterations < h.params.Iterations ||
+		params.Parallelism < h.params.Parallelism ||
+		len(salt) < argon2SaltLength ||
+		len(key) < argon2KeyLength
+}
+
+func checkArgon2id(pwd string, hashedPwd string) error {
+	params, salt, key, err := decodeArgon2id(hashedPwd)
+	if err != nil {
+		return err
+	}
+	other := argon2.IDKey([]byte(pwd), salt, params.Iterations, params.Memory, params.Parallelism, uint32(len(key)))
+	if subtle.ConstantTimeCompare(key, other) != 1 {
+		return ErrMismatchedPassword
+	}
+	return nil
+}
+
+func decodeArgon2id(hashedPwd string) (params Argon2Params, salt, key []byte, err error) {
+	parts := strings.Split(hashedPwd, "$")
+	if len(parts) != 6 || parts[1] != Argon2id {
+		err = ErrUnknownHashFormat
+		return
+	}
+
+	var version int
+	if _, err = fmt.Sscanf(parts[2], "v=%d", &version); err != nil {
+		err = fmt.Errorf("invalid argon2id hash: %w", err)
+		return
+	}
+	if version != argon2.Version {
+		err = fmt.Errorf("unsupported argon2 version %d", version)
+		return
+	}
+	_, err = fmt.Sscanf(parts[3], "m=%d,t=%d,p=%d", &params.Memory, &params.Iterations, &params.Parallelism)
+	if err != nil {
+		err = fmt.Errorf("invalid argon2id hash: %w", err)
+		return
+	}
+
+	if salt, err = base64.RawStdEncoding.DecodeString(parts[4]); err != nil {
+		err = fmt.Errorf("invalid argon2id salt: %w", err)
+		return
+	}
+	if key, err = base64.RawStdEncoding.DecodeString(parts[5]); err != nil {
+		err = fmt.Errorf("invalid argon2id hash: %w", err)
+		return
+	}
+	if len(key) == 0 || params.Iterations == 0 || params.Parallelism == 0 {
+		err = fmt.Errorf("invalid argon2id hash: bad parameters")
+	}
+	return
+}
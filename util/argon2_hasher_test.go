@@ -0,0 +1,92 @@
+package util
+
+import (
+	"fmt"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+// testArgon2Params are cheap so the tests stay fast.
+var testArgon2Params = Argon2Params{Memory: 64, Iterations: 1, Parallelism: 1}
+
+func TestArgon2idHasher(t *testing.T) {
+	h, err := NewArgon2idHasher(testArgon2Params)
+	require.NoError(t, err)
+
+	pwd := RandomString(6)
+	hashedPwd, err := h.Hash(pwd)
+	require.NoError(t, err)
+	require.True(t, strings.HasPrefix(hashedPwd, "$argon2id$v=19$m=64,t=1,p=1$"))
+	require.Len(t, strings.Split(hashedPwd, "$"), 6)
+
+	require.NoError(t, h.Check(pwd, hashedPwd))
+	require.ErrorIs(t, h.Check(RandomString(6), hashedPwd), ErrMismatchedPassword)
+	require.False(t, h.NeedsRehash(hashedPwd))
+
+	other, err := h.Hash(pwd)
+	require.NoError(t, err)
+	require.NotEqual(t, hashedPwd, other)
+}
+
+func TestArgon2idHasherNeedsRehash(t *testing.T) {
+	h, err := NewArgon2idHasher(testArgon2Params)
+	require.NoError(t, err)
+
+	weaker, err := NewArgon2idHasher(Argon2Params{Memory: 32, Iterations: 1, Parallelism: 1})
+	require.NoError(t, err)
+	hashedPwd, err := weaker.Hash("secret")
+	require.NoError(t, err)
+	require.True(t, h.NeedsRehash(hashedPwd))
+	// the old parameters still check
+	require.NoError(t, h.Check("secret", hashedPwd))
+
+	b, err := NewBcryptHasher(4)
+	require.NoError(t, err)
+	hashedPwd, err = b.Hash("secret")
+	require.NoError(t, err)
+	require.True(t, h.NeedsRehash(hashedPwd))
+
+	require.True(t, h.NeedsRehash("garbage"))
+}
+
+func TestArgon2idHasherDoesNotDowngrade(t *testing.T) {
+	stronger, err := NewArgon2idHasher(Argon2Params{Memory: 128, Iterations: 2, Parallelism: 2})
+	require.NoError(t, err)
+	hashedPwd, err := stronger.Hash("secret")
+	require.NoError(t, err)
+
+	lower := []Argon2Params{
+		{Memory: 64, Iterations: 2, Parallelism: 2},
+		{Memory: 128, Iterations: 1, Parallelism: 2},
+		{Memory: 128, Iterations: 2, Parallelism: 1},
+	}
+	for _, params := range lower {
+		h, err := NewArgon2idHasher(params)
+		require.NoError(t, err)
+		require.False(t, h.NeedsRehash(hashedPwd))
+	}
+
+	// weaker in one parameter is weaker, whatever the others
+	h, err := NewArgon2idHasher(Argon2Params{Memory: 64, Iterations: 3, Parallelism: 2})
+	require.NoError(t, err)
+	require.True(t, h.NeedsRehash(hashedPwd))
+}
+
+func TestNewArgon2idHasherDefaults(t *testing.T) {
+	h, err := NewArgon2idHasher(Argon2Params{})
+	require.NoError(t, err)
+
+	hashedPwd, err := HashPassword("secret")
+	require.NoError(t, err)
+	require.False(t, h.NeedsRehash(hashedPwd))
+	require.Contains(t, hashedPwd, fmt.Sprintf("$m=%d,t=%d,p=%d$",
+		DefaultArgon2Params.Memory, DefaultArgon2Params.Iterations, DefaultArgon2Params.Parallelism))
+}
+
+func TestNewArgon2idHasherInvalidMemory(t *testing.T) {
+	h, err := NewArgon2idHasher(Argon2Params{Memory: 8, Iterations: 1, Parallelism: 4})
+	require.Error(t, err)
+	require.Nil(t, h)
+}
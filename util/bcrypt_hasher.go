@@ -0,0 +1,44 @@
+package util
+
+import (
+	"fmt"
+
+	"golang.org/x/crypto/bcrypt"
+)
+
+type BcryptHasher struct {
+	cost int
+}
+
+// NewBcryptHasher returns a hasher using cost, or bcrypt.DefaultCost if it is zero.
+func NewBcryptHasher(cost int) (PasswordHasher, error) {
+	if cost == 0 {
+		cost = bcrypt.DefaultCost
+	}
+	if cost < bcrypt.MinCost || cost > bcrypt.MaxCost {
+		return nil, fmt.Errorf("invalid bcrypt cost: must be between %d and %d", bcrypt.MinCost, bcrypt.MaxCost)
+	}
+	return &BcryptHasher{cost: cost}, nil
+}
+
+func (h *BcryptHasher) Hash(pwd string) (string, error) {
+	hashedPwd, err := bcrypt.GenerateFromPassword([]byte(pwd), h.cost)
+	if err != nil {
+		return "", fmt.Errorf("failed to hash password: %w", err)
+	}
+	return string(hashedPwd), nil
+}
+
+func (h *BcryptHasher) Check(pwd string, hashedPwd string) error {
+	return CheckPassword(pwd, hashedPwd)
+}
+
+// NeedsRehash reports whether hashedPwd is not bcrypt or has a lower cost than h. A hash of higher cost is
+// kept, so lowering the configured cost never downgrades stored hashes.
+func (h *BcryptHasher) NeedsRehash(hashedPwd string) bool {
+	if hashAlgorithm(hashedPwd) != Bcrypt {
+		return true
+	}
+	cost, err := bcrypt.Cost([]byte(hashedPwd))
+	return err != nil || cost < h.cost
+}
@@ -0,0 +1,63 @@
+package util
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+	"golang.org/x/crypto/bcrypt"
+)
+
+func TestBcryptHasher(t *testing.T) {
+	h, err := NewBcryptHasher(bcrypt.MinCost)
+	require.NoError(t, err)
+
+	pwd := RandomString(6)
+	hashedPwd, err := h.Hash(pwd)
+	require.NoError(t, err)
+	require.True(t, strings.HasPrefix(hashedPwd, "$2a$04$"))
+
+	require.NoError(t, h.Check(pwd, hashedPwd))
+	require.ErrorIs(t, h.Check(RandomString(6), hashedPwd), ErrMismatchedPassword)
+	require.False(t, h.NeedsRehash(hashedPwd))
+}
+
+func TestBcryptHasherNeedsRehash(t *testing.T) {
+	h, err := NewBcryptHasher(bcrypt.MinCost + 1)
+	require.NoError(t, err)
+
+	cheaper, err := NewBcryptHasher(bcrypt.MinCost)
+	require.NoError(t, err)
+	hashedPwd, err := cheaper.Hash("secret")
+	require.NoError(t, err)
+	require.True(t, h.NeedsRehash(hashedPwd))
+
+	// switching back from argon2id rehashes too
+	a, err := NewArgon2idHasher(testArgon2Params)
+	require.NoError(t, err)
+	hashedPwd, err = a.Hash("secret")
+	require.NoError(t, err)
+	require.True(t, h.NeedsRehash(hashedPwd))
+	require.NoError(t, h.Check("secret", hashedPwd))
+}
+
+func TestBcryptHasherDoesNotDowngrade(t *testing.T) {
+	h, err := NewBcryptHasher(bcrypt.MinCost + 1)
+	require.NoError(t, err)
+	hashedPwd, err := h.Hash("secret")
+	require.NoError(t, err)
+
+	cheaper, err := NewBcryptHasher(bcrypt.MinCost)
+	require.NoError(t, err)
+	require.False(t, cheaper.NeedsRehash(hashedPwd))
+}
+
+func TestNewBcryptHasherInvalidCost(t *testing.T) {
+	h, err := NewBcryptHasher(bcrypt.MaxCost + 1)
+	require.Error(t, err)
+	require.Nil(t, h)
+
+	h, err = NewBcryptHasher(0)
+	require.NoError(t, err)
+	require.Equal(t, bcrypt.DefaultCost, h.(*BcryptHasher).cost)
+}
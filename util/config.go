@@ -7,11 +7,46 @@ import (
 )
 
 type Config struct {
-	DBDriver            string        `mapstructure:"DB_DRIVER"`
-	DBSource            string        `mapstructure:"DB_SOURCE"`
-	ServerAddr          string        `mapstructure:"SERVER_ADDR"`
-	TokenSecretKey      string        `mapstructure:"TOKEN_SECRET_KEY"`
-	AccessTokenDuration time.Duration `mapstructure:"ACCESS_TOKEN_DURATION"`
+	DBDriver                 string        `mapstructure:"DB_DRIVER"`
+	DBSource                 string        `mapstructure:"DB_SOURCE"`
+	ServerAddr               string        `mapstructure:"SERVER_ADDR"`
+	TrustedProxies           []string      `mapstructure:"TRUSTED_PROXIES"`
+	TokenSecretKey           string        `mapstructure:"TOKEN_SECRET_KEY"`
+	AccessTokenDuration      time.Duration `mapstructure:"ACCESS_TOKEN_DURATION"`
+	AdminUsernames           []string      `mapstructure:"ADMIN_USERNAMES"`
+	StepUpThreshold          int64         `mapstructure:"STEP_UP_THRESHOLD"`
+	StepUpThresholds         string        `mapstructure:"STEP_UP_THRESHOLDS"`
+	StepUpMaxAge             time.Duration `mapstructure:"STEP_UP_MAX_AGE"`
+	PasswordHasher           string        `mapstructure:"PASSWORD_HASHER"`
+	Argon2Memory             uint32        `mapstructure:"ARGON2_MEMORY"`
+	Argon2Iterations         uint32        `mapstructure:"ARGON2_ITERATIONS"`
+	Argon2Parallelism        uint8         `mapstructure:"ARGON2_PARALLELISM"`
+	BcryptCost               int           `mapstructure:"BCRYPT_COST"`
+	PasswordMinLength        int           `mapstructure:"PASSWORD_MIN_LENGTH"`
+	PasswordMinCharClasses   int           `mapstructure:"PASSWORD_MIN_CHAR_CLASSES"`
+	BreachedPasswordsFile    string        `mapstructure:"BREACHED_PASSWORDS_FILE"`
+	LoginMaxFailures         int32         `mapstructure:"LOGIN_MAX_FAILURES"`
+	LoginMaxFailuresPerIP    int32         `mapstructure:"LOGIN_MAX_FAILURES_PER_IP"`
+	LoginFailureWindow       time.Duration `mapstructure:"LOGIN_FAILURE_WINDOW"`
+	LoginLockout             time.Duration `mapstructure:"LOGIN_LOCKOUT"`
+	LoginMaxLockout          time.Duration `mapstructure:"LOGIN_MAX_LOCKOUT"`
+	OAuthCodeDuration        time.Duration `mapstructure:"OAUTH_CODE_DURATION"`
+	OAuthTokenDuration       time.Duration `mapstructure:"OAUTH_TOKEN_DURATION"`
+	OAuthRevocationCleanup   time.Duration `mapstructure:"OAUTH_REVOCATION_CLEANUP"`
+	ConsentMaxDuration       time.Duration `mapstructure:"CONSENT_MAX_DURATION"`
+	PayeeCoolingOff          time.Duration `mapstructure:"PAYEE_COOLING_OFF"`
+	PayeeCoolingOffThreshold int64         `mapstructure:"PAYEE_COOLING_OFF_THRESHOLD"`
+	OutboxRelayInterval      time.Duration `mapstructure:"OUTBOX_RELAY_INTERVAL"`
+	WebhookPollInterval      time.Duration `mapstructure:"WEBHOOK_POLL_INTERVAL"`
+	WebhookTimeout           time.Duration `mapstructure:"WEBHOOK_TIMEOUT"`
+	JobPollInterval          time.Duration `mapstructure:"JOB_POLL_INTERVAL"`
+	JobWorkers               int           `mapstructure:"JOB_WORKERS"`
+	PublicURL                string        `mapstructure:"PUBLIC_URL"`
+	MailFrom                 string        `mapstructure:"MAIL_FROM"`
+	MailDir                  string        `mapstructure:"MAIL_DIR"`
+	SMTPAddr                 string        `mapstructure:"SMTP_ADDR"`
+	SMTPUsername             string        `mapstructure:"SMTP_USERNAME"`
+	SMTPPassword             string        `mapstructure:"SMTP_PASSWORD"`
 }
 
 // LoadConfig reads configuration from file or environment variables.
@@ -1,5 +1,11 @@
 package util
 
+import (
+	"fmt"
+	"strconv"
+	"strings"
+)
+
 const (
 	USD = "USD"
 	EUR = "EUR"
@@ -13,3 +19,31 @@ func IsSupportedCurrency(currency string) bool {
 	}
 	return false
 }
+
+// ParseCurrencyAmounts reads a list such as "USD=100000,EUR=90000" into amounts by currency. Every currency
+// must be supported and listed once, and every amount a non-negative integer in that currency's minor unit.
+func ParseCurrencyAmounts(s string) (map[string]int64, error) {
+	amounts := make(map[string]int64)
+	if strings.TrimSpace(s) == "" {
+		return amounts, nil
+	}
+	for _, pair := range strings.Split(s, ",") {
+		kv := strings.SplitN(strings.TrimSpace(pair), "=", 2)
+		if len(kv) != 2 {
+			return nil, fmt.Errorf("%q is not CURRENCY=AMOUNT", pair)
+		}
+		currency := strings.ToUpper(strings.TrimSpace(kv[0]))
+		if !IsSupportedCurrency(currency) {
+			return nil, fmt.Errorf("unsupported currency %q", kv[0])
+		}
+		if _, ok := amounts[currency]; ok {
+			return nil, fmt.Errorf("currency %s is listed twice", currency)
+		}
+		amount, err := strconv.ParseInt(strings.TrimSpace(kv[1]), 10, 64)
+		if err != nil || amount < 0 {
+			return nil, fmt.Errorf("invalid amount %q for %s", kv[1], currency)
+		}
+		amounts[currency] = amount
+	}
+	return amounts, nil
+}
@@ -0,0 +1,22 @@
+package util
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestParseCurrencyAmounts(t *testing.T) {
+	amounts, err := ParseCurrencyAmounts(" usd=100000, EUR=90000 ")
+	require.NoError(t, err)
+	require.Equal(t, map[string]int64{USD: 100000, EUR: 90000}, amounts)
+
+	amounts, err = ParseCurrencyAmounts("")
+	require.NoError(t, err)
+	require.Empty(t, amounts)
+
+	for _, bad := range []string{"USD", "USD=", "USD=-1", "USD=1.5", "GBP=100", "USD=1,USD=2", "USD=1,"} {
+		_, err := ParseCurrencyAmounts(bad)
+		require.Error(t, err, bad)
+	}
+}
@@ -1,20 +1,65 @@
 package util
 
 import (
+	"errors"
 	"fmt"
+	"strings"
 
 	"golang.org/x/crypto/bcrypt"
 )
 
+// Password hashing algorithms.
+const (
+	Argon2id = "argon2id"
+	Bcrypt   = "bcrypt"
+)
+
+var (
+	ErrMismatchedPassword = errors.New("password does not match")
+	ErrUnknownHashFormat  = errors.New("unknown password hash format")
+)
+
+// PasswordHasher hashes new passwords with one algorithm and parameters, but checks hashes made by any
+// supported one, so stored hashes keep working while they are upgraded.
+type PasswordHasher interface {
+	Hash(pwd string) (string, error)
+	Check(pwd string, hashedPwd string) error
+	// NeedsRehash reports whether hashedPwd was made with another algorithm or weaker parameters
+	// than Hash would use now.
+	NeedsRehash(hashedPwd string) bool
+}
+
+// HashPassword hashes pwd with argon2id and the default parameters.
 func HashPassword(pwd string) (string, error) {
-	hashedPwd, err := bcrypt.GenerateFromPassword([]byte(pwd), bcrypt.DefaultCost)
+	h := &Argon2idHasher{params: DefaultArgon2Params}
+	return h.Hash(pwd)
+}
 
-	if err != nil {
-		return "", fmt.Errorf("failed to hash password: %w", err)
+// CheckPassword checks pwd against a hash made by any supported algorithm.
+func CheckPassword(pwd string, hashedPwd string) error {
+	switch hashAlgorithm(hashedPwd) {
+	case Argon2id:
+		return checkArgon2id(pwd, hashedPwd)
+	case Bcrypt:
+		err := bcrypt.CompareHashAndPassword([]byte(hashedPwd), []byte(pwd))
+		if err == bcrypt.ErrMismatchedHashAndPassword {
+			return ErrMismatchedPassword
+		}
+		if err != nil {
+			return fmt.Errorf("invalid bcrypt hash: %w", err)
+		}
+		return nil
 	}
-	return string(hashedPwd), nil
+	return ErrUnknownHashFormat
 }
 
-func CheckPassword(pwd string, hashedPwd string) error {
-	return bcrypt.CompareHashAndPassword([]byte(hashedPwd), []byte(pwd))
+// hashAlgorithm tells the algorithm from the PHC identifier at the start of hashedPwd.
+func hashAlgorithm(hashedPwd string) string {
+	switch {
+	case strings.HasPrefix(hashedPwd, "$argon2id$"):
+		return Argon2id
+	case strings.HasPrefix(hashedPwd, "$2a$"), strings.HasPrefix(hashedPwd, "$2b$"), strings.HasPrefix(hashedPwd, "$2y$"):
+		return Bcrypt
+	}
+	return ""
 }
@@ -0,0 +1,89 @@
+package util
+
+import (
+	"fmt"
+	"strings"
+	"unicode"
+	"unicode/utf8"
+)
+
+const (
+	// MinPasswordLength is the floor under any configured minimum.
+	MinPasswordLength = 6
+	// MaxPasswordLength bounds the work a single password costs to hash.
+	MaxPasswordLength = 128
+	// minPersonalInfoLength keeps very short usernames from ruling out passwords by accident.
+	minPersonalInfoLength = 3
+)
+
+// BreachedPasswords tells whether a password is known from a data breach.
+type BreachedPasswords interface {
+	Contains(pwd string) bool
+}
+
+// PasswordPolicy decides which new passwords are acceptable. Zero fields leave their rule out.
+type PasswordPolicy struct {
+	MinLength int
+	// MinCharClasses is how many of lowercase letters, uppercase letters, digits and symbols must appear.
+	MinCharClasses int
+	Breached       BreachedPasswords
+}
+
+// Check returns what is wrong with pwd as a password for the user with username and email, or nil if nothing is.
+func (p PasswordPolicy) Check(pwd, username, email string) []string {
+	var problems []string
+
+	minLength := p.MinLength
+	if minLength < MinPasswordLength {
+		minLength = MinPasswordLength
+	}
+	length := utf8.RuneCountInString(pwd)
+	if length < minLength {
+		problems = append(problems, fmt.Sprintf("must be at least %d characters long", minLength))
+	}
+	if length > MaxPasswordLength {
+		problems = append(problems, fmt.Sprintf("must be at most %d characters long", MaxPasswordLength))
+	}
+
+	if p.MinCharClasses > 0 && charClasses(pwd) < p.MinCharClasses {
+		problems = append(problems,
+			fmt.Sprintf("must mix at least %d of lowercase letters, uppercase letters, digits and symbols", p.MinCharClasses))
+	}
+
+	lower := strings.ToLower(pwd)
+	if len(username) >= minPersonalInfoLength && strings.Contains(lower, strings.ToLower(username)) {
+		problems = append(problems, "must not contain the username")
+	}
+	if local := strings.SplitN(email, "@", 2)[0]; len(local) >= minPersonalInfoLength && strings.Contains(lower, strings.ToLower(local)) {
+		problems = append(problems, "must not contain the email address")
+	}
+
+	if p.Breached != nil && p.Breached.Contains(pwd) {
+		problems = append(problems, "appears in a list of breached passwords, choose another one")
+	}
+	return problems
+}
+
+func charClasses(pwd string) int {
+	var lower, upper, digit, symbol bool
+	for _, r := range pwd {
+		switch {
+		case unicode.IsUpper(r):
+			upper = true
+		case unicode.IsLetter(r):
+			lower = true
+		case unicode.IsDigit(r):
+			digit = true
+		default:
+			symbol = true
+		}
+	}
+
+	n := 0
+	for _, present := range []bool{lower, upper, digit, symbol} {
+		if present {
+			n++
+		}
+	}
+	return n
+}
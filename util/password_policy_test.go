@@ -0,0 +1,93 @@
+package util
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+type breachedList map[string]bool
+
+func (b breachedList) Contains(pwd string) bool {
+	return b[pwd]
+}
+
+func TestPasswordPolicy(t *testing.T) {
+	policy := PasswordPolicy{
+		MinLength:      10,
+		MinCharClasses: 3,
+		Breached:       breachedList{"Password123!": true},
+	}
+
+	testCases := []struct {
+		name     string
+		pwd      string
+		problems []string
+	}{
+		{
+			name: "OK",
+			pwd:  "c0rrect-horse",
+		},
+		{
+			name:     "TooShort",
+			pwd:      "Sh0rt!",
+			problems: []string{"must be at least 10 characters long"},
+		},
+		{
+			name:     "TooLong",
+			pwd:      "Aa1-" + RandomString(MaxPasswordLength),
+			problems: []string{"must be at most 128 characters long"},
+		},
+		{
+			name:     "TooFewCharClasses",
+			pwd:      "alllowercase1",
+			problems: []string{"must mix at least 3 of lowercase letters, uppercase letters, digits and symbols"},
+		},
+		{
+			name:     "ContainsUsername",
+			pwd:      "my-Alice-2024",
+			problems: []string{"must not contain the username"},
+		},
+		{
+			name:     "ContainsEmail",
+			pwd:      "Wonder.Land-1",
+			problems: []string{"must not contain the email address"},
+		},
+		{
+			name:     "Breached",
+			pwd:      "Password123!",
+			problems: []string{"appears in a list of breached passwords, choose another one"},
+		},
+		{
+			name: "Several",
+			pwd:  "alice",
+			problems: []string{
+				"must be at least 10 characters long",
+				"must mix at least 3 of lowercase letters, uppercase letters, digits and symbols",
+				"must not contain the username",
+			},
+		},
+		{
+			name: "UnicodeCountsRunes",
+			pwd:  "Pässwörtér-1",
+		},
+	}
+
+	for i := range testCases {
+		tc := testCases[i]
+
+		t.Run(tc.name, func(t *testing.T) {
+			require.Equal(t, tc.problems, policy.Check(tc.pwd, "alice", "wonder.land@example.com"))
+		})
+	}
+}
+
+func TestPasswordPolicyZeroValue(t *testing.T) {
+	var policy PasswordPolicy
+
+	require.Empty(t, policy.Check("abcdef", "alice", "alice@example.com"))
+	// the old six character minimum still holds
+	require.Equal(t, []string{"must be at least 6 characters long"}, policy.Check("abc", "alice", "alice@example.com"))
+	// a short username does not rule out every password that happens to contain it
+	require.Empty(t, policy.Check("abcdef", "ab", "ab@example.com"))
+}
@@ -4,6 +4,7 @@ import (
 	"testing"
 
 	"github.com/stretchr/testify/require"
+	"golang.org/x/crypto/bcrypt"
 )
 
 func TestPassword(t *testing.T) {
@@ -16,12 +17,30 @@ func TestPassword(t *testing.T) {
 	err = CheckPassword(pwd, hashedPwd1)
 	require.NoError(t, err)
 
-	// wrongPassword := RandomString(6)
-	// err = CheckPassword(wrongPassword, hashedPassword)
-	// require.EqualError(t, err, bcrypt.ErrMismatchedHashAndPassword.Error())
+	wrongPassword := RandomString(6)
+	err = CheckPassword(wrongPassword, hashedPwd1)
+	require.ErrorIs(t, err, ErrMismatchedPassword)
 
 	hashedPwd2, err := HashPassword(pwd)
 	require.NoError(t, err)
 	require.NotEmpty(t, hashedPwd2)
 	require.NotEqual(t, hashedPwd1, hashedPwd2)
 }
+
+func TestCheckLegacyBcryptPassword(t *testing.T) {
+	pwd := RandomString(6)
+
+	// hashes stored before argon2id still check
+	hashedPwd, err := bcrypt.GenerateFromPassword([]byte(pwd), bcrypt.MinCost)
+	require.NoError(t, err)
+
+	require.NoError(t, CheckPassword(pwd, string(hashedPwd)))
+	require.ErrorIs(t, CheckPassword(RandomString(6), string(hashedPwd)), ErrMismatchedPassword)
+}
+
+func TestCheckPasswordUnknownFormat(t *testing.T) {
+	require.ErrorIs(t, CheckPassword("secret", "secret"), ErrUnknownHashFormat)
+	require.ErrorIs(t, CheckPassword("secret", "$scrypt$ln=15,r=8,p=1$c2FsdA$aGFzaA"), ErrUnknownHashFormat)
+	require.Error(t, CheckPassword("secret", "$argon2id$v=19$m=x$c2FsdA$aGFzaA"))
+	require.Error(t, CheckPassword("secret", "$argon2id$v=16$m=64,t=1,p=1$c2FsdA$aGFzaA"))
+}
@@ -42,3 +42,8 @@ func RandomCurrency() string {
 	n := len(currencies)
 	return currencies[rand.Intn(n)]
 }
+
+// RandomAccountNumber returns an account number with valid check digits for tests, which need no crypto/rand.
+func RandomAccountNumber() string {
+	return accountNumber(RandomInt(0, 9999999999))
+}
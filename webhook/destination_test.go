@@ -0,0 +1,74 @@
+package webhook
+
+import (
+	"context"
+	"errors"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+)
+
+type fakeResolver map[string][]string
+
+func (r fakeResolver) LookupIPAddr(_ context.Context, host string) ([]net.IPAddr, error) {
+	ips, ok := r[host]
+	if !ok {
+		return nil, errors.New("no such host")
+	}
+	var addrs []net.IPAddr
+	for _, ip := range ips {
+		addrs = append(addrs, net.IPAddr{IP: net.ParseIP(ip)})
+	}
+	return addrs, nil
+}
+
+func TestCheckURL(t *testing.T) {
+	resolver := fakeResolver{
+		"example.com":       {"93.184.216.34"},
+		"internal.example":  {"10.0.0.5"},
+		"rebind.example":    {"93.184.216.34", "127.0.0.1"},
+		"metadata.internal": {"169.254.169.254"},
+	}
+
+	ok := []string{
+		"https://example.com/hooks",
+		"http://93.184.216.34:8080/hooks",
+	}
+	for _, u := range ok {
+		require.NoError(t, CheckURL(context.Background(), resolver, u), u)
+	}
+
+	forbidden := []string{
+		"http://127.0.0.1/hooks",
+		"http://[::1]/hooks",
+		"http://169.254.169.254/latest/meta-data",
+		"http://10.1.2.3/",
+		"http://172.16.0.1/",
+		"http://192.168.1.1/",
+		"http://100.64.0.1/",
+		"http://0.0.0.0/",
+		"http://[fd00::1]/",
+		"http://[::ffff:127.0.0.1]/",
+		"https://internal.example/hooks",
+		"https://rebind.example/hooks",
+		"https://metadata.internal/",
+	}
+	for _, u := range forbidden {
+		require.ErrorIs(t, CheckURL(context.Background(), resolver, u), ErrForbiddenDestination, u)
+	}
+
+	require.Error(t, CheckURL(context.Background(), resolver, "ftp://example.com/"))
+	require.Error(t, CheckURL(context.Background(), resolver, "https://unknown.example/"))
+}
+
+func TestNewClientRefusesPrivateAddresses(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {}))
+	defer srv.Close()
+
+	_, err := NewClient(time.Second).Get(srv.URL)
+	require.ErrorIs(t, err, ErrForbiddenDestination)
+}
@@ -0,0 +1,118 @@
+package webhook
+
+import (
+	"context"
+	"encoding/json"
+	db "gobank/db/sqlc"
+	"gobank/event"
+	"time"
+)
+
+// Event types endpoints can subscribe to.
+const (
+	EventTransferIncoming = "transfer.incoming"
+	EventTransferOutgoing = "transfer.outgoing"
+	EventAccountCreated   = "account.created"
+)
+
+func IsSupportedEventType(t string) bool {
+	switch t {
+	case EventTransferIncoming, EventTransferOutgoing, EventAccountCreated:
+		return true
+	}
+	return false
+}
+
+// Payload is the JSON body posted to an endpoint.
+type Payload struct {
+	EventID   int64       `json:"event_id"`
+	Type      string      `json:"type"`
+	CreatedAt time.Time   `json:"created_at"`
+	Data      interface{} `json:"data"`
+}
+
+// TransferData is the data of a transfer event, limited to what the receiving side of it may see.
+type TransferData struct {
+	Transfer db.Transfer `json:"transfer"`
+	Entry    db.Entry    `json:"entry"`
+}
+
+// Dispatcher is an event.Publisher that queues a delivery for every endpoint subscribed to a domain event.
+type Dispatcher struct {
+	store db.Store
+}
+
+func NewDispatcher(store db.Store) *Dispatcher {
+	return &Dispatcher{store: store}
+}
+
+// notification is one webhook event type for one user, derived from a domain event.
+type notification struct {
+	owner string
+	typ   string
+	data  interface{}
+}
+
+func (d *Dispatcher) Publish(ctx context.Context, e event.Event) error {
+	notifications, err := notificationsFor(e)
+	if err != nil {
+		return err
+	}
+
+	for _, n := range notifications {
+		endpoints, err := d.store.ListWebhookEndpointsForEvent(ctx, db.ListWebhookEndpointsForEventParams{
+			Owner:     n.owner,
+			EventType: n.typ,
+		})
+		if err != nil {
+			return err
+		}
+		if len(endpoints) == 0 {
+			continue
+		}
+
+		body, err := json.Marshal(Payload{
+			EventID:   e.ID,
+			Type:      n.typ,
+			CreatedAt: e.OccurredAt,
+			Data:      n.data,
+		})
+		if err != nil {
+			return err
+		}
+		for _, ep := range endpoints {
+			//a delivery is unique per endpoint and event, so a relay retrying this event does not queue it twice
+			err := d.store.CreateWebhookDelivery(ctx, db.CreateWebhookDeliveryParams{
+				EndpointID: ep.ID,
+				EventID:    e.ID,
+				EventType:  n.typ,
+				Payload:    body,
+			})
+			if err != nil {
+				return err
+			}
+		}
+	}
+	return nil
+}
+
+func notificationsFor(e event.Event) ([]notification, error) {
+	switch e.Type {
+	case db.EventTransferCompleted:
+		var tr db.TransferTxResult
+		if err := json.Unmarshal(e.Payload, &tr); err != nil {
+			return nil, err
+		}
+		return []notification{
+			{owner: tr.FromAccount.Owner, typ: EventTransferOutgoing, data: TransferData{Transfer: tr.Transfer, Entry: tr.FromEntry}},
+			{owner: tr.ToAccount.Owner, typ: EventTransferIncoming, data: TransferData{Transfer: tr.Transfer, Entry: tr.ToEntry}},
+		}, nil
+	case db.EventAccountCreated:
+		var acc db.Account
+		if err := json.Unmarshal(e.Payload, &acc); err != nil {
+			return nil, err
+		}
+		return []notification{{owner: acc.Owner, typ: EventAccountCreated, data: acc}}, nil
+	}
+	return nil, nil
+}
@@ -0,0 +1,75 @@
+package webhook
+
+import (
+	"context"
+	"encoding/json"
+	mockdb "gobank/db/mock"
+	db "gobank/db/sqlc"
+	"gobank/event"
+	"testing"
+	"time"
+
+	"github.com/golang/mock/gomock"
+	"github.com/stretchr/testify/require"
+)
+
+func TestDispatcherPublishTransfer(t *testing.T) {
+	tr := db.TransferTxResult{
+		Transfer:    db.Transfer{ID: 5, FromAccountID: 1, ToAccountID: 2, Amount: 10},
+		FromAccount: db.Account{ID: 1, Owner: "alice", Balance: 90},
+		ToAccount:   db.Account{ID: 2, Owner: "bob", Balance: 110},
+		FromEntry:   db.Entry{ID: 11, AccountID: 1, Amount: -10},
+		ToEntry:     db.Entry{ID: 12, AccountID: 2, Amount: 10},
+	}
+	data, err := json.Marshal(tr)
+	require.NoError(t, err)
+	e := event.Event{ID: 9, Type: db.EventTransferCompleted, Payload: data, OccurredAt: time.Now()}
+
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	store := mockdb.NewMockStore(ctrl)
+	store.EXPECT().
+		ListWebhookEndpointsForEvent(gomock.Any(), gomock.Eq(db.ListWebhookEndpointsForEventParams{Owner: "alice", EventType: EventTransferOutgoing})).
+		Times(1).
+		Return([]db.WebhookEndpoint{}, nil)
+	store.EXPECT().
+		ListWebhookEndpointsForEvent(gomock.Any(), gomock.Eq(db.ListWebhookEndpointsForEventParams{Owner: "bob", EventType: EventTransferIncoming})).
+		Times(1).
+		Return([]db.WebhookEndpoint{{ID: 3, Owner: "bob"}}, nil)
+	store.EXPECT().
+		CreateWebhookDelivery(gomock.Any(), gomock.Any()).
+		Times(1).
+		DoAndReturn(func(ctx context.Context, arg db.CreateWebhookDeliveryParams) error {
+			require.Equal(t, int64(3), arg.EndpointID)
+			require.Equal(t, e.ID, arg.EventID)
+			require.Equal(t, EventTransferIncoming, arg.EventType)
+
+			var body struct {
+				Type string `json:"type"`
+				Data struct {
+					Entry       db.Entry        `json:"entry"`
+					FromAccount json.RawMessage `json:"from_account"`
+				} `json:"data"`
+			}
+			require.NoError(t, json.Unmarshal(arg.Payload, &body))
+			require.Equal(t, EventTransferIncoming, body.Type)
+			require.Equal(t, tr.ToEntry.ID, body.Data.Entry.ID)
+			// the receiver must not learn the sender's balance
+			require.Nil(t, body.Data.FromAccount)
+			return nil
+		})
+
+	require.NoError(t, NewDispatcher(store).Publish(context.Background(), e))
+}
+
+func TestDispatcherIgnoresUnmappedEvents(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	store := mockdb.NewMockStore(ctrl)
+	store.EXPECT().ListWebhookEndpointsForEvent(gomock.Any(), gomock.Any()).Times(0)
+
+	e := event.Event{ID: 1, Type: db.EventUserCreated, Payload: json.RawMessage(`{}`)}
+	require.NoError(t, NewDispatcher(store).Publish(context.Background(), e))
+}
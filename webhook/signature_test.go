@@ -0,0 +1,32 @@
+package webhook
+
+import (
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestSignAndVerify(t *testing.T) {
+	secret, err := NewSecret()
+	require.NoError(t, err)
+	require.True(t, strings.HasPrefix(secret, "whsec_"))
+
+	body := []byte(`{"event_id":1}`)
+	now := time.Now()
+	header := Sign(secret, now, body)
+
+	require.NoError(t, Verify(secret, header, body, now, time.Minute))
+	require.Equal(t, ErrInvalidSignature, Verify(secret, header, []byte(`{"event_id":2}`), now, time.Minute))
+	require.Equal(t, ErrInvalidSignature, Verify("other", header, body, now, time.Minute))
+	require.Equal(t, ErrInvalidSignature, Verify(secret, "garbage", body, now, time.Minute))
+	require.Equal(t, ErrExpiredSignature, Verify(secret, header, body, now.Add(2*time.Minute), time.Minute))
+}
+
+func TestBackoff(t *testing.T) {
+	require.Equal(t, 30*time.Second, Backoff(1))
+	require.Equal(t, time.Minute, Backoff(2))
+	require.Equal(t, 4*time.Minute, Backoff(4))
+	require.Equal(t, maxBackoff, Backoff(20))
+}
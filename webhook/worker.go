@@ -0,0 +1,160 @@
+package webhook
+
+import (
+	"bytes"
+	"context"
+	"database/sql"
+	"fmt"
+	db "gobank/db/sqlc"
+	"io"
+	"io/ioutil"
+	"log"
+	"net/http"
+	"strconv"
+	"time"
+)
+
+// Delivery statuses.
+const (
+	StatusPending   = "pending"
+	StatusSucceeded = "succeeded"
+	StatusFailed    = "failed"
+)
+
+const (
+	defaultBatchSize   = 20
+	defaultMaxAttempts = 8
+	// claimed deliveries are hidden from other workers for this long, so a crashed worker's batch is retried
+	claimLease  = time.Minute
+	baseBackoff = 30 * time.Second
+	maxBackoff  = 6 * time.Hour
+)
+
+// Worker posts due deliveries to their endpoints and schedules retries with exponential backoff.
+type Worker struct {
+	store       db.Store
+	client      *http.Client
+	interval    time.Duration
+	batchSize   int32
+	maxAttempts int32
+	now         func() time.Time
+}
+
+func NewWorker(store db.Store, client *http.Client, interval time.Duration) *Worker {
+	return &Worker{
+		store:       store,
+		client:      client,
+		interval:    interval,
+		batchSize:   defaultBatchSize,
+		maxAttempts: defaultMaxAttempts,
+		now:         time.Now,
+	}
+}
+
+// Run delivers due webhooks every interval until ctx is cancelled.
+func (w *Worker) Run(ctx context.Context) {
+	ticker := time.NewTicker(w.interval)
+	defer ticker.Stop()
+
+	for {
+		if _, err := w.DeliverDue(ctx); err != nil && ctx.Err() == nil {
+			log.Println("cannot deliver webhooks:", err)
+		}
+
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+	}
+}
+
+// DeliverDue attempts one batch of due deliveries and returns how many were attempted. A delivery that cannot be
+// attempted is logged and left for its lease to expire; the rest of the batch still goes out, and the first such
+// error is returned.
+func (w *Worker) DeliverDue(ctx context.Context) (int, error) {
+	deliveries, err := w.store.ClaimDueWebhookDeliveries(ctx, db.ClaimDueWebhookDeliveriesParams{
+		LeaseUntil:    w.now().Add(claimLease),
+		MaxDeliveries: w.batchSize,
+	})
+	if err != nil {
+		return 0, err
+	}
+	var firstErr error
+	for _, d := range deliveries {
+		if _, err := w.Deliver(ctx, d); err != nil {
+			log.Printf("cannot deliver webhook delivery %d: %v", d.ID, err)
+			if firstErr == nil {
+				firstErr = err
+			}
+		}
+	}
+	return len(deliveries), firstErr
+}
+
+// Deliver makes one attempt at d and records the outcome.
+func (w *Worker) Deliver(ctx context.Context, d db.WebhookDelivery) (db.WebhookDelivery, error) {
+	endpoint, err := w.store.GetWebhookEndpoint(ctx, d.EndpointID)
+	if err != nil {
+		return d, err
+	}
+
+	now := w.now()
+	code, sendErr := w.send(ctx, endpoint, d, now)
+
+	attempts := d.Attempts + 1
+	arg := db.RecordWebhookDeliveryAttemptParams{
+		ID:             d.ID,
+		Status:         StatusSucceeded,
+		ResponseStatus: int32(code),
+		NextAttemptAt:  now,
+		DeliveredAt:    sql.NullTime{Time: now, Valid: true},
+	}
+	if sendErr != nil {
+		arg.LastError = sendErr.Error()
+		arg.DeliveredAt = sql.NullTime{}
+		arg.Status = StatusPending
+		arg.NextAttemptAt = now.Add(Backoff(attempts))
+		if attempts >= w.maxAttempts {
+			arg.Status = StatusFailed
+		}
+	}
+	return w.store.RecordWebhookDeliveryAttempt(ctx, arg)
+}
+
+// send posts the stored payload, returning the response status and an error for anything but a 2xx.
+func (w *Worker) send(ctx context.Context, endpoint db.WebhookEndpoint, d db.WebhookDelivery, now time.Time) (int, error) {
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, endpoint.Url, bytes.NewReader(d.Payload))
+	if err != nil {
+		return 0, err
+	}
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set(SignatureHeader, Sign(endpoint.Secret, now, d.Payload))
+	req.Header.Set(EventHeader, d.EventType)
+	req.Header.Set(DeliveryHeader, strconv.FormatInt(d.ID, 10))
+
+	// redirects are followed by the same client, so they get the same destination check as the endpoint
+	res, err := w.client.Do(req)
+	if err != nil {
+		return 0, err
+	}
+	defer res.Body.Close()
+	io.Copy(ioutil.Discard, io.LimitReader(res.Body, 64<<10))
+
+	if res.StatusCode < 200 || res.StatusCode > 299 {
+		return res.StatusCode, fmt.Errorf("endpoint responded with %s", res.Status)
+	}
+	return res.StatusCode, nil
+}
+
+// Backoff is the wait before the attempt following attempt number attempts: 30s, 1m, 2m, ... capped at 6h.
+func Backoff(attempts int32) time.Duration {
+	d := baseBackoff
+	for i := int32(1); i < attempts; i++ {
+		d *= 2
+		if d >= maxBackoff {
+			return maxBackoff
+		}
+	}
+	return d
+}
@@ -0,0 +1,164 @@
+package webhook
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	mockdb "gobank/db/mock"
+	db "gobank/db/sqlc"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/golang/mock/gomock"
+	"github.com/stretchr/testify/require"
+)
+
+func TestWorkerDeliver(t *testing.T) {
+	now := time.Now().Truncate(time.Second)
+	payload := json.RawMessage(`{"event_id":9,"type":"transfer.incoming"}`)
+
+	testCases := []struct {
+		name     string
+		status   int
+		attempts int32
+		check    func(t *testing.T, arg db.RecordWebhookDeliveryAttemptParams)
+	}{
+		{
+			name:   "Succeeded",
+			status: http.StatusNoContent,
+			check: func(t *testing.T, arg db.RecordWebhookDeliveryAttemptParams) {
+				require.Equal(t, StatusSucceeded, arg.Status)
+				require.Equal(t, int32(http.StatusNoContent), arg.ResponseStatus)
+				require.True(t, arg.DeliveredAt.Valid)
+				require.Empty(t, arg.LastError)
+			},
+		},
+		{
+			name:   "RetriedWithBackoff",
+			status: http.StatusInternalServerError,
+			check: func(t *testing.T, arg db.RecordWebhookDeliveryAttemptParams) {
+				require.Equal(t, StatusPending, arg.Status)
+				require.Equal(t, int32(http.StatusInternalServerError), arg.ResponseStatus)
+				require.Equal(t, now.Add(baseBackoff), arg.NextAttemptAt)
+				require.False(t, arg.DeliveredAt.Valid)
+				require.Contains(t, arg.LastError, "500")
+			},
+		},
+		{
+			name:     "GivesUpAfterMaxAttempts",
+			status:   http.StatusBadGateway,
+			attempts: defaultMaxAttempts - 1,
+			check: func(t *testing.T, arg db.RecordWebhookDeliveryAttemptParams) {
+				require.Equal(t, StatusFailed, arg.Status)
+			},
+		},
+	}
+
+	for i := range testCases {
+		tc := testCases[i]
+
+		t.Run(tc.name, func(t *testing.T) {
+			endpoint := db.WebhookEndpoint{ID: 3, Owner: "alice", Secret: "whsec_test"}
+			delivery := db.WebhookDelivery{ID: 42, EndpointID: endpoint.ID, EventType: EventTransferIncoming, Payload: payload, Attempts: tc.attempts}
+
+			var received bool
+			srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				body, err := ioutil.ReadAll(r.Body)
+				require.NoError(t, err)
+				require.JSONEq(t, string(payload), string(body))
+				require.NoError(t, Verify(endpoint.Secret, r.Header.Get(SignatureHeader), body, now, time.Minute))
+				require.Equal(t, EventTransferIncoming, r.Header.Get(EventHeader))
+				require.Equal(t, "42", r.Header.Get(DeliveryHeader))
+				received = true
+				w.WriteHeader(tc.status)
+			}))
+			defer srv.Close()
+			endpoint.Url = srv.URL
+
+			ctrl := gomock.NewController(t)
+			defer ctrl.Finish()
+
+			store := mockdb.NewMockStore(ctrl)
+			store.EXPECT().GetWebhookEndpoint(gomock.Any(), gomock.Eq(endpoint.ID)).Times(1).Return(endpoint, nil)
+			store.EXPECT().
+				RecordWebhookDeliveryAttempt(gomock.Any(), gomock.Any()).
+				Times(1).
+				DoAndReturn(func(ctx context.Context, arg db.RecordWebhookDeliveryAttemptParams) (db.WebhookDelivery, error) {
+					require.Equal(t, delivery.ID, arg.ID)
+					tc.check(t, arg)
+					return delivery, nil
+				})
+
+			w := NewWorker(store, srv.Client(), time.Second)
+			w.now = func() time.Time { return now }
+
+			_, err := w.Deliver(context.Background(), delivery)
+			require.NoError(t, err)
+			require.True(t, received)
+		})
+	}
+}
+
+func TestWorkerDeliverUnreachable(t *testing.T) {
+	srv := httptest.NewServer(http.NotFoundHandler())
+	url := srv.URL
+	srv.Close()
+
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	store := mockdb.NewMockStore(ctrl)
+	store.EXPECT().GetWebhookEndpoint(gomock.Any(), gomock.Any()).Times(1).Return(db.WebhookEndpoint{ID: 1, Url: url}, nil)
+	store.EXPECT().
+		RecordWebhookDeliveryAttempt(gomock.Any(), gomock.Any()).
+		Times(1).
+		DoAndReturn(func(ctx context.Context, arg db.RecordWebhookDeliveryAttemptParams) (db.WebhookDelivery, error) {
+			require.Equal(t, StatusPending, arg.Status)
+			require.Zero(t, arg.ResponseStatus)
+			require.NotEmpty(t, arg.LastError)
+			return db.WebhookDelivery{}, nil
+		})
+
+	w := NewWorker(store, http.DefaultClient, time.Second)
+	_, err := w.Deliver(context.Background(), db.WebhookDelivery{ID: 1, EndpointID: 1, Payload: json.RawMessage(`{}`)})
+	require.NoError(t, err)
+}
+
+func TestWorkerDeliverDueContinuesAfterError(t *testing.T) {
+	var received int
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		received++
+		w.WriteHeader(http.StatusNoContent)
+	}))
+	defer srv.Close()
+
+	deliveries := []db.WebhookDelivery{
+		{ID: 1, EndpointID: 1, Payload: json.RawMessage(`{}`)},
+		{ID: 2, EndpointID: 2, Payload: json.RawMessage(`{}`)},
+	}
+
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	store := mockdb.NewMockStore(ctrl)
+	store.EXPECT().ClaimDueWebhookDeliveries(gomock.Any(), gomock.Any()).Times(1).Return(deliveries, nil)
+	store.EXPECT().GetWebhookEndpoint(gomock.Any(), gomock.Eq(int64(1))).Times(1).Return(db.WebhookEndpoint{}, errors.New("connection reset"))
+	store.EXPECT().GetWebhookEndpoint(gomock.Any(), gomock.Eq(int64(2))).Times(1).Return(db.WebhookEndpoint{ID: 2, Url: srv.URL}, nil)
+	store.EXPECT().
+		RecordWebhookDeliveryAttempt(gomock.Any(), gomock.Any()).
+		Times(1).
+		DoAndReturn(func(ctx context.Context, arg db.RecordWebhookDeliveryAttemptParams) (db.WebhookDelivery, error) {
+			require.Equal(t, int64(2), arg.ID)
+			require.Equal(t, StatusSucceeded, arg.Status)
+			return deliveries[1], nil
+		})
+
+	w := NewWorker(store, srv.Client(), time.Second)
+	n, err := w.DeliverDue(context.Background())
+	require.EqualError(t, err, "connection reset")
+	require.Equal(t, 2, n)
+	require.Equal(t, 1, received)
+}